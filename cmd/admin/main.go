@@ -0,0 +1,411 @@
+// Command admin manages the users of the core service in its database: creating, disabling and
+// deleting users, resetting passwords, granting admin rights, unlocking accounts and issuing API
+// keys. It also gives the recipes from before recipes had owners to a user. It reads the same
+// configuration as the core service (see core.Config).
+//
+//	admin users
+//	admin create-user -username anton
+//	echo "$PASSWORD" | admin reset-password -username anton
+//	admin unlock -username anton
+//	admin migrate-recipes -owner anton
+//	admin create-api-key -username anton -name "Kitchen shortcuts" -scopes recipes:read,ai:import -expires 2160h
+//
+// Passwords are read from the terminal without echo, or from stdin if it is not a terminal.
+// Every change is recorded in the audit log with the operating system user as the actor.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/user"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core"
+	"github.com/AntonLuning/RecipeBank/internal/core/auth"
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+)
+
+const usage = `Usage: admin <command> [flags]
+
+Commands:
+  users             List users
+  create-user       Create a user (-username)
+  reset-password    Reset the password of a user (-username)
+  disable-user      Stop a user from logging in (-username)
+  enable-user       Allow a disabled user to log in again (-username)
+  delete-user       Delete a user (-username, -yes)
+  grant-admin       Allow a user to see and change all recipes (-username)
+  revoke-admin      Revoke the admin rights of a user (-username)
+  unlock            Unlock logins of a user or client IP (-username or -ip)
+  create-api-key    Issue an API key for a user (-username, -name, -scopes, -expires)
+  migrate-recipes   Give the recipes without an owner to a user (-owner, -visibility)
+
+Run "admin <command> -h" for the flags of a command.
+`
+
+// command runs a subcommand with its arguments
+type command func(ctx context.Context, a *admin, args []string) error
+
+var commands = map[string]command{
+	"users":           listUsers,
+	"create-user":     createUser,
+	"reset-password":  resetPassword,
+	"disable-user":    disableUser,
+	"enable-user":     enableUser,
+	"delete-user":     deleteUser,
+	"grant-admin":     grantAdmin,
+	"revoke-admin":    revokeAdmin,
+	"unlock":          unlock,
+	"create-api-key":  createAPIKey,
+	"migrate-recipes": migrateRecipes,
+}
+
+// admin is what the commands operate on
+type admin struct {
+	storage       *storage.MongoStorage
+	authenticator *auth.Authenticator
+	// Recorded in the audit log
+	actor string
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprint(os.Stderr, usage)
+		os.Exit(2)
+	}
+	run, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown command %q\n\n%s", os.Args[1], usage)
+		os.Exit(2)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a, closeStorage, err := newAdmin(ctx)
+	if err != nil {
+		slog.Error("Unable to connect", "error", err.Error())
+		os.Exit(1)
+	}
+	defer closeStorage()
+
+	if err := run(ctx, a, os.Args[2:]); err != nil {
+		if !errors.Is(err, flag.ErrHelp) {
+			fmt.Fprintf(os.Stderr, "Error: %s\n", err)
+		}
+		closeStorage()
+		os.Exit(1)
+	}
+}
+
+func newAdmin(ctx context.Context) (*admin, func(), error) {
+	cfg := core.Config()
+
+	mongoStorage, err := storage.NewMongoStorage(ctx, storage.StorageConfig{
+		Host:     cfg.Database.Host,
+		Port:     int(cfg.Database.Port),
+		Username: cfg.Database.Username,
+		Password: cfg.Database.Password,
+		Database: cfg.Database.Database,
+	})
+	if err != nil {
+		return nil, nil, err
+	}
+	closeStorage := func() {
+		if err := mongoStorage.Close(context.Background()); err != nil {
+			slog.Error("Unable to close storage", "error", err.Error())
+		}
+	}
+
+	// The indexes, e.g. for unique usernames, may not exist yet if the core service never ran
+	if err := mongoStorage.Initialize(ctx); err != nil {
+		closeStorage()
+		return nil, nil, err
+	}
+
+	authenticator, err := auth.NewAuthenticator(mongoStorage, mongoStorage, mongoStorage, auth.Config{
+		Secret:               []byte(strings.TrimSpace(cfg.Auth.JWTSecret)),
+		Issuer:               cfg.Auth.Issuer,
+		AccessTokenTTL:       cfg.Auth.AccessTokenTTL,
+		RefreshTokenTTL:      cfg.Auth.RefreshTokenTTL,
+		MaxFailedLogins:      cfg.Auth.MaxFailedLogins,
+		MaxFailedLoginsPerIP: cfg.Auth.MaxFailedLoginsPerIP,
+		LockoutDuration:      cfg.Auth.LockoutDuration,
+	})
+	if err != nil {
+		closeStorage()
+		return nil, nil, err
+	}
+
+	actor := "admin-cli"
+	if current, err := user.Current(); err == nil {
+		actor += ":" + current.Username
+	}
+
+	return &admin{storage: mongoStorage, authenticator: authenticator, actor: actor}, closeStorage, nil
+}
+
+func listUsers(ctx context.Context, a *admin, args []string) error {
+	flags := flag.NewFlagSet("users", flag.ContinueOnError)
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	users, err := a.authenticator.GetUsers(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "USERNAME\tID\tCREATED\tROLE\tSTATUS")
+	for _, user := range users {
+		role := "user"
+		if user.Admin {
+			role = "admin"
+		}
+		status := "active"
+		if user.Disabled() {
+			status = "disabled since " + user.DisabledAt.Local().Format(time.DateTime)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", user.Username, user.ID.Hex(), user.CreatedAt.Local().Format(time.DateTime), role, status)
+	}
+	return w.Flush()
+}
+
+func createUser(ctx context.Context, a *admin, args []string) error {
+	flags := flag.NewFlagSet("create-user", flag.ContinueOnError)
+	username := flags.String("username", "", "username of the new user")
+	if err := parseWithUsername(flags, args, username); err != nil {
+		return err
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+
+	user, err := a.authenticator.CreateUser(ctx, *username, password, a.actor)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created user %s (%s)\n", user.Username, user.ID.Hex())
+	return nil
+}
+
+func resetPassword(ctx context.Context, a *admin, args []string) error {
+	flags := flag.NewFlagSet("reset-password", flag.ContinueOnError)
+	username := flags.String("username", "", "username of the user")
+	if err := parseWithUsername(flags, args, username); err != nil {
+		return err
+	}
+
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+
+	if err := a.authenticator.ResetPassword(ctx, *username, password, a.actor); err != nil {
+		return err
+	}
+
+	fmt.Printf("Reset the password of %s\n", *username)
+	return nil
+}
+
+func disableUser(ctx context.Context, a *admin, args []string) error {
+	flags := flag.NewFlagSet("disable-user", flag.ContinueOnError)
+	username := flags.String("username", "", "username of the user")
+	if err := parseWithUsername(flags, args, username); err != nil {
+		return err
+	}
+
+	if err := a.authenticator.DisableUser(ctx, *username, a.actor); err != nil {
+		return err
+	}
+
+	fmt.Printf("Disabled %s, access tokens issued before stay valid until they expire\n", *username)
+	return nil
+}
+
+func enableUser(ctx context.Context, a *admin, args []string) error {
+	flags := flag.NewFlagSet("enable-user", flag.ContinueOnError)
+	username := flags.String("username", "", "username of the user")
+	if err := parseWithUsername(flags, args, username); err != nil {
+		return err
+	}
+
+	if err := a.authenticator.EnableUser(ctx, *username, a.actor); err != nil {
+		return err
+	}
+
+	fmt.Printf("Enabled %s\n", *username)
+	return nil
+}
+
+func deleteUser(ctx context.Context, a *admin, args []string) error {
+	flags := flag.NewFlagSet("delete-user", flag.ContinueOnError)
+	username := flags.String("username", "", "username of the user")
+	yes := flags.Bool("yes", false, "confirm the deletion")
+	if err := parseWithUsername(flags, args, username); err != nil {
+		return err
+	}
+	if !*yes {
+		return fmt.Errorf("deleting %s cannot be undone, confirm with -yes", *username)
+	}
+
+	if err := a.authenticator.DeleteUser(ctx, *username, a.actor); err != nil {
+		return err
+	}
+
+	fmt.Printf("Deleted %s\n", *username)
+	return nil
+}
+
+func grantAdmin(ctx context.Context, a *admin, args []string) error {
+	return setAdmin(ctx, a, "grant-admin", args, true)
+}
+
+func revokeAdmin(ctx context.Context, a *admin, args []string) error {
+	return setAdmin(ctx, a, "revoke-admin", args, false)
+}
+
+func setAdmin(ctx context.Context, a *admin, name string, args []string, isAdmin bool) error {
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	username := flags.String("username", "", "username of the user")
+	if err := parseWithUsername(flags, args, username); err != nil {
+		return err
+	}
+
+	if err := a.authenticator.SetAdmin(ctx, *username, isAdmin, a.actor); err != nil {
+		return err
+	}
+
+	if isAdmin {
+		fmt.Printf("%s is an admin, from the next login or token refresh\n", *username)
+	} else {
+		fmt.Printf("%s is no longer an admin, access tokens issued before keep the rights until they expire\n", *username)
+	}
+	return nil
+}
+
+func unlock(ctx context.Context, a *admin, args []string) error {
+	flags := flag.NewFlagSet("unlock", flag.ContinueOnError)
+	username := flags.String("username", "", "username to unlock")
+	clientIP := flags.String("ip", "", "client IP to unlock")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	var err error
+	switch {
+	case *username != "" && *clientIP == "":
+		err = a.authenticator.Unlock(ctx, *username, a.actor)
+	case *clientIP != "" && *username == "":
+		err = a.authenticator.UnlockClient(ctx, *clientIP, a.actor)
+	default:
+		return errors.New("either -username or -ip is required")
+	}
+	if errors.Is(err, auth.ErrNotLocked) {
+		fmt.Println("Not locked")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	fmt.Println("Unlocked")
+	return nil
+}
+
+func createAPIKey(ctx context.Context, a *admin, args []string) error {
+	flags := flag.NewFlagSet("create-api-key", flag.ContinueOnError)
+	username := flags.String("username", "", "username of the user the key acts as")
+	name := flags.String("name", "", "name of the key, e.g. the script that uses it")
+	scopes := flags.String("scopes", "", "comma-separated scopes: recipes:read, recipes:write, ai:import")
+	expires := flags.Duration("expires", 0, "lifetime of the key, e.g. 2160h (0 never expires)")
+	if err := parseWithUsername(flags, args, username); err != nil {
+		return err
+	}
+
+	user, err := a.storage.GetUserByUsername(ctx, *username)
+	if err != nil {
+		return err
+	}
+	if user.Disabled() {
+		return fmt.Errorf("%s is disabled", *username)
+	}
+
+	var expiresAt *time.Time
+	if *expires > 0 {
+		at := time.Now().Add(*expires)
+		expiresAt = &at
+	}
+
+	key, err := a.authenticator.CreateAPIKey(ctx, user, *name, splitList(*scopes), expiresAt)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created API key %s (%s) for %s with scopes %s\n", key.Name, key.ID.Hex(), *username, strings.Join(key.Scopes, ", "))
+	fmt.Println("Store the key now, it cannot be shown again:")
+	fmt.Println(key.Key)
+	return nil
+}
+
+// parseWithUsername parses the flags of a command that requires a username
+func parseWithUsername(flags *flag.FlagSet, args []string, username *string) error {
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *username == "" {
+		return errors.New("-username is required")
+	}
+	return nil
+}
+
+func splitList(s string) []string {
+	items := []string{}
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func migrateRecipes(ctx context.Context, a *admin, args []string) error {
+	flags := flag.NewFlagSet("migrate-recipes", flag.ContinueOnError)
+	owner := flags.String("owner", "", "username of the user that gets the recipes")
+	visibility := flags.String("visibility", models.VisibilityPublic, "visibility of the recipes: private, household or public")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if *owner == "" {
+		return errors.New("-owner is required")
+	}
+	if !slices.Contains(models.Visibilities, *visibility) {
+		return fmt.Errorf("-visibility must be one of %s", strings.Join(models.Visibilities, ", "))
+	}
+
+	user, err := a.storage.GetUserByUsername(ctx, *owner)
+	if err != nil {
+		return err
+	}
+
+	assigned, err := a.storage.AssignRecipeOwner(ctx, user.ID.Hex(), *visibility)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Gave %d recipes without an owner to %s (%s)\n", assigned, *owner, *visibility)
+	return nil
+}
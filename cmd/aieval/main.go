@@ -0,0 +1,82 @@
+// Command aieval runs an AI provider over a set of golden recipes and reports the field-level
+// accuracy of the extracted recipes, so a new model, prompt version or provider can be checked
+// before switching over to it.
+//
+// Every fixture is an input file (an image, an HTML page or a text document) with the expected
+// result in a JSON file of the same name, e.g. omelett.jpeg and omelett.json.
+//
+//	OPENAI_API_KEY=... go run ./cmd/aieval -model gpt-4.1-mini-2025-04-14 -version v2
+//	go run ./cmd/aieval -provider openai -base-url http://localhost:11434/v1 -model llama3.2
+//	go run ./cmd/aieval -provider fake
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/ai"
+	"github.com/AntonLuning/RecipeBank/internal/core/eval"
+)
+
+func main() {
+	provider := flag.String("provider", "openai", `AI provider, "openai" (also for local OpenAI-compatible servers) or "fake"`)
+	model := flag.String("model", "gpt-4.1-mini-2025-04-14", "model of the provider")
+	baseURL := flag.String("base-url", "", "base URL of an OpenAI-compatible API (empty for OpenAI)")
+	promptsDir := flag.String("prompts", "", "directory with the prompt templates (empty for the built-in templates)")
+	version := flag.String("version", ai.DefaultPromptVersion, "version of the prompt templates")
+	fixtures := flag.String("fixtures", "testdata/eval", "directory with the fixtures")
+	timeout := flag.Duration("timeout", 60*time.Second, "timeout of the extraction of a fixture")
+	minScore := flag.Float64("min-score", 0, "exit with an error if the average overall score is lower")
+	flag.Parse()
+
+	var recipeAI ai.RecipeAI
+	switch *provider {
+	case "openai":
+		apiKey := os.Getenv("OPENAI_API_KEY")
+		if apiKey == "" && *baseURL == "" {
+			slog.Error("OPENAI_API_KEY is not set")
+			os.Exit(1)
+		}
+
+		prompts, err := ai.LoadPrompts(*promptsDir, *version)
+		if err != nil {
+			slog.Error("Unable to load AI prompts", "error", err.Error())
+			os.Exit(1)
+		}
+
+		recipeAI = ai.NewOpenAI(ai.OpenAIConfig{
+			APIKey:  apiKey,
+			Model:   *model,
+			BaseURL: *baseURL,
+			Prompts: prompts,
+		})
+		fmt.Printf("Provider openai, model %s, prompt version %s\n\n", *model, prompts.Version())
+	case "fake":
+		recipeAI = ai.NewFakeRecipeAI()
+		fmt.Printf("Provider fake\n\n")
+	default:
+		slog.Error("Unsupported AI provider", "provider", *provider)
+		os.Exit(1)
+	}
+
+	goldens, err := eval.LoadFixtures(*fixtures)
+	if err != nil {
+		slog.Error("Unable to load fixtures", "error", err.Error())
+		os.Exit(1)
+	}
+
+	report := eval.Run(context.Background(), recipeAI, goldens, *timeout)
+	if err := report.Write(os.Stdout); err != nil {
+		slog.Error("Unable to write report", "error", err.Error())
+		os.Exit(1)
+	}
+
+	if overall := report.Average().Overall(); overall < *minScore {
+		fmt.Printf("Average overall score %.2f is lower than %.2f\n", overall, *minScore)
+		os.Exit(1)
+	}
+}
@@ -3,11 +3,16 @@ package main
 import (
 	"context"
 	"log/slog"
+	"strings"
 
 	"github.com/AntonLuning/RecipeBank/internal/core"
 	"github.com/AntonLuning/RecipeBank/internal/core/ai"
+	"github.com/AntonLuning/RecipeBank/internal/core/auth"
+	"github.com/AntonLuning/RecipeBank/internal/core/fetch"
+	"github.com/AntonLuning/RecipeBank/internal/core/metrics"
 	"github.com/AntonLuning/RecipeBank/internal/core/service"
 	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/internal/tracing"
 )
 
 func main() {
@@ -16,6 +21,18 @@ func main() {
 
 	cfg := core.Config()
 
+	// Initialize tracing, flushing the remaining spans on exit
+	shutdownTracing, err := tracing.Setup(ctx, "recipebank-core", cfg.Tracing)
+	if err != nil {
+		slog.Error("Unable to set up tracing", "error", err.Error())
+		return
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("Unable to flush traces", "error", err.Error())
+		}
+	}()
+
 	dbConfig := storage.StorageConfig{
 		Host:     cfg.Database.Host,
 		Port:     int(cfg.Database.Port),
@@ -44,20 +61,109 @@ func main() {
 		return
 	}
 
+	// Initialize client for fetching user-provided URLs, the only one that does (the AI is given
+	// the fetched pages)
+	fetcher := fetch.NewClient(fetch.Config{
+		Timeout:      cfg.Fetch.Timeout,
+		MaxRedirects: cfg.Fetch.MaxRedirects,
+		MaxBodySize:  cfg.Fetch.MaxBodySize,
+		UserAgent:    cfg.Fetch.UserAgent,
+		AllowPrivate: cfg.Fetch.AllowPrivate,
+	})
+
 	// Initialize AI client
 	var aiClient ai.RecipeAI = nil
 	switch cfg.AI.Provider {
 	case "openai":
-		aiClient = ai.NewOpenAI(cfg.AI.APIKey, cfg.AI.Model)
+		if cfg.AI.APIKey == "" && cfg.AI.BaseURL == "" {
+			slog.Error("An API key is required for OpenAI")
+			return
+		}
+
+		prompts, err := ai.LoadPrompts(cfg.AI.PromptsDir, cfg.AI.PromptVersion)
+		if err != nil {
+			slog.Error("Unable to load AI prompts", "error", err.Error())
+			return
+		}
+		slog.Info("Loaded AI prompts", "version", prompts.Version())
+
+		aiClient = ai.NewOpenAI(ai.OpenAIConfig{
+			APIKey:  cfg.AI.APIKey,
+			Model:   cfg.AI.Model,
+			BaseURL: cfg.AI.BaseURL,
+			Prompts: prompts,
+		})
+		aiClient = ai.NewResilientRecipeAI(aiClient, ai.ResilienceConfig{
+			Timeout:          cfg.AI.Timeout,
+			MaxRetries:       cfg.AI.MaxRetries,
+			InitialBackoff:   cfg.AI.InitialBackoff,
+			MaxBackoff:       cfg.AI.MaxBackoff,
+			FailureThreshold: cfg.AI.BreakerThreshold,
+			OpenDuration:     cfg.AI.BreakerCooldown,
+		})
+		aiClient = ai.NewInstrumentedRecipeAI(aiClient)
+		aiClient = ai.NewTracedRecipeAI(aiClient, cfg.AI.Provider, cfg.AI.Model)
+
+		// Record token usage and cost of every AI call, and enforce the budget
+		prices, err := ai.ParsePriceTable(cfg.AI.Prices)
+		if err != nil {
+			slog.Error("Invalid AI prices", "error", err.Error())
+			return
+		}
+		aiClient = ai.NewMeteredRecipeAI(aiClient, storage, prices, cfg.AI.MonthlyBudget)
+	case "fake":
+		slog.Warn("Using the fake AI provider, recipes are extracted with simple rules and images are not supported")
+		aiClient = ai.NewTracedRecipeAI(ai.NewFakeRecipeAI(), cfg.AI.Provider, "")
 	default:
 		slog.Warn("Empty or unsupported AI provider, running without AI", "provider", cfg.AI.Provider)
 	}
 
+	// Cache AI results, re-importing the same content should not call the model again
+	if cfg.AI.Provider == "openai" && cfg.AI.CacheTTL > 0 {
+		aiClient = ai.NewCachedRecipeAI(aiClient, storage, cfg.AI.Model, cfg.AI.PromptVersion, cfg.AI.CacheTTL)
+	}
+
+	// Count recipes when the metrics are scraped
+	metrics.Registry.MustRegister(metrics.NewRecipeCollector(storage.CountRecipesByVisibility))
+
 	// Initialize service layer
-	recipeService := service.NewRecipeService(storage, aiClient)
+	recipeService := service.NewRecipeService(storage, storage, aiClient, fetcher)
+	householdService := service.NewHouseholdService(storage)
+
+	// Recipes created before they had duplicate keys would not be found as duplicates
+	migrated, err := recipeService.MigrateDuplicateKeys(ctx)
+	if err != nil {
+		slog.Error("Unable to migrate duplicate keys of recipes", "error", err.Error())
+		return
+	}
+	if migrated > 0 {
+		slog.Info("Migrated duplicate keys of recipes", "recipes", migrated)
+	}
+
+	// Initialize authentication of requests that change data
+	authenticator, err := auth.NewAuthenticator(storage, storage, storage, auth.Config{
+		Secret:               []byte(strings.TrimSpace(cfg.Auth.JWTSecret)),
+		Issuer:               cfg.Auth.Issuer,
+		AccessTokenTTL:       cfg.Auth.AccessTokenTTL,
+		RefreshTokenTTL:      cfg.Auth.RefreshTokenTTL,
+		MaxFailedLogins:      cfg.Auth.MaxFailedLogins,
+		MaxFailedLoginsPerIP: cfg.Auth.MaxFailedLoginsPerIP,
+		LockoutDuration:      cfg.Auth.LockoutDuration,
+	})
+	if err != nil {
+		slog.Error("Unable to create authenticator", "error", err.Error())
+		return
+	}
+
+	// Initialize rate limiting of the requests of every client
+	rateLimits, err := core.ParseRateLimits(cfg.RateLimit)
+	if err != nil {
+		slog.Error("Invalid rate limits", "error", err.Error())
+		return
+	}
 
 	// Initialize API server
-	server := core.NewAPIServer(cfg.AppAddress(), recipeService)
+	server := core.NewAPIServer(cfg.AppAddress(), service.NewTracedService(recipeService), householdService, authenticator, core.NewRateLimiter(rateLimits))
 
 	// Start the server
 	if err := server.Run(); err != nil {
@@ -1,22 +1,36 @@
 package main
 
 import (
+	"context"
 	"log/slog"
 	"net/http"
 
+	"github.com/AntonLuning/RecipeBank/internal/tracing"
 	"github.com/AntonLuning/RecipeBank/internal/ui"
 )
 
 func main() {
 	cfg := ui.Config()
 
+	// Initialize tracing, flushing the remaining spans on exit
+	shutdownTracing, err := tracing.Setup(context.Background(), "recipebank-ui", cfg.Tracing)
+	if err != nil {
+		slog.Error("Unable to set up tracing", "error", err.Error())
+		return
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			slog.Error("Unable to flush traces", "error", err.Error())
+		}
+	}()
+
 	// Initialize Server
 	mux := http.NewServeMux()
 	ui.InitAssets(mux, cfg.AssetsPath, cfg.Debug)
-	ui.InitRoutes(mux)
+	ui.InitRoutes(mux, cfg.PublicURL)
 
 	// Start the server
-	if err := http.ListenAndServe(cfg.AppAddress(), mux); err != nil {
+	if err := http.ListenAndServe(cfg.AppAddress(), tracing.Middleware(mux)); err != nil {
 		slog.Error("Unable to run UI server", "error", err.Error())
 	}
 }
@@ -2,9 +2,33 @@ package ai
 
 import (
 	"context"
+
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
 )
 
 type RecipeAI interface {
 	AnalyzeRecipeImage(ctx context.Context, base64Image string, imageContentType ImageContentType) (*RecipeAnalysisResult, error)
-	AnalyzeRecipeWebpage(ctx context.Context, url string) (*RecipeAnalysisResult, error)
+	// AnalyzeRecipeImages analyzes several images (e.g. pages) of the same recipe, in order
+	AnalyzeRecipeImages(ctx context.Context, images []Image) (*RecipeAnalysisResult, error)
+	// AnalyzeRecipeWebpage analyzes a webpage fetched from the URL. The caller fetches the page
+	// with the SSRF-safe client, which it needs anyway to tell webpages from images and PDFs, so
+	// the providers never make requests to user-provided URLs.
+	AnalyzeRecipeWebpage(ctx context.Context, url string, page []byte) (*RecipeAnalysisResult, error)
+	// AnalyzeRecipeText analyzes plain text of a recipe (e.g. the text layer of a PDF)
+	AnalyzeRecipeText(ctx context.Context, text string) (*RecipeAnalysisResult, error)
+	// SuggestRecipeTags suggests tags and cuisine, course and dietary labels for a recipe,
+	// preferring the tags that are already used in the collection
+	SuggestRecipeTags(ctx context.Context, recipe *models.Recipe, existingTags []string) (*TagSuggestion, error)
+	// TranslateRecipe translates the text of a recipe into the language, keeping the quantities
+	// and units and the order of the ingredients and steps
+	TranslateRecipe(ctx context.Context, recipe *models.Recipe, language string) (*RecipeAnalysisResult, error)
+	// SuggestSubstitutions suggests substitutes for an ingredient of a recipe that meet the
+	// dietary constraints, with the quantities adjusted to the recipe
+	SuggestSubstitutions(ctx context.Context, recipe *models.Recipe, ingredient models.Ingredient, dietary []string) (*SubstitutionResult, error)
+}
+
+// Image is a base64 encoded image to be analyzed
+type Image struct {
+	Base64      string
+	ContentType ImageContentType
 }
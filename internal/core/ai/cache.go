@@ -0,0 +1,214 @@
+package ai
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/fetch"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+)
+
+// Bump to invalidate all cached results, e.g. when the result format changes. Extraction results
+// are also keyed on the prompt version, so new prompt templates do not need a bump.
+const _CacheKeyVersion = "v1"
+
+// Cache stores serialized analysis results by key until the entry expires
+type Cache interface {
+	// GetCacheEntry returns the value for the key, or false if there is no unexpired entry
+	GetCacheEntry(ctx context.Context, key string) ([]byte, bool, error)
+	SetCacheEntry(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+type cacheBypassKey struct{}
+
+// WithoutCache returns a context for which cached results are ignored. Fresh results are still
+// stored, so this also refreshes the cache.
+func WithoutCache(ctx context.Context) context.Context {
+	return context.WithValue(ctx, cacheBypassKey{}, true)
+}
+
+// CacheBypassed reports whether cached results are ignored for the context
+func CacheBypassed(ctx context.Context) bool {
+	bypass, _ := ctx.Value(cacheBypassKey{}).(bool)
+	return bypass
+}
+
+// CachedRecipeAI caches the results of another RecipeAI. The results are keyed on a SHA-256
+// hash of the analyzed content together with the model name (and the prompt version for
+// extractions), so re-importing the same photo, page or document does not call the model again.
+type CachedRecipeAI struct {
+	next          RecipeAI
+	cache         Cache
+	model         string
+	promptVersion string
+	ttl           time.Duration
+}
+
+func NewCachedRecipeAI(next RecipeAI, cache Cache, model string, promptVersion string, ttl time.Duration) RecipeAI {
+	return &CachedRecipeAI{
+		next:          next,
+		cache:         cache,
+		model:         model,
+		promptVersion: promptVersion,
+		ttl:           ttl,
+	}
+}
+
+func (c *CachedRecipeAI) AnalyzeRecipeImage(ctx context.Context, base64Image string, imageContentType ImageContentType) (*RecipeAnalysisResult, error) {
+	key := c.key("image", c.promptVersion, imageHash(base64Image))
+
+	return cached(ctx, c, key, func() (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeImage(ctx, base64Image, imageContentType)
+	})
+}
+
+func (c *CachedRecipeAI) AnalyzeRecipeImages(ctx context.Context, images []Image) (*RecipeAnalysisResult, error) {
+	hashes := make([]string, 0, len(images))
+	for _, image := range images {
+		hashes = append(hashes, imageHash(image.Base64))
+	}
+	key := c.key("images", append([]string{c.promptVersion}, hashes...)...)
+
+	return cached(ctx, c, key, func() (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeImages(ctx, images)
+	})
+}
+
+func (c *CachedRecipeAI) AnalyzeRecipeWebpage(ctx context.Context, url string, page []byte) (*RecipeAnalysisResult, error) {
+	key := c.key("webpage", c.promptVersion, fetch.NormalizeURL(url), hash(page))
+
+	return cached(ctx, c, key, func() (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeWebpage(ctx, url, page)
+	})
+}
+
+func (c *CachedRecipeAI) AnalyzeRecipeText(ctx context.Context, text string) (*RecipeAnalysisResult, error) {
+	key := c.key("text", c.promptVersion, hash([]byte(text)))
+
+	return cached(ctx, c, key, func() (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeText(ctx, text)
+	})
+}
+
+func (c *CachedRecipeAI) SuggestRecipeTags(ctx context.Context, recipe *models.Recipe, existingTags []string) (*TagSuggestion, error) {
+	content, err := recipeHash(recipe)
+	if err != nil {
+		return nil, err
+	}
+
+	// The suggestions also depend on the tags of the collection
+	tags := slices.Clone(existingTags)
+	slices.Sort(tags)
+	key := c.key("tags", content, strings.Join(tags, "\n"))
+
+	return cached(ctx, c, key, func() (*TagSuggestion, error) {
+		return c.next.SuggestRecipeTags(ctx, recipe, existingTags)
+	})
+}
+
+func (c *CachedRecipeAI) TranslateRecipe(ctx context.Context, recipe *models.Recipe, language string) (*RecipeAnalysisResult, error) {
+	content, err := recipeHash(recipe)
+	if err != nil {
+		return nil, err
+	}
+	key := c.key("translate", content, strings.ToLower(strings.TrimSpace(language)))
+
+	return cached(ctx, c, key, func() (*RecipeAnalysisResult, error) {
+		return c.next.TranslateRecipe(ctx, recipe, language)
+	})
+}
+
+func (c *CachedRecipeAI) SuggestSubstitutions(ctx context.Context, recipe *models.Recipe, ingredient models.Ingredient, dietary []string) (*SubstitutionResult, error) {
+	content, err := recipeHash(recipe)
+	if err != nil {
+		return nil, err
+	}
+	constraints := slices.Clone(dietary)
+	slices.Sort(constraints)
+	key := c.key("substitutions", content, ingredientText(ingredient), strings.Join(constraints, ","))
+
+	return cached(ctx, c, key, func() (*SubstitutionResult, error) {
+		return c.next.SuggestSubstitutions(ctx, recipe, ingredient, dietary)
+	})
+}
+
+// cached returns the cached result for the key, or analyzes and caches the result. Cache errors
+// are logged and never fail the analysis.
+func cached[T any](ctx context.Context, c *CachedRecipeAI, key string, analyze func() (*T, error)) (*T, error) {
+	if !CacheBypassed(ctx) {
+		value, found, err := c.cache.GetCacheEntry(ctx, key)
+		if err != nil {
+			slog.Warn("Unable to read AI cache", "error", err.Error())
+		}
+		if found {
+			result := new(T)
+			if err := json.Unmarshal(value, result); err == nil {
+				slog.Info("AI cache hit", "key", key)
+				return result, nil
+			}
+			slog.Warn("Ignoring invalid AI cache entry", "key", key)
+		}
+	}
+
+	result, err := analyze()
+	if err != nil {
+		return nil, err
+	}
+
+	value, err := json.Marshal(result)
+	if err == nil {
+		err = c.cache.SetCacheEntry(ctx, key, value, c.ttl)
+	}
+	if err != nil {
+		slog.Warn("Unable to write AI cache", "error", err.Error())
+	}
+
+	return result, nil
+}
+
+// key builds the cache key from the kind of analysis, the model and the content parts
+func (c *CachedRecipeAI) key(kind string, parts ...string) string {
+	h := sha256.New()
+	for _, part := range append([]string{c.model}, parts...) {
+		h.Write([]byte(part))
+		h.Write([]byte{0}) // separator, so parts cannot run into each other
+	}
+
+	return _CacheKeyVersion + ":" + kind + ":" + hex.EncodeToString(h.Sum(nil))
+}
+
+// imageHash hashes the decoded image bytes, so the key does not depend on the base64 encoding
+func imageHash(base64Image string) string {
+	data, err := base64.StdEncoding.DecodeString(base64Image)
+	if err != nil {
+		data = []byte(base64Image)
+	}
+	return hash(data)
+}
+
+// recipeHash hashes the content of the recipe that is sent to the model
+func recipeHash(recipe *models.Recipe) (string, error) {
+	content, err := json.Marshal(struct {
+		Title       string
+		Description string
+		Ingredients []models.Ingredient
+		Steps       []string
+	}{recipe.Title, recipe.Description, recipe.Ingredients, recipe.Steps})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal recipe: %w", err)
+	}
+	return hash(content), nil
+}
+
+func hash(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
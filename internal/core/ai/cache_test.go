@@ -0,0 +1,246 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// MockRecipeAI is a mock implementation of the RecipeAI interface
+type MockRecipeAI struct {
+	mock.Mock
+}
+
+func (m *MockRecipeAI) AnalyzeRecipeImage(ctx context.Context, base64Image string, imageContentType ImageContentType) (*RecipeAnalysisResult, error) {
+	args := m.Called(ctx, base64Image, imageContentType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*RecipeAnalysisResult), args.Error(1)
+}
+
+func (m *MockRecipeAI) AnalyzeRecipeImages(ctx context.Context, images []Image) (*RecipeAnalysisResult, error) {
+	args := m.Called(ctx, images)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*RecipeAnalysisResult), args.Error(1)
+}
+
+func (m *MockRecipeAI) AnalyzeRecipeWebpage(ctx context.Context, url string, page []byte) (*RecipeAnalysisResult, error) {
+	args := m.Called(ctx, url, page)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*RecipeAnalysisResult), args.Error(1)
+}
+
+func (m *MockRecipeAI) AnalyzeRecipeText(ctx context.Context, text string) (*RecipeAnalysisResult, error) {
+	args := m.Called(ctx, text)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*RecipeAnalysisResult), args.Error(1)
+}
+
+func (m *MockRecipeAI) SuggestRecipeTags(ctx context.Context, recipe *models.Recipe, existingTags []string) (*TagSuggestion, error) {
+	args := m.Called(ctx, recipe, existingTags)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*TagSuggestion), args.Error(1)
+}
+
+func (m *MockRecipeAI) TranslateRecipe(ctx context.Context, recipe *models.Recipe, language string) (*RecipeAnalysisResult, error) {
+	args := m.Called(ctx, recipe, language)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*RecipeAnalysisResult), args.Error(1)
+}
+
+func (m *MockRecipeAI) SuggestSubstitutions(ctx context.Context, recipe *models.Recipe, ingredient models.Ingredient, dietary []string) (*SubstitutionResult, error) {
+	args := m.Called(ctx, recipe, ingredient, dietary)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*SubstitutionResult), args.Error(1)
+}
+
+// memoryCache is an in-memory Cache for tests
+type memoryCache struct {
+	entries map[string][]byte
+	ttls    map[string]time.Duration
+	err     error
+}
+
+func newMemoryCache() *memoryCache {
+	return &memoryCache{entries: map[string][]byte{}, ttls: map[string]time.Duration{}}
+}
+
+func (c *memoryCache) GetCacheEntry(ctx context.Context, key string) ([]byte, bool, error) {
+	if c.err != nil {
+		return nil, false, c.err
+	}
+	value, ok := c.entries[key]
+	return value, ok, nil
+}
+
+func (c *memoryCache) SetCacheEntry(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	if c.err != nil {
+		return c.err
+	}
+	c.entries[key] = value
+	c.ttls[key] = ttl
+	return nil
+}
+
+var testAnalysisResult = &RecipeAnalysisResult{
+	Title:       "Omelett",
+	Ingredients: []models.Ingredient{{Name: "Egg", Quantity: 3, Unit: "st"}},
+	Steps:       []string{"Whisk", "Fry"},
+}
+
+func TestCachedRecipeAI_Image(t *testing.T) {
+	ctx := context.Background()
+	next := new(MockRecipeAI)
+	cache := newMemoryCache()
+	cached := NewCachedRecipeAI(next, cache, "gpt-test", "v1", time.Hour)
+
+	next.On("AnalyzeRecipeImage", ctx, "/9j/4AAQ", ImageContentTypeJPEG).Return(testAnalysisResult, nil).Once()
+
+	// The second call is served from the cache
+	for range 2 {
+		result, err := cached.AnalyzeRecipeImage(ctx, "/9j/4AAQ", ImageContentTypeJPEG)
+		require.NoError(t, err)
+		assert.Equal(t, testAnalysisResult, result)
+	}
+	next.AssertExpectations(t)
+	assert.Len(t, cache.entries, 1)
+	for _, ttl := range cache.ttls {
+		assert.Equal(t, time.Hour, ttl)
+	}
+
+	// Another model does not share the entries
+	other := NewCachedRecipeAI(next, cache, "gpt-other", "v1", time.Hour)
+	next.On("AnalyzeRecipeImage", ctx, "/9j/4AAQ", ImageContentTypeJPEG).Return(testAnalysisResult, nil).Once()
+	_, err := other.AnalyzeRecipeImage(ctx, "/9j/4AAQ", ImageContentTypeJPEG)
+	require.NoError(t, err)
+	next.AssertExpectations(t)
+	assert.Len(t, cache.entries, 2)
+
+	// Neither does another prompt version
+	other = NewCachedRecipeAI(next, cache, "gpt-test", "v2", time.Hour)
+	next.On("AnalyzeRecipeImage", ctx, "/9j/4AAQ", ImageContentTypeJPEG).Return(testAnalysisResult, nil).Once()
+	_, err = other.AnalyzeRecipeImage(ctx, "/9j/4AAQ", ImageContentTypeJPEG)
+	require.NoError(t, err)
+	next.AssertExpectations(t)
+	assert.Len(t, cache.entries, 3)
+}
+
+func TestCachedRecipeAI_Webpage(t *testing.T) {
+	ctx := context.Background()
+	next := new(MockRecipeAI)
+	cache := newMemoryCache()
+	cached := NewCachedRecipeAI(next, cache, "gpt-test", "v1", time.Hour)
+
+	page := []byte("<html><body>Pancakes</body></html>")
+	next.On("AnalyzeRecipeWebpage", ctx, "https://Example.com/pancakes?utm_source=x#top", page).Return(testAnalysisResult, nil).Once()
+
+	_, err := cached.AnalyzeRecipeWebpage(ctx, "https://Example.com/pancakes?utm_source=x#top", page)
+	require.NoError(t, err)
+
+	// Same page behind a trivially different URL
+	_, err = cached.AnalyzeRecipeWebpage(ctx, "https://example.com:443/pancakes", page)
+	require.NoError(t, err)
+	next.AssertExpectations(t)
+
+	// Changed page content is analyzed again
+	changed := []byte("<html><body>Better pancakes</body></html>")
+	next.On("AnalyzeRecipeWebpage", ctx, "https://example.com/pancakes", changed).Return(testAnalysisResult, nil).Once()
+	_, err = cached.AnalyzeRecipeWebpage(ctx, "https://example.com/pancakes", changed)
+	require.NoError(t, err)
+	next.AssertExpectations(t)
+}
+
+func TestCachedRecipeAI_Tags(t *testing.T) {
+	ctx := context.Background()
+	next := new(MockRecipeAI)
+	cache := newMemoryCache()
+	cached := NewCachedRecipeAI(next, cache, "gpt-test", "v1", time.Hour)
+
+	recipe := &models.Recipe{Title: "Omelett", Steps: []string{"Whisk", "Fry"}}
+	suggestion := &TagSuggestion{Tags: []string{"eggs"}, Course: "breakfast"}
+
+	next.On("SuggestRecipeTags", ctx, recipe, []string{"quick", "dinner"}).Return(suggestion, nil).Once()
+
+	_, err := cached.SuggestRecipeTags(ctx, recipe, []string{"quick", "dinner"})
+	require.NoError(t, err)
+
+	// Same recipe and tags in another order
+	result, err := cached.SuggestRecipeTags(ctx, recipe, []string{"dinner", "quick"})
+	require.NoError(t, err)
+	assert.Equal(t, suggestion, result)
+	next.AssertExpectations(t)
+
+	// New tags in the collection give new suggestions
+	next.On("SuggestRecipeTags", ctx, recipe, []string{"quick", "dinner", "eggs"}).Return(suggestion, nil).Once()
+	_, err = cached.SuggestRecipeTags(ctx, recipe, []string{"quick", "dinner", "eggs"})
+	require.NoError(t, err)
+	next.AssertExpectations(t)
+}
+
+func TestCachedRecipeAI_Bypass(t *testing.T) {
+	ctx := WithoutCache(context.Background())
+	next := new(MockRecipeAI)
+	cache := newMemoryCache()
+	cached := NewCachedRecipeAI(next, cache, "gpt-test", "v1", time.Hour)
+
+	next.On("AnalyzeRecipeText", ctx, "3 eggs").Return(testAnalysisResult, nil).Twice()
+
+	for range 2 {
+		_, err := cached.AnalyzeRecipeText(ctx, "3 eggs")
+		require.NoError(t, err)
+	}
+	next.AssertExpectations(t)
+
+	// Bypassed results still refresh the cache
+	assert.Len(t, cache.entries, 1)
+	assert.False(t, CacheBypassed(context.Background()))
+	assert.True(t, CacheBypassed(ctx))
+}
+
+func TestCachedRecipeAI_Errors(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Analysis errors are not cached", func(t *testing.T) {
+		next := new(MockRecipeAI)
+		cache := newMemoryCache()
+		cached := NewCachedRecipeAI(next, cache, "gpt-test", "v1", time.Hour)
+
+		next.On("AnalyzeRecipeImages", ctx, mock.Anything).Return(nil, errors.New("model error")).Once()
+
+		_, err := cached.AnalyzeRecipeImages(ctx, []Image{{Base64: "/9j/4AAQ", ContentType: ImageContentTypeJPEG}})
+		assert.Error(t, err)
+		assert.Empty(t, cache.entries)
+	})
+
+	t.Run("Cache errors do not fail the analysis", func(t *testing.T) {
+		next := new(MockRecipeAI)
+		cache := newMemoryCache()
+		cache.err = errors.New("database down")
+		cached := NewCachedRecipeAI(next, cache, "gpt-test", "v1", time.Hour)
+
+		next.On("AnalyzeRecipeText", ctx, "3 eggs").Return(testAnalysisResult, nil).Once()
+
+		result, err := cached.AnalyzeRecipeText(ctx, "3 eggs")
+		require.NoError(t, err)
+		assert.Equal(t, testAnalysisResult, result)
+	})
+}
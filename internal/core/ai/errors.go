@@ -0,0 +1,13 @@
+package ai
+
+import "errors"
+
+var (
+	ErrRateLimited    = errors.New("AI provider rate limit exceeded")
+	ErrTimeout        = errors.New("AI request timed out")
+	ErrUnavailable    = errors.New("AI provider is unavailable")
+	ErrCircuitOpen    = errors.New("AI provider is failing, not calling it for a while")
+	ErrRefused        = errors.New("AI refused to process the content")
+	ErrEmptyResponse  = errors.New("AI returned an empty response")
+	ErrBudgetExceeded = errors.New("monthly AI budget exceeded")
+)
@@ -0,0 +1,206 @@
+package ai
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+)
+
+// Model and prompt version recorded on the results of the fake provider, which uses neither
+const (
+	FakeModel         = "fake"
+	FakePromptVersion = "fake"
+)
+
+var (
+	// Headings of the ingredient and step sections
+	ingredientHeading = regexp.MustCompile(`(?i)^(ingredients?|ingredienser|zutaten)\b`)
+	stepHeading       = regexp.MustCompile(`(?i)^(instructions?|directions?|method|steps|preparation|gör så här|instruktioner)\b`)
+	// Page headers and footers of documents
+	pageMarker = regexp.MustCompile(`(?i)\bpage \d+\b`)
+	// Numbered steps, e.g. "1. Whisk" or "2) Fry"
+	numberedLine = regexp.MustCompile(`^\d+[.)]\s+(.+)$`)
+	// Leading quantity of an ingredient, e.g. "2", "0.5", "1,5", "1/2" or "1 1/2"
+	quantityPrefix  = regexp.MustCompile(`^(\d+(?:[.,]\d+)?(?:\s+\d+/\d+)?|\d+/\d+)\s+(.+)$`)
+	cookTimePattern = regexp.MustCompile(`(?i)(\d+)\s*(?:min|mins|minutes|minuter)\b`)
+	servingsPattern = regexp.MustCompile(`(?i)(?:serves|servings|portions)\D{0,3}(\d+)|(\d+)\s*(?:servings|portions|portioner)\b`)
+)
+
+// Units that are split from the name of an ingredient
+var fakeUnits = map[string]bool{
+	"g": true, "kg": true, "mg": true, "ml": true, "cl": true, "dl": true, "l": true,
+	"tsp": true, "tbsp": true, "cup": true, "cups": true, "oz": true, "lb": true,
+	"msk": true, "tsk": true, "krm": true, "st": true, "clove": true, "cloves": true,
+	"pinch": true, "can": true, "cans": true,
+}
+
+// FakeRecipeAI is a rule-based stand-in for an AI provider that runs offline, e.g. for local
+// development and for the evaluation harness. It extracts recipes from webpages and documents
+// with simple heuristics, and cannot read images or translate.
+type FakeRecipeAI struct{}
+
+func NewFakeRecipeAI() RecipeAI {
+	return &FakeRecipeAI{}
+}
+
+func (c *FakeRecipeAI) AnalyzeRecipeImage(ctx context.Context, base64Image string, imageContentType ImageContentType) (*RecipeAnalysisResult, error) {
+	return nil, fmt.Errorf("%w: the fake provider cannot read images", ErrRefused)
+}
+
+func (c *FakeRecipeAI) AnalyzeRecipeImages(ctx context.Context, images []Image) (*RecipeAnalysisResult, error) {
+	return nil, fmt.Errorf("%w: the fake provider cannot read images", ErrRefused)
+}
+
+func (c *FakeRecipeAI) AnalyzeRecipeWebpage(ctx context.Context, url string, page []byte) (*RecipeAnalysisResult, error) {
+	webpage, err := extractWebpageText(bytes.NewReader(page), _WebpageTokenBudget)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract webpage content: %w", err)
+	}
+
+	return parseRecipeText(webpage), nil
+}
+
+func (c *FakeRecipeAI) AnalyzeRecipeText(ctx context.Context, text string) (*RecipeAnalysisResult, error) {
+	text = tidyLines(text)
+	if text == "" {
+		return nil, fmt.Errorf("no text to analyze")
+	}
+
+	return parseRecipeText(text), nil
+}
+
+func (c *FakeRecipeAI) SuggestRecipeTags(ctx context.Context, recipe *models.Recipe, existingTags []string) (*TagSuggestion, error) {
+	return &TagSuggestion{Tags: []string{}, Dietary: []string{}}, nil
+}
+
+func (c *FakeRecipeAI) TranslateRecipe(ctx context.Context, recipe *models.Recipe, language string) (*RecipeAnalysisResult, error) {
+	return nil, fmt.Errorf("%w: the fake provider cannot translate", ErrRefused)
+}
+
+func (c *FakeRecipeAI) SuggestSubstitutions(ctx context.Context, recipe *models.Recipe, ingredient models.Ingredient, dietary []string) (*SubstitutionResult, error) {
+	return &SubstitutionResult{Options: []models.SubstitutionOption{}}, nil
+}
+
+// parseRecipeText extracts a recipe from text with Markdown-like headings and lists (see
+// extractWebpageText) or from plain text with the section headings on lines of their own
+func parseRecipeText(text string) *RecipeAnalysisResult {
+	result := &RecipeAnalysisResult{
+		Ingredients:   []models.Ingredient{},
+		Steps:         []string{},
+		Model:         FakeModel,
+		PromptVersion: FakePromptVersion,
+	}
+
+	const (
+		intro = iota
+		ingredients
+		steps
+	)
+	section := intro
+
+	for _, line := range strings.Split(text, "\n") {
+		line = collapseSpaces(line)
+		if line == "" || pageMarker.MatchString(line) {
+			continue
+		}
+
+		heading := strings.HasPrefix(line, "#")
+		line = strings.TrimSpace(strings.TrimLeft(line, "#"))
+
+		switch {
+		case ingredientHeading.MatchString(line):
+			section = ingredients
+			continue
+		case stepHeading.MatchString(line):
+			section = steps
+			continue
+		case result.Title == "" && (heading || section == intro):
+			result.Title = line
+			continue
+		}
+
+		switch section {
+		case intro:
+			if result.Description == "" && !isListItem(line) && len(line) > 20 {
+				result.Description = line
+			}
+		case ingredients:
+			if ingredient, ok := parseIngredient(line); ok {
+				result.Ingredients = append(result.Ingredients, ingredient)
+			}
+		case steps:
+			if match := numberedLine.FindStringSubmatch(line); match != nil {
+				result.Steps = append(result.Steps, match[1])
+				continue
+			}
+			if isListItem(line) {
+				result.Steps = append(result.Steps, strings.TrimSpace(line[2:]))
+				continue
+			}
+		}
+
+		// Times in the steps are not the cook time, so only other lines are searched
+		if match := cookTimePattern.FindStringSubmatch(line); match != nil && result.CookTime == 0 {
+			result.CookTime, _ = strconv.Atoi(match[1])
+		}
+		if match := servingsPattern.FindStringSubmatch(line); match != nil && result.Servings == 0 {
+			result.Servings, _ = strconv.Atoi(match[1] + match[2])
+		}
+	}
+
+	return result
+}
+
+func isListItem(line string) bool {
+	return strings.HasPrefix(line, "- ") || strings.HasPrefix(line, "* ")
+}
+
+// parseIngredient splits an ingredient line, e.g. "- 1 1/2 dl | milk", into its quantity, unit
+// and name
+func parseIngredient(line string) (models.Ingredient, bool) {
+	if isListItem(line) {
+		line = line[2:]
+	}
+	line = collapseSpaces(strings.ReplaceAll(line, "|", " "))
+	if line == "" || strings.HasSuffix(line, ":") {
+		return models.Ingredient{}, false
+	}
+
+	ingredient := models.Ingredient{Name: line}
+
+	if match := quantityPrefix.FindStringSubmatch(line); match != nil {
+		ingredient.Quantity = parseQuantity(match[1])
+		ingredient.Name = match[2]
+	}
+
+	if unit, name, found := strings.Cut(ingredient.Name, " "); found && fakeUnits[strings.ToLower(strings.TrimSuffix(unit, "."))] {
+		ingredient.Unit = unit
+		ingredient.Name = name
+	}
+
+	return ingredient, true
+}
+
+// parseQuantity parses a quantity such as "2", "0.5", "1,5", "1/2" or "1 1/2"
+func parseQuantity(s string) float32 {
+	var quantity float64
+	for _, part := range strings.Fields(strings.ReplaceAll(s, ",", ".")) {
+		if numerator, denominator, ok := strings.Cut(part, "/"); ok {
+			n, errN := strconv.ParseFloat(numerator, 64)
+			d, errD := strconv.ParseFloat(denominator, 64)
+			if errN == nil && errD == nil && d != 0 {
+				quantity += n / d
+			}
+			continue
+		}
+		if value, err := strconv.ParseFloat(part, 64); err == nil {
+			quantity += value
+		}
+	}
+	return float32(quantity)
+}
@@ -0,0 +1,91 @@
+package ai
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFakeRecipeAIWebpage(t *testing.T) {
+	fake := NewFakeRecipeAI()
+
+	page, err := os.ReadFile("testdata/webpages/food_blog.html")
+	require.NoError(t, err)
+
+	result, err := fake.AnalyzeRecipeWebpage(context.Background(), "https://example.com/soup", page)
+	require.NoError(t, err)
+	assert.Equal(t, "Creamy Tomato Soup", result.Title)
+	assert.Equal(t, "A rich and velvety soup made with roasted tomatoes, garlic and a splash of cream.", result.Description)
+	assert.Equal(t, 45, result.CookTime)
+	assert.Equal(t, 4, result.Servings)
+	assert.Equal(t, []models.Ingredient{
+		{Name: "ripe tomatoes", Quantity: 1, Unit: "kg"},
+		{Name: "yellow onion", Quantity: 1},
+		{Name: "garlic", Quantity: 4, Unit: "cloves"},
+		{Name: "olive oil", Quantity: 2, Unit: "tbsp"},
+		{Name: "vegetable stock", Quantity: 500, Unit: "ml"},
+		{Name: "heavy cream", Quantity: 100, Unit: "ml"},
+	}, result.Ingredients)
+	assert.Len(t, result.Steps, 4)
+	assert.Equal(t, "Roast for 30 minutes, until the tomatoes are soft and slightly charred.", result.Steps[1])
+	assert.Equal(t, FakePromptVersion, result.PromptVersion)
+
+	page, err = os.ReadFile("testdata/webpages/swedish_recipe_site.html")
+	require.NoError(t, err)
+
+	result, err = fake.AnalyzeRecipeWebpage(context.Background(), "https://example.se/kladdkaka", page)
+	require.NoError(t, err)
+	assert.Equal(t, "Klassisk kladdkaka", result.Title)
+	assert.Equal(t, 40, result.CookTime)
+	assert.Equal(t, 8, result.Servings)
+	assert.Contains(t, result.Ingredients, models.Ingredient{Name: "vetemjöl", Quantity: 1.5, Unit: "dl"})
+	assert.Contains(t, result.Ingredients, models.Ingredient{Name: "ägg", Quantity: 2})
+	assert.Len(t, result.Steps, 4)
+}
+
+func TestFakeRecipeAIText(t *testing.T) {
+	fake := NewFakeRecipeAI()
+
+	text := "Cookbook, page 3\n\nOmelett\nA quick breakfast for two people.\n\nIngredients\n4 eggs\n4 tbsp milk\nSalt\n\nTopping:\n150 g feta cheese\n\nInstructions\n1. Whisk the eggs and the milk.\n2. Fry for 5 minutes.\n\nTime: 20 min\n"
+
+	result, err := fake.AnalyzeRecipeText(context.Background(), text)
+	require.NoError(t, err)
+	assert.Equal(t, &RecipeAnalysisResult{
+		Title:       "Omelett",
+		Description: "A quick breakfast for two people.",
+		Ingredients: []models.Ingredient{
+			{Name: "eggs", Quantity: 4},
+			{Name: "milk", Quantity: 4, Unit: "tbsp"},
+			{Name: "Salt"},
+			{Name: "feta cheese", Quantity: 150, Unit: "g"},
+		},
+		Steps:         []string{"Whisk the eggs and the milk.", "Fry for 5 minutes."},
+		CookTime:      20,
+		Model:         FakeModel,
+		PromptVersion: FakePromptVersion,
+	}, result)
+
+	_, err = fake.AnalyzeRecipeText(context.Background(), " \n ")
+	assert.Error(t, err)
+
+	_, err = fake.AnalyzeRecipeImage(context.Background(), "/9j/4AAQ", ImageContentTypeJPEG)
+	assert.ErrorIs(t, err, ErrRefused)
+}
+
+func TestParseQuantity(t *testing.T) {
+	tests := map[string]float32{
+		"2":     2,
+		"0.5":   0.5,
+		"1,5":   1.5,
+		"1/2":   0.5,
+		"1 1/2": 1.5,
+		"1/0":   0,
+	}
+	for input, want := range tests {
+		assert.Equal(t, want, parseQuantity(input), input)
+	}
+}
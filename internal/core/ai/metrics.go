@@ -0,0 +1,101 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/metrics"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+)
+
+// InstrumentedRecipeAI records the count, latency, failures and token usage of every call to
+// another RecipeAI in the metrics
+type InstrumentedRecipeAI struct {
+	next RecipeAI
+}
+
+func NewInstrumentedRecipeAI(next RecipeAI) RecipeAI {
+	return &InstrumentedRecipeAI{next: next}
+}
+
+func (c *InstrumentedRecipeAI) AnalyzeRecipeImage(ctx context.Context, base64Image string, imageContentType ImageContentType) (*RecipeAnalysisResult, error) {
+	return instrumented("image", func() (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeImage(ctx, base64Image, imageContentType)
+	})
+}
+
+func (c *InstrumentedRecipeAI) AnalyzeRecipeImages(ctx context.Context, images []Image) (*RecipeAnalysisResult, error) {
+	return instrumented("images", func() (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeImages(ctx, images)
+	})
+}
+
+func (c *InstrumentedRecipeAI) AnalyzeRecipeWebpage(ctx context.Context, url string, page []byte) (*RecipeAnalysisResult, error) {
+	return instrumented("webpage", func() (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeWebpage(ctx, url, page)
+	})
+}
+
+func (c *InstrumentedRecipeAI) AnalyzeRecipeText(ctx context.Context, text string) (*RecipeAnalysisResult, error) {
+	return instrumented("text", func() (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeText(ctx, text)
+	})
+}
+
+func (c *InstrumentedRecipeAI) SuggestRecipeTags(ctx context.Context, recipe *models.Recipe, existingTags []string) (*TagSuggestion, error) {
+	return instrumented("tags", func() (*TagSuggestion, error) {
+		return c.next.SuggestRecipeTags(ctx, recipe, existingTags)
+	})
+}
+
+func (c *InstrumentedRecipeAI) TranslateRecipe(ctx context.Context, recipe *models.Recipe, language string) (*RecipeAnalysisResult, error) {
+	return instrumented("translate", func() (*RecipeAnalysisResult, error) {
+		return c.next.TranslateRecipe(ctx, recipe, language)
+	})
+}
+
+func (c *InstrumentedRecipeAI) SuggestSubstitutions(ctx context.Context, recipe *models.Recipe, ingredient models.Ingredient, dietary []string) (*SubstitutionResult, error) {
+	return instrumented("substitutions", func() (*SubstitutionResult, error) {
+		return c.next.SuggestSubstitutions(ctx, recipe, ingredient, dietary)
+	})
+}
+
+func instrumented[T usageReporter](operation string, analyze func() (T, error)) (T, error) {
+	start := time.Now()
+	result, err := analyze()
+	metrics.AICallDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	metrics.AICalls.WithLabelValues(operation, callResult(err)).Inc()
+	if err != nil {
+		return result, err
+	}
+
+	if usage := result.usage(); usage != nil {
+		metrics.AITokens.WithLabelValues(usage.Model, "prompt").Add(float64(usage.PromptTokens))
+		metrics.AITokens.WithLabelValues(usage.Model, "completion").Add(float64(usage.CompletionTokens))
+	}
+
+	return result, nil
+}
+
+// callResult returns the result label of a call, "success" or the kind of failure
+func callResult(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, ErrRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrTimeout):
+		return "timeout"
+	case errors.Is(err, ErrUnavailable):
+		return "unavailable"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, ErrRefused):
+		return "refused"
+	case errors.Is(err, ErrEmptyResponse):
+		return "empty_response"
+	default:
+		return "error"
+	}
+}
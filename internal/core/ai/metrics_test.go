@@ -0,0 +1,48 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/metrics"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInstrumentedRecipeAI(t *testing.T) {
+	ctx := context.Background()
+	next := new(MockRecipeAI)
+	instrumented := NewInstrumentedRecipeAI(next)
+
+	t.Run("Success", func(t *testing.T) {
+		calls := testutil.ToFloat64(metrics.AICalls.WithLabelValues("text", "success"))
+		prompt := testutil.ToFloat64(metrics.AITokens.WithLabelValues("gpt-metrics", "prompt"))
+		completion := testutil.ToFloat64(metrics.AITokens.WithLabelValues("gpt-metrics", "completion"))
+
+		next.On("AnalyzeRecipeText", ctx, "3 eggs").Return(&RecipeAnalysisResult{
+			Title: "Omelett",
+			Usage: &Usage{Model: "gpt-metrics", PromptTokens: 1000, CompletionTokens: 500},
+		}, nil).Once()
+
+		_, err := instrumented.AnalyzeRecipeText(ctx, "3 eggs")
+		require.NoError(t, err)
+		assert.Equal(t, calls+1, testutil.ToFloat64(metrics.AICalls.WithLabelValues("text", "success")))
+		assert.Equal(t, prompt+1000, testutil.ToFloat64(metrics.AITokens.WithLabelValues("gpt-metrics", "prompt")))
+		assert.Equal(t, completion+500, testutil.ToFloat64(metrics.AITokens.WithLabelValues("gpt-metrics", "completion")))
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		calls := testutil.ToFloat64(metrics.AICalls.WithLabelValues("webpage", "rate_limited"))
+
+		next.On("AnalyzeRecipeWebpage", ctx, "https://example.com", []byte("<html></html>")).
+			Return(nil, fmt.Errorf("%w: slow down", ErrRateLimited)).Once()
+
+		_, err := instrumented.AnalyzeRecipeWebpage(ctx, "https://example.com", []byte("<html></html>"))
+		assert.ErrorIs(t, err, ErrRateLimited)
+		assert.Equal(t, calls+1, testutil.ToFloat64(metrics.AICalls.WithLabelValues("webpage", "rate_limited")))
+	})
+
+	next.AssertExpectations(t)
+}
@@ -12,6 +12,21 @@ type RecipeAnalysisResult struct {
 	Steps       []string            `json:"steps"`
 	CookTime    int                 `json:"cook_time"` // in minutes
 	Servings    int                 `json:"servings"`
+
+	// Model and version of the prompt templates the recipe was extracted with, not part of the
+	// model output
+	Model         string `json:"model,omitempty"`
+	PromptVersion string `json:"prompt_version,omitempty"`
+
+	// Token usage of the AI call, not part of the model output (nil for cached results)
+	Usage *Usage `json:"-"`
+}
+
+// Usage is the token usage of an AI call
+type Usage struct {
+	Model            string
+	PromptTokens     int64
+	CompletionTokens int64
 }
 
 // JSONSchema returns a JSON schema definition for the RecipeAnalysisResult
@@ -49,3 +64,78 @@ func (r *RecipeAnalysisResult) JSONSchema() map[string]any {
 		"additionalProperties": false,
 	}
 }
+
+// TagSuggestion represents the structured output from the AI tag suggestion
+type TagSuggestion struct {
+	Tags    []string `json:"tags"`
+	Cuisine string   `json:"cuisine"`
+	Course  string   `json:"course"`
+	Dietary []string `json:"dietary"`
+
+	// Token usage of the AI call, not part of the model output (nil for cached results)
+	Usage *Usage `json:"-"`
+}
+
+// JSONSchema returns a JSON schema definition for the TagSuggestion
+func (s *TagSuggestion) JSONSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"tags": map[string]any{
+				"type":  "array",
+				"items": map[string]string{"type": "string"},
+			},
+			"cuisine": map[string]string{"type": "string"},
+			"course":  map[string]string{"type": "string"},
+			"dietary": map[string]any{
+				"type":  "array",
+				"items": map[string]string{"type": "string"},
+			},
+		},
+		"required":             []string{"tags", "cuisine", "course", "dietary"},
+		"additionalProperties": false,
+	}
+}
+
+// SubstitutionResult represents the structured output from the AI substitution suggestion
+type SubstitutionResult struct {
+	Options []models.SubstitutionOption `json:"options"`
+
+	// Token usage of the AI call, not part of the model output (nil for cached results)
+	Usage *Usage `json:"-"`
+}
+
+// JSONSchema returns a JSON schema definition for the SubstitutionResult
+func (r *SubstitutionResult) JSONSchema() map[string]any {
+	return map[string]any{
+		"type": "object",
+		"properties": map[string]any{
+			"options": map[string]any{
+				"type": "array",
+				"items": map[string]any{
+					"type": "object",
+					"properties": map[string]any{
+						"ingredients": map[string]any{
+							"type": "array",
+							"items": map[string]any{
+								"type": "object",
+								"properties": map[string]any{
+									"name":     map[string]string{"type": "string"},
+									"quantity": map[string]string{"type": "number"},
+									"unit":     map[string]string{"type": "string"},
+								},
+								"required":             []string{"name", "quantity", "unit"},
+								"additionalProperties": false,
+							},
+						},
+						"notes": map[string]string{"type": "string"},
+					},
+					"required":             []string{"ingredients", "notes"},
+					"additionalProperties": false,
+				},
+			},
+		},
+		"required":             []string{"options"},
+		"additionalProperties": false,
+	}
+}
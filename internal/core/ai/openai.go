@@ -1,10 +1,18 @@
 package ai
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
 	"github.com/openai/openai-go"
 	"github.com/openai/openai-go/option"
 )
@@ -16,123 +24,210 @@ const (
 	ImageContentTypePNG  ImageContentType = "image/png"
 )
 
-const (
-	_PromptRules = `
-	1. Do NOT translate any content.
-	2. Do NOT change the text or the order of the text content (e.g. ingredients, steps, etc.).
-	3. If you cannot find the information, leave the JSON field empty. I.e., if an ingredient is missing quantity or unit, set those to default values (0 or "").
-	4. Do NOT make up any information.`
-)
-
 type OpenAI struct {
-	client openai.Client
-	model  string
+	client  openai.Client
+	model   string
+	prompts *Prompts
+}
+
+// OpenAIConfig configures the OpenAI client
+type OpenAIConfig struct {
+	APIKey string
+	Model  string
+	// Base URL of an OpenAI-compatible API, e.g. a local model server (empty for OpenAI)
+	BaseURL string
+	// Prompt templates (nil for the default built-in templates)
+	Prompts *Prompts
 }
 
-func NewOpenAI(apiKey string, model string) RecipeAI {
-	client := openai.NewClient(
-		option.WithAPIKey(apiKey),
-	)
+func NewOpenAI(config OpenAIConfig) RecipeAI {
+	options := []option.RequestOption{
+		option.WithAPIKey(config.APIKey),
+		// Retries are handled by ResilientRecipeAI
+		option.WithMaxRetries(0),
+	}
+	if config.BaseURL != "" {
+		options = append(options, option.WithBaseURL(config.BaseURL))
+	}
+
+	prompts := config.Prompts
+	if prompts == nil {
+		prompts = DefaultPrompts()
+	}
 
 	return &OpenAI{
-		client: client,
-		model:  model,
+		client:  openai.NewClient(options...),
+		model:   config.Model,
+		prompts: prompts,
 	}
 }
 
 func (c *OpenAI) AnalyzeRecipeImage(ctx context.Context, base64Image string, imageContentType ImageContentType) (*RecipeAnalysisResult, error) {
-	result := &RecipeAnalysisResult{}
+	// Create the prompt
+	prompt, err := c.prompts.render(_ImagePrompt, promptData{})
+	if err != nil {
+		return nil, err
+	}
 
-	// Create the data URI for the image
-	dataURI := fmt.Sprintf("data:%s;base64,%s", imageContentType, base64Image)
+	parts := []openai.ChatCompletionContentPartUnionParam{
+		imageContentPart(Image{Base64: base64Image, ContentType: imageContentType}),
+		openai.TextContentPart(prompt),
+	}
+
+	return c.extract(ctx, openai.UserMessage(parts))
+}
+
+func (c *OpenAI) AnalyzeRecipeImages(ctx context.Context, images []Image) (*RecipeAnalysisResult, error) {
+	if len(images) == 0 {
+		return nil, fmt.Errorf("no images to analyze")
+	}
 
 	// Create the prompt
-	prompt := fmt.Sprintf("Analyze the attached image of a recipe and extract the data. You must follow the rules below.\n\nOutput rules:\n%s",
-		_PromptRules)
+	prompt, err := c.prompts.render(_ImagesPrompt, promptData{ImageCount: len(images)})
+	if err != nil {
+		return nil, err
+	}
 
-	// Create the request body
-	params := openai.ChatCompletionNewParams{
-		Messages: []openai.ChatCompletionMessageParamUnion{
-			{
-				OfUser: &openai.ChatCompletionUserMessageParam{
-					Content: openai.ChatCompletionUserMessageParamContentUnion{
-						OfArrayOfContentParts: []openai.ChatCompletionContentPartUnionParam{
-							{
-								OfImageURL: &openai.ChatCompletionContentPartImageParam{
-									ImageURL: openai.ChatCompletionContentPartImageImageURLParam{
-										URL: dataURI,
-									},
-								},
-							},
-							{
-								OfText: &openai.ChatCompletionContentPartTextParam{
-									Text: prompt,
-								},
-							},
-						},
-					},
-				},
-			},
-		},
-		Model:               c.model,
-		MaxCompletionTokens: openai.Int(3000),
-		Temperature:         openai.Float(0),
-		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
-			OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
-				JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
-					Name:        "recipe",
-					Strict:      openai.Opt(true),
-					Description: openai.Opt("A JSON object representing a recipe"),
-					Schema:      result.JSONSchema(),
-				},
-			},
-		},
+	parts := make([]openai.ChatCompletionContentPartUnionParam, 0, len(images)+1)
+	for _, image := range images {
+		parts = append(parts, imageContentPart(image))
 	}
+	parts = append(parts, openai.TextContentPart(prompt))
 
-	// Call the API
-	chatCompletion, err := c.client.Chat.Completions.New(ctx, params)
+	return c.extract(ctx, openai.UserMessage(parts))
+}
+
+func (c *OpenAI) AnalyzeRecipeWebpage(ctx context.Context, url string, page []byte) (*RecipeAnalysisResult, error) {
+	// Extract the main content of the webpage
+	webpage, err := extractWebpageText(bytes.NewReader(page), _WebpageTokenBudget)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract webpage content: %w", err)
+	}
+
+	// Create the prompt
+	prompt, err := c.prompts.render(_WebpagePrompt, promptData{Content: webpage})
 	if err != nil {
 		return nil, err
 	}
 
-	// Unmarshal the JSON response into the provided struct
-	content := chatCompletion.Choices[0].Message.Content
-	if err := json.Unmarshal([]byte(content), result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	return c.extract(ctx, openai.UserMessage(prompt))
+}
+
+func (c *OpenAI) AnalyzeRecipeText(ctx context.Context, text string) (*RecipeAnalysisResult, error) {
+	text = truncateToTokens(tidyLines(text), _TextTokenBudget)
+	if text == "" {
+		return nil, fmt.Errorf("no text to analyze")
 	}
 
+	// Create the prompt
+	prompt, err := c.prompts.render(_TextPrompt, promptData{Content: text})
+	if err != nil {
+		return nil, err
+	}
+
+	return c.extract(ctx, openai.UserMessage(prompt))
+}
+
+func (c *OpenAI) SuggestRecipeTags(ctx context.Context, recipe *models.Recipe, existingTags []string) (*TagSuggestion, error) {
+	existing := "(none yet)"
+	if len(existingTags) > 0 {
+		existing = strings.Join(existingTags, ", ")
+	}
+
+	// Create the prompt
+	prompt, err := c.prompts.render(_TagsPrompt, promptData{Recipe: recipeText(recipe), ExistingTags: existing})
+	if err != nil {
+		return nil, err
+	}
+
+	suggestion := &TagSuggestion{}
+
+	usage, err := c.complete(ctx, openai.UserMessage(prompt), "recipe_tags", "Tags and labels for a recipe", suggestion.JSONSchema(), 500, suggestion)
+	if err != nil {
+		return nil, err
+	}
+	suggestion.Usage = usage
+
+	return suggestion, nil
+}
+
+func (c *OpenAI) TranslateRecipe(ctx context.Context, recipe *models.Recipe, language string) (*RecipeAnalysisResult, error) {
+	// Create the prompt
+	prompt, err := c.prompts.render(_TranslatePrompt, promptData{Recipe: recipeText(recipe), Language: language})
+	if err != nil {
+		return nil, err
+	}
+
+	return c.analyze(ctx, openai.UserMessage(prompt))
+}
+
+func (c *OpenAI) SuggestSubstitutions(ctx context.Context, recipe *models.Recipe, ingredient models.Ingredient, dietary []string) (*SubstitutionResult, error) {
+	constraints := "none"
+	if len(dietary) > 0 {
+		constraints = strings.Join(dietary, ", ")
+	}
+
+	// Create the prompt
+	prompt, err := c.prompts.render(_SubstitutionsPrompt, promptData{Recipe: recipeText(recipe), Ingredient: ingredientText(ingredient), Dietary: constraints})
+	if err != nil {
+		return nil, err
+	}
+
+	result := &SubstitutionResult{}
+
+	usage, err := c.complete(ctx, openai.UserMessage(prompt), "substitutions", "Substitution options for an ingredient", result.JSONSchema(), 1000, result)
+	if err != nil {
+		return nil, err
+	}
+	result.Usage = usage
+
 	return result, nil
 }
 
-func (c *OpenAI) AnalyzeRecipeWebpage(ctx context.Context, url string) (*RecipeAnalysisResult, error) {
+// extract analyzes a message built from the prompt templates and records the model and the
+// version of the templates on the result
+func (c *OpenAI) extract(ctx context.Context, message openai.ChatCompletionMessageParamUnion) (*RecipeAnalysisResult, error) {
+	result, err := c.analyze(ctx, message)
+	if err != nil {
+		return nil, err
+	}
+	result.Model = c.model
+	result.PromptVersion = c.prompts.Version()
+
+	return result, nil
+}
+
+// analyze sends the message to the model and parses the structured recipe in the response
+func (c *OpenAI) analyze(ctx context.Context, message openai.ChatCompletionMessageParamUnion) (*RecipeAnalysisResult, error) {
 	result := &RecipeAnalysisResult{}
 
-	// Fetch the webpage
-	webpage, err := fetchWebpageBody(url)
+	usage, err := c.complete(ctx, message, "recipe", "A JSON object representing a recipe", result.JSONSchema(), 3000, result)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch webpage: %w", err)
+		return nil, err
 	}
+	result.Usage = usage
 
-	// Create the prompt
-	prompt := fmt.Sprintf("Analyze the webpage including a recipe and extract the data. You must follow the rules below.\n\nOutput rules:\n%s\n\nWebpage:\n%s",
-		_PromptRules,
-		webpage)
+	return result, nil
+}
 
+// complete sends the message to the model and unmarshals the structured response, which must
+// follow the JSON schema, into result
+func (c *OpenAI) complete(ctx context.Context, message openai.ChatCompletionMessageParamUnion, name string, description string, schema map[string]any, maxTokens int64, result any) (*Usage, error) {
 	// Create the request body
 	params := openai.ChatCompletionNewParams{
 		Messages: []openai.ChatCompletionMessageParamUnion{
-			openai.UserMessage(prompt),
+			message,
 		},
 		Model:               c.model,
-		MaxCompletionTokens: openai.Int(3000),
+		MaxCompletionTokens: openai.Int(maxTokens),
 		Temperature:         openai.Float(0),
 		ResponseFormat: openai.ChatCompletionNewParamsResponseFormatUnion{
 			OfJSONSchema: &openai.ResponseFormatJSONSchemaParam{
 				JSONSchema: openai.ResponseFormatJSONSchemaJSONSchemaParam{
-					Name:        "recipe",
+					Name:        name,
 					Strict:      openai.Opt(true),
-					Description: openai.Opt("A JSON object representing a recipe"),
-					Schema:      result.JSONSchema(),
+					Description: openai.Opt(description),
+					Schema:      schema,
 				},
 			},
 		},
@@ -141,14 +236,132 @@ func (c *OpenAI) AnalyzeRecipeWebpage(ctx context.Context, url string) (*RecipeA
 	// Call the API
 	chatCompletion, err := c.client.Chat.Completions.New(ctx, params)
 	if err != nil {
-		return nil, err
+		return nil, classifyOpenAIError(err)
+	}
+
+	// The provider charges for the tokens also when the response cannot be used
+	usage := &Usage{
+		Model:            chatCompletion.Model,
+		PromptTokens:     chatCompletion.Usage.PromptTokens,
+		CompletionTokens: chatCompletion.Usage.CompletionTokens,
+	}
+
+	if len(chatCompletion.Choices) == 0 {
+		return nil, &usageError{err: fmt.Errorf("%w: no choices in response", ErrEmptyResponse), usage: usage}
+	}
+	choice := chatCompletion.Choices[0]
+
+	switch {
+	case choice.Message.Refusal != "":
+		return nil, &usageError{err: fmt.Errorf("%w: %s", ErrRefused, choice.Message.Refusal), usage: usage}
+	case choice.FinishReason == "content_filter":
+		return nil, &usageError{err: fmt.Errorf("%w: response was filtered", ErrRefused), usage: usage}
+	case choice.FinishReason == "length":
+		return nil, &usageError{err: fmt.Errorf("%w: response was cut off at the token limit", ErrEmptyResponse), usage: usage}
+	case strings.TrimSpace(choice.Message.Content) == "":
+		return nil, &usageError{err: fmt.Errorf("%w: no content in response", ErrEmptyResponse), usage: usage}
 	}
 
 	// Unmarshal the JSON response into the provided struct
-	content := chatCompletion.Choices[0].Message.Content
-	if err := json.Unmarshal([]byte(content), result); err != nil {
-		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	if err := json.Unmarshal([]byte(choice.Message.Content), result); err != nil {
+		return nil, &usageError{err: fmt.Errorf("failed to unmarshal response: %w", err), usage: usage}
 	}
 
-	return result, nil
+	return usage, nil
+}
+
+// classifyOpenAIError wraps errors from the OpenAI client with the matching AI error
+func classifyOpenAIError(err error) error {
+	var apiErr *openai.Error
+	if errors.As(err, &apiErr) {
+		switch {
+		case apiErr.StatusCode == http.StatusTooManyRequests && apiErr.Code == "insufficient_quota":
+			// Out of credits, retrying does not help
+			return fmt.Errorf("%w: %w", ErrRefused, err)
+		case apiErr.StatusCode == http.StatusTooManyRequests:
+			return &retryAfterError{err: fmt.Errorf("%w: %w", ErrRateLimited, err), after: parseRetryAfter(apiErr.Response)}
+		case apiErr.StatusCode == http.StatusRequestTimeout:
+			return fmt.Errorf("%w: %w", ErrTimeout, err)
+		case apiErr.StatusCode >= 500:
+			return fmt.Errorf("%w: %w", ErrUnavailable, err)
+		}
+		return err
+	}
+
+	if errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %w", ErrTimeout, err)
+	}
+	if errors.Is(err, context.Canceled) {
+		return err
+	}
+
+	// Connection errors (DNS, refused, reset)
+	var netErr net.Error
+	var opErr *net.OpError
+	if errors.As(err, &netErr) || errors.As(err, &opErr) {
+		if netErr != nil && netErr.Timeout() {
+			return fmt.Errorf("%w: %w", ErrTimeout, err)
+		}
+		return fmt.Errorf("%w: %w", ErrUnavailable, err)
+	}
+
+	return err
+}
+
+// parseRetryAfter reads the delay the provider asks for before retrying
+func parseRetryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	if ms, err := strconv.Atoi(resp.Header.Get("Retry-After-Ms")); err == nil && ms > 0 {
+		return time.Duration(ms) * time.Millisecond
+	}
+	if s, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && s > 0 {
+		return time.Duration(s) * time.Second
+	}
+	return 0
+}
+
+func imageContentPart(image Image) openai.ChatCompletionContentPartUnionParam {
+	// Create the data URI for the image
+	dataURI := fmt.Sprintf("data:%s;base64,%s", image.ContentType, image.Base64)
+
+	return openai.ImageContentPart(openai.ChatCompletionContentPartImageImageURLParam{
+		URL: dataURI,
+	})
+}
+
+// recipeText renders the recipe as plain text for a prompt
+func recipeText(recipe *models.Recipe) string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "Title: %s\n", recipe.Title)
+	if recipe.Description != "" {
+		fmt.Fprintf(&b, "Description: %s\n", recipe.Description)
+	}
+
+	b.WriteString("Ingredients:\n")
+	for _, ingredient := range recipe.Ingredients {
+		fmt.Fprintf(&b, "- %s\n", ingredientText(ingredient))
+	}
+
+	b.WriteString("Steps:\n")
+	for i, step := range recipe.Steps {
+		fmt.Fprintf(&b, "%d. %s\n", i+1, step)
+	}
+
+	return b.String()
+}
+
+// ingredientText renders the ingredient as plain text, e.g. "2.5 dl milk"
+func ingredientText(ingredient models.Ingredient) string {
+	var b strings.Builder
+	if ingredient.Quantity > 0 {
+		fmt.Fprintf(&b, "%s ", strconv.FormatFloat(float64(ingredient.Quantity), 'f', -1, 32))
+	}
+	if ingredient.Unit != "" {
+		fmt.Fprintf(&b, "%s ", ingredient.Unit)
+	}
+	b.WriteString(ingredient.Name)
+	return b.String()
 }
@@ -3,10 +3,18 @@ package ai
 import (
 	"context"
 	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"testing"
 	"time"
 
+	"github.com/AntonLuning/RecipeBank/internal/core/fetch"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/openai/openai-go"
+	"github.com/openai/openai-go/option"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -47,7 +55,7 @@ func TestNewOpenAIClient(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			client := NewOpenAI(tt.apiKey, tt.model)
+			client := NewOpenAI(OpenAIConfig{APIKey: tt.apiKey, Model: tt.model})
 			assert.NotNil(t, client)
 		})
 	}
@@ -55,7 +63,7 @@ func TestNewOpenAIClient(t *testing.T) {
 
 func TestAnalyzeImage(t *testing.T) {
 	apiKey := getAPIKey(t)
-	client := NewOpenAI(apiKey, OpenAIModel)
+	client := NewOpenAI(OpenAIConfig{APIKey: apiKey, Model: OpenAIModel})
 
 	// Get image path from environment variable or use default test image
 	imagePath := os.Getenv("TEST_IMAGE_PATH")
@@ -85,18 +93,22 @@ func TestAnalyzeImage(t *testing.T) {
 
 func TestAnalyzeURL(t *testing.T) {
 	apiKey := getAPIKey(t)
-	client := NewOpenAI(apiKey, OpenAIModel)
+	client := NewOpenAI(OpenAIConfig{APIKey: apiKey, Model: OpenAIModel})
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	result, err := client.AnalyzeRecipeWebpage(ctx, "https://www.ica.se/recept/klassisk-lasagne-679675/")
+	url := "https://www.ica.se/recept/klassisk-lasagne-679675/"
+	page, err := fetch.NewClient(fetch.DefaultConfig()).Get(ctx, url)
+	require.NoError(t, err)
+
+	result, err := client.AnalyzeRecipeWebpage(ctx, url, page.Body)
 	require.NoError(t, err)
 	assert.NotEmpty(t, result)
 }
 
 func TestAnalyzeImage_InvalidAPIKey(t *testing.T) {
-	client := NewOpenAI("invalid-api-key", OpenAIModel)
+	client := NewOpenAI(OpenAIConfig{APIKey: "invalid-api-key", Model: OpenAIModel})
 
 	imageData := []byte("fake-image-data")
 	base64Image := base64.StdEncoding.EncodeToString(imageData)
@@ -107,3 +119,258 @@ func TestAnalyzeImage_InvalidAPIKey(t *testing.T) {
 	_, err := client.AnalyzeRecipeImage(ctx, base64Image, ImageContentTypeJPEG)
 	assert.Error(t, err)
 }
+
+// newTestOpenAI creates an OpenAI client that talks to a fake API server
+func newTestOpenAI(t *testing.T, handler http.HandlerFunc) *OpenAI {
+	t.Helper()
+
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	return &OpenAI{
+		client: openai.NewClient(
+			option.WithAPIKey("test-api-key"),
+			option.WithBaseURL(server.URL),
+			option.WithMaxRetries(0),
+		),
+		model:   "test-model",
+		prompts: DefaultPrompts(),
+	}
+}
+
+func chatCompletionResponse(finishReason string, content string, refusal string) string {
+	message, _ := json.Marshal(map[string]any{
+		"role":    "assistant",
+		"content": content,
+		"refusal": refusal,
+	})
+	return fmt.Sprintf(`{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"test-model","choices":[{"index":0,"finish_reason":%q,"message":%s}],"usage":{"prompt_tokens":100,"completion_tokens":20,"total_tokens":120}}`,
+		finishReason, message)
+}
+
+func TestOpenAIAnalyzeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		status  int
+		headers map[string]string
+		body    string
+		wantErr error
+		charged bool
+	}{
+		{
+			name:    "no choices",
+			status:  http.StatusOK,
+			body:    `{"id":"chatcmpl-1","object":"chat.completion","created":1,"model":"test-model","choices":[]}`,
+			wantErr: ErrEmptyResponse,
+			charged: true,
+		},
+		{
+			name:    "empty content",
+			status:  http.StatusOK,
+			body:    chatCompletionResponse("stop", "", ""),
+			wantErr: ErrEmptyResponse,
+			charged: true,
+		},
+		{
+			name:    "cut off",
+			status:  http.StatusOK,
+			body:    chatCompletionResponse("length", `{"title":"Pan`, ""),
+			wantErr: ErrEmptyResponse,
+			charged: true,
+		},
+		{
+			name:    "refusal",
+			status:  http.StatusOK,
+			body:    chatCompletionResponse("stop", "", "I can't help with that."),
+			wantErr: ErrRefused,
+			charged: true,
+		},
+		{
+			name:    "content filter",
+			status:  http.StatusOK,
+			body:    chatCompletionResponse("content_filter", "", ""),
+			wantErr: ErrRefused,
+			charged: true,
+		},
+		{
+			name:    "rate limited",
+			status:  http.StatusTooManyRequests,
+			body:    `{"error":{"message":"Rate limit reached","type":"requests","code":"rate_limit_exceeded"}}`,
+			wantErr: ErrRateLimited,
+		},
+		{
+			name:    "out of quota",
+			status:  http.StatusTooManyRequests,
+			body:    `{"error":{"message":"You exceeded your current quota","type":"insufficient_quota","code":"insufficient_quota"}}`,
+			wantErr: ErrRefused,
+		},
+		{
+			name:    "server error",
+			status:  http.StatusServiceUnavailable,
+			body:    `{"error":{"message":"The server is overloaded","type":"server_error"}}`,
+			wantErr: ErrUnavailable,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.WriteHeader(tt.status)
+				w.Write([]byte(tt.body))
+			})
+
+			_, err := client.AnalyzeRecipeText(context.Background(), "3 eggs, whisk and fry")
+			assert.ErrorIs(t, err, tt.wantErr)
+			if tt.charged {
+				require.NotNil(t, errorUsage(err))
+				assert.Equal(t, "test-model", errorUsage(err).Model)
+			} else {
+				assert.Nil(t, errorUsage(err))
+			}
+		})
+	}
+}
+
+func TestOpenAIAnalyze(t *testing.T) {
+	client := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(chatCompletionResponse("stop", `{"title":"Omelett","description":"","ingredients":[{"name":"Egg","quantity":3,"unit":""}],"steps":["Whisk","Fry"],"cook_time":10,"servings":1}`, "")))
+	})
+
+	result, err := client.AnalyzeRecipeText(context.Background(), "3 eggs, whisk and fry")
+	require.NoError(t, err)
+	assert.Equal(t, "Omelett", result.Title)
+	assert.Len(t, result.Steps, 2)
+	assert.Equal(t, "test-model", result.Model)
+	assert.Equal(t, DefaultPromptVersion, result.PromptVersion)
+}
+
+func TestOpenAIAnalyzeWebpage(t *testing.T) {
+	// The page is given by the caller, the provider never fetches the URL itself
+	page := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request of the page: %s", r.URL)
+	}))
+	t.Cleanup(page.Close)
+
+	var prompt string
+	client := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		prompt = body.Messages[0].Content
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(chatCompletionResponse("stop", `{"title":"Omelett","description":"","ingredients":[{"name":"Egg","quantity":3,"unit":""}],"steps":["Whisk","Fry"],"cook_time":10,"servings":1}`, "")))
+	})
+
+	result, err := client.AnalyzeRecipeWebpage(context.Background(), page.URL, []byte("<html><body><h1>Omelett</h1><p>Whisk 3 eggs and fry them.</p></body></html>"))
+	require.NoError(t, err)
+	assert.Equal(t, "Omelett", result.Title)
+	assert.Contains(t, prompt, "Whisk 3 eggs and fry them.")
+}
+
+func TestOpenAISuggestRecipeTags(t *testing.T) {
+	var prompt string
+	client := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		prompt = body.Messages[0].Content
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(chatCompletionResponse("stop", `{"tags":["eggs","quick"],"cuisine":"french","course":"breakfast","dietary":["vegetarian"]}`, "")))
+	})
+
+	recipe := &models.Recipe{
+		Title:       "Omelett",
+		Ingredients: []models.Ingredient{{Name: "Egg", Quantity: 3}, {Name: "Butter", Quantity: 0.5, Unit: "tbsp"}},
+		Steps:       []string{"Whisk", "Fry"},
+	}
+
+	suggestion, err := client.SuggestRecipeTags(context.Background(), recipe, []string{"quick", "dinner"})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"eggs", "quick"}, suggestion.Tags)
+	assert.Equal(t, "french", suggestion.Cuisine)
+	assert.Equal(t, "breakfast", suggestion.Course)
+	assert.Equal(t, []string{"vegetarian"}, suggestion.Dietary)
+
+	assert.Contains(t, prompt, "quick, dinner")
+	assert.Contains(t, prompt, "- 3 Egg\n- 0.5 tbsp Butter\n")
+	assert.Contains(t, prompt, "2. Fry")
+}
+
+func TestOpenAITranslateRecipe(t *testing.T) {
+	var prompt string
+	client := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		prompt = body.Messages[0].Content
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(chatCompletionResponse("stop", `{"title":"Omelette","description":"","ingredients":[{"name":"Eggs","quantity":3,"unit":""}],"steps":["Whisk","Fry"],"cook_time":10,"servings":1}`, "")))
+	})
+
+	recipe := &models.Recipe{
+		Title:       "Omelett",
+		Ingredients: []models.Ingredient{{Name: "Ägg", Quantity: 3}},
+		Steps:       []string{"Vispa", "Stek"},
+	}
+
+	result, err := client.TranslateRecipe(context.Background(), recipe, "english")
+	require.NoError(t, err)
+	assert.Equal(t, "Omelette", result.Title)
+
+	assert.Contains(t, prompt, "into english")
+	assert.Contains(t, prompt, "- 3 Ägg\n")
+	assert.Contains(t, prompt, "1. Vispa")
+}
+
+func TestOpenAISuggestSubstitutions(t *testing.T) {
+	var prompt string
+	client := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		prompt = body.Messages[0].Content
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(chatCompletionResponse("stop", `{"options":[{"ingredients":[{"name":"ground flaxseed","quantity":3,"unit":"tbsp"},{"name":"water","quantity":9,"unit":"tbsp"}],"notes":"Let it thicken"}]}`, "")))
+	})
+
+	recipe := &models.Recipe{
+		Title:       "Pancakes",
+		Ingredients: []models.Ingredient{{Name: "Eggs", Quantity: 3}, {Name: "Milk", Quantity: 6, Unit: "dl"}},
+		Steps:       []string{"Whisk", "Fry"},
+	}
+
+	result, err := client.SuggestSubstitutions(context.Background(), recipe, recipe.Ingredients[0], []string{"vegan", "nut-free"})
+	require.NoError(t, err)
+	require.Len(t, result.Options, 1)
+	assert.Len(t, result.Options[0].Ingredients, 2)
+	assert.Equal(t, "Let it thicken", result.Options[0].Notes)
+	assert.NotNil(t, result.Usage)
+
+	assert.Contains(t, prompt, `ingredient "3 Eggs"`)
+	assert.Contains(t, prompt, "Dietary constraints: vegan, nut-free")
+	assert.Contains(t, prompt, "- 6 dl Milk")
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	assert.Equal(t, time.Duration(0), parseRetryAfter(nil))
+	assert.Equal(t, 2*time.Second, parseRetryAfter(&http.Response{Header: http.Header{"Retry-After": []string{"2"}}}))
+	assert.Equal(t, 1500*time.Millisecond, parseRetryAfter(&http.Response{Header: http.Header{"Retry-After-Ms": []string{"1500"}, "Retry-After": []string{"2"}}}))
+}
@@ -0,0 +1,118 @@
+package ai
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+	"text/template"
+)
+
+// Version of the built-in prompt templates
+const DefaultPromptVersion = "v1"
+
+// Templates that every prompt version must define
+const (
+	_ImagePrompt         = "image.tmpl"
+	_ImagesPrompt        = "images.tmpl"
+	_WebpagePrompt       = "webpage.tmpl"
+	_TextPrompt          = "text.tmpl"
+	_TagsPrompt          = "tags.tmpl"
+	_TranslatePrompt     = "translate.tmpl"
+	_SubstitutionsPrompt = "substitutions.tmpl"
+)
+
+var requiredPrompts = []string{_ImagePrompt, _ImagesPrompt, _WebpagePrompt, _TextPrompt, _TagsPrompt, _TranslatePrompt, _SubstitutionsPrompt}
+
+// Built-in prompt templates, one directory per version
+//
+//go:embed prompts
+var builtinPrompts embed.FS
+
+// Prompts are the text/template templates of the AI prompts. Every version is a directory with
+// one template file per prompt: the recipe extraction prompts (image.tmpl, images.tmpl,
+// webpage.tmpl and text.tmpl), tags.tmpl, translate.tmpl and substitutions.tmpl; other .tmpl
+// files in the directory can define shared templates, e.g. the rules.
+type Prompts struct {
+	version   string
+	templates *template.Template
+}
+
+// promptData holds the variables of the prompt templates
+type promptData struct {
+	// Number of attached images (images.tmpl)
+	ImageCount int
+	// Extracted webpage or document text (webpage.tmpl and text.tmpl)
+	Content string
+	// Text of the recipe (tags.tmpl, translate.tmpl and substitutions.tmpl)
+	Recipe string
+	// Tags already used in the collection, comma separated (tags.tmpl)
+	ExistingTags string
+	// Language to translate into (translate.tmpl)
+	Language string
+	// Ingredient to substitute and the dietary constraints, comma separated (substitutions.tmpl)
+	Ingredient string
+	Dietary    string
+}
+
+// LoadPrompts loads a version of the prompt templates from the directory, which holds one
+// directory per version. An empty directory loads the built-in templates.
+func LoadPrompts(dir string, version string) (*Prompts, error) {
+	if dir == "" {
+		sub, err := fs.Sub(builtinPrompts, "prompts")
+		if err != nil {
+			return nil, err
+		}
+		return parsePrompts(sub, version)
+	}
+
+	return parsePrompts(os.DirFS(dir), version)
+}
+
+// DefaultPrompts returns the default version of the built-in prompt templates
+func DefaultPrompts() *Prompts {
+	prompts, err := LoadPrompts("", DefaultPromptVersion)
+	if err != nil {
+		panic(fmt.Sprintf("invalid built-in prompts: %s", err.Error()))
+	}
+	return prompts
+}
+
+func parsePrompts(fsys fs.FS, version string) (*Prompts, error) {
+	if version == "" || !fs.ValidPath(version) || strings.Contains(version, "/") {
+		return nil, fmt.Errorf("invalid prompt version %q", version)
+	}
+
+	templates, err := template.New(version).Option("missingkey=error").ParseFS(fsys, path.Join(version, "*.tmpl"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse prompt templates of version %s: %w", version, err)
+	}
+
+	for _, name := range requiredPrompts {
+		if templates.Lookup(name) == nil {
+			return nil, fmt.Errorf("prompt template %s is missing in version %s", name, version)
+		}
+	}
+
+	return &Prompts{
+		version:   version,
+		templates: templates,
+	}, nil
+}
+
+// Version returns the version of the prompt templates, which is recorded on imported recipes
+func (p *Prompts) Version() string {
+	return p.version
+}
+
+// render executes the prompt template with the data
+func (p *Prompts) render(name string, data promptData) (string, error) {
+	var b bytes.Buffer
+	if err := p.templates.ExecuteTemplate(&b, name, data); err != nil {
+		return "", fmt.Errorf("failed to render prompt %s of version %s: %w", name, p.version, err)
+	}
+	return strings.TrimSpace(b.String()), nil
+}
@@ -0,0 +1,117 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDefaultPrompts(t *testing.T) {
+	prompts := DefaultPrompts()
+	assert.Equal(t, DefaultPromptVersion, prompts.Version())
+
+	prompt, err := prompts.render(_ImagesPrompt, promptData{ImageCount: 3})
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "Analyze the 3 attached images")
+	assert.Contains(t, prompt, "Do NOT make up any information.")
+
+	prompt, err = prompts.render(_WebpagePrompt, promptData{Content: "Pancakes"})
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "Output rules:")
+	assert.True(t, strings.HasSuffix(prompt, "Webpage:\nPancakes"))
+
+	prompt, err = prompts.render(_SubstitutionsPrompt, promptData{Recipe: "Pancakes", Ingredient: "2 eggs", Dietary: "vegan"})
+	require.NoError(t, err)
+	assert.Contains(t, prompt, "the ingredient \"2 eggs\"")
+	assert.Contains(t, prompt, "Dietary constraints: vegan")
+	assert.True(t, strings.HasSuffix(prompt, "Recipe:\nPancakes"))
+}
+
+func TestParsePrompts(t *testing.T) {
+	fsys := fstest.MapFS{
+		"v2/rules.tmpl":         {Data: []byte(`{{define "rules"}}Be precise.{{end}}`)},
+		"v2/image.tmpl":         {Data: []byte("Extract the recipe in the image. {{template \"rules\"}}")},
+		"v2/images.tmpl":        {Data: []byte("Extract the recipe in the {{.ImageCount}} images. {{template \"rules\"}}")},
+		"v2/webpage.tmpl":       {Data: []byte("Extract the recipe.\n\n{{.Content}}\n")},
+		"v2/text.tmpl":          {Data: []byte("Extract the recipe.\n\n{{.Content}}\n")},
+		"v2/tags.tmpl":          {Data: []byte("Suggest tags, used: {{.ExistingTags}}\n\n{{.Recipe}}")},
+		"v2/translate.tmpl":     {Data: []byte("Translate into {{.Language}}.\n\n{{.Recipe}}")},
+		"v2/substitutions.tmpl": {Data: []byte("Substitute {{.Ingredient}} ({{.Dietary}}).\n\n{{.Recipe}}")},
+		"v3/image.tmpl":         {Data: []byte("Extract the recipe in the image.")},
+		"v4/image.tmpl":         {Data: []byte("{{.Unknown}}")},
+		"v4/images.tmpl":        {Data: []byte("{{.ImageCount}}")},
+		"v4/webpage.tmpl":       {Data: []byte("{{.Content}}")},
+		"v4/text.tmpl":          {Data: []byte("{{.Content")},
+	}
+
+	prompts, err := parsePrompts(fsys, "v2")
+	require.NoError(t, err)
+	assert.Equal(t, "v2", prompts.Version())
+
+	prompt, err := prompts.render(_ImagesPrompt, promptData{ImageCount: 2})
+	require.NoError(t, err)
+	assert.Equal(t, "Extract the recipe in the 2 images. Be precise.", prompt)
+
+	prompt, err = prompts.render(_TextPrompt, promptData{Content: "3 eggs"})
+	require.NoError(t, err)
+	assert.Equal(t, "Extract the recipe.\n\n3 eggs", prompt)
+
+	prompt, err = prompts.render(_TranslatePrompt, promptData{Recipe: "Pancakes", Language: "Swedish"})
+	require.NoError(t, err)
+	assert.Equal(t, "Translate into Swedish.\n\nPancakes", prompt)
+
+	// Every prompt must be defined
+	_, err = parsePrompts(fsys, "v3")
+	assert.ErrorContains(t, err, "images.tmpl is missing")
+
+	// Invalid templates
+	_, err = parsePrompts(fsys, "v4")
+	assert.ErrorContains(t, err, "failed to parse")
+
+	// Unknown versions
+	_, err = parsePrompts(fsys, "v5")
+	assert.Error(t, err)
+	for _, version := range []string{"", "..", "v2/../v3", "/v2"} {
+		_, err = parsePrompts(fsys, version)
+		assert.ErrorContains(t, err, "invalid prompt version", version)
+	}
+}
+
+func TestOpenAIPromptVersion(t *testing.T) {
+	prompts, err := parsePrompts(fstest.MapFS{
+		"v2/image.tmpl":         {Data: []byte("image")},
+		"v2/images.tmpl":        {Data: []byte("images")},
+		"v2/webpage.tmpl":       {Data: []byte("webpage: {{.Content}}")},
+		"v2/text.tmpl":          {Data: []byte("text: {{.Content}}")},
+		"v2/tags.tmpl":          {Data: []byte("tags: {{.Recipe}}")},
+		"v2/translate.tmpl":     {Data: []byte("translate: {{.Recipe}}")},
+		"v2/substitutions.tmpl": {Data: []byte("substitutions: {{.Recipe}}")},
+	}, "v2")
+	require.NoError(t, err)
+
+	var prompt string
+	client := newTestOpenAI(t, func(w http.ResponseWriter, r *http.Request) {
+		var body struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		json.NewDecoder(r.Body).Decode(&body)
+		prompt = body.Messages[0].Content
+
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(chatCompletionResponse("stop", `{"title":"Omelett","description":"","ingredients":[],"steps":[],"cook_time":0,"servings":0}`, "")))
+	})
+	client.prompts = prompts
+
+	result, err := client.AnalyzeRecipeText(context.Background(), "3 eggs, whisk and fry")
+	require.NoError(t, err)
+	assert.Equal(t, "text: 3 eggs, whisk and fry", prompt)
+	assert.Equal(t, "v2", result.PromptVersion)
+}
@@ -0,0 +1,258 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+)
+
+type ResilienceConfig struct {
+	// Timeout of a single call to the provider
+	Timeout time.Duration
+	// Maximum number of retries of a call that failed with a retryable error
+	MaxRetries int
+	// Backoff before the first retry, doubled for every following retry
+	InitialBackoff time.Duration
+	// Maximum backoff between two retries
+	MaxBackoff time.Duration
+	// Number of consecutive failed calls that opens the circuit (0 disables the circuit breaker)
+	FailureThreshold int
+	// How long the circuit stays open before a trial call is let through
+	OpenDuration time.Duration
+}
+
+func DefaultResilienceConfig() ResilienceConfig {
+	return ResilienceConfig{
+		Timeout:          60 * time.Second,
+		MaxRetries:       3,
+		InitialBackoff:   time.Second,
+		MaxBackoff:       20 * time.Second,
+		FailureThreshold: 5,
+		OpenDuration:     30 * time.Second,
+	}
+}
+
+// ResilientRecipeAI protects the calls to another RecipeAI with a per-call timeout, retries
+// with exponential backoff on rate limits, timeouts and provider errors, and a circuit breaker
+// that fails fast while the provider is down.
+type ResilientRecipeAI struct {
+	next    RecipeAI
+	config  ResilienceConfig
+	breaker *circuitBreaker
+}
+
+func NewResilientRecipeAI(next RecipeAI, config ResilienceConfig) RecipeAI {
+	return &ResilientRecipeAI{
+		next:   next,
+		config: config,
+		breaker: &circuitBreaker{
+			threshold:    config.FailureThreshold,
+			openDuration: config.OpenDuration,
+			now:          time.Now,
+		},
+	}
+}
+
+func (c *ResilientRecipeAI) AnalyzeRecipeImage(ctx context.Context, base64Image string, imageContentType ImageContentType) (*RecipeAnalysisResult, error) {
+	return call(ctx, c, func(ctx context.Context) (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeImage(ctx, base64Image, imageContentType)
+	})
+}
+
+func (c *ResilientRecipeAI) AnalyzeRecipeImages(ctx context.Context, images []Image) (*RecipeAnalysisResult, error) {
+	return call(ctx, c, func(ctx context.Context) (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeImages(ctx, images)
+	})
+}
+
+func (c *ResilientRecipeAI) AnalyzeRecipeWebpage(ctx context.Context, url string, page []byte) (*RecipeAnalysisResult, error) {
+	return call(ctx, c, func(ctx context.Context) (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeWebpage(ctx, url, page)
+	})
+}
+
+func (c *ResilientRecipeAI) AnalyzeRecipeText(ctx context.Context, text string) (*RecipeAnalysisResult, error) {
+	return call(ctx, c, func(ctx context.Context) (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeText(ctx, text)
+	})
+}
+
+func (c *ResilientRecipeAI) SuggestRecipeTags(ctx context.Context, recipe *models.Recipe, existingTags []string) (*TagSuggestion, error) {
+	return call(ctx, c, func(ctx context.Context) (*TagSuggestion, error) {
+		return c.next.SuggestRecipeTags(ctx, recipe, existingTags)
+	})
+}
+
+func (c *ResilientRecipeAI) TranslateRecipe(ctx context.Context, recipe *models.Recipe, language string) (*RecipeAnalysisResult, error) {
+	return call(ctx, c, func(ctx context.Context) (*RecipeAnalysisResult, error) {
+		return c.next.TranslateRecipe(ctx, recipe, language)
+	})
+}
+
+func (c *ResilientRecipeAI) SuggestSubstitutions(ctx context.Context, recipe *models.Recipe, ingredient models.Ingredient, dietary []string) (*SubstitutionResult, error) {
+	return call(ctx, c, func(ctx context.Context) (*SubstitutionResult, error) {
+		return c.next.SuggestSubstitutions(ctx, recipe, ingredient, dietary)
+	})
+}
+
+// call runs the AI call, retrying retryable errors until the retries or the context run out
+func call[T any](ctx context.Context, c *ResilientRecipeAI, analyze func(context.Context) (T, error)) (T, error) {
+	var zero T
+
+	for attempt := 0; ; attempt++ {
+		result, err := callOnce(ctx, c, analyze)
+		if err == nil {
+			return result, nil
+		}
+
+		if !isRetryable(err) || attempt >= c.config.MaxRetries {
+			return zero, err
+		}
+
+		backoff := c.backoff(attempt, err)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < backoff {
+			return zero, err
+		}
+
+		slog.Warn("AI call failed, retrying", "error", err.Error(), "attempt", attempt+1, "backoff", backoff)
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return zero, err
+		case <-timer.C:
+		}
+	}
+}
+
+// callOnce makes a single call to the provider, guarded by the circuit breaker and the timeout
+func callOnce[T any](ctx context.Context, c *ResilientRecipeAI, analyze func(context.Context) (T, error)) (T, error) {
+	if !c.breaker.allow() {
+		var zero T
+		return zero, ErrCircuitOpen
+	}
+
+	callCtx, cancel := ctx, context.CancelFunc(func() {})
+	if c.config.Timeout > 0 {
+		callCtx, cancel = context.WithTimeout(ctx, c.config.Timeout)
+	}
+	defer cancel()
+
+	result, err := analyze(callCtx)
+
+	// The call timed out, not the caller's context
+	if err != nil && ctx.Err() == nil && callCtx.Err() != nil && !errors.Is(err, ErrTimeout) {
+		err = fmt.Errorf("%w: no response within %s: %w", ErrTimeout, c.config.Timeout, err)
+	}
+
+	switch {
+	case err == nil:
+		c.breaker.success()
+	case errors.Is(err, ErrUnavailable) || errors.Is(err, ErrTimeout):
+		c.breaker.failure()
+	default:
+		// The provider answered (e.g. refused the content) or the caller gave up
+		c.breaker.release()
+	}
+
+	return result, err
+}
+
+// backoff returns the delay before the retry, with jitter so that concurrent calls spread out
+func (c *ResilientRecipeAI) backoff(attempt int, err error) time.Duration {
+	backoff := c.config.InitialBackoff << attempt
+	if backoff <= 0 || backoff > c.config.MaxBackoff {
+		backoff = c.config.MaxBackoff
+	}
+	if backoff > 0 {
+		backoff = backoff/2 + rand.N(backoff/2+1)
+	}
+
+	// Wait at least as long as the provider asks for
+	var retryErr *retryAfterError
+	if errors.As(err, &retryErr) && retryErr.after > backoff {
+		backoff = min(retryErr.after, c.config.MaxBackoff)
+	}
+
+	return backoff
+}
+
+func isRetryable(err error) bool {
+	return errors.Is(err, ErrRateLimited) || errors.Is(err, ErrTimeout) || errors.Is(err, ErrUnavailable)
+}
+
+// retryAfterError is an error for which the provider asked to wait before retrying
+type retryAfterError struct {
+	err   error
+	after time.Duration
+}
+
+func (e *retryAfterError) Error() string {
+	return e.err.Error()
+}
+
+func (e *retryAfterError) Unwrap() error {
+	return e.err
+}
+
+// circuitBreaker opens after a number of consecutive failures and then rejects calls until the
+// open duration has passed. Then a single trial call is let through (half-open), which closes
+// the circuit on success and opens it again on failure.
+type circuitBreaker struct {
+	mu           sync.Mutex
+	threshold    int
+	openDuration time.Duration
+	now          func() time.Time
+
+	failures int
+	openedAt time.Time
+	trial    bool
+}
+
+func (b *circuitBreaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.threshold <= 0 || b.failures < b.threshold {
+		return true
+	}
+	if b.trial || b.now().Sub(b.openedAt) < b.openDuration {
+		return false
+	}
+
+	b.trial = true
+	return true
+}
+
+func (b *circuitBreaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+	b.trial = false
+}
+
+func (b *circuitBreaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.trial = false
+	if b.failures >= b.threshold {
+		b.openedAt = b.now()
+	}
+}
+
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.trial = false
+}
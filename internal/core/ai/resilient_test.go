@@ -0,0 +1,157 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func testResilienceConfig() ResilienceConfig {
+	return ResilienceConfig{
+		Timeout:          time.Second,
+		MaxRetries:       2,
+		InitialBackoff:   time.Millisecond,
+		MaxBackoff:       5 * time.Millisecond,
+		FailureThreshold: 3,
+		OpenDuration:     time.Minute,
+	}
+}
+
+func TestResilientRecipeAI_Retries(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Retryable errors are retried", func(t *testing.T) {
+		next := new(MockRecipeAI)
+		resilient := NewResilientRecipeAI(next, testResilienceConfig())
+
+		next.On("AnalyzeRecipeText", mock.Anything, "3 eggs").Return(nil, fmt.Errorf("%w: 429", ErrRateLimited)).Once()
+		next.On("AnalyzeRecipeText", mock.Anything, "3 eggs").Return(nil, fmt.Errorf("%w: 503", ErrUnavailable)).Once()
+		next.On("AnalyzeRecipeText", mock.Anything, "3 eggs").Return(testAnalysisResult, nil).Once()
+
+		result, err := resilient.AnalyzeRecipeText(ctx, "3 eggs")
+		require.NoError(t, err)
+		assert.Equal(t, testAnalysisResult, result)
+		next.AssertExpectations(t)
+	})
+
+	t.Run("Gives up after the maximum retries", func(t *testing.T) {
+		next := new(MockRecipeAI)
+		resilient := NewResilientRecipeAI(next, testResilienceConfig())
+
+		next.On("AnalyzeRecipeText", mock.Anything, "3 eggs").Return(nil, fmt.Errorf("%w: 429", ErrRateLimited)).Times(3)
+
+		_, err := resilient.AnalyzeRecipeText(ctx, "3 eggs")
+		assert.ErrorIs(t, err, ErrRateLimited)
+		next.AssertExpectations(t)
+	})
+
+	t.Run("Other errors are not retried", func(t *testing.T) {
+		for _, callErr := range []error{ErrRefused, ErrEmptyResponse, errors.New("failed to unmarshal response")} {
+			next := new(MockRecipeAI)
+			resilient := NewResilientRecipeAI(next, testResilienceConfig())
+
+			next.On("AnalyzeRecipeText", mock.Anything, "3 eggs").Return(nil, callErr).Once()
+
+			_, err := resilient.AnalyzeRecipeText(ctx, "3 eggs")
+			assert.ErrorIs(t, err, callErr)
+			next.AssertExpectations(t)
+		}
+	})
+}
+
+func TestResilientRecipeAI_Timeout(t *testing.T) {
+	next := new(MockRecipeAI)
+	config := testResilienceConfig()
+	config.Timeout = 10 * time.Millisecond
+	config.MaxRetries = 0
+	resilient := NewResilientRecipeAI(next, config)
+
+	// The call blocks until its context is done, like a hanging HTTP request
+	next.On("AnalyzeRecipeImage", mock.Anything, "/9j/4AAQ", ImageContentTypeJPEG).Run(func(args mock.Arguments) {
+		<-args.Get(0).(context.Context).Done()
+	}).Return(nil, context.DeadlineExceeded).Once()
+
+	_, err := resilient.AnalyzeRecipeImage(context.Background(), "/9j/4AAQ", ImageContentTypeJPEG)
+	assert.ErrorIs(t, err, ErrTimeout)
+	next.AssertExpectations(t)
+}
+
+func TestResilientRecipeAI_CircuitBreaker(t *testing.T) {
+	ctx := context.Background()
+	next := new(MockRecipeAI)
+	config := testResilienceConfig()
+	config.MaxRetries = 0
+	resilient := NewResilientRecipeAI(next, config).(*ResilientRecipeAI)
+
+	now := time.Now()
+	resilient.breaker.now = func() time.Time { return now }
+
+	// Opens after three consecutive failures
+	next.On("AnalyzeRecipeText", mock.Anything, "3 eggs").Return(nil, ErrUnavailable).Times(3)
+	for range 3 {
+		_, err := resilient.AnalyzeRecipeText(ctx, "3 eggs")
+		assert.ErrorIs(t, err, ErrUnavailable)
+	}
+
+	// Fails fast without calling the provider
+	_, err := resilient.AnalyzeRecipeText(ctx, "3 eggs")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+	next.AssertExpectations(t)
+
+	// A failed trial call after the open duration opens the circuit again
+	now = now.Add(config.OpenDuration)
+	next.On("AnalyzeRecipeText", mock.Anything, "3 eggs").Return(nil, ErrUnavailable).Once()
+	_, err = resilient.AnalyzeRecipeText(ctx, "3 eggs")
+	assert.ErrorIs(t, err, ErrUnavailable)
+	_, err = resilient.AnalyzeRecipeText(ctx, "3 eggs")
+	assert.ErrorIs(t, err, ErrCircuitOpen)
+
+	// A successful trial call closes the circuit
+	now = now.Add(config.OpenDuration)
+	next.On("AnalyzeRecipeText", mock.Anything, "3 eggs").Return(testAnalysisResult, nil).Twice()
+	for range 2 {
+		_, err = resilient.AnalyzeRecipeText(ctx, "3 eggs")
+		assert.NoError(t, err)
+	}
+	next.AssertExpectations(t)
+}
+
+func TestResilientRecipeAI_CircuitBreakerIgnoresContentErrors(t *testing.T) {
+	next := new(MockRecipeAI)
+	config := testResilienceConfig()
+	config.MaxRetries = 0
+	resilient := NewResilientRecipeAI(next, config)
+
+	// The provider is up, it just refuses the content
+	next.On("AnalyzeRecipeText", mock.Anything, "3 eggs").Return(nil, ErrRefused).Times(5)
+	for range 5 {
+		_, err := resilient.AnalyzeRecipeText(context.Background(), "3 eggs")
+		assert.ErrorIs(t, err, ErrRefused)
+	}
+	next.AssertExpectations(t)
+}
+
+func TestResilientRecipeAI_Backoff(t *testing.T) {
+	resilient := NewResilientRecipeAI(nil, ResilienceConfig{
+		InitialBackoff: time.Second,
+		MaxBackoff:     10 * time.Second,
+	}).(*ResilientRecipeAI)
+
+	for attempt, limit := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second} {
+		backoff := resilient.backoff(attempt, ErrUnavailable)
+		assert.GreaterOrEqual(t, backoff, limit/2, "attempt %d", attempt)
+		assert.LessOrEqual(t, backoff, limit, "attempt %d", attempt)
+	}
+
+	// The provider's Retry-After is honored, up to the maximum backoff
+	retryErr := &retryAfterError{err: ErrRateLimited, after: 5 * time.Second}
+	assert.Equal(t, 5*time.Second, resilient.backoff(0, retryErr))
+	retryErr.after = time.Minute
+	assert.Equal(t, 10*time.Second, resilient.backoff(0, retryErr))
+}
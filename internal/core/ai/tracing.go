@@ -0,0 +1,90 @@
+package ai
+
+import (
+	"context"
+
+	"github.com/AntonLuning/RecipeBank/internal/tracing"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.28.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracedRecipeAI records a span with the model and token usage for every call to another
+// RecipeAI
+type TracedRecipeAI struct {
+	next     RecipeAI
+	provider string
+	model    string
+}
+
+func NewTracedRecipeAI(next RecipeAI, provider string, model string) RecipeAI {
+	return &TracedRecipeAI{next: next, provider: provider, model: model}
+}
+
+func (c *TracedRecipeAI) AnalyzeRecipeImage(ctx context.Context, base64Image string, imageContentType ImageContentType) (*RecipeAnalysisResult, error) {
+	return traced(ctx, c, "image", func(ctx context.Context) (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeImage(ctx, base64Image, imageContentType)
+	})
+}
+
+func (c *TracedRecipeAI) AnalyzeRecipeImages(ctx context.Context, images []Image) (*RecipeAnalysisResult, error) {
+	return traced(ctx, c, "images", func(ctx context.Context) (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeImages(ctx, images)
+	})
+}
+
+func (c *TracedRecipeAI) AnalyzeRecipeWebpage(ctx context.Context, url string, page []byte) (*RecipeAnalysisResult, error) {
+	return traced(ctx, c, "webpage", func(ctx context.Context) (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeWebpage(ctx, url, page)
+	})
+}
+
+func (c *TracedRecipeAI) AnalyzeRecipeText(ctx context.Context, text string) (*RecipeAnalysisResult, error) {
+	return traced(ctx, c, "text", func(ctx context.Context) (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeText(ctx, text)
+	})
+}
+
+func (c *TracedRecipeAI) SuggestRecipeTags(ctx context.Context, recipe *models.Recipe, existingTags []string) (*TagSuggestion, error) {
+	return traced(ctx, c, "tags", func(ctx context.Context) (*TagSuggestion, error) {
+		return c.next.SuggestRecipeTags(ctx, recipe, existingTags)
+	})
+}
+
+func (c *TracedRecipeAI) TranslateRecipe(ctx context.Context, recipe *models.Recipe, language string) (*RecipeAnalysisResult, error) {
+	return traced(ctx, c, "translate", func(ctx context.Context) (*RecipeAnalysisResult, error) {
+		return c.next.TranslateRecipe(ctx, recipe, language)
+	})
+}
+
+func (c *TracedRecipeAI) SuggestSubstitutions(ctx context.Context, recipe *models.Recipe, ingredient models.Ingredient, dietary []string) (*SubstitutionResult, error) {
+	return traced(ctx, c, "substitutions", func(ctx context.Context) (*SubstitutionResult, error) {
+		return c.next.SuggestSubstitutions(ctx, recipe, ingredient, dietary)
+	})
+}
+
+func traced[T usageReporter](ctx context.Context, c *TracedRecipeAI, operation string, analyze func(ctx context.Context) (T, error)) (T, error) {
+	attributes := []attribute.KeyValue{
+		semconv.GenAISystemKey.String(c.provider),
+		semconv.GenAIOperationNameKey.String(operation),
+	}
+	if c.model != "" {
+		attributes = append(attributes, semconv.GenAIRequestModel(c.model))
+	}
+	ctx, span := tracing.Start(ctx, "ai "+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attributes...))
+
+	result, err := analyze(ctx)
+	if err == nil {
+		if usage := result.usage(); usage != nil {
+			span.SetAttributes(
+				semconv.GenAIResponseModel(usage.Model),
+				semconv.GenAIUsageInputTokens(int(usage.PromptTokens)),
+				semconv.GenAIUsageOutputTokens(int(usage.CompletionTokens)),
+			)
+		}
+	}
+	tracing.End(span, err)
+
+	return result, err
+}
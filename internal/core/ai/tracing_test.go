@@ -0,0 +1,51 @@
+package ai
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestTracedRecipeAI(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	ctx := context.Background()
+	next := new(MockRecipeAI)
+	traced := NewTracedRecipeAI(next, "openai", "gpt-test")
+
+	next.On("AnalyzeRecipeText", mock.Anything, "3 eggs").Return(&RecipeAnalysisResult{
+		Title: "Omelett",
+		Usage: &Usage{Model: "gpt-test-2025", PromptTokens: 1000, CompletionTokens: 500},
+	}, nil).Once()
+	next.On("AnalyzeRecipeText", mock.Anything, "slow").Return(nil, fmt.Errorf("%w: after 60s", ErrTimeout)).Once()
+
+	_, err := traced.AnalyzeRecipeText(ctx, "3 eggs")
+	require.NoError(t, err)
+	_, err = traced.AnalyzeRecipeText(ctx, "slow")
+	assert.ErrorIs(t, err, ErrTimeout)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "ai text", spans[0].Name())
+	assert.Subset(t, spans[0].Attributes(), []attribute.KeyValue{
+		attribute.String("gen_ai.system", "openai"),
+		attribute.String("gen_ai.request.model", "gpt-test"),
+		attribute.String("gen_ai.response.model", "gpt-test-2025"),
+		attribute.Int("gen_ai.usage.input_tokens", 1000),
+		attribute.Int("gen_ai.usage.output_tokens", 500),
+	})
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	next.AssertExpectations(t)
+}
@@ -0,0 +1,227 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+)
+
+// ModelPrice is the price of a model in USD per million tokens
+type ModelPrice struct {
+	Input  float64
+	Output float64
+}
+
+// PriceTable maps model names to prices. Model names are matched on the longest prefix, so a
+// price for "gpt-4.1-mini" also applies to snapshots like "gpt-4.1-mini-2025-04-14".
+type PriceTable map[string]ModelPrice
+
+// ParsePriceTable parses prices in the format "model=input/output,...", with the prices in USD
+// per million tokens, e.g. "gpt-4.1-mini=0.40/1.60,gpt-4.1=2.00/8.00"
+func ParsePriceTable(s string) (PriceTable, error) {
+	prices := PriceTable{}
+
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		model, price, ok := strings.Cut(entry, "=")
+		input, output, ok2 := strings.Cut(price, "/")
+		if !ok || !ok2 || strings.TrimSpace(model) == "" {
+			return nil, fmt.Errorf("invalid price %q, expected model=input/output", entry)
+		}
+
+		inputPrice, err := strconv.ParseFloat(strings.TrimSpace(input), 64)
+		if err != nil || inputPrice < 0 {
+			return nil, fmt.Errorf("invalid input price in %q", entry)
+		}
+		outputPrice, err := strconv.ParseFloat(strings.TrimSpace(output), 64)
+		if err != nil || outputPrice < 0 {
+			return nil, fmt.Errorf("invalid output price in %q", entry)
+		}
+
+		prices[strings.TrimSpace(model)] = ModelPrice{Input: inputPrice, Output: outputPrice}
+	}
+
+	return prices, nil
+}
+
+// Cost estimates the cost in USD of the usage, false if there is no price for the model
+func (p PriceTable) Cost(usage Usage) (float64, bool) {
+	var match string
+	for model := range p {
+		if strings.HasPrefix(usage.Model, model) && len(model) > len(match) {
+			match = model
+		}
+	}
+	if match == "" {
+		return 0, false
+	}
+
+	price := p[match]
+	return (float64(usage.PromptTokens)*price.Input + float64(usage.CompletionTokens)*price.Output) / 1_000_000, true
+}
+
+// UsageStore stores the usage records of AI calls
+type UsageStore interface {
+	CreateAIUsage(ctx context.Context, usage *models.AIUsage) error
+	// GetAIUsageCost returns the total cost of the usage created in [from, to)
+	GetAIUsageCost(ctx context.Context, from time.Time, to time.Time) (float64, error)
+}
+
+// usageReporter is an AI result that reports the token usage of its call
+type usageReporter interface {
+	usage() *Usage
+}
+
+func (r *RecipeAnalysisResult) usage() *Usage { return r.Usage }
+
+func (s *TagSuggestion) usage() *Usage { return s.Usage }
+
+func (r *SubstitutionResult) usage() *Usage { return r.Usage }
+
+// usageError is an error from a call that the provider answered, and therefore charged for,
+// but whose response could not be used, e.g. a refused or truncated completion
+type usageError struct {
+	err   error
+	usage *Usage
+}
+
+func (e *usageError) Error() string {
+	return e.err.Error()
+}
+
+func (e *usageError) Unwrap() error {
+	return e.err
+}
+
+// errorUsage returns the token usage carried by the error, nil if the call was not charged
+func errorUsage(err error) *Usage {
+	var usageErr *usageError
+	if errors.As(err, &usageErr) {
+		return usageErr.usage
+	}
+	return nil
+}
+
+// MeteredRecipeAI records the token usage and estimated cost of every call to another RecipeAI,
+// and refuses calls once the monthly budget is reached
+type MeteredRecipeAI struct {
+	next          RecipeAI
+	store         UsageStore
+	prices        PriceTable
+	monthlyBudget float64 // in USD, 0 for no budget
+	now           func() time.Time
+}
+
+func NewMeteredRecipeAI(next RecipeAI, store UsageStore, prices PriceTable, monthlyBudget float64) RecipeAI {
+	return &MeteredRecipeAI{
+		next:          next,
+		store:         store,
+		prices:        prices,
+		monthlyBudget: monthlyBudget,
+		now:           time.Now,
+	}
+}
+
+func (c *MeteredRecipeAI) AnalyzeRecipeImage(ctx context.Context, base64Image string, imageContentType ImageContentType) (*RecipeAnalysisResult, error) {
+	return metered(ctx, c, "image", func() (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeImage(ctx, base64Image, imageContentType)
+	})
+}
+
+func (c *MeteredRecipeAI) AnalyzeRecipeImages(ctx context.Context, images []Image) (*RecipeAnalysisResult, error) {
+	return metered(ctx, c, "images", func() (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeImages(ctx, images)
+	})
+}
+
+func (c *MeteredRecipeAI) AnalyzeRecipeWebpage(ctx context.Context, url string, page []byte) (*RecipeAnalysisResult, error) {
+	return metered(ctx, c, "webpage", func() (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeWebpage(ctx, url, page)
+	})
+}
+
+func (c *MeteredRecipeAI) AnalyzeRecipeText(ctx context.Context, text string) (*RecipeAnalysisResult, error) {
+	return metered(ctx, c, "text", func() (*RecipeAnalysisResult, error) {
+		return c.next.AnalyzeRecipeText(ctx, text)
+	})
+}
+
+func (c *MeteredRecipeAI) SuggestRecipeTags(ctx context.Context, recipe *models.Recipe, existingTags []string) (*TagSuggestion, error) {
+	return metered(ctx, c, "tags", func() (*TagSuggestion, error) {
+		return c.next.SuggestRecipeTags(ctx, recipe, existingTags)
+	})
+}
+
+func (c *MeteredRecipeAI) TranslateRecipe(ctx context.Context, recipe *models.Recipe, language string) (*RecipeAnalysisResult, error) {
+	return metered(ctx, c, "translate", func() (*RecipeAnalysisResult, error) {
+		return c.next.TranslateRecipe(ctx, recipe, language)
+	})
+}
+
+func (c *MeteredRecipeAI) SuggestSubstitutions(ctx context.Context, recipe *models.Recipe, ingredient models.Ingredient, dietary []string) (*SubstitutionResult, error) {
+	return metered(ctx, c, "substitutions", func() (*SubstitutionResult, error) {
+		return c.next.SuggestSubstitutions(ctx, recipe, ingredient, dietary)
+	})
+}
+
+// metered checks the budget, runs the analysis and records its usage, also when the analysis
+// failed after the provider charged for it. The budget month is in UTC, like the usage report.
+// Failures to read or write the usage are logged and do not fail the analysis.
+func metered[T usageReporter](ctx context.Context, c *MeteredRecipeAI, operation string, analyze func() (T, error)) (T, error) {
+	var zero T
+
+	if c.monthlyBudget > 0 {
+		now := c.now().UTC()
+		monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+		spent, err := c.store.GetAIUsageCost(ctx, monthStart, now)
+		if err != nil {
+			slog.Warn("Unable to check the AI budget", "error", err.Error())
+		} else if spent >= c.monthlyBudget {
+			return zero, fmt.Errorf("%w: spent %.2f of %.2f USD this month", ErrBudgetExceeded, spent, c.monthlyBudget)
+		}
+	}
+
+	result, err := analyze()
+	if err != nil {
+		if callUsage := errorUsage(err); callUsage != nil {
+			c.record(ctx, operation, callUsage)
+		}
+		return zero, err
+	}
+	if callUsage := result.usage(); callUsage != nil {
+		c.record(ctx, operation, callUsage)
+	}
+
+	return result, nil
+}
+
+// record stores the usage of a call with its estimated cost
+func (c *MeteredRecipeAI) record(ctx context.Context, operation string, callUsage *Usage) {
+	cost, ok := c.prices.Cost(*callUsage)
+	if !ok {
+		slog.Warn("No price for AI model, cost is not estimated", "model", callUsage.Model)
+	}
+
+	usage := &models.AIUsage{
+		Operation:        operation,
+		Model:            callUsage.Model,
+		PromptTokens:     callUsage.PromptTokens,
+		CompletionTokens: callUsage.CompletionTokens,
+		Cost:             cost,
+		CreatedAt:        c.now(),
+	}
+	if err := c.store.CreateAIUsage(ctx, usage); err != nil {
+		slog.Warn("Unable to record AI usage", "error", err.Error())
+	}
+}
@@ -0,0 +1,174 @@
+package ai
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryUsageStore is an in-memory UsageStore for tests
+type memoryUsageStore struct {
+	usages []*models.AIUsage
+	err    error
+}
+
+func (s *memoryUsageStore) CreateAIUsage(ctx context.Context, usage *models.AIUsage) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.usages = append(s.usages, usage)
+	return nil
+}
+
+func (s *memoryUsageStore) GetAIUsageCost(ctx context.Context, from time.Time, to time.Time) (float64, error) {
+	if s.err != nil {
+		return 0, s.err
+	}
+	var cost float64
+	for _, usage := range s.usages {
+		if !usage.CreatedAt.Before(from) && usage.CreatedAt.Before(to) {
+			cost += usage.Cost
+		}
+	}
+	return cost, nil
+}
+
+func TestParsePriceTable(t *testing.T) {
+	prices, err := ParsePriceTable(" gpt-4.1=2.00/8.00, gpt-4.1-mini=0.40/1.60 ,")
+	require.NoError(t, err)
+	assert.Equal(t, PriceTable{
+		"gpt-4.1":      {Input: 2, Output: 8},
+		"gpt-4.1-mini": {Input: 0.4, Output: 1.6},
+	}, prices)
+
+	for _, invalid := range []string{"gpt-4.1", "gpt-4.1=2.00", "=1/2", "gpt-4.1=two/8", "gpt-4.1=2/-8"} {
+		_, err := ParsePriceTable(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestPriceTableCost(t *testing.T) {
+	prices := PriceTable{
+		"gpt-4.1":      {Input: 2, Output: 8},
+		"gpt-4.1-mini": {Input: 0.4, Output: 1.6},
+	}
+
+	// The longest matching prefix wins
+	cost, ok := prices.Cost(Usage{Model: "gpt-4.1-mini-2025-04-14", PromptTokens: 1_000_000, CompletionTokens: 500_000})
+	assert.True(t, ok)
+	assert.InDelta(t, 1.2, cost, 1e-9)
+
+	cost, ok = prices.Cost(Usage{Model: "gpt-4.1-2025-04-14", PromptTokens: 1000, CompletionTokens: 100})
+	assert.True(t, ok)
+	assert.InDelta(t, 0.0028, cost, 1e-9)
+
+	_, ok = prices.Cost(Usage{Model: "o3", PromptTokens: 1000})
+	assert.False(t, ok)
+}
+
+func TestMeteredRecipeAI(t *testing.T) {
+	ctx := context.Background()
+	prices := PriceTable{"gpt-test": {Input: 1, Output: 2}}
+	now := time.Date(2025, 3, 15, 12, 0, 0, 0, time.UTC)
+
+	result := &RecipeAnalysisResult{
+		Title: "Omelett",
+		Usage: &Usage{Model: "gpt-test-2025", PromptTokens: 1000, CompletionTokens: 500},
+	}
+
+	t.Run("Records the usage", func(t *testing.T) {
+		next := new(MockRecipeAI)
+		store := &memoryUsageStore{}
+		metered := NewMeteredRecipeAI(next, store, prices, 0).(*MeteredRecipeAI)
+		metered.now = func() time.Time { return now }
+
+		next.On("AnalyzeRecipeWebpage", ctx, "https://example.com", mock.Anything).Return(result, nil).Once()
+
+		_, err := metered.AnalyzeRecipeWebpage(ctx, "https://example.com", []byte("<html></html>"))
+		require.NoError(t, err)
+		require.Len(t, store.usages, 1)
+		assert.Equal(t, &models.AIUsage{
+			Operation:        "webpage",
+			Model:            "gpt-test-2025",
+			PromptTokens:     1000,
+			CompletionTokens: 500,
+			Cost:             0.002,
+			CreatedAt:        now,
+		}, store.usages[0])
+	})
+
+	t.Run("Refuses calls over the monthly budget", func(t *testing.T) {
+		next := new(MockRecipeAI)
+		store := &memoryUsageStore{usages: []*models.AIUsage{
+			{Cost: 4, CreatedAt: now.AddDate(0, -1, 0)}, // last month
+			{Cost: 1, CreatedAt: now.Add(-time.Hour)},
+		}}
+		metered := NewMeteredRecipeAI(next, store, prices, 2).(*MeteredRecipeAI)
+		metered.now = func() time.Time { return now }
+
+		next.On("AnalyzeRecipeText", ctx, "3 eggs").Return(result, nil).Once()
+		_, err := metered.AnalyzeRecipeText(ctx, "3 eggs")
+		require.NoError(t, err)
+
+		store.usages = append(store.usages, &models.AIUsage{Cost: 1, CreatedAt: now.Add(-time.Minute)})
+		_, err = metered.AnalyzeRecipeText(ctx, "3 eggs")
+		assert.ErrorIs(t, err, ErrBudgetExceeded)
+		next.AssertExpectations(t)
+	})
+
+	t.Run("Records the usage of failed calls", func(t *testing.T) {
+		next := new(MockRecipeAI)
+		store := &memoryUsageStore{}
+		metered := NewMeteredRecipeAI(next, store, prices, 0).(*MeteredRecipeAI)
+		metered.now = func() time.Time { return now }
+
+		refused := &usageError{err: ErrRefused, usage: &Usage{Model: "gpt-test", PromptTokens: 1000, CompletionTokens: 10}}
+		next.On("AnalyzeRecipeText", ctx, "3 eggs").Return(nil, refused).Once()
+		next.On("AnalyzeRecipeText", ctx, "4 eggs").Return(nil, ErrUnavailable).Once()
+
+		_, err := metered.AnalyzeRecipeText(ctx, "3 eggs")
+		assert.ErrorIs(t, err, ErrRefused)
+		_, err = metered.AnalyzeRecipeText(ctx, "4 eggs")
+		assert.ErrorIs(t, err, ErrUnavailable)
+
+		require.Len(t, store.usages, 1)
+		assert.Equal(t, "text", store.usages[0].Operation)
+		assert.Equal(t, int64(1000), store.usages[0].PromptTokens)
+		assert.Equal(t, int64(10), store.usages[0].CompletionTokens)
+		assert.InDelta(t, 0.00102, store.usages[0].Cost, 1e-9)
+	})
+
+	t.Run("The budget month is in UTC", func(t *testing.T) {
+		next := new(MockRecipeAI)
+		// Spent on the last day of February in UTC, which is already March in UTC+2
+		store := &memoryUsageStore{usages: []*models.AIUsage{
+			{Cost: 3, CreatedAt: time.Date(2025, 2, 28, 23, 0, 0, 0, time.UTC)},
+		}}
+		metered := NewMeteredRecipeAI(next, store, prices, 2).(*MeteredRecipeAI)
+		metered.now = func() time.Time { return time.Date(2025, 3, 1, 10, 0, 0, 0, time.FixedZone("UTC+2", 2*60*60)) }
+
+		next.On("AnalyzeRecipeText", ctx, "3 eggs").Return(result, nil).Once()
+
+		_, err := metered.AnalyzeRecipeText(ctx, "3 eggs")
+		require.NoError(t, err)
+		next.AssertExpectations(t)
+	})
+
+	t.Run("Store errors do not fail the analysis", func(t *testing.T) {
+		next := new(MockRecipeAI)
+		store := &memoryUsageStore{err: errors.New("database down")}
+		metered := NewMeteredRecipeAI(next, store, prices, 2)
+
+		next.On("AnalyzeRecipeText", ctx, "3 eggs").Return(result, nil).Once()
+
+		got, err := metered.AnalyzeRecipeText(ctx, "3 eggs")
+		require.NoError(t, err)
+		assert.Equal(t, result, got)
+	})
+}
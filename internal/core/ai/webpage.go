@@ -0,0 +1,655 @@
+package ai
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/net/html"
+	"golang.org/x/net/html/atom"
+)
+
+const (
+	// Rough average of characters per token for the OpenAI tokenizers
+	_CharsPerToken = 4
+	// Default token budget for the webpage content sent to the model
+	_WebpageTokenBudget = 6000
+	// Default token budget for the document text (e.g. of a PDF) sent to the model
+	_TextTokenBudget = 6000
+	// Marker appended when the content had to be cut to fit the token budget
+	_TruncatedMarker = "[...]"
+)
+
+var (
+	// Class/id hints for elements that are likely to hold the recipe
+	positiveHints = regexp.MustCompile(`(?i)recipe|ingredient|instruction|direction|method|step|preparation|article|content|main|entry|post|body|text`)
+	// Class/id hints that keep an element even if it also looks like boilerplate
+	recipeHints = regexp.MustCompile(`(?i)recipe|ingredient|instruction|direction|method|step`)
+	// Class/id hints for elements that are likely to be boilerplate
+	negativeHints = regexp.MustCompile(`(?i)comment|share|social|advert|\bads?\b|\bad-|banner|cookie|consent|newsletter|subscribe|related|promo|sponsor|sidebar|popup|modal|breadcrumb|rating|review|author-bio|footer|masthead|menu|navbar|skip`)
+	// Class/id hints for elements that are boilerplate even within a recipe
+	boilerplateHints = regexp.MustCompile(`(?i)comment|share|social|advert|cookie|consent|newsletter|subscribe|related|promo|sponsor|breadcrumb`)
+)
+
+// Elements that never contain recipe content and are dropped with their children
+var skippedElements = map[atom.Atom]bool{
+	atom.Script:   true,
+	atom.Style:    true,
+	atom.Svg:      true,
+	atom.Noscript: true,
+	atom.Iframe:   true,
+	atom.Nav:      true,
+	atom.Footer:   true,
+	atom.Header:   true,
+	atom.Aside:    true,
+	atom.Button:   true,
+	atom.Form:     true,
+	atom.Input:    true,
+	atom.Textarea: true,
+	atom.Select:   true,
+	atom.Template: true,
+	atom.Dialog:   true,
+	atom.Object:   true,
+	atom.Embed:    true,
+	atom.Canvas:   true,
+	atom.Video:    true,
+	atom.Audio:    true,
+	atom.Head:     true,
+}
+
+// Elements that start a new block of text when rendered
+var blockElements = map[atom.Atom]bool{
+	atom.P:          true,
+	atom.Div:        true,
+	atom.Section:    true,
+	atom.Article:    true,
+	atom.Main:       true,
+	atom.Blockquote: true,
+	atom.Pre:        true,
+	atom.Table:      true,
+	atom.Tr:         true,
+	atom.Ul:         true,
+	atom.Ol:         true,
+	atom.Dl:         true,
+	atom.Dt:         true,
+	atom.Dd:         true,
+	atom.Figure:     true,
+	atom.Figcaption: true,
+	atom.Hr:         true,
+}
+
+// Elements whose text is scored as content when looking for the main content
+var scoredElements = map[atom.Atom]bool{
+	atom.P:   true,
+	atom.Li:  true,
+	atom.Td:  true,
+	atom.Pre: true,
+	atom.Dd:  true,
+	atom.H1:  true,
+	atom.H2:  true,
+	atom.H3:  true,
+	atom.H4:  true,
+}
+
+// extractWebpageText parses an HTML document, detects its main content and renders it
+// as compact Markdown-like text limited to roughly maxTokens tokens.
+func extractWebpageText(r io.Reader, maxTokens int) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse HTML: %w", err)
+	}
+
+	body := findFirst(doc, atom.Body)
+	if body == nil {
+		body = doc
+	}
+
+	filter := newContentFilter(body)
+	content := filter.findMainContent(body)
+
+	var sb strings.Builder
+	if title := pageTitle(doc); title != "" && !strings.Contains(collapseSpaces(filter.textContent(content)), title) {
+		sb.WriteString("# ")
+		sb.WriteString(title)
+		sb.WriteString("\n\n")
+	}
+
+	w := &markdownWriter{sb: &sb, filter: filter}
+	w.render(content)
+
+	return truncateToTokens(tidyLines(sb.String()), maxTokens), nil
+}
+
+// contentFilter decides which nodes of a page are ignored. Boilerplate hints are not applied to
+// the layout wrappers of a page, which hold most of its text even when their class or id looks
+// like boilerplate (e.g. <div id="main" class="has-sidebar">), nor to the main content and its
+// ancestors once it is detected.
+type contentFilter struct {
+	kept map[*html.Node]bool
+}
+
+func newContentFilter(root *html.Node) *contentFilter {
+	f := &contentFilter{kept: map[*html.Node]bool{}}
+
+	total := textLength(root)
+	walk(root, func(n *html.Node) bool {
+		if isSkipped(n) {
+			return false
+		}
+		if isBoilerplate(n) && textLength(n)*2 > total {
+			f.kept[n] = true
+		}
+		return true
+	})
+
+	return f
+}
+
+// skip reports whether the node (and its children) should be ignored entirely
+func (f *contentFilter) skip(n *html.Node) bool {
+	return isSkipped(n) || (isBoilerplate(n) && !f.kept[n])
+}
+
+// findMainContent scores the block elements of the page (a simplified version of the
+// readability algorithm) and returns the node most likely to contain the recipe. The node and
+// its ancestors are kept from then on.
+func (f *contentFilter) findMainContent(root *html.Node) *html.Node {
+	content := f.scoreMainContent(root)
+	for n := content; n != nil; n = n.Parent {
+		f.kept[n] = true
+	}
+	return content
+}
+
+func (f *contentFilter) scoreMainContent(root *html.Node) *html.Node {
+	scores := map[*html.Node]float64{}
+	var candidates []*html.Node // in document order, to keep the result deterministic
+
+	walk(root, func(n *html.Node) bool {
+		if f.skip(n) {
+			return false
+		}
+		if n.Type != html.ElementNode || !scoredElements[n.DataAtom] {
+			return true
+		}
+
+		text := collapseSpaces(f.textContent(n))
+		if utf8.RuneCountInString(text) < 20 && n.DataAtom != atom.Li {
+			return true
+		}
+
+		// Base score on the amount of text and the number of clauses
+		score := 1 + float64(strings.Count(text, ",")) + min(float64(utf8.RuneCountInString(text))/100, 3)
+
+		// Propagate to the ancestors, with decreasing weight
+		ancestor := n.Parent
+		for level := 0; ancestor != nil && level < 3; level++ {
+			if _, ok := scores[ancestor]; !ok {
+				scores[ancestor] = initialScore(ancestor)
+				candidates = append(candidates, ancestor)
+			}
+			scores[ancestor] += score / float64(level+1)
+			ancestor = ancestor.Parent
+		}
+
+		return true
+	})
+
+	var best *html.Node
+	var bestScore float64
+	for _, n := range candidates {
+		// Penalize link-heavy elements (menus, tag clouds, "related recipes", etc.)
+		score := scores[n] * (1 - f.linkDensity(n))
+		scores[n] = score
+
+		if best == nil || score > bestScore {
+			best = n
+			bestScore = score
+		}
+	}
+
+	if best == nil {
+		return root
+	}
+
+	// Recipes are often split into sibling containers (e.g. ingredients and steps),
+	// so climb to the parent as long as it holds a comparable amount of content.
+	for best != root && best.Parent != nil && scores[best.Parent] >= bestScore*0.75 {
+		best = best.Parent
+		bestScore = max(bestScore, scores[best])
+	}
+
+	return best
+}
+
+func initialScore(n *html.Node) float64 {
+	var score float64
+
+	switch n.DataAtom {
+	case atom.Article, atom.Main:
+		score += 10
+	case atom.Div, atom.Section:
+		score += 5
+	case atom.Ol, atom.Ul, atom.Pre, atom.Td, atom.Blockquote:
+		score += 3
+	case atom.Form, atom.Li, atom.Dl, atom.Dd:
+		score -= 3
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6, atom.Th:
+		score -= 5
+	}
+
+	hints := attr(n, "class") + " " + attr(n, "id") + " " + attr(n, "itemtype")
+	if positiveHints.MatchString(hints) {
+		score += 25
+	}
+	if negativeHints.MatchString(hints) {
+		score -= 25
+	}
+
+	return score
+}
+
+func (f *contentFilter) linkDensity(n *html.Node) float64 {
+	textLength := utf8.RuneCountInString(collapseSpaces(f.textContent(n)))
+	if textLength == 0 {
+		return 0
+	}
+
+	var linkLength int
+	walk(n, func(c *html.Node) bool {
+		if c.Type == html.ElementNode && c.DataAtom == atom.A {
+			linkLength += utf8.RuneCountInString(collapseSpaces(f.textContent(c)))
+			return false
+		}
+		return true
+	})
+
+	return float64(linkLength) / float64(textLength)
+}
+
+// isSkipped reports whether the node (and its children) never holds content, regardless of
+// its class or id
+func isSkipped(n *html.Node) bool {
+	switch n.Type {
+	case html.CommentNode, html.DoctypeNode:
+		return true
+	case html.ElementNode:
+	default:
+		return false
+	}
+
+	if isContentElement(n) {
+		return false
+	}
+
+	if skippedElements[n.DataAtom] {
+		return true
+	}
+
+	if hasAttr(n, "hidden") || strings.EqualFold(attr(n, "aria-hidden"), "true") {
+		return true
+	}
+
+	style := strings.ReplaceAll(strings.ToLower(attr(n, "style")), " ", "")
+	if strings.Contains(style, "display:none") || strings.Contains(style, "visibility:hidden") {
+		return true
+	}
+
+	switch strings.ToLower(attr(n, "role")) {
+	case "navigation", "banner", "contentinfo", "complementary", "dialog", "alert":
+		return true
+	}
+
+	return false
+}
+
+// isBoilerplate reports whether the class or id of the element looks like boilerplate, unless it
+// also looks like recipe content
+func isBoilerplate(n *html.Node) bool {
+	if n.Type != html.ElementNode || isContentElement(n) {
+		return false
+	}
+
+	hints := attr(n, "class") + " " + attr(n, "id")
+	if boilerplateHints.MatchString(hints) {
+		return true
+	}
+	return negativeHints.MatchString(hints) && !recipeHints.MatchString(hints)
+}
+
+// isContentElement reports whether the element is the body or marked up as the main content,
+// which is never skipped (e.g. <body class="has-sidebar">)
+func isContentElement(n *html.Node) bool {
+	switch n.DataAtom {
+	case atom.Body, atom.Main, atom.Article:
+		return true
+	default:
+		return false
+	}
+}
+
+// markdownWriter renders a node tree as compact Markdown-like text
+type markdownWriter struct {
+	sb      *strings.Builder
+	filter  *contentFilter
+	lists   []*listState
+	inPre   bool
+	pending bool // whether a space is pending before the next word
+	marker  bool // whether a list marker was written without any text after it
+}
+
+type listState struct {
+	ordered bool
+	index   int
+}
+
+func (w *markdownWriter) render(n *html.Node) {
+	if w.filter.skip(n) {
+		return
+	}
+
+	switch n.Type {
+	case html.TextNode:
+		w.text(n.Data)
+		return
+	case html.ElementNode:
+	default:
+		w.children(n)
+		return
+	}
+
+	switch n.DataAtom {
+	case atom.H1, atom.H2, atom.H3, atom.H4, atom.H5, atom.H6:
+		level, _ := strconv.Atoi(n.Data[1:])
+		w.block()
+		w.sb.WriteString(strings.Repeat("#", level) + " ")
+		w.children(n)
+		w.block()
+	case atom.Ul, atom.Ol:
+		w.lists = append(w.lists, &listState{ordered: n.DataAtom == atom.Ol})
+		w.block()
+		w.children(n)
+		w.lists = w.lists[:len(w.lists)-1]
+		w.block()
+	case atom.Li:
+		w.line()
+		w.sb.WriteString(w.listMarker())
+		w.marker = true
+		w.children(n)
+		w.marker = false
+		w.line()
+	case atom.Br:
+		w.line()
+	case atom.Td, atom.Th:
+		if previousElement(n) != nil {
+			w.sb.WriteString(" | ")
+			w.pending = false
+		}
+		w.children(n)
+	case atom.Tr:
+		w.line()
+		w.children(n)
+		w.line()
+	case atom.Pre:
+		w.block()
+		w.inPre = true
+		w.children(n)
+		w.inPre = false
+		w.block()
+	case atom.Img:
+		if alt := collapseSpaces(attr(n, "alt")); alt != "" && len(w.lists) == 0 {
+			w.text("[Image: " + alt + "]")
+		}
+	default:
+		if blockElements[n.DataAtom] {
+			w.block()
+			w.children(n)
+			w.block()
+		} else {
+			w.children(n)
+		}
+	}
+}
+
+func (w *markdownWriter) children(n *html.Node) {
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		w.render(c)
+	}
+}
+
+func (w *markdownWriter) text(s string) {
+	if w.inPre {
+		w.sb.WriteString(s)
+		return
+	}
+
+	if s != "" && isSpace(s[0]) {
+		w.pending = true
+	}
+
+	for i, word := range strings.Fields(s) {
+		if i > 0 || w.pending {
+			w.space()
+		}
+		w.sb.WriteString(word)
+		w.pending = false
+		w.marker = false
+	}
+
+	if s != "" && isSpace(s[len(s)-1]) {
+		w.pending = true
+	}
+}
+
+// space writes a single space unless the output is at the start of a line
+func (w *markdownWriter) space() {
+	out := w.sb.String()
+	if w.marker || out == "" || strings.HasSuffix(out, "\n") || strings.HasSuffix(out, " ") {
+		return
+	}
+	w.sb.WriteByte(' ')
+}
+
+// line ends the current line (if any)
+func (w *markdownWriter) line() {
+	w.pending = false
+	if w.marker {
+		return // keep the text of a list item on the same line as its marker
+	}
+	out := w.sb.String()
+	if out != "" && !strings.HasSuffix(out, "\n") {
+		w.sb.WriteByte('\n')
+	}
+}
+
+// block separates blocks with an empty line
+func (w *markdownWriter) block() {
+	w.line()
+	if len(w.lists) > 0 {
+		return // keep list items tight
+	}
+	out := w.sb.String()
+	if out != "" && !strings.HasSuffix(out, "\n\n") {
+		w.sb.WriteByte('\n')
+	}
+}
+
+func (w *markdownWriter) listMarker() string {
+	if len(w.lists) == 0 {
+		return "- "
+	}
+
+	list := w.lists[len(w.lists)-1]
+	indent := strings.Repeat("  ", len(w.lists)-1)
+	if list.ordered {
+		list.index++
+		return fmt.Sprintf("%s%d. ", indent, list.index)
+	}
+	return indent + "- "
+}
+
+// Node helpers
+
+// walk visits the nodes depth-first; returning false skips the children of a node
+func walk(n *html.Node, visit func(*html.Node) bool) {
+	if !visit(n) {
+		return
+	}
+	for c := n.FirstChild; c != nil; c = c.NextSibling {
+		walk(c, visit)
+	}
+}
+
+func findFirst(n *html.Node, a atom.Atom) *html.Node {
+	var found *html.Node
+	walk(n, func(c *html.Node) bool {
+		if found != nil {
+			return false
+		}
+		if c.Type == html.ElementNode && c.DataAtom == a {
+			found = c
+			return false
+		}
+		return true
+	})
+	return found
+}
+
+func pageTitle(doc *html.Node) string {
+	title := findFirst(doc, atom.Title)
+	if title == nil || title.FirstChild == nil {
+		return ""
+	}
+
+	// Drop the site name, e.g. "Pancakes | My Food Blog"
+	text := collapseSpaces(title.FirstChild.Data)
+	for _, sep := range []string{" | ", " - ", " – ", " — "} {
+		if i := strings.Index(text, sep); i > 0 {
+			text = text[:i]
+		}
+	}
+	return text
+}
+
+// textContent returns the text of the node, ignoring skipped elements
+func (f *contentFilter) textContent(n *html.Node) string {
+	var sb strings.Builder
+	walk(n, func(c *html.Node) bool {
+		if f.skip(c) {
+			return false
+		}
+		if c.Type == html.TextNode {
+			sb.WriteString(c.Data)
+			sb.WriteByte(' ')
+		}
+		return true
+	})
+	return sb.String()
+}
+
+// textLength returns the number of characters of text in the node, ignoring the elements that
+// never hold content
+func textLength(n *html.Node) int {
+	var length int
+	walk(n, func(c *html.Node) bool {
+		if isSkipped(c) {
+			return false
+		}
+		if c.Type == html.TextNode {
+			length += len(strings.TrimSpace(c.Data))
+		}
+		return true
+	})
+	return length
+}
+
+func previousElement(n *html.Node) *html.Node {
+	for p := n.PrevSibling; p != nil; p = p.PrevSibling {
+		if p.Type == html.ElementNode {
+			return p
+		}
+	}
+	return nil
+}
+
+func attr(n *html.Node, key string) string {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return a.Val
+		}
+	}
+	return ""
+}
+
+func hasAttr(n *html.Node, key string) bool {
+	for _, a := range n.Attr {
+		if a.Key == key {
+			return true
+		}
+	}
+	return false
+}
+
+// Text helpers
+
+func isSpace(b byte) bool {
+	return b == ' ' || b == '\t' || b == '\n' || b == '\r' || b == '\f'
+}
+
+func collapseSpaces(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// tidyLines trims trailing spaces and collapses repeated empty lines
+func tidyLines(s string) string {
+	lines := strings.Split(s, "\n")
+	result := make([]string, 0, len(lines))
+
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t")
+		if line == "" && (len(result) == 0 || result[len(result)-1] == "") {
+			continue
+		}
+		result = append(result, line)
+	}
+
+	return strings.TrimSpace(strings.Join(result, "\n"))
+}
+
+// estimateTokens approximates the number of tokens the text will use
+func estimateTokens(s string) int {
+	return (utf8.RuneCountInString(s) + _CharsPerToken - 1) / _CharsPerToken
+}
+
+// truncateToTokens cuts the text at a line boundary so it fits the token budget
+func truncateToTokens(s string, maxTokens int) string {
+	if maxTokens <= 0 || estimateTokens(s) <= maxTokens {
+		return s
+	}
+
+	maxRunes := maxTokens*_CharsPerToken - utf8.RuneCountInString("\n"+_TruncatedMarker)
+	if maxRunes <= 0 {
+		return ""
+	}
+
+	// Cut at a rune boundary
+	cut := len(s)
+	for i := range s {
+		if maxRunes == 0 {
+			cut = i
+			break
+		}
+		maxRunes--
+	}
+	truncated := s[:cut]
+
+	// Prefer ending at a complete line
+	if i := strings.LastIndexByte(truncated, '\n'); i > 0 {
+		truncated = truncated[:i]
+	}
+
+	return strings.TrimRight(truncated, " \n") + "\n" + _TruncatedMarker
+}
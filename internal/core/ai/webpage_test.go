@@ -0,0 +1,170 @@
+package ai
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var updateGolden = flag.Bool("update", false, "update the golden files of the webpage extraction tests")
+
+func TestExtractWebpageText_Golden(t *testing.T) {
+	pages, err := filepath.Glob(filepath.Join("testdata", "webpages", "*.html"))
+	require.NoError(t, err)
+	require.NotEmpty(t, pages, "no sample pages found")
+
+	for _, page := range pages {
+		t.Run(filepath.Base(page), func(t *testing.T) {
+			file, err := os.Open(page)
+			require.NoError(t, err)
+			defer file.Close()
+
+			got, err := extractWebpageText(file, _WebpageTokenBudget)
+			require.NoError(t, err)
+
+			goldenPath := strings.TrimSuffix(page, ".html") + ".golden"
+			if *updateGolden {
+				require.NoError(t, os.WriteFile(goldenPath, []byte(got+"\n"), 0o644))
+			}
+
+			want, err := os.ReadFile(goldenPath)
+			require.NoError(t, err, "missing golden file, run the tests with -update")
+			assert.Equal(t, strings.TrimSuffix(string(want), "\n"), got)
+		})
+	}
+}
+
+func TestExtractWebpageText(t *testing.T) {
+	tests := []struct {
+		name        string
+		html        string
+		contains    []string
+		notContains []string
+	}{
+		{
+			name:        "self-closing and void tags",
+			html:        `<body><div class="recipe"><p>Mix the flour<br/>and the milk</p><img src="a.jpg"/><script src="x.js"/></script><p>Bake it.</p></div></body>`,
+			contains:    []string{"Mix the flour\nand the milk", "Bake it."},
+			notContains: []string{"x.js", "<"},
+		},
+		{
+			name:        "unquoted attributes",
+			html:        `<body><div class=comments><p>Nice!</p></div><div class=recipe data-id=12 aria-label=Recipe><p>Stir well</p></div></body>`,
+			contains:    []string{"Stir well"},
+			notContains: []string{"Nice!", "data-id", "aria-label"},
+		},
+		{
+			name:        "nested skipped elements",
+			html:        `<body><main><p>Keep</p><nav><div><nav><a href="/">Home</a></nav></div></nav><p>Also keep</p></main></body>`,
+			contains:    []string{"Keep", "Also keep"},
+			notContains: []string{"Home"},
+		},
+		{
+			name:     "lists",
+			html:     `<body><div class="recipe"><ul><li>Salt</li><li>Pepper</li></ul><ol><li>First</li><li>Second</li></ol></div></body>`,
+			contains: []string{"- Salt\n- Pepper", "1. First\n2. Second"},
+		},
+		{
+			name:     "entities",
+			html:     `<body><p>Salt &amp; pepper, 200&nbsp;&deg;C</p></body>`,
+			contains: []string{"Salt & pepper, 200"},
+		},
+		{
+			name:     "body with boilerplate class",
+			html:     `<body class="has-sidebar"><p>Whisk the eggs with the sugar until light and fluffy.</p></body>`,
+			contains: []string{"Whisk the eggs"},
+		},
+		{
+			name:        "wrapper with boilerplate class",
+			html:        `<body><div id="main" class="has-sidebar"><p>Whisk the eggs with the sugar until light and fluffy.</p><div class="sidebar"><p>Popular posts this week on the blog</p></div></div></body>`,
+			contains:    []string{"Whisk the eggs"},
+			notContains: []string{"Popular posts"},
+		},
+		{
+			name:     "article with boilerplate class",
+			html:     `<body><article class="share-enabled"><p>Whisk the eggs with the sugar until light and fluffy.</p></article></body>`,
+			contains: []string{"Whisk the eggs"},
+		},
+		{
+			name: "no body",
+			html: `just some text`,
+			contains: []string{
+				"just some text",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := extractWebpageText(strings.NewReader(tt.html), _WebpageTokenBudget)
+			require.NoError(t, err)
+
+			for _, s := range tt.contains {
+				assert.Contains(t, got, s)
+			}
+			for _, s := range tt.notContains {
+				assert.NotContains(t, got, s)
+			}
+		})
+	}
+}
+
+func TestExtractWebpageText_TokenBudget(t *testing.T) {
+	var sb strings.Builder
+	sb.WriteString("<body><article>")
+	for range 500 {
+		sb.WriteString("<p>Stir the sauce slowly, season with salt, and let it simmer for another minute.</p>")
+	}
+	sb.WriteString("</article></body>")
+
+	got, err := extractWebpageText(strings.NewReader(sb.String()), 100)
+	require.NoError(t, err)
+
+	assert.LessOrEqual(t, estimateTokens(got), 100)
+	assert.True(t, strings.HasSuffix(got, _TruncatedMarker))
+	assert.Contains(t, got, "Stir the sauce slowly")
+}
+
+func TestTruncateToTokens(t *testing.T) {
+	assert.Equal(t, "short", truncateToTokens("short", 10))
+	assert.Equal(t, "unlimited", truncateToTokens("unlimited", 0))
+
+	got := truncateToTokens("åäö åäö åäö\nåäö åäö åäö\nåäö åäö åäö", 5)
+	assert.True(t, utf8.ValidString(got))
+	assert.LessOrEqual(t, estimateTokens(got), 5)
+}
+
+func FuzzExtractWebpageText(f *testing.F) {
+	pages, _ := filepath.Glob(filepath.Join("testdata", "webpages", "*.html"))
+	for _, page := range pages {
+		if data, err := os.ReadFile(page); err == nil {
+			f.Add(string(data), 200)
+		}
+	}
+	f.Add(`<p class=a id=b>unquoted</p><br/><img/>`, 10)
+	f.Add(`<div><ul><li><ol><li>deep</ol></ul></div>`, 0)
+	f.Add(`<table><tr><td>1 dl<td>milk</table>`, 50)
+	f.Add("<p>\xff\xfe invalid utf-8</p>", 5)
+
+	f.Fuzz(func(t *testing.T, page string, maxTokens int) {
+		maxTokens = maxTokens % 10000
+
+		got, err := extractWebpageText(strings.NewReader(page), maxTokens)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if maxTokens > 0 && estimateTokens(got) > maxTokens {
+			t.Errorf("output exceeds token budget: %d > %d", estimateTokens(got), maxTokens)
+		}
+		if strings.HasPrefix(got, "\n") || strings.HasSuffix(got, "\n") {
+			t.Errorf("output is not trimmed: %q", got)
+		}
+	})
+}
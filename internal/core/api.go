@@ -10,7 +10,11 @@ import (
 	"net/url"
 	"strconv"
 	"strings"
+	"time"
 
+	"github.com/AntonLuning/RecipeBank/internal/core/ai"
+	"github.com/AntonLuning/RecipeBank/internal/core/auth"
+	"github.com/AntonLuning/RecipeBank/internal/core/metrics"
 	"github.com/AntonLuning/RecipeBank/internal/core/service"
 	"github.com/AntonLuning/RecipeBank/internal/core/storage"
 	"github.com/AntonLuning/RecipeBank/pkg/core/models"
@@ -21,20 +25,40 @@ import (
 
 type apiFunc func(context.Context, http.ResponseWriter, *http.Request) error
 
+const (
+	// Maximum size of a JSON request body
+	_MaxBodySize = 1 << 20 // 1 MB
+	// Maximum size of a JSON request body with base64 encoded files (several images or a PDF)
+	_MaxUploadBodySize = 20 << 20 // 20 MB
+)
+
 type APIServer struct {
-	addr    string
-	service service.Service
-	mux     *http.ServeMux
+	addr          string
+	service       service.Service
+	households    service.Households
+	authenticator *auth.Authenticator
+	rateLimiter   *RateLimiter
+	mux           *http.ServeMux
 }
 
-func NewAPIServer(addr string, service service.Service) *APIServer {
+// NewAPIServer creates the API server. Requests that change data require an access token of the
+// authenticator. Without an authenticator (e.g. in tests) the access tokens are not checked and
+// requests are anonymous, the service still decides what anonymous callers can do (e.g. they
+// cannot create recipes). Without a rate limiter requests are not limited.
+func NewAPIServer(addr string, service service.Service, households service.Households, authenticator *auth.Authenticator, rateLimiter *RateLimiter) *APIServer {
 	server := APIServer{
-		addr:    addr,
-		service: service,
+		addr:          addr,
+		service:       service,
+		households:    households,
+		authenticator: authenticator,
+		rateLimiter:   rateLimiter,
 	}
 
 	mux := http.NewServeMux()
-	mux.Handle("/api/v1/", http.StripPrefix("/api/v1", server.v1Mux()))
+	mux.Handle("/api/v1/", observeRequests(traceRequests(http.StripPrefix("/api/v1", server.requireAuth(server.rateLimit(server.v1Mux()))))))
+
+	// Prometheus metrics, which are not part of the versioned API
+	mux.Handle("GET /metrics", metrics.Handler())
 
 	// Swagger documentation route
 	mux.HandleFunc("/", httpSwagger.WrapHandler)
@@ -59,17 +83,65 @@ func (s *APIServer) v1Mux() http.Handler {
 	v1Mux.HandleFunc("POST /recipe", makeHTTPHandlerFunc(s.handlePostRecipe))
 	v1Mux.HandleFunc("PUT /recipe/{id}", makeHTTPHandlerFunc(s.handlePutRecipe))
 	v1Mux.HandleFunc("DELETE /recipe/{id}", makeHTTPHandlerFunc(s.handleDeleteRecipe))
+	v1Mux.HandleFunc("GET /recipe/duplicates", makeHTTPHandlerFunc(s.handleGetDuplicateRecipes))
+	v1Mux.HandleFunc("POST /recipe/{id}/share", makeHTTPHandlerFunc(s.handlePostRecipeShare))
+	v1Mux.HandleFunc("GET /recipe/{id}/shares", makeHTTPHandlerFunc(s.handleGetRecipeShares))
+	v1Mux.HandleFunc("DELETE /recipe/{id}/shares/{share_id}", makeHTTPHandlerFunc(s.handleDeleteRecipeShare))
+
+	// Collections, meal plans and grocery lists, in the active household
+	v1Mux.HandleFunc("GET /collections", makeHTTPHandlerFunc(s.handleGetCollections))
+	v1Mux.HandleFunc("GET /collections/{id}", makeHTTPHandlerFunc(s.handleGetCollection))
+	v1Mux.HandleFunc("POST /collections", makeHTTPHandlerFunc(s.handlePostCollection))
+	v1Mux.HandleFunc("PUT /collections/{id}", makeHTTPHandlerFunc(s.handlePutCollection))
+	v1Mux.HandleFunc("DELETE /collections/{id}", makeHTTPHandlerFunc(s.handleDeleteCollection))
+	v1Mux.HandleFunc("GET /meal-plans", makeHTTPHandlerFunc(s.handleGetMealPlans))
+	v1Mux.HandleFunc("GET /meal-plans/{id}", makeHTTPHandlerFunc(s.handleGetMealPlan))
+	v1Mux.HandleFunc("POST /meal-plans", makeHTTPHandlerFunc(s.handlePostMealPlan))
+	v1Mux.HandleFunc("PUT /meal-plans/{id}", makeHTTPHandlerFunc(s.handlePutMealPlan))
+	v1Mux.HandleFunc("DELETE /meal-plans/{id}", makeHTTPHandlerFunc(s.handleDeleteMealPlan))
+	v1Mux.HandleFunc("GET /grocery-lists", makeHTTPHandlerFunc(s.handleGetGroceryLists))
+	v1Mux.HandleFunc("GET /grocery-lists/{id}", makeHTTPHandlerFunc(s.handleGetGroceryList))
+	v1Mux.HandleFunc("POST /grocery-lists", makeHTTPHandlerFunc(s.handlePostGroceryList))
+	v1Mux.HandleFunc("PUT /grocery-lists/{id}", makeHTTPHandlerFunc(s.handlePutGroceryList))
+	v1Mux.HandleFunc("DELETE /grocery-lists/{id}", makeHTTPHandlerFunc(s.handleDeleteGroceryList))
+
+	// Shared recipes, which need no authentication
+	v1Mux.HandleFunc("GET /shared/{token}", makeHTTPHandlerFunc(s.handleGetSharedRecipe))
 
 	// AI-powered recipe creation
 	v1Mux.HandleFunc("POST /recipe/ai/from-image", makeHTTPHandlerFunc(s.handlePostRecipeFromImage))
+	v1Mux.HandleFunc("POST /recipe/ai/from-images", makeHTTPHandlerFunc(s.handlePostRecipeFromImages))
+	v1Mux.HandleFunc("POST /recipe/ai/from-pdf", makeHTTPHandlerFunc(s.handlePostRecipeFromPDF))
 	v1Mux.HandleFunc("POST /recipe/ai/from-url", makeHTTPHandlerFunc(s.handlePostRecipeFromURL))
+	v1Mux.HandleFunc("POST /recipe/{id}/ai/suggest-tags", makeHTTPHandlerFunc(s.handlePostSuggestTags))
+	v1Mux.HandleFunc("POST /recipe/{id}/ai/translate", makeHTTPHandlerFunc(s.handlePostTranslateRecipe))
+	v1Mux.HandleFunc("POST /recipe/{id}/ai/substitutions", makeHTTPHandlerFunc(s.handlePostSubstitutions))
+
+	// AI usage and cost accounting
+	v1Mux.HandleFunc("GET /ai/usage", makeHTTPHandlerFunc(s.handleGetAIUsage))
+
+	// Authentication
+	v1Mux.HandleFunc("POST /auth/login", makeHTTPHandlerFunc(s.handlePostLogin))
+	v1Mux.HandleFunc("POST /auth/refresh", makeHTTPHandlerFunc(s.handlePostRefresh))
+	v1Mux.HandleFunc("POST /auth/api-keys", makeHTTPHandlerFunc(s.handlePostAPIKey))
+	v1Mux.HandleFunc("GET /auth/api-keys", makeHTTPHandlerFunc(s.handleGetAPIKeys))
+	v1Mux.HandleFunc("DELETE /auth/api-keys/{id}", makeHTTPHandlerFunc(s.handleDeleteAPIKey))
+
+	// Households
+	v1Mux.HandleFunc("POST /households", makeHTTPHandlerFunc(s.handlePostHousehold))
+	v1Mux.HandleFunc("GET /households", makeHTTPHandlerFunc(s.handleGetHouseholds))
+	v1Mux.HandleFunc("GET /households/{id}", makeHTTPHandlerFunc(s.handleGetHousehold))
+	v1Mux.HandleFunc("POST /households/{id}/invites", makeHTTPHandlerFunc(s.handlePostHouseholdInvite))
+	v1Mux.HandleFunc("POST /households/join", makeHTTPHandlerFunc(s.handlePostJoinHousehold))
+	v1Mux.HandleFunc("PUT /households/{id}/members/{user_id}", makeHTTPHandlerFunc(s.handlePutHouseholdMember))
+	v1Mux.HandleFunc("DELETE /households/{id}/members/{user_id}", makeHTTPHandlerFunc(s.handleDeleteHouseholdMember))
 
 	return v1Mux
 }
 
 // GetRecipes godoc
 // @Summary Get all recipes
-// @Description Get a paginated list of recipes with optional filtering
+// @Description Get a paginated list of the recipes the caller can see (public recipes without logging in), with optional filtering
 // @Tags recipes
 // @Accept json
 // @Produce json
@@ -79,6 +151,13 @@ func (s *APIServer) v1Mux() http.Handler {
 // @Param cook_time query int false "Filter by maximum cook time in minutes"
 // @Param ingredients query string false "Filter by ingredient names (comma-separated)"
 // @Param tags query string false "Filter by tags (comma-separated)"
+// @Param translation_of query string false "Filter by the ID of the original recipe, to list its translations"
+// @Param language query string false "Filter by the language of translated copies"
+// @Param source_type query string false "Filter by the origin of the recipes" Enums(manual, image, url, pdf, file)
+// @Param source_url query string false "Filter by a part of the source URL, e.g. the domain"
+// @Param source_model query string false "Filter by the AI model the recipes were extracted with"
+// @Param mine query bool false "Only recipes owned by the caller (requires logging in)"
+// @Param X-Household-ID header string false "Active household, only its recipes are listed"
 // @Success 200 {object} models.APIResponse{data=models.RecipePage} "Successful response"
 // @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid query parameters"
 // @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
@@ -99,7 +178,7 @@ func (s *APIServer) handleGetRecipes(ctx context.Context, w http.ResponseWriter,
 
 // GetRecipeByID godoc
 // @Summary Get recipe by ID
-// @Description Get a specific recipe by its ID
+// @Description Get a specific recipe by its ID. Recipes the caller cannot see are not found.
 // @Tags recipes
 // @Accept json
 // @Produce json
@@ -130,9 +209,14 @@ func (s *APIServer) handleGetRecipeByID(ctx context.Context, w http.ResponseWrit
 // @Accept json
 // @Produce json
 // @Param recipe body models.CreateRecipeRequest true "Recipe information"
+// @Param force query bool false "Create the recipe even if it is likely a duplicate of an existing recipe"
+// @Param X-Household-ID header string false "Active household, the recipe is created in it"
 // @Success 201 {object} models.APIResponse{data=models.Recipe} "Recipe created successfully"
 // @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid input data"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 409 {object} models.APIResponse{error=models.APIError{details=models.DuplicateDetails}} "Likely duplicate of existing recipes"
 // @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Security BearerAuth
 // @Router /recipe [post]
 func (s *APIServer) handlePostRecipe(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	var req models.CreateRecipeRequest
@@ -140,6 +224,11 @@ func (s *APIServer) handlePostRecipe(ctx context.Context, w http.ResponseWriter,
 		return err
 	}
 
+	ctx, err := createContext(ctx, r)
+	if err != nil {
+		return err
+	}
+
 	recipe := createRecipeFromRequest(req)
 
 	createdRecipe, err := s.service.CreateRecipe(ctx, recipe)
@@ -152,7 +241,7 @@ func (s *APIServer) handlePostRecipe(ctx context.Context, w http.ResponseWriter,
 
 // PutRecipe godoc
 // @Summary Update a recipe
-// @Description Update an existing recipe with the provided information
+// @Description Update an existing recipe with the provided information. Only the owner of the recipe and admins can update it.
 // @Tags recipes
 // @Accept json
 // @Produce json
@@ -160,8 +249,11 @@ func (s *APIServer) handlePostRecipe(ctx context.Context, w http.ResponseWriter,
 // @Param recipe body models.UpdateRecipeRequest true "Updated recipe information"
 // @Success 200 {object} models.APIResponse{data=models.Recipe} "Recipe updated successfully"
 // @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid input data or recipe ID"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Not the owner of the recipe"
 // @Failure 404 {object} models.APIResponse{error=models.APIError} "Recipe not found"
 // @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Security BearerAuth
 // @Router /recipe/{id} [put]
 func (s *APIServer) handlePutRecipe(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	id := r.PathValue("id")
@@ -186,15 +278,18 @@ func (s *APIServer) handlePutRecipe(ctx context.Context, w http.ResponseWriter,
 
 // DeleteRecipe godoc
 // @Summary Delete a recipe
-// @Description Delete a recipe by its ID
+// @Description Delete a recipe by its ID. Only the owner of the recipe and admins can delete it. The translations of the recipe are deleted with it.
 // @Tags recipes
 // @Accept json
 // @Produce json
 // @Param id path string true "Recipe ID"
 // @Success 204 {object} models.APIResponse "Recipe deleted successfully"
 // @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid recipe ID"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Not the owner of the recipe"
 // @Failure 404 {object} models.APIResponse{error=models.APIError} "Recipe not found"
 // @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Security BearerAuth
 // @Router /recipe/{id} [delete]
 func (s *APIServer) handleDeleteRecipe(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	id := r.PathValue("id")
@@ -209,6 +304,23 @@ func (s *APIServer) handleDeleteRecipe(ctx context.Context, w http.ResponseWrite
 	return writeSuccessResponse(w, http.StatusNoContent, nil)
 }
 
+// GetDuplicateRecipes godoc
+// @Summary Get duplicate recipes
+// @Description Get the clusters of existing recipes the caller can see that are likely duplicates of each other: recipes imported from the same URL, or with similar titles and ingredients. Translations are not included.
+// @Tags recipes
+// @Produce json
+// @Success 200 {object} models.APIResponse{data=[]models.DuplicateCluster} "Successful response"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /recipe/duplicates [get]
+func (s *APIServer) handleGetDuplicateRecipes(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	clusters, err := s.service.GetDuplicateRecipes(ctx)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, clusters)
+}
+
 // PostRecipeFromImage godoc
 // @Summary Create recipe from image using AI
 // @Description Create a new recipe by analyzing an image using AI
@@ -216,9 +328,20 @@ func (s *APIServer) handleDeleteRecipe(ctx context.Context, w http.ResponseWrite
 // @Accept json
 // @Produce json
 // @Param request body models.CreateRecipeFromImageRequest true "Image data and type"
+// @Param no_cache query bool false "Ignore cached AI results and analyze the content again"
+// @Param force query bool false "Create the recipe even if it is likely a duplicate of an existing recipe"
 // @Success 201 {object} models.APIResponse{data=models.Recipe} "Recipe created successfully from image"
 // @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid input data or AI processing error"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 402 {object} models.APIResponse{error=models.APIError} "Monthly AI budget exceeded"
+// @Failure 409 {object} models.APIResponse{error=models.APIError{details=models.DuplicateDetails}} "Likely duplicate of existing recipes"
+// @Failure 422 {object} models.APIResponse{error=models.APIError} "AI refused to process the content"
+// @Failure 429 {object} models.APIResponse{error=models.APIError} "AI provider rate limit exceeded"
+// @Failure 502 {object} models.APIResponse{error=models.APIError} "Empty or incomplete AI response"
+// @Failure 503 {object} models.APIResponse{error=models.APIError} "AI provider unavailable"
+// @Failure 504 {object} models.APIResponse{error=models.APIError} "AI provider timed out"
 // @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Security BearerAuth
 // @Router /recipe/ai/from-image [post]
 func (s *APIServer) handlePostRecipeFromImage(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	var req models.CreateRecipeFromImageRequest
@@ -226,6 +349,16 @@ func (s *APIServer) handlePostRecipeFromImage(ctx context.Context, w http.Respon
 		return err
 	}
 
+	ctx, err := aiContext(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	ctx, err = createContext(ctx, r)
+	if err != nil {
+		return err
+	}
+
 	recipe, err := s.service.CreateRecipeFromImage(ctx, req.Image, req.ImageType)
 	if err != nil {
 		return err
@@ -234,16 +367,121 @@ func (s *APIServer) handlePostRecipeFromImage(ctx context.Context, w http.Respon
 	return writeSuccessResponse(w, http.StatusCreated, recipe)
 }
 
+// PostRecipeFromImages godoc
+// @Summary Create recipe from several images using AI
+// @Description Create a new recipe by analyzing an ordered list of images of the same recipe (e.g. the front and back of a recipe card) using AI
+// @Tags ai-recipes
+// @Accept json
+// @Produce json
+// @Param request body models.CreateRecipeFromImagesRequest true "Images in reading order"
+// @Param no_cache query bool false "Ignore cached AI results and analyze the content again"
+// @Param force query bool false "Create the recipe even if it is likely a duplicate of an existing recipe"
+// @Success 201 {object} models.APIResponse{data=models.Recipe} "Recipe created successfully from images"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid input data or AI processing error"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 413 {object} models.APIResponse{error=models.APIError} "Request body too large"
+// @Failure 402 {object} models.APIResponse{error=models.APIError} "Monthly AI budget exceeded"
+// @Failure 409 {object} models.APIResponse{error=models.APIError{details=models.DuplicateDetails}} "Likely duplicate of existing recipes"
+// @Failure 422 {object} models.APIResponse{error=models.APIError} "AI refused to process the content"
+// @Failure 429 {object} models.APIResponse{error=models.APIError} "AI provider rate limit exceeded"
+// @Failure 502 {object} models.APIResponse{error=models.APIError} "Empty or incomplete AI response"
+// @Failure 503 {object} models.APIResponse{error=models.APIError} "AI provider unavailable"
+// @Failure 504 {object} models.APIResponse{error=models.APIError} "AI provider timed out"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Security BearerAuth
+// @Router /recipe/ai/from-images [post]
+func (s *APIServer) handlePostRecipeFromImages(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req models.CreateRecipeFromImagesRequest
+	if err := s.parseJSONBodyWithLimit(w, r, &req, _MaxUploadBodySize); err != nil {
+		return err
+	}
+
+	ctx, err := aiContext(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	ctx, err = createContext(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	recipe, err := s.service.CreateRecipeFromImages(ctx, req.Images)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusCreated, recipe)
+}
+
+// PostRecipeFromPDF godoc
+// @Summary Create recipe from PDF using AI
+// @Description Create a new recipe by analyzing a PDF using AI. The text layer is used when present, otherwise the images of the scanned pages (at most 10).
+// @Tags ai-recipes
+// @Accept json
+// @Produce json
+// @Param request body models.CreateRecipeFromPDFRequest true "Base64 encoded PDF"
+// @Param no_cache query bool false "Ignore cached AI results and analyze the content again"
+// @Param force query bool false "Create the recipe even if it is likely a duplicate of an existing recipe"
+// @Success 201 {object} models.APIResponse{data=models.Recipe} "Recipe created successfully from PDF"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid input data or AI processing error"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 413 {object} models.APIResponse{error=models.APIError} "Request body too large"
+// @Failure 402 {object} models.APIResponse{error=models.APIError} "Monthly AI budget exceeded"
+// @Failure 409 {object} models.APIResponse{error=models.APIError{details=models.DuplicateDetails}} "Likely duplicate of existing recipes"
+// @Failure 422 {object} models.APIResponse{error=models.APIError} "AI refused to process the content"
+// @Failure 429 {object} models.APIResponse{error=models.APIError} "AI provider rate limit exceeded"
+// @Failure 502 {object} models.APIResponse{error=models.APIError} "Empty or incomplete AI response"
+// @Failure 503 {object} models.APIResponse{error=models.APIError} "AI provider unavailable"
+// @Failure 504 {object} models.APIResponse{error=models.APIError} "AI provider timed out"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Security BearerAuth
+// @Router /recipe/ai/from-pdf [post]
+func (s *APIServer) handlePostRecipeFromPDF(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req models.CreateRecipeFromPDFRequest
+	if err := s.parseJSONBodyWithLimit(w, r, &req, _MaxUploadBodySize); err != nil {
+		return err
+	}
+
+	ctx, err := aiContext(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	ctx, err = createContext(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	recipe, err := s.service.CreateRecipeFromPDF(ctx, req.PDF)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusCreated, recipe)
+}
+
 // PostRecipeFromURL godoc
 // @Summary Create recipe from URL using AI
-// @Description Create a new recipe by analyzing content from a URL using AI
+// @Description Create a new recipe by analyzing a webpage or a direct link to an image (JPEG/PNG) or a PDF using AI. Images are also stored as the recipe photo.
 // @Tags ai-recipes
 // @Accept json
 // @Produce json
 // @Param request body models.CreateRecipeFromUrlRequest true "URL to analyze"
+// @Param no_cache query bool false "Ignore cached AI results and analyze the content again"
+// @Param force query bool false "Create the recipe even if it is likely a duplicate of an existing recipe"
 // @Success 201 {object} models.APIResponse{data=models.Recipe} "Recipe created successfully from URL"
 // @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid input data or AI processing error"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 402 {object} models.APIResponse{error=models.APIError} "Monthly AI budget exceeded"
+// @Failure 409 {object} models.APIResponse{error=models.APIError{details=models.DuplicateDetails}} "Likely duplicate of existing recipes"
+// @Failure 422 {object} models.APIResponse{error=models.APIError} "AI refused to process the content"
+// @Failure 429 {object} models.APIResponse{error=models.APIError} "AI provider rate limit exceeded"
+// @Failure 502 {object} models.APIResponse{error=models.APIError} "Empty or incomplete AI response"
+// @Failure 503 {object} models.APIResponse{error=models.APIError} "AI provider unavailable"
+// @Failure 504 {object} models.APIResponse{error=models.APIError} "AI provider timed out"
 // @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Security BearerAuth
 // @Router /recipe/ai/from-url [post]
 func (s *APIServer) handlePostRecipeFromURL(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
 	var req models.CreateRecipeFromUrlRequest
@@ -251,6 +489,16 @@ func (s *APIServer) handlePostRecipeFromURL(ctx context.Context, w http.Response
 		return err
 	}
 
+	ctx, err := aiContext(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	ctx, err = createContext(ctx, r)
+	if err != nil {
+		return err
+	}
+
 	recipe, err := s.service.CreateRecipeFromURL(ctx, req.URL)
 	if err != nil {
 		return err
@@ -259,13 +507,183 @@ func (s *APIServer) handlePostRecipeFromURL(ctx context.Context, w http.Response
 	return writeSuccessResponse(w, http.StatusCreated, recipe)
 }
 
+// PostSuggestTags godoc
+// @Summary Suggest tags for a recipe using AI
+// @Description Suggest tags and cuisine, course and dietary labels for an existing recipe using AI, preferring the tags already used in the collection. The recipe is not changed; update it with the tags to keep.
+// @Tags ai-recipes
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param no_cache query bool false "Ignore cached AI results and suggest tags again"
+// @Success 200 {object} models.APIResponse{data=models.TagSuggestion} "Suggested tags"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid recipe ID or AI processing error"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 402 {object} models.APIResponse{error=models.APIError} "Monthly AI budget exceeded"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Recipe not found"
+// @Failure 422 {object} models.APIResponse{error=models.APIError} "AI refused to process the content"
+// @Failure 429 {object} models.APIResponse{error=models.APIError} "AI provider rate limit exceeded"
+// @Failure 502 {object} models.APIResponse{error=models.APIError} "Empty or incomplete AI response"
+// @Failure 503 {object} models.APIResponse{error=models.APIError} "AI provider unavailable"
+// @Failure 504 {object} models.APIResponse{error=models.APIError} "AI provider timed out"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Security BearerAuth
+// @Router /recipe/{id}/ai/suggest-tags [post]
+func (s *APIServer) handlePostSuggestTags(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+
+	ctx, err := aiContext(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	suggestion, err := s.service.SuggestTags(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, suggestion)
+}
+
+// PostTranslateRecipe godoc
+// @Summary Translate a recipe using AI
+// @Description Translate a recipe into another language using AI. The translation is stored as a copy linked to the original recipe (translation_of), with the same quantities, units, tags and image. An earlier translation into the same language is replaced. Only the owner of the recipe and admins can translate it, the translation has the same owner and visibility. List the translations of a recipe with GET /recipe?translation_of={id}.
+// @Tags ai-recipes
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param request body models.TranslateRecipeRequest true "Target language"
+// @Param no_cache query bool false "Ignore cached AI results and translate again"
+// @Success 200 {object} models.APIResponse{data=models.Recipe} "Translated copy of the recipe"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid input data or AI processing error"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 402 {object} models.APIResponse{error=models.APIError} "Monthly AI budget exceeded"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Not the owner of the recipe"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Recipe not found"
+// @Failure 422 {object} models.APIResponse{error=models.APIError} "AI refused to process the content"
+// @Failure 429 {object} models.APIResponse{error=models.APIError} "AI provider rate limit exceeded"
+// @Failure 502 {object} models.APIResponse{error=models.APIError} "Empty or incomplete AI response"
+// @Failure 503 {object} models.APIResponse{error=models.APIError} "AI provider unavailable"
+// @Failure 504 {object} models.APIResponse{error=models.APIError} "AI provider timed out"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Security BearerAuth
+// @Router /recipe/{id}/ai/translate [post]
+func (s *APIServer) handlePostTranslateRecipe(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+
+	var req models.TranslateRecipeRequest
+	if err := s.parseJSONBody(w, r, &req); err != nil {
+		return err
+	}
+
+	ctx, err := aiContext(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	translation, err := s.service.TranslateRecipe(ctx, id, req.Language)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, translation)
+}
+
+// PostSubstitutions godoc
+// @Summary Suggest substitutes for an ingredient of a recipe
+// @Description Suggest substitutes for an ingredient of a recipe that meet the dietary constraints, with the quantities adjusted to the recipe. The substitutes are suggested by AI, or taken from a built-in substitution table of common ingredients when AI is not enabled.
+// @Tags ai-recipes
+// @Accept json
+// @Produce json
+// @Param id path string true "Recipe ID"
+// @Param request body models.SubstitutionsRequest true "Ingredient and dietary constraints"
+// @Param no_cache query bool false "Ignore cached AI results and suggest substitutes again"
+// @Success 200 {object} models.APIResponse{data=models.Substitutions} "Substitution options"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid input data or AI processing error"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 402 {object} models.APIResponse{error=models.APIError} "Monthly AI budget exceeded"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Recipe not found"
+// @Failure 422 {object} models.APIResponse{error=models.APIError} "AI refused to process the content"
+// @Failure 429 {object} models.APIResponse{error=models.APIError} "AI provider rate limit exceeded"
+// @Failure 502 {object} models.APIResponse{error=models.APIError} "Empty or incomplete AI response"
+// @Failure 503 {object} models.APIResponse{error=models.APIError} "AI provider unavailable"
+// @Failure 504 {object} models.APIResponse{error=models.APIError} "AI provider timed out"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Security BearerAuth
+// @Router /recipe/{id}/ai/substitutions [post]
+func (s *APIServer) handlePostSubstitutions(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+
+	var req models.SubstitutionsRequest
+	if err := s.parseJSONBody(w, r, &req); err != nil {
+		return err
+	}
+
+	ctx, err := aiContext(ctx, r)
+	if err != nil {
+		return err
+	}
+
+	substitutions, err := s.service.SuggestSubstitutions(ctx, id, req.Ingredient, req.Dietary)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, substitutions)
+}
+
+// GetAIUsage godoc
+// @Summary Get AI usage
+// @Description Get the aggregated AI token usage and estimated cost of the imports in a period, in total and per model. Only admins can see the AI usage.
+// @Tags ai-usage
+// @Produce json
+// @Param from query string false "Start of the period (RFC 3339 or YYYY-MM-DD), defaults to the start of the current month"
+// @Param to query string false "End of the period (RFC 3339 or YYYY-MM-DD, inclusive), defaults to now"
+// @Success 200 {object} models.APIResponse{data=models.AIUsageSummary} "Successful response"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid query parameters"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Not an admin"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /ai/usage [get]
+func (s *APIServer) handleGetAIUsage(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	q := r.URL.Query()
+	now := time.Now().UTC()
+
+	from, err := parseTimeParam(q, "from", time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC), false)
+	if err != nil {
+		return err
+	}
+	to, err := parseTimeParam(q, "to", now, true)
+	if err != nil {
+		return err
+	}
+
+	usage, err := s.service.GetAIUsage(ctx, from, to)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, usage)
+}
+
 func makeHTTPHandlerFunc(apiFn apiFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		ctx := context.Background()
+		ctx := r.Context()
 
 		slog.Info("Incoming request", "method", r.Method, "path", r.URL.Path)
 
-		if err := apiFn(ctx, w, r); err != nil {
+		var err error
+		defer func() { setRoutedRequest(r, err) }()
+
+		if err = apiFn(ctx, w, r); err != nil {
 			slog.Error("Request failed", "error", err)
 
 			switch {
@@ -285,16 +703,32 @@ func makeHTTPHandlerFunc(apiFn apiFunc) http.HandlerFunc {
 				writeErrorResponse(w, http.StatusBadRequest, "missing_path_param", msg)
 			case errors.Is(err, ErrRequestBodyTooLarge):
 				writeErrorResponse(w, http.StatusRequestEntityTooLarge, "request_too_large", "The request body exceeds the maximum allowed size")
-			case errors.Is(err, service.ErrValidation):
+			case errors.Is(err, service.ErrValidation), errors.Is(err, auth.ErrValidation):
 				writeErrorResponse(w, http.StatusBadRequest, "validation_error", extractValidationDetails(err.Error()))
 			case errors.Is(err, service.ErrInvalidInput):
 				writeErrorResponse(w, http.StatusBadRequest, "invalid_input", extractInputErrorDetails(err.Error()))
+			case errors.Is(err, auth.ErrInvalidCredentials):
+				writeErrorResponse(w, http.StatusUnauthorized, "invalid_credentials", "The username or password is incorrect")
+			case errors.Is(err, auth.ErrAccountDisabled):
+				writeErrorResponse(w, http.StatusForbidden, "account_disabled", "The account is disabled")
+			case errors.Is(err, auth.ErrAccountLocked):
+				writeLockedErrorResponse(w, err)
+			case errors.Is(err, auth.ErrInvalidToken):
+				writeErrorResponse(w, http.StatusUnauthorized, "invalid_token", "The token is invalid or has expired")
+			case errors.Is(err, service.ErrForbidden):
+				writeErrorResponse(w, http.StatusForbidden, "forbidden", extractForbiddenDetails(err.Error()))
+			case errors.Is(err, service.ErrDuplicate):
+				writeDuplicateErrorResponse(w, err)
 			case errors.Is(err, service.ErrAIUnsupported):
 				writeErrorResponse(w, http.StatusBadRequest, "ai_unsupported", "AI processing is not supported/enabled")
 			case errors.Is(err, service.ErrAI):
-				writeErrorResponse(w, http.StatusBadRequest, "ai_error", "An error occurred while processing the AI request")
+				writeAIErrorResponse(w, err)
 			case errors.Is(err, storage.ErrInvalidID):
 				writeErrorResponse(w, http.StatusBadRequest, "invalid_id", "The provided ID is invalid or malformed")
+			case errors.Is(err, storage.ErrAlreadyExists):
+				writeErrorResponse(w, http.StatusConflict, "already_exists", fmt.Sprintf(
+					"The %s already exists", extractResourceTypeFromError(err.Error()),
+				))
 			case errors.Is(err, storage.ErrNotFound):
 				writeErrorResponse(w, http.StatusNotFound, "not_found", fmt.Sprintf(
 					"The requested %s was not found", extractResourceTypeFromError(err.Error()),
@@ -309,16 +743,24 @@ func makeHTTPHandlerFunc(apiFn apiFunc) http.HandlerFunc {
 // Helper functions
 
 func (s *APIServer) parseJSONBody(w http.ResponseWriter, r *http.Request, v interface{}) error {
-	r.Body = http.MaxBytesReader(w, r.Body, 1<<20) // 1 MB limit
+	return s.parseJSONBodyWithLimit(w, r, v, _MaxBodySize)
+}
+
+func (s *APIServer) parseJSONBodyWithLimit(w http.ResponseWriter, r *http.Request, v interface{}, limit int64) error {
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
 	err := json.NewDecoder(r.Body).Decode(v)
 	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return fmt.Errorf("%w: limit is %d bytes", ErrRequestBodyTooLarge, maxBytesErr.Limit)
+		}
 		return fmt.Errorf("%w: %v", ErrJSONDecode, err)
 	}
 	return nil
 }
 
 func createRecipeFromRequest(req models.RecipeRequest) *models.Recipe {
-	return &models.Recipe{
+	recipe := &models.Recipe{
 		Title:       req.Title,
 		Description: req.Description,
 		Ingredients: req.Ingredients,
@@ -327,7 +769,12 @@ func createRecipeFromRequest(req models.RecipeRequest) *models.Recipe {
 		Servings:    req.Servings,
 		Tags:        req.Tags,
 		Image:       req.Image,
+		Visibility:  req.Visibility,
 	}
+	if req.Source != nil {
+		recipe.Source = &models.RecipeSource{Type: req.Source.Type, URL: req.Source.URL}
+	}
+	return recipe
 }
 
 func writeJSON(w http.ResponseWriter, statusCode int, content any) error {
@@ -353,6 +800,45 @@ func writeErrorResponse(w http.ResponseWriter, status int, code, message string)
 	})
 }
 
+// writeDuplicateErrorResponse writes the error response of a likely duplicate recipe, with the
+// existing recipes it duplicates
+func writeDuplicateErrorResponse(w http.ResponseWriter, err error) error {
+	details := models.DuplicateDetails{Candidates: []models.DuplicateCandidate{}}
+	var duplicateErr *service.DuplicateError
+	if errors.As(err, &duplicateErr) {
+		details.Candidates = duplicateErr.Candidates
+	}
+
+	return writeJSON(w, http.StatusConflict, models.APIResponse{
+		Success: false,
+		Error: &models.APIError{
+			Code:    "duplicate_recipe",
+			Message: "The recipe is likely a duplicate of existing recipes, create it anyway with force=true",
+			Details: details,
+		},
+	})
+}
+
+// writeAIErrorResponse writes the error response of a failed AI request, with a code for the reason
+func writeAIErrorResponse(w http.ResponseWriter, err error) error {
+	switch {
+	case errors.Is(err, ai.ErrRateLimited):
+		return writeErrorResponse(w, http.StatusTooManyRequests, "ai_rate_limited", "The AI provider is rate limiting requests, please try again later")
+	case errors.Is(err, ai.ErrTimeout):
+		return writeErrorResponse(w, http.StatusGatewayTimeout, "ai_timeout", "The AI provider did not respond in time")
+	case errors.Is(err, ai.ErrUnavailable), errors.Is(err, ai.ErrCircuitOpen):
+		return writeErrorResponse(w, http.StatusServiceUnavailable, "ai_unavailable", "The AI provider is currently unavailable, please try again later")
+	case errors.Is(err, ai.ErrRefused):
+		return writeErrorResponse(w, http.StatusUnprocessableEntity, "ai_refused", "The AI refused to process the content")
+	case errors.Is(err, ai.ErrBudgetExceeded):
+		return writeErrorResponse(w, http.StatusPaymentRequired, "ai_budget_exceeded", "The monthly AI budget has been reached")
+	case errors.Is(err, ai.ErrEmptyResponse):
+		return writeErrorResponse(w, http.StatusBadGateway, "ai_empty_response", "The AI returned an empty or incomplete response")
+	default:
+		return writeErrorResponse(w, http.StatusBadRequest, "ai_error", "An error occurred while processing the AI request")
+	}
+}
+
 func (s *APIServer) parseQueryParams(r *http.Request, query *models.GetRecipesQuery) error {
 	q := r.URL.Query()
 
@@ -386,11 +872,85 @@ func (s *APIServer) parseQueryParams(r *http.Request, query *models.GetRecipesQu
 		filter.Tags = strings.Split(tags, ",")
 	}
 
+	filter.TranslationOf = q.Get("translation_of")
+	filter.Language = strings.ToLower(strings.TrimSpace(q.Get("language")))
+	filter.SourceType = strings.ToLower(strings.TrimSpace(q.Get("source_type")))
+	filter.SourceURL = strings.TrimSpace(q.Get("source_url"))
+	filter.SourceModel = strings.TrimSpace(q.Get("source_model"))
+
+	filter.Mine, err = parseBoolParam(q, "mine", false)
+	if err != nil {
+		return err
+	}
+
 	query.Filter = filter
 
 	return nil
 }
 
+func parseBoolParam(q url.Values, key string, defaultValue bool) (bool, error) {
+	str := q.Get(key)
+	if str == "" {
+		return defaultValue, nil
+	}
+
+	val, err := strconv.ParseBool(str)
+	if err != nil {
+		return false, fmt.Errorf("%w: %s parameter is invalid", ErrInvalidQueryParams, key)
+	}
+
+	return val, nil
+}
+
+// parseTimeParam parses an RFC 3339 time or a date. A date as the end of a period (endOfDay)
+// includes the whole day.
+func parseTimeParam(q url.Values, key string, defaultValue time.Time, endOfDay bool) (time.Time, error) {
+	str := q.Get(key)
+	if str == "" {
+		return defaultValue, nil
+	}
+
+	if val, err := time.Parse(time.RFC3339, str); err == nil {
+		return val, nil
+	}
+
+	val, err := time.Parse(time.DateOnly, str)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s parameter is invalid", ErrInvalidQueryParams, key)
+	}
+	if endOfDay {
+		val = val.AddDate(0, 0, 1)
+	}
+
+	return val, nil
+}
+
+// aiContext applies the query parameters of AI-powered requests to the context
+func aiContext(ctx context.Context, r *http.Request) (context.Context, error) {
+	noCache, err := parseBoolParam(r.URL.Query(), "no_cache", false)
+	if err != nil {
+		return nil, err
+	}
+	if noCache {
+		ctx = ai.WithoutCache(ctx)
+	}
+
+	return ctx, nil
+}
+
+// createContext applies the query parameters of recipe creation requests to the context
+func createContext(ctx context.Context, r *http.Request) (context.Context, error) {
+	force, err := parseBoolParam(r.URL.Query(), "force", false)
+	if err != nil {
+		return nil, err
+	}
+	if force {
+		ctx = service.AllowDuplicates(ctx)
+	}
+
+	return ctx, nil
+}
+
 func parseIntParam(q url.Values, key string, defaultValue int) (int, error) {
 	str := q.Get(key)
 	if str == "" {
@@ -425,6 +985,13 @@ func extractValidationDetails(errMsg string) string {
 	return strings.TrimPrefix(errMsg, "validation error: ")
 }
 
+func extractForbiddenDetails(errMsg string) string {
+	if _, details, found := strings.Cut(errMsg, service.ErrForbidden.Error()+": "); found {
+		return details
+	}
+	return "You are not allowed to perform this action"
+}
+
 func extractInputErrorDetails(_ string) string {
 	// TODO:This can be enhanced to parse specific input error types
 
@@ -436,7 +1003,7 @@ func extractResourceTypeFromError(errMsg string) string {
 	resourceType := "resource"
 
 	lowerMsg := strings.ToLower(errMsg)
-	for _, knownType := range []string{"recipe", "ingredient", "tag"} {
+	for _, knownType := range []string{"recipe share", "shared recipe", "recipe", "ingredient", "tag", "household invite", "household member", "household"} {
 		if strings.Contains(lowerMsg, knownType) {
 			resourceType = knownType
 			break
@@ -0,0 +1,114 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/auth"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+)
+
+// PostAPIKey godoc
+// @Summary Create an API key
+// @Description Create an API key for scripts and integrations, which acts as the logged in user within its scopes: "recipes:read", "recipes:write" and "ai:import". The key is only returned in this response. API keys cannot manage API keys.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateAPIKeyRequest true "API key to create"
+// @Success 201 {object} models.APIResponse{data=models.CreatedAPIKey} "Created API key"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid name, scopes or expiry"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Authenticated with an API key"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /auth/api-keys [post]
+func (s *APIServer) handlePostAPIKey(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req models.CreateAPIKeyRequest
+	if err := s.parseJSONBody(w, r, &req); err != nil {
+		return err
+	}
+
+	user, err := s.authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	key, err := s.authenticator.CreateAPIKey(ctx, user, req.Name, req.Scopes, req.ExpiresAt)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusCreated, key)
+}
+
+// GetAPIKeys godoc
+// @Summary List API keys
+// @Description List the API keys of the logged in user, including revoked and expired keys, the newest first
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=[]models.APIKey} "API keys"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Authenticated with an API key"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /auth/api-keys [get]
+func (s *APIServer) handleGetAPIKeys(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	user, err := s.authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	keys, err := s.authenticator.GetAPIKeys(ctx, user)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, keys)
+}
+
+// DeleteAPIKey godoc
+// @Summary Revoke an API key
+// @Description Revoke an API key of the logged in user, it cannot be used again
+// @Tags auth
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "API key ID"
+// @Success 204 "API key revoked"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid ID"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Authenticated with an API key"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "No active API key with the ID"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /auth/api-keys/{id} [delete]
+func (s *APIServer) handleDeleteAPIKey(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+
+	user, err := s.authenticatedUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	if err := s.authenticator.RevokeAPIKey(ctx, user, id); err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusNoContent, nil)
+}
+
+// authenticatedUser returns the user of the request, which requireAuth has authenticated
+func (s *APIServer) authenticatedUser(ctx context.Context) (*models.User, error) {
+	if s.authenticator == nil {
+		return nil, errors.New("authentication is not configured")
+	}
+
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return nil, errors.New("request is not authenticated")
+	}
+	return user, nil
+}
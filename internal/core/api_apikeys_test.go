@@ -0,0 +1,199 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/auth"
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestAPIKeyScopes(t *testing.T) {
+	user := &models.User{ID: primitive.NewObjectID(), Username: "anton"}
+	lastUsedAt := time.Now()
+
+	mockService := new(MockService)
+	mockUsers := new(MockUserStorage)
+	mockKeys := new(MockAPIKeyStorage)
+	apiServer := NewAPIServer(":8080", mockService, nil, newTestAuthenticator(t, mockUsers, mockKeys, 0), nil)
+
+	// The test keys are told apart by their scopes, in place of their hashes
+	withKey := func(req *http.Request, scopes ...string) *http.Request {
+		mockKeys.On("GetAPIKeyByHash", mock.Anything, mock.Anything).Return(&models.APIKey{
+			ID: primitive.NewObjectID(), UserID: user.ID, Scopes: scopes, LastUsedAt: &lastUsedAt,
+		}, nil).Once()
+		mockUsers.On("GetUserByID", mock.Anything, user.ID.Hex()).Return(user, nil).Once()
+		req.Header.Set("Authorization", "Bearer "+auth.APIKeyPrefix+"test-key")
+		return req
+	}
+	reqBody := `{"title":"Pancakes","ingredients":[{"name":"Flour"}],"steps":["Fry"]}`
+
+	t.Run("Write scope", func(t *testing.T) {
+		authenticated := mock.MatchedBy(func(ctx context.Context) bool {
+			identity, ok := auth.IdentityFromContext(ctx)
+			return ok && identity.User.ID == user.ID && identity.APIKeyID != nil
+		})
+		mockService.On("CreateRecipe", authenticated, mock.AnythingOfType("*models.Recipe")).Return(&models.Recipe{Title: "Pancakes"}, nil).Once()
+
+		req := withKey(httptest.NewRequest(http.MethodPost, "/api/v1/recipe", bytes.NewBufferString(reqBody)), models.ScopeRecipesWrite)
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Read scope cannot write", func(t *testing.T) {
+		req := withKey(httptest.NewRequest(http.MethodPost, "/api/v1/recipe", bytes.NewBufferString(reqBody)), models.ScopeRecipesRead)
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "insufficient_scope")
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), models.ScopeRecipesWrite)
+	})
+
+	t.Run("AI import scope", func(t *testing.T) {
+		req := withKey(httptest.NewRequest(http.MethodPost, "/api/v1/recipe/ai/from-url", bytes.NewBufferString(`{"url":"https://example.com"}`)), models.ScopeRecipesWrite)
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		mockService.On("CreateRecipeFromURL", mock.Anything, "https://example.com").Return(&models.Recipe{Title: "Pancakes"}, nil).Once()
+		req = withKey(httptest.NewRequest(http.MethodPost, "/api/v1/recipe/ai/from-url", bytes.NewBufferString(`{"url":"https://example.com"}`)), models.ScopeAIImport)
+		w = httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+	})
+
+	t.Run("Read scope", func(t *testing.T) {
+		recipeID := primitive.NewObjectID()
+		req := withKey(httptest.NewRequest(http.MethodGet, "/api/v1/recipe/"+recipeID.Hex(), nil), models.ScopeAIImport)
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusForbidden, w.Code)
+
+		mockService.On("GetRecipe", mock.Anything, recipeID.Hex()).Return(&models.Recipe{ID: recipeID}, nil).Once()
+		req = withKey(httptest.NewRequest(http.MethodGet, "/api/v1/recipe/"+recipeID.Hex(), nil), models.ScopeRecipesRead)
+		w = httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Invalid key", func(t *testing.T) {
+		mockKeys.On("GetAPIKeyByHash", mock.Anything, mock.Anything).Return(nil, storage.ErrNotFound).Once()
+
+		// Invalid credentials are rejected even where anonymous requests are allowed
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/recipe", nil)
+		req.Header.Set("Authorization", "Bearer "+auth.APIKeyPrefix+"unknown")
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_token")
+	})
+
+	t.Run("Keys cannot manage keys", func(t *testing.T) {
+		req := withKey(httptest.NewRequest(http.MethodGet, "/api/v1/auth/api-keys", nil), models.APIKeyScopes...)
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		mockKeys.AssertNotCalled(t, "GetAPIKeys", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAPIKeyManagement(t *testing.T) {
+	hash, err := auth.HashPassword("secret password")
+	require.NoError(t, err)
+	user := &models.User{ID: primitive.NewObjectID(), Username: "anton", PasswordHash: hash}
+
+	mockUsers := new(MockUserStorage)
+	mockKeys := new(MockAPIKeyStorage)
+	authenticator := newTestAuthenticator(t, mockUsers, mockKeys, 0)
+	apiServer := NewAPIServer(":8080", new(MockService), nil, authenticator, nil)
+
+	mockUsers.On("GetUserByUsername", mock.Anything, "anton").Return(user, nil).Once()
+	tokens, err := authenticator.Login(context.Background(), "anton", "secret password", "")
+	require.NoError(t, err)
+	bearer := "Bearer " + tokens.AccessToken
+
+	t.Run("Create", func(t *testing.T) {
+		mockKeys.On("CreateAPIKey", mock.Anything, mock.MatchedBy(func(key *models.APIKey) bool {
+			return key.UserID == user.ID && key.Name == "Kitchen shortcuts"
+		})).Return(&models.APIKey{ID: primitive.NewObjectID(), UserID: user.ID, Name: "Kitchen shortcuts", Scopes: []string{models.ScopeAIImport}}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/api-keys", bytes.NewBufferString(`{"name":"Kitchen shortcuts","scopes":["ai:import"]}`))
+		req.Header.Set("Authorization", bearer)
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		require.Equal(t, http.StatusCreated, w.Code)
+		var response struct {
+			Data models.CreatedAPIKey `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Contains(t, response.Data.Key, auth.APIKeyPrefix)
+		assert.Equal(t, "Kitchen shortcuts", response.Data.Name)
+		assert.NotContains(t, w.Body.String(), "key_hash")
+		mockKeys.AssertExpectations(t)
+	})
+
+	t.Run("Invalid scope", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/api-keys", bytes.NewBufferString(`{"name":"Script","scopes":["admin"]}`))
+		req.Header.Set("Authorization", bearer)
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "validation_error")
+	})
+
+	t.Run("List", func(t *testing.T) {
+		mockKeys.On("GetAPIKeys", mock.Anything, user.ID.Hex()).Return([]models.APIKey{{ID: primitive.NewObjectID(), Name: "Kitchen shortcuts"}}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/auth/api-keys", nil)
+		req.Header.Set("Authorization", bearer)
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "Kitchen shortcuts")
+
+		// Listing requires authentication, unlike viewing recipes
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/auth/api-keys", nil)
+		w = httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		keyID := primitive.NewObjectID()
+		mockKeys.On("RevokeAPIKey", mock.Anything, keyID.Hex(), user.ID.Hex()).Return(nil).Once()
+		mockKeys.On("RevokeAPIKey", mock.Anything, "other", user.ID.Hex()).Return(storage.ErrInvalidID).Once()
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/auth/api-keys/"+keyID.Hex(), nil)
+		req.Header.Set("Authorization", bearer)
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusNoContent, w.Code)
+
+		req = httptest.NewRequest(http.MethodDelete, "/api/v1/auth/api-keys/other", nil)
+		req.Header.Set("Authorization", bearer)
+		w = httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+
+		mockKeys.AssertExpectations(t)
+	})
+}
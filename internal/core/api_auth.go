@@ -0,0 +1,230 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/auth"
+	"github.com/AntonLuning/RecipeBank/internal/core/service"
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+)
+
+// HouseholdHeader selects the active household of a request by its ID. Recipes are then viewed,
+// created and changed in the household, otherwise outside households.
+const HouseholdHeader = "X-Household-ID"
+
+// Routes that are used to authenticate, so they are public
+var publicRoutes = map[string]bool{
+	"/auth/login":   true,
+	"/auth/refresh": true,
+}
+
+// isPublicRoute reports whether a route needs no authentication, even if credentials are sent.
+// Shared recipes are public, the token of the link grants access.
+func isPublicRoute(r *http.Request) bool {
+	return publicRoutes[r.URL.Path] || (isViewingMethod(r.Method) && strings.HasPrefix(r.URL.Path, "/shared/"))
+}
+
+// PostLogin godoc
+// @Summary Log in
+// @Description Log in with the pre-shared credentials of a user. Returns a short-lived access token for the Authorization header of requests that change data, and a refresh token for new tokens when it expires. After too many failed logins the account (or client) is locked for a while, see the Retry-After header.
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.LoginRequest true "Credentials"
+// @Success 200 {object} models.APIResponse{data=models.AuthTokens} "Issued tokens"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid JSON"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Invalid username or password"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Account disabled"
+// @Failure 429 {object} models.APIResponse{error=models.APIError} "Account locked after too many failed logins"
+// @Header 429 {integer} Retry-After "Seconds until the lock expires"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /auth/login [post]
+func (s *APIServer) handlePostLogin(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req models.LoginRequest
+	if err := s.parseJSONBody(w, r, &req); err != nil {
+		return err
+	}
+
+	if s.authenticator == nil {
+		return errors.New("authentication is not configured")
+	}
+
+	tokens, err := s.authenticator.Login(ctx, req.Username, req.Password, s.clientIP(r))
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, tokens)
+}
+
+// PostRefresh godoc
+// @Summary Refresh tokens
+// @Description Exchange a refresh token for new tokens
+// @Tags auth
+// @Accept json
+// @Produce json
+// @Param request body models.RefreshTokenRequest true "Refresh token"
+// @Success 200 {object} models.APIResponse{data=models.AuthTokens} "Issued tokens"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid JSON"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Invalid or expired refresh token"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /auth/refresh [post]
+func (s *APIServer) handlePostRefresh(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req models.RefreshTokenRequest
+	if err := s.parseJSONBody(w, r, &req); err != nil {
+		return err
+	}
+
+	if s.authenticator == nil {
+		return errors.New("authentication is not configured")
+	}
+
+	tokens, err := s.authenticator.Refresh(ctx, req.RefreshToken)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, tokens)
+}
+
+// requireAuth authenticates requests with an access token or API key, and puts the identity into
+// the request context. Requests that change data require authentication, viewing is also allowed
+// anonymously. The identity must have the scope of the route (see requiredScope), and be a member
+// of the active household if one is selected.
+func (s *APIServer) requireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.authenticator == nil || isPublicRoute(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scope, anonymous := requiredScope(r)
+
+		token, ok := bearerToken(r)
+		if !ok {
+			if anonymous && r.Header.Get(HouseholdHeader) == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			w.Header().Set("WWW-Authenticate", `Bearer realm="RecipeBank"`)
+			writeErrorResponse(w, http.StatusUnauthorized, "unauthorized", "An access token or API key is required")
+			return
+		}
+
+		// Every check counts as failed until the credentials turn out to be valid, so parallel
+		// guesses are limited as well
+		if !s.reserveAuthentication(w, r) {
+			return
+		}
+		identity, err := s.authenticator.Authenticate(r.Context(), token)
+		if err == nil {
+			s.releaseAuthentication(r)
+		}
+		if errors.Is(err, auth.ErrInvalidToken) {
+			slog.Info("Rejected credentials", "method", r.Method, "path", r.URL.Path, "error", err.Error())
+			w.Header().Set("WWW-Authenticate", `Bearer realm="RecipeBank", error="invalid_token"`)
+			writeErrorResponse(w, http.StatusUnauthorized, "invalid_token", "The token or API key is invalid or has expired")
+			return
+		}
+		if err != nil {
+			slog.Error("Unable to authenticate request", "error", err.Error())
+			writeErrorResponse(w, http.StatusInternalServerError, "internal_error", "An internal server error occurred")
+			return
+		}
+
+		if !identity.HasScope(scope) {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="RecipeBank", error="insufficient_scope", scope=%q`, scope))
+			writeErrorResponse(w, http.StatusForbidden, "insufficient_scope", fmt.Sprintf("The API key lacks the %s scope", scope))
+			return
+		}
+
+		ctx := r.Context()
+		if householdID := r.Header.Get(HouseholdHeader); householdID != "" {
+			var ok bool
+			if ctx, ok = s.withActiveHousehold(w, r, identity, householdID); !ok {
+				return
+			}
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithIdentity(ctx, identity)))
+	})
+}
+
+// withActiveHousehold makes the household the active household of the identity, and scopes the
+// recipe storage of the returned context to it. It writes the error response and returns false
+// if the household cannot be selected.
+func (s *APIServer) withActiveHousehold(w http.ResponseWriter, r *http.Request, identity *auth.Identity, householdID string) (context.Context, bool) {
+	if s.households == nil {
+		writeErrorResponse(w, http.StatusBadRequest, "households_unsupported", "Households are not enabled")
+		return nil, false
+	}
+
+	household, role, err := s.households.GetRole(r.Context(), householdID, identity.User)
+	switch {
+	case errors.Is(err, storage.ErrInvalidID), errors.Is(err, storage.ErrNotFound), errors.Is(err, service.ErrForbidden):
+		writeErrorResponse(w, http.StatusForbidden, "invalid_household", fmt.Sprintf("The %s header is not a household you are a member of", HouseholdHeader))
+		return nil, false
+	case err != nil:
+		slog.Error("Unable to get active household", "error", err.Error())
+		writeErrorResponse(w, http.StatusInternalServerError, "internal_error", "An internal server error occurred")
+		return nil, false
+	}
+
+	identity.HouseholdID = household.ID
+	identity.HouseholdRole = role
+	return storage.WithHousehold(r.Context(), household.ID), true
+}
+
+// requiredScope returns the scope a request requires, and whether it is also allowed anonymously
+func requiredScope(r *http.Request) (string, bool) {
+	switch {
+	case strings.HasPrefix(r.URL.Path, "/auth/api-keys"):
+		return auth.ScopeAPIKeys, false
+	case strings.HasPrefix(r.URL.Path, "/households"):
+		return auth.ScopeHouseholds, false
+	case r.URL.Path == "/ai/usage":
+		// The usage and spend of the whole instance, for admins only
+		return models.ScopeAIImport, false
+	case isViewingMethod(r.Method):
+		return models.ScopeRecipesRead, true
+	case strings.Contains(r.URL.Path, "/ai/"):
+		return models.ScopeAIImport, false
+	default:
+		return models.ScopeRecipesWrite, false
+	}
+}
+
+func isViewingMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// bearerToken returns the token of the Authorization header
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
+
+// writeLockedErrorResponse writes the error response of a locked login, with the seconds until
+// the lock expires in the Retry-After header
+func writeLockedErrorResponse(w http.ResponseWriter, err error) error {
+	var lockedErr *auth.LockedError
+	if errors.As(err, &lockedErr) {
+		retryAfter := max(1, int(math.Ceil(time.Until(lockedErr.Until).Seconds())))
+		w.Header().Set("Retry-After", fmt.Sprint(retryAfter))
+	}
+
+	return writeErrorResponse(w, http.StatusTooManyRequests, "account_locked", "The account is locked after too many failed logins, please try again later")
+}
@@ -0,0 +1,323 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/auth"
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockUserStorage is a mock implementation of the storage.UserStorage interface
+type MockUserStorage struct {
+	mock.Mock
+}
+
+func (m *MockUserStorage) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserStorage) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	args := m.Called(ctx, username)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserStorage) GetUsers(ctx context.Context) ([]models.User, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func (m *MockUserStorage) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
+	args := m.Called(ctx, user)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserStorage) SetUserPassword(ctx context.Context, id string, passwordHash string) error {
+	args := m.Called(ctx, id, passwordHash)
+	return args.Error(0)
+}
+
+func (m *MockUserStorage) SetUserDisabled(ctx context.Context, id string, disabledAt *time.Time) error {
+	args := m.Called(ctx, id, disabledAt)
+	return args.Error(0)
+}
+
+func (m *MockUserStorage) SetUserAdmin(ctx context.Context, id string, admin bool) error {
+	args := m.Called(ctx, id, admin)
+	return args.Error(0)
+}
+
+func (m *MockUserStorage) DeleteUser(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserStorage) GetLoginAttempts(ctx context.Context, key string) (*models.LoginAttempts, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LoginAttempts), args.Error(1)
+}
+
+func (m *MockUserStorage) RecordFailedLogin(ctx context.Context, key string, window time.Duration) (*models.LoginAttempts, error) {
+	args := m.Called(ctx, key, window)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LoginAttempts), args.Error(1)
+}
+
+func (m *MockUserStorage) ReleaseLoginAttempt(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockUserStorage) LockLogin(ctx context.Context, key string, until time.Time) error {
+	args := m.Called(ctx, key, until)
+	return args.Error(0)
+}
+
+func (m *MockUserStorage) DeleteLoginAttempts(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+// MockAuditStorage is a mock implementation of the storage.AuditStorage interface
+type MockAuditStorage struct {
+	mock.Mock
+}
+
+func (m *MockAuditStorage) CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+// MockAPIKeyStorage is a mock implementation of the storage.APIKeyStorage interface
+type MockAPIKeyStorage struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyStorage) CreateAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyStorage) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	args := m.Called(ctx, keyHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyStorage) GetAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyStorage) RevokeAPIKey(ctx context.Context, id string, userID string) error {
+	args := m.Called(ctx, id, userID)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyStorage) SetAPIKeyLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	args := m.Called(ctx, id, usedAt)
+	return args.Error(0)
+}
+
+func newTestAuthenticator(t *testing.T, users storage.UserStorage, keys storage.APIKeyStorage, maxFailedLogins int) *auth.Authenticator {
+	authenticator, err := auth.NewAuthenticator(users, new(MockAuditStorage), keys, auth.Config{
+		Secret:          []byte("0123456789abcdef0123456789abcdef"),
+		Issuer:          "recipebank",
+		AccessTokenTTL:  15 * time.Minute,
+		RefreshTokenTTL: time.Hour,
+		MaxFailedLogins: maxFailedLogins,
+		LockoutDuration: 15 * time.Minute,
+	})
+	require.NoError(t, err)
+	return authenticator
+}
+
+func TestAuthentication(t *testing.T) {
+	hash, err := auth.HashPassword("secret password")
+	require.NoError(t, err)
+	user := &models.User{ID: primitive.NewObjectID(), Username: "anton", PasswordHash: hash}
+
+	mockService := new(MockService)
+	mockUsers := new(MockUserStorage)
+	apiServer := NewAPIServer(":8080", mockService, nil, newTestAuthenticator(t, mockUsers, new(MockAPIKeyStorage), 0), nil)
+
+	login := func(t *testing.T) models.AuthTokens {
+		mockUsers.On("GetUserByUsername", mock.Anything, "anton").Return(user, nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewBufferString(`{"username":"anton","password":"secret password"}`))
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Data models.AuthTokens `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		return response.Data
+	}
+
+	t.Run("Viewing is public", func(t *testing.T) {
+		recipeID := primitive.NewObjectID()
+		mockService.On("GetRecipe", mock.Anything, recipeID.Hex()).Return(&models.Recipe{ID: recipeID, Title: "Pancakes"}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/recipe/"+recipeID.Hex(), nil)
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Missing token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/recipe/"+primitive.NewObjectID().Hex(), nil)
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Header().Get("WWW-Authenticate"), "Bearer")
+		assert.Contains(t, w.Body.String(), "unauthorized")
+		mockService.AssertNotCalled(t, "DeleteRecipe", mock.Anything, mock.Anything)
+	})
+
+	t.Run("AI usage is not public", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/ai/usage", nil)
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		mockService.AssertNotCalled(t, "GetAIUsage", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid token", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe", bytes.NewBufferString(`{"title":"Pancakes"}`))
+		req.Header.Set("Authorization", "Bearer not-a-token")
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_token")
+		mockService.AssertNotCalled(t, "CreateRecipe", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Valid token", func(t *testing.T) {
+		tokens := login(t)
+		recipeID := primitive.NewObjectID()
+		authenticated := mock.MatchedBy(func(ctx context.Context) bool {
+			ctxUser, ok := auth.UserFromContext(ctx)
+			return ok && ctxUser.ID == user.ID
+		})
+		mockService.On("DeleteRecipe", authenticated, recipeID.Hex()).Return(nil).Once()
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/recipe/"+recipeID.Hex(), nil)
+		req.Header.Set("Authorization", "Bearer "+tokens.AccessToken)
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Wrong password", func(t *testing.T) {
+		mockUsers.On("GetUserByUsername", mock.Anything, "anton").Return(user, nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewBufferString(`{"username":"anton","password":"wrong"}`))
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_credentials")
+	})
+
+	t.Run("Disabled user", func(t *testing.T) {
+		disabledAt := time.Now()
+		disabled := *user
+		disabled.DisabledAt = &disabledAt
+		mockUsers.On("GetUserByUsername", mock.Anything, "anton").Return(&disabled, nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewBufferString(`{"username":"anton","password":"secret password"}`))
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "account_disabled")
+	})
+
+	t.Run("Refresh", func(t *testing.T) {
+		tokens := login(t)
+		mockUsers.On("GetUserByID", mock.Anything, user.ID.Hex()).Return(user, nil).Once()
+
+		body, err := json.Marshal(models.RefreshTokenRequest{RefreshToken: tokens.RefreshToken})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", bytes.NewBuffer(body))
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		// An access token cannot be used as a refresh token
+		body, err = json.Marshal(models.RefreshTokenRequest{RefreshToken: tokens.AccessToken})
+		require.NoError(t, err)
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/auth/refresh", bytes.NewBuffer(body))
+		w = httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_token")
+
+		mockUsers.AssertExpectations(t)
+	})
+}
+
+func TestLoginLockout(t *testing.T) {
+	mockUsers := new(MockUserStorage)
+	apiServer := NewAPIServer(":8080", new(MockService), nil, newTestAuthenticator(t, mockUsers, new(MockAPIKeyStorage), 5), nil)
+
+	lockedUntil := time.Now().Add(10 * time.Minute)
+	mockUsers.On("GetLoginAttempts", mock.Anything, "user:anton").Return(&models.LoginAttempts{Key: "user:anton", Failures: 5, LockedUntil: &lockedUntil}, nil).Once()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewBufferString(`{"username":"anton","password":"secret password"}`))
+	w := httptest.NewRecorder()
+	apiServer.mux.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), "account_locked")
+	retryAfter, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	assert.InDelta(t, 600, retryAfter, 2)
+	mockUsers.AssertNotCalled(t, "GetUserByUsername", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,268 @@
+package core
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/service"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+)
+
+// PostHousehold godoc
+// @Summary Create a household
+// @Description Create a household, such as a family, to share recipes in. The logged in user becomes its owner.
+// @Tags households
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param request body models.CreateHouseholdRequest true "Household to create"
+// @Success 201 {object} models.APIResponse{data=models.Household} "Created household"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid name"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Authenticated with an API key"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /households [post]
+func (s *APIServer) handlePostHousehold(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req models.CreateHouseholdRequest
+	if err := s.parseJSONBody(w, r, &req); err != nil {
+		return err
+	}
+
+	households, err := s.householdService()
+	if err != nil {
+		return err
+	}
+
+	household, err := households.CreateHousehold(ctx, req.Name)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusCreated, household)
+}
+
+// GetHouseholds godoc
+// @Summary List households
+// @Description List the households the logged in user is a member of, by name. Select one as the active household of a request with the X-Household-ID header.
+// @Tags households
+// @Produce json
+// @Security BearerAuth
+// @Success 200 {object} models.APIResponse{data=[]models.Household} "Households"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Authenticated with an API key"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /households [get]
+func (s *APIServer) handleGetHouseholds(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	households, err := s.householdService()
+	if err != nil {
+		return err
+	}
+
+	result, err := households.GetHouseholds(ctx)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, result)
+}
+
+// GetHousehold godoc
+// @Summary Get a household
+// @Description Get a household of the logged in user with its members
+// @Tags households
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Household ID"
+// @Success 200 {object} models.APIResponse{data=models.Household} "Household"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid ID"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Authenticated with an API key"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Household not found or not a member"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /households/{id} [get]
+func (s *APIServer) handleGetHousehold(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+
+	households, err := s.householdService()
+	if err != nil {
+		return err
+	}
+
+	household, err := households.GetHousehold(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, household)
+}
+
+// PostHouseholdInvite godoc
+// @Summary Create an invite link
+// @Description Create an invite link to join the household with a role, which can be accepted once before it expires (in 7 days by default). The token is only returned in this response. Only owners can invite.
+// @Tags households
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Household ID"
+// @Param request body models.CreateHouseholdInviteRequest true "Invite to create"
+// @Success 201 {object} models.APIResponse{data=models.CreatedHouseholdInvite} "Created invite"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid ID, role or expiry"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Not an owner of the household, or authenticated with an API key"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Household not found or not a member"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /households/{id}/invites [post]
+func (s *APIServer) handlePostHouseholdInvite(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+
+	var req models.CreateHouseholdInviteRequest
+	if err := s.parseJSONBody(w, r, &req); err != nil {
+		return err
+	}
+
+	households, err := s.householdService()
+	if err != nil {
+		return err
+	}
+
+	invite, err := households.CreateInvite(ctx, id, req.Role, req.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	invite.Link = "/api/v1/households/join?token=" + url.QueryEscape(invite.Token)
+
+	return writeSuccessResponse(w, http.StatusCreated, invite)
+}
+
+// PostJoinHousehold godoc
+// @Summary Accept an invite link
+// @Description Join the household of an invite link as the logged in user, with the role of the invite
+// @Tags households
+// @Produce json
+// @Security BearerAuth
+// @Param token query string true "Invite token"
+// @Success 200 {object} models.APIResponse{data=models.Household} "Joined household"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Missing token"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Authenticated with an API key"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Invite not found, accepted or expired"
+// @Failure 409 {object} models.APIResponse{error=models.APIError} "Already a member of the household"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /households/join [post]
+func (s *APIServer) handlePostJoinHousehold(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		return fmt.Errorf("%w: token parameter is required", ErrInvalidQueryParams)
+	}
+
+	households, err := s.householdService()
+	if err != nil {
+		return err
+	}
+
+	household, err := households.AcceptInvite(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, household)
+}
+
+// PutHouseholdMember godoc
+// @Summary Change the role of a member
+// @Description Change the role of a household member. Only owners can change roles, and the last owner cannot be demoted.
+// @Tags households
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Household ID"
+// @Param user_id path string true "User ID of the member"
+// @Param request body models.UpdateHouseholdMemberRequest true "New role"
+// @Success 200 {object} models.APIResponse{data=models.Household} "Updated household"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid ID or role, or the last owner"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Not an owner of the household, or authenticated with an API key"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Household or member not found"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /households/{id}/members/{user_id} [put]
+func (s *APIServer) handlePutHouseholdMember(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+	userID := r.PathValue("user_id")
+	if userID == "" {
+		return fmt.Errorf("%w: user_id parameter is required", ErrMissingPathParam)
+	}
+
+	var req models.UpdateHouseholdMemberRequest
+	if err := s.parseJSONBody(w, r, &req); err != nil {
+		return err
+	}
+
+	households, err := s.householdService()
+	if err != nil {
+		return err
+	}
+
+	household, err := households.UpdateMember(ctx, id, userID, req.Role)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, household)
+}
+
+// DeleteHouseholdMember godoc
+// @Summary Remove a member
+// @Description Remove a member from the household, or leave it with the own user ID. Only owners can remove other members, and the last owner cannot leave. The recipes of the member stay in the household.
+// @Tags households
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Household ID"
+// @Param user_id path string true "User ID of the member"
+// @Success 204 "Member removed"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid ID, or the last owner"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Not an owner of the household, or authenticated with an API key"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Household or member not found"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /households/{id}/members/{user_id} [delete]
+func (s *APIServer) handleDeleteHouseholdMember(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+	userID := r.PathValue("user_id")
+	if userID == "" {
+		return fmt.Errorf("%w: user_id parameter is required", ErrMissingPathParam)
+	}
+
+	households, err := s.householdService()
+	if err != nil {
+		return err
+	}
+
+	if err := households.RemoveMember(ctx, id, userID); err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusNoContent, nil)
+}
+
+// householdService returns the household service, which is not available without authentication
+func (s *APIServer) householdService() (service.Households, error) {
+	if s.households == nil || s.authenticator == nil {
+		return nil, errors.New("households are not configured")
+	}
+	return s.households, nil
+}
@@ -0,0 +1,235 @@
+package core
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/auth"
+	"github.com/AntonLuning/RecipeBank/internal/core/service"
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockHouseholds is a mock implementation of the service.Households interface
+type MockHouseholds struct {
+	mock.Mock
+}
+
+func (m *MockHouseholds) CreateHousehold(ctx context.Context, name string) (*models.Household, error) {
+	args := m.Called(ctx, name)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Household), args.Error(1)
+}
+
+func (m *MockHouseholds) GetHouseholds(ctx context.Context) ([]models.Household, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Household), args.Error(1)
+}
+
+func (m *MockHouseholds) GetHousehold(ctx context.Context, id string) (*models.Household, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Household), args.Error(1)
+}
+
+func (m *MockHouseholds) CreateInvite(ctx context.Context, id string, role string, expiresAt *time.Time) (*models.CreatedHouseholdInvite, error) {
+	args := m.Called(ctx, id, role, expiresAt)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CreatedHouseholdInvite), args.Error(1)
+}
+
+func (m *MockHouseholds) AcceptInvite(ctx context.Context, token string) (*models.Household, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Household), args.Error(1)
+}
+
+func (m *MockHouseholds) UpdateMember(ctx context.Context, id string, userID string, role string) (*models.Household, error) {
+	args := m.Called(ctx, id, userID, role)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Household), args.Error(1)
+}
+
+func (m *MockHouseholds) RemoveMember(ctx context.Context, id string, userID string) error {
+	args := m.Called(ctx, id, userID)
+	return args.Error(0)
+}
+
+func (m *MockHouseholds) GetRole(ctx context.Context, id string, user *models.User) (*models.Household, string, error) {
+	args := m.Called(ctx, id, user)
+	if args.Get(0) == nil {
+		return nil, "", args.Error(2)
+	}
+	return args.Get(0).(*models.Household), args.String(1), args.Error(2)
+}
+
+func TestActiveHousehold(t *testing.T) {
+	hash, err := auth.HashPassword("secret password")
+	require.NoError(t, err)
+	user := &models.User{ID: primitive.NewObjectID(), Username: "anton", PasswordHash: hash}
+	household := &models.Household{ID: primitive.NewObjectID(), Name: "The Smiths"}
+
+	mockService := new(MockService)
+	mockHouseholds := new(MockHouseholds)
+	mockUsers := new(MockUserStorage)
+	apiServer := NewAPIServer(":8080", mockService, mockHouseholds, newTestAuthenticator(t, mockUsers, new(MockAPIKeyStorage), 0), nil)
+
+	mockUsers.On("GetUserByUsername", mock.Anything, "anton").Return(user, nil).Once()
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/auth/login", bytes.NewBufferString(`{"username":"anton","password":"secret password"}`))
+	w := httptest.NewRecorder()
+	apiServer.mux.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	var login struct {
+		Data models.AuthTokens `json:"data"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &login))
+
+	t.Run("Member", func(t *testing.T) {
+		mockHouseholds.On("GetRole", mock.Anything, household.ID.Hex(), mock.MatchedBy(func(u *models.User) bool {
+			return u.ID == user.ID
+		})).Return(household, models.HouseholdRoleEditor, nil).Once()
+		inHousehold := mock.MatchedBy(func(ctx context.Context) bool {
+			identity, ok := auth.IdentityFromContext(ctx)
+			scope, scoped := storage.HouseholdFromContext(ctx)
+			return ok && identity.HouseholdID == household.ID && identity.HouseholdRole == models.HouseholdRoleEditor &&
+				scoped && scope == household.ID
+		})
+		mockService.On("GetRecipes", inHousehold, mock.Anything, 1, 10).Return(&models.RecipePage{}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/recipe", nil)
+		req.Header.Set("Authorization", "Bearer "+login.Data.AccessToken)
+		req.Header.Set(HouseholdHeader, household.ID.Hex())
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+		mockHouseholds.AssertExpectations(t)
+	})
+
+	t.Run("Not a member", func(t *testing.T) {
+		other := primitive.NewObjectID().Hex()
+		mockHouseholds.On("GetRole", mock.Anything, other, mock.Anything).
+			Return(nil, "", fmt.Errorf("%w: not a member of the household", service.ErrForbidden)).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/recipe", nil)
+		req.Header.Set("Authorization", "Bearer "+login.Data.AccessToken)
+		req.Header.Set(HouseholdHeader, other)
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_household")
+	})
+
+	t.Run("Anonymous", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/recipe", nil)
+		req.Header.Set(HouseholdHeader, household.ID.Hex())
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+	})
+
+	t.Run("Households require logging in", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/households", nil)
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusUnauthorized, w.Code)
+		mockHouseholds.AssertNotCalled(t, "GetHouseholds", mock.Anything)
+	})
+}
+
+func TestHouseholdHandlers(t *testing.T) {
+	user := &models.User{ID: primitive.NewObjectID(), Username: "anton"}
+	household := &models.Household{ID: primitive.NewObjectID(), Name: "The Smiths"}
+
+	mockHouseholds := new(MockHouseholds)
+	apiServer := NewAPIServer(":8080", new(MockService), mockHouseholds, newTestAuthenticator(t, new(MockUserStorage), new(MockAPIKeyStorage), 0), nil)
+	// Serves the request as authenticated by requireAuth
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		apiServer.v1Mux().ServeHTTP(w, req.WithContext(auth.WithIdentity(req.Context(), &auth.Identity{User: user})))
+		return w
+	}
+
+	t.Run("Create", func(t *testing.T) {
+		mockHouseholds.On("CreateHousehold", mock.Anything, "The Smiths").Return(household, nil).Once()
+
+		w := serve(httptest.NewRequest(http.MethodPost, "/households", bytes.NewBufferString(`{"name":"The Smiths"}`)))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), household.ID.Hex())
+	})
+
+	t.Run("Invite link", func(t *testing.T) {
+		mockHouseholds.On("CreateInvite", mock.Anything, household.ID.Hex(), models.HouseholdRoleViewer, (*time.Time)(nil)).
+			Return(&models.CreatedHouseholdInvite{Token: "hhi_secret"}, nil).Once()
+
+		w := serve(httptest.NewRequest(http.MethodPost, "/households/"+household.ID.Hex()+"/invites", bytes.NewBufferString(`{"role":"viewer"}`)))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"link":"/api/v1/households/join?token=hhi_secret"`)
+	})
+
+	t.Run("Join", func(t *testing.T) {
+		mockHouseholds.On("AcceptInvite", mock.Anything, "hhi_secret").Return(household, nil).Once()
+
+		w := serve(httptest.NewRequest(http.MethodPost, "/households/join?token=hhi_secret", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Join without token", func(t *testing.T) {
+		w := serve(httptest.NewRequest(http.MethodPost, "/households/join", nil))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("Already a member", func(t *testing.T) {
+		mockHouseholds.On("AcceptInvite", mock.Anything, "hhi_again").
+			Return(nil, fmt.Errorf("failed to join household: %w", fmt.Errorf("%w: household member %s in household %s", storage.ErrAlreadyExists, user.ID.Hex(), household.ID.Hex()))).Once()
+
+		w := serve(httptest.NewRequest(http.MethodPost, "/households/join?token=hhi_again", nil))
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+		assert.Contains(t, w.Body.String(), "The household member already exists")
+	})
+
+	t.Run("Not an owner", func(t *testing.T) {
+		memberID := primitive.NewObjectID().Hex()
+		mockHouseholds.On("RemoveMember", mock.Anything, household.ID.Hex(), memberID).
+			Return(fmt.Errorf("%w: only owners can manage the household", service.ErrForbidden)).Once()
+
+		w := serve(httptest.NewRequest(http.MethodDelete, "/households/"+household.ID.Hex()+"/members/"+memberID, nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "only owners can manage the household")
+	})
+
+	mockHouseholds.AssertExpectations(t)
+}
@@ -0,0 +1,59 @@
+package core
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/metrics"
+)
+
+// statusRecorder remembers the status code written to the response
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Unwrap() http.ResponseWriter {
+	return r.ResponseWriter
+}
+
+// observeRequests records every API request in the metrics, also the requests rejected before
+// they are routed (e.g. unauthenticated or rate limited). Requests are recorded by the route
+// pattern they matched (e.g. /recipe/{id}), or "unmatched", so the routes have a bounded number of
+// labels.
+func observeRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		r, routed := withRoutedRequest(r)
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		route := routed.route
+		if route == "" {
+			route = "unmatched"
+		}
+		labels := []string{r.Method, route, strconv.Itoa(recorder.status)}
+		metrics.HTTPRequests.WithLabelValues(labels...).Inc()
+		metrics.HTTPRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	})
+}
+
+// requestRoute returns the route pattern the request matched without its method, or "unmatched"
+func requestRoute(r *http.Request) string {
+	_, route, found := strings.Cut(r.Pattern, " ")
+	if !found {
+		route = r.Pattern
+	}
+	if route == "" {
+		route = "unmatched"
+	}
+	return route
+}
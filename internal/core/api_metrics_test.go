@@ -0,0 +1,73 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/metrics"
+	"github.com/AntonLuning/RecipeBank/internal/core/ratelimit"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestMetrics(t *testing.T) {
+	mockService := new(MockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, nil, nil)
+
+	id := primitive.NewObjectID().Hex()
+	found := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/recipe/{id}", "200")
+	notFound := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "/recipe/{id}", "404")
+	foundBefore, notFoundBefore := testutil.ToFloat64(found), testutil.ToFloat64(notFound)
+
+	mockService.On("GetRecipe", mock.Anything, id).Return(&models.Recipe{Title: "Omelett"}, nil).Once()
+	mockService.On("GetRecipe", mock.Anything, "missing").Return(nil, ErrNotFound).Once()
+
+	for _, recipeID := range []string{id, "missing"} {
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/v1/recipe/"+recipeID, nil))
+	}
+
+	// Requests are recorded by the route pattern, not the path
+	assert.Equal(t, foundBefore+1, testutil.ToFloat64(found))
+	assert.Equal(t, notFoundBefore+1, testutil.ToFloat64(notFound))
+
+	w := httptest.NewRecorder()
+	apiServer.mux.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `recipebank_http_requests_total{method="GET",route="/recipe/{id}",status="200"}`)
+	assert.Contains(t, w.Body.String(), "recipebank_http_request_duration_seconds_bucket")
+	mockService.AssertExpectations(t)
+}
+
+func TestRejectedRequestMetrics(t *testing.T) {
+	mockService := new(MockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, newTestAuthenticator(t, new(MockUserStorage), new(MockAPIKeyStorage), 0), NewRateLimiter(RateLimits{
+		Read: ratelimit.Budget{Requests: 1, Period: time.Hour},
+	}))
+	mockService.On("GetRecipes", mock.Anything, mock.Anything, 1, 10).Return(&models.RecipePage{}, nil).Once()
+
+	// Requests rejected before they are routed are recorded as unmatched
+	unauthorized := metrics.HTTPRequests.WithLabelValues(http.MethodPost, "unmatched", "401")
+	rateLimited := metrics.HTTPRequests.WithLabelValues(http.MethodGet, "unmatched", "429")
+	unauthorizedBefore, rateLimitedBefore := testutil.ToFloat64(unauthorized), testutil.ToFloat64(rateLimited)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe", nil)
+	req.Header.Set("Authorization", "Bearer invalid")
+	w := httptest.NewRecorder()
+	apiServer.mux.ServeHTTP(w, req)
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+
+	for range 2 {
+		apiServer.mux.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/api/v1/recipe", nil))
+	}
+
+	assert.Equal(t, unauthorizedBefore+1, testutil.ToFloat64(unauthorized))
+	assert.Equal(t, rateLimitedBefore+1, testutil.ToFloat64(rateLimited))
+	mockService.AssertExpectations(t)
+}
@@ -0,0 +1,460 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+)
+
+// GetCollections godoc
+// @Summary List collections
+// @Description List the collections of the active household, or the logged in user's own collections outside households, by name.
+// @Tags collections
+// @Produce json
+// @Security BearerAuth
+// @Param X-Household-ID header string false "Active household, only its collections are listed"
+// @Success 200 {object} models.APIResponse{data=[]models.Collection} "Collections"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Not logged in"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /collections [get]
+func (s *APIServer) handleGetCollections(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	collections, err := s.service.GetCollections(ctx)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, collections)
+}
+
+// GetCollection godoc
+// @Summary Get a collection
+// @Description Get a collection of the active household, or one of the logged in user's own collections outside households.
+// @Tags collections
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Collection ID"
+// @Param X-Household-ID header string false "Active household of the collection"
+// @Success 200 {object} models.APIResponse{data=models.Collection} "Collection"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid ID"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Not logged in"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Collection not found"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /collections/{id} [get]
+func (s *APIServer) handleGetCollection(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+
+	collection, err := s.service.GetCollection(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, collection)
+}
+
+// PostCollection godoc
+// @Summary Create a collection
+// @Description Create a collection in the active household, or a personal collection outside households. The recipes must be visible to the user. Viewers of a household cannot create collections in it.
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param X-Household-ID header string false "Active household, the collection is created in it"
+// @Param request body models.CollectionRequest true "Collection to create"
+// @Success 201 {object} models.APIResponse{data=models.Collection} "Created collection"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid collection"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Viewer of the household"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /collections [post]
+func (s *APIServer) handlePostCollection(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req models.CollectionRequest
+	if err := s.parseJSONBody(w, r, &req); err != nil {
+		return err
+	}
+
+	collection, err := s.service.CreateCollection(ctx, collectionFromRequest(req))
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusCreated, collection)
+}
+
+// PutCollection godoc
+// @Summary Update a collection
+// @Description Replace a collection of the active household, or one of the logged in user's own collections outside households. Viewers of a household cannot change its collections.
+// @Tags collections
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Collection ID"
+// @Param X-Household-ID header string false "Active household of the collection"
+// @Param request body models.CollectionRequest true "Updated collection"
+// @Success 200 {object} models.APIResponse{data=models.Collection} "Updated collection"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid ID or collection"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Viewer of the household"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Collection not found"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /collections/{id} [put]
+func (s *APIServer) handlePutCollection(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+
+	var req models.CollectionRequest
+	if err := s.parseJSONBody(w, r, &req); err != nil {
+		return err
+	}
+
+	collection, err := s.service.UpdateCollection(ctx, id, collectionFromRequest(req))
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, collection)
+}
+
+// DeleteCollection godoc
+// @Summary Delete a collection
+// @Description Delete a collection of the active household, or one of the logged in user's own collections outside households. Viewers of a household cannot delete its collections.
+// @Tags collections
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Collection ID"
+// @Param X-Household-ID header string false "Active household of the collection"
+// @Success 204 {object} models.APIResponse "Collection deleted"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid ID"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Viewer of the household"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Collection not found"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /collections/{id} [delete]
+func (s *APIServer) handleDeleteCollection(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+
+	if err := s.service.DeleteCollection(ctx, id); err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusNoContent, nil)
+}
+
+// GetMealPlans godoc
+// @Summary List meal plans
+// @Description List the meal plans of the active household, or the logged in user's own meal plans outside households, by name.
+// @Tags meal plans
+// @Produce json
+// @Security BearerAuth
+// @Param X-Household-ID header string false "Active household, only its meal plans are listed"
+// @Success 200 {object} models.APIResponse{data=[]models.MealPlan} "Meal plans"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Not logged in"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /meal-plans [get]
+func (s *APIServer) handleGetMealPlans(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	plans, err := s.service.GetMealPlans(ctx)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, plans)
+}
+
+// GetMealPlan godoc
+// @Summary Get a meal plan
+// @Description Get a meal plan of the active household, or one of the logged in user's own meal plans outside households.
+// @Tags meal plans
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Meal plan ID"
+// @Param X-Household-ID header string false "Active household of the meal plan"
+// @Success 200 {object} models.APIResponse{data=models.MealPlan} "Meal plan"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid ID"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Not logged in"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Meal plan not found"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /meal-plans/{id} [get]
+func (s *APIServer) handleGetMealPlan(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+
+	plan, err := s.service.GetMealPlan(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, plan)
+}
+
+// PostMealPlan godoc
+// @Summary Create a meal plan
+// @Description Create a meal plan in the active household, or a personal meal plan outside households. The recipes must be visible to the user, the meals are sorted by date and meal. Viewers of a household cannot create meal plans in it.
+// @Tags meal plans
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param X-Household-ID header string false "Active household, the meal plan is created in it"
+// @Param request body models.MealPlanRequest true "Meal plan to create"
+// @Success 201 {object} models.APIResponse{data=models.MealPlan} "Created meal plan"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid meal plan"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Viewer of the household"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /meal-plans [post]
+func (s *APIServer) handlePostMealPlan(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req models.MealPlanRequest
+	if err := s.parseJSONBody(w, r, &req); err != nil {
+		return err
+	}
+
+	plan, err := s.service.CreateMealPlan(ctx, planFromRequest(req))
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusCreated, plan)
+}
+
+// PutMealPlan godoc
+// @Summary Update a meal plan
+// @Description Replace a meal plan of the active household, or one of the logged in user's own meal plans outside households. Viewers of a household cannot change its meal plans.
+// @Tags meal plans
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Meal plan ID"
+// @Param X-Household-ID header string false "Active household of the meal plan"
+// @Param request body models.MealPlanRequest true "Updated meal plan"
+// @Success 200 {object} models.APIResponse{data=models.MealPlan} "Updated meal plan"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid ID or meal plan"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Viewer of the household"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Meal plan not found"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /meal-plans/{id} [put]
+func (s *APIServer) handlePutMealPlan(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+
+	var req models.MealPlanRequest
+	if err := s.parseJSONBody(w, r, &req); err != nil {
+		return err
+	}
+
+	plan, err := s.service.UpdateMealPlan(ctx, id, planFromRequest(req))
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, plan)
+}
+
+// DeleteMealPlan godoc
+// @Summary Delete a meal plan
+// @Description Delete a meal plan of the active household, or one of the logged in user's own meal plans outside households. Viewers of a household cannot delete its meal plans.
+// @Tags meal plans
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Meal plan ID"
+// @Param X-Household-ID header string false "Active household of the meal plan"
+// @Success 204 {object} models.APIResponse "Meal plan deleted"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid ID"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Viewer of the household"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Meal plan not found"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /meal-plans/{id} [delete]
+func (s *APIServer) handleDeleteMealPlan(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+
+	if err := s.service.DeleteMealPlan(ctx, id); err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusNoContent, nil)
+}
+
+// GetGroceryLists godoc
+// @Summary List grocery lists
+// @Description List the grocery lists of the active household, or the logged in user's own grocery lists outside households, by name.
+// @Tags grocery lists
+// @Produce json
+// @Security BearerAuth
+// @Param X-Household-ID header string false "Active household, only its grocery lists are listed"
+// @Success 200 {object} models.APIResponse{data=[]models.GroceryList} "Grocery lists"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Not logged in"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /grocery-lists [get]
+func (s *APIServer) handleGetGroceryLists(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	lists, err := s.service.GetGroceryLists(ctx)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, lists)
+}
+
+// GetGroceryList godoc
+// @Summary Get a grocery list
+// @Description Get a grocery list of the active household, or one of the logged in user's own grocery lists outside households.
+// @Tags grocery lists
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Grocery list ID"
+// @Param X-Household-ID header string false "Active household of the grocery list"
+// @Success 200 {object} models.APIResponse{data=models.GroceryList} "Grocery list"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid ID"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Not logged in"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Grocery list not found"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /grocery-lists/{id} [get]
+func (s *APIServer) handleGetGroceryList(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+
+	list, err := s.service.GetGroceryList(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, list)
+}
+
+// PostGroceryList godoc
+// @Summary Create a grocery list
+// @Description Create a grocery list in the active household, or a personal grocery list outside households. Viewers of a household cannot create grocery lists in it.
+// @Tags grocery lists
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param X-Household-ID header string false "Active household, the grocery list is created in it"
+// @Param request body models.GroceryListRequest true "Grocery list to create"
+// @Success 201 {object} models.APIResponse{data=models.GroceryList} "Created grocery list"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid grocery list"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Viewer of the household"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /grocery-lists [post]
+func (s *APIServer) handlePostGroceryList(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var req models.GroceryListRequest
+	if err := s.parseJSONBody(w, r, &req); err != nil {
+		return err
+	}
+
+	list, err := s.service.CreateGroceryList(ctx, listFromRequest(req))
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusCreated, list)
+}
+
+// PutGroceryList godoc
+// @Summary Update a grocery list
+// @Description Replace a grocery list of the active household, or one of the logged in user's own grocery lists outside households. Viewers of a household cannot change its grocery lists.
+// @Tags grocery lists
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Grocery list ID"
+// @Param X-Household-ID header string false "Active household of the grocery list"
+// @Param request body models.GroceryListRequest true "Updated grocery list"
+// @Success 200 {object} models.APIResponse{data=models.GroceryList} "Updated grocery list"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid ID or grocery list"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Viewer of the household"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Grocery list not found"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /grocery-lists/{id} [put]
+func (s *APIServer) handlePutGroceryList(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+
+	var req models.GroceryListRequest
+	if err := s.parseJSONBody(w, r, &req); err != nil {
+		return err
+	}
+
+	list, err := s.service.UpdateGroceryList(ctx, id, listFromRequest(req))
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, list)
+}
+
+// DeleteGroceryList godoc
+// @Summary Delete a grocery list
+// @Description Delete a grocery list of the active household, or one of the logged in user's own grocery lists outside households. Viewers of a household cannot delete its grocery lists.
+// @Tags grocery lists
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Grocery list ID"
+// @Param X-Household-ID header string false "Active household of the grocery list"
+// @Success 204 {object} models.APIResponse "Grocery list deleted"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid ID"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Viewer of the household"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Grocery list not found"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /grocery-lists/{id} [delete]
+func (s *APIServer) handleDeleteGroceryList(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+
+	if err := s.service.DeleteGroceryList(ctx, id); err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusNoContent, nil)
+}
+
+func collectionFromRequest(req models.CollectionRequest) *models.Collection {
+	return &models.Collection{
+		Name:        req.Name,
+		Description: req.Description,
+		RecipeIDs:   req.RecipeIDs,
+	}
+}
+
+func planFromRequest(req models.MealPlanRequest) *models.MealPlan {
+	return &models.MealPlan{
+		Name:  req.Name,
+		Meals: req.Meals,
+	}
+}
+
+func listFromRequest(req models.GroceryListRequest) *models.GroceryList {
+	return &models.GroceryList{
+		Name:  req.Name,
+		Items: req.Items,
+	}
+}
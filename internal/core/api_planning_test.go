@@ -0,0 +1,87 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/auth"
+	"github.com/AntonLuning/RecipeBank/internal/core/service"
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestPlanning(t *testing.T) {
+	user := &models.User{ID: primitive.NewObjectID(), Username: "anton"}
+
+	mockService := new(MockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, newTestAuthenticator(t, new(MockUserStorage), new(MockAPIKeyStorage), 0), nil)
+	// Serves the request as authenticated by requireAuth
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		apiServer.v1Mux().ServeHTTP(w, req.WithContext(auth.WithIdentity(req.Context(), &auth.Identity{User: user})))
+		return w
+	}
+
+	t.Run("Create a collection", func(t *testing.T) {
+		recipeID := primitive.NewObjectID()
+		mockService.On("CreateCollection", mock.Anything, &models.Collection{Name: "Weeknight dinners", RecipeIDs: []primitive.ObjectID{recipeID}}).
+			Return(&models.Collection{ID: primitive.NewObjectID(), Name: "Weeknight dinners", RecipeIDs: []primitive.ObjectID{recipeID}}, nil).Once()
+
+		w := serve(httptest.NewRequest(http.MethodPost, "/collections", bytes.NewBufferString(fmt.Sprintf(`{"name":"Weeknight dinners","recipe_ids":[%q]}`, recipeID.Hex()))))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), recipeID.Hex())
+	})
+
+	t.Run("Invalid recipe ID", func(t *testing.T) {
+		w := serve(httptest.NewRequest(http.MethodPost, "/collections", bytes.NewBufferString(`{"name":"Weeknight dinners","recipe_ids":["not-an-id"]}`)))
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
+	t.Run("List meal plans", func(t *testing.T) {
+		mockService.On("GetMealPlans", mock.Anything).Return([]models.MealPlan{{Name: "Week 12", Meals: []models.PlannedMeal{}}}, nil).Once()
+
+		w := serve(httptest.NewRequest(http.MethodGet, "/meal-plans", nil))
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"name":"Week 12"`)
+	})
+
+	t.Run("Viewer of the household", func(t *testing.T) {
+		id := primitive.NewObjectID().Hex()
+		mockService.On("UpdateGroceryList", mock.Anything, id, &models.GroceryList{Name: "Saturday", Items: []models.GroceryItem{{Name: "Flour", Checked: true}}}).
+			Return(nil, fmt.Errorf("%w: viewers of a household cannot change its collections, meal plans and grocery lists", service.ErrForbidden)).Once()
+
+		w := serve(httptest.NewRequest(http.MethodPut, "/grocery-lists/"+id, bytes.NewBufferString(`{"name":"Saturday","items":[{"name":"Flour","checked":true}]}`)))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		id := primitive.NewObjectID().Hex()
+		mockService.On("DeleteGroceryList", mock.Anything, id).Return(nil).Once()
+
+		w := serve(httptest.NewRequest(http.MethodDelete, "/grocery-lists/"+id, nil))
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		id := primitive.NewObjectID().Hex()
+		mockService.On("GetMealPlan", mock.Anything, id).
+			Return(nil, fmt.Errorf("failed to get meal plan: %w", fmt.Errorf("%w: meal plan with ID %s", storage.ErrNotFound, id))).Once()
+
+		w := serve(httptest.NewRequest(http.MethodGet, "/meal-plans/"+id, nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+	})
+
+	mockService.AssertExpectations(t)
+}
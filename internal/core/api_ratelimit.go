@@ -0,0 +1,252 @@
+package core
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/auth"
+	"github.com/AntonLuning/RecipeBank/internal/core/metrics"
+	"github.com/AntonLuning/RecipeBank/internal/core/ratelimit"
+)
+
+// Route groups with their own rate limit budget
+const (
+	RouteGroupRead  = "read"
+	RouteGroupWrite = "write"
+	RouteGroupAI    = "ai"
+	// Failed authentications, which are limited per client IP before the route group
+	RouteGroupFailedAuth = "failed_auth"
+)
+
+// RateLimits configures the rate limiting of API requests
+type RateLimits struct {
+	// Budgets of every client per route group, empty budgets do not limit the group
+	Read  ratelimit.Budget
+	Write ratelimit.Budget
+	AI    ratelimit.Budget
+	// Budget of failed authentications per client IP, which limits guessing of tokens and API keys
+	FailedAuth ratelimit.Budget
+	// Reverse proxies whose X-Forwarded-For header is trusted to tell the client IP
+	TrustedProxies []netip.Prefix
+}
+
+// RateLimiter limits the requests of every client per route group. Clients are told apart by
+// their API key, their user, or their IP address when they are anonymous.
+type RateLimiter struct {
+	limiters       map[string]*ratelimit.Limiter
+	failedAuth     *ratelimit.Limiter
+	trustedProxies []netip.Prefix
+}
+
+func NewRateLimiter(limits RateLimits) *RateLimiter {
+	return &RateLimiter{
+		limiters: map[string]*ratelimit.Limiter{
+			RouteGroupRead:  ratelimit.NewLimiter(limits.Read),
+			RouteGroupWrite: ratelimit.NewLimiter(limits.Write),
+			RouteGroupAI:    ratelimit.NewLimiter(limits.AI),
+		},
+		failedAuth:     ratelimit.NewLimiter(limits.FailedAuth),
+		trustedProxies: limits.TrustedProxies,
+	}
+}
+
+// ParseRateLimits parses the budgets and trusted proxies of the configuration
+func ParseRateLimits(config RateLimitConfig) (RateLimits, error) {
+	var limits RateLimits
+	var err error
+	if limits.Read, err = ratelimit.ParseBudget(config.Read); err != nil {
+		return RateLimits{}, err
+	}
+	if limits.Write, err = ratelimit.ParseBudget(config.Write); err != nil {
+		return RateLimits{}, err
+	}
+	if limits.AI, err = ratelimit.ParseBudget(config.AI); err != nil {
+		return RateLimits{}, err
+	}
+	if limits.FailedAuth, err = ratelimit.ParseBudget(config.FailedAuth); err != nil {
+		return RateLimits{}, err
+	}
+	if limits.TrustedProxies, err = parseTrustedProxies(config.TrustedProxies); err != nil {
+		return RateLimits{}, err
+	}
+	return limits, nil
+}
+
+// parseTrustedProxies parses the IP addresses and CIDR ranges of trusted proxies
+func parseTrustedProxies(proxies []string) ([]netip.Prefix, error) {
+	prefixes := []netip.Prefix{}
+	for _, proxy := range proxies {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			addr, err := netip.ParseAddr(proxy)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+			}
+			proxy = netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()).String()
+		}
+		prefix, err := netip.ParsePrefix(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		prefixes = append(prefixes, prefix.Masked())
+	}
+	return prefixes, nil
+}
+
+// rateLimit rejects requests of clients that have used up the budget of the route group, with
+// 429 rate_limited. The RateLimit headers tell clients their budget. It runs after requireAuth,
+// so authenticated clients are limited by their credentials; failed authentications are limited
+// by requireAuth itself, per client IP.
+func (s *APIServer) rateLimit(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.rateLimiter == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		group := routeGroup(r)
+		limiter := s.rateLimiter.limiters[group]
+		if !limiter.Budget().Enabled() {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key := s.rateLimitKey(r)
+		result := limiter.Allow(key)
+		setRateLimitHeaders(w, limiter.Budget(), result)
+		if !result.Allowed {
+			writeRateLimited(w, r, group, key, result)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// reserveAuthentication takes an attempt from the failed authentication budget of the client IP
+// before its credentials are checked, so tokens and API keys cannot be guessed faster than the
+// budget allows. It writes 429 rate_limited and returns false if the budget is used up.
+func (s *APIServer) reserveAuthentication(w http.ResponseWriter, r *http.Request) bool {
+	if s.rateLimiter == nil {
+		return true
+	}
+
+	key := "ip:" + s.clientIP(r)
+	result := s.rateLimiter.failedAuth.Allow(key)
+	if !result.Allowed {
+		setRateLimitHeaders(w, s.rateLimiter.failedAuth.Budget(), result)
+		writeRateLimited(w, r, RouteGroupFailedAuth, key, result)
+		return false
+	}
+	return true
+}
+
+// releaseAuthentication gives the attempt back once the credentials turned out to be valid
+func (s *APIServer) releaseAuthentication(r *http.Request) {
+	if s.rateLimiter == nil {
+		return
+	}
+	s.rateLimiter.failedAuth.Refund("ip:" + s.clientIP(r))
+}
+
+// setRateLimitHeaders tells clients their budget
+func setRateLimitHeaders(w http.ResponseWriter, budget ratelimit.Budget, result ratelimit.Result) {
+	w.Header().Set("RateLimit-Limit", fmt.Sprint(result.Limit))
+	w.Header().Set("RateLimit-Remaining", fmt.Sprint(result.Remaining))
+	w.Header().Set("RateLimit-Reset", fmt.Sprint(seconds(result.Reset)))
+	w.Header().Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", budget.Requests, seconds(budget.Period)))
+}
+
+// writeRateLimited rejects the request of a client that has used up its budget
+func writeRateLimited(w http.ResponseWriter, r *http.Request, group string, key string, result ratelimit.Result) {
+	retryAfter := seconds(result.RetryAfter)
+	slog.Info("Rate limited request", "method", r.Method, "path", r.URL.Path, "group", group, "client", key)
+	metrics.RateLimitedRequests.WithLabelValues(group).Inc()
+	w.Header().Set("Retry-After", fmt.Sprint(retryAfter))
+	writeErrorResponse(w, http.StatusTooManyRequests, "rate_limited", fmt.Sprintf("Too many requests, please try again in %d seconds", retryAfter))
+}
+
+// rateLimitKey returns the client a request is counted for
+func (s *APIServer) rateLimitKey(r *http.Request) string {
+	identity, ok := auth.IdentityFromContext(r.Context())
+	switch {
+	case ok && identity.APIKeyID != nil:
+		return "key:" + identity.APIKeyID.Hex()
+	case ok:
+		return "user:" + identity.User.ID.Hex()
+	default:
+		return "ip:" + s.clientIP(r)
+	}
+}
+
+// routeGroup returns the rate limit budget a request counts against
+func routeGroup(r *http.Request) string {
+	switch {
+	case isViewingMethod(r.Method):
+		return RouteGroupRead
+	case strings.Contains(r.URL.Path, "/ai/"):
+		return RouteGroupAI
+	default:
+		return RouteGroupWrite
+	}
+}
+
+// clientIP returns the IP address of the client that sent the request. Behind trusted proxies it
+// is the last address of the X-Forwarded-For header that is not a trusted proxy, since the
+// addresses before it can be forged by the client.
+func (s *APIServer) clientIP(r *http.Request) string {
+	var trustedProxies []netip.Prefix
+	if s.rateLimiter != nil {
+		trustedProxies = s.rateLimiter.trustedProxies
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !isTrustedProxy(addr, trustedProxies) {
+		return host
+	}
+
+	forwarded := []string{}
+	for _, header := range r.Header.Values("X-Forwarded-For") {
+		forwarded = append(forwarded, strings.Split(header, ",")...)
+	}
+	for i := len(forwarded) - 1; i >= 0; i-- {
+		hop, err := netip.ParseAddr(strings.TrimSpace(forwarded[i]))
+		if err != nil {
+			break
+		}
+		addr = hop
+		if !isTrustedProxy(hop, trustedProxies) {
+			break
+		}
+	}
+
+	return addr.Unmap().String()
+}
+
+func isTrustedProxy(addr netip.Addr, trustedProxies []netip.Prefix) bool {
+	addr = addr.Unmap()
+	for _, prefix := range trustedProxies {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// seconds rounds a duration up to whole seconds, as used in headers
+func seconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,157 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/auth"
+	"github.com/AntonLuning/RecipeBank/internal/core/ratelimit"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRateLimit(t *testing.T) {
+	mockService := new(MockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, nil, NewRateLimiter(RateLimits{
+		Read:           ratelimit.Budget{Requests: 2, Period: time.Hour},
+		AI:             ratelimit.Budget{Requests: 1, Period: time.Hour},
+		TrustedProxies: []netip.Prefix{netip.MustParsePrefix("10.0.0.0/8")},
+	}))
+	mockService.On("GetRecipes", mock.Anything, mock.Anything, 1, 10).Return(&models.RecipePage{}, nil)
+
+	get := func(remoteAddr string, forwardedFor string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/recipe", nil)
+		req.RemoteAddr = remoteAddr
+		if forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", forwardedFor)
+		}
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	t.Run("Budget of a client", func(t *testing.T) {
+		w := get("192.0.2.1:1234", "")
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Equal(t, "2", w.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, "1", w.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "2;w=3600", w.Header().Get("RateLimit-Policy"))
+
+		assert.Equal(t, http.StatusOK, get("192.0.2.1:1234", "").Code)
+
+		w = get("192.0.2.1:1234", "")
+		assert.Equal(t, http.StatusTooManyRequests, w.Code)
+		assert.Equal(t, "0", w.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "1800", w.Header().Get("Retry-After"))
+		assert.Contains(t, w.Body.String(), `"success":false`)
+		assert.Contains(t, w.Body.String(), `"code":"rate_limited"`)
+
+		// Other clients have their own budget
+		assert.Equal(t, http.StatusOK, get("192.0.2.2:1234", "").Code)
+	})
+
+	t.Run("Forwarded for is only trusted from proxies", func(t *testing.T) {
+		assert.Equal(t, http.StatusOK, get("10.0.0.1:1234", "198.51.100.1").Code)
+		assert.Equal(t, http.StatusOK, get("10.0.0.2:1234", "198.51.100.1").Code)
+		assert.Equal(t, http.StatusTooManyRequests, get("10.0.0.1:1234", "198.51.100.1").Code)
+		// Forged addresses before the last untrusted one are ignored
+		assert.Equal(t, http.StatusTooManyRequests, get("10.0.0.1:1234", "203.0.113.9, 198.51.100.1").Code)
+		assert.Equal(t, http.StatusOK, get("10.0.0.1:1234", "198.51.100.2").Code)
+
+		assert.Equal(t, http.StatusOK, get("192.0.2.3:1234", "198.51.100.3").Code)
+		assert.Equal(t, http.StatusOK, get("192.0.2.3:1234", "198.51.100.4").Code)
+		assert.Equal(t, http.StatusTooManyRequests, get("192.0.2.3:1234", "198.51.100.5").Code)
+	})
+
+	t.Run("Route groups", func(t *testing.T) {
+		user := &models.User{ID: primitive.NewObjectID(), Username: "anton"}
+		keyID := primitive.NewObjectID()
+		next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { w.WriteHeader(http.StatusOK) })
+		serve := func(method string, path string, identity *auth.Identity) int {
+			req := httptest.NewRequest(method, path, nil)
+			w := httptest.NewRecorder()
+			apiServer.rateLimit(next).ServeHTTP(w, req.WithContext(auth.WithIdentity(req.Context(), identity)))
+			return w.Code
+		}
+
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/recipe/ai/from-url", &auth.Identity{User: user}))
+		assert.Equal(t, http.StatusTooManyRequests, serve(http.MethodPost, "/recipe/ai/from-url", &auth.Identity{User: user}))
+		// API keys have their own budget, and writes are not limited
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/recipe/ai/from-url", &auth.Identity{User: user, APIKeyID: &keyID}))
+		assert.Equal(t, http.StatusOK, serve(http.MethodPost, "/recipe", &auth.Identity{User: user}))
+		assert.Equal(t, http.StatusOK, serve(http.MethodGet, "/ai/usage", &auth.Identity{User: user}))
+	})
+}
+
+func TestFailedAuthenticationLimit(t *testing.T) {
+	mockService := new(MockService)
+	mockUsers := new(MockUserStorage)
+	mockKeys := new(MockAPIKeyStorage)
+	authenticator := newTestAuthenticator(t, mockUsers, mockKeys, 0)
+	apiServer := NewAPIServer(":8080", mockService, nil, authenticator, NewRateLimiter(RateLimits{
+		FailedAuth: ratelimit.Budget{Requests: 3, Period: time.Hour},
+	}))
+	mockService.On("GetRecipes", mock.Anything, mock.Anything, 1, 10).Return(&models.RecipePage{}, nil)
+	mockKeys.On("GetAPIKeyByHash", mock.Anything, mock.Anything).Return(nil, ErrNotFound)
+
+	hash, err := auth.HashPassword("secret password")
+	require.NoError(t, err)
+	user := &models.User{ID: primitive.NewObjectID(), Username: "anton", PasswordHash: hash}
+	mockUsers.On("GetUserByUsername", mock.Anything, "anton").Return(user, nil).Once()
+	tokens, err := authenticator.Login(context.Background(), "anton", "secret password", "")
+	require.NoError(t, err)
+
+	get := func(remoteAddr string, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/recipe", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+		return w
+	}
+
+	// Valid credentials do not use up the budget
+	for range 5 {
+		assert.Equal(t, http.StatusOK, get("192.0.2.1:1234", tokens.AccessToken).Code)
+	}
+
+	for i := range 3 {
+		assert.Equal(t, http.StatusUnauthorized, get("192.0.2.1:1234", fmt.Sprintf("%sguess%d", auth.APIKeyPrefix, i)).Code)
+	}
+	w := get("192.0.2.1:1234", auth.APIKeyPrefix+"guess3")
+	assert.Equal(t, http.StatusTooManyRequests, w.Code)
+	assert.Contains(t, w.Body.String(), `"code":"rate_limited"`)
+	assert.NotEmpty(t, w.Header().Get("Retry-After"))
+	// The key is not looked up once the budget is used up
+	mockKeys.AssertNumberOfCalls(t, "GetAPIKeyByHash", 3)
+
+	// Valid credentials are refused as well from the client, but other clients are not limited
+	assert.Equal(t, http.StatusTooManyRequests, get("192.0.2.1:1234", tokens.AccessToken).Code)
+	assert.Equal(t, http.StatusUnauthorized, get("192.0.2.2:1234", auth.APIKeyPrefix+"guess").Code)
+}
+
+func TestParseRateLimits(t *testing.T) {
+	limits, err := ParseRateLimits(RateLimitConfig{Read: "300/1m", Write: "", AI: "10/1h", FailedAuth: "20/1m", TrustedProxies: []string{"10.0.0.0/8", " 192.0.2.1 ", "::1"}})
+	require.NoError(t, err)
+	assert.Equal(t, ratelimit.Budget{Requests: 300, Period: time.Minute}, limits.Read)
+	assert.False(t, limits.Write.Enabled())
+	assert.Equal(t, ratelimit.Budget{Requests: 20, Period: time.Minute}, limits.FailedAuth)
+	assert.Equal(t, []netip.Prefix{
+		netip.MustParsePrefix("10.0.0.0/8"),
+		netip.MustParsePrefix("192.0.2.1/32"),
+		netip.MustParsePrefix("::1/128"),
+	}, limits.TrustedProxies)
+
+	_, err = ParseRateLimits(RateLimitConfig{AI: "often"})
+	assert.Error(t, err)
+	_, err = ParseRateLimits(RateLimitConfig{TrustedProxies: []string{"proxy.local"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,133 @@
+package core
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+)
+
+// PostRecipeShare godoc
+// @Summary Share a recipe
+// @Description Create a public link to a recipe, which anyone with the token can view without logging in, regardless of the visibility of the recipe, until it expires or is revoked. Links never expire without an expiry. The token is only returned in this response. Only those who can change the recipe can share it.
+// @Tags recipes
+// @Accept json
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Recipe ID"
+// @Param request body models.CreateRecipeShareRequest false "Optional expiry"
+// @Success 201 {object} models.APIResponse{data=models.CreatedRecipeShare} "Created share link"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid ID or expiry"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Not allowed to change the recipe"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Recipe not found"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /recipe/{id}/share [post]
+func (s *APIServer) handlePostRecipeShare(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+
+	// The body is optional, a link without expiry needs none
+	var req models.CreateRecipeShareRequest
+	if r.ContentLength != 0 {
+		if err := s.parseJSONBody(w, r, &req); err != nil {
+			return err
+		}
+	}
+
+	share, err := s.service.ShareRecipe(ctx, id, req.ExpiresAt)
+	if err != nil {
+		return err
+	}
+	share.Link = "/shared/" + url.PathEscape(share.Token)
+
+	return writeSuccessResponse(w, http.StatusCreated, share)
+}
+
+// GetRecipeShares godoc
+// @Summary List the share links of a recipe
+// @Description List the share links of a recipe, the newest first, including revoked and expired links. Only those who can change the recipe can see them.
+// @Tags recipes
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Recipe ID"
+// @Success 200 {object} models.APIResponse{data=[]models.RecipeShare} "Share links"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid ID"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Not allowed to change the recipe"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Recipe not found"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /recipe/{id}/shares [get]
+func (s *APIServer) handleGetRecipeShares(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+
+	shares, err := s.service.GetRecipeShares(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, shares)
+}
+
+// DeleteRecipeShare godoc
+// @Summary Revoke a share link
+// @Description Revoke a share link of a recipe, it cannot be used again
+// @Tags recipes
+// @Produce json
+// @Security BearerAuth
+// @Param id path string true "Recipe ID"
+// @Param share_id path string true "Share link ID"
+// @Success 204 "Share link revoked"
+// @Failure 400 {object} models.APIResponse{error=models.APIError} "Invalid ID"
+// @Failure 401 {object} models.APIResponse{error=models.APIError} "Missing or invalid access token"
+// @Failure 403 {object} models.APIResponse{error=models.APIError} "Not allowed to change the recipe"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Recipe not found, or no active share link with the ID"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /recipe/{id}/shares/{share_id} [delete]
+func (s *APIServer) handleDeleteRecipeShare(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	id := r.PathValue("id")
+	if id == "" {
+		return fmt.Errorf("%w: id parameter is required", ErrMissingPathParam)
+	}
+	shareID := r.PathValue("share_id")
+	if shareID == "" {
+		return fmt.Errorf("%w: share_id parameter is required", ErrMissingPathParam)
+	}
+
+	if err := s.service.RevokeRecipeShare(ctx, id, shareID); err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusNoContent, nil)
+}
+
+// GetSharedRecipe godoc
+// @Summary Get a shared recipe
+// @Description Get the read-only recipe of a share link. No authentication is needed, the token grants access.
+// @Tags recipes
+// @Produce json
+// @Param token path string true "Share token"
+// @Success 200 {object} models.APIResponse{data=models.SharedRecipe} "Shared recipe"
+// @Failure 404 {object} models.APIResponse{error=models.APIError} "Share link not found, revoked or expired"
+// @Failure 500 {object} models.APIResponse{error=models.APIError} "Internal server error"
+// @Router /shared/{token} [get]
+func (s *APIServer) handleGetSharedRecipe(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	token := r.PathValue("token")
+	if token == "" {
+		return fmt.Errorf("%w: token parameter is required", ErrMissingPathParam)
+	}
+
+	recipe, err := s.service.GetSharedRecipe(ctx, token)
+	if err != nil {
+		return err
+	}
+
+	return writeSuccessResponse(w, http.StatusOK, recipe)
+}
@@ -0,0 +1,127 @@
+package core
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/auth"
+	"github.com/AntonLuning/RecipeBank/internal/core/service"
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestRecipeShares(t *testing.T) {
+	user := &models.User{ID: primitive.NewObjectID(), Username: "anton"}
+	recipeID := primitive.NewObjectID().Hex()
+
+	mockService := new(MockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, newTestAuthenticator(t, new(MockUserStorage), new(MockAPIKeyStorage), 0), nil)
+	// Serves the request as authenticated by requireAuth
+	serve := func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		apiServer.v1Mux().ServeHTTP(w, req.WithContext(auth.WithIdentity(req.Context(), &auth.Identity{User: user})))
+		return w
+	}
+
+	t.Run("Share without expiry", func(t *testing.T) {
+		mockService.On("ShareRecipe", mock.Anything, recipeID, (*time.Time)(nil)).
+			Return(&models.CreatedRecipeShare{Token: "shr_secret"}, nil).Once()
+
+		w := serve(httptest.NewRequest(http.MethodPost, "/recipe/"+recipeID+"/share", nil))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		assert.Contains(t, w.Body.String(), `"link":"/shared/shr_secret"`)
+	})
+
+	t.Run("Share with expiry", func(t *testing.T) {
+		expiresAt := time.Date(2030, 1, 2, 3, 4, 5, 0, time.UTC)
+		mockService.On("ShareRecipe", mock.Anything, recipeID, mock.MatchedBy(func(e *time.Time) bool {
+			return e != nil && e.Equal(expiresAt)
+		})).Return(&models.CreatedRecipeShare{Token: "shr_expiring"}, nil).Once()
+
+		w := serve(httptest.NewRequest(http.MethodPost, "/recipe/"+recipeID+"/share", bytes.NewBufferString(`{"expires_at":"2030-01-02T03:04:05Z"}`)))
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+	})
+
+	t.Run("Not allowed to share", func(t *testing.T) {
+		other := primitive.NewObjectID().Hex()
+		mockService.On("ShareRecipe", mock.Anything, other, (*time.Time)(nil)).
+			Return(nil, fmt.Errorf("%w: only the owner of the recipe or editors of its household can change it", service.ErrForbidden)).Once()
+
+		w := serve(httptest.NewRequest(http.MethodPost, "/recipe/"+other+"/share", nil))
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		shareID := primitive.NewObjectID().Hex()
+		mockService.On("RevokeRecipeShare", mock.Anything, recipeID, shareID).Return(nil).Once()
+
+		w := serve(httptest.NewRequest(http.MethodDelete, "/recipe/"+recipeID+"/shares/"+shareID, nil))
+
+		assert.Equal(t, http.StatusNoContent, w.Code)
+	})
+
+	t.Run("Revoke revoked link", func(t *testing.T) {
+		shareID := primitive.NewObjectID().Hex()
+		mockService.On("RevokeRecipeShare", mock.Anything, recipeID, shareID).
+			Return(fmt.Errorf("failed to revoke share link: %w", fmt.Errorf("%w: recipe share with ID %s", storage.ErrNotFound, shareID))).Once()
+
+		w := serve(httptest.NewRequest(http.MethodDelete, "/recipe/"+recipeID+"/shares/"+shareID, nil))
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "The requested recipe share was not found")
+	})
+
+	mockService.AssertExpectations(t)
+}
+
+func TestGetSharedRecipe(t *testing.T) {
+	mockService := new(MockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, newTestAuthenticator(t, new(MockUserStorage), new(MockAPIKeyStorage), 0), nil)
+
+	t.Run("Anonymous", func(t *testing.T) {
+		mockService.On("GetSharedRecipe", mock.Anything, "shr_secret").Return(&models.SharedRecipe{Title: "Pancakes"}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/shared/shr_secret", nil)
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), "Pancakes")
+		assert.NotContains(t, w.Body.String(), "owner_id")
+	})
+
+	t.Run("Invalid credentials are ignored", func(t *testing.T) {
+		mockService.On("GetSharedRecipe", mock.Anything, "shr_secret").Return(&models.SharedRecipe{Title: "Pancakes"}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/shared/shr_secret", nil)
+		req.Header.Set("Authorization", "Bearer expired")
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+	})
+
+	t.Run("Revoked", func(t *testing.T) {
+		mockService.On("GetSharedRecipe", mock.Anything, "shr_revoked").
+			Return(nil, fmt.Errorf("failed to get shared recipe: %w", fmt.Errorf("%w: shared recipe", storage.ErrNotFound))).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/shared/shr_revoked", nil)
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		assert.Contains(t, w.Body.String(), "The requested shared recipe was not found")
+	})
+
+	mockService.AssertExpectations(t)
+}
@@ -5,12 +5,14 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"net/url"
 	"testing"
 	"time"
 
+	"github.com/AntonLuning/RecipeBank/internal/core/ai"
 	"github.com/AntonLuning/RecipeBank/internal/core/service"
 	"github.com/AntonLuning/RecipeBank/internal/core/storage"
 	"github.com/AntonLuning/RecipeBank/pkg/core/models"
@@ -75,6 +77,219 @@ func (m *MockService) CreateRecipeFromImage(ctx context.Context, image string, i
 	return args.Get(0).(*models.Recipe), args.Error(1)
 }
 
+// CreateRecipeFromImages mocks the CreateRecipeFromImages method
+func (m *MockService) CreateRecipeFromImages(ctx context.Context, images []models.CreateRecipeFromImageRequest) (*models.Recipe, error) {
+	args := m.Called(ctx, images)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Recipe), args.Error(1)
+}
+
+// CreateRecipeFromPDF mocks the CreateRecipeFromPDF method
+func (m *MockService) CreateRecipeFromPDF(ctx context.Context, pdf string) (*models.Recipe, error) {
+	args := m.Called(ctx, pdf)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Recipe), args.Error(1)
+}
+
+// SuggestTags mocks the SuggestTags method
+func (m *MockService) SuggestTags(ctx context.Context, id string) (*models.TagSuggestion, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.TagSuggestion), args.Error(1)
+}
+
+// TranslateRecipe mocks the TranslateRecipe method
+func (m *MockService) TranslateRecipe(ctx context.Context, id string, language string) (*models.Recipe, error) {
+	args := m.Called(ctx, id, language)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Recipe), args.Error(1)
+}
+
+// SuggestSubstitutions mocks the SuggestSubstitutions method
+func (m *MockService) SuggestSubstitutions(ctx context.Context, id string, ingredient string, dietary []string) (*models.Substitutions, error) {
+	args := m.Called(ctx, id, ingredient, dietary)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Substitutions), args.Error(1)
+}
+
+// GetAIUsage mocks the GetAIUsage method
+func (m *MockService) GetAIUsage(ctx context.Context, from time.Time, to time.Time) (*models.AIUsageSummary, error) {
+	args := m.Called(ctx, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AIUsageSummary), args.Error(1)
+}
+
+// ShareRecipe mocks the ShareRecipe method
+func (m *MockService) ShareRecipe(ctx context.Context, id string, expiresAt *time.Time) (*models.CreatedRecipeShare, error) {
+	args := m.Called(ctx, id, expiresAt)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.CreatedRecipeShare), args.Error(1)
+}
+
+// GetRecipeShares mocks the GetRecipeShares method
+func (m *MockService) GetRecipeShares(ctx context.Context, id string) ([]models.RecipeShare, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.RecipeShare), args.Error(1)
+}
+
+// RevokeRecipeShare mocks the RevokeRecipeShare method
+func (m *MockService) RevokeRecipeShare(ctx context.Context, id string, shareID string) error {
+	args := m.Called(ctx, id, shareID)
+	return args.Error(0)
+}
+
+// GetCollections mocks the GetCollections method
+func (m *MockService) GetCollections(ctx context.Context) ([]models.Collection, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Collection), args.Error(1)
+}
+
+// GetCollection mocks the GetCollection method
+func (m *MockService) GetCollection(ctx context.Context, id string) (*models.Collection, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Collection), args.Error(1)
+}
+
+// CreateCollection mocks the CreateCollection method
+func (m *MockService) CreateCollection(ctx context.Context, collection *models.Collection) (*models.Collection, error) {
+	args := m.Called(ctx, collection)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Collection), args.Error(1)
+}
+
+// UpdateCollection mocks the UpdateCollection method
+func (m *MockService) UpdateCollection(ctx context.Context, id string, collection *models.Collection) (*models.Collection, error) {
+	args := m.Called(ctx, id, collection)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Collection), args.Error(1)
+}
+
+// DeleteCollection mocks the DeleteCollection method
+func (m *MockService) DeleteCollection(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// GetMealPlans mocks the GetMealPlans method
+func (m *MockService) GetMealPlans(ctx context.Context) ([]models.MealPlan, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.MealPlan), args.Error(1)
+}
+
+// GetMealPlan mocks the GetMealPlan method
+func (m *MockService) GetMealPlan(ctx context.Context, id string) (*models.MealPlan, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MealPlan), args.Error(1)
+}
+
+// CreateMealPlan mocks the CreateMealPlan method
+func (m *MockService) CreateMealPlan(ctx context.Context, plan *models.MealPlan) (*models.MealPlan, error) {
+	args := m.Called(ctx, plan)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MealPlan), args.Error(1)
+}
+
+// UpdateMealPlan mocks the UpdateMealPlan method
+func (m *MockService) UpdateMealPlan(ctx context.Context, id string, plan *models.MealPlan) (*models.MealPlan, error) {
+	args := m.Called(ctx, id, plan)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MealPlan), args.Error(1)
+}
+
+// DeleteMealPlan mocks the DeleteMealPlan method
+func (m *MockService) DeleteMealPlan(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// GetGroceryLists mocks the GetGroceryLists method
+func (m *MockService) GetGroceryLists(ctx context.Context) ([]models.GroceryList, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.GroceryList), args.Error(1)
+}
+
+// GetGroceryList mocks the GetGroceryList method
+func (m *MockService) GetGroceryList(ctx context.Context, id string) (*models.GroceryList, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.GroceryList), args.Error(1)
+}
+
+// CreateGroceryList mocks the CreateGroceryList method
+func (m *MockService) CreateGroceryList(ctx context.Context, list *models.GroceryList) (*models.GroceryList, error) {
+	args := m.Called(ctx, list)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.GroceryList), args.Error(1)
+}
+
+// UpdateGroceryList mocks the UpdateGroceryList method
+func (m *MockService) UpdateGroceryList(ctx context.Context, id string, list *models.GroceryList) (*models.GroceryList, error) {
+	args := m.Called(ctx, id, list)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.GroceryList), args.Error(1)
+}
+
+// DeleteGroceryList mocks the DeleteGroceryList method
+func (m *MockService) DeleteGroceryList(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+// GetSharedRecipe mocks the GetSharedRecipe method
+func (m *MockService) GetSharedRecipe(ctx context.Context, token string) (*models.SharedRecipe, error) {
+	args := m.Called(ctx, token)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.SharedRecipe), args.Error(1)
+}
+
 // UpdateRecipe mocks the UpdateRecipe method
 func (m *MockService) UpdateRecipe(ctx context.Context, id string, recipe *models.Recipe) (*models.Recipe, error) {
 	args := m.Called(ctx, id, recipe)
@@ -90,10 +305,19 @@ func (m *MockService) DeleteRecipe(ctx context.Context, id string) error {
 	return args.Error(0)
 }
 
+// GetDuplicateRecipes mocks the GetDuplicateRecipes method
+func (m *MockService) GetDuplicateRecipes(ctx context.Context) ([]models.DuplicateCluster, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.DuplicateCluster), args.Error(1)
+}
+
 // TestHandleGetRecipeByID tests the handleGetRecipeByID method
 func TestHandleGetRecipeByID(t *testing.T) {
 	mockService := new(MockService)
-	apiServer := NewAPIServer(":8080", mockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, nil, nil)
 
 	// Create a valid recipe ID
 	validID := primitive.NewObjectID().Hex()
@@ -196,7 +420,7 @@ func TestHandleGetRecipeByID(t *testing.T) {
 // TestHandleGetRecipes tests the handleGetRecipes method
 func TestHandleGetRecipes(t *testing.T) {
 	mockService := new(MockService)
-	apiServer := NewAPIServer(":8080", mockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, nil, nil)
 
 	t.Run("Success", func(t *testing.T) {
 		// Create a test recipe page
@@ -310,6 +534,61 @@ func TestHandleGetRecipes(t *testing.T) {
 		mockService.AssertExpectations(t)
 	})
 
+	t.Run("Translations", func(t *testing.T) {
+		originalID := primitive.NewObjectID().Hex()
+		expectedFilter := models.RecipeFilter{
+			TranslationOf: originalID,
+			Language:      "japanese",
+		}
+
+		mockService.On("GetRecipes", mock.Anything, expectedFilter, 1, 10).Return(&models.RecipePage{}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/recipe?translation_of="+originalID+"&language=Japanese", nil)
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Source", func(t *testing.T) {
+		expectedFilter := models.RecipeFilter{
+			SourceType:  models.SourceURL,
+			SourceURL:   "example.com",
+			SourceModel: "gpt-4.1-mini",
+		}
+
+		mockService.On("GetRecipes", mock.Anything, expectedFilter, 1, 10).Return(&models.RecipePage{}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/recipe?source_type=URL&source_url=example.com&source_model=gpt-4.1-mini", nil)
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Mine", func(t *testing.T) {
+		mockService.On("GetRecipes", mock.Anything, models.RecipeFilter{Mine: true}, 1, 10).Return(&models.RecipePage{}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/recipe?mine=true", nil)
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+
+		req = httptest.NewRequest(http.MethodGet, "/api/v1/recipe?mine=maybe", nil)
+		w = httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+	})
+
 	t.Run("Invalid Query Parameters", func(t *testing.T) {
 		// Create a test request with invalid query parameters
 		req := httptest.NewRequest(http.MethodGet, "/api/v1/recipe?page=invalid&limit=invalid", nil)
@@ -343,7 +622,7 @@ func TestHandleGetRecipes(t *testing.T) {
 // TestHandlePostRecipe tests the handlePostRecipe method
 func TestHandlePostRecipe(t *testing.T) {
 	mockService := new(MockService)
-	apiServer := NewAPIServer(":8080", mockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, nil, nil)
 
 	t.Run("Success", func(t *testing.T) {
 		// Create a test recipe request
@@ -402,6 +681,30 @@ func TestHandlePostRecipe(t *testing.T) {
 		mockService.AssertExpectations(t)
 	})
 
+	t.Run("Source", func(t *testing.T) {
+		recipeReq := models.CreateRecipeRequest{
+			Title:       "Test Recipe",
+			Ingredients: []models.Ingredient{{Name: "Test Ingredient", Quantity: 1}},
+			Steps:       []string{"Step 1"},
+			Source:      &models.SourceRequest{Type: models.SourceFile, URL: "https://example.com/recipes.json"},
+		}
+
+		mockService.On("CreateRecipe", mock.Anything, mock.MatchedBy(func(r *models.Recipe) bool {
+			return r.Source != nil && r.Source.Type == models.SourceFile && r.Source.URL == "https://example.com/recipes.json"
+		})).Return(&models.Recipe{Title: recipeReq.Title}, nil).Once()
+
+		reqBody, err := json.Marshal(recipeReq)
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
 	t.Run("Invalid JSON", func(t *testing.T) {
 		// Create a test request with invalid JSON
 		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe", bytes.NewBuffer([]byte("invalid json")))
@@ -512,10 +815,487 @@ func TestHandlePostRecipe(t *testing.T) {
 	})
 }
 
+// TestHandlePostRecipeFromImages tests the handlePostRecipeFromImages method
+func TestHandlePostRecipeFromImages(t *testing.T) {
+	mockService := new(MockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, nil, nil)
+
+	t.Run("Success", func(t *testing.T) {
+		images := []models.CreateRecipeFromImageRequest{
+			{Image: "/9j/front", ImageType: "jpeg"},
+			{Image: "/9j/back", ImageType: "jpeg"},
+		}
+		expectedRecipe := &models.Recipe{
+			ID:    primitive.NewObjectID(),
+			Title: "Recipe Card",
+		}
+
+		mockService.On("CreateRecipeFromImages", mock.Anything, images).Return(expectedRecipe, nil).Once()
+
+		reqBody, err := json.Marshal(models.CreateRecipeFromImagesRequest{Images: images})
+		require.NoError(t, err)
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe/ai/from-images", bytes.NewBuffer(reqBody))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var response models.APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.True(t, response.Success)
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Validation Error", func(t *testing.T) {
+		mockService.On("CreateRecipeFromImages", mock.Anything, []models.CreateRecipeFromImageRequest(nil)).
+			Return(nil, fmt.Errorf("%w: at least one image is required", service.ErrValidation)).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe/ai/from-images", bytes.NewBufferString(`{"images":null}`))
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "at least one image is required")
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Request Body Too Large", func(t *testing.T) {
+		large := bytes.Repeat([]byte("a"), _MaxUploadBodySize+1)
+		reqBody := []byte(`{"images":[{"image":"` + string(large) + `","image_type":"jpeg"}]}`)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe/ai/from-images", bytes.NewBuffer(reqBody))
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+}
+
+// TestHandlePostRecipeFromPDF tests the handlePostRecipeFromPDF method
+func TestHandlePostRecipeFromPDF(t *testing.T) {
+	mockService := new(MockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, nil, nil)
+
+	t.Run("Success", func(t *testing.T) {
+		expectedRecipe := &models.Recipe{
+			ID:    primitive.NewObjectID(),
+			Title: "Magazine Recipe",
+		}
+
+		mockService.On("CreateRecipeFromPDF", mock.Anything, "JVBERi0xLjQK").Return(expectedRecipe, nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe/ai/from-pdf", bytes.NewBufferString(`{"pdf":"JVBERi0xLjQK"}`))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		var response models.APIResponse
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.True(t, response.Success)
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Validation Error", func(t *testing.T) {
+		mockService.On("CreateRecipeFromPDF", mock.Anything, "").
+			Return(nil, fmt.Errorf("%w: PDF data cannot be empty", service.ErrValidation)).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe/ai/from-pdf", bytes.NewBufferString(`{}`))
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "PDF data cannot be empty")
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Request Body Too Large", func(t *testing.T) {
+		large := bytes.Repeat([]byte("a"), _MaxUploadBodySize+1)
+		reqBody := []byte(`{"pdf":"` + string(large) + `"}`)
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe/ai/from-pdf", bytes.NewBuffer(reqBody))
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	})
+}
+
+// TestAIContext tests the cache bypass flag of the AI-powered endpoints
+func TestAIContext(t *testing.T) {
+	mockService := new(MockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, nil, nil)
+
+	recipe := &models.Recipe{ID: primitive.NewObjectID(), Title: "Omelett"}
+	reqBody := `{"image":"/9j/4AAQ","image_type":"jpeg"}`
+
+	for _, tt := range []struct {
+		query  string
+		bypass bool
+	}{
+		{"", false},
+		{"?no_cache=false", false},
+		{"?no_cache=true", true},
+		{"?no_cache=1", true},
+	} {
+		t.Run("no_cache"+tt.query, func(t *testing.T) {
+			mockService.On("CreateRecipeFromImage", mock.MatchedBy(func(ctx context.Context) bool {
+				return ai.CacheBypassed(ctx) == tt.bypass
+			}), "/9j/4AAQ", "jpeg").Return(recipe, nil).Once()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe/ai/from-image"+tt.query, bytes.NewBufferString(reqBody))
+			w := httptest.NewRecorder()
+
+			apiServer.mux.ServeHTTP(w, req)
+
+			assert.Equal(t, http.StatusCreated, w.Code)
+			mockService.AssertExpectations(t)
+		})
+	}
+
+	t.Run("Invalid flag", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe/ai/from-url?no_cache=maybe", bytes.NewBufferString(`{"url":"https://example.com"}`))
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "no_cache")
+		mockService.AssertNotCalled(t, "CreateRecipeFromURL", mock.Anything, mock.Anything)
+	})
+}
+
+// TestDuplicateRecipes tests the duplicate error, the force flag and the duplicate clusters
+func TestDuplicateRecipes(t *testing.T) {
+	mockService := new(MockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, nil, nil)
+
+	existingID := primitive.NewObjectID()
+	reqBody := `{"title":"Pancakes","ingredients":[{"name":"Flour"}],"steps":["Fry"]}`
+
+	t.Run("Duplicate", func(t *testing.T) {
+		mockService.On("CreateRecipe", mock.Anything, mock.AnythingOfType("*models.Recipe")).Return(nil, &service.DuplicateError{
+			Candidates: []models.DuplicateCandidate{{ID: existingID, Title: "Pancakes", Reason: models.DuplicateSimilar, Similarity: 0.9}},
+		}).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe", bytes.NewBufferString(reqBody))
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusConflict, w.Code)
+
+		var response struct {
+			Error struct {
+				Code    string                  `json:"code"`
+				Details models.DuplicateDetails `json:"details"`
+			} `json:"error"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		assert.Equal(t, "duplicate_recipe", response.Error.Code)
+		require.Len(t, response.Error.Details.Candidates, 1)
+		assert.Equal(t, existingID, response.Error.Details.Candidates[0].ID)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Force", func(t *testing.T) {
+		mockService.On("CreateRecipe", mock.MatchedBy(service.DuplicatesAllowed), mock.AnythingOfType("*models.Recipe")).Return(&models.Recipe{Title: "Pancakes"}, nil).Once()
+		mockService.On("CreateRecipeFromURL", mock.MatchedBy(service.DuplicatesAllowed), "https://example.com").Return(&models.Recipe{Title: "Pancakes"}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe?force=true", bytes.NewBufferString(reqBody))
+		w := httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/recipe/ai/from-url?force=true", bytes.NewBufferString(`{"url":"https://example.com"}`))
+		w = httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusCreated, w.Code)
+
+		req = httptest.NewRequest(http.MethodPost, "/api/v1/recipe?force=maybe", bytes.NewBufferString(reqBody))
+		w = httptest.NewRecorder()
+		apiServer.mux.ServeHTTP(w, req)
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "force")
+
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Clusters", func(t *testing.T) {
+		clusters := []models.DuplicateCluster{{
+			Reason: models.DuplicateSourceURL,
+			Recipes: []models.DuplicateRecipe{
+				{ID: existingID, Title: "Pancakes", SourceURL: "https://example.com"},
+				{ID: primitive.NewObjectID(), Title: "Pancakes", SourceURL: "https://example.com"},
+			},
+		}}
+		mockService.On("GetDuplicateRecipes", mock.Anything).Return(clusters, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/recipe/duplicates", nil)
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+
+		var response struct {
+			Data []models.DuplicateCluster `json:"data"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+		require.Len(t, response.Data, 1)
+		assert.Len(t, response.Data[0].Recipes, 2)
+		mockService.AssertExpectations(t)
+	})
+}
+
+// TestAIErrorResponses tests that the reasons of AI errors get distinct error codes
+func TestAIErrorResponses(t *testing.T) {
+	tests := []struct {
+		err        error
+		wantStatus int
+		wantCode   string
+	}{
+		{ai.ErrRateLimited, http.StatusTooManyRequests, "ai_rate_limited"},
+		{ai.ErrTimeout, http.StatusGatewayTimeout, "ai_timeout"},
+		{ai.ErrUnavailable, http.StatusServiceUnavailable, "ai_unavailable"},
+		{ai.ErrCircuitOpen, http.StatusServiceUnavailable, "ai_unavailable"},
+		{ai.ErrRefused, http.StatusUnprocessableEntity, "ai_refused"},
+		{ai.ErrEmptyResponse, http.StatusBadGateway, "ai_empty_response"},
+		{ai.ErrBudgetExceeded, http.StatusPaymentRequired, "ai_budget_exceeded"},
+		{errors.New("failed to unmarshal response"), http.StatusBadRequest, "ai_error"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.wantCode, func(t *testing.T) {
+			mockService := new(MockService)
+			apiServer := NewAPIServer(":8080", mockService, nil, nil, nil)
+
+			mockService.On("CreateRecipeFromURL", mock.Anything, "https://example.com/recipe").
+				Return(nil, fmt.Errorf("%w: failed to create recipe from URL: %w", service.ErrAI, tt.err)).Once()
+
+			req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe/ai/from-url", bytes.NewBufferString(`{"url":"https://example.com/recipe"}`))
+			w := httptest.NewRecorder()
+
+			apiServer.mux.ServeHTTP(w, req)
+
+			assert.Equal(t, tt.wantStatus, w.Code)
+
+			var response models.APIResponse
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &response))
+			assert.Equal(t, tt.wantCode, response.Error.Code)
+		})
+	}
+}
+
+// TestHandlePostSuggestTags tests the handlePostSuggestTags method
+func TestHandlePostSuggestTags(t *testing.T) {
+	mockService := new(MockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, nil, nil)
+
+	validID := primitive.NewObjectID().Hex()
+
+	t.Run("Success", func(t *testing.T) {
+		suggestion := &models.TagSuggestion{
+			Tags:    []string{"pasta", "italian", "main course"},
+			Cuisine: "italian",
+			Course:  "main course",
+		}
+		mockService.On("SuggestTags", mock.Anything, validID).Return(suggestion, nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe/"+validID+"/ai/suggest-tags", nil)
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"cuisine":"italian"`)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Recipe Not Found", func(t *testing.T) {
+		nonExistentID := primitive.NewObjectID().Hex()
+		mockService.On("SuggestTags", mock.Anything, nonExistentID).Return(nil, ErrNotFound).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe/"+nonExistentID+"/ai/suggest-tags", nil)
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusNotFound, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("AI error", func(t *testing.T) {
+		mockService.On("SuggestTags", mock.Anything, validID).Return(nil, fmt.Errorf("%w: %w", service.ErrAI, ai.ErrUnavailable)).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe/"+validID+"/ai/suggest-tags", nil)
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+		mockService.AssertExpectations(t)
+	})
+}
+
+// TestHandlePostTranslateRecipe tests the handlePostTranslateRecipe method
+func TestHandlePostTranslateRecipe(t *testing.T) {
+	mockService := new(MockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, nil, nil)
+
+	originalID := primitive.NewObjectID()
+
+	t.Run("Success", func(t *testing.T) {
+		translation := &models.Recipe{
+			ID:            primitive.NewObjectID(),
+			Title:         "Tomato Spaghetti",
+			Language:      "english",
+			TranslationOf: &originalID,
+		}
+		mockService.On("TranslateRecipe", mock.Anything, originalID.Hex(), "English").Return(translation, nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe/"+originalID.Hex()+"/ai/translate", bytes.NewBufferString(`{"language":"English"}`))
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"translation_of":"`+originalID.Hex()+`"`)
+		assert.Contains(t, w.Body.String(), `"language":"english"`)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid language", func(t *testing.T) {
+		mockService.On("TranslateRecipe", mock.Anything, originalID.Hex(), "").Return(nil, fmt.Errorf("%w: language is required", service.ErrValidation)).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe/"+originalID.Hex()+"/ai/translate", bytes.NewBufferString(`{}`))
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid JSON", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe/"+originalID.Hex()+"/ai/translate", bytes.NewBufferString(`{"language":`))
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "invalid_json")
+	})
+}
+
+// TestHandlePostSubstitutions tests the handlePostSubstitutions method
+func TestHandlePostSubstitutions(t *testing.T) {
+	mockService := new(MockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, nil, nil)
+
+	validID := primitive.NewObjectID().Hex()
+
+	t.Run("Success", func(t *testing.T) {
+		substitutions := &models.Substitutions{
+			Ingredient: models.Ingredient{Name: "Butter", Quantity: 50, Unit: "g"},
+			Options: []models.SubstitutionOption{
+				{Ingredients: []models.Ingredient{{Name: "vegetable oil", Quantity: 37.5, Unit: "g"}}},
+			},
+			Source: "table",
+		}
+		mockService.On("SuggestSubstitutions", mock.Anything, validID, "butter", []string{"vegan"}).Return(substitutions, nil).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe/"+validID+"/ai/substitutions", bytes.NewBufferString(`{"ingredient":"butter","dietary":["vegan"]}`))
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"vegetable oil"`)
+		assert.Contains(t, w.Body.String(), `"source":"table"`)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Validation error", func(t *testing.T) {
+		mockService.On("SuggestSubstitutions", mock.Anything, validID, "saffron", []string(nil)).Return(nil, fmt.Errorf("%w: ingredient saffron is not in the recipe", service.ErrValidation)).Once()
+
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe/"+validID+"/ai/substitutions", bytes.NewBufferString(`{"ingredient":"saffron"}`))
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "validation_error")
+		mockService.AssertExpectations(t)
+	})
+}
+
+// TestHandleGetAIUsage tests the handleGetAIUsage method
+func TestHandleGetAIUsage(t *testing.T) {
+	mockService := new(MockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, nil, nil)
+
+	t.Run("Period", func(t *testing.T) {
+		from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+		to := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC) // the whole last day is included
+		summary := &models.AIUsageSummary{From: from, To: to, Imports: 3, Cost: 0.0042}
+
+		mockService.On("GetAIUsage", mock.Anything, from, to).Return(summary, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/ai/usage?from=2025-03-01&to=2025-03-31", nil)
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Contains(t, w.Body.String(), `"imports":3`)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Defaults to the current month", func(t *testing.T) {
+		now := time.Now().UTC()
+		monthStart := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+
+		mockService.On("GetAIUsage", mock.Anything, monthStart, mock.MatchedBy(func(to time.Time) bool {
+			return time.Since(to) < time.Minute
+		})).Return(&models.AIUsageSummary{}, nil).Once()
+
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/ai/usage", nil)
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusOK, w.Code)
+		mockService.AssertExpectations(t)
+	})
+
+	t.Run("Invalid time", func(t *testing.T) {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/ai/usage?from=last-week", nil)
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusBadRequest, w.Code)
+		assert.Contains(t, w.Body.String(), "from")
+	})
+}
+
 // TestHandlePutRecipe tests the handlePutRecipe method
 func TestHandlePutRecipe(t *testing.T) {
 	mockService := new(MockService)
-	apiServer := NewAPIServer(":8080", mockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, nil, nil)
 
 	// Create a valid recipe ID
 	validID := primitive.NewObjectID().Hex()
@@ -644,7 +1424,7 @@ func TestHandlePutRecipe(t *testing.T) {
 // TestHandleDeleteRecipe tests the handleDeleteRecipe method
 func TestHandleDeleteRecipe(t *testing.T) {
 	mockService := new(MockService)
-	apiServer := NewAPIServer(":8080", mockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, nil, nil)
 
 	// Create a valid recipe ID
 	validID := primitive.NewObjectID().Hex()
@@ -686,6 +1466,20 @@ func TestHandleDeleteRecipe(t *testing.T) {
 		mockService.AssertExpectations(t)
 	})
 
+	t.Run("Not Owner", func(t *testing.T) {
+		mockService.On("DeleteRecipe", mock.Anything, validID).Return(fmt.Errorf("%w: only the owner of the recipe can change it", service.ErrForbidden)).Once()
+
+		req := httptest.NewRequest(http.MethodDelete, "/api/v1/recipe/"+validID, nil)
+		w := httptest.NewRecorder()
+
+		apiServer.mux.ServeHTTP(w, req)
+
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.Contains(t, w.Body.String(), "forbidden")
+		assert.Contains(t, w.Body.String(), "only the owner of the recipe can change it")
+		mockService.AssertExpectations(t)
+	})
+
 	t.Run("Missing ID Parameter", func(t *testing.T) {
 		// Create a test request without an ID
 		req := httptest.NewRequest(http.MethodDelete, "/api/v1/recipe/", nil)
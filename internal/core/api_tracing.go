@@ -0,0 +1,76 @@
+package core
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/AntonLuning/RecipeBank/internal/tracing"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.28.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+type routedRequestKey struct{}
+
+// routedRequest is what the handler of a request tells the middleware in front of the routing,
+// which only sees the request before it is routed
+type routedRequest struct {
+	route string // Route pattern the request matched, without its method
+	err   error  // Error the handler failed with
+}
+
+// withRoutedRequest returns the request with the holder that its handler records the route and
+// error in, shared by the middlewares in front of the routing
+func withRoutedRequest(r *http.Request) (*http.Request, *routedRequest) {
+	if routed, ok := r.Context().Value(routedRequestKey{}).(*routedRequest); ok {
+		return r, routed
+	}
+	routed := &routedRequest{}
+	return r.WithContext(context.WithValue(r.Context(), routedRequestKey{}, routed)), routed
+}
+
+// setRoutedRequest records the route the request matched and the error its handler failed with,
+// for the middlewares that trace and measure it
+func setRoutedRequest(r *http.Request, err error) {
+	if routed, ok := r.Context().Value(routedRequestKey{}).(*routedRequest); ok {
+		routed.route = requestRoute(r)
+		routed.err = err
+	}
+}
+
+// traceRequests starts the server span of every API request before it is authenticated and rate
+// limited, so the credential lookups and the requests they reject are traced too. The span
+// continues the trace of the caller (e.g. the UI server) from the W3C trace context headers, and
+// is named by the route the request matched once it is routed. Only the route is recorded, the
+// path can contain share link tokens.
+func traceRequests(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx, span := tracing.Start(tracing.Extract(r.Context(), r.Header), r.Method,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(semconv.HTTPRequestMethodKey.String(r.Method)),
+		)
+		r, routed := withRoutedRequest(r.WithContext(ctx))
+
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		if routed.route != "" {
+			span.SetName(r.Method + " " + routed.route)
+			span.SetAttributes(semconv.HTTPRoute(routed.route))
+		}
+		endRequestSpan(span, recorder.status, routed.err)
+	})
+}
+
+// endRequestSpan ends the server span of a request. Only server errors mark the span as failed,
+// client errors are part of normal operation.
+func endRequestSpan(span trace.Span, status int, err error) {
+	span.SetAttributes(semconv.HTTPResponseStatusCode(status))
+	if err != nil {
+		span.RecordError(err)
+	}
+	if status >= http.StatusInternalServerError {
+		span.SetStatus(codes.Error, http.StatusText(status))
+	}
+	span.End()
+}
@@ -0,0 +1,88 @@
+package core
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestRequestSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider, previousPropagator := otel.GetTracerProvider(), otel.GetTextMapPropagator()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+	t.Cleanup(func() {
+		otel.SetTracerProvider(previousProvider)
+		otel.SetTextMapPropagator(previousPropagator)
+	})
+
+	mockService := new(MockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, nil, nil)
+	id := primitive.NewObjectID().Hex()
+
+	var serviceSpan trace.SpanContext
+	mockService.On("GetRecipe", mock.Anything, id).Run(func(args mock.Arguments) {
+		serviceSpan = trace.SpanContextFromContext(args.Get(0).(context.Context))
+	}).Return(&models.Recipe{Title: "Omelett"}, nil).Once()
+	mockService.On("GetRecipe", mock.Anything, "missing").Return(nil, ErrNotFound).Once()
+
+	for _, recipeID := range []string{id, "missing"} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/recipe/"+recipeID, nil)
+		req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+		apiServer.mux.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	for _, span := range spans {
+		// The requests continue the trace of the caller
+		assert.Equal(t, "GET /recipe/{id}", span.Name())
+		assert.Equal(t, trace.SpanKindServer, span.SpanKind())
+		assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", span.SpanContext().TraceID().String())
+		assert.Equal(t, "00f067aa0ba902b7", span.Parent().SpanID().String())
+	}
+	// The service is called within the span of the request
+	assert.Equal(t, spans[0].SpanContext().SpanID(), serviceSpan.SpanID())
+
+	// Client errors are recorded, but do not fail the span
+	assert.Contains(t, spans[1].Attributes(), attribute.Int("http.response.status_code", http.StatusNotFound))
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+	assert.Len(t, spans[1].Events(), 1)
+	mockService.AssertExpectations(t)
+}
+
+func TestRejectedRequestSpans(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previousProvider := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previousProvider) })
+
+	mockService := new(MockService)
+	apiServer := NewAPIServer(":8080", mockService, nil, newTestAuthenticator(t, new(MockUserStorage), new(MockAPIKeyStorage), 0), nil)
+
+	req := httptest.NewRequest(http.MethodPost, "/api/v1/recipe", nil)
+	req.Header.Set("Authorization", "Bearer invalid")
+	apiServer.mux.ServeHTTP(httptest.NewRecorder(), req)
+
+	// Requests rejected before they are routed are traced by their method
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "POST", spans[0].Name())
+	assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind())
+	assert.Contains(t, spans[0].Attributes(), attribute.Int("http.response.status_code", http.StatusUnauthorized))
+	mockService.AssertNotCalled(t, "CreateRecipe", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+)
+
+const (
+	// Prefix of API keys, which tells them apart from access tokens
+	APIKeyPrefix = "rbk_"
+	// Random bytes of an API key
+	_APIKeyBytes = 32
+	// Length of the start of a key that is stored to tell keys apart
+	_APIKeyDisplayLength = len(APIKeyPrefix) + 8
+	// How often the last use of a key is recorded
+	_APIKeyLastUsedInterval = time.Minute
+	// Maximum length of the name of a key
+	_APIKeyMaxNameLength = 100
+)
+
+// CreateAPIKey creates an API key of the user with the scopes, which never expires if expiresAt
+// is nil. The key itself is only returned here, only its hash is stored.
+func (a *Authenticator) CreateAPIKey(ctx context.Context, user *models.User, name string, scopes []string, expiresAt *time.Time) (*models.CreatedAPIKey, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrValidation)
+	}
+	if len(name) > _APIKeyMaxNameLength {
+		return nil, fmt.Errorf("%w: name must be at most %d characters", ErrValidation, _APIKeyMaxNameLength)
+	}
+	if len(scopes) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrValidation)
+	}
+	for _, scope := range scopes {
+		if !slices.Contains(models.APIKeyScopes, scope) {
+			return nil, fmt.Errorf("%w: unknown scope %q, must be one of %s", ErrValidation, scope, strings.Join(models.APIKeyScopes, ", "))
+		}
+	}
+	now := time.Now()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, fmt.Errorf("%w: expiry must be in the future", ErrValidation)
+	}
+
+	random := make([]byte, _APIKeyBytes)
+	if _, err := rand.Read(random); err != nil {
+		return nil, fmt.Errorf("failed to generate API key: %w", err)
+	}
+	key := APIKeyPrefix + base64.RawURLEncoding.EncodeToString(random)
+
+	scopes = slices.Clone(scopes)
+	slices.Sort(scopes)
+
+	apiKey, err := a.keys.CreateAPIKey(ctx, &models.APIKey{
+		UserID:    user.ID,
+		Name:      name,
+		Prefix:    key[:_APIKeyDisplayLength],
+		KeyHash:   hashAPIKey(key),
+		Scopes:    slices.Compact(scopes),
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create API key: %w", err)
+	}
+
+	return &models.CreatedAPIKey{APIKey: *apiKey, Key: key}, nil
+}
+
+// GetAPIKeys returns the API keys of the user, the newest first
+func (a *Authenticator) GetAPIKeys(ctx context.Context, user *models.User) ([]models.APIKey, error) {
+	keys, err := a.keys.GetAPIKeys(ctx, user.ID.Hex())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API keys: %w", err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes an API key of the user, it cannot be used again
+func (a *Authenticator) RevokeAPIKey(ctx context.Context, user *models.User, id string) error {
+	if err := a.keys.RevokeAPIKey(ctx, id, user.ID.Hex()); err != nil {
+		return fmt.Errorf("failed to revoke API key: %w", err)
+	}
+	return nil
+}
+
+// authenticateAPIKey verifies an API key and returns the identity of its user, limited to the
+// scopes of the key
+func (a *Authenticator) authenticateAPIKey(ctx context.Context, key string) (*Identity, error) {
+	apiKey, err := a.keys.GetAPIKeyByHash(ctx, hashAPIKey(key))
+	if errors.Is(err, storage.ErrNotFound) {
+		return nil, fmt.Errorf("%w: unknown API key", ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get API key: %w", err)
+	}
+
+	now := time.Now()
+	if !apiKey.Active(now) {
+		return nil, fmt.Errorf("%w: API key is revoked or expired", ErrInvalidToken)
+	}
+
+	// Keys of deleted and disabled users stop working
+	user, err := checkUser(a.users.GetUserByID(ctx, apiKey.UserID.Hex()))
+	if err != nil {
+		return nil, err
+	}
+
+	// The last use is approximate, so not every request writes to the database
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= _APIKeyLastUsedInterval {
+		if err := a.keys.SetAPIKeyLastUsed(ctx, apiKey.ID.Hex(), now); err != nil {
+			slog.Error("Unable to record API key use", "key", apiKey.Prefix, "error", err.Error())
+		}
+	}
+
+	return &Identity{
+		User:     &models.User{ID: user.ID, Username: user.Username},
+		Scopes:   apiKey.Scopes,
+		APIKeyID: &apiKey.ID,
+	}, nil
+}
+
+// hashAPIKey returns the hash of a key that is stored. Keys are random, so a fast hash is enough
+// and allows looking keys up by their hash.
+func hashAPIKey(key string) string {
+	hash := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(hash[:])
+}
@@ -0,0 +1,168 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	ctx := context.Background()
+	user := &models.User{ID: primitive.NewObjectID(), Username: "anton"}
+
+	t.Run("Success", func(t *testing.T) {
+		keys := new(MockAPIKeyStorage)
+		authenticator, err := NewAuthenticator(new(MockUserStorage), new(MockAuditStorage), keys, testConfig)
+		require.NoError(t, err)
+
+		stored := &models.APIKey{}
+		keys.On("CreateAPIKey", ctx, mock.AnythingOfType("*models.APIKey")).Run(func(args mock.Arguments) {
+			*stored = *args.Get(1).(*models.APIKey)
+			stored.ID = primitive.NewObjectID()
+		}).Return(stored, nil).Once()
+
+		expiresAt := time.Now().Add(24 * time.Hour)
+		created, err := authenticator.CreateAPIKey(ctx, user, " Kitchen shortcuts ", []string{models.ScopeRecipesWrite, models.ScopeRecipesRead, models.ScopeRecipesWrite}, &expiresAt)
+
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(created.Key, APIKeyPrefix))
+		assert.Equal(t, "Kitchen shortcuts", created.Name)
+		assert.Equal(t, user.ID, created.UserID)
+		assert.Equal(t, []string{models.ScopeRecipesRead, models.ScopeRecipesWrite}, created.Scopes)
+		assert.True(t, strings.HasPrefix(created.Key, created.Prefix))
+		// Only the hash of the key is stored
+		assert.Equal(t, hashAPIKey(created.Key), stored.KeyHash)
+		assert.NotContains(t, stored.KeyHash, created.Key)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		keys := new(MockAPIKeyStorage)
+		authenticator, err := NewAuthenticator(new(MockUserStorage), new(MockAuditStorage), keys, testConfig)
+		require.NoError(t, err)
+
+		past := time.Now().Add(-time.Hour)
+		tests := []struct {
+			name      string
+			keyName   string
+			scopes    []string
+			expiresAt *time.Time
+		}{
+			{"No name", " ", []string{models.ScopeRecipesRead}, nil},
+			{"No scopes", "Script", nil, nil},
+			{"Unknown scope", "Script", []string{ScopeAPIKeys}, nil},
+			{"Expired", "Script", []string{models.ScopeRecipesRead}, &past},
+		}
+		for _, test := range tests {
+			_, err := authenticator.CreateAPIKey(ctx, user, test.keyName, test.scopes, test.expiresAt)
+			assert.ErrorIs(t, err, ErrValidation, test.name)
+		}
+		keys.AssertNotCalled(t, "CreateAPIKey", mock.Anything, mock.Anything)
+	})
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	ctx := context.Background()
+	user := newTestUser(t, "secret password")
+	key := APIKeyPrefix + "test-key"
+
+	newAuthenticator := func(t *testing.T) (*Authenticator, *MockUserStorage, *MockAPIKeyStorage) {
+		users := new(MockUserStorage)
+		keys := new(MockAPIKeyStorage)
+		authenticator, err := NewAuthenticator(users, new(MockAuditStorage), keys, testConfig)
+		require.NoError(t, err)
+		return authenticator, users, keys
+	}
+	apiKey := func() *models.APIKey {
+		return &models.APIKey{ID: primitive.NewObjectID(), UserID: user.ID, Prefix: key[:8], KeyHash: hashAPIKey(key), Scopes: []string{models.ScopeRecipesRead, models.ScopeAIImport}}
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		authenticator, users, keys := newAuthenticator(t)
+
+		stored := apiKey()
+		keys.On("GetAPIKeyByHash", ctx, hashAPIKey(key)).Return(stored, nil).Once()
+		users.On("GetUserByID", ctx, user.ID.Hex()).Return(user, nil).Once()
+		keys.On("SetAPIKeyLastUsed", ctx, stored.ID.Hex(), mock.AnythingOfType("time.Time")).Return(nil).Once()
+
+		identity, err := authenticator.Authenticate(ctx, key)
+
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, identity.User.ID)
+		assert.Empty(t, identity.User.PasswordHash)
+		assert.Equal(t, &stored.ID, identity.APIKeyID)
+		assert.True(t, identity.HasScope(models.ScopeAIImport))
+		assert.False(t, identity.HasScope(models.ScopeRecipesWrite))
+		assert.False(t, identity.HasScope(ScopeAPIKeys))
+		keys.AssertExpectations(t)
+	})
+
+	t.Run("Recently used", func(t *testing.T) {
+		authenticator, users, keys := newAuthenticator(t)
+
+		stored := apiKey()
+		lastUsedAt := time.Now().Add(-10 * time.Second)
+		stored.LastUsedAt = &lastUsedAt
+		keys.On("GetAPIKeyByHash", ctx, hashAPIKey(key)).Return(stored, nil).Once()
+		users.On("GetUserByID", ctx, user.ID.Hex()).Return(user, nil).Once()
+
+		_, err := authenticator.Authenticate(ctx, key)
+
+		assert.NoError(t, err)
+		keys.AssertNotCalled(t, "SetAPIKeyLastUsed", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown", func(t *testing.T) {
+		authenticator, _, keys := newAuthenticator(t)
+
+		keys.On("GetAPIKeyByHash", ctx, hashAPIKey(key)).Return(nil, storage.ErrNotFound).Once()
+
+		_, err := authenticator.Authenticate(ctx, key)
+
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Revoked", func(t *testing.T) {
+		authenticator, _, keys := newAuthenticator(t)
+
+		stored := apiKey()
+		revokedAt := time.Now().Add(-time.Hour)
+		stored.RevokedAt = &revokedAt
+		keys.On("GetAPIKeyByHash", ctx, hashAPIKey(key)).Return(stored, nil).Once()
+
+		_, err := authenticator.Authenticate(ctx, key)
+
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		authenticator, _, keys := newAuthenticator(t)
+
+		stored := apiKey()
+		expiresAt := time.Now().Add(-time.Minute)
+		stored.ExpiresAt = &expiresAt
+		keys.On("GetAPIKeyByHash", ctx, hashAPIKey(key)).Return(stored, nil).Once()
+
+		_, err := authenticator.Authenticate(ctx, key)
+
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Deleted user", func(t *testing.T) {
+		authenticator, users, keys := newAuthenticator(t)
+
+		keys.On("GetAPIKeyByHash", ctx, hashAPIKey(key)).Return(apiKey(), nil).Once()
+		users.On("GetUserByID", ctx, user.ID.Hex()).Return(nil, storage.ErrNotFound).Once()
+
+		_, err := authenticator.Authenticate(ctx, key)
+
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
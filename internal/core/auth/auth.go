@@ -0,0 +1,249 @@
+// Package auth authenticates users with pre-shared credentials and issues signed, short-lived
+// JWTs for the endpoints that change data. Tokens are stateless: a request is authenticated by
+// the signature and claims of its token, without a session in the database. Scripts and
+// integrations authenticate with API keys instead, which are limited to their scopes.
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/golang-jwt/jwt/v5"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Minimum length of the signing secret in bytes
+const _MinSecretLength = 32
+
+// Token types, a refresh token cannot be used as an access token and vice versa
+const (
+	_AccessToken  = "access"
+	_RefreshToken = "refresh"
+)
+
+type Config struct {
+	// Secret the tokens are signed with (HMAC-SHA256), at least 32 bytes
+	Secret []byte
+	// Issuer of the tokens
+	Issuer string
+	// Lifetime of access tokens, which authenticate requests
+	AccessTokenTTL time.Duration
+	// Lifetime of refresh tokens, which are exchanged for new tokens
+	RefreshTokenTTL time.Duration
+	// Failed logins of a username, and of a client IP, before it is locked (0 never locks)
+	MaxFailedLogins      int
+	MaxFailedLoginsPerIP int
+	// How long failed logins are counted, and how long a lock lasts
+	LockoutDuration time.Duration
+}
+
+type claims struct {
+	Username  string `json:"username"`
+	Admin     bool   `json:"admin,omitempty"`
+	TokenType string `json:"token_type"`
+	jwt.RegisteredClaims
+}
+
+// Authenticator logs users in and verifies their tokens
+type Authenticator struct {
+	users  storage.UserStorage
+	audits storage.AuditStorage
+	keys   storage.APIKeyStorage
+	config Config
+	parser *jwt.Parser
+	// Hash compared on logins of unknown users, so they take as long as logins of known users
+	dummyHash string
+}
+
+func NewAuthenticator(users storage.UserStorage, audits storage.AuditStorage, keys storage.APIKeyStorage, config Config) (*Authenticator, error) {
+	if len(config.Secret) < _MinSecretLength {
+		return nil, fmt.Errorf("secret must be at least %d bytes", _MinSecretLength)
+	}
+	if config.AccessTokenTTL <= 0 || config.RefreshTokenTTL <= 0 {
+		return nil, fmt.Errorf("token lifetimes must be positive")
+	}
+	if (config.MaxFailedLogins > 0 || config.MaxFailedLoginsPerIP > 0) && config.LockoutDuration <= 0 {
+		return nil, fmt.Errorf("lockout duration must be positive")
+	}
+
+	dummyHash, err := HashPassword("dummy password")
+	if err != nil {
+		return nil, err
+	}
+
+	return &Authenticator{
+		users:  users,
+		audits: audits,
+		keys:   keys,
+		config: config,
+		parser: jwt.NewParser(
+			jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+			jwt.WithIssuer(config.Issuer),
+			jwt.WithExpirationRequired(),
+			jwt.WithIssuedAt(),
+		),
+		dummyHash: dummyHash,
+	}, nil
+}
+
+// Login checks the credentials of the user and issues new tokens. Failed logins are counted
+// for the username and the client IP (if known), which are locked after too many of them.
+func (a *Authenticator) Login(ctx context.Context, username string, password string, clientIP string) (*models.AuthTokens, error) {
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	// Locked logins are refused even with the correct password
+	lockouts := a.lockouts(username, clientIP)
+	if err := a.checkLocks(ctx, lockouts); err != nil {
+		return nil, err
+	}
+	reserved, err := a.reserveAttempts(ctx, lockouts)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := a.users.GetUserByUsername(ctx, username)
+	if err != nil && !errors.Is(err, storage.ErrNotFound) {
+		// The attempt is not a failed login
+		if err := a.releaseAttempts(ctx, lockouts); err != nil {
+			slog.Error("Unable to release login attempt", "error", err.Error())
+		}
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+
+	// Unknown users are compared with a dummy hash, so they take as long as known users
+	hash := a.dummyHash
+	if user != nil {
+		hash = user.PasswordHash
+	}
+	if !checkPassword(hash, password) || user == nil {
+		if err := a.recordFailure(ctx, lockouts, reserved); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	// Only users that know their password learn that they are disabled
+	if user.Disabled() {
+		if err := a.releaseAttempts(ctx, lockouts); err != nil {
+			return nil, err
+		}
+		return nil, ErrAccountDisabled
+	}
+
+	// The failures of the username are reset, but only the attempt itself is uncounted for the
+	// client IP, a valid login must not hide guessing of others
+	for _, lockout := range lockouts {
+		var err error
+		if lockout.key == usernameKey(username) {
+			err = a.users.DeleteLoginAttempts(ctx, lockout.key)
+		} else {
+			err = a.users.ReleaseLoginAttempt(ctx, lockout.key)
+		}
+		if err != nil && !errors.Is(err, storage.ErrNotFound) {
+			return nil, fmt.Errorf("failed to reset failed logins: %w", err)
+		}
+	}
+
+	return a.issueTokens(user)
+}
+
+// Refresh exchanges a refresh token for new tokens, if its user still exists and is not disabled
+func (a *Authenticator) Refresh(ctx context.Context, refreshToken string) (*models.AuthTokens, error) {
+	claims, err := a.parse(refreshToken, _RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := checkUser(a.users.GetUserByID(ctx, claims.Subject))
+	if err != nil {
+		return nil, err
+	}
+
+	return a.issueTokens(user)
+}
+
+// Authenticate verifies an access token or API key and returns its identity. The user of an
+// access token is as recorded in the token.
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (*Identity, error) {
+	if strings.HasPrefix(token, APIKeyPrefix) {
+		return a.authenticateAPIKey(ctx, token)
+	}
+
+	claims, err := a.parse(token, _AccessToken)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := primitive.ObjectIDFromHex(claims.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid subject", ErrInvalidToken)
+	}
+
+	return &Identity{
+		User:   &models.User{ID: id, Username: claims.Username, Admin: claims.Admin},
+		Scopes: accessTokenScopes,
+	}, nil
+}
+
+func (a *Authenticator) issueTokens(user *models.User) (*models.AuthTokens, error) {
+	now := time.Now()
+
+	accessToken, err := a.sign(user, _AccessToken, now, a.config.AccessTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := a.sign(user, _RefreshToken, now, a.config.RefreshTokenTTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.AuthTokens{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(a.config.AccessTokenTTL.Seconds()),
+	}, nil
+}
+
+func (a *Authenticator) sign(user *models.User, tokenType string, now time.Time, ttl time.Duration) (string, error) {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		Username:  user.Username,
+		Admin:     user.Admin,
+		TokenType: tokenType,
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    a.config.Issuer,
+			Subject:   user.ID.Hex(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
+		},
+	})
+
+	signed, err := token.SignedString(a.config.Secret)
+	if err != nil {
+		return "", fmt.Errorf("failed to sign token: %w", err)
+	}
+	return signed, nil
+}
+
+func (a *Authenticator) parse(token string, tokenType string) (*claims, error) {
+	parsed := &claims{}
+	_, err := a.parser.ParseWithClaims(token, parsed, func(*jwt.Token) (any, error) {
+		return a.config.Secret, nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	if parsed.TokenType != tokenType {
+		return nil, fmt.Errorf("%w: token type is %q, not %q", ErrInvalidToken, parsed.TokenType, tokenType)
+	}
+
+	return parsed, nil
+}
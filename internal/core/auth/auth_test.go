@@ -0,0 +1,371 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockUserStorage is a mock implementation of the storage.UserStorage interface
+type MockUserStorage struct {
+	mock.Mock
+}
+
+func (m *MockUserStorage) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserStorage) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	args := m.Called(ctx, username)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserStorage) GetUsers(ctx context.Context) ([]models.User, error) {
+	args := m.Called(ctx)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.User), args.Error(1)
+}
+
+func (m *MockUserStorage) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
+	args := m.Called(ctx, user)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.User), args.Error(1)
+}
+
+func (m *MockUserStorage) SetUserPassword(ctx context.Context, id string, passwordHash string) error {
+	args := m.Called(ctx, id, passwordHash)
+	return args.Error(0)
+}
+
+func (m *MockUserStorage) SetUserDisabled(ctx context.Context, id string, disabledAt *time.Time) error {
+	args := m.Called(ctx, id, disabledAt)
+	return args.Error(0)
+}
+
+func (m *MockUserStorage) SetUserAdmin(ctx context.Context, id string, admin bool) error {
+	args := m.Called(ctx, id, admin)
+	return args.Error(0)
+}
+
+func (m *MockUserStorage) DeleteUser(ctx context.Context, id string) error {
+	args := m.Called(ctx, id)
+	return args.Error(0)
+}
+
+func (m *MockUserStorage) GetLoginAttempts(ctx context.Context, key string) (*models.LoginAttempts, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LoginAttempts), args.Error(1)
+}
+
+func (m *MockUserStorage) RecordFailedLogin(ctx context.Context, key string, window time.Duration) (*models.LoginAttempts, error) {
+	args := m.Called(ctx, key, window)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.LoginAttempts), args.Error(1)
+}
+
+func (m *MockUserStorage) ReleaseLoginAttempt(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func (m *MockUserStorage) LockLogin(ctx context.Context, key string, until time.Time) error {
+	args := m.Called(ctx, key, until)
+	return args.Error(0)
+}
+
+func (m *MockUserStorage) DeleteLoginAttempts(ctx context.Context, key string) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+// MockAuditStorage is a mock implementation of the storage.AuditStorage interface
+type MockAuditStorage struct {
+	mock.Mock
+}
+
+func (m *MockAuditStorage) CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	args := m.Called(ctx, entry)
+	return args.Error(0)
+}
+
+// MockAPIKeyStorage is a mock implementation of the storage.APIKeyStorage interface
+type MockAPIKeyStorage struct {
+	mock.Mock
+}
+
+func (m *MockAPIKeyStorage) CreateAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	args := m.Called(ctx, key)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyStorage) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	args := m.Called(ctx, keyHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyStorage) GetAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.APIKey), args.Error(1)
+}
+
+func (m *MockAPIKeyStorage) RevokeAPIKey(ctx context.Context, id string, userID string) error {
+	args := m.Called(ctx, id, userID)
+	return args.Error(0)
+}
+
+func (m *MockAPIKeyStorage) SetAPIKeyLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	args := m.Called(ctx, id, usedAt)
+	return args.Error(0)
+}
+
+var testConfig = Config{
+	Secret:          []byte("0123456789abcdef0123456789abcdef"),
+	Issuer:          "recipebank",
+	AccessTokenTTL:  15 * time.Minute,
+	RefreshTokenTTL: time.Hour,
+}
+
+func newTestUser(t *testing.T, password string) *models.User {
+	hash, err := HashPassword(password)
+	require.NoError(t, err)
+	return &models.User{ID: primitive.NewObjectID(), Username: "anton", PasswordHash: hash}
+}
+
+func TestNewAuthenticator(t *testing.T) {
+	_, err := NewAuthenticator(new(MockUserStorage), new(MockAuditStorage), new(MockAPIKeyStorage), testConfig)
+	assert.NoError(t, err)
+
+	config := testConfig
+	config.Secret = []byte("too short")
+	_, err = NewAuthenticator(new(MockUserStorage), new(MockAuditStorage), new(MockAPIKeyStorage), config)
+	assert.Error(t, err)
+
+	config = testConfig
+	config.MaxFailedLogins = 5
+	_, err = NewAuthenticator(new(MockUserStorage), new(MockAuditStorage), new(MockAPIKeyStorage), config)
+	assert.Error(t, err)
+
+	config = testConfig
+	config.AccessTokenTTL = 0
+	_, err = NewAuthenticator(new(MockUserStorage), new(MockAuditStorage), new(MockAPIKeyStorage), config)
+	assert.Error(t, err)
+}
+
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+	user := newTestUser(t, "secret password")
+
+	t.Run("Success", func(t *testing.T) {
+		users := new(MockUserStorage)
+		authenticator, err := NewAuthenticator(users, new(MockAuditStorage), new(MockAPIKeyStorage), testConfig)
+		require.NoError(t, err)
+
+		users.On("GetUserByUsername", ctx, "anton").Return(user, nil).Once()
+
+		tokens, err := authenticator.Login(ctx, "anton", "secret password", "")
+
+		require.NoError(t, err)
+		assert.Equal(t, "Bearer", tokens.TokenType)
+		assert.Equal(t, 900, tokens.ExpiresIn)
+
+		identity, err := authenticator.Authenticate(ctx, tokens.AccessToken)
+		require.NoError(t, err)
+		assert.Equal(t, user.ID, identity.User.ID)
+		assert.Equal(t, "anton", identity.User.Username)
+		assert.Empty(t, identity.User.PasswordHash)
+		assert.Nil(t, identity.APIKeyID)
+		assert.True(t, identity.HasScope(ScopeAPIKeys))
+	})
+
+	t.Run("Wrong password", func(t *testing.T) {
+		users := new(MockUserStorage)
+		authenticator, err := NewAuthenticator(users, new(MockAuditStorage), new(MockAPIKeyStorage), testConfig)
+		require.NoError(t, err)
+
+		users.On("GetUserByUsername", ctx, "anton").Return(user, nil).Once()
+
+		_, err = authenticator.Login(ctx, "anton", "wrong password", "")
+
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		users := new(MockUserStorage)
+		authenticator, err := NewAuthenticator(users, new(MockAuditStorage), new(MockAPIKeyStorage), testConfig)
+		require.NoError(t, err)
+
+		users.On("GetUserByUsername", ctx, "nobody").Return(nil, storage.ErrNotFound).Once()
+
+		_, err = authenticator.Login(ctx, "nobody", "secret password", "")
+
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("Empty credentials", func(t *testing.T) {
+		users := new(MockUserStorage)
+		authenticator, err := NewAuthenticator(users, new(MockAuditStorage), new(MockAPIKeyStorage), testConfig)
+		require.NoError(t, err)
+
+		_, err = authenticator.Login(ctx, "anton", "", "")
+
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		users.AssertNotCalled(t, "GetUserByUsername", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Storage error", func(t *testing.T) {
+		users := new(MockUserStorage)
+		authenticator, err := NewAuthenticator(users, new(MockAuditStorage), new(MockAPIKeyStorage), testConfig)
+		require.NoError(t, err)
+
+		users.On("GetUserByUsername", ctx, "anton").Return(nil, errors.New("database down")).Once()
+
+		_, err = authenticator.Login(ctx, "anton", "secret password", "")
+
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrInvalidCredentials)
+	})
+}
+
+func TestRefresh(t *testing.T) {
+	ctx := context.Background()
+	user := newTestUser(t, "secret password")
+
+	users := new(MockUserStorage)
+	authenticator, err := NewAuthenticator(users, new(MockAuditStorage), new(MockAPIKeyStorage), testConfig)
+	require.NoError(t, err)
+
+	users.On("GetUserByUsername", ctx, "anton").Return(user, nil).Once()
+	tokens, err := authenticator.Login(ctx, "anton", "secret password", "")
+	require.NoError(t, err)
+
+	t.Run("Success", func(t *testing.T) {
+		users.On("GetUserByID", ctx, user.ID.Hex()).Return(user, nil).Once()
+
+		refreshed, err := authenticator.Refresh(ctx, tokens.RefreshToken)
+
+		require.NoError(t, err)
+		_, err = authenticator.Authenticate(ctx, refreshed.AccessToken)
+		assert.NoError(t, err)
+	})
+
+	t.Run("Deleted user", func(t *testing.T) {
+		users.On("GetUserByID", ctx, user.ID.Hex()).Return(nil, storage.ErrNotFound).Once()
+
+		_, err := authenticator.Refresh(ctx, tokens.RefreshToken)
+
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Access token", func(t *testing.T) {
+		_, err := authenticator.Refresh(ctx, tokens.AccessToken)
+
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	ctx := context.Background()
+	user := newTestUser(t, "secret password")
+
+	users := new(MockUserStorage)
+	authenticator, err := NewAuthenticator(users, new(MockAuditStorage), new(MockAPIKeyStorage), testConfig)
+	require.NoError(t, err)
+
+	users.On("GetUserByUsername", ctx, "anton").Return(user, nil).Once()
+	tokens, err := authenticator.Login(ctx, "anton", "secret password", "")
+	require.NoError(t, err)
+
+	t.Run("Refresh token", func(t *testing.T) {
+		_, err := authenticator.Authenticate(ctx, tokens.RefreshToken)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Tampered", func(t *testing.T) {
+		_, err := authenticator.Authenticate(ctx, tokens.AccessToken+"x")
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Other secret", func(t *testing.T) {
+		config := testConfig
+		config.Secret = []byte("fedcba9876543210fedcba9876543210")
+		other, err := NewAuthenticator(users, new(MockAuditStorage), new(MockAPIKeyStorage), config)
+		require.NoError(t, err)
+
+		_, err = other.Authenticate(ctx, tokens.AccessToken)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Expired", func(t *testing.T) {
+		expired, err := authenticator.sign(user, _AccessToken, time.Now().Add(-time.Hour), time.Minute)
+		require.NoError(t, err)
+
+		_, err = authenticator.Authenticate(ctx, expired)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("Unsigned", func(t *testing.T) {
+		token := jwt.NewWithClaims(jwt.SigningMethodNone, claims{
+			TokenType: _AccessToken,
+			RegisteredClaims: jwt.RegisteredClaims{
+				Issuer:    testConfig.Issuer,
+				Subject:   user.ID.Hex(),
+				ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+			},
+		})
+		unsigned, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+		require.NoError(t, err)
+
+		_, err = authenticator.Authenticate(ctx, unsigned)
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
+
+func TestIdentityContext(t *testing.T) {
+	_, ok := UserFromContext(context.Background())
+	assert.False(t, ok)
+
+	user := &models.User{ID: primitive.NewObjectID(), Username: "anton"}
+	ctx := WithIdentity(context.Background(), &Identity{User: user, Scopes: []string{models.ScopeRecipesRead}})
+	fromContext, ok := UserFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, user, fromContext)
+
+	identity, ok := IdentityFromContext(ctx)
+	assert.True(t, ok)
+	assert.True(t, identity.HasScope(models.ScopeRecipesRead))
+	assert.False(t, identity.HasScope(models.ScopeRecipesWrite))
+}
@@ -0,0 +1,61 @@
+package auth
+
+import (
+	"context"
+	"slices"
+
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// ScopeAPIKeys allows managing API keys. It is only granted to access tokens, an API key
+	// cannot create other keys.
+	ScopeAPIKeys = "api-keys"
+	// ScopeHouseholds allows managing households, their members and invites. It is only granted
+	// to access tokens.
+	ScopeHouseholds = "households"
+)
+
+// Scopes of access tokens, which act as their user without restrictions
+var accessTokenScopes = append(slices.Clone(models.APIKeyScopes), ScopeAPIKeys, ScopeHouseholds)
+
+// Identity is an authenticated user, and the scopes of the credentials it authenticated with
+type Identity struct {
+	User   *models.User
+	Scopes []string
+	// ID of the API key it authenticated with, nil for access tokens
+	APIKeyID *primitive.ObjectID
+	// Active household of the request, zero if none is selected
+	HouseholdID primitive.ObjectID
+	// Role in the active household, empty for admins that are not members
+	HouseholdRole string
+}
+
+// HasScope reports whether the identity is allowed the scope
+func (i *Identity) HasScope(scope string) bool {
+	return slices.Contains(i.Scopes, scope)
+}
+
+type identityKey struct{}
+
+// WithIdentity returns a context with the authenticated identity
+func WithIdentity(ctx context.Context, identity *Identity) context.Context {
+	return context.WithValue(ctx, identityKey{}, identity)
+}
+
+// IdentityFromContext returns the authenticated identity of the context, or false for anonymous
+// requests
+func IdentityFromContext(ctx context.Context) (*Identity, bool) {
+	identity, ok := ctx.Value(identityKey{}).(*Identity)
+	return identity, ok && identity != nil && identity.User != nil
+}
+
+// UserFromContext returns the authenticated user of the context, or false for anonymous requests
+func UserFromContext(ctx context.Context) (*models.User, bool) {
+	identity, ok := IdentityFromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	return identity.User, true
+}
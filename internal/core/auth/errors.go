@@ -0,0 +1,30 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrInvalidToken       = errors.New("invalid or expired token")
+	ErrAccountLocked      = errors.New("account is locked")
+	ErrAccountDisabled    = errors.New("account is disabled")
+	ErrNotLocked          = errors.New("not locked")
+	ErrValidation         = errors.New("validation error")
+)
+
+// LockedError is returned for logins of locked accounts and client IPs. It wraps
+// ErrAccountLocked.
+type LockedError struct {
+	Until time.Time
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s until %s", ErrAccountLocked, e.Until.Format(time.RFC3339))
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrAccountLocked
+}
@@ -0,0 +1,192 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+)
+
+// Login attempts are tracked per username and per client IP, so guessing the passwords of
+// many accounts from one client is locked as well
+func usernameKey(username string) string {
+	return "user:" + username
+}
+
+func clientIPKey(clientIP string) string {
+	return "ip:" + clientIP
+}
+
+// Unlock removes the lock and failed logins of the username. The actor is recorded in the audit
+// log.
+func (a *Authenticator) Unlock(ctx context.Context, username string, actor string) error {
+	return a.unlock(ctx, usernameKey(username), &models.AuditEntry{Username: username, Actor: actor})
+}
+
+// UnlockClient removes the lock and failed logins of the client IP. The actor is recorded in
+// the audit log.
+func (a *Authenticator) UnlockClient(ctx context.Context, clientIP string, actor string) error {
+	return a.unlock(ctx, clientIPKey(clientIP), &models.AuditEntry{ClientIP: clientIP, Actor: actor})
+}
+
+func (a *Authenticator) unlock(ctx context.Context, key string, entry *models.AuditEntry) error {
+	attempts, err := a.users.GetLoginAttempts(ctx, key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return ErrNotLocked
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get login attempts: %w", err)
+	}
+	if !attempts.Locked(time.Now()) {
+		return ErrNotLocked
+	}
+
+	if err := a.users.DeleteLoginAttempts(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+		return fmt.Errorf("failed to unlock: %w", err)
+	}
+
+	entry.Action = models.AuditAccountUnlocked
+	a.audit(ctx, entry)
+	return nil
+}
+
+// lockout is a username or client IP that is locked after too many failed logins
+type lockout struct {
+	key         string
+	maxFailures int
+	// Audit entry of its lock
+	entry models.AuditEntry
+}
+
+// lockouts returns the enabled lockouts of a login
+func (a *Authenticator) lockouts(username string, clientIP string) []lockout {
+	lockouts := []lockout{}
+	if a.config.MaxFailedLogins > 0 {
+		lockouts = append(lockouts, lockout{
+			key:         usernameKey(username),
+			maxFailures: a.config.MaxFailedLogins,
+			entry:       models.AuditEntry{Username: username, ClientIP: clientIP},
+		})
+	}
+	if a.config.MaxFailedLoginsPerIP > 0 && clientIP != "" {
+		lockouts = append(lockouts, lockout{
+			key:         clientIPKey(clientIP),
+			maxFailures: a.config.MaxFailedLoginsPerIP,
+			entry:       models.AuditEntry{ClientIP: clientIP},
+		})
+	}
+	return lockouts
+}
+
+// checkLocks returns a LockedError if any of the lockouts is locked
+func (a *Authenticator) checkLocks(ctx context.Context, lockouts []lockout) error {
+	now := time.Now()
+	for _, lockout := range lockouts {
+		attempts, err := a.users.GetLoginAttempts(ctx, lockout.key)
+		if errors.Is(err, storage.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get login attempts: %w", err)
+		}
+		if attempts.Locked(now) {
+			return &LockedError{Until: *attempts.LockedUntil}
+		}
+	}
+	return nil
+}
+
+// reserveAttempts counts the login attempt for the lockouts before its password is checked, so
+// parallel guesses cannot all pass a lockout that is not locked yet. It returns the attempts of
+// each lockout, including this one, or a LockedError if any of them is over its maximum of failed
+// logins. A refused attempt stays counted.
+func (a *Authenticator) reserveAttempts(ctx context.Context, lockouts []lockout) ([]*models.LoginAttempts, error) {
+	now := time.Now()
+	reserved := make([]*models.LoginAttempts, len(lockouts))
+	var locked *LockedError
+	for i, lockout := range lockouts {
+		attempts, err := a.users.RecordFailedLogin(ctx, lockout.key, a.config.LockoutDuration)
+		if err != nil {
+			return nil, fmt.Errorf("failed to record login attempt: %w", err)
+		}
+		reserved[i] = attempts
+
+		switch {
+		case attempts.Locked(now):
+			locked = &LockedError{Until: *attempts.LockedUntil}
+		case attempts.Failures > lockout.maxFailures:
+			until, err := a.lock(ctx, lockout, attempts.Failures)
+			if err != nil {
+				return nil, err
+			}
+			locked = &LockedError{Until: until}
+		}
+	}
+
+	if locked != nil {
+		return nil, locked
+	}
+	return reserved, nil
+}
+
+// releaseAttempts uncounts the reserved login attempt for the lockouts, as it was not a failed
+// login
+func (a *Authenticator) releaseAttempts(ctx context.Context, lockouts []lockout) error {
+	for _, lockout := range lockouts {
+		if err := a.users.ReleaseLoginAttempt(ctx, lockout.key); err != nil {
+			return fmt.Errorf("failed to release login attempt: %w", err)
+		}
+	}
+	return nil
+}
+
+// recordFailure keeps the reserved login attempts as failed logins, and locks the lockouts that
+// reached their maximum of failed logins. It returns a LockedError if any of them is locked.
+func (a *Authenticator) recordFailure(ctx context.Context, lockouts []lockout, reserved []*models.LoginAttempts) error {
+	var locked *LockedError
+	for i, lockout := range lockouts {
+		if reserved[i].Failures < lockout.maxFailures {
+			continue
+		}
+
+		until, err := a.lock(ctx, lockout, reserved[i].Failures)
+		if err != nil {
+			return err
+		}
+		locked = &LockedError{Until: until}
+	}
+
+	if locked != nil {
+		return locked
+	}
+	return nil
+}
+
+// lock locks logins of the lockout and records it in the audit log
+func (a *Authenticator) lock(ctx context.Context, lockout lockout, failures int) (time.Time, error) {
+	until := time.Now().Add(a.config.LockoutDuration)
+	if err := a.users.LockLogin(ctx, lockout.key, until); err != nil {
+		return time.Time{}, fmt.Errorf("failed to lock logins: %w", err)
+	}
+
+	entry := lockout.entry
+	entry.Action = models.AuditAccountLocked
+	entry.Failures = failures
+	entry.LockedUntil = &until
+	a.audit(ctx, &entry)
+
+	return until, nil
+}
+
+// audit records the entry in the audit log. A failure is logged, but does not fail the action
+// that is audited.
+func (a *Authenticator) audit(ctx context.Context, entry *models.AuditEntry) {
+	entry.CreatedAt = time.Now()
+	if err := a.audits.CreateAuditEntry(ctx, entry); err != nil {
+		slog.Error("Unable to write audit entry", "action", entry.Action, "error", err.Error())
+	}
+}
@@ -0,0 +1,353 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoginLockout(t *testing.T) {
+	ctx := context.Background()
+	user := newTestUser(t, "secret password")
+
+	config := testConfig
+	config.MaxFailedLogins = 3
+	config.MaxFailedLoginsPerIP = 10
+	config.LockoutDuration = 15 * time.Minute
+
+	newAuthenticator := func(t *testing.T) (*Authenticator, *MockUserStorage, *MockAuditStorage) {
+		users := new(MockUserStorage)
+		audits := new(MockAuditStorage)
+		authenticator, err := NewAuthenticator(users, audits, new(MockAPIKeyStorage), config)
+		require.NoError(t, err)
+		return authenticator, users, audits
+	}
+	notLocked := func(users *MockUserStorage) {
+		users.On("GetLoginAttempts", ctx, "user:anton").Return(nil, storage.ErrNotFound).Once()
+		users.On("GetLoginAttempts", ctx, "ip:192.0.2.1").Return(nil, storage.ErrNotFound).Once()
+	}
+
+	t.Run("Failure counted", func(t *testing.T) {
+		authenticator, users, _ := newAuthenticator(t)
+
+		notLocked(users)
+		users.On("GetUserByUsername", ctx, "anton").Return(user, nil).Once()
+		users.On("RecordFailedLogin", ctx, "user:anton", config.LockoutDuration).Return(&models.LoginAttempts{Failures: 1}, nil).Once()
+		users.On("RecordFailedLogin", ctx, "ip:192.0.2.1", config.LockoutDuration).Return(&models.LoginAttempts{Failures: 1}, nil).Once()
+
+		_, err := authenticator.Login(ctx, "anton", "wrong password", "192.0.2.1")
+
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+		users.AssertExpectations(t)
+		users.AssertNotCalled(t, "LockLogin", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Locked after max failures", func(t *testing.T) {
+		authenticator, users, audits := newAuthenticator(t)
+
+		notLocked(users)
+		users.On("GetUserByUsername", ctx, "anton").Return(user, nil).Once()
+		users.On("RecordFailedLogin", ctx, "user:anton", config.LockoutDuration).Return(&models.LoginAttempts{Failures: 3}, nil).Once()
+		users.On("RecordFailedLogin", ctx, "ip:192.0.2.1", config.LockoutDuration).Return(&models.LoginAttempts{Failures: 3}, nil).Once()
+		users.On("LockLogin", ctx, "user:anton", mock.AnythingOfType("time.Time")).Return(nil).Once()
+		audits.On("CreateAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == models.AuditAccountLocked && entry.Username == "anton" && entry.ClientIP == "192.0.2.1" && entry.Failures == 3
+		})).Return(nil).Once()
+
+		_, err := authenticator.Login(ctx, "anton", "wrong password", "192.0.2.1")
+
+		var lockedErr *LockedError
+		assert.ErrorIs(t, err, ErrAccountLocked)
+		if assert.ErrorAs(t, err, &lockedErr) {
+			assert.WithinDuration(t, time.Now().Add(config.LockoutDuration), lockedErr.Until, time.Minute)
+		}
+		users.AssertExpectations(t)
+		audits.AssertExpectations(t)
+	})
+
+	t.Run("Unknown users are locked", func(t *testing.T) {
+		authenticator, users, audits := newAuthenticator(t)
+
+		users.On("GetLoginAttempts", ctx, "user:nobody").Return(nil, storage.ErrNotFound).Once()
+		users.On("GetUserByUsername", ctx, "nobody").Return(nil, storage.ErrNotFound).Once()
+		users.On("RecordFailedLogin", ctx, "user:nobody", config.LockoutDuration).Return(&models.LoginAttempts{Failures: 3}, nil).Once()
+		users.On("LockLogin", ctx, "user:nobody", mock.AnythingOfType("time.Time")).Return(nil).Once()
+		audits.On("CreateAuditEntry", ctx, mock.Anything).Return(nil).Once()
+
+		// Without a client IP only the username is counted
+		_, err := authenticator.Login(ctx, "nobody", "secret password", "")
+
+		assert.ErrorIs(t, err, ErrAccountLocked)
+		users.AssertExpectations(t)
+	})
+
+	t.Run("Locked account", func(t *testing.T) {
+		authenticator, users, _ := newAuthenticator(t)
+
+		lockedUntil := time.Now().Add(5 * time.Minute)
+		users.On("GetLoginAttempts", ctx, "user:anton").Return(&models.LoginAttempts{Failures: 3, LockedUntil: &lockedUntil}, nil).Once()
+
+		// The correct password does not help
+		_, err := authenticator.Login(ctx, "anton", "secret password", "192.0.2.1")
+
+		var lockedErr *LockedError
+		if assert.ErrorAs(t, err, &lockedErr) {
+			assert.Equal(t, lockedUntil, lockedErr.Until)
+		}
+		users.AssertNotCalled(t, "GetUserByUsername", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Locked client IP", func(t *testing.T) {
+		authenticator, users, _ := newAuthenticator(t)
+
+		lockedUntil := time.Now().Add(5 * time.Minute)
+		users.On("GetLoginAttempts", ctx, "user:anton").Return(nil, storage.ErrNotFound).Once()
+		users.On("GetLoginAttempts", ctx, "ip:192.0.2.1").Return(&models.LoginAttempts{Failures: 10, LockedUntil: &lockedUntil}, nil).Once()
+
+		_, err := authenticator.Login(ctx, "anton", "secret password", "192.0.2.1")
+
+		assert.ErrorIs(t, err, ErrAccountLocked)
+	})
+
+	t.Run("Expired lock", func(t *testing.T) {
+		authenticator, users, _ := newAuthenticator(t)
+
+		lockedUntil := time.Now().Add(-time.Minute)
+		users.On("GetLoginAttempts", ctx, "user:anton").Return(&models.LoginAttempts{Failures: 3, LockedUntil: &lockedUntil}, nil).Once()
+		users.On("GetLoginAttempts", ctx, "ip:192.0.2.1").Return(nil, storage.ErrNotFound).Once()
+		users.On("RecordFailedLogin", ctx, "user:anton", config.LockoutDuration).Return(&models.LoginAttempts{Failures: 1}, nil).Once()
+		users.On("RecordFailedLogin", ctx, "ip:192.0.2.1", config.LockoutDuration).Return(&models.LoginAttempts{Failures: 1}, nil).Once()
+		users.On("GetUserByUsername", ctx, "anton").Return(user, nil).Once()
+		users.On("DeleteLoginAttempts", ctx, "user:anton").Return(nil).Once()
+		users.On("ReleaseLoginAttempt", ctx, "ip:192.0.2.1").Return(nil).Once()
+
+		_, err := authenticator.Login(ctx, "anton", "secret password", "192.0.2.1")
+
+		assert.NoError(t, err)
+		users.AssertExpectations(t)
+	})
+
+	t.Run("Over max attempts", func(t *testing.T) {
+		authenticator, users, audits := newAuthenticator(t)
+
+		// Attempts in parallel have taken the remaining failures of the username
+		notLocked(users)
+		users.On("RecordFailedLogin", ctx, "user:anton", config.LockoutDuration).Return(&models.LoginAttempts{Failures: 4}, nil).Once()
+		users.On("RecordFailedLogin", ctx, "ip:192.0.2.1", config.LockoutDuration).Return(&models.LoginAttempts{Failures: 4}, nil).Once()
+		users.On("LockLogin", ctx, "user:anton", mock.AnythingOfType("time.Time")).Return(nil).Once()
+		audits.On("CreateAuditEntry", ctx, mock.Anything).Return(nil).Once()
+
+		// The correct password is not checked
+		_, err := authenticator.Login(ctx, "anton", "secret password", "192.0.2.1")
+
+		assert.ErrorIs(t, err, ErrAccountLocked)
+		users.AssertExpectations(t)
+		users.AssertNotCalled(t, "GetUserByUsername", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Storage error releases attempt", func(t *testing.T) {
+		authenticator, users, _ := newAuthenticator(t)
+
+		notLocked(users)
+		users.On("RecordFailedLogin", ctx, "user:anton", config.LockoutDuration).Return(&models.LoginAttempts{Failures: 1}, nil).Once()
+		users.On("RecordFailedLogin", ctx, "ip:192.0.2.1", config.LockoutDuration).Return(&models.LoginAttempts{Failures: 1}, nil).Once()
+		users.On("GetUserByUsername", ctx, "anton").Return(nil, errors.New("database down")).Once()
+		users.On("ReleaseLoginAttempt", ctx, "user:anton").Return(nil).Once()
+		users.On("ReleaseLoginAttempt", ctx, "ip:192.0.2.1").Return(nil).Once()
+
+		_, err := authenticator.Login(ctx, "anton", "secret password", "192.0.2.1")
+
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrInvalidCredentials)
+		users.AssertExpectations(t)
+	})
+
+	t.Run("Success resets failures", func(t *testing.T) {
+		authenticator, users, _ := newAuthenticator(t)
+
+		notLocked(users)
+		users.On("RecordFailedLogin", ctx, "user:anton", config.LockoutDuration).Return(&models.LoginAttempts{Failures: 3}, nil).Once()
+		users.On("RecordFailedLogin", ctx, "ip:192.0.2.1", config.LockoutDuration).Return(&models.LoginAttempts{Failures: 5}, nil).Once()
+		users.On("GetUserByUsername", ctx, "anton").Return(user, nil).Once()
+		users.On("DeleteLoginAttempts", ctx, "user:anton").Return(nil).Once()
+		users.On("ReleaseLoginAttempt", ctx, "ip:192.0.2.1").Return(nil).Once()
+
+		_, err := authenticator.Login(ctx, "anton", "secret password", "192.0.2.1")
+
+		// The failures of the client IP are kept, only this attempt is uncounted
+		assert.NoError(t, err)
+		users.AssertExpectations(t)
+		users.AssertNotCalled(t, "DeleteLoginAttempts", ctx, "ip:192.0.2.1")
+	})
+}
+
+// attemptStorage counts login attempts in memory, with the atomicity of the database, and the
+// users looked up for their password check
+type attemptStorage struct {
+	*MockUserStorage
+	user     *models.User
+	mu       sync.Mutex
+	attempts map[string]*models.LoginAttempts
+	checked  atomic.Int32
+}
+
+func (s *attemptStorage) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	s.checked.Add(1)
+	return s.user, nil
+}
+
+func (s *attemptStorage) GetLoginAttempts(ctx context.Context, key string) (*models.LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempts, ok := s.attempts[key]
+	if !ok {
+		return nil, storage.ErrNotFound
+	}
+	copied := *attempts
+	return &copied, nil
+}
+
+func (s *attemptStorage) RecordFailedLogin(ctx context.Context, key string, window time.Duration) (*models.LoginAttempts, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	attempts, ok := s.attempts[key]
+	if !ok {
+		attempts = &models.LoginAttempts{Key: key}
+		s.attempts[key] = attempts
+	}
+	attempts.Failures++
+	copied := *attempts
+	return &copied, nil
+}
+
+func (s *attemptStorage) ReleaseLoginAttempt(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if attempts, ok := s.attempts[key]; ok && attempts.Failures > 0 {
+		attempts.Failures--
+	}
+	return nil
+}
+
+func (s *attemptStorage) LockLogin(ctx context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attempts[key].LockedUntil = &until
+	return nil
+}
+
+func TestLoginLockoutConcurrent(t *testing.T) {
+	ctx := context.Background()
+
+	config := testConfig
+	config.MaxFailedLogins = 3
+	config.LockoutDuration = 15 * time.Minute
+
+	users := &attemptStorage{
+		MockUserStorage: new(MockUserStorage),
+		user:            newTestUser(t, "secret password"),
+		attempts:        map[string]*models.LoginAttempts{},
+	}
+	audits := new(MockAuditStorage)
+	audits.On("CreateAuditEntry", ctx, mock.Anything).Return(nil)
+	authenticator, err := NewAuthenticator(users, audits, new(MockAPIKeyStorage), config)
+	require.NoError(t, err)
+
+	// Parallel guesses must not pass the lockout before any of them is counted as failed
+	var wg sync.WaitGroup
+	var invalid, locked atomic.Int32
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := authenticator.Login(ctx, "anton", "wrong password", "")
+			switch {
+			case errors.Is(err, ErrAccountLocked):
+				locked.Add(1)
+			case errors.Is(err, ErrInvalidCredentials):
+				invalid.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, users.checked.Load(), int32(config.MaxFailedLogins))
+	assert.LessOrEqual(t, invalid.Load(), int32(config.MaxFailedLogins-1))
+	assert.Equal(t, int32(20), invalid.Load()+locked.Load())
+
+	// The correct password does not help afterwards
+	_, err = authenticator.Login(ctx, "anton", "secret password", "")
+	assert.ErrorIs(t, err, ErrAccountLocked)
+}
+
+func TestUnlock(t *testing.T) {
+	ctx := context.Background()
+
+	config := testConfig
+	config.MaxFailedLogins = 3
+	config.LockoutDuration = 15 * time.Minute
+
+	t.Run("Account", func(t *testing.T) {
+		users := new(MockUserStorage)
+		audits := new(MockAuditStorage)
+		authenticator, err := NewAuthenticator(users, audits, new(MockAPIKeyStorage), config)
+		require.NoError(t, err)
+
+		lockedUntil := time.Now().Add(5 * time.Minute)
+		users.On("GetLoginAttempts", ctx, "user:anton").Return(&models.LoginAttempts{Failures: 3, LockedUntil: &lockedUntil}, nil).Once()
+		users.On("DeleteLoginAttempts", ctx, "user:anton").Return(nil).Once()
+		audits.On("CreateAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == models.AuditAccountUnlocked && entry.Username == "anton" && entry.Actor == "admin"
+		})).Return(nil).Once()
+
+		err = authenticator.Unlock(ctx, "anton", "admin")
+
+		assert.NoError(t, err)
+		users.AssertExpectations(t)
+		audits.AssertExpectations(t)
+	})
+
+	t.Run("Client IP", func(t *testing.T) {
+		users := new(MockUserStorage)
+		audits := new(MockAuditStorage)
+		authenticator, err := NewAuthenticator(users, audits, new(MockAPIKeyStorage), config)
+		require.NoError(t, err)
+
+		lockedUntil := time.Now().Add(5 * time.Minute)
+		users.On("GetLoginAttempts", ctx, "ip:192.0.2.1").Return(&models.LoginAttempts{Failures: 20, LockedUntil: &lockedUntil}, nil).Once()
+		users.On("DeleteLoginAttempts", ctx, "ip:192.0.2.1").Return(nil).Once()
+		// A failed audit entry does not fail the unlock
+		audits.On("CreateAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == models.AuditAccountUnlocked && entry.ClientIP == "192.0.2.1"
+		})).Return(errors.New("database down")).Once()
+
+		err = authenticator.UnlockClient(ctx, "192.0.2.1", "admin")
+
+		assert.NoError(t, err)
+		users.AssertExpectations(t)
+	})
+
+	t.Run("Not locked", func(t *testing.T) {
+		users := new(MockUserStorage)
+		audits := new(MockAuditStorage)
+		authenticator, err := NewAuthenticator(users, audits, new(MockAPIKeyStorage), config)
+		require.NoError(t, err)
+
+		users.On("GetLoginAttempts", ctx, "user:anton").Return(&models.LoginAttempts{Failures: 1}, nil).Once()
+		users.On("GetLoginAttempts", ctx, "user:nobody").Return(nil, storage.ErrNotFound).Once()
+
+		assert.ErrorIs(t, authenticator.Unlock(ctx, "anton", "admin"), ErrNotLocked)
+		assert.ErrorIs(t, authenticator.Unlock(ctx, "nobody", "admin"), ErrNotLocked)
+		users.AssertNotCalled(t, "DeleteLoginAttempts", mock.Anything, mock.Anything)
+		audits.AssertNotCalled(t, "CreateAuditEntry", mock.Anything, mock.Anything)
+	})
+}
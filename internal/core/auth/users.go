@@ -0,0 +1,196 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+)
+
+const (
+	_MaxUsernameLength = 64
+	_MinPasswordLength = 12
+	// bcrypt ignores everything after the first 72 bytes
+	_MaxPasswordLength = 72
+)
+
+// GetUsers returns all users, by username
+func (a *Authenticator) GetUsers(ctx context.Context) ([]models.User, error) {
+	users, err := a.users.GetUsers(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	return users, nil
+}
+
+// CreateUser creates a user with the password. The actor is recorded in the audit log.
+func (a *Authenticator) CreateUser(ctx context.Context, username string, password string, actor string) (*models.User, error) {
+	if err := validateUsername(username); err != nil {
+		return nil, err
+	}
+	hash, err := hashValidPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	user, err := a.users.CreateUser(ctx, &models.User{
+		Username:     username,
+		PasswordHash: hash,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create user: %w", err)
+	}
+
+	a.audit(ctx, &models.AuditEntry{Action: models.AuditUserCreated, Username: username, Actor: actor})
+	return user, nil
+}
+
+// ResetPassword replaces the password of the user. Tokens issued before stay valid until they
+// expire.
+func (a *Authenticator) ResetPassword(ctx context.Context, username string, password string, actor string) error {
+	hash, err := hashValidPassword(password)
+	if err != nil {
+		return err
+	}
+
+	user, err := a.getUser(ctx, username)
+	if err != nil {
+		return err
+	}
+	if err := a.users.SetUserPassword(ctx, user.ID.Hex(), hash); err != nil {
+		return fmt.Errorf("failed to reset password: %w", err)
+	}
+
+	a.audit(ctx, &models.AuditEntry{Action: models.AuditPasswordReset, Username: username, Actor: actor})
+	return nil
+}
+
+// DisableUser stops the user from logging in, refreshing tokens and using API keys. Access
+// tokens issued before stay valid until they expire.
+func (a *Authenticator) DisableUser(ctx context.Context, username string, actor string) error {
+	user, err := a.getUser(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	if err := a.users.SetUserDisabled(ctx, user.ID.Hex(), &now); err != nil {
+		return fmt.Errorf("failed to disable user: %w", err)
+	}
+
+	a.audit(ctx, &models.AuditEntry{Action: models.AuditUserDisabled, Username: username, Actor: actor})
+	return nil
+}
+
+// EnableUser allows a disabled user to log in again
+func (a *Authenticator) EnableUser(ctx context.Context, username string, actor string) error {
+	user, err := a.getUser(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	if err := a.users.SetUserDisabled(ctx, user.ID.Hex(), nil); err != nil {
+		return fmt.Errorf("failed to enable user: %w", err)
+	}
+
+	a.audit(ctx, &models.AuditEntry{Action: models.AuditUserEnabled, Username: username, Actor: actor})
+	return nil
+}
+
+// SetAdmin grants or revokes admin rights of the user. Access tokens issued before keep the
+// rights they were issued with until they expire.
+func (a *Authenticator) SetAdmin(ctx context.Context, username string, admin bool, actor string) error {
+	user, err := a.getUser(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	if err := a.users.SetUserAdmin(ctx, user.ID.Hex(), admin); err != nil {
+		return fmt.Errorf("failed to set admin: %w", err)
+	}
+
+	action := models.AuditAdminGranted
+	if !admin {
+		action = models.AuditAdminRevoked
+	}
+	a.audit(ctx, &models.AuditEntry{Action: action, Username: username, Actor: actor})
+	return nil
+}
+
+// DeleteUser deletes the user, whose API keys stop working
+func (a *Authenticator) DeleteUser(ctx context.Context, username string, actor string) error {
+	user, err := a.getUser(ctx, username)
+	if err != nil {
+		return err
+	}
+
+	if err := a.users.DeleteUser(ctx, user.ID.Hex()); err != nil {
+		return fmt.Errorf("failed to delete user: %w", err)
+	}
+
+	a.audit(ctx, &models.AuditEntry{Action: models.AuditUserDeleted, Username: username, Actor: actor})
+	return nil
+}
+
+func (a *Authenticator) getUser(ctx context.Context, username string) (*models.User, error) {
+	user, err := a.users.GetUserByUsername(ctx, username)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	return user, nil
+}
+
+func validateUsername(username string) error {
+	if username == "" {
+		return fmt.Errorf("%w: username is required", ErrValidation)
+	}
+	if len(username) > _MaxUsernameLength {
+		return fmt.Errorf("%w: username must be at most %d characters", ErrValidation, _MaxUsernameLength)
+	}
+	if strings.IndexFunc(username, func(r rune) bool { return unicode.IsSpace(r) || !unicode.IsPrint(r) }) >= 0 {
+		return fmt.Errorf("%w: username must not contain spaces or control characters", ErrValidation)
+	}
+	return nil
+}
+
+// hashValidPassword checks that the password is strong enough and returns its hash
+func hashValidPassword(password string) (string, error) {
+	if len([]rune(password)) < _MinPasswordLength {
+		return "", fmt.Errorf("%w: password must be at least %d characters", ErrValidation, _MinPasswordLength)
+	}
+	if len(password) > _MaxPasswordLength {
+		return "", fmt.Errorf("%w: password must be at most %d bytes", ErrValidation, _MaxPasswordLength)
+	}
+
+	hash, err := HashPassword(password)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash password: %w", err)
+	}
+	return hash, nil
+}
+
+// errUserDisabled is the reason a token or API key of a disabled user is invalid
+var errUserDisabled = errors.New("user is disabled")
+
+// checkUser returns an error wrapping ErrInvalidToken if a user of a token or API key was
+// deleted or disabled since it was issued
+func checkUser(user *models.User, err error) (*models.User, error) {
+	if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidID) {
+		return nil, fmt.Errorf("%w: user no longer exists", ErrInvalidToken)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get user: %w", err)
+	}
+	if user.Disabled() {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, errUserDisabled)
+	}
+	return user, nil
+}
@@ -0,0 +1,243 @@
+package auth
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCreateUser(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Success", func(t *testing.T) {
+		users := new(MockUserStorage)
+		audits := new(MockAuditStorage)
+		authenticator, err := NewAuthenticator(users, audits, new(MockAPIKeyStorage), testConfig)
+		require.NoError(t, err)
+
+		created := &models.User{}
+		users.On("CreateUser", ctx, mock.AnythingOfType("*models.User")).Run(func(args mock.Arguments) {
+			*created = *args.Get(1).(*models.User)
+			created.ID = primitive.NewObjectID()
+		}).Return(created, nil).Once()
+		audits.On("CreateAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == models.AuditUserCreated && entry.Username == "anton" && entry.Actor == "admin-cli:root"
+		})).Return(nil).Once()
+
+		user, err := authenticator.CreateUser(ctx, "anton", "correct horse battery", "admin-cli:root")
+
+		require.NoError(t, err)
+		assert.Equal(t, "anton", user.Username)
+		assert.True(t, checkPassword(user.PasswordHash, "correct horse battery"))
+		audits.AssertExpectations(t)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		users := new(MockUserStorage)
+		authenticator, err := NewAuthenticator(users, new(MockAuditStorage), new(MockAPIKeyStorage), testConfig)
+		require.NoError(t, err)
+
+		tests := []struct {
+			name     string
+			username string
+			password string
+		}{
+			{"No username", "", "correct horse battery"},
+			{"Username with space", "anton luning", "correct horse battery"},
+			{"Short password", "anton", "short"},
+			{"Long password", "anton", strings.Repeat("a", 73)},
+		}
+		for _, test := range tests {
+			_, err := authenticator.CreateUser(ctx, test.username, test.password, "admin")
+			assert.ErrorIs(t, err, ErrValidation, test.name)
+		}
+		users.AssertNotCalled(t, "CreateUser", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Username taken", func(t *testing.T) {
+		users := new(MockUserStorage)
+		audits := new(MockAuditStorage)
+		authenticator, err := NewAuthenticator(users, audits, new(MockAPIKeyStorage), testConfig)
+		require.NoError(t, err)
+
+		users.On("CreateUser", ctx, mock.AnythingOfType("*models.User")).Return(nil, storage.ErrAlreadyExists).Once()
+
+		_, err = authenticator.CreateUser(ctx, "anton", "correct horse battery", "admin")
+
+		assert.ErrorIs(t, err, storage.ErrAlreadyExists)
+		audits.AssertNotCalled(t, "CreateAuditEntry", mock.Anything, mock.Anything)
+	})
+}
+
+func TestManageUser(t *testing.T) {
+	ctx := context.Background()
+	user := newTestUser(t, "secret password")
+
+	newAuthenticator := func(t *testing.T, action string) (*Authenticator, *MockUserStorage, *MockAuditStorage) {
+		users := new(MockUserStorage)
+		audits := new(MockAuditStorage)
+		authenticator, err := NewAuthenticator(users, audits, new(MockAPIKeyStorage), testConfig)
+		require.NoError(t, err)
+
+		users.On("GetUserByUsername", ctx, "anton").Return(user, nil).Once()
+		audits.On("CreateAuditEntry", ctx, mock.MatchedBy(func(entry *models.AuditEntry) bool {
+			return entry.Action == action && entry.Username == "anton" && entry.Actor == "admin"
+		})).Return(nil).Once()
+		return authenticator, users, audits
+	}
+
+	t.Run("Reset password", func(t *testing.T) {
+		authenticator, users, audits := newAuthenticator(t, models.AuditPasswordReset)
+		users.On("SetUserPassword", ctx, user.ID.Hex(), mock.MatchedBy(func(hash string) bool {
+			return checkPassword(hash, "correct horse battery")
+		})).Return(nil).Once()
+
+		err := authenticator.ResetPassword(ctx, "anton", "correct horse battery", "admin")
+
+		assert.NoError(t, err)
+		users.AssertExpectations(t)
+		audits.AssertExpectations(t)
+	})
+
+	t.Run("Disable", func(t *testing.T) {
+		authenticator, users, audits := newAuthenticator(t, models.AuditUserDisabled)
+		users.On("SetUserDisabled", ctx, user.ID.Hex(), mock.MatchedBy(func(disabledAt *time.Time) bool {
+			return disabledAt != nil
+		})).Return(nil).Once()
+
+		err := authenticator.DisableUser(ctx, "anton", "admin")
+
+		assert.NoError(t, err)
+		users.AssertExpectations(t)
+		audits.AssertExpectations(t)
+	})
+
+	t.Run("Enable", func(t *testing.T) {
+		authenticator, users, audits := newAuthenticator(t, models.AuditUserEnabled)
+		users.On("SetUserDisabled", ctx, user.ID.Hex(), (*time.Time)(nil)).Return(nil).Once()
+
+		err := authenticator.EnableUser(ctx, "anton", "admin")
+
+		assert.NoError(t, err)
+		users.AssertExpectations(t)
+		audits.AssertExpectations(t)
+	})
+
+	t.Run("Grant admin", func(t *testing.T) {
+		authenticator, users, audits := newAuthenticator(t, models.AuditAdminGranted)
+		users.On("SetUserAdmin", ctx, user.ID.Hex(), true).Return(nil).Once()
+
+		err := authenticator.SetAdmin(ctx, "anton", true, "admin")
+
+		assert.NoError(t, err)
+		users.AssertExpectations(t)
+		audits.AssertExpectations(t)
+	})
+
+	t.Run("Revoke admin", func(t *testing.T) {
+		authenticator, users, audits := newAuthenticator(t, models.AuditAdminRevoked)
+		users.On("SetUserAdmin", ctx, user.ID.Hex(), false).Return(nil).Once()
+
+		err := authenticator.SetAdmin(ctx, "anton", false, "admin")
+
+		assert.NoError(t, err)
+		users.AssertExpectations(t)
+		audits.AssertExpectations(t)
+	})
+
+	t.Run("Delete", func(t *testing.T) {
+		authenticator, users, audits := newAuthenticator(t, models.AuditUserDeleted)
+		users.On("DeleteUser", ctx, user.ID.Hex()).Return(nil).Once()
+
+		err := authenticator.DeleteUser(ctx, "anton", "admin")
+
+		assert.NoError(t, err)
+		users.AssertExpectations(t)
+		audits.AssertExpectations(t)
+	})
+
+	t.Run("Unknown user", func(t *testing.T) {
+		users := new(MockUserStorage)
+		authenticator, err := NewAuthenticator(users, new(MockAuditStorage), new(MockAPIKeyStorage), testConfig)
+		require.NoError(t, err)
+
+		users.On("GetUserByUsername", ctx, "nobody").Return(nil, storage.ErrNotFound).Once()
+
+		assert.ErrorIs(t, authenticator.DisableUser(ctx, "nobody", "admin"), storage.ErrNotFound)
+	})
+}
+
+func TestDisabledUser(t *testing.T) {
+	ctx := context.Background()
+	user := newTestUser(t, "secret password")
+
+	users := new(MockUserStorage)
+	keys := new(MockAPIKeyStorage)
+	authenticator, err := NewAuthenticator(users, new(MockAuditStorage), keys, testConfig)
+	require.NoError(t, err)
+
+	// Tokens issued before the user was disabled
+	users.On("GetUserByUsername", ctx, "anton").Return(user, nil).Once()
+	tokens, err := authenticator.Login(ctx, "anton", "secret password", "")
+	require.NoError(t, err)
+
+	disabledAt := time.Now()
+	disabled := *user
+	disabled.DisabledAt = &disabledAt
+
+	t.Run("Login", func(t *testing.T) {
+		users.On("GetUserByUsername", ctx, "anton").Return(&disabled, nil).Twice()
+
+		_, err := authenticator.Login(ctx, "anton", "secret password", "")
+		assert.ErrorIs(t, err, ErrAccountDisabled)
+
+		// A wrong password does not reveal that the user is disabled
+		_, err = authenticator.Login(ctx, "anton", "wrong password", "")
+		assert.ErrorIs(t, err, ErrInvalidCredentials)
+	})
+
+	t.Run("Refresh", func(t *testing.T) {
+		users.On("GetUserByID", ctx, user.ID.Hex()).Return(&disabled, nil).Once()
+
+		_, err := authenticator.Refresh(ctx, tokens.RefreshToken)
+
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+
+	t.Run("API key", func(t *testing.T) {
+		key := APIKeyPrefix + "test-key"
+		keys.On("GetAPIKeyByHash", ctx, hashAPIKey(key)).Return(&models.APIKey{ID: primitive.NewObjectID(), UserID: user.ID, Scopes: models.APIKeyScopes}, nil).Once()
+		users.On("GetUserByID", ctx, user.ID.Hex()).Return(&disabled, nil).Once()
+
+		_, err := authenticator.Authenticate(ctx, key)
+
+		assert.ErrorIs(t, err, ErrInvalidToken)
+	})
+}
+
+func TestAdminClaim(t *testing.T) {
+	ctx := context.Background()
+	admin := newTestUser(t, "secret password")
+	admin.Admin = true
+
+	users := new(MockUserStorage)
+	authenticator, err := NewAuthenticator(users, new(MockAuditStorage), new(MockAPIKeyStorage), testConfig)
+	require.NoError(t, err)
+
+	users.On("GetUserByUsername", ctx, "anton").Return(admin, nil).Once()
+	tokens, err := authenticator.Login(ctx, "anton", "secret password", "")
+	require.NoError(t, err)
+
+	identity, err := authenticator.Authenticate(ctx, tokens.AccessToken)
+
+	require.NoError(t, err)
+	assert.True(t, identity.User.Admin)
+}
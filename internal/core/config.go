@@ -2,7 +2,9 @@ package core
 
 import (
 	"fmt"
+	"time"
 
+	"github.com/AntonLuning/RecipeBank/internal/tracing"
 	"github.com/caarlos0/env/v11"
 )
 
@@ -19,6 +21,14 @@ type AppConfig struct {
 	Database DatabaseConfig `envPrefix:"DB_"`
 	// AI configuration
 	AI AIConfig `envPrefix:"AI_"`
+	// Outbound fetching of user-provided URLs
+	Fetch FetchConfig `envPrefix:"FETCH_"`
+	// Authentication of requests that change data
+	Auth AuthConfig `envPrefix:"AUTH_"`
+	// Rate limiting of API requests per client
+	RateLimit RateLimitConfig `envPrefix:"RATE_LIMIT_"`
+	// Tracing of requests across the API, service, storage and AI
+	Tracing tracing.Config `envPrefix:"TRACING_"`
 }
 
 type DatabaseConfig struct {
@@ -35,12 +45,81 @@ type DatabaseConfig struct {
 }
 
 type AIConfig struct {
-	// AI provider
+	// AI provider ("openai", or "fake" for rule-based extraction without a model)
 	Provider string `env:"PROVIDER" envDefault:""`
-	// OpenAI API key
-	APIKey string `env:"API_KEY,required"`
+	// OpenAI API key (not needed by most local OpenAI-compatible servers)
+	APIKey string `env:"API_KEY" envDefault:""`
+	// Base URL of an OpenAI-compatible API, e.g. "http://localhost:11434/v1" for a local model
+	// server (empty for OpenAI)
+	BaseURL string `env:"BASE_URL" envDefault:""`
 	// OpenAI model
 	Model string `env:"MODEL" envDefault:"gpt-4.1-mini-2025-04-14"`
+	// Directory with the prompt templates, one directory per version (empty for the built-in templates)
+	PromptsDir string `env:"PROMPTS_DIR" envDefault:""`
+	// Version of the prompt templates used for recipe extraction
+	PromptVersion string `env:"PROMPT_VERSION" envDefault:"v1"`
+	// How long AI results are cached (0 disables the cache)
+	CacheTTL time.Duration `env:"CACHE_TTL" envDefault:"720h"`
+	// Timeout of a single AI call
+	Timeout time.Duration `env:"TIMEOUT" envDefault:"60s"`
+	// Maximum number of retries of a failed AI call (rate limits, timeouts and provider errors)
+	MaxRetries int `env:"MAX_RETRIES" envDefault:"3"`
+	// Backoff before the first retry, doubled for every retry up to the maximum
+	InitialBackoff time.Duration `env:"INITIAL_BACKOFF" envDefault:"1s"`
+	MaxBackoff     time.Duration `env:"MAX_BACKOFF" envDefault:"20s"`
+	// Consecutive failed AI calls before failing fast (0 disables the circuit breaker)
+	BreakerThreshold int `env:"BREAKER_THRESHOLD" envDefault:"5"`
+	// How long to fail fast before trying the AI provider again
+	BreakerCooldown time.Duration `env:"BREAKER_COOLDOWN" envDefault:"30s"`
+	// Prices in USD per million input/output tokens, as "model=input/output,..."
+	Prices string `env:"PRICES" envDefault:"gpt-4.1=2.00/8.00,gpt-4.1-mini=0.40/1.60,gpt-4.1-nano=0.10/0.40,gpt-4o=2.50/10.00,gpt-4o-mini=0.15/0.60"`
+	// Monthly AI budget in USD, imports are refused once it is reached (0 for no budget)
+	MonthlyBudget float64 `env:"MONTHLY_BUDGET" envDefault:"0"`
+}
+
+type FetchConfig struct {
+	// Timeout of a whole request (including redirects)
+	Timeout time.Duration `env:"TIMEOUT" envDefault:"10s"`
+	// Maximum number of redirects to follow
+	MaxRedirects int `env:"MAX_REDIRECTS" envDefault:"5"`
+	// Maximum response body size in bytes
+	MaxBodySize int64 `env:"MAX_BODY_SIZE" envDefault:"5242880"`
+	// User-Agent header sent with every request
+	UserAgent string `env:"USER_AGENT" envDefault:"RecipeBank/1.0 (+https://github.com/AntonLuning/RecipeBank)"`
+	// Allow fetching from private and loopback addresses (only for local development)
+	AllowPrivate bool `env:"ALLOW_PRIVATE" envDefault:"false"`
+}
+
+type AuthConfig struct {
+	// Secret the tokens are signed with, at least 32 bytes
+	JWTSecret string `env:"JWT_SECRET_FILE,required,file"`
+	// Issuer of the tokens
+	Issuer string `env:"ISSUER" envDefault:"recipebank"`
+	// Lifetime of access tokens
+	AccessTokenTTL time.Duration `env:"ACCESS_TOKEN_TTL" envDefault:"15m"`
+	// Lifetime of refresh tokens
+	RefreshTokenTTL time.Duration `env:"REFRESH_TOKEN_TTL" envDefault:"168h"`
+	// Failed logins of a username before it is locked (0 never locks)
+	MaxFailedLogins int `env:"MAX_FAILED_LOGINS" envDefault:"5"`
+	// Failed logins from a client IP before it is locked (0 never locks)
+	MaxFailedLoginsPerIP int `env:"MAX_FAILED_LOGINS_PER_IP" envDefault:"20"`
+	// How long failed logins are counted, and how long a lock lasts
+	LockoutDuration time.Duration `env:"LOCKOUT_DURATION" envDefault:"15m"`
+}
+
+type RateLimitConfig struct {
+	// Requests per client that view data, as "requests/period" (empty or 0 disables the limit)
+	Read string `env:"READ" envDefault:"300/1m"`
+	// Requests per client that change data, including logins
+	Write string `env:"WRITE" envDefault:"60/1m"`
+	// Requests per client that use AI, which are the most expensive
+	AI string `env:"AI" envDefault:"10/1m"`
+	// Failed authentications with a token or API key per client IP, before its requests are
+	// authenticated at all
+	FailedAuth string `env:"FAILED_AUTH" envDefault:"20/1m"`
+	// IP addresses or CIDR ranges of reverse proxies whose X-Forwarded-For header is trusted
+	// (comma separated, none by default)
+	TrustedProxies []string `env:"TRUSTED_PROXIES" envSeparator:","`
 }
 
 func Config() AppConfig {
@@ -5,7 +5,7 @@
 //
 // @title RecipeBank API
 // @version 1.0
-// @description A recipe management API with AI-powered features
+// @description A recipe management API with AI-powered features. Requests are rate limited per client (API key, user or IP address) with separate budgets for reads, writes and AI, which the RateLimit-Limit, RateLimit-Remaining, RateLimit-Reset and RateLimit-Policy headers describe. A client that exceeds its budget gets 429 with the error code rate_limited and a Retry-After header.
 // @termsOfService http://swagger.io/terms/
 //
 // @contact.name API Support
@@ -22,4 +22,9 @@
 //
 // @produce json
 // @consumes json
+//
+// @securityDefinitions.apikey BearerAuth
+// @in header
+// @name Authorization
+// @description Access token from POST /auth/login or API key from POST /auth/api-keys, as "Bearer <token>". Required for all requests that change data.
 package core
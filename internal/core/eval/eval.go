@@ -0,0 +1,316 @@
+// Package eval measures the recipe extraction quality of an ai.RecipeAI against golden
+// recipes, so changes of the model, the prompts or the provider can be compared.
+package eval
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+	"unicode"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/ai"
+)
+
+// Minimum name similarity for an extracted ingredient to be matched with an expected one
+const _MinIngredientSimilarity = 0.5
+
+var ErrUnsupportedFixture = errors.New("unsupported fixture type")
+
+// Fixture is an input file (an image, an HTML page or a text document) with the expected
+// result in a JSON file of the same name, e.g. omelett.jpeg and omelett.json
+type Fixture struct {
+	Name     string
+	Path     string
+	Expected *ai.RecipeAnalysisResult
+}
+
+// LoadFixtures loads the fixtures of the directory, in name order
+func LoadFixtures(dir string) ([]Fixture, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %w", err)
+	}
+
+	fixtures := []Fixture{}
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || ext == ".json" {
+			continue
+		}
+
+		path := filepath.Join(dir, entry.Name())
+		expected, err := readExpected(strings.TrimSuffix(path, ext) + ".json")
+		if err != nil {
+			return nil, fmt.Errorf("fixture %s: %w", entry.Name(), err)
+		}
+
+		fixtures = append(fixtures, Fixture{
+			Name:     strings.TrimSuffix(entry.Name(), ext),
+			Path:     path,
+			Expected: expected,
+		})
+	}
+	slices.SortFunc(fixtures, func(a, b Fixture) int { return strings.Compare(a.Name, b.Name) })
+
+	return fixtures, nil
+}
+
+func readExpected(path string) (*ai.RecipeAnalysisResult, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("missing expected result: %w", err)
+	}
+
+	expected := &ai.RecipeAnalysisResult{}
+	if err := json.Unmarshal(data, expected); err != nil {
+		return nil, fmt.Errorf("invalid expected result: %w", err)
+	}
+	return expected, nil
+}
+
+// Analyze extracts the recipe of the fixture the way it would be imported
+func Analyze(ctx context.Context, recipeAI ai.RecipeAI, fixture Fixture) (*ai.RecipeAnalysisResult, error) {
+	data, err := os.ReadFile(fixture.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	switch ext := strings.ToLower(filepath.Ext(fixture.Path)); ext {
+	case ".jpg", ".jpeg":
+		return recipeAI.AnalyzeRecipeImage(ctx, base64.StdEncoding.EncodeToString(data), ai.ImageContentTypeJPEG)
+	case ".png":
+		return recipeAI.AnalyzeRecipeImage(ctx, base64.StdEncoding.EncodeToString(data), ai.ImageContentTypePNG)
+	case ".html", ".htm":
+		return recipeAI.AnalyzeRecipeWebpage(ctx, "file://"+filepath.Base(fixture.Path), data)
+	case ".txt":
+		return recipeAI.AnalyzeRecipeText(ctx, string(data))
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedFixture, ext)
+	}
+}
+
+// Score is the field-level accuracy of an extracted recipe, every field from 0 to 1
+type Score struct {
+	Title float64
+	// Share of the ingredients that were matched by name, weighted by the name similarity
+	IngredientNames float64
+	// Share of the ingredients that were matched with the same quantity
+	Quantities float64
+	// Share of the ingredients that were matched with the same unit
+	Units float64
+	// Text similarity of the steps, in order
+	Steps    float64
+	CookTime float64
+	Servings float64
+}
+
+// Overall is the mean of the field scores
+func (s Score) Overall() float64 {
+	return (s.Title + s.IngredientNames + s.Quantities + s.Units + s.Steps + s.CookTime + s.Servings) / 7
+}
+
+// ScoreResult scores the extracted recipe against the expected one. Ingredients are matched by
+// name regardless of their order, missing and extra ingredients and steps lower the scores.
+func ScoreResult(expected *ai.RecipeAnalysisResult, result *ai.RecipeAnalysisResult) Score {
+	score := Score{
+		Title:    similarity(expected.Title, result.Title),
+		Steps:    stepSimilarity(expected.Steps, result.Steps),
+		CookTime: equal(expected.CookTime, result.CookTime),
+		Servings: equal(expected.Servings, result.Servings),
+	}
+
+	count := max(len(expected.Ingredients), len(result.Ingredients))
+	if count == 0 {
+		score.IngredientNames, score.Quantities, score.Units = 1, 1, 1
+		return score
+	}
+
+	matched := make([]bool, len(result.Ingredients))
+	for _, want := range expected.Ingredients {
+		best, bestSimilarity := -1, _MinIngredientSimilarity
+		for i, got := range result.Ingredients {
+			if s := similarity(want.Name, got.Name); !matched[i] && s >= bestSimilarity {
+				best, bestSimilarity = i, s
+			}
+		}
+		if best < 0 {
+			continue
+		}
+		matched[best] = true
+
+		got := result.Ingredients[best]
+		score.IngredientNames += bestSimilarity
+		score.Quantities += equal(want.Quantity, got.Quantity)
+		score.Units += equal(normalize(want.Unit), normalize(got.Unit))
+	}
+	score.IngredientNames /= float64(count)
+	score.Quantities /= float64(count)
+	score.Units /= float64(count)
+
+	return score
+}
+
+// stepSimilarity compares the steps in order
+func stepSimilarity(expected []string, result []string) float64 {
+	count := max(len(expected), len(result))
+	if count == 0 {
+		return 1
+	}
+
+	var total float64
+	for i := range min(len(expected), len(result)) {
+		total += similarity(expected[i], result[i])
+	}
+	return total / float64(count)
+}
+
+// similarity is the Dice coefficient of the words of the texts, ignoring case and punctuation
+func similarity(a string, b string) float64 {
+	wordsA, wordsB := words(a), words(b)
+	if len(wordsA)+len(wordsB) == 0 {
+		return 1
+	}
+
+	counts := map[string]int{}
+	for _, word := range wordsA {
+		counts[word]++
+	}
+	common := 0
+	for _, word := range wordsB {
+		if counts[word] > 0 {
+			counts[word]--
+			common++
+		}
+	}
+
+	return 2 * float64(common) / float64(len(wordsA)+len(wordsB))
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func normalize(s string) string {
+	return strings.Join(words(s), " ")
+}
+
+func equal[T comparable](a T, b T) float64 {
+	if a == b {
+		return 1
+	}
+	return 0
+}
+
+// FixtureResult is the score of a fixture, or the error if the extraction failed
+type FixtureResult struct {
+	Name     string
+	Score    Score
+	Err      error
+	Duration time.Duration
+}
+
+// Report is the result of an evaluation run
+type Report struct {
+	Results []FixtureResult
+}
+
+// Run extracts and scores every fixture, a fixture that fails scores 0
+func Run(ctx context.Context, recipeAI ai.RecipeAI, fixtures []Fixture, timeout time.Duration) *Report {
+	report := &Report{Results: make([]FixtureResult, 0, len(fixtures))}
+
+	for _, fixture := range fixtures {
+		start := time.Now()
+
+		fixtureCtx, cancel := context.WithTimeout(ctx, timeout)
+		result, err := Analyze(fixtureCtx, recipeAI, fixture)
+		cancel()
+
+		fixtureResult := FixtureResult{Name: fixture.Name, Err: err, Duration: time.Since(start)}
+		if err == nil {
+			fixtureResult.Score = ScoreResult(fixture.Expected, result)
+		}
+		report.Results = append(report.Results, fixtureResult)
+	}
+
+	return report
+}
+
+// Average is the mean score of all fixtures
+func (r *Report) Average() Score {
+	var average Score
+	if len(r.Results) == 0 {
+		return average
+	}
+
+	for _, result := range r.Results {
+		average.Title += result.Score.Title
+		average.IngredientNames += result.Score.IngredientNames
+		average.Quantities += result.Score.Quantities
+		average.Units += result.Score.Units
+		average.Steps += result.Score.Steps
+		average.CookTime += result.Score.CookTime
+		average.Servings += result.Score.Servings
+	}
+
+	n := float64(len(r.Results))
+	return Score{
+		Title:           average.Title / n,
+		IngredientNames: average.IngredientNames / n,
+		Quantities:      average.Quantities / n,
+		Units:           average.Units / n,
+		Steps:           average.Steps / n,
+		CookTime:        average.CookTime / n,
+		Servings:        average.Servings / n,
+	}
+}
+
+// Failed is the number of fixtures whose extraction failed
+func (r *Report) Failed() int {
+	failed := 0
+	for _, result := range r.Results {
+		if result.Err != nil {
+			failed++
+		}
+	}
+	return failed
+}
+
+// Write prints the report as a table with a row per fixture and the average, followed by the
+// errors of the failed fixtures
+func (r *Report) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+
+	fmt.Fprintln(tw, "FIXTURE\tTITLE\tNAMES\tQUANTITIES\tUNITS\tSTEPS\tTIME\tSERVINGS\tOVERALL\tDURATION\t")
+	for _, result := range r.Results {
+		writeRow(tw, result.Name, result.Score, result.Duration.Round(time.Millisecond).String())
+	}
+	writeRow(tw, "AVERAGE", r.Average(), "")
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, result := range r.Results {
+		if result.Err != nil {
+			fmt.Fprintf(w, "\n%s failed: %s", result.Name, result.Err.Error())
+		}
+	}
+	_, err := fmt.Fprintf(w, "\n%d fixtures, %d failed\n", len(r.Results), r.Failed())
+	return err
+}
+
+func writeRow(w io.Writer, name string, score Score, duration string) {
+	fmt.Fprintf(w, "%s\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%.2f\t%s\t\n",
+		name, score.Title, score.IngredientNames, score.Quantities, score.Units, score.Steps, score.CookTime, score.Servings, score.Overall(), duration)
+}
@@ -0,0 +1,141 @@
+package eval
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/ai"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var expected = &ai.RecipeAnalysisResult{
+	Title: "Creamy Tomato Soup",
+	Ingredients: []models.Ingredient{
+		{Name: "ripe tomatoes", Quantity: 1, Unit: "kg"},
+		{Name: "garlic", Quantity: 4, Unit: "cloves"},
+		{Name: "heavy cream", Quantity: 100, Unit: "ml"},
+		{Name: "salt"},
+	},
+	Steps:    []string{"Roast the tomatoes and the garlic.", "Blend with the cream."},
+	CookTime: 45,
+	Servings: 4,
+}
+
+func TestScoreResult(t *testing.T) {
+	t.Run("Exact match", func(t *testing.T) {
+		score := ScoreResult(expected, expected)
+		assert.Equal(t, Score{Title: 1, IngredientNames: 1, Quantities: 1, Units: 1, Steps: 1, CookTime: 1, Servings: 1}, score)
+		assert.Equal(t, 1.0, score.Overall())
+	})
+
+	t.Run("Field differences", func(t *testing.T) {
+		result := &ai.RecipeAnalysisResult{
+			Title: "creamy tomato soup!",
+			Ingredients: []models.Ingredient{
+				// Order does not matter, case and punctuation are ignored
+				{Name: "Heavy cream", Quantity: 1, Unit: "dl"},
+				{Name: "tomatoes", Quantity: 1, Unit: "KG"},
+				{Name: "garlic", Quantity: 4, Unit: "cloves"},
+				// Not in the expected recipe
+				{Name: "pepper"},
+			},
+			Steps:    []string{"Roast the tomatoes and the garlic."},
+			CookTime: 45,
+		}
+
+		score := ScoreResult(expected, result)
+		assert.Equal(t, 1.0, score.Title)
+		assert.InDelta(t, (1+2.0/3+1)/4, score.IngredientNames, 0.001)
+		assert.InDelta(t, 2.0/4, score.Quantities, 0.001)
+		assert.InDelta(t, 2.0/4, score.Units, 0.001)
+		assert.InDelta(t, 1.0/2, score.Steps, 0.001)
+		assert.Equal(t, 1.0, score.CookTime)
+		assert.Equal(t, 0.0, score.Servings)
+	})
+
+	t.Run("Empty result", func(t *testing.T) {
+		score := ScoreResult(expected, &ai.RecipeAnalysisResult{})
+		assert.Equal(t, Score{}, score)
+	})
+
+	t.Run("No ingredients or steps", func(t *testing.T) {
+		score := ScoreResult(&ai.RecipeAnalysisResult{Title: "Water"}, &ai.RecipeAnalysisResult{Title: "Water"})
+		assert.Equal(t, Score{Title: 1, IngredientNames: 1, Quantities: 1, Units: 1, Steps: 1, CookTime: 1, Servings: 1}, score)
+	})
+}
+
+func TestSimilarity(t *testing.T) {
+	assert.Equal(t, 1.0, similarity("Blend until smooth.", "blend until  smooth"))
+	assert.Equal(t, 0.0, similarity("salt", "pepper"))
+	assert.InDelta(t, 2*2.0/(3+2), similarity("ripe red tomatoes", "red tomatoes"), 0.001)
+	assert.Equal(t, 1.0, similarity("", " "))
+	assert.Equal(t, 1.0, similarity("Smält smöret", "smält smöret"))
+}
+
+func TestRun(t *testing.T) {
+	fixtures, err := LoadFixtures("../../../testdata/eval")
+	require.NoError(t, err)
+	require.NotEmpty(t, fixtures)
+
+	names := []string{}
+	for _, fixture := range fixtures {
+		names = append(names, fixture.Name)
+		assert.NotEmpty(t, fixture.Expected.Title, fixture.Name)
+	}
+	assert.Contains(t, names, "omelett")
+	assert.Contains(t, names, "tomato_soup")
+
+	// The fake provider runs offline, but cannot read images
+	report := Run(context.Background(), ai.NewFakeRecipeAI(), fixtures, time.Minute)
+	require.Len(t, report.Results, len(fixtures))
+	assert.Equal(t, 1, report.Failed())
+
+	for _, result := range report.Results {
+		if result.Name == "omelett" {
+			assert.ErrorIs(t, result.Err, ai.ErrRefused)
+			assert.Equal(t, Score{}, result.Score)
+			continue
+		}
+		assert.NoError(t, result.Err, result.Name)
+		assert.Greater(t, result.Score.Overall(), 0.5, result.Name)
+	}
+
+	var b bytes.Buffer
+	require.NoError(t, report.Write(&b))
+	assert.Contains(t, b.String(), "FIXTURE")
+	assert.Contains(t, b.String(), "tomato_soup")
+	assert.Contains(t, b.String(), "AVERAGE")
+	assert.Contains(t, b.String(), "omelett failed: ")
+	assert.Contains(t, b.String(), "1 failed")
+}
+
+func TestLoadFixturesErrors(t *testing.T) {
+	_, err := LoadFixtures("does-not-exist")
+	assert.Error(t, err)
+
+	dir := t.TempDir()
+	require.NoError(t, writeFile(dir+"/soup.html", "<html></html>"))
+	_, err = LoadFixtures(dir)
+	assert.ErrorContains(t, err, "missing expected result")
+
+	require.NoError(t, writeFile(dir+"/soup.json", "{"))
+	_, err = LoadFixtures(dir)
+	assert.ErrorContains(t, err, "invalid expected result")
+
+	require.NoError(t, writeFile(dir+"/soup.json", `{"title":"Soup"}`))
+	require.NoError(t, writeFile(dir+"/notes.md", "notes"))
+	require.NoError(t, writeFile(dir+"/notes.json", `{"title":"Notes"}`))
+	fixtures, err := LoadFixtures(dir)
+	require.NoError(t, err)
+	_, err = Analyze(context.Background(), ai.NewFakeRecipeAI(), fixtures[0])
+	assert.ErrorIs(t, err, ErrUnsupportedFixture)
+}
+
+func writeFile(path string, content string) error {
+	return os.WriteFile(path, []byte(content), 0o644)
+}
@@ -0,0 +1,12 @@
+package fetch
+
+import "errors"
+
+var (
+	ErrInvalidURL        = errors.New("invalid URL")
+	ErrUnsupportedScheme = errors.New("unsupported URL scheme")
+	ErrBlockedAddress    = errors.New("address is not allowed")
+	ErrTooManyRedirects  = errors.New("too many redirects")
+	ErrBodyTooLarge      = errors.New("response body too large")
+	ErrUnexpectedStatus  = errors.New("unexpected response status")
+)
@@ -0,0 +1,249 @@
+package fetch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/tracing"
+	semconv "go.opentelemetry.io/otel/semconv/v1.28.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Address ranges that are never fetched, in addition to loopback, private,
+// link-local, multicast and unspecified addresses
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),       // "This" network
+	netip.MustParsePrefix("100.64.0.0/10"),   // Carrier-grade NAT
+	netip.MustParsePrefix("192.0.0.0/24"),    // IETF protocol assignments
+	netip.MustParsePrefix("192.0.2.0/24"),    // TEST-NET-1
+	netip.MustParsePrefix("198.18.0.0/15"),   // Benchmarking
+	netip.MustParsePrefix("198.51.100.0/24"), // TEST-NET-2
+	netip.MustParsePrefix("203.0.113.0/24"),  // TEST-NET-3
+	netip.MustParsePrefix("240.0.0.0/4"),     // Reserved
+	netip.MustParsePrefix("64:ff9b::/96"),    // NAT64
+	netip.MustParsePrefix("2001:db8::/32"),   // Documentation
+}
+
+type Config struct {
+	// Timeout of a whole request, including redirects and reading the body
+	Timeout time.Duration
+	// Maximum number of redirects to follow
+	MaxRedirects int
+	// Maximum size of a response body in bytes
+	MaxBodySize int64
+	// User-Agent header sent with every request
+	UserAgent string
+	// Allow requests to private and loopback addresses (local development only)
+	AllowPrivate bool
+}
+
+// Response is a fetched HTTP response with its body read into memory
+type Response struct {
+	// Final URL after following redirects
+	URL         string
+	StatusCode  int
+	ContentType string // media type without parameters, e.g. "text/html"
+	Body        []byte
+}
+
+// Client is an HTTP client for fetching user-provided URLs. It only allows http and https,
+// refuses to connect to private and loopback addresses (checked after DNS resolution, so
+// it cannot be bypassed with a DNS name), and caps redirects and response sizes.
+type Client struct {
+	client *http.Client
+	config Config
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Timeout:      10 * time.Second,
+		MaxRedirects: 5,
+		MaxBodySize:  5 << 20, // 5 MB
+		UserAgent:    "RecipeBank/1.0 (+https://github.com/AntonLuning/RecipeBank)",
+	}
+}
+
+func NewClient(config Config) *Client {
+	dialer := &net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	if !config.AllowPrivate {
+		dialer.Control = controlAddress
+	}
+
+	transport := &http.Transport{
+		// No proxy, a proxy would make the dialer check the proxy address instead of the target
+		Proxy:                 nil,
+		DialContext:           dialer.DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          20,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   5 * time.Second,
+		ResponseHeaderTimeout: config.Timeout,
+	}
+
+	c := &Client{config: config}
+	c.client = &http.Client{
+		Timeout:       config.Timeout,
+		Transport:     transport,
+		CheckRedirect: c.checkRedirect,
+	}
+
+	return c
+}
+
+// Get fetches the URL and reads the body, failing on non-2xx responses
+func (c *Client) Get(ctx context.Context, rawURL string) (*Response, error) {
+	return c.do(ctx, http.MethodGet, rawURL)
+}
+
+// Head checks that the URL is reachable without reading a body, failing on non-2xx responses
+func (c *Client) Head(ctx context.Context, rawURL string) (*Response, error) {
+	return c.do(ctx, http.MethodHead, rawURL)
+}
+
+// do fetches the URL in a span. The trace context is not sent to the site, it is not part of the
+// trace. The span only records the URL without its query, which can hold tokens.
+func (c *Client) do(ctx context.Context, method string, rawURL string) (*Response, error) {
+	ctx, span := tracing.Start(ctx, "fetch "+method,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.HTTPRequestMethodKey.String(method), semconv.URLFull(redactURL(rawURL))),
+	)
+	resp, err := c.send(ctx, method, rawURL)
+	if resp != nil {
+		span.SetAttributes(semconv.HTTPResponseStatusCode(resp.StatusCode), semconv.HTTPResponseBodySize(len(resp.Body)))
+	}
+	tracing.End(span, err)
+	return resp, err
+}
+
+func (c *Client) send(ctx context.Context, method string, rawURL string) (*Response, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidURL, rawURL)
+	}
+	if err := checkScheme(u); err != nil {
+		return nil, err
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("%w: %s", ErrInvalidURL, rawURL)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidURL, err)
+	}
+	req.Header.Set("User-Agent", c.config.UserAgent)
+	req.Header.Set("Accept", "text/html,application/xhtml+xml,image/jpeg,image/png,application/pdf;q=0.9,*/*;q=0.8")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		// Unwrap the url.Error so the sentinel errors are visible to errors.Is
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return nil, fmt.Errorf("failed to fetch %s: %w", rawURL, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("%w: %s returned %d", ErrUnexpectedStatus, rawURL, resp.StatusCode)
+	}
+
+	result := &Response{
+		URL:        resp.Request.URL.String(),
+		StatusCode: resp.StatusCode,
+	}
+	if mediaType, _, err := mime.ParseMediaType(resp.Header.Get("Content-Type")); err == nil {
+		result.ContentType = mediaType
+	}
+
+	if method == http.MethodHead {
+		return result, nil
+	}
+
+	if resp.ContentLength > c.config.MaxBodySize {
+		return nil, fmt.Errorf("%w: %d bytes", ErrBodyTooLarge, resp.ContentLength)
+	}
+
+	// Read one byte more than allowed to detect bodies that are too large
+	body, err := io.ReadAll(io.LimitReader(resp.Body, c.config.MaxBodySize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body: %w", err)
+	}
+	if int64(len(body)) > c.config.MaxBodySize {
+		return nil, fmt.Errorf("%w: more than %d bytes", ErrBodyTooLarge, c.config.MaxBodySize)
+	}
+	result.Body = body
+
+	return result, nil
+}
+
+func (c *Client) checkRedirect(req *http.Request, via []*http.Request) error {
+	if len(via) > c.config.MaxRedirects {
+		return fmt.Errorf("%w: stopped after %d redirects", ErrTooManyRedirects, c.config.MaxRedirects)
+	}
+	if err := checkScheme(req.URL); err != nil {
+		return err
+	}
+
+	req.Header.Set("User-Agent", c.config.UserAgent)
+	return nil
+}
+
+func checkScheme(u *url.URL) error {
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return fmt.Errorf("%w: %q", ErrUnsupportedScheme, u.Scheme)
+	}
+	return nil
+}
+
+// controlAddress is called by the dialer with the resolved IP address before connecting
+func controlAddress(_ string, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, address)
+	}
+
+	addr, err := netip.ParseAddr(host)
+	if err != nil || !IsPublicAddress(addr) {
+		return fmt.Errorf("%w: %s", ErrBlockedAddress, host)
+	}
+
+	return nil
+}
+
+// IsPublicAddress reports whether the IP address is publicly routable
+func IsPublicAddress(addr netip.Addr) bool {
+	addr = addr.Unmap()
+
+	if !addr.IsValid() ||
+		addr.IsLoopback() ||
+		addr.IsPrivate() ||
+		addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() ||
+		addr.IsLinkLocalMulticast() ||
+		addr.IsInterfaceLocalMulticast() ||
+		addr.IsMulticast() {
+		return false
+	}
+
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
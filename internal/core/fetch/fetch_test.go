@@ -0,0 +1,167 @@
+package fetch
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestClient(modify func(*Config)) *Client {
+	config := DefaultConfig()
+	config.AllowPrivate = true // httptest servers listen on loopback
+	if modify != nil {
+		modify(&config)
+	}
+	return NewClient(config)
+}
+
+func TestGet(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, DefaultConfig().UserAgent, r.Header.Get("User-Agent"))
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<html><body>Recipe</body></html>"))
+	}))
+	defer server.Close()
+
+	resp, err := newTestClient(nil).Get(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/html", resp.ContentType)
+	assert.Equal(t, "<html><body>Recipe</body></html>", string(resp.Body))
+}
+
+func TestGet_BlocksPrivateAddresses(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request should not reach the server")
+	}))
+	defer server.Close()
+
+	client := NewClient(DefaultConfig())
+
+	urls := []string{
+		server.URL,
+		strings.Replace(server.URL, "127.0.0.1", "localhost", 1), // resolved by DNS
+		"http://[::1]:27017/",
+		"http://169.254.169.254/latest/meta-data/",
+		"http://10.0.0.1/",
+		"http://0.0.0.0:27017/",
+	}
+
+	for _, u := range urls {
+		t.Run(u, func(t *testing.T) {
+			_, err := client.Get(context.Background(), u)
+			assert.ErrorIs(t, err, ErrBlockedAddress)
+		})
+	}
+}
+
+func TestGet_UnsupportedScheme(t *testing.T) {
+	client := newTestClient(nil)
+
+	for _, u := range []string{"file:///etc/passwd", "ftp://example.com/recipe.txt", "gopher://example.com"} {
+		_, err := client.Get(context.Background(), u)
+		assert.ErrorIs(t, err, ErrUnsupportedScheme, u)
+	}
+
+	_, err := client.Get(context.Background(), "http://")
+	assert.ErrorIs(t, err, ErrInvalidURL)
+}
+
+func TestGet_RedirectLimit(t *testing.T) {
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/file":
+			http.Redirect(w, r, "file:///etc/passwd", http.StatusFound)
+		case "/done":
+			w.Write([]byte("done"))
+		default:
+			var n int
+			if len(r.URL.Path) > 1 {
+				n = len(r.URL.Path) - 1
+			}
+			if n >= 3 {
+				http.Redirect(w, r, server.URL+"/done", http.StatusFound)
+				return
+			}
+			http.Redirect(w, r, r.URL.Path+"x", http.StatusFound)
+		}
+	}))
+	defer server.Close()
+
+	// Four redirects in total
+	resp, err := newTestClient(nil).Get(context.Background(), server.URL+"/")
+	require.NoError(t, err)
+	assert.Equal(t, server.URL+"/done", resp.URL)
+
+	_, err = newTestClient(func(c *Config) { c.MaxRedirects = 2 }).Get(context.Background(), server.URL+"/")
+	assert.ErrorIs(t, err, ErrTooManyRedirects)
+
+	_, err = newTestClient(nil).Get(context.Background(), server.URL+"/file")
+	assert.ErrorIs(t, err, ErrUnsupportedScheme)
+}
+
+func TestGet_MaxBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("chunked") == "true" {
+			w.(http.Flusher).Flush() // no Content-Length
+		}
+		w.Write([]byte(strings.Repeat("a", 2048)))
+	}))
+	defer server.Close()
+
+	client := newTestClient(func(c *Config) { c.MaxBodySize = 1024 })
+
+	_, err := client.Get(context.Background(), server.URL)
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+
+	_, err = client.Get(context.Background(), server.URL+"?chunked=true")
+	assert.ErrorIs(t, err, ErrBodyTooLarge)
+
+	resp, err := newTestClient(func(c *Config) { c.MaxBodySize = 2048 }).Get(context.Background(), server.URL)
+	require.NoError(t, err)
+	assert.Len(t, resp.Body, 2048)
+}
+
+func TestHead_UnexpectedStatus(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	defer server.Close()
+
+	_, err := newTestClient(nil).Head(context.Background(), server.URL)
+	assert.ErrorIs(t, err, ErrUnexpectedStatus)
+}
+
+func TestIsPublicAddress(t *testing.T) {
+	tests := []struct {
+		addr string
+		want bool
+	}{
+		{"93.184.216.34", true},
+		{"2606:2800:220:1:248:1893:25c8:1946", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"::", false},
+		{"fd00::1", false},
+		{"fe80::1", false},
+		{"::ffff:127.0.0.1", false},
+		{"224.0.0.1", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, tt.want, IsPublicAddress(netip.MustParseAddr(tt.addr)))
+		})
+	}
+}
@@ -0,0 +1,61 @@
+package fetch
+
+import (
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// Query parameters that only track where a visitor came from and do not change the page
+var trackingParams = []string{"utm_", "fbclid", "gclid", "mc_cid", "mc_eid", "ref"}
+
+// redactURL returns the URL without its user info, query and fragment, which can hold secrets
+// like tokens, so it can be recorded. URLs that cannot be parsed are left out entirely.
+func redactURL(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return ""
+	}
+	u.User = nil
+	u.RawQuery = ""
+	u.ForceQuery = false
+	u.Fragment = ""
+	u.RawFragment = ""
+	return u.String()
+}
+
+// NormalizeURL normalizes a URL so that trivially different links to the same page compare
+// equal: the scheme and host are lowercased, default ports, fragments and tracking
+// parameters are removed and the remaining query parameters are sorted.
+func NormalizeURL(rawURL string) string {
+	u, err := url.Parse(strings.TrimSpace(rawURL))
+	if err != nil {
+		return rawURL
+	}
+
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	if (u.Scheme == "http" && u.Port() == "80") || (u.Scheme == "https" && u.Port() == "443") {
+		u.Host = u.Hostname()
+	}
+	u.Fragment = ""
+	u.RawFragment = ""
+	if u.Path == "" {
+		u.Path = "/"
+	}
+
+	query := u.Query()
+	for param := range query {
+		for _, tracking := range trackingParams {
+			if param == tracking || (strings.HasSuffix(tracking, "_") && strings.HasPrefix(param, tracking)) {
+				query.Del(param)
+			}
+		}
+	}
+	for _, values := range query {
+		sort.Strings(values)
+	}
+	u.RawQuery = query.Encode() // Encode sorts by key
+
+	return u.String()
+}
@@ -0,0 +1,141 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const _Namespace = "recipebank"
+
+// Registry holds the metrics of the core API, which Handler exposes
+var Registry = prometheus.NewRegistry()
+
+var (
+	// HTTPRequests counts the API requests per route and status
+	HTTPRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: _Namespace,
+		Subsystem: "http",
+		Name:      "requests_total",
+		Help:      "API requests by method, route and status code.",
+	}, []string{"method", "route", "status"})
+	// HTTPRequestDuration observes the latency of API requests per route and status
+	HTTPRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: _Namespace,
+		Subsystem: "http",
+		Name:      "request_duration_seconds",
+		Help:      "Latency of API requests by method, route and status code.",
+		Buckets:   []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10, 30, 60},
+	}, []string{"method", "route", "status"})
+	// RateLimitedRequests counts the requests rejected by the rate limiter per route group
+	RateLimitedRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: _Namespace,
+		Subsystem: "http",
+		Name:      "rate_limited_requests_total",
+		Help:      "API requests rejected by the rate limiter by route group.",
+	}, []string{"group"})
+
+	// StorageOperationDuration observes the latency of database commands per collection
+	StorageOperationDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: _Namespace,
+		Subsystem: "storage",
+		Name:      "operation_duration_seconds",
+		Help:      "Latency of database commands by collection and command.",
+		Buckets:   []float64{0.001, 0.0025, 0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5},
+	}, []string{"collection", "command"})
+	// StorageOperationErrors counts the failed database commands per collection
+	StorageOperationErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: _Namespace,
+		Subsystem: "storage",
+		Name:      "operation_errors_total",
+		Help:      "Failed database commands by collection and command.",
+	}, []string{"collection", "command"})
+
+	// AICalls counts the calls to the AI provider per operation and result, which is "success"
+	// or the kind of failure
+	AICalls = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: _Namespace,
+		Subsystem: "ai",
+		Name:      "calls_total",
+		Help:      "Calls to the AI provider by operation and result (success or the kind of failure).",
+	}, []string{"operation", "result"})
+	// AICallDuration observes the latency of calls to the AI provider, including retries
+	AICallDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: _Namespace,
+		Subsystem: "ai",
+		Name:      "call_duration_seconds",
+		Help:      "Latency of calls to the AI provider by operation, including retries.",
+		Buckets:   []float64{0.5, 1, 2.5, 5, 10, 20, 30, 60, 120},
+	}, []string{"operation"})
+	// AITokens counts the tokens used by the AI provider per model and type (prompt or completion)
+	AITokens = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: _Namespace,
+		Subsystem: "ai",
+		Name:      "tokens_total",
+		Help:      "Tokens used by the AI provider by model and type (prompt or completion).",
+	}, []string{"model", "type"})
+
+	recipesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(_Namespace, "", "recipes"),
+		"Recipes by visibility.",
+		[]string{"visibility"}, nil,
+	)
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		HTTPRequests,
+		HTTPRequestDuration,
+		RateLimitedRequests,
+		StorageOperationDuration,
+		StorageOperationErrors,
+		AICalls,
+		AICallDuration,
+		AITokens,
+	)
+}
+
+// Handler serves the metrics in the Prometheus text format
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{})
+}
+
+// RecipeCounter counts the recipes by visibility
+type RecipeCounter func(ctx context.Context) (map[string]int64, error)
+
+// NewRecipeCollector returns a collector of the recipe gauge, which counts the recipes when the
+// metrics are scraped
+func NewRecipeCollector(count RecipeCounter) prometheus.Collector {
+	return recipeCollector{count: count}
+}
+
+type recipeCollector struct {
+	count RecipeCounter
+}
+
+func (c recipeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- recipesDesc
+}
+
+func (c recipeCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	counts, err := c.count(ctx)
+	if err != nil {
+		slog.Warn("Unable to count recipes for metrics", "error", err.Error())
+		ch <- prometheus.NewInvalidMetric(recipesDesc, err)
+		return
+	}
+
+	for visibility, count := range counts {
+		ch <- prometheus.MustNewConstMetric(recipesDesc, prometheus.GaugeValue, float64(count), visibility)
+	}
+}
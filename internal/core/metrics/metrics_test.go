@@ -0,0 +1,39 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecipeCollector(t *testing.T) {
+	t.Run("Counts recipes by visibility", func(t *testing.T) {
+		collector := NewRecipeCollector(func(ctx context.Context) (map[string]int64, error) {
+			return map[string]int64{"private": 3, "public": 1}, nil
+		})
+
+		err := testutil.CollectAndCompare(collector, strings.NewReader(`
+# HELP recipebank_recipes Recipes by visibility.
+# TYPE recipebank_recipes gauge
+recipebank_recipes{visibility="private"} 3
+recipebank_recipes{visibility="public"} 1
+`))
+		assert.NoError(t, err)
+	})
+
+	t.Run("Fails the scrape when counting fails", func(t *testing.T) {
+		registry := prometheus.NewRegistry()
+		require.NoError(t, registry.Register(NewRecipeCollector(func(ctx context.Context) (map[string]int64, error) {
+			return nil, errors.New("database is down")
+		})))
+
+		_, err := registry.Gather()
+		assert.ErrorContains(t, err, "database is down")
+	})
+}
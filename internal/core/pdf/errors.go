@@ -0,0 +1,8 @@
+package pdf
+
+import "errors"
+
+var (
+	ErrInvalidPDF   = errors.New("invalid PDF")
+	ErrEncryptedPDF = errors.New("PDF is encrypted")
+)
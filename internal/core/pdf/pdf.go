@@ -0,0 +1,290 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"image"
+	"image/png"
+	"io"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	pdflib "github.com/ledongthuc/pdf"
+)
+
+const (
+	// Images smaller than this (in pixels, both sides) are logos or icons rather than scanned pages
+	_MinPageImageSize = 300
+	// Maximum number of pixels of a decoded image, to protect against decompression bombs
+	_MaxImagePixels = 50_000_000
+)
+
+var (
+	objectHeaderPattern = regexp.MustCompile(`\d+\s+\d+\s+obj\b`)
+	imageSubtypePattern = regexp.MustCompile(`/Subtype\s*/Image\b`)
+	lengthPattern       = regexp.MustCompile(`/Length\s+(\d+)(\s+\d+\s+R)?`)
+	widthPattern        = regexp.MustCompile(`/Width\s+(\d+)`)
+	heightPattern       = regexp.MustCompile(`/Height\s+(\d+)`)
+	bitsPattern         = regexp.MustCompile(`/BitsPerComponent\s+(\d+)`)
+	colorSpacePattern   = regexp.MustCompile(`/ColorSpace\s*/(\w+)`)
+	filterPattern       = regexp.MustCompile(`/Filter\s*(?:/(\w+)|\[\s*/(\w+)\s*\])`)
+)
+
+// Image is an image of a page extracted from a PDF
+type Image struct {
+	Data        []byte
+	ContentType string // "image/jpeg" or "image/png"
+}
+
+// IsPDF reports whether the data starts with a PDF header
+func IsPDF(data []byte) bool {
+	return bytes.HasPrefix(data, []byte("%PDF-"))
+}
+
+// ExtractText extracts the text layer of a PDF, one line per line of text on the pages and
+// with a blank line between pages. Scanned PDFs without a text layer give an empty string.
+func ExtractText(data []byte) (text string, err error) {
+	if !IsPDF(data) {
+		return "", fmt.Errorf("%w: missing PDF header", ErrInvalidPDF)
+	}
+
+	// The PDF library panics on malformed or unsupported content
+	defer func() {
+		if r := recover(); r != nil {
+			text, err = "", fmt.Errorf("%w: %v", ErrInvalidPDF, r)
+		}
+	}()
+
+	reader, err := pdflib.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		if errors.Is(err, pdflib.ErrInvalidPassword) {
+			return "", ErrEncryptedPDF
+		}
+		return "", fmt.Errorf("%w: %v", ErrInvalidPDF, err)
+	}
+
+	pages := make([]string, 0, reader.NumPage())
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+
+		if pageText := pageLines(page.Content().Text); pageText != "" {
+			pages = append(pages, pageText)
+		}
+	}
+
+	return strings.Join(pages, "\n\n"), nil
+}
+
+// pageLines joins the characters of a page into lines. The characters are kept in the order of
+// the content stream, which is the reading order for nearly all PDFs, and a new line is started
+// when the baseline moves.
+func pageLines(chars []pdflib.Text) string {
+	var sb strings.Builder
+	var line strings.Builder
+
+	flush := func() {
+		if s := strings.Join(strings.Fields(line.String()), " "); s != "" {
+			sb.WriteString(s)
+			sb.WriteByte('\n')
+		}
+		line.Reset()
+	}
+
+	for i, char := range chars {
+		if i > 0 {
+			prev := chars[i-1]
+			size := math.Max(prev.FontSize, 1)
+
+			switch {
+			case math.Abs(char.Y-prev.Y) > size/2:
+				flush()
+			case char.X-(prev.X+prev.W) > size/4:
+				// Words are often positioned separately instead of separated by a space
+				line.WriteByte(' ')
+			}
+		}
+		line.WriteString(char.S)
+	}
+	flush()
+
+	return strings.TrimSpace(sb.String())
+}
+
+// ExtractPageImages extracts the images of scanned pages from a PDF, in the order they are stored
+// in the file, which for scanners and the common export tools is the page order. Pure Go has no
+// PDF renderer, so instead of rasterizing, the images embedded in the pages are used directly:
+// JPEGs are passed through as is and uncompressed or Flate compressed RGB and grayscale images
+// are converted to PNG. Small images (logos, icons) are skipped.
+func ExtractPageImages(data []byte, maxImages int) ([]Image, error) {
+	if !IsPDF(data) {
+		return nil, fmt.Errorf("%w: missing PDF header", ErrInvalidPDF)
+	}
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		return nil, ErrEncryptedPDF
+	}
+
+	var images []Image
+	for _, header := range objectHeaderPattern.FindAllIndex(data, -1) {
+		if maxImages > 0 && len(images) >= maxImages {
+			break
+		}
+
+		dict, stream, ok := objectStream(data[header[1]:])
+		if !ok || !imageSubtypePattern.Match(dict) {
+			continue
+		}
+
+		if img, ok := pageImage(dict, stream); ok {
+			images = append(images, img)
+		}
+	}
+
+	return images, nil
+}
+
+// objectStream splits an indirect object (starting after "obj") into its dictionary and stream data
+func objectStream(object []byte) (dict []byte, stream []byte, ok bool) {
+	streamStart := bytes.Index(object, []byte("stream"))
+	if streamStart < 0 {
+		return nil, nil, false
+	}
+	// The stream must belong to this object
+	if end := bytes.Index(object, []byte("endobj")); end >= 0 && end < streamStart {
+		return nil, nil, false
+	}
+	dict = object[:streamStart]
+
+	// The stream data starts after the end of line following the keyword
+	start := streamStart + len("stream")
+	if bytes.HasPrefix(object[start:], []byte("\r\n")) {
+		start += 2
+	} else if start < len(object) && (object[start] == '\n' || object[start] == '\r') {
+		start++
+	}
+
+	// Use the length from the dictionary when it is direct and consistent, else search for the end
+	if m := lengthPattern.FindSubmatch(dict); m != nil && len(m[2]) == 0 {
+		if length, err := strconv.Atoi(string(m[1])); err == nil && start+length <= len(object) {
+			rest := bytes.TrimLeft(object[start+length:], "\r\n\t ")
+			if bytes.HasPrefix(rest, []byte("endstream")) {
+				return dict, object[start : start+length], true
+			}
+		}
+	}
+
+	end := bytes.Index(object[start:], []byte("endstream"))
+	if end < 0 {
+		return nil, nil, false
+	}
+	return dict, bytes.TrimRight(object[start:start+end], "\r\n"), true
+}
+
+// pageImage converts the stream of an image XObject to an image the AI can read
+func pageImage(dict []byte, stream []byte) (Image, bool) {
+	width, height := intValue(widthPattern, dict), intValue(heightPattern, dict)
+	if width < _MinPageImageSize || height < _MinPageImageSize {
+		return Image{}, false
+	}
+	// The dimensions are untrusted, compare without multiplying them so the product cannot overflow
+	if width > _MaxImagePixels/height {
+		return Image{}, false
+	}
+
+	colorSpace := ""
+	if m := colorSpacePattern.FindSubmatch(dict); m != nil {
+		colorSpace = string(m[1])
+	}
+
+	filter := ""
+	if bytes.Contains(dict, []byte("/Filter")) {
+		// Only a single filter is supported, not chained filters
+		m := filterPattern.FindSubmatch(dict)
+		if m == nil {
+			return Image{}, false
+		}
+		filter = string(m[1]) + string(m[2])
+	}
+
+	switch filter {
+	case "DCTDecode":
+		// CMYK JPEGs are not supported by the vision models
+		if colorSpace == "DeviceCMYK" || !bytes.HasPrefix(stream, []byte{0xFF, 0xD8}) {
+			return Image{}, false
+		}
+		return Image{Data: stream, ContentType: "image/jpeg"}, true
+	case "FlateDecode", "":
+		if bytes.Contains(dict, []byte("/DecodeParms")) || intValue(bitsPattern, dict) != 8 {
+			return Image{}, false
+		}
+
+		var r io.Reader = bytes.NewReader(stream)
+		if filter == "FlateDecode" {
+			zr, err := zlib.NewReader(r)
+			if err != nil {
+				return Image{}, false
+			}
+			defer zr.Close()
+			r = zr
+		}
+
+		data, ok := encodePNG(r, colorSpace, width, height)
+		if !ok {
+			return Image{}, false
+		}
+		return Image{Data: data, ContentType: "image/png"}, true
+	default:
+		return Image{}, false
+	}
+}
+
+// encodePNG encodes raw 8-bit RGB or grayscale samples as PNG
+func encodePNG(r io.Reader, colorSpace string, width, height int) ([]byte, bool) {
+	var img image.Image
+	switch colorSpace {
+	case "DeviceRGB":
+		samples := make([]byte, width*height*3)
+		if _, err := io.ReadFull(r, samples); err != nil {
+			return nil, false
+		}
+
+		rgba := image.NewRGBA(image.Rect(0, 0, width, height))
+		for i := 0; i < width*height; i++ {
+			copy(rgba.Pix[i*4:i*4+3], samples[i*3:i*3+3])
+			rgba.Pix[i*4+3] = 0xFF
+		}
+		img = rgba
+	case "DeviceGray":
+		gray := image.NewGray(image.Rect(0, 0, width, height))
+		if _, err := io.ReadFull(r, gray.Pix); err != nil {
+			return nil, false
+		}
+		img = gray
+	default:
+		return nil, false
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+func intValue(pattern *regexp.Regexp, dict []byte) int {
+	m := pattern.FindSubmatch(dict)
+	if m == nil {
+		return 0
+	}
+	n, err := strconv.Atoi(string(m[1]))
+	if err != nil {
+		return 0
+	}
+	return n
+}
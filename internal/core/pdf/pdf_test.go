@@ -0,0 +1,210 @@
+package pdf
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// buildPDF writes a PDF with one page per content stream, the given extra objects and a valid
+// cross-reference table. Objects 1 and 2 are the catalog and the page tree, object 3 is a font.
+func buildPDF(t *testing.T, pages []string, images [][]byte) []byte {
+	t.Helper()
+
+	var objects []string
+	objects = append(objects, "") // catalog, written below
+	objects = append(objects, "") // page tree, written below
+	objects = append(objects, "<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+
+	// Image XObjects are available on every page as /Im1, /Im2, ...
+	var xobjects []string
+	for _, img := range images {
+		objects = append(objects, string(img))
+		xobjects = append(xobjects, fmt.Sprintf("/Im%d %d 0 R", len(xobjects)+1, len(objects)))
+	}
+
+	var kids []string
+	for _, content := range pages {
+		objects = append(objects, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+		contentRef := len(objects)
+		objects = append(objects, fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Contents %d 0 R /Resources << /Font << /F1 3 0 R >> /XObject << %s >> >> >>",
+			contentRef, strings.Join(xobjects, " ")))
+		kids = append(kids, fmt.Sprintf("%d 0 R", len(objects)))
+	}
+	objects[0] = "<< /Type /Catalog /Pages 2 0 R >>"
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(kids))
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+func textContent(lines ...string) string {
+	var sb strings.Builder
+	sb.WriteString("BT /F1 12 Tf 72 770 Td\n")
+	for _, line := range lines {
+		fmt.Fprintf(&sb, "(%s) Tj 0 -16 Td\n", line)
+	}
+	sb.WriteString("ET")
+	return sb.String()
+}
+
+func jpegImageObject(t *testing.T, width, height int) ([]byte, []byte) {
+	t.Helper()
+
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for i := range img.Pix {
+		img.Pix[i] = byte(i)
+	}
+	var data bytes.Buffer
+	require.NoError(t, jpeg.Encode(&data, img, nil))
+
+	object := fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n",
+		width, height, data.Len())
+	return append(append([]byte(object), data.Bytes()...), []byte("\nendstream")...), data.Bytes()
+}
+
+func grayImageObject(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	var data bytes.Buffer
+	zw := zlib.NewWriter(&data)
+	_, err := zw.Write(bytes.Repeat([]byte{0x80}, width*height))
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+
+	// Indirect length, so the end of the stream has to be found by searching
+	object := fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width %d /Height %d /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter [/FlateDecode] /Length 99 0 R >>\nstream\n",
+		width, height)
+	return append(append([]byte(object), data.Bytes()...), []byte("\nendstream")...)
+}
+
+func TestExtractText(t *testing.T) {
+	data := buildPDF(t, []string{
+		textContent("Pancakes", "3 dl flour", "6 dl milk"),
+		textContent("1. Whisk everything together.", "2. Fry in butter."),
+	}, nil)
+
+	text, err := ExtractText(data)
+	require.NoError(t, err)
+	assert.Equal(t, "Pancakes\n3 dl flour\n6 dl milk\n\n1. Whisk everything together.\n2. Fry in butter.", text)
+}
+
+func TestExtractText_NoTextLayer(t *testing.T) {
+	object, _ := jpegImageObject(t, 400, 600)
+	data := buildPDF(t, []string{"q 595 0 0 842 0 0 cm /Im1 Do Q"}, [][]byte{object})
+
+	text, err := ExtractText(data)
+	require.NoError(t, err)
+	assert.Empty(t, text)
+}
+
+func TestExtractText_Invalid(t *testing.T) {
+	for _, data := range [][]byte{
+		[]byte("not a pdf"),
+		[]byte("%PDF-1.4\n1 0 obj\n<< >>\nendobj\n"), // no cross-reference table
+		[]byte("%PDF-1.4\nstartxref\n999999\n%%EOF\n"),
+	} {
+		_, err := ExtractText(data)
+		assert.ErrorIs(t, err, ErrInvalidPDF, string(data))
+	}
+}
+
+func TestExtractPageImages(t *testing.T) {
+	page1, jpegData := jpegImageObject(t, 400, 600)
+	page2 := grayImageObject(t, 320, 480)
+	logo, _ := jpegImageObject(t, 64, 64)
+
+	data := buildPDF(t, []string{
+		"q 595 0 0 842 0 0 cm /Im1 Do Q",
+		"q 595 0 0 842 0 0 cm /Im2 Do Q q 64 0 0 64 10 10 cm /Im3 Do Q",
+	}, [][]byte{page1, page2, logo})
+
+	images, err := ExtractPageImages(data, 10)
+	require.NoError(t, err)
+	require.Len(t, images, 2, "the logo should be skipped")
+
+	assert.Equal(t, "image/jpeg", images[0].ContentType)
+	assert.Equal(t, jpegData, images[0].Data)
+
+	assert.Equal(t, "image/png", images[1].ContentType)
+	img, err := png.Decode(bytes.NewReader(images[1].Data))
+	require.NoError(t, err)
+	assert.Equal(t, image.Rect(0, 0, 320, 480), img.Bounds())
+	assert.Equal(t, color.Gray{Y: 0x80}, img.At(10, 10))
+
+	images, err = ExtractPageImages(data, 1)
+	require.NoError(t, err)
+	assert.Len(t, images, 1)
+}
+
+func TestExtractPageImages_Unsupported(t *testing.T) {
+	_, err := ExtractPageImages([]byte("not a pdf"), 10)
+	assert.ErrorIs(t, err, ErrInvalidPDF)
+
+	encrypted := []byte("%PDF-1.4\ntrailer\n<< /Encrypt 5 0 R >>\n%%EOF\n")
+	_, err = ExtractPageImages(encrypted, 10)
+	assert.ErrorIs(t, err, ErrEncryptedPDF)
+
+	// Chained filters and CMYK are skipped
+	chained := []byte("%PDF-1.4\n1 0 obj\n<< /Subtype /Image /Width 400 /Height 400 /ColorSpace /DeviceRGB /BitsPerComponent 8 /Filter [/ASCII85Decode /DCTDecode] /Length 4 >>\nstream\nabcd\nendstream\nendobj\n")
+	cmyk := []byte("%PDF-1.4\n1 0 obj\n<< /Subtype /Image /Width 400 /Height 400 /ColorSpace /DeviceCMYK /BitsPerComponent 8 /Filter /DCTDecode /Length 4 >>\nstream\n\xff\xd8\xff\xe0\nendstream\nendobj\n")
+	for _, data := range [][]byte{chained, cmyk} {
+		images, err := ExtractPageImages(data, 10)
+		require.NoError(t, err)
+		assert.Empty(t, images)
+	}
+}
+
+func TestExtractPageImages_OversizedDimensions(t *testing.T) {
+	// The product of the dimensions overflows, which must not reach the image allocation
+	for _, size := range []string{
+		"/Width 4294967296 /Height 4294967296",
+		"/Width 9223372036854775807 /Height 2",
+		"/Width 3037000500 /Height 3037000500",
+		"/Width 100000 /Height 100000",
+	} {
+		for _, colorSpace := range []string{"DeviceGray", "DeviceRGB"} {
+			data := []byte("%PDF-1.4\n1 0 obj\n<< /Subtype /Image " + size + " /ColorSpace /" + colorSpace +
+				" /BitsPerComponent 8 /Length 4 >>\nstream\nabcd\nendstream\nendobj\n")
+
+			images, err := ExtractPageImages(data, 10)
+			require.NoError(t, err, size)
+			assert.Empty(t, images, size)
+		}
+	}
+}
+
+func FuzzExtractText(f *testing.F) {
+	f.Add([]byte("%PDF-1.4\n%%EOF"))
+	f.Add([]byte("%PDF-1.4\nxref\n0 1\n0000000000 65535 f \ntrailer\n<< /Size 1 >>\nstartxref\n9\n%%EOF\n"))
+	f.Add([]byte("%PDF-1.4\n1 0 obj\n<< /Subtype /Image /Width 4294967296 /Height 4294967296 /ColorSpace /DeviceGray /BitsPerComponent 8 /Length 4 >>\nstream\nabcd\nendstream\nendobj\n"))
+
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Must never panic
+		_, _ = ExtractText(data)
+		_, _ = ExtractPageImages(data, 10)
+	})
+}
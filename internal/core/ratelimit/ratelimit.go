@@ -0,0 +1,166 @@
+package ratelimit
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Budget is the number of requests a client can make per period. It is enforced as a token
+// bucket that holds Requests tokens and is refilled evenly over the period, so a client can burst
+// up to the whole budget and then continues at the average rate.
+type Budget struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseBudget parses a budget as "requests/period", e.g. "60/1m". An empty budget or 0 requests
+// disables the limit.
+func ParseBudget(s string) (Budget, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return Budget{}, nil
+	}
+
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Budget{}, fmt.Errorf("invalid rate limit %q, expected requests/period", s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n < 0 {
+		return Budget{}, fmt.Errorf("invalid number of requests in rate limit %q", s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || d <= 0 {
+		return Budget{}, fmt.Errorf("invalid period in rate limit %q", s)
+	}
+
+	return Budget{Requests: n, Period: d}, nil
+}
+
+// Enabled reports whether the budget limits requests
+func (b Budget) Enabled() bool {
+	return b.Requests > 0 && b.Period > 0
+}
+
+func (b Budget) String() string {
+	return fmt.Sprintf("%d/%s", b.Requests, b.Period)
+}
+
+// Result is the outcome of a request against a budget
+type Result struct {
+	Allowed bool
+	// Requests of the budget
+	Limit int
+	// Requests that are left right now
+	Remaining int
+	// Time until the whole budget is available again
+	Reset time.Duration
+	// Time until the next request is allowed, zero if this one is
+	RetryAfter time.Duration
+}
+
+// Limiter enforces a budget per client key, e.g. a user or IP address. Buckets of clients that
+// have been idle long enough to refill are dropped, so memory only grows with active clients.
+type Limiter struct {
+	mu      sync.Mutex
+	budget  Budget
+	rate    float64 // tokens per second
+	now     func() time.Time
+	buckets map[string]*bucket
+	swept   time.Time
+}
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+func NewLimiter(budget Budget) *Limiter {
+	l := &Limiter{
+		budget:  budget,
+		now:     time.Now,
+		buckets: map[string]*bucket{},
+	}
+	if budget.Enabled() {
+		l.rate = float64(budget.Requests) / budget.Period.Seconds()
+	}
+	return l
+}
+
+// Budget returns the budget the limiter enforces
+func (l *Limiter) Budget() Budget {
+	return l.budget
+}
+
+// Allow takes a request of the client from its budget, if there is one left
+func (l *Limiter) Allow(key string) Result {
+	if !l.budget.Enabled() {
+		return Result{Allowed: true}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	limit := float64(l.budget.Requests)
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: limit, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(limit, b.tokens+now.Sub(b.updated).Seconds()*l.rate)
+	b.updated = now
+
+	result := Result{Limit: l.budget.Requests}
+	if b.tokens >= 1 {
+		b.tokens--
+		result.Allowed = true
+	} else {
+		result.RetryAfter = l.duration(1 - b.tokens)
+	}
+	result.Remaining = int(b.tokens)
+	result.Reset = l.duration(limit - b.tokens)
+
+	return result
+}
+
+// Refund gives a request that was taken by Allow back to the client, e.g. when it turned out not
+// to count against the budget
+func (l *Limiter) Refund(key string) {
+	if !l.budget.Enabled() {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if b, ok := l.buckets[key]; ok {
+		b.tokens = math.Min(float64(l.budget.Requests), b.tokens+1)
+	}
+}
+
+// sweep drops the buckets that are full again, at most once per period
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.swept) < l.budget.Period {
+		return
+	}
+	l.swept = now
+
+	limit := float64(l.budget.Requests)
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.updated).Seconds()*l.rate >= limit {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// duration returns the time it takes to refill the tokens
+func (l *Limiter) duration(tokens float64) time.Duration {
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
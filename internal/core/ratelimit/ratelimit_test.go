@@ -0,0 +1,101 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseBudget(t *testing.T) {
+	budget, err := ParseBudget(" 60 / 1m ")
+	require.NoError(t, err)
+	assert.Equal(t, Budget{Requests: 60, Period: time.Minute}, budget)
+
+	budget, err = ParseBudget("")
+	require.NoError(t, err)
+	assert.False(t, budget.Enabled())
+
+	for _, invalid := range []string{"60", "many/1m", "-1/1m", "60/soon", "60/0s"} {
+		_, err := ParseBudget(invalid)
+		assert.Error(t, err, invalid)
+	}
+}
+
+func TestLimiter(t *testing.T) {
+	now := time.Now()
+	newLimiter := func(budget Budget) *Limiter {
+		l := NewLimiter(budget)
+		l.now = func() time.Time { return now }
+		return l
+	}
+
+	t.Run("Burst then refill", func(t *testing.T) {
+		l := newLimiter(Budget{Requests: 3, Period: 3 * time.Second})
+
+		for remaining := 2; remaining >= 0; remaining-- {
+			result := l.Allow("anton")
+			assert.True(t, result.Allowed)
+			assert.Equal(t, 3, result.Limit)
+			assert.Equal(t, remaining, result.Remaining)
+		}
+
+		result := l.Allow("anton")
+		assert.False(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+		assert.Equal(t, time.Second, result.RetryAfter)
+		assert.Equal(t, 3*time.Second, result.Reset)
+
+		// Other clients have their own budget
+		assert.True(t, l.Allow("zoe").Allowed)
+
+		now = now.Add(time.Second)
+		result = l.Allow("anton")
+		assert.True(t, result.Allowed)
+		assert.Equal(t, 0, result.Remaining)
+		assert.False(t, l.Allow("anton").Allowed)
+	})
+
+	t.Run("Refund", func(t *testing.T) {
+		l := newLimiter(Budget{Requests: 2, Period: time.Hour})
+
+		for range 10 {
+			assert.True(t, l.Allow("anton").Allowed)
+			l.Refund("anton")
+		}
+		assert.True(t, l.Allow("anton").Allowed)
+		assert.True(t, l.Allow("anton").Allowed)
+		assert.False(t, l.Allow("anton").Allowed)
+
+		// The budget does not grow beyond its requests
+		l.Refund("zoe")
+		l.Refund("zoe")
+		assert.True(t, l.Allow("zoe").Allowed)
+		assert.True(t, l.Allow("zoe").Allowed)
+		assert.False(t, l.Allow("zoe").Allowed)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		l := newLimiter(Budget{})
+
+		for range 100 {
+			assert.True(t, l.Allow("anton").Allowed)
+		}
+	})
+
+	t.Run("Idle clients are dropped", func(t *testing.T) {
+		l := newLimiter(Budget{Requests: 2, Period: time.Minute})
+
+		l.Allow("anton")
+		l.Allow("zoe")
+		now = now.Add(30 * time.Second)
+		l.Allow("zoe")
+		now = now.Add(time.Minute)
+		l.Allow("new")
+
+		assert.NotContains(t, l.buckets, "anton")
+		assert.NotContains(t, l.buckets, "zoe")
+		assert.Contains(t, l.buckets, "new")
+	})
+}
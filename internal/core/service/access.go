@@ -0,0 +1,73 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"slices"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/auth"
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+)
+
+// viewerFromContext returns the authenticated user of the context as a viewer of recipes, in
+// its active household, anonymous if there is none
+func viewerFromContext(ctx context.Context) models.RecipeViewer {
+	identity, ok := auth.IdentityFromContext(ctx)
+	if !ok {
+		return models.RecipeViewer{}
+	}
+	return models.RecipeViewer{
+		UserID:        identity.User.ID,
+		Admin:         identity.User.Admin,
+		HouseholdID:   identity.HouseholdID,
+		HouseholdRole: identity.HouseholdRole,
+	}
+}
+
+// getEditableRecipe returns the recipe if the viewer of the context can change it. Recipes the
+// viewer cannot see are not found, so their existence is not revealed.
+func (s *RecipeService) getEditableRecipe(ctx context.Context, id string) (*models.Recipe, error) {
+	recipe, err := s.GetRecipe(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !recipe.EditableBy(viewerFromContext(ctx)) {
+		return nil, fmt.Errorf("%w: only the owner of the recipe or editors of its household can change it", ErrForbidden)
+	}
+	return recipe, nil
+}
+
+// setOwner makes the viewer of the context the owner of a new recipe. Without another visibility
+// the recipe is visible to the active household, or private if there is none. The storage
+// creates the recipe in the active household.
+func setOwner(ctx context.Context, recipe *models.Recipe) error {
+	viewer := viewerFromContext(ctx)
+	if viewer.Anonymous() {
+		return fmt.Errorf("%w: recipes can only be created by users", ErrForbidden)
+	}
+	if !viewer.HouseholdID.IsZero() && !viewer.CanEditHousehold() {
+		return fmt.Errorf("%w: viewers of a household cannot create recipes in it", ErrForbidden)
+	}
+
+	recipe.OwnerID = viewer.UserID
+	if recipe.Visibility == "" {
+		recipe.Visibility = models.VisibilityPrivate
+		if !viewer.HouseholdID.IsZero() {
+			recipe.Visibility = models.VisibilityHousehold
+		}
+	}
+	return nil
+}
+
+// errRecipeNotFound is returned for recipes that do not exist or the viewer cannot see
+func errRecipeNotFound(id string) error {
+	return fmt.Errorf("%w: recipe with ID %s", storage.ErrNotFound, id)
+}
+
+func validateVisibility(visibility string) error {
+	if !slices.Contains(models.Visibilities, visibility) {
+		return fmt.Errorf("visibility must be one of %v", models.Visibilities)
+	}
+	return nil
+}
@@ -0,0 +1,337 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/fetch"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+)
+
+const (
+	// Minimum title and ingredient set similarity of recipes that are likely duplicates, and the
+	// minimum mean of the two
+	_DuplicateTitleSimilarity      = 0.5
+	_DuplicateIngredientSimilarity = 0.7
+	_DuplicateSimilarity           = 0.75
+	// Minimum name similarity of ingredients that are the same
+	_SameIngredientSimilarity = 0.5
+)
+
+// Prefixes of the duplicate keys of a recipe
+const (
+	_DuplicateKeySourceURL = "url:"
+	_DuplicateKeyTitle     = "title:"
+)
+
+// Minimum length of the title words that are duplicate keys
+const _MinTitleKeyLength = 3
+
+// titleStopwords are the title words that are not duplicate keys, since they are in the titles of
+// many unrelated recipes and would select most of the collection as candidates
+var titleStopwords = map[string]bool{
+	"and": true, "the": true, "with": true, "for": true, "from": true, "without": true,
+	"into": true, "over": true, "your": true, "our": true, "recipe": true,
+	"och": true, "med": true, "utan": true, "till": true, "för": true,
+}
+
+type allowDuplicatesKey struct{}
+
+// AllowDuplicates returns a context for which recipes are created even if they are likely
+// duplicates of existing recipes
+func AllowDuplicates(ctx context.Context) context.Context {
+	return context.WithValue(ctx, allowDuplicatesKey{}, true)
+}
+
+// DuplicatesAllowed reports whether likely duplicates are created for the context
+func DuplicatesAllowed(ctx context.Context) bool {
+	allowed, _ := ctx.Value(allowDuplicatesKey{}).(bool)
+	return allowed
+}
+
+// DuplicateError is returned when a new recipe is likely a duplicate of existing recipes. It
+// wraps ErrDuplicate.
+type DuplicateError struct {
+	Candidates []models.DuplicateCandidate
+}
+
+func (e *DuplicateError) Error() string {
+	return fmt.Sprintf("%s: likely duplicate of %d existing recipes", ErrDuplicate, len(e.Candidates))
+}
+
+func (e *DuplicateError) Unwrap() error {
+	return ErrDuplicate
+}
+
+// GetDuplicateRecipes groups the existing recipes that are likely duplicates of each other.
+// Recipes are in the same cluster if they are connected by pairs of likely duplicates. Only the
+// recipes the viewer of the context can see are compared.
+func (s *RecipeService) GetDuplicateRecipes(ctx context.Context) ([]models.DuplicateCluster, error) {
+	viewer := viewerFromContext(ctx)
+	fingerprints, err := s.storage.GetRecipeFingerprints(ctx, &viewer)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recipes: %w", err)
+	}
+
+	features := make([]duplicateFeatures, len(fingerprints))
+	for i, fingerprint := range fingerprints {
+		features[i] = newDuplicateFeatures(fingerprint)
+	}
+
+	// Union-find over the pairs of likely duplicates, with the oldest recipe as the root
+	parents := make([]int, len(features))
+	for i := range parents {
+		parents[i] = i
+	}
+	var root func(i int) int
+	root = func(i int) int {
+		if parents[i] != i {
+			parents[i] = root(parents[i])
+		}
+		return parents[i]
+	}
+
+	// Only recipes that share a duplicate key can be likely duplicates, so each recipe is compared
+	// with the older recipes it shares a key with
+	duplicated := make([]bool, len(features))
+	sharing := make(map[string][]int)
+	for j := range features {
+		compared := make(map[int]bool)
+		for _, key := range features[j].keys() {
+			for _, i := range sharing[key] {
+				if compared[i] {
+					continue
+				}
+				compared[i] = true
+
+				if _, _, ok := compareDuplicates(features[i], features[j]); ok {
+					parents[max(root(i), root(j))] = min(root(i), root(j))
+					duplicated[i], duplicated[j] = true, true
+				}
+			}
+			sharing[key] = append(sharing[key], j)
+		}
+	}
+
+	clusters := []models.DuplicateCluster{}
+	clusterIndex := make(map[int]int)
+	for i, fingerprint := range fingerprints {
+		if !duplicated[i] {
+			continue
+		}
+
+		index, ok := clusterIndex[root(i)]
+		if !ok {
+			index = len(clusters)
+			clusterIndex[root(i)] = index
+			clusters = append(clusters, models.DuplicateCluster{Reason: models.DuplicateSourceURL, Recipes: []models.DuplicateRecipe{}})
+		}
+
+		// The cluster is similar unless all its recipes are imported from the same URL
+		if features[i].sourceURL == "" || features[i].sourceURL != features[root(i)].sourceURL {
+			clusters[index].Reason = models.DuplicateSimilar
+		}
+		clusters[index].Recipes = append(clusters[index].Recipes, models.DuplicateRecipe{
+			ID:        fingerprint.ID,
+			Title:     fingerprint.Title,
+			SourceURL: fingerprint.SourceURL,
+			CreatedAt: fingerprint.CreatedAt,
+		})
+	}
+
+	return clusters, nil
+}
+
+// checkDuplicates returns a DuplicateError if the recipe is likely a duplicate of existing
+// recipes the viewer of the context can see, unless duplicates are allowed for the context
+func (s *RecipeService) checkDuplicates(ctx context.Context, recipe *models.Recipe) error {
+	if DuplicatesAllowed(ctx) {
+		return nil
+	}
+
+	return s.checkFingerprintDuplicates(ctx, models.NewRecipeFingerprint(recipe))
+}
+
+// checkSourceDuplicates returns a DuplicateError if recipes imported from the URL exist, unless
+// duplicates are allowed for the context. It is checked before the URL is fetched and analyzed.
+func (s *RecipeService) checkSourceDuplicates(ctx context.Context, url string) error {
+	if DuplicatesAllowed(ctx) {
+		return nil
+	}
+
+	return s.checkFingerprintDuplicates(ctx, models.RecipeFingerprint{SourceURL: url})
+}
+
+func (s *RecipeService) checkFingerprintDuplicates(ctx context.Context, fingerprint models.RecipeFingerprint) error {
+	// Recipes without keys, e.g. with a title of only common words, have no candidates
+	keys := duplicateKeys(fingerprint)
+	if len(keys) == 0 {
+		return nil
+	}
+
+	viewer := viewerFromContext(ctx)
+	fingerprints, err := s.storage.GetRecipeFingerprintsByKeys(ctx, &viewer, keys)
+	if err != nil {
+		return fmt.Errorf("failed to check for duplicates: %w", err)
+	}
+
+	candidates := findDuplicates(fingerprint, fingerprints)
+	if len(candidates) > 0 {
+		return &DuplicateError{Candidates: candidates}
+	}
+	return nil
+}
+
+// duplicateKeys returns the keys that the likely duplicates of a recipe share with it: its
+// normalized source URL, and the distinctive words of its title, since similar titles have a
+// word in common
+func duplicateKeys(fingerprint models.RecipeFingerprint) []string {
+	return newDuplicateFeatures(fingerprint).keys()
+}
+
+// findDuplicates returns the existing recipes that are likely duplicates of the recipe
+func findDuplicates(recipe models.RecipeFingerprint, existing []models.RecipeFingerprint) []models.DuplicateCandidate {
+	features := newDuplicateFeatures(recipe)
+
+	candidates := []models.DuplicateCandidate{}
+	for _, fingerprint := range existing {
+		reason, similarity, ok := compareDuplicates(features, newDuplicateFeatures(fingerprint))
+		if !ok {
+			continue
+		}
+
+		candidates = append(candidates, models.DuplicateCandidate{
+			ID:         fingerprint.ID,
+			Title:      fingerprint.Title,
+			Reason:     reason,
+			Similarity: similarity,
+		})
+	}
+
+	return candidates
+}
+
+// duplicateFeatures is the normalized form of a fingerprint that is compared
+type duplicateFeatures struct {
+	sourceURL   string
+	title       []string
+	ingredients [][]string
+}
+
+func (f duplicateFeatures) keys() []string {
+	keys := []string{}
+	if f.sourceURL != "" {
+		keys = append(keys, _DuplicateKeySourceURL+f.sourceURL)
+	}
+	seen := make(map[string]bool, len(f.title))
+	for _, word := range f.title {
+		if seen[word] || titleStopwords[word] || utf8.RuneCountInString(word) < _MinTitleKeyLength {
+			continue
+		}
+		seen[word] = true
+		keys = append(keys, _DuplicateKeyTitle+word)
+	}
+	return keys
+}
+
+func newDuplicateFeatures(fingerprint models.RecipeFingerprint) duplicateFeatures {
+	features := duplicateFeatures{
+		title:       words(fingerprint.Title),
+		ingredients: make([][]string, 0, len(fingerprint.IngredientNames)),
+	}
+	if fingerprint.SourceURL != "" {
+		features.sourceURL = fetch.NormalizeURL(fingerprint.SourceURL)
+	}
+	for _, name := range fingerprint.IngredientNames {
+		if nameWords := words(name); len(nameWords) > 0 {
+			features.ingredients = append(features.ingredients, nameWords)
+		}
+	}
+	return features
+}
+
+// compareDuplicates reports whether the recipes are likely duplicates: they are imported from
+// the same URL, or both their titles and ingredients are similar
+func compareDuplicates(a duplicateFeatures, b duplicateFeatures) (string, float64, bool) {
+	if a.sourceURL != "" && a.sourceURL == b.sourceURL {
+		return models.DuplicateSourceURL, 1, true
+	}
+
+	title := wordSimilarity(a.title, b.title)
+	if title < _DuplicateTitleSimilarity {
+		return "", 0, false
+	}
+	ingredients := ingredientSimilarity(a.ingredients, b.ingredients)
+	if ingredients < _DuplicateIngredientSimilarity {
+		return "", 0, false
+	}
+
+	similarity := (title + ingredients) / 2
+	if similarity < _DuplicateSimilarity {
+		return "", 0, false
+	}
+	return models.DuplicateSimilar, similarity, true
+}
+
+// ingredientSimilarity is the Dice coefficient of the ingredient sets, where ingredients with
+// similar names (e.g. "flour" and "wheat flour") are the same
+func ingredientSimilarity(a [][]string, b [][]string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	matched := make([]bool, len(b))
+	common := 0
+	for _, nameA := range a {
+		best, bestSimilarity := -1, _SameIngredientSimilarity
+		for i, nameB := range b {
+			if similarity := wordSimilarity(nameA, nameB); !matched[i] && similarity >= bestSimilarity {
+				best, bestSimilarity = i, similarity
+			}
+		}
+		if best >= 0 {
+			matched[best] = true
+			common++
+		}
+	}
+
+	return 2 * float64(common) / float64(len(a)+len(b))
+}
+
+// wordSimilarity is the Dice coefficient of the words
+func wordSimilarity(a []string, b []string) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	counts := make(map[string]int, len(a))
+	for _, word := range a {
+		counts[word]++
+	}
+	common := 0
+	for _, word := range b {
+		if counts[word] > 0 {
+			counts[word]--
+			common++
+		}
+	}
+
+	return 2 * float64(common) / float64(len(a)+len(b))
+}
+
+// words splits the text into lowercase words, ignoring punctuation and a plural s (e.g. "eggs"
+// and "egg" are the same word)
+func words(s string) []string {
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for i, word := range words {
+		if len(word) > 3 && strings.HasSuffix(word, "s") && !strings.HasSuffix(word, "ss") {
+			words[i] = strings.TrimSuffix(word, "s")
+		}
+	}
+	return words
+}
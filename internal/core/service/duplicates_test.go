@@ -0,0 +1,195 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/ai"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCreateRecipeDuplicates(t *testing.T) {
+	ctx := userContext(testUser)
+	existing := []models.RecipeFingerprint{
+		{ID: primitive.NewObjectID(), Title: "Swedish Pancakes", IngredientNames: []string{"Flour", "Milk", "Eggs", "Butter"}},
+		{ID: primitive.NewObjectID(), Title: "Tomato Soup", IngredientNames: []string{"Tomatoes", "Onion"}, SourceURL: "https://example.com/soup?utm_source=news"},
+	}
+	newRecipe := func(title string, source *models.RecipeSource, ingredients ...string) *models.Recipe {
+		recipe := &models.Recipe{Title: title, Steps: []string{"Cook"}, Source: source}
+		for _, name := range ingredients {
+			recipe.Ingredients = append(recipe.Ingredients, models.Ingredient{Name: name, Quantity: 1})
+		}
+		return recipe
+	}
+
+	t.Run("Similar", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+
+		// Only the recipes that share a title word are compared
+		mockStorage.On("GetRecipeFingerprintsByKeys", ctx, mock.Anything, []string{"title:pancake", "title:swedish"}).Return(existing, nil).Once()
+
+		// Word order, case and similar ingredient names do not matter
+		_, err := recipeService.CreateRecipe(ctx, newRecipe("pancakes, swedish", nil, "wheat flour", "whole milk", "eggs", "butter"))
+
+		var duplicateErr *DuplicateError
+		assert.ErrorIs(t, err, ErrDuplicate)
+		if assert.ErrorAs(t, err, &duplicateErr) && assert.Len(t, duplicateErr.Candidates, 1) {
+			assert.Equal(t, existing[0].ID, duplicateErr.Candidates[0].ID)
+			assert.Equal(t, models.DuplicateSimilar, duplicateErr.Candidates[0].Reason)
+			assert.InDelta(t, 0.9, duplicateErr.Candidates[0].Similarity, 0.1)
+		}
+		mockStorage.AssertNotCalled(t, "CreateRecipe", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Source URL", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+
+		mockStorage.On("GetRecipeFingerprintsByKeys", ctx, mock.Anything, mock.Anything).Return(existing, nil).Once()
+
+		_, err := recipeService.CreateRecipe(ctx, newRecipe("Grandma's soup", &models.RecipeSource{Type: models.SourceFile, URL: "https://EXAMPLE.com/soup#comments"}, "Water"))
+
+		var duplicateErr *DuplicateError
+		if assert.ErrorAs(t, err, &duplicateErr) && assert.Len(t, duplicateErr.Candidates, 1) {
+			assert.Equal(t, existing[1].ID, duplicateErr.Candidates[0].ID)
+			assert.Equal(t, models.DuplicateSourceURL, duplicateErr.Candidates[0].Reason)
+			assert.Equal(t, 1.0, duplicateErr.Candidates[0].Similarity)
+		}
+	})
+
+	t.Run("Not a duplicate", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+
+		// The same title with other ingredients is another recipe
+		recipe := newRecipe("Swedish Pancakes", nil, "Buckwheat flour", "Oat milk", "Banana")
+		mockStorage.On("GetRecipeFingerprintsByKeys", ctx, mock.Anything, mock.Anything).Return(existing, nil).Once()
+		mockStorage.On("CreateRecipe", ctx, recipe).Return(recipe, nil).Once()
+
+		_, err := recipeService.CreateRecipe(ctx, recipe)
+
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"title:swedish", "title:pancake"}, recipe.DuplicateKeys)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Common words are not keys", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+
+		// Short words and stopwords would select unrelated recipes as candidates
+		recipe := newRecipe("Soup with a twist and the best bread", nil, "Water")
+		mockStorage.On("GetRecipeFingerprintsByKeys", ctx, mock.Anything, []string{"title:soup", "title:twist", "title:best", "title:bread"}).Return([]models.RecipeFingerprint{}, nil).Once()
+		mockStorage.On("CreateRecipe", ctx, recipe).Return(recipe, nil).Once()
+
+		_, err := recipeService.CreateRecipe(ctx, recipe)
+		assert.NoError(t, err)
+
+		// A title of only common words selects no candidates
+		recipe = newRecipe("And so on", nil, "Water")
+		mockStorage.On("CreateRecipe", ctx, recipe).Return(recipe, nil).Once()
+
+		_, err = recipeService.CreateRecipe(ctx, recipe)
+		assert.NoError(t, err)
+		assert.Empty(t, recipe.DuplicateKeys)
+		mockStorage.AssertExpectations(t)
+		mockStorage.AssertNumberOfCalls(t, "GetRecipeFingerprintsByKeys", 1)
+	})
+
+	t.Run("Allowed", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+
+		ctx := AllowDuplicates(ctx)
+		recipe := newRecipe("Swedish Pancakes", nil, "Flour", "Milk", "Eggs", "Butter")
+		mockStorage.On("CreateRecipe", ctx, recipe).Return(recipe, nil).Once()
+
+		_, err := recipeService.CreateRecipe(ctx, recipe)
+
+		assert.NoError(t, err)
+		mockStorage.AssertNotCalled(t, "GetRecipeFingerprintsByKeys", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("AI import", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, nil)
+
+		image := "/9j/4AAQSkZJRgABAQEASABIAAD/2Q=="
+		mockAI.On("AnalyzeRecipeImage", ctx, image, ai.ImageContentTypeJPEG).Return(&ai.RecipeAnalysisResult{
+			Title:       "Swedish pancakes",
+			Ingredients: []models.Ingredient{{Name: "Flour"}, {Name: "Milk"}, {Name: "Eggs"}, {Name: "Butter"}, {Name: "Salt"}},
+			Steps:       []string{"Whisk", "Fry"},
+		}, nil).Once()
+		mockStorage.On("GetRecipeFingerprintsByKeys", ctx, mock.Anything, mock.Anything).Return(existing, nil).Once()
+
+		_, err := recipeService.CreateRecipeFromImage(ctx, image, "jpeg")
+
+		// Duplicates are not tagged
+		assert.ErrorIs(t, err, ErrDuplicate)
+		mockAI.AssertNotCalled(t, "SuggestRecipeTags", mock.Anything, mock.Anything, mock.Anything)
+		mockStorage.AssertNotCalled(t, "CreateRecipe", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Storage error", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+
+		mockStorage.On("GetRecipeFingerprintsByKeys", ctx, mock.Anything, mock.Anything).Return(nil, errors.New("database down")).Once()
+
+		_, err := recipeService.CreateRecipe(ctx, newRecipe("Swedish Pancakes", nil, "Flour"))
+
+		assert.Error(t, err)
+		assert.NotErrorIs(t, err, ErrDuplicate)
+	})
+}
+
+func TestGetDuplicateRecipes(t *testing.T) {
+	ctx := userContext(testUser)
+	created := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	fingerprint := func(title string, sourceURL string, ingredients ...string) models.RecipeFingerprint {
+		created = created.Add(time.Hour)
+		return models.RecipeFingerprint{ID: primitive.NewObjectID(), Title: title, IngredientNames: ingredients, SourceURL: sourceURL, CreatedAt: created}
+	}
+
+	fingerprints := []models.RecipeFingerprint{
+		fingerprint("Tomato Soup", "https://example.com/soup", "Tomatoes", "Onion"),
+		fingerprint("Pancakes", "", "Flour", "Milk", "Eggs"),
+		fingerprint("Roasted tomato soup", "https://example.com/soup?utm_source=news", "Tomatoes", "Garlic"),
+		fingerprint("Kladdkaka", "", "Sugar", "Butter", "Cocoa"),
+		fingerprint("Pancakes with milk", "", "Flour", "Milk", "Egg"),
+		fingerprint("Classic pancakes", "", "Wheat flour", "Milk", "Eggs", "Butter"),
+	}
+
+	mockStorage := new(MockStorage)
+	recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+	// Only the recipes the user can see are compared
+	mockStorage.On("GetRecipeFingerprints", ctx, &models.RecipeViewer{UserID: testUser.ID}).Return(fingerprints, nil).Once()
+
+	clusters, err := recipeService.GetDuplicateRecipes(ctx)
+
+	assert.NoError(t, err)
+	if !assert.Len(t, clusters, 2) {
+		return
+	}
+
+	// Clusters and their recipes are in creation order
+	assert.Equal(t, models.DuplicateSourceURL, clusters[0].Reason)
+	assert.Equal(t, []primitive.ObjectID{fingerprints[0].ID, fingerprints[2].ID}, clusterIDs(clusters[0]))
+	assert.Equal(t, models.DuplicateSimilar, clusters[1].Reason)
+	assert.Equal(t, []primitive.ObjectID{fingerprints[1].ID, fingerprints[4].ID, fingerprints[5].ID}, clusterIDs(clusters[1]))
+	assert.Equal(t, fingerprints[1].CreatedAt, clusters[1].Recipes[0].CreatedAt)
+}
+
+func clusterIDs(cluster models.DuplicateCluster) []primitive.ObjectID {
+	ids := []primitive.ObjectID{}
+	for _, recipe := range cluster.Recipes {
+		ids = append(ids, recipe.ID)
+	}
+	return ids
+}
@@ -7,4 +7,6 @@ var (
 	ErrInvalidInput  = errors.New("invalid input")
 	ErrAIUnsupported = errors.New("AI is not supported")
 	ErrAI            = errors.New("AI error")
+	ErrDuplicate     = errors.New("duplicate recipe")
+	ErrForbidden     = errors.New("forbidden")
 )
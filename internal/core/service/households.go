@@ -0,0 +1,322 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/auth"
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+)
+
+const (
+	// Prefix of household invite tokens
+	InviteTokenPrefix = "hhi_"
+	// How long an invite is valid without another expiry
+	_InviteDefaultTTL = 7 * 24 * time.Hour
+	// Maximum length of the name of a household
+	_HouseholdMaxNameLength = 100
+)
+
+// Households manages households, their members and invites for the user of the context
+type Households interface {
+	CreateHousehold(ctx context.Context, name string) (*models.Household, error)
+	GetHouseholds(ctx context.Context) ([]models.Household, error)
+	GetHousehold(ctx context.Context, id string) (*models.Household, error)
+	CreateInvite(ctx context.Context, id string, role string, expiresAt *time.Time) (*models.CreatedHouseholdInvite, error)
+	AcceptInvite(ctx context.Context, token string) (*models.Household, error)
+	UpdateMember(ctx context.Context, id string, userID string, role string) (*models.Household, error)
+	RemoveMember(ctx context.Context, id string, userID string) error
+	// GetRole returns the role of the user in the household, which is selected as the active
+	// household of a request. Admins that are not members have an empty role.
+	GetRole(ctx context.Context, id string, user *models.User) (*models.Household, string, error)
+}
+
+type HouseholdService struct {
+	storage storage.HouseholdStorage
+}
+
+func NewHouseholdService(storage storage.HouseholdStorage) *HouseholdService {
+	return &HouseholdService{storage: storage}
+}
+
+// CreateHousehold creates a household with the user of the context as its owner
+func (s *HouseholdService) CreateHousehold(ctx context.Context, name string) (*models.Household, error) {
+	user, err := householdUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return nil, fmt.Errorf("%w: name is required", ErrValidation)
+	}
+	if len(name) > _HouseholdMaxNameLength {
+		return nil, fmt.Errorf("%w: name must be at most %d characters", ErrValidation, _HouseholdMaxNameLength)
+	}
+
+	now := time.Now()
+	household, err := s.storage.CreateHousehold(ctx, &models.Household{
+		Name:      name,
+		Members:   []models.HouseholdMember{{UserID: user.ID, Role: models.HouseholdRoleOwner, JoinedAt: now}},
+		CreatedAt: now,
+		UpdatedAt: now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create household: %w", err)
+	}
+
+	return household, nil
+}
+
+// GetHouseholds returns the households the user of the context is a member of
+func (s *HouseholdService) GetHouseholds(ctx context.Context) ([]models.Household, error) {
+	user, err := householdUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	households, err := s.storage.GetHouseholds(ctx, user.ID.Hex())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get households: %w", err)
+	}
+
+	return households, nil
+}
+
+// GetHousehold returns a household of the user of the context. Households the user is not a
+// member of are not found, unless the user is an admin.
+func (s *HouseholdService) GetHousehold(ctx context.Context, id string) (*models.Household, error) {
+	user, err := householdUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	household, _, err := s.GetRole(ctx, id, user)
+	if errors.Is(err, ErrForbidden) {
+		return nil, errHouseholdNotFound(id)
+	}
+	return household, err
+}
+
+// CreateInvite creates an invite to join the household with the role, which expires in 7 days
+// if expiresAt is nil. Only owners can invite. The token is only returned here, only its hash is
+// stored.
+func (s *HouseholdService) CreateInvite(ctx context.Context, id string, role string, expiresAt *time.Time) (*models.CreatedHouseholdInvite, error) {
+	household, user, err := s.getOwnedHousehold(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateHouseholdRole(role); err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if expiresAt == nil {
+		expiry := now.Add(_InviteDefaultTTL)
+		expiresAt = &expiry
+	}
+	if !expiresAt.After(now) {
+		return nil, fmt.Errorf("%w: expiry must be in the future", ErrValidation)
+	}
+
+	token, err := newToken(InviteTokenPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate invite token: %w", err)
+	}
+
+	invite, err := s.storage.CreateHouseholdInvite(ctx, &models.HouseholdInvite{
+		HouseholdID: household.ID,
+		TokenHash:   hashToken(token),
+		Role:        role,
+		CreatedBy:   user.ID,
+		ExpiresAt:   *expiresAt,
+		CreatedAt:   now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create invite: %w", err)
+	}
+
+	return &models.CreatedHouseholdInvite{HouseholdInvite: *invite, Token: token}, nil
+}
+
+// AcceptInvite makes the user of the context a member of the household of the invite, with the
+// role of the invite. Members that accept an invite of their household stay as they are, and the
+// invite is left for someone else.
+func (s *HouseholdService) AcceptInvite(ctx context.Context, token string) (*models.Household, error) {
+	user, err := householdUser(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token = strings.TrimSpace(token)
+	if !strings.HasPrefix(token, InviteTokenPrefix) {
+		return nil, fmt.Errorf("%w: household invite", storage.ErrNotFound)
+	}
+	tokenHash := hashToken(token)
+
+	invite, err := s.storage.GetHouseholdInviteByHash(ctx, tokenHash)
+	if err != nil {
+		return nil, err
+	}
+	household, err := s.storage.GetHouseholdByID(ctx, invite.HouseholdID.Hex())
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := household.Member(user.ID); ok {
+		return household, nil
+	}
+
+	invite, err = s.storage.AcceptHouseholdInvite(ctx, tokenHash, user.ID.Hex(), time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to accept invite: %w", err)
+	}
+
+	err = s.storage.AddHouseholdMember(ctx, invite.HouseholdID.Hex(), models.HouseholdMember{
+		UserID:   user.ID,
+		Role:     invite.Role,
+		JoinedAt: *invite.AcceptedAt,
+	})
+	if err != nil {
+		// Give the invite back, it was not used to join
+		if releaseErr := s.storage.ReleaseHouseholdInvite(ctx, invite.ID.Hex(), user.ID.Hex()); releaseErr != nil {
+			slog.Error("Unable to release household invite", "invite", invite.ID.Hex(), "error", releaseErr.Error())
+		}
+		if !errors.Is(err, storage.ErrAlreadyExists) {
+			return nil, fmt.Errorf("failed to join household: %w", err)
+		}
+		// Joined in the meantime, with another invite
+	}
+
+	return s.storage.GetHouseholdByID(ctx, invite.HouseholdID.Hex())
+}
+
+// UpdateMember changes the role of a member. Only owners can change roles, and the last owner
+// cannot be demoted.
+func (s *HouseholdService) UpdateMember(ctx context.Context, id string, userID string, role string) (*models.Household, error) {
+	household, _, err := s.getOwnedHousehold(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := validateHouseholdRole(role); err != nil {
+		return nil, err
+	}
+	member, err := householdMember(household, userID)
+	if err != nil {
+		return nil, err
+	}
+	if member.Role == models.HouseholdRoleOwner && role != models.HouseholdRoleOwner && household.Owners() == 1 {
+		return nil, fmt.Errorf("%w: the last owner of a household cannot be demoted", ErrValidation)
+	}
+
+	if err := s.storage.SetHouseholdMemberRole(ctx, id, userID, role); err != nil {
+		return nil, fmt.Errorf("failed to update member: %w", err)
+	}
+
+	return s.storage.GetHouseholdByID(ctx, id)
+}
+
+// RemoveMember removes a member from the household. Owners can remove any member and members can
+// leave, but the last owner cannot leave. The recipes of the member stay in the household.
+func (s *HouseholdService) RemoveMember(ctx context.Context, id string, userID string) error {
+	user, err := householdUser(ctx)
+	if err != nil {
+		return err
+	}
+
+	var household *models.Household
+	if userID == user.ID.Hex() {
+		household, err = s.GetHousehold(ctx, id)
+	} else {
+		household, _, err = s.getOwnedHousehold(ctx, id)
+	}
+	if err != nil {
+		return err
+	}
+
+	member, err := householdMember(household, userID)
+	if err != nil {
+		return err
+	}
+	if member.Role == models.HouseholdRoleOwner && household.Owners() == 1 {
+		return fmt.Errorf("%w: the last owner of a household cannot leave it", ErrValidation)
+	}
+
+	if err := s.storage.RemoveHouseholdMember(ctx, id, userID); err != nil {
+		return fmt.Errorf("failed to remove member: %w", err)
+	}
+
+	return nil
+}
+
+func (s *HouseholdService) GetRole(ctx context.Context, id string, user *models.User) (*models.Household, string, error) {
+	household, err := s.storage.GetHouseholdByID(ctx, id)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if member, ok := household.Member(user.ID); ok {
+		return household, member.Role, nil
+	}
+	if user.Admin {
+		return household, "", nil
+	}
+	return nil, "", fmt.Errorf("%w: not a member of the household", ErrForbidden)
+}
+
+// getOwnedHousehold returns the household if the user of the context is one of its owners, or an
+// admin
+func (s *HouseholdService) getOwnedHousehold(ctx context.Context, id string) (*models.Household, *models.User, error) {
+	user, err := householdUser(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	household, err := s.GetHousehold(ctx, id)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	member, ok := household.Member(user.ID)
+	if !user.Admin && (!ok || member.Role != models.HouseholdRoleOwner) {
+		return nil, nil, fmt.Errorf("%w: only owners can manage the household", ErrForbidden)
+	}
+
+	return household, user, nil
+}
+
+// householdUser returns the authenticated user of the context, households are not available
+// anonymously
+func householdUser(ctx context.Context) (*models.User, error) {
+	user, ok := auth.UserFromContext(ctx)
+	if !ok {
+		return nil, fmt.Errorf("%w: households are only available to users", ErrForbidden)
+	}
+	return user, nil
+}
+
+func householdMember(household *models.Household, userID string) (*models.HouseholdMember, error) {
+	for i := range household.Members {
+		if household.Members[i].UserID.Hex() == userID {
+			return &household.Members[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: household member %s in household %s", storage.ErrNotFound, userID, household.ID.Hex())
+}
+
+func errHouseholdNotFound(id string) error {
+	return fmt.Errorf("%w: household with ID %s", storage.ErrNotFound, id)
+}
+
+func validateHouseholdRole(role string) error {
+	if !slices.Contains(models.HouseholdRoles, role) {
+		return fmt.Errorf("%w: role must be one of %v", ErrValidation, models.HouseholdRoles)
+	}
+	return nil
+}
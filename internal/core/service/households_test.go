@@ -0,0 +1,420 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// MockHouseholdStorage is a mock implementation of the storage.HouseholdStorage interface
+type MockHouseholdStorage struct {
+	mock.Mock
+}
+
+func (m *MockHouseholdStorage) CreateHousehold(ctx context.Context, household *models.Household) (*models.Household, error) {
+	args := m.Called(ctx, household)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Household), args.Error(1)
+}
+
+func (m *MockHouseholdStorage) GetHouseholdByID(ctx context.Context, id string) (*models.Household, error) {
+	args := m.Called(ctx, id)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Household), args.Error(1)
+}
+
+func (m *MockHouseholdStorage) GetHouseholds(ctx context.Context, userID string) ([]models.Household, error) {
+	args := m.Called(ctx, userID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Household), args.Error(1)
+}
+
+func (m *MockHouseholdStorage) AddHouseholdMember(ctx context.Context, id string, member models.HouseholdMember) error {
+	args := m.Called(ctx, id, member)
+	return args.Error(0)
+}
+
+func (m *MockHouseholdStorage) SetHouseholdMemberRole(ctx context.Context, id string, userID string, role string) error {
+	args := m.Called(ctx, id, userID, role)
+	return args.Error(0)
+}
+
+func (m *MockHouseholdStorage) RemoveHouseholdMember(ctx context.Context, id string, userID string) error {
+	args := m.Called(ctx, id, userID)
+	return args.Error(0)
+}
+
+func (m *MockHouseholdStorage) CreateHouseholdInvite(ctx context.Context, invite *models.HouseholdInvite) (*models.HouseholdInvite, error) {
+	args := m.Called(ctx, invite)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.HouseholdInvite), args.Error(1)
+}
+
+func (m *MockHouseholdStorage) AcceptHouseholdInvite(ctx context.Context, tokenHash string, userID string, now time.Time) (*models.HouseholdInvite, error) {
+	args := m.Called(ctx, tokenHash, userID, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.HouseholdInvite), args.Error(1)
+}
+
+func (m *MockHouseholdStorage) GetHouseholdInviteByHash(ctx context.Context, tokenHash string) (*models.HouseholdInvite, error) {
+	args := m.Called(ctx, tokenHash)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.HouseholdInvite), args.Error(1)
+}
+
+func (m *MockHouseholdStorage) ReleaseHouseholdInvite(ctx context.Context, id string, userID string) error {
+	args := m.Called(ctx, id, userID)
+	return args.Error(0)
+}
+
+// testHousehold returns a household with testUser as owner and the other user as editor
+func testHousehold(other *models.User) *models.Household {
+	return &models.Household{
+		ID:   primitive.NewObjectID(),
+		Name: "The Smiths",
+		Members: []models.HouseholdMember{
+			{UserID: testUser.ID, Role: models.HouseholdRoleOwner},
+			{UserID: other.ID, Role: models.HouseholdRoleEditor},
+		},
+	}
+}
+
+func TestCreateHousehold(t *testing.T) {
+	ctx := userContext(testUser)
+
+	t.Run("Success", func(t *testing.T) {
+		mockStorage := new(MockHouseholdStorage)
+		households := NewHouseholdService(mockStorage)
+
+		mockStorage.On("CreateHousehold", ctx, mock.MatchedBy(func(h *models.Household) bool {
+			return h.Name == "The Smiths" &&
+				len(h.Members) == 1 && h.Members[0].UserID == testUser.ID && h.Members[0].Role == models.HouseholdRoleOwner
+		})).Return(&models.Household{Name: "The Smiths"}, nil).Once()
+
+		household, err := households.CreateHousehold(ctx, " The Smiths ")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "The Smiths", household.Name)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Validation errors", func(t *testing.T) {
+		households := NewHouseholdService(new(MockHouseholdStorage))
+
+		_, err := households.CreateHousehold(ctx, " ")
+		assert.ErrorIs(t, err, ErrValidation)
+
+		_, err = households.CreateHousehold(ctx, string(make([]byte, 101)))
+		assert.ErrorIs(t, err, ErrValidation)
+	})
+
+	t.Run("Anonymous", func(t *testing.T) {
+		households := NewHouseholdService(new(MockHouseholdStorage))
+
+		_, err := households.CreateHousehold(context.Background(), "The Smiths")
+
+		assert.ErrorIs(t, err, ErrForbidden)
+	})
+}
+
+func TestGetHousehold(t *testing.T) {
+	member := &models.User{ID: primitive.NewObjectID(), Username: "zoe"}
+	household := testHousehold(member)
+	id := household.ID.Hex()
+
+	t.Run("Member", func(t *testing.T) {
+		mockStorage := new(MockHouseholdStorage)
+		households := NewHouseholdService(mockStorage)
+		ctx := userContext(member)
+		mockStorage.On("GetHouseholdByID", ctx, id).Return(household, nil).Once()
+
+		result, err := households.GetHousehold(ctx, id)
+
+		assert.NoError(t, err)
+		assert.Equal(t, household, result)
+	})
+
+	t.Run("Not a member", func(t *testing.T) {
+		mockStorage := new(MockHouseholdStorage)
+		households := NewHouseholdService(mockStorage)
+		ctx := userContext(&models.User{ID: primitive.NewObjectID(), Username: "eve"})
+		mockStorage.On("GetHouseholdByID", ctx, id).Return(household, nil).Once()
+
+		_, err := households.GetHousehold(ctx, id)
+
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("Role of an admin that is not a member", func(t *testing.T) {
+		mockStorage := new(MockHouseholdStorage)
+		households := NewHouseholdService(mockStorage)
+		admin := &models.User{ID: primitive.NewObjectID(), Username: "root", Admin: true}
+		mockStorage.On("GetHouseholdByID", mock.Anything, id).Return(household, nil).Once()
+
+		result, role, err := households.GetRole(context.Background(), id, admin)
+
+		assert.NoError(t, err)
+		assert.Equal(t, household, result)
+		assert.Empty(t, role)
+	})
+}
+
+func TestHouseholdInvites(t *testing.T) {
+	member := &models.User{ID: primitive.NewObjectID(), Username: "zoe"}
+	household := testHousehold(member)
+	id := household.ID.Hex()
+
+	t.Run("Create", func(t *testing.T) {
+		mockStorage := new(MockHouseholdStorage)
+		households := NewHouseholdService(mockStorage)
+		ctx := userContext(testUser)
+
+		var stored *models.HouseholdInvite
+		mockStorage.On("GetHouseholdByID", ctx, id).Return(household, nil).Once()
+		mockStorage.On("CreateHouseholdInvite", ctx, mock.AnythingOfType("*models.HouseholdInvite")).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*models.HouseholdInvite)
+		}).Return(&models.HouseholdInvite{HouseholdID: household.ID, Role: models.HouseholdRoleViewer}, nil).Once()
+
+		invite, err := households.CreateInvite(ctx, id, models.HouseholdRoleViewer, nil)
+
+		assert.NoError(t, err)
+		assert.True(t, len(invite.Token) > len(InviteTokenPrefix))
+		// Only the hash of the token is stored, the invite expires in a week
+		assert.Equal(t, hashToken(invite.Token), stored.TokenHash)
+		assert.Equal(t, household.ID, stored.HouseholdID)
+		assert.Equal(t, testUser.ID, stored.CreatedBy)
+		assert.WithinDuration(t, time.Now().Add(7*24*time.Hour), stored.ExpiresAt, time.Minute)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Only owners invite", func(t *testing.T) {
+		mockStorage := new(MockHouseholdStorage)
+		households := NewHouseholdService(mockStorage)
+		ctx := userContext(member)
+		mockStorage.On("GetHouseholdByID", ctx, id).Return(household, nil).Once()
+
+		_, err := households.CreateInvite(ctx, id, models.HouseholdRoleViewer, nil)
+
+		assert.ErrorIs(t, err, ErrForbidden)
+		mockStorage.AssertNotCalled(t, "CreateHouseholdInvite", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid role or expiry", func(t *testing.T) {
+		mockStorage := new(MockHouseholdStorage)
+		households := NewHouseholdService(mockStorage)
+		ctx := userContext(testUser)
+		mockStorage.On("GetHouseholdByID", ctx, id).Return(household, nil).Twice()
+
+		_, err := households.CreateInvite(ctx, id, "cook", nil)
+		assert.ErrorIs(t, err, ErrValidation)
+
+		past := time.Now().Add(-time.Hour)
+		_, err = households.CreateInvite(ctx, id, models.HouseholdRoleViewer, &past)
+		assert.ErrorIs(t, err, ErrValidation)
+	})
+
+	t.Run("Accept", func(t *testing.T) {
+		mockStorage := new(MockHouseholdStorage)
+		households := NewHouseholdService(mockStorage)
+		newcomer := &models.User{ID: primitive.NewObjectID(), Username: "max"}
+		ctx := userContext(newcomer)
+		token := InviteTokenPrefix + "secret"
+		acceptedAt := time.Now()
+
+		mockStorage.On("GetHouseholdInviteByHash", ctx, hashToken(token)).Return(&models.HouseholdInvite{HouseholdID: household.ID}, nil).Once()
+		mockStorage.On("GetHouseholdByID", ctx, id).Return(household, nil).Twice()
+		mockStorage.On("AcceptHouseholdInvite", ctx, hashToken(token), newcomer.ID.Hex(), mock.AnythingOfType("time.Time")).
+			Return(&models.HouseholdInvite{HouseholdID: household.ID, Role: models.HouseholdRoleViewer, AcceptedAt: &acceptedAt}, nil).Once()
+		mockStorage.On("AddHouseholdMember", ctx, id, models.HouseholdMember{UserID: newcomer.ID, Role: models.HouseholdRoleViewer, JoinedAt: acceptedAt}).Return(nil).Once()
+
+		result, err := households.AcceptInvite(ctx, token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, household, result)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Accept as a member", func(t *testing.T) {
+		mockStorage := new(MockHouseholdStorage)
+		households := NewHouseholdService(mockStorage)
+		ctx := userContext(member)
+		token := InviteTokenPrefix + "secret"
+
+		// Also an invite the member accepted before, when accepting again after a lost response
+		mockStorage.On("GetHouseholdInviteByHash", ctx, hashToken(token)).Return(&models.HouseholdInvite{HouseholdID: household.ID, AcceptedBy: &member.ID}, nil).Once()
+		mockStorage.On("GetHouseholdByID", ctx, id).Return(household, nil).Once()
+
+		result, err := households.AcceptInvite(ctx, token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, household, result)
+		mockStorage.AssertNotCalled(t, "AcceptHouseholdInvite", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Failing to join releases the invite", func(t *testing.T) {
+		mockStorage := new(MockHouseholdStorage)
+		households := NewHouseholdService(mockStorage)
+		newcomer := &models.User{ID: primitive.NewObjectID(), Username: "max"}
+		ctx := userContext(newcomer)
+		token := InviteTokenPrefix + "secret"
+		acceptedAt := time.Now()
+		invite := &models.HouseholdInvite{ID: primitive.NewObjectID(), HouseholdID: household.ID, Role: models.HouseholdRoleViewer, AcceptedAt: &acceptedAt}
+
+		mockStorage.On("GetHouseholdInviteByHash", ctx, hashToken(token)).Return(invite, nil).Once()
+		mockStorage.On("GetHouseholdByID", ctx, id).Return(household, nil).Once()
+		mockStorage.On("AcceptHouseholdInvite", ctx, hashToken(token), newcomer.ID.Hex(), mock.Anything).Return(invite, nil).Once()
+		mockStorage.On("AddHouseholdMember", ctx, id, mock.Anything).Return(storage.ErrDatabaseError).Once()
+		mockStorage.On("ReleaseHouseholdInvite", ctx, invite.ID.Hex(), newcomer.ID.Hex()).Return(nil).Once()
+
+		_, err := households.AcceptInvite(ctx, token)
+
+		assert.ErrorIs(t, err, storage.ErrDatabaseError)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Joined with another invite in the meantime", func(t *testing.T) {
+		mockStorage := new(MockHouseholdStorage)
+		households := NewHouseholdService(mockStorage)
+		newcomer := &models.User{ID: primitive.NewObjectID(), Username: "max"}
+		ctx := userContext(newcomer)
+		token := InviteTokenPrefix + "secret"
+		acceptedAt := time.Now()
+		invite := &models.HouseholdInvite{ID: primitive.NewObjectID(), HouseholdID: household.ID, Role: models.HouseholdRoleViewer, AcceptedAt: &acceptedAt}
+
+		mockStorage.On("GetHouseholdInviteByHash", ctx, hashToken(token)).Return(invite, nil).Once()
+		mockStorage.On("GetHouseholdByID", ctx, id).Return(household, nil).Twice()
+		mockStorage.On("AcceptHouseholdInvite", ctx, hashToken(token), newcomer.ID.Hex(), mock.Anything).Return(invite, nil).Once()
+		mockStorage.On("AddHouseholdMember", ctx, id, mock.Anything).Return(storage.ErrAlreadyExists).Once()
+		mockStorage.On("ReleaseHouseholdInvite", ctx, invite.ID.Hex(), newcomer.ID.Hex()).Return(nil).Once()
+
+		result, err := households.AcceptInvite(ctx, token)
+
+		assert.NoError(t, err)
+		assert.Equal(t, household, result)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Accept unknown invite", func(t *testing.T) {
+		mockStorage := new(MockHouseholdStorage)
+		households := NewHouseholdService(mockStorage)
+		newcomer := &models.User{ID: primitive.NewObjectID(), Username: "max"}
+		ctx := userContext(newcomer)
+
+		_, err := households.AcceptInvite(ctx, "not-an-invite")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+
+		mockStorage.On("GetHouseholdInviteByHash", ctx, hashToken(InviteTokenPrefix+"unknown")).Return(nil, storage.ErrNotFound).Once()
+		_, err = households.AcceptInvite(ctx, InviteTokenPrefix+"unknown")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+
+		mockStorage.On("GetHouseholdInviteByHash", ctx, hashToken(InviteTokenPrefix+"expired")).Return(&models.HouseholdInvite{HouseholdID: household.ID}, nil).Once()
+		mockStorage.On("GetHouseholdByID", ctx, id).Return(household, nil).Once()
+		mockStorage.On("AcceptHouseholdInvite", ctx, mock.Anything, newcomer.ID.Hex(), mock.Anything).Return(nil, storage.ErrNotFound).Once()
+		_, err = households.AcceptInvite(ctx, InviteTokenPrefix+"expired")
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		mockStorage.AssertNotCalled(t, "AddHouseholdMember", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+func TestHouseholdMembers(t *testing.T) {
+	member := &models.User{ID: primitive.NewObjectID(), Username: "zoe"}
+
+	t.Run("Change role", func(t *testing.T) {
+		mockStorage := new(MockHouseholdStorage)
+		households := NewHouseholdService(mockStorage)
+		household := testHousehold(member)
+		id := household.ID.Hex()
+		ctx := userContext(testUser)
+
+		mockStorage.On("GetHouseholdByID", ctx, id).Return(household, nil).Twice()
+		mockStorage.On("SetHouseholdMemberRole", ctx, id, member.ID.Hex(), models.HouseholdRoleViewer).Return(nil).Once()
+
+		_, err := households.UpdateMember(ctx, id, member.ID.Hex(), models.HouseholdRoleViewer)
+
+		assert.NoError(t, err)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Last owner", func(t *testing.T) {
+		mockStorage := new(MockHouseholdStorage)
+		households := NewHouseholdService(mockStorage)
+		household := testHousehold(member)
+		id := household.ID.Hex()
+		ctx := userContext(testUser)
+
+		mockStorage.On("GetHouseholdByID", ctx, id).Return(household, nil).Twice()
+
+		_, err := households.UpdateMember(ctx, id, testUser.ID.Hex(), models.HouseholdRoleEditor)
+		assert.ErrorIs(t, err, ErrValidation)
+
+		err = households.RemoveMember(ctx, id, testUser.ID.Hex())
+		assert.ErrorIs(t, err, ErrValidation)
+
+		mockStorage.AssertNotCalled(t, "SetHouseholdMemberRole", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+		mockStorage.AssertNotCalled(t, "RemoveHouseholdMember", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Members leave", func(t *testing.T) {
+		mockStorage := new(MockHouseholdStorage)
+		households := NewHouseholdService(mockStorage)
+		household := testHousehold(member)
+		id := household.ID.Hex()
+		ctx := userContext(member)
+
+		mockStorage.On("GetHouseholdByID", ctx, id).Return(household, nil).Once()
+		mockStorage.On("RemoveHouseholdMember", ctx, id, member.ID.Hex()).Return(nil).Once()
+
+		err := households.RemoveMember(ctx, id, member.ID.Hex())
+
+		assert.NoError(t, err)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Only owners remove others", func(t *testing.T) {
+		mockStorage := new(MockHouseholdStorage)
+		households := NewHouseholdService(mockStorage)
+		household := testHousehold(member)
+		id := household.ID.Hex()
+		ctx := userContext(member)
+
+		mockStorage.On("GetHouseholdByID", ctx, id).Return(household, nil).Once()
+
+		err := households.RemoveMember(ctx, id, testUser.ID.Hex())
+
+		assert.ErrorIs(t, err, ErrForbidden)
+		mockStorage.AssertNotCalled(t, "RemoveHouseholdMember", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Unknown member", func(t *testing.T) {
+		mockStorage := new(MockHouseholdStorage)
+		households := NewHouseholdService(mockStorage)
+		household := testHousehold(member)
+		id := household.ID.Hex()
+		ctx := userContext(testUser)
+
+		mockStorage.On("GetHouseholdByID", ctx, id).Return(household, nil).Once()
+
+		_, err := households.UpdateMember(ctx, id, primitive.NewObjectID().Hex(), models.HouseholdRoleViewer)
+
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+}
@@ -0,0 +1,402 @@
+package service
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// Maximum length of the name of a collection, meal plan or grocery list
+	_PlanningMaxNameLength = 100
+	// Maximum number of recipes in a collection
+	_CollectionMaxRecipes = 200
+	// Maximum number of meals in a meal plan
+	_MealPlanMaxMeals = 100
+	// Maximum number of items in a grocery list
+	_GroceryListMaxItems = 200
+	// Format of the dates of planned meals
+	_MealPlanDateFormat = time.DateOnly
+)
+
+// GetCollections returns the collections of the active household, or the user's own collections
+// outside households
+func (s *RecipeService) GetCollections(ctx context.Context) ([]models.Collection, error) {
+	viewer, err := planningViewer(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	collections, err := s.storage.GetCollections(ctx, viewer.UserID.Hex())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collections: %w", err)
+	}
+	return collections, nil
+}
+
+func (s *RecipeService) GetCollection(ctx context.Context, id string) (*models.Collection, error) {
+	viewer, err := planningViewer(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	collection, err := s.storage.GetCollectionByID(ctx, id, viewer.UserID.Hex())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get collection: %w", err)
+	}
+	return collection, nil
+}
+
+// CreateCollection creates a collection of recipes the user can see, in the active household
+func (s *RecipeService) CreateCollection(ctx context.Context, collection *models.Collection) (*models.Collection, error) {
+	viewer, err := planningViewer(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateCollection(ctx, collection); err != nil {
+		return nil, err
+	}
+
+	collection.OwnerID = viewer.UserID
+	collection.CreatedAt = time.Now()
+	collection.UpdatedAt = collection.CreatedAt
+
+	created, err := s.storage.CreateCollection(ctx, collection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create collection: %w", err)
+	}
+	return created, nil
+}
+
+func (s *RecipeService) UpdateCollection(ctx context.Context, id string, collection *models.Collection) (*models.Collection, error) {
+	viewer, err := planningViewer(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateCollection(ctx, collection); err != nil {
+		return nil, err
+	}
+
+	collection.UpdatedAt = time.Now()
+
+	updated, err := s.storage.UpdateCollection(ctx, id, viewer.UserID.Hex(), collection)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update collection: %w", err)
+	}
+	return updated, nil
+}
+
+func (s *RecipeService) DeleteCollection(ctx context.Context, id string) error {
+	viewer, err := planningViewer(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	if err := s.storage.DeleteCollection(ctx, id, viewer.UserID.Hex()); err != nil {
+		return fmt.Errorf("failed to delete collection: %w", err)
+	}
+	return nil
+}
+
+// GetMealPlans returns the meal plans of the active household, or the user's own meal plans
+// outside households
+func (s *RecipeService) GetMealPlans(ctx context.Context) ([]models.MealPlan, error) {
+	viewer, err := planningViewer(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	plans, err := s.storage.GetMealPlans(ctx, viewer.UserID.Hex())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get meal plans: %w", err)
+	}
+	return plans, nil
+}
+
+func (s *RecipeService) GetMealPlan(ctx context.Context, id string) (*models.MealPlan, error) {
+	viewer, err := planningViewer(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	plan, err := s.storage.GetMealPlanByID(ctx, id, viewer.UserID.Hex())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get meal plan: %w", err)
+	}
+	return plan, nil
+}
+
+// CreateMealPlan creates a meal plan of recipes the user can see, in the active household
+func (s *RecipeService) CreateMealPlan(ctx context.Context, plan *models.MealPlan) (*models.MealPlan, error) {
+	viewer, err := planningViewer(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateMealPlan(ctx, plan); err != nil {
+		return nil, err
+	}
+
+	plan.OwnerID = viewer.UserID
+	plan.CreatedAt = time.Now()
+	plan.UpdatedAt = plan.CreatedAt
+
+	created, err := s.storage.CreateMealPlan(ctx, plan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create meal plan: %w", err)
+	}
+	return created, nil
+}
+
+func (s *RecipeService) UpdateMealPlan(ctx context.Context, id string, plan *models.MealPlan) (*models.MealPlan, error) {
+	viewer, err := planningViewer(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.validateMealPlan(ctx, plan); err != nil {
+		return nil, err
+	}
+
+	plan.UpdatedAt = time.Now()
+
+	updated, err := s.storage.UpdateMealPlan(ctx, id, viewer.UserID.Hex(), plan)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update meal plan: %w", err)
+	}
+	return updated, nil
+}
+
+func (s *RecipeService) DeleteMealPlan(ctx context.Context, id string) error {
+	viewer, err := planningViewer(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	if err := s.storage.DeleteMealPlan(ctx, id, viewer.UserID.Hex()); err != nil {
+		return fmt.Errorf("failed to delete meal plan: %w", err)
+	}
+	return nil
+}
+
+// GetGroceryLists returns the grocery lists of the active household, or the user's own grocery
+// lists outside households
+func (s *RecipeService) GetGroceryLists(ctx context.Context) ([]models.GroceryList, error) {
+	viewer, err := planningViewer(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	lists, err := s.storage.GetGroceryLists(ctx, viewer.UserID.Hex())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get grocery lists: %w", err)
+	}
+	return lists, nil
+}
+
+func (s *RecipeService) GetGroceryList(ctx context.Context, id string) (*models.GroceryList, error) {
+	viewer, err := planningViewer(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := s.storage.GetGroceryListByID(ctx, id, viewer.UserID.Hex())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get grocery list: %w", err)
+	}
+	return list, nil
+}
+
+// CreateGroceryList creates a grocery list in the active household
+func (s *RecipeService) CreateGroceryList(ctx context.Context, list *models.GroceryList) (*models.GroceryList, error) {
+	viewer, err := planningViewer(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateGroceryList(list); err != nil {
+		return nil, err
+	}
+
+	list.OwnerID = viewer.UserID
+	list.CreatedAt = time.Now()
+	list.UpdatedAt = list.CreatedAt
+
+	created, err := s.storage.CreateGroceryList(ctx, list)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create grocery list: %w", err)
+	}
+	return created, nil
+}
+
+func (s *RecipeService) UpdateGroceryList(ctx context.Context, id string, list *models.GroceryList) (*models.GroceryList, error) {
+	viewer, err := planningViewer(ctx, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateGroceryList(list); err != nil {
+		return nil, err
+	}
+
+	list.UpdatedAt = time.Now()
+
+	updated, err := s.storage.UpdateGroceryList(ctx, id, viewer.UserID.Hex(), list)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update grocery list: %w", err)
+	}
+	return updated, nil
+}
+
+func (s *RecipeService) DeleteGroceryList(ctx context.Context, id string) error {
+	viewer, err := planningViewer(ctx, true)
+	if err != nil {
+		return err
+	}
+
+	if err := s.storage.DeleteGroceryList(ctx, id, viewer.UserID.Hex()); err != nil {
+		return fmt.Errorf("failed to delete grocery list: %w", err)
+	}
+	return nil
+}
+
+// planningViewer returns the viewer of the context, since collections, meal plans and grocery
+// lists are not available anonymously. To change them in the active household the viewer must
+// be able to change its recipes.
+func planningViewer(ctx context.Context, change bool) (models.RecipeViewer, error) {
+	viewer := viewerFromContext(ctx)
+	if viewer.Anonymous() {
+		return viewer, fmt.Errorf("%w: collections, meal plans and grocery lists are only available to users", ErrForbidden)
+	}
+	if change && !viewer.HouseholdID.IsZero() && !viewer.CanEditHousehold() {
+		return viewer, fmt.Errorf("%w: viewers of a household cannot change its collections, meal plans and grocery lists", ErrForbidden)
+	}
+	return viewer, nil
+}
+
+func (s *RecipeService) validateCollection(ctx context.Context, collection *models.Collection) error {
+	name, err := validatePlanningName(collection.Name)
+	if err != nil {
+		return err
+	}
+	collection.Name = name
+	collection.Description = strings.TrimSpace(collection.Description)
+
+	// A recipe is only once in a collection
+	recipeIDs := []primitive.ObjectID{}
+	for _, recipeID := range collection.RecipeIDs {
+		if !slices.Contains(recipeIDs, recipeID) {
+			recipeIDs = append(recipeIDs, recipeID)
+		}
+	}
+	if len(recipeIDs) > _CollectionMaxRecipes {
+		return fmt.Errorf("%w: a collection can have at most %d recipes", ErrValidation, _CollectionMaxRecipes)
+	}
+	if err := s.checkRecipesVisible(ctx, recipeIDs); err != nil {
+		return err
+	}
+	collection.RecipeIDs = recipeIDs
+
+	return nil
+}
+
+// validateMealPlan validates the meal plan and sorts its meals by date and meal
+func (s *RecipeService) validateMealPlan(ctx context.Context, plan *models.MealPlan) error {
+	name, err := validatePlanningName(plan.Name)
+	if err != nil {
+		return err
+	}
+	plan.Name = name
+
+	if len(plan.Meals) > _MealPlanMaxMeals {
+		return fmt.Errorf("%w: a meal plan can have at most %d meals", ErrValidation, _MealPlanMaxMeals)
+	}
+
+	recipeIDs := []primitive.ObjectID{}
+	for i, meal := range plan.Meals {
+		if _, err := time.Parse(_MealPlanDateFormat, meal.Date); err != nil {
+			return fmt.Errorf("%w: meal %d must have a date in the format YYYY-MM-DD", ErrValidation, i+1)
+		}
+		if !slices.Contains(models.Meals, meal.Meal) {
+			return fmt.Errorf("%w: meal %d must be one of %v", ErrValidation, i+1, models.Meals)
+		}
+		if meal.Servings < 0 {
+			return fmt.Errorf("%w: meal %d must have positive servings", ErrValidation, i+1)
+		}
+		if !slices.Contains(recipeIDs, meal.RecipeID) {
+			recipeIDs = append(recipeIDs, meal.RecipeID)
+		}
+	}
+	if err := s.checkRecipesVisible(ctx, recipeIDs); err != nil {
+		return err
+	}
+
+	slices.SortStableFunc(plan.Meals, func(a, b models.PlannedMeal) int {
+		return cmp.Or(
+			strings.Compare(a.Date, b.Date),
+			cmp.Compare(slices.Index(models.Meals, a.Meal), slices.Index(models.Meals, b.Meal)),
+		)
+	})
+	if plan.Meals == nil {
+		plan.Meals = []models.PlannedMeal{}
+	}
+
+	return nil
+}
+
+func validateGroceryList(list *models.GroceryList) error {
+	name, err := validatePlanningName(list.Name)
+	if err != nil {
+		return err
+	}
+	list.Name = name
+
+	if len(list.Items) > _GroceryListMaxItems {
+		return fmt.Errorf("%w: a grocery list can have at most %d items", ErrValidation, _GroceryListMaxItems)
+	}
+	for i := range list.Items {
+		item := &list.Items[i]
+		item.Name = strings.TrimSpace(item.Name)
+		item.Unit = strings.TrimSpace(item.Unit)
+		if item.Name == "" {
+			return fmt.Errorf("%w: item %d must have a name", ErrValidation, i+1)
+		}
+		if item.Quantity < 0 {
+			return fmt.Errorf("%w: item %s must have a positive quantity", ErrValidation, item.Name)
+		}
+	}
+	if list.Items == nil {
+		list.Items = []models.GroceryItem{}
+	}
+
+	return nil
+}
+
+func validatePlanningName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: name is required", ErrValidation)
+	}
+	if len(name) > _PlanningMaxNameLength {
+		return "", fmt.Errorf("%w: name must be at most %d characters", ErrValidation, _PlanningMaxNameLength)
+	}
+	return name, nil
+}
+
+// checkRecipesVisible checks that the viewer of the context can see the recipes, in the active
+// household, so that collections and meal plans do not reveal other recipes
+func (s *RecipeService) checkRecipesVisible(ctx context.Context, recipeIDs []primitive.ObjectID) error {
+	for _, recipeID := range recipeIDs {
+		if _, err := s.GetRecipe(ctx, recipeID.Hex()); err != nil {
+			if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidID) {
+				return fmt.Errorf("%w: recipe %s not found", ErrValidation, recipeID.Hex())
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,168 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestCollections(t *testing.T) {
+	household := primitive.NewObjectID()
+	recipe := &models.Recipe{ID: primitive.NewObjectID(), Title: "Omelett", OwnerID: testUser.ID, Visibility: models.VisibilityHousehold, HouseholdID: &household}
+
+	t.Run("Create in the household", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		service := NewRecipeService(mockStorage, nil, nil, nil)
+		ctx := householdContext(testUser, household, models.HouseholdRoleEditor)
+
+		mockStorage.On("GetRecipeByID", ctx, recipe.ID.Hex()).Return(recipe, nil).Once()
+		mockStorage.On("CreateCollection", ctx, mock.MatchedBy(func(c *models.Collection) bool {
+			// Trimmed, and each recipe only once
+			return c.Name == "Weeknight dinners" && c.OwnerID == testUser.ID && len(c.RecipeIDs) == 1 && !c.CreatedAt.IsZero()
+		})).Return(&models.Collection{Name: "Weeknight dinners"}, nil).Once()
+
+		collection, err := service.CreateCollection(ctx, &models.Collection{Name: " Weeknight dinners ", RecipeIDs: []primitive.ObjectID{recipe.ID, recipe.ID}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Weeknight dinners", collection.Name)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Recipes the user cannot see", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		service := NewRecipeService(mockStorage, nil, nil, nil)
+		ctx := userContext(testUser)
+		other := &models.Recipe{ID: primitive.NewObjectID(), Title: "Secret", OwnerID: primitive.NewObjectID(), Visibility: models.VisibilityPrivate}
+
+		mockStorage.On("GetRecipeByID", ctx, other.ID.Hex()).Return(other, nil).Once()
+
+		_, err := service.CreateCollection(ctx, &models.Collection{Name: "Mine", RecipeIDs: []primitive.ObjectID{other.ID}})
+
+		assert.ErrorIs(t, err, ErrValidation)
+		mockStorage.AssertNotCalled(t, "CreateCollection", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Viewers of the household cannot change collections", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		service := NewRecipeService(mockStorage, nil, nil, nil)
+		ctx := householdContext(testUser, household, models.HouseholdRoleViewer)
+		id := primitive.NewObjectID().Hex()
+
+		_, err := service.CreateCollection(ctx, &models.Collection{Name: "Weeknight dinners"})
+		assert.ErrorIs(t, err, ErrForbidden)
+		_, err = service.UpdateCollection(ctx, id, &models.Collection{Name: "Weeknight dinners"})
+		assert.ErrorIs(t, err, ErrForbidden)
+		assert.ErrorIs(t, service.DeleteCollection(ctx, id), ErrForbidden)
+
+		// But they see them
+		mockStorage.On("GetCollections", ctx, testUser.ID.Hex()).Return([]models.Collection{{Name: "Weeknight dinners"}}, nil).Once()
+		collections, err := service.GetCollections(ctx)
+		assert.NoError(t, err)
+		assert.Len(t, collections, 1)
+	})
+
+	t.Run("Anonymous", func(t *testing.T) {
+		service := NewRecipeService(new(MockStorage), nil, nil, nil)
+
+		_, err := service.GetCollections(context.Background())
+		assert.ErrorIs(t, err, ErrForbidden)
+	})
+
+	t.Run("Not found", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		service := NewRecipeService(mockStorage, nil, nil, nil)
+		ctx := userContext(testUser)
+		id := primitive.NewObjectID().Hex()
+
+		mockStorage.On("UpdateCollection", ctx, id, testUser.ID.Hex(), mock.Anything).Return(nil, storage.ErrNotFound).Once()
+
+		_, err := service.UpdateCollection(ctx, id, &models.Collection{Name: "Weeknight dinners"})
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+}
+
+func TestMealPlans(t *testing.T) {
+	recipe := &models.Recipe{ID: primitive.NewObjectID(), Title: "Omelett", OwnerID: testUser.ID, Visibility: models.VisibilityPrivate}
+
+	t.Run("Meals are sorted by date and meal", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		service := NewRecipeService(mockStorage, nil, nil, nil)
+		ctx := userContext(testUser)
+
+		mockStorage.On("GetRecipeByID", ctx, recipe.ID.Hex()).Return(recipe, nil).Once()
+		var stored *models.MealPlan
+		mockStorage.On("CreateMealPlan", ctx, mock.AnythingOfType("*models.MealPlan")).Run(func(args mock.Arguments) {
+			stored = args.Get(1).(*models.MealPlan)
+		}).Return(&models.MealPlan{}, nil).Once()
+
+		_, err := service.CreateMealPlan(ctx, &models.MealPlan{Name: "Week 12", Meals: []models.PlannedMeal{
+			{Date: "2025-03-18", Meal: models.MealBreakfast, RecipeID: recipe.ID},
+			{Date: "2025-03-17", Meal: models.MealDinner, RecipeID: recipe.ID},
+			{Date: "2025-03-17", Meal: models.MealLunch, RecipeID: recipe.ID, Servings: 2},
+		}})
+
+		assert.NoError(t, err)
+		assert.Equal(t, []models.PlannedMeal{
+			{Date: "2025-03-17", Meal: models.MealLunch, RecipeID: recipe.ID, Servings: 2},
+			{Date: "2025-03-17", Meal: models.MealDinner, RecipeID: recipe.ID},
+			{Date: "2025-03-18", Meal: models.MealBreakfast, RecipeID: recipe.ID},
+		}, stored.Meals)
+		assert.Equal(t, testUser.ID, stored.OwnerID)
+	})
+
+	t.Run("Validation errors", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		service := NewRecipeService(mockStorage, nil, nil, nil)
+		ctx := userContext(testUser)
+
+		for _, meal := range []models.PlannedMeal{
+			{Date: "17/3/2025", Meal: models.MealDinner, RecipeID: recipe.ID},
+			{Date: "2025-03-17", Meal: "brunch", RecipeID: recipe.ID},
+			{Date: "2025-03-17", Meal: models.MealDinner, RecipeID: recipe.ID, Servings: -1},
+		} {
+			_, err := service.CreateMealPlan(ctx, &models.MealPlan{Name: "Week 12", Meals: []models.PlannedMeal{meal}})
+			assert.ErrorIs(t, err, ErrValidation)
+		}
+
+		_, err := service.CreateMealPlan(ctx, &models.MealPlan{Name: " "})
+		assert.ErrorIs(t, err, ErrValidation)
+
+		mockStorage.On("GetRecipeByID", ctx, mock.Anything).Return(nil, storage.ErrNotFound).Once()
+		_, err = service.CreateMealPlan(ctx, &models.MealPlan{Name: "Week 12", Meals: []models.PlannedMeal{{Date: "2025-03-17", Meal: models.MealDinner, RecipeID: primitive.NewObjectID()}}})
+		assert.ErrorIs(t, err, ErrValidation)
+		mockStorage.AssertNotCalled(t, "CreateMealPlan", mock.Anything, mock.Anything)
+	})
+}
+
+func TestGroceryLists(t *testing.T) {
+	t.Run("Update", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		service := NewRecipeService(mockStorage, nil, nil, nil)
+		ctx := userContext(testUser)
+		id := primitive.NewObjectID().Hex()
+
+		mockStorage.On("UpdateGroceryList", ctx, id, testUser.ID.Hex(), mock.MatchedBy(func(l *models.GroceryList) bool {
+			return l.Name == "Saturday" && l.Items[0] == models.GroceryItem{Name: "Flour", Quantity: 2, Unit: "kg", Checked: true} && !l.UpdatedAt.IsZero()
+		})).Return(&models.GroceryList{Name: "Saturday"}, nil).Once()
+
+		_, err := service.UpdateGroceryList(ctx, id, &models.GroceryList{Name: "Saturday", Items: []models.GroceryItem{{Name: " Flour ", Quantity: 2, Unit: " kg", Checked: true}}})
+
+		assert.NoError(t, err)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Validation errors", func(t *testing.T) {
+		service := NewRecipeService(new(MockStorage), nil, nil, nil)
+		ctx := userContext(testUser)
+
+		_, err := service.CreateGroceryList(ctx, &models.GroceryList{Name: "Saturday", Items: []models.GroceryItem{{Name: " "}}})
+		assert.ErrorIs(t, err, ErrValidation)
+		_, err = service.CreateGroceryList(ctx, &models.GroceryList{Name: "Saturday", Items: []models.GroceryItem{{Name: "Flour", Quantity: -1}}})
+		assert.ErrorIs(t, err, ErrValidation)
+	})
+}
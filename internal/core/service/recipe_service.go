@@ -2,23 +2,49 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"log/slog"
+	"regexp"
+	"strings"
 	"time"
 
 	"github.com/AntonLuning/RecipeBank/internal/core/ai"
+	"github.com/AntonLuning/RecipeBank/internal/core/fetch"
+	"github.com/AntonLuning/RecipeBank/internal/core/pdf"
 	"github.com/AntonLuning/RecipeBank/internal/core/storage"
 	"github.com/AntonLuning/RecipeBank/pkg/core/models"
 )
 
+const (
+	// Maximum number of images in a multi-image import
+	_MaxImportImages = 10
+	// Minimum number of non-whitespace characters of a PDF text layer to analyze it as text,
+	// shorter text layers (e.g. only a page number) are treated as scanned pages
+	_MinPDFTextLength = 100
+	// Maximum number of existing tags of the collection given to the AI to choose from
+	_MaxExistingTags = 200
+	// Maximum length of the name of a translation language
+	_MaxLanguageLength = 40
+)
+
+// Language names (e.g. "english") or codes (e.g. "pt-BR") to translate recipes into
+var languagePattern = regexp.MustCompile(`^[\p{L}]+(?:[ -][\p{L}]+)*$`)
+
 type RecipeService struct {
 	storage storage.RecipeStorage
+	usage   storage.UsageStorage
 	ai      ai.RecipeAI
+	fetcher *fetch.Client
 }
 
-func NewRecipeService(storage storage.RecipeStorage, ai ai.RecipeAI) *RecipeService {
+func NewRecipeService(storage storage.RecipeStorage, usage storage.UsageStorage, ai ai.RecipeAI, fetcher *fetch.Client) *RecipeService {
 	return &RecipeService{
 		storage: storage,
+		usage:   usage,
 		ai:      ai,
+		fetcher: fetcher,
 	}
 }
 
@@ -31,11 +57,21 @@ func (s *RecipeService) GetRecipe(ctx context.Context, id string) (*models.Recip
 	if err != nil {
 		return nil, fmt.Errorf("failed to get recipe: %w", err)
 	}
+	if !recipe.VisibleTo(viewerFromContext(ctx)) {
+		return nil, fmt.Errorf("failed to get recipe: %w", errRecipeNotFound(id))
+	}
 	return recipe, nil
 }
 
+// GetRecipes returns the recipes matching the filter that the viewer of the context can see
 func (s *RecipeService) GetRecipes(ctx context.Context, filter models.RecipeFilter, page int, limit int) (*models.RecipePage, error) {
-	// No validation here - storage layer handles default values
+	// No validation of the pagination here - storage layer handles default values
+
+	viewer := viewerFromContext(ctx)
+	if filter.Mine && viewer.Anonymous() {
+		return nil, fmt.Errorf("%w: mine requires logging in", ErrValidation)
+	}
+	filter.Viewer = &viewer
 
 	recipes, err := s.storage.GetRecipes(ctx, filter, page, limit)
 	if err != nil {
@@ -45,12 +81,41 @@ func (s *RecipeService) GetRecipes(ctx context.Context, filter models.RecipeFilt
 }
 
 func (s *RecipeService) CreateRecipe(ctx context.Context, recipe *models.Recipe) (*models.Recipe, error) {
+	// Recipes created directly are entered by hand or imported from a file, the other sources
+	// are only set by the AI imports
+	if recipe != nil {
+		source, err := newDirectSource(recipe.Source)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrValidation, err.Error())
+		}
+		recipe.Source = source
+
+		if err := setOwner(ctx, recipe); err != nil {
+			return nil, err
+		}
+	}
+
+	// Invalid recipes are rejected when created
+	if validateRecipe(recipe) == nil {
+		if err := s.checkDuplicates(ctx, recipe); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.createRecipe(ctx, recipe)
+}
+
+func (s *RecipeService) createRecipe(ctx context.Context, recipe *models.Recipe) (*models.Recipe, error) {
 	if err := validateRecipe(recipe); err != nil {
 		return nil, fmt.Errorf("%w: %s", ErrValidation, err.Error())
 	}
 
 	recipe.CreatedAt = time.Now()
 	recipe.UpdatedAt = recipe.CreatedAt
+	if recipe.Source != nil && recipe.Source.ImportedAt.IsZero() {
+		recipe.Source.ImportedAt = recipe.CreatedAt
+	}
+	recipe.DuplicateKeys = duplicateKeys(models.NewRecipeFingerprint(recipe))
 
 	createdRecipe, err := s.storage.CreateRecipe(ctx, recipe)
 	if err != nil {
@@ -64,30 +129,42 @@ func (s *RecipeService) CreateRecipeFromImage(ctx context.Context, image string,
 		return nil, fmt.Errorf("%w: AI is not enabled", ErrAIUnsupported)
 	}
 
-	// Validate image
-	if err := validateBase64Image(image, imageType); err != nil {
-		return nil, fmt.Errorf("%w: image is not a valid %s (base64 encoded) or type is not supported", ErrValidation, imageType)
+	result, err := s.analyzeImage(ctx, image, imageType)
+	if err != nil {
+		return nil, err
 	}
 
-	// Convert imageType to ImageContentType
-	var imageContentType ai.ImageContentType
+	return s.createAnalyzedRecipe(ctx, newRecipeFromAnalysisResult(result, models.RecipeSource{Type: models.SourceImage, Image: imageReference(image)}))
+}
 
-	switch imageType {
-	case "jpeg", "jpg":
-		imageContentType = ai.ImageContentTypeJPEG
-	case "png":
-		imageContentType = ai.ImageContentTypePNG
-	default:
-		return nil, fmt.Errorf("%w: image type %s is not supported", ErrValidation, imageType)
+func (s *RecipeService) CreateRecipeFromImages(ctx context.Context, images []models.CreateRecipeFromImageRequest) (*models.Recipe, error) {
+	if s.ai == nil {
+		return nil, fmt.Errorf("%w: AI is not enabled", ErrAIUnsupported)
 	}
 
-	// Analyze the image using AI
-	result, err := s.ai.AnalyzeRecipeImage(ctx, image, imageContentType)
+	if len(images) == 0 {
+		return nil, fmt.Errorf("%w: at least one image is required", ErrValidation)
+	}
+	if len(images) > _MaxImportImages {
+		return nil, fmt.Errorf("%w: at most %d images are allowed", ErrValidation, _MaxImportImages)
+	}
+
+	aiImages := make([]ai.Image, 0, len(images))
+	for i, image := range images {
+		imageContentType, err := validateImage(image.Image, image.ImageType)
+		if err != nil {
+			return nil, fmt.Errorf("%w (image %d)", err, i+1)
+		}
+		aiImages = append(aiImages, ai.Image{Base64: image.Image, ContentType: imageContentType})
+	}
+
+	// Analyze the images together, so the model merges them into one recipe
+	result, err := s.ai.AnalyzeRecipeImages(ctx, aiImages)
 	if err != nil {
-		return nil, fmt.Errorf("%w: failed to create recipe from image: %w", ErrAI, err)
+		return nil, fmt.Errorf("%w: failed to create recipe from images: %w", ErrAI, err)
 	}
 
-	return s.CreateRecipe(ctx, newRecipeFromAnalysisResult(result))
+	return s.createAnalyzedRecipe(ctx, newRecipeFromAnalysisResult(result, models.RecipeSource{Type: models.SourceImage, Image: imageReference(images[0].Image)}))
 }
 
 func (s *RecipeService) CreateRecipeFromURL(ctx context.Context, url string) (*models.Recipe, error) {
@@ -95,19 +172,147 @@ func (s *RecipeService) CreateRecipeFromURL(ctx context.Context, url string) (*m
 		return nil, fmt.Errorf("%w: AI is not enabled", ErrAIUnsupported)
 	}
 
-	// Validate URL and the it exists
-	if err := validateURL(url); err != nil {
+	// Recipes imported from the URL before are found without fetching and analyzing it again
+	if err := s.checkSourceDuplicates(ctx, url); err != nil {
+		return nil, err
+	}
+
+	// Fetch the URL (validates that it exists and is allowed)
+	resp, err := fetchURL(ctx, s.fetcher, url)
+	if err != nil {
+		if errors.Is(err, fetch.ErrBlockedAddress) || errors.Is(err, fetch.ErrUnsupportedScheme) {
+			return nil, fmt.Errorf("%w: URL is not allowed: %s", ErrValidation, url)
+		}
+		if errors.Is(err, fetch.ErrBodyTooLarge) {
+			return nil, fmt.Errorf("%w: content of URL is too large: %s", ErrValidation, url)
+		}
 		return nil, fmt.Errorf("%w: URL could not be found: %s", ErrValidation, url)
 	}
 
-	result, err := s.ai.AnalyzeRecipeWebpage(ctx, url)
+	switch {
+	case isImageContentType(resp.ContentType):
+		// Direct link to an image of a recipe
+		imageType, ok := imageTypeFromContentType(resp.ContentType)
+		if !ok {
+			return nil, fmt.Errorf("%w: image type %s is not supported", ErrValidation, resp.ContentType)
+		}
+
+		image := base64.StdEncoding.EncodeToString(resp.Body)
+
+		result, err := s.analyzeImage(ctx, image, imageType)
+		if err != nil {
+			return nil, err
+		}
+
+		// Keep the fetched image as the recipe photo
+		recipe := newRecipeFromAnalysisResult(result, models.RecipeSource{Type: models.SourceURL, URL: resp.URL, Image: resp.URL})
+		recipe.Image = image
+
+		return s.createAnalyzedRecipe(ctx, recipe)
+	case resp.ContentType == "application/pdf":
+		result, err := s.analyzePDF(ctx, resp.Body)
+		if err != nil {
+			return nil, err
+		}
+
+		return s.createAnalyzedRecipe(ctx, newRecipeFromAnalysisResult(result, models.RecipeSource{Type: models.SourceURL, URL: resp.URL}))
+	case isWebpageContentType(resp.ContentType):
+		result, err := s.ai.AnalyzeRecipeWebpage(ctx, resp.URL, resp.Body)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to create recipe from URL: %w", ErrAI, err)
+		}
+
+		return s.createAnalyzedRecipe(ctx, newRecipeFromAnalysisResult(result, models.RecipeSource{Type: models.SourceURL, URL: resp.URL}))
+	default:
+		return nil, fmt.Errorf("%w: content type %s of URL is not supported", ErrValidation, resp.ContentType)
+	}
+}
+
+func (s *RecipeService) CreateRecipeFromPDF(ctx context.Context, pdfData string) (*models.Recipe, error) {
+	if s.ai == nil {
+		return nil, fmt.Errorf("%w: AI is not enabled", ErrAIUnsupported)
+	}
+
+	if pdfData == "" {
+		return nil, fmt.Errorf("%w: PDF data cannot be empty", ErrValidation)
+	}
+	data, err := base64.StdEncoding.DecodeString(pdfData)
+	if err != nil {
+		return nil, fmt.Errorf("%w: PDF is not base64 encoded", ErrValidation)
+	}
+
+	result, err := s.analyzePDF(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.createAnalyzedRecipe(ctx, newRecipeFromAnalysisResult(result, models.RecipeSource{Type: models.SourcePDF}))
+}
+
+// analyzePDF analyzes the text layer of a PDF using AI, or the page images when the PDF is scanned
+func (s *RecipeService) analyzePDF(ctx context.Context, data []byte) (*ai.RecipeAnalysisResult, error) {
+	if !pdf.IsPDF(data) {
+		return nil, fmt.Errorf("%w: data is not a PDF", ErrValidation)
+	}
+
+	text, err := pdf.ExtractText(data)
+	if err != nil {
+		if errors.Is(err, pdf.ErrEncryptedPDF) {
+			return nil, fmt.Errorf("%w: encrypted PDFs are not supported", ErrValidation)
+		}
+		return nil, fmt.Errorf("%w: PDF could not be read: %s", ErrValidation, err.Error())
+	}
+
+	if len(strings.Join(strings.Fields(text), "")) >= _MinPDFTextLength {
+		result, err := s.ai.AnalyzeRecipeText(ctx, text)
+		if err != nil {
+			return nil, fmt.Errorf("%w: failed to create recipe from PDF: %w", ErrAI, err)
+		}
+		return result, nil
+	}
+
+	// No text layer, fall back to the images of the scanned pages
+	pageImages, err := pdf.ExtractPageImages(data, _MaxImportImages)
 	if err != nil {
-		return nil, fmt.Errorf("%w: failed to create recipe from URL: %w", ErrAI, err)
+		return nil, fmt.Errorf("%w: PDF could not be read: %s", ErrValidation, err.Error())
+	}
+	if len(pageImages) == 0 {
+		return nil, fmt.Errorf("%w: PDF contains no text or page images", ErrValidation)
+	}
+
+	images := make([]ai.Image, 0, len(pageImages))
+	for _, pageImage := range pageImages {
+		images = append(images, ai.Image{
+			Base64:      base64.StdEncoding.EncodeToString(pageImage.Data),
+			ContentType: ai.ImageContentType(pageImage.ContentType),
+		})
 	}
 
-	return s.CreateRecipe(ctx, newRecipeFromAnalysisResult(result))
+	result, err := s.ai.AnalyzeRecipeImages(ctx, images)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to create recipe from PDF: %w", ErrAI, err)
+	}
+	return result, nil
 }
 
+// analyzeImage validates a base64 encoded image and analyzes it using AI
+func (s *RecipeService) analyzeImage(ctx context.Context, image string, imageType string) (*ai.RecipeAnalysisResult, error) {
+	imageContentType, err := validateImage(image, imageType)
+	if err != nil {
+		return nil, err
+	}
+
+	// Analyze the image using AI
+	result, err := s.ai.AnalyzeRecipeImage(ctx, image, imageContentType)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to create recipe from image: %w", ErrAI, err)
+	}
+
+	return result, nil
+}
+
+// UpdateRecipe updates a recipe of the viewer of the context, or any recipe for admins. The
+// visibility is kept if not given, and a new visibility also applies to the translations.
 func (s *RecipeService) UpdateRecipe(ctx context.Context, id string, recipe *models.Recipe) (*models.Recipe, error) {
 	if id == "" {
 		return nil, fmt.Errorf("%w: invalid recipe ID", ErrInvalidInput)
@@ -117,26 +322,73 @@ func (s *RecipeService) UpdateRecipe(ctx context.Context, id string, recipe *mod
 		return nil, fmt.Errorf("%w: %s", ErrValidation, err.Error())
 	}
 
+	existing, err := s.getEditableRecipe(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
 	recipe.UpdatedAt = time.Now()
+	// The source is recorded on creation and cannot be changed, the stored one is kept
+	recipe.Source = existing.Source
+	recipe.DuplicateKeys = duplicateKeys(models.NewRecipeFingerprint(recipe))
+	// Ownership does not change
+	recipe.OwnerID = existing.OwnerID
+	if recipe.Visibility == "" {
+		recipe.Visibility = existing.Visibility
+	}
 
 	updatedRecipe, err := s.storage.UpdateRecipe(ctx, id, recipe)
 	if err != nil {
 		return nil, fmt.Errorf("failed to update recipe: %w", err)
 	}
+
+	if existing.TranslationOf == nil && recipe.Visibility != existing.Visibility {
+		// Translations are seen by the same viewers as their original
+		if err := s.storage.SetTranslationsVisibility(ctx, id, recipe.Visibility); err != nil {
+			return nil, fmt.Errorf("failed to update translations: %w", err)
+		}
+	}
+
 	return updatedRecipe, nil
 }
 
+// DeleteRecipe deletes a recipe of the viewer of the context, or any recipe for admins, with its
+// translations
 func (s *RecipeService) DeleteRecipe(ctx context.Context, id string) error {
 	if id == "" {
 		return fmt.Errorf("%w: invalid recipe ID", ErrInvalidInput)
 	}
 
+	if _, err := s.getEditableRecipe(ctx, id); err != nil {
+		return err
+	}
+
 	if err := s.storage.DeleteRecipe(ctx, id); err != nil {
 		return fmt.Errorf("failed to delete recipe: %w", err)
 	}
 	return nil
 }
 
+// GetAIUsage returns the AI usage of the whole instance in the period, which only admins can see
+func (s *RecipeService) GetAIUsage(ctx context.Context, from time.Time, to time.Time) (*models.AIUsageSummary, error) {
+	if !viewerFromContext(ctx).Admin {
+		return nil, fmt.Errorf("%w: only admins can see the AI usage", ErrForbidden)
+	}
+	if s.usage == nil {
+		return nil, fmt.Errorf("%w: AI usage accounting is not enabled", ErrAIUnsupported)
+	}
+
+	if !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidInput)
+	}
+
+	summary, err := s.usage.GetAIUsageSummary(ctx, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get AI usage: %w", err)
+	}
+	return summary, nil
+}
+
 func validateRecipe(recipe *models.Recipe) error {
 	if recipe == nil {
 		return fmt.Errorf("recipe cannot be nil")
@@ -177,6 +429,13 @@ func validateRecipe(recipe *models.Recipe) error {
 		return fmt.Errorf("servings must be positive")
 	}
 
+	// Set from the owner or the existing recipe if not given
+	if recipe.Visibility != "" {
+		if err := validateVisibility(recipe.Visibility); err != nil {
+			return err
+		}
+	}
+
 	// Validate optional image field
 	if recipe.Image != "" {
 		imageType, err := detectImageTypeFromBase64(recipe.Image)
@@ -191,7 +450,258 @@ func validateRecipe(recipe *models.Recipe) error {
 	return nil
 }
 
-func newRecipeFromAnalysisResult(result *ai.RecipeAnalysisResult) *models.Recipe {
+// validateImage validates a base64 encoded image and converts its type to an ImageContentType
+func validateImage(image string, imageType string) (ai.ImageContentType, error) {
+	if err := validateBase64Image(image, imageType); err != nil {
+		return "", fmt.Errorf("%w: image is not a valid %s (base64 encoded) or type is not supported", ErrValidation, imageType)
+	}
+
+	switch imageType {
+	case "jpeg", "jpg":
+		return ai.ImageContentTypeJPEG, nil
+	case "png":
+		return ai.ImageContentTypePNG, nil
+	default:
+		return "", fmt.Errorf("%w: image type %s is not supported", ErrValidation, imageType)
+	}
+}
+
+// SuggestTags suggests tags for an existing recipe using AI. The recipe is not changed.
+func (s *RecipeService) SuggestTags(ctx context.Context, id string) (*models.TagSuggestion, error) {
+	if s.ai == nil {
+		return nil, fmt.Errorf("%w: AI is not enabled", ErrAIUnsupported)
+	}
+
+	recipe, err := s.GetRecipe(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.suggestTags(ctx, recipe)
+}
+
+// suggestTags suggests tags for the recipe, matching the spelling of the tags in the recipes the
+// viewer of the context can see
+func (s *RecipeService) suggestTags(ctx context.Context, recipe *models.Recipe) (*models.TagSuggestion, error) {
+	viewer := viewerFromContext(ctx)
+	existingTags, err := s.storage.GetTags(ctx, &viewer, _MaxExistingTags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get tags: %w", err)
+	}
+
+	suggestion, err := s.ai.SuggestRecipeTags(ctx, recipe, existingTags)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to suggest tags: %w", ErrAI, err)
+	}
+
+	// Labels are tags too, so they can be used in the tag filter
+	tags := mergeTags(existingTags, suggestion.Tags, []string{suggestion.Cuisine, suggestion.Course}, suggestion.Dietary)
+
+	return &models.TagSuggestion{
+		Tags:    tags,
+		Cuisine: normalizeTag(suggestion.Cuisine),
+		Course:  normalizeTag(suggestion.Course),
+		Dietary: mergeTags(existingTags, suggestion.Dietary),
+	}, nil
+}
+
+// TranslateRecipe translates a recipe into the language using AI. The translation is stored as a
+// copy linked to the original recipe, replacing an earlier translation into the same language.
+// Translations of translations are made from the original, so they do not drift. Only those who
+// can change the original can translate it, the translation has the owner and visibility of the
+// original.
+func (s *RecipeService) TranslateRecipe(ctx context.Context, id string, language string) (*models.Recipe, error) {
+	if s.ai == nil {
+		return nil, fmt.Errorf("%w: AI is not enabled", ErrAIUnsupported)
+	}
+
+	language = strings.ToLower(strings.Join(strings.Fields(language), " "))
+	if language == "" {
+		return nil, fmt.Errorf("%w: language is required", ErrValidation)
+	}
+	if len(language) > _MaxLanguageLength || !languagePattern.MatchString(language) {
+		return nil, fmt.Errorf("%w: language is invalid", ErrValidation)
+	}
+
+	original, err := s.GetRecipe(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if original.TranslationOf != nil {
+		original, err = s.GetRecipe(ctx, original.TranslationOf.Hex())
+		if err != nil {
+			return nil, err
+		}
+	}
+	viewer := viewerFromContext(ctx)
+	if !original.EditableBy(viewer) {
+		return nil, fmt.Errorf("%w: only the owner of the recipe or editors of its household can translate it", ErrForbidden)
+	}
+	// Translations are created in the active household, which must be the one of the original
+	if original.HouseholdID != nil && !original.InHousehold(viewer.HouseholdID) {
+		return nil, fmt.Errorf("%w: the recipe can only be translated in its household", ErrForbidden)
+	}
+
+	result, err := s.ai.TranslateRecipe(ctx, original, language)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to translate recipe: %w", ErrAI, err)
+	}
+
+	translation, err := newTranslation(original, result, language)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to translate recipe: %w", ErrAI, err)
+	}
+
+	existing, err := s.storage.GetRecipes(ctx, models.RecipeFilter{TranslationOf: original.ID.Hex(), Language: language}, 1, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get translations: %w", err)
+	}
+	if len(existing.Recipes) > 0 {
+		translation.CreatedAt = existing.Recipes[0].CreatedAt
+		return s.UpdateRecipe(ctx, existing.Recipes[0].ID.Hex(), translation)
+	}
+
+	return s.createRecipe(ctx, translation)
+}
+
+// newTranslation creates the translated copy of the recipe. The quantities and units are taken
+// from the original, so only the text is translated.
+func newTranslation(original *models.Recipe, result *ai.RecipeAnalysisResult, language string) (*models.Recipe, error) {
+	if len(result.Ingredients) != len(original.Ingredients) {
+		return nil, fmt.Errorf("translation has %d ingredients instead of %d", len(result.Ingredients), len(original.Ingredients))
+	}
+	if len(result.Steps) != len(original.Steps) {
+		return nil, fmt.Errorf("translation has %d steps instead of %d", len(result.Steps), len(original.Steps))
+	}
+
+	ingredients := make([]models.Ingredient, len(original.Ingredients))
+	for i, ingredient := range original.Ingredients {
+		ingredients[i] = models.Ingredient{
+			Name:     result.Ingredients[i].Name,
+			Quantity: ingredient.Quantity,
+			Unit:     ingredient.Unit,
+		}
+	}
+
+	title := result.Title
+	if title == "" {
+		title = original.Title
+	}
+
+	// The translation keeps the source of the original
+	var source *models.RecipeSource
+	if original.Source != nil {
+		originalSource := *original.Source
+		source = &originalSource
+	}
+
+	return &models.Recipe{
+		Title:         title,
+		Description:   result.Description,
+		Ingredients:   ingredients,
+		Steps:         result.Steps,
+		CookTime:      original.CookTime,
+		Servings:      original.Servings,
+		Tags:          original.Tags,
+		Image:         original.Image,
+		Source:        source,
+		OwnerID:       original.OwnerID,
+		Visibility:    original.Visibility,
+		Language:      language,
+		TranslationOf: &original.ID,
+	}, nil
+}
+
+// createAnalyzedRecipe tags and creates a recipe from an AI analysis, unless it is likely a
+// duplicate. The recipe is created without tags if the tags cannot be suggested.
+func (s *RecipeService) createAnalyzedRecipe(ctx context.Context, recipe *models.Recipe) (*models.Recipe, error) {
+	if err := setOwner(ctx, recipe); err != nil {
+		return nil, err
+	}
+
+	if validateRecipe(recipe) == nil {
+		if err := s.checkDuplicates(ctx, recipe); err != nil {
+			return nil, err
+		}
+
+		suggestion, err := s.suggestTags(ctx, recipe)
+		if err != nil {
+			slog.Warn("Unable to suggest tags for imported recipe", "error", err.Error())
+		} else {
+			recipe.Tags = suggestion.Tags
+		}
+	}
+
+	return s.createRecipe(ctx, recipe)
+}
+
+// MigrateDuplicateKeys sets the duplicate keys of the recipes created before recipes had them,
+// so they are found as duplicates. It returns the number of recipes updated.
+func (s *RecipeService) MigrateDuplicateKeys(ctx context.Context) (int64, error) {
+	updated, err := s.storage.SetMissingDuplicateKeys(ctx, duplicateKeys)
+	if err != nil {
+		return updated, fmt.Errorf("failed to migrate duplicate keys: %w", err)
+	}
+	return updated, nil
+}
+
+// mergeTags normalizes and deduplicates the tags, using the spelling of an existing tag when
+// one matches regardless of case
+func mergeTags(existingTags []string, tagLists ...[]string) []string {
+	spelling := make(map[string]string, len(existingTags))
+	for _, tag := range existingTags {
+		if key := normalizeTag(tag); key != "" {
+			if _, ok := spelling[key]; !ok {
+				spelling[key] = tag
+			}
+		}
+	}
+
+	merged := []string{}
+	seen := map[string]bool{}
+	for _, tags := range tagLists {
+		for _, tag := range tags {
+			key := normalizeTag(tag)
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			if existing, ok := spelling[key]; ok {
+				merged = append(merged, existing)
+			} else {
+				merged = append(merged, key)
+			}
+		}
+	}
+
+	return merged
+}
+
+func normalizeTag(tag string) string {
+	return strings.ToLower(strings.Join(strings.Fields(tag), " "))
+}
+
+// newDirectSource validates the source given for a recipe created directly, manual if not given
+func newDirectSource(source *models.RecipeSource) (*models.RecipeSource, error) {
+	if source == nil {
+		return &models.RecipeSource{Type: models.SourceManual}, nil
+	}
+	if source.Type != models.SourceManual && source.Type != models.SourceFile {
+		return nil, fmt.Errorf("source type must be %s or %s", models.SourceManual, models.SourceFile)
+	}
+	if err := validateSourceURL(source.URL); err != nil {
+		return nil, err
+	}
+	return &models.RecipeSource{Type: source.Type, URL: source.URL}, nil
+}
+
+// newRecipeFromAnalysisResult creates a recipe from the AI result, with the model and the prompt
+// version of the result recorded on the source
+func newRecipeFromAnalysisResult(result *ai.RecipeAnalysisResult, source models.RecipeSource) *models.Recipe {
+	source.Model = result.Model
+	source.PromptVersion = result.PromptVersion
+
 	return &models.Recipe{
 		Title:       result.Title,
 		Description: result.Description,
@@ -199,5 +709,6 @@ func newRecipeFromAnalysisResult(result *ai.RecipeAnalysisResult) *models.Recipe
 		Steps:       result.Steps,
 		CookTime:    result.CookTime,
 		Servings:    result.Servings,
+		Source:      &source,
 	}
 }
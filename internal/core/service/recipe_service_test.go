@@ -1,12 +1,23 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/base64"
 	"errors"
+	"fmt"
+	"image"
+	"image/jpeg"
+	"net/http"
+	"net/http/httptest"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/AntonLuning/RecipeBank/internal/core/ai"
+	"github.com/AntonLuning/RecipeBank/internal/core/auth"
+	"github.com/AntonLuning/RecipeBank/internal/core/fetch"
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
 	"github.com/AntonLuning/RecipeBank/pkg/core/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
@@ -54,12 +65,216 @@ func (m *MockStorage) UpdateRecipe(ctx context.Context, id string, recipe *model
 	return args.Get(0).(*models.Recipe), args.Error(1)
 }
 
+// SetTranslationsVisibility mocks the SetTranslationsVisibility method
+func (m *MockStorage) SetTranslationsVisibility(ctx context.Context, id string, visibility string) error {
+	args := m.Called(ctx, id, visibility)
+	return args.Error(0)
+}
+
 // DeleteRecipe mocks the DeleteRecipe method
 func (m *MockStorage) DeleteRecipe(ctx context.Context, id string) error {
 	args := m.Called(ctx, id)
 	return args.Error(0)
 }
 
+// GetTags mocks the GetTags method
+func (m *MockStorage) GetTags(ctx context.Context, viewer *models.RecipeViewer, limit int) ([]string, error) {
+	args := m.Called(ctx, viewer, limit)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]string), args.Error(1)
+}
+
+// GetRecipeFingerprints mocks the GetRecipeFingerprints method
+func (m *MockStorage) GetRecipeFingerprints(ctx context.Context, viewer *models.RecipeViewer) ([]models.RecipeFingerprint, error) {
+	args := m.Called(ctx, viewer)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.RecipeFingerprint), args.Error(1)
+}
+
+// GetRecipeFingerprintsByKeys mocks the GetRecipeFingerprintsByKeys method
+func (m *MockStorage) GetRecipeFingerprintsByKeys(ctx context.Context, viewer *models.RecipeViewer, keys []string) ([]models.RecipeFingerprint, error) {
+	args := m.Called(ctx, viewer, keys)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.RecipeFingerprint), args.Error(1)
+}
+
+// SetMissingDuplicateKeys mocks the SetMissingDuplicateKeys method
+func (m *MockStorage) SetMissingDuplicateKeys(ctx context.Context, keys func(models.RecipeFingerprint) []string) (int64, error) {
+	args := m.Called(ctx, keys)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// AssignRecipeOwner mocks the AssignRecipeOwner method
+func (m *MockStorage) AssignRecipeOwner(ctx context.Context, ownerID string, visibility string) (int64, error) {
+	args := m.Called(ctx, ownerID, visibility)
+	return args.Get(0).(int64), args.Error(1)
+}
+
+// CreateRecipeShare mocks the CreateRecipeShare method
+func (m *MockStorage) CreateRecipeShare(ctx context.Context, share *models.RecipeShare) (*models.RecipeShare, error) {
+	args := m.Called(ctx, share)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.RecipeShare), args.Error(1)
+}
+
+// GetRecipeShares mocks the GetRecipeShares method
+func (m *MockStorage) GetRecipeShares(ctx context.Context, recipeID string) ([]models.RecipeShare, error) {
+	args := m.Called(ctx, recipeID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.RecipeShare), args.Error(1)
+}
+
+// RevokeRecipeShare mocks the RevokeRecipeShare method
+func (m *MockStorage) RevokeRecipeShare(ctx context.Context, id string, recipeID string) error {
+	args := m.Called(ctx, id, recipeID)
+	return args.Error(0)
+}
+
+// GetSharedRecipe mocks the GetSharedRecipe method
+func (m *MockStorage) GetSharedRecipe(ctx context.Context, tokenHash string, now time.Time) (*models.Recipe, error) {
+	args := m.Called(ctx, tokenHash, now)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Recipe), args.Error(1)
+}
+
+// CreateCollection mocks the CreateCollection method
+func (m *MockStorage) CreateCollection(ctx context.Context, collection *models.Collection) (*models.Collection, error) {
+	args := m.Called(ctx, collection)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Collection), args.Error(1)
+}
+
+// GetCollectionByID mocks the GetCollectionByID method
+func (m *MockStorage) GetCollectionByID(ctx context.Context, id string, ownerID string) (*models.Collection, error) {
+	args := m.Called(ctx, id, ownerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Collection), args.Error(1)
+}
+
+// GetCollections mocks the GetCollections method
+func (m *MockStorage) GetCollections(ctx context.Context, ownerID string) ([]models.Collection, error) {
+	args := m.Called(ctx, ownerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.Collection), args.Error(1)
+}
+
+// UpdateCollection mocks the UpdateCollection method
+func (m *MockStorage) UpdateCollection(ctx context.Context, id string, ownerID string, collection *models.Collection) (*models.Collection, error) {
+	args := m.Called(ctx, id, ownerID, collection)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.Collection), args.Error(1)
+}
+
+// DeleteCollection mocks the DeleteCollection method
+func (m *MockStorage) DeleteCollection(ctx context.Context, id string, ownerID string) error {
+	args := m.Called(ctx, id, ownerID)
+	return args.Error(0)
+}
+
+// CreateMealPlan mocks the CreateMealPlan method
+func (m *MockStorage) CreateMealPlan(ctx context.Context, plan *models.MealPlan) (*models.MealPlan, error) {
+	args := m.Called(ctx, plan)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MealPlan), args.Error(1)
+}
+
+// GetMealPlanByID mocks the GetMealPlanByID method
+func (m *MockStorage) GetMealPlanByID(ctx context.Context, id string, ownerID string) (*models.MealPlan, error) {
+	args := m.Called(ctx, id, ownerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MealPlan), args.Error(1)
+}
+
+// GetMealPlans mocks the GetMealPlans method
+func (m *MockStorage) GetMealPlans(ctx context.Context, ownerID string) ([]models.MealPlan, error) {
+	args := m.Called(ctx, ownerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.MealPlan), args.Error(1)
+}
+
+// UpdateMealPlan mocks the UpdateMealPlan method
+func (m *MockStorage) UpdateMealPlan(ctx context.Context, id string, ownerID string, plan *models.MealPlan) (*models.MealPlan, error) {
+	args := m.Called(ctx, id, ownerID, plan)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.MealPlan), args.Error(1)
+}
+
+// DeleteMealPlan mocks the DeleteMealPlan method
+func (m *MockStorage) DeleteMealPlan(ctx context.Context, id string, ownerID string) error {
+	args := m.Called(ctx, id, ownerID)
+	return args.Error(0)
+}
+
+// CreateGroceryList mocks the CreateGroceryList method
+func (m *MockStorage) CreateGroceryList(ctx context.Context, list *models.GroceryList) (*models.GroceryList, error) {
+	args := m.Called(ctx, list)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.GroceryList), args.Error(1)
+}
+
+// GetGroceryListByID mocks the GetGroceryListByID method
+func (m *MockStorage) GetGroceryListByID(ctx context.Context, id string, ownerID string) (*models.GroceryList, error) {
+	args := m.Called(ctx, id, ownerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.GroceryList), args.Error(1)
+}
+
+// GetGroceryLists mocks the GetGroceryLists method
+func (m *MockStorage) GetGroceryLists(ctx context.Context, ownerID string) ([]models.GroceryList, error) {
+	args := m.Called(ctx, ownerID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).([]models.GroceryList), args.Error(1)
+}
+
+// UpdateGroceryList mocks the UpdateGroceryList method
+func (m *MockStorage) UpdateGroceryList(ctx context.Context, id string, ownerID string, list *models.GroceryList) (*models.GroceryList, error) {
+	args := m.Called(ctx, id, ownerID, list)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.GroceryList), args.Error(1)
+}
+
+// DeleteGroceryList mocks the DeleteGroceryList method
+func (m *MockStorage) DeleteGroceryList(ctx context.Context, id string, ownerID string) error {
+	args := m.Called(ctx, id, ownerID)
+	return args.Error(0)
+}
+
 // Initialize mocks the Initialize method
 func (m *MockStorage) Initialize(ctx context.Context) error {
 	args := m.Called(ctx)
@@ -72,12 +287,144 @@ func (m *MockStorage) Close(ctx context.Context) error {
 	return args.Error(0)
 }
 
+// MockUsageStorage is a mock implementation of the storage.UsageStorage interface
+type MockUsageStorage struct {
+	mock.Mock
+}
+
+// CreateAIUsage mocks the CreateAIUsage method
+func (m *MockUsageStorage) CreateAIUsage(ctx context.Context, usage *models.AIUsage) error {
+	args := m.Called(ctx, usage)
+	return args.Error(0)
+}
+
+// GetAIUsageCost mocks the GetAIUsageCost method
+func (m *MockUsageStorage) GetAIUsageCost(ctx context.Context, from time.Time, to time.Time) (float64, error) {
+	args := m.Called(ctx, from, to)
+	return args.Get(0).(float64), args.Error(1)
+}
+
+// GetAIUsageSummary mocks the GetAIUsageSummary method
+func (m *MockUsageStorage) GetAIUsageSummary(ctx context.Context, from time.Time, to time.Time) (*models.AIUsageSummary, error) {
+	args := m.Called(ctx, from, to)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*models.AIUsageSummary), args.Error(1)
+}
+
+// MockAI is a mock implementation of the ai.RecipeAI interface
+type MockAI struct {
+	mock.Mock
+}
+
+// AnalyzeRecipeImage mocks the AnalyzeRecipeImage method
+func (m *MockAI) AnalyzeRecipeImage(ctx context.Context, base64Image string, imageContentType ai.ImageContentType) (*ai.RecipeAnalysisResult, error) {
+	args := m.Called(ctx, base64Image, imageContentType)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ai.RecipeAnalysisResult), args.Error(1)
+}
+
+// AnalyzeRecipeImages mocks the AnalyzeRecipeImages method
+func (m *MockAI) AnalyzeRecipeImages(ctx context.Context, images []ai.Image) (*ai.RecipeAnalysisResult, error) {
+	args := m.Called(ctx, images)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ai.RecipeAnalysisResult), args.Error(1)
+}
+
+// AnalyzeRecipeWebpage mocks the AnalyzeRecipeWebpage method
+func (m *MockAI) AnalyzeRecipeWebpage(ctx context.Context, url string, page []byte) (*ai.RecipeAnalysisResult, error) {
+	args := m.Called(ctx, url, page)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ai.RecipeAnalysisResult), args.Error(1)
+}
+
+// AnalyzeRecipeText mocks the AnalyzeRecipeText method
+func (m *MockAI) AnalyzeRecipeText(ctx context.Context, text string) (*ai.RecipeAnalysisResult, error) {
+	args := m.Called(ctx, text)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ai.RecipeAnalysisResult), args.Error(1)
+}
+
+// SuggestRecipeTags mocks the SuggestRecipeTags method
+func (m *MockAI) SuggestRecipeTags(ctx context.Context, recipe *models.Recipe, existingTags []string) (*ai.TagSuggestion, error) {
+	args := m.Called(ctx, recipe, existingTags)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ai.TagSuggestion), args.Error(1)
+}
+
+// TranslateRecipe mocks the TranslateRecipe method
+func (m *MockAI) TranslateRecipe(ctx context.Context, recipe *models.Recipe, language string) (*ai.RecipeAnalysisResult, error) {
+	args := m.Called(ctx, recipe, language)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ai.RecipeAnalysisResult), args.Error(1)
+}
+
+// SuggestSubstitutions mocks the SuggestSubstitutions method
+func (m *MockAI) SuggestSubstitutions(ctx context.Context, recipe *models.Recipe, ingredient models.Ingredient, dietary []string) (*ai.SubstitutionResult, error) {
+	args := m.Called(ctx, recipe, ingredient, dietary)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*ai.SubstitutionResult), args.Error(1)
+}
+
+// newTestFetcher creates a fetch client that may access httptest servers (loopback)
+func newTestFetcher() *fetch.Client {
+	config := fetch.DefaultConfig()
+	config.AllowPrivate = true
+	return fetch.NewClient(config)
+}
+
+// testUser owns the recipes of the tests, unless another owner is given
+var testUser = &models.User{ID: primitive.NewObjectID(), Username: "anton"}
+
+// userContext returns a context authenticated as the user
+func userContext(user *models.User) context.Context {
+	return auth.WithIdentity(context.Background(), &auth.Identity{User: user})
+}
+
+// householdContext returns a context authenticated as the user, with the household as active
+// household
+func householdContext(user *models.User, householdID primitive.ObjectID, role string) context.Context {
+	return auth.WithIdentity(context.Background(), &auth.Identity{User: user, HouseholdID: householdID, HouseholdRole: role})
+}
+
+// expectNoDuplicates sets up the duplicate check of a new recipe, finding no existing recipes
+func expectNoDuplicates(ctx context.Context, mockStorage *MockStorage) {
+	mockStorage.On("GetRecipeFingerprintsByKeys", ctx, mock.Anything, mock.Anything).Return([]models.RecipeFingerprint{}, nil).Once()
+}
+
+// expectNoSourceDuplicates sets up the duplicate check of a URL before it is imported, finding no
+// recipes imported from it. It must be set up before expectNoDuplicates.
+func expectNoSourceDuplicates(ctx context.Context, mockStorage *MockStorage, url string) {
+	mockStorage.On("GetRecipeFingerprintsByKeys", ctx, mock.Anything, []string{"url:" + fetch.NormalizeURL(url)}).Return([]models.RecipeFingerprint{}, nil).Once()
+}
+
+// expectTagSuggestion sets up the tag suggestion of an AI import, suggesting no tags
+func expectTagSuggestion(ctx context.Context, mockStorage *MockStorage, mockAI *MockAI) {
+	mockStorage.On("GetTags", ctx, mock.Anything, _MaxExistingTags).Return([]string{}, nil).Once()
+	mockAI.On("SuggestRecipeTags", ctx, mock.Anything, []string{}).Return(&ai.TagSuggestion{}, nil).Once()
+}
+
 // TestGetRecipe tests the GetRecipe method
 func TestGetRecipe(t *testing.T) {
 	mockStorage := new(MockStorage)
-	recipeService := NewRecipeService(mockStorage, nil)
+	recipeService := NewRecipeService(mockStorage, nil, nil, nil)
 
-	ctx := context.Background()
+	ctx := userContext(testUser)
 	recipeID := "507f1f77bcf86cd799439011"
 	objID, _ := primitive.ObjectIDFromHex(recipeID)
 
@@ -89,11 +436,13 @@ func TestGetRecipe(t *testing.T) {
 			Ingredients: []models.Ingredient{
 				{Name: "Test Ingredient", Quantity: 1, Unit: "cup"},
 			},
-			Steps:     []string{"Step 1", "Step 2"},
-			CookTime:  30,
-			Servings:  4,
-			CreatedAt: time.Now(),
-			UpdatedAt: time.Now(),
+			Steps:      []string{"Step 1", "Step 2"},
+			CookTime:   30,
+			Servings:   4,
+			OwnerID:    testUser.ID,
+			Visibility: models.VisibilityPrivate,
+			CreatedAt:  time.Now(),
+			UpdatedAt:  time.Now(),
 		}
 
 		mockStorage.On("GetRecipeByID", ctx, recipeID).Return(expectedRecipe, nil).Once()
@@ -105,6 +454,38 @@ func TestGetRecipe(t *testing.T) {
 		mockStorage.AssertExpectations(t)
 	})
 
+	t.Run("Visibility", func(t *testing.T) {
+		other := &models.User{ID: primitive.NewObjectID(), Username: "zoe"}
+		admin := &models.User{ID: primitive.NewObjectID(), Username: "root", Admin: true}
+
+		tests := []struct {
+			name       string
+			ctx        context.Context
+			visibility string
+			visible    bool
+		}{
+			{"Owner", ctx, models.VisibilityPrivate, true},
+			{"Other user", userContext(other), models.VisibilityPrivate, false},
+			{"Other user of household recipe", userContext(other), models.VisibilityHousehold, false},
+			{"Other user of public recipe", userContext(other), models.VisibilityPublic, true},
+			{"Anonymous", context.Background(), models.VisibilityPrivate, false},
+			{"Anonymous of public recipe", context.Background(), models.VisibilityPublic, true},
+			{"Admin", userContext(admin), models.VisibilityPrivate, true},
+		}
+		for _, test := range tests {
+			recipe := &models.Recipe{ID: objID, Title: "Test Recipe", OwnerID: testUser.ID, Visibility: test.visibility}
+			mockStorage.On("GetRecipeByID", test.ctx, recipeID).Return(recipe, nil).Once()
+
+			_, err := recipeService.GetRecipe(test.ctx, recipeID)
+
+			if test.visible {
+				assert.NoError(t, err, test.name)
+			} else {
+				assert.ErrorIs(t, err, storage.ErrNotFound, test.name)
+			}
+		}
+	})
+
 	t.Run("Error", func(t *testing.T) {
 		expectedErr := errors.New("database error")
 		mockStorage.On("GetRecipeByID", ctx, recipeID).Return(nil, expectedErr).Once()
@@ -121,12 +502,15 @@ func TestGetRecipe(t *testing.T) {
 // TestGetRecipes tests the GetRecipes method
 func TestGetRecipes(t *testing.T) {
 	mockStorage := new(MockStorage)
-	recipeService := NewRecipeService(mockStorage, nil)
+	recipeService := NewRecipeService(mockStorage, nil, nil, nil)
 
-	ctx := context.Background()
+	ctx := userContext(testUser)
 	filter := models.RecipeFilter{
 		Title: "Test",
 	}
+	// The filter is restricted to the recipes the user can see
+	viewerFilter := filter
+	viewerFilter.Viewer = &models.RecipeViewer{UserID: testUser.ID}
 
 	t.Run("Success", func(t *testing.T) {
 		expectedPage := &models.RecipePage{
@@ -150,7 +534,7 @@ func TestGetRecipes(t *testing.T) {
 			TotalPages: 1,
 		}
 
-		mockStorage.On("GetRecipes", ctx, filter, 1, 10).Return(expectedPage, nil).Once()
+		mockStorage.On("GetRecipes", ctx, viewerFilter, 1, 10).Return(expectedPage, nil).Once()
 
 		page, err := recipeService.GetRecipes(ctx, filter, 1, 10)
 
@@ -159,6 +543,23 @@ func TestGetRecipes(t *testing.T) {
 		mockStorage.AssertExpectations(t)
 	})
 
+	t.Run("Anonymous", func(t *testing.T) {
+		anonymousFilter := filter
+		anonymousFilter.Viewer = &models.RecipeViewer{}
+		mockStorage.On("GetRecipes", context.Background(), anonymousFilter, 1, 10).Return(&models.RecipePage{}, nil).Once()
+
+		_, err := recipeService.GetRecipes(context.Background(), filter, 1, 10)
+
+		assert.NoError(t, err)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Mine requires logging in", func(t *testing.T) {
+		_, err := recipeService.GetRecipes(context.Background(), models.RecipeFilter{Mine: true}, 1, 10)
+
+		assert.ErrorIs(t, err, ErrValidation)
+	})
+
 	t.Run("Zero Page and Limit", func(t *testing.T) {
 		// Storage should handle default values for page and limit
 		expectedPage := &models.RecipePage{
@@ -169,7 +570,7 @@ func TestGetRecipes(t *testing.T) {
 			TotalPages: 0,
 		}
 
-		mockStorage.On("GetRecipes", ctx, filter, 0, 0).Return(expectedPage, nil).Once()
+		mockStorage.On("GetRecipes", ctx, viewerFilter, 0, 0).Return(expectedPage, nil).Once()
 
 		page, err := recipeService.GetRecipes(ctx, filter, 0, 0)
 
@@ -180,7 +581,7 @@ func TestGetRecipes(t *testing.T) {
 
 	t.Run("Storage Error", func(t *testing.T) {
 		expectedErr := errors.New("database error")
-		mockStorage.On("GetRecipes", ctx, filter, 1, 10).Return(nil, expectedErr).Once()
+		mockStorage.On("GetRecipes", ctx, viewerFilter, 1, 10).Return(nil, expectedErr).Once()
 
 		page, err := recipeService.GetRecipes(ctx, filter, 1, 10)
 
@@ -194,9 +595,9 @@ func TestGetRecipes(t *testing.T) {
 // TestCreateRecipe tests the CreateRecipe method
 func TestCreateRecipe(t *testing.T) {
 	mockStorage := new(MockStorage)
-	recipeService := NewRecipeService(mockStorage, nil)
+	recipeService := NewRecipeService(mockStorage, nil, nil, nil)
 
-	ctx := context.Background()
+	ctx := userContext(testUser)
 
 	t.Run("Success", func(t *testing.T) {
 		recipe := &models.Recipe{
@@ -220,6 +621,7 @@ func TestCreateRecipe(t *testing.T) {
 			Servings:    recipe.Servings,
 		}
 
+		expectNoDuplicates(ctx, mockStorage)
 		mockStorage.On("CreateRecipe", ctx, mock.AnythingOfType("*models.Recipe")).Return(expectedRecipe, nil).Once()
 
 		createdRecipe, err := recipeService.CreateRecipe(ctx, recipe)
@@ -228,6 +630,74 @@ func TestCreateRecipe(t *testing.T) {
 		assert.Equal(t, expectedRecipe, createdRecipe)
 		assert.NotZero(t, recipe.CreatedAt)
 		assert.NotZero(t, recipe.UpdatedAt)
+		assert.Equal(t, &models.RecipeSource{Type: models.SourceManual, ImportedAt: recipe.CreatedAt}, recipe.Source)
+		// Owned by the user, private unless given
+		assert.Equal(t, testUser.ID, recipe.OwnerID)
+		assert.Equal(t, models.VisibilityPrivate, recipe.Visibility)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Visibility", func(t *testing.T) {
+		newRecipe := func(visibility string) *models.Recipe {
+			return &models.Recipe{
+				Title:       "Test Recipe",
+				Ingredients: []models.Ingredient{{Name: "Test Ingredient", Quantity: 1}},
+				Steps:       []string{"Step 1"},
+				Visibility:  visibility,
+			}
+		}
+
+		recipe := newRecipe(models.VisibilityPublic)
+		expectNoDuplicates(ctx, mockStorage)
+		mockStorage.On("CreateRecipe", ctx, recipe).Return(recipe, nil).Once()
+
+		_, err := recipeService.CreateRecipe(ctx, recipe)
+		assert.NoError(t, err)
+		assert.Equal(t, models.VisibilityPublic, recipe.Visibility)
+
+		_, err = recipeService.CreateRecipe(ctx, newRecipe("friends"))
+		assert.ErrorIs(t, err, ErrValidation)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Anonymous", func(t *testing.T) {
+		recipe := &models.Recipe{
+			Title:       "Test Recipe",
+			Ingredients: []models.Ingredient{{Name: "Test Ingredient", Quantity: 1}},
+			Steps:       []string{"Step 1"},
+		}
+
+		_, err := recipeService.CreateRecipe(context.Background(), recipe)
+
+		assert.ErrorIs(t, err, ErrForbidden)
+		mockStorage.AssertNotCalled(t, "CreateRecipe", mock.Anything, recipe)
+	})
+
+	t.Run("Source", func(t *testing.T) {
+		newRecipe := func(source *models.RecipeSource) *models.Recipe {
+			return &models.Recipe{
+				Title:       "Test Recipe",
+				Ingredients: []models.Ingredient{{Name: "Test Ingredient", Quantity: 1}},
+				Steps:       []string{"Step 1"},
+				Source:      source,
+			}
+		}
+
+		// Imported from a file, AI details cannot be given
+		recipe := newRecipe(&models.RecipeSource{Type: models.SourceFile, URL: "https://example.com/export", Model: "gpt-test"})
+		expectNoDuplicates(ctx, mockStorage)
+		mockStorage.On("CreateRecipe", ctx, recipe).Return(recipe, nil).Once()
+
+		_, err := recipeService.CreateRecipe(ctx, recipe)
+		assert.NoError(t, err)
+		assert.Equal(t, &models.RecipeSource{Type: models.SourceFile, URL: "https://example.com/export", ImportedAt: recipe.CreatedAt}, recipe.Source)
+
+		// Only the AI imports set the other sources
+		_, err = recipeService.CreateRecipe(ctx, newRecipe(&models.RecipeSource{Type: models.SourceURL, URL: "https://example.com"}))
+		assert.ErrorIs(t, err, ErrValidation)
+
+		_, err = recipeService.CreateRecipe(ctx, newRecipe(&models.RecipeSource{Type: models.SourceFile, URL: "javascript:alert(1)"}))
+		assert.ErrorIs(t, err, ErrValidation)
 		mockStorage.AssertExpectations(t)
 	})
 
@@ -266,6 +736,7 @@ func TestCreateRecipe(t *testing.T) {
 		}
 
 		expectedErr := errors.New("database error")
+		expectNoDuplicates(ctx, mockStorage)
 		mockStorage.On("CreateRecipe", ctx, mock.AnythingOfType("*models.Recipe")).Return(nil, expectedErr).Once()
 
 		createdRecipe, err := recipeService.CreateRecipe(ctx, recipe)
@@ -280,10 +751,12 @@ func TestCreateRecipe(t *testing.T) {
 // TestUpdateRecipe tests the UpdateRecipe method
 func TestUpdateRecipe(t *testing.T) {
 	mockStorage := new(MockStorage)
-	recipeService := NewRecipeService(mockStorage, nil)
+	recipeService := NewRecipeService(mockStorage, nil, nil, nil)
 
-	ctx := context.Background()
+	ctx := userContext(testUser)
 	recipeID := "507f1f77bcf86cd799439011"
+	objID, _ := primitive.ObjectIDFromHex(recipeID)
+	existing := &models.Recipe{ID: objID, Title: "Recipe", OwnerID: testUser.ID, Visibility: models.VisibilityPrivate}
 
 	t.Run("Success", func(t *testing.T) {
 		recipe := &models.Recipe{
@@ -306,6 +779,7 @@ func TestUpdateRecipe(t *testing.T) {
 			Servings:    recipe.Servings,
 		}
 
+		mockStorage.On("GetRecipeByID", ctx, recipeID).Return(existing, nil).Once()
 		mockStorage.On("UpdateRecipe", ctx, recipeID, mock.AnythingOfType("*models.Recipe")).Return(expectedRecipe, nil).Once()
 
 		updatedRecipe, err := recipeService.UpdateRecipe(ctx, recipeID, recipe)
@@ -313,9 +787,73 @@ func TestUpdateRecipe(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, expectedRecipe, updatedRecipe)
 		assert.NotZero(t, recipe.UpdatedAt)
+		// The owner and visibility are kept
+		assert.Equal(t, testUser.ID, recipe.OwnerID)
+		assert.Equal(t, models.VisibilityPrivate, recipe.Visibility)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Source is kept", func(t *testing.T) {
+		imported := &models.Recipe{ID: objID, Title: "Recipe", OwnerID: testUser.ID, Visibility: models.VisibilityPrivate,
+			Source: &models.RecipeSource{Type: models.SourceURL, URL: "https://example.com/recipe", PromptVersion: "v1"}}
+		recipe := &models.Recipe{Title: "Updated Recipe", Ingredients: []models.Ingredient{{Name: "Flour"}}, Steps: []string{"Mix"},
+			Source: &models.RecipeSource{Type: models.SourceManual}}
+		mockStorage.On("GetRecipeByID", ctx, recipeID).Return(imported, nil).Once()
+		mockStorage.On("UpdateRecipe", ctx, recipeID, recipe).Return(recipe, nil).Once()
+
+		updatedRecipe, err := recipeService.UpdateRecipe(ctx, recipeID, recipe)
+
+		assert.NoError(t, err)
+		// The response has the stored source, which the duplicate keys are computed from
+		assert.Equal(t, imported.Source, updatedRecipe.Source)
+		assert.Contains(t, updatedRecipe.DuplicateKeys, _DuplicateKeySourceURL+"https://example.com/recipe")
 		mockStorage.AssertExpectations(t)
 	})
 
+	t.Run("Not owner", func(t *testing.T) {
+		other := &models.Recipe{ID: objID, Title: "Recipe", OwnerID: primitive.NewObjectID(), Visibility: models.VisibilityPublic}
+		recipe := &models.Recipe{Title: "Updated Recipe", Ingredients: []models.Ingredient{{Name: "Flour"}}, Steps: []string{"Mix"}}
+		mockStorage.On("GetRecipeByID", ctx, recipeID).Return(other, nil).Once()
+
+		_, err := recipeService.UpdateRecipe(ctx, recipeID, recipe)
+
+		assert.ErrorIs(t, err, ErrForbidden)
+		mockStorage.AssertNotCalled(t, "UpdateRecipe", ctx, recipeID, recipe)
+	})
+
+	t.Run("Admin", func(t *testing.T) {
+		adminCtx := userContext(&models.User{ID: primitive.NewObjectID(), Username: "root", Admin: true})
+		recipe := &models.Recipe{Title: "Updated Recipe", Ingredients: []models.Ingredient{{Name: "Flour"}}, Steps: []string{"Mix"}}
+		mockStorage.On("GetRecipeByID", adminCtx, recipeID).Return(existing, nil).Once()
+		mockStorage.On("UpdateRecipe", adminCtx, recipeID, recipe).Return(recipe, nil).Once()
+
+		_, err := recipeService.UpdateRecipe(adminCtx, recipeID, recipe)
+
+		assert.NoError(t, err)
+		assert.Equal(t, testUser.ID, recipe.OwnerID)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Visibility applies to translations", func(t *testing.T) {
+		recipe := &models.Recipe{Title: "Updated Recipe", Ingredients: []models.Ingredient{{Name: "Flour"}}, Steps: []string{"Mix"}, Visibility: models.VisibilityPublic}
+		mockStorage.On("GetRecipeByID", ctx, recipeID).Return(existing, nil).Once()
+		mockStorage.On("UpdateRecipe", ctx, recipeID, recipe).Return(recipe, nil).Once()
+		mockStorage.On("SetTranslationsVisibility", ctx, recipeID, models.VisibilityPublic).Return(nil).Once()
+
+		_, err := recipeService.UpdateRecipe(ctx, recipeID, recipe)
+
+		assert.NoError(t, err)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Invalid visibility", func(t *testing.T) {
+		recipe := &models.Recipe{Title: "Updated Recipe", Ingredients: []models.Ingredient{{Name: "Flour"}}, Steps: []string{"Mix"}, Visibility: "friends"}
+
+		_, err := recipeService.UpdateRecipe(ctx, recipeID, recipe)
+
+		assert.ErrorIs(t, err, ErrValidation)
+	})
+
 	t.Run("Validation Error", func(t *testing.T) {
 		invalidRecipe := &models.Recipe{
 			Title:       "",
@@ -345,6 +883,7 @@ func TestUpdateRecipe(t *testing.T) {
 		}
 
 		expectedErr := errors.New("database error")
+		mockStorage.On("GetRecipeByID", ctx, recipeID).Return(existing, nil).Once()
 		mockStorage.On("UpdateRecipe", ctx, recipeID, mock.AnythingOfType("*models.Recipe")).Return(nil, expectedErr).Once()
 
 		updatedRecipe, err := recipeService.UpdateRecipe(ctx, recipeID, recipe)
@@ -359,12 +898,15 @@ func TestUpdateRecipe(t *testing.T) {
 // TestDeleteRecipe tests the DeleteRecipe method
 func TestDeleteRecipe(t *testing.T) {
 	mockStorage := new(MockStorage)
-	recipeService := NewRecipeService(mockStorage, nil)
+	recipeService := NewRecipeService(mockStorage, nil, nil, nil)
 
-	ctx := context.Background()
+	ctx := userContext(testUser)
 	recipeID := "507f1f77bcf86cd799439011"
+	objID, _ := primitive.ObjectIDFromHex(recipeID)
+	existing := &models.Recipe{ID: objID, Title: "Recipe", OwnerID: testUser.ID, Visibility: models.VisibilityPrivate}
 
 	t.Run("Success", func(t *testing.T) {
+		mockStorage.On("GetRecipeByID", ctx, recipeID).Return(existing, nil).Once()
 		mockStorage.On("DeleteRecipe", ctx, recipeID).Return(nil).Once()
 
 		err := recipeService.DeleteRecipe(ctx, recipeID)
@@ -373,8 +915,30 @@ func TestDeleteRecipe(t *testing.T) {
 		mockStorage.AssertExpectations(t)
 	})
 
+	t.Run("Not owner", func(t *testing.T) {
+		otherCtx := userContext(&models.User{ID: primitive.NewObjectID(), Username: "zoe"})
+		public := &models.Recipe{ID: objID, Title: "Recipe", OwnerID: testUser.ID, Visibility: models.VisibilityPublic}
+		mockStorage.On("GetRecipeByID", otherCtx, recipeID).Return(public, nil).Once()
+
+		err := recipeService.DeleteRecipe(otherCtx, recipeID)
+
+		assert.ErrorIs(t, err, ErrForbidden)
+		mockStorage.AssertNotCalled(t, "DeleteRecipe", otherCtx, recipeID)
+	})
+
+	t.Run("Not visible", func(t *testing.T) {
+		otherCtx := userContext(&models.User{ID: primitive.NewObjectID(), Username: "zoe"})
+		mockStorage.On("GetRecipeByID", otherCtx, recipeID).Return(existing, nil).Once()
+
+		err := recipeService.DeleteRecipe(otherCtx, recipeID)
+
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		mockStorage.AssertNotCalled(t, "DeleteRecipe", otherCtx, recipeID)
+	})
+
 	t.Run("Error", func(t *testing.T) {
 		expectedErr := errors.New("database error")
+		mockStorage.On("GetRecipeByID", ctx, recipeID).Return(existing, nil).Once()
 		mockStorage.On("DeleteRecipe", ctx, recipeID).Return(expectedErr).Once()
 
 		err := recipeService.DeleteRecipe(ctx, recipeID)
@@ -386,11 +950,122 @@ func TestDeleteRecipe(t *testing.T) {
 }
 
 // TestGetRecipeWithEmptyID tests the GetRecipe method with an empty ID
+func TestHouseholdRecipes(t *testing.T) {
+	householdID := primitive.NewObjectID()
+	member := &models.User{ID: primitive.NewObjectID(), Username: "zoe"}
+	recipeID := primitive.NewObjectID()
+	newRecipe := func() *models.Recipe {
+		return &models.Recipe{Title: "Pancakes", Ingredients: []models.Ingredient{{Name: "Flour"}}, Steps: []string{"Mix"}}
+	}
+	// Recipe of another member, visible to the household
+	shared := &models.Recipe{ID: recipeID, Title: "Waffles", OwnerID: testUser.ID, Visibility: models.VisibilityHousehold, HouseholdID: &householdID}
+
+	t.Run("Created visible to the household", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+		ctx := householdContext(member, householdID, models.HouseholdRoleEditor)
+
+		recipe := newRecipe()
+		expectNoDuplicates(ctx, mockStorage)
+		mockStorage.On("CreateRecipe", ctx, recipe).Return(recipe, nil).Once()
+
+		_, err := recipeService.CreateRecipe(ctx, recipe)
+
+		assert.NoError(t, err)
+		assert.Equal(t, member.ID, recipe.OwnerID)
+		assert.Equal(t, models.VisibilityHousehold, recipe.Visibility)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Viewers cannot create", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+		ctx := householdContext(member, householdID, models.HouseholdRoleViewer)
+
+		_, err := recipeService.CreateRecipe(ctx, newRecipe())
+
+		assert.ErrorIs(t, err, ErrForbidden)
+		mockStorage.AssertNotCalled(t, "CreateRecipe", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Editors change recipes of the household", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+		ctx := householdContext(member, householdID, models.HouseholdRoleEditor)
+
+		recipe := newRecipe()
+		mockStorage.On("GetRecipeByID", ctx, recipeID.Hex()).Return(shared, nil).Once()
+		mockStorage.On("UpdateRecipe", ctx, recipeID.Hex(), recipe).Return(recipe, nil).Once()
+
+		_, err := recipeService.UpdateRecipe(ctx, recipeID.Hex(), recipe)
+
+		assert.NoError(t, err)
+		assert.Equal(t, testUser.ID, recipe.OwnerID)
+		assert.Equal(t, models.VisibilityHousehold, recipe.Visibility)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Viewers cannot change recipes of the household", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+		ctx := householdContext(member, householdID, models.HouseholdRoleViewer)
+
+		mockStorage.On("GetRecipeByID", ctx, recipeID.Hex()).Return(shared, nil).Twice()
+
+		recipe, err := recipeService.GetRecipe(ctx, recipeID.Hex())
+		assert.NoError(t, err)
+		assert.Equal(t, shared, recipe)
+
+		err = recipeService.DeleteRecipe(ctx, recipeID.Hex())
+		assert.ErrorIs(t, err, ErrForbidden)
+		mockStorage.AssertNotCalled(t, "DeleteRecipe", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Private recipes of other members", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+		ctx := householdContext(member, householdID, models.HouseholdRoleOwner)
+
+		private := &models.Recipe{ID: recipeID, Title: "Waffles", OwnerID: testUser.ID, Visibility: models.VisibilityPrivate, HouseholdID: &householdID}
+		mockStorage.On("GetRecipeByID", ctx, recipeID.Hex()).Return(private, nil).Once()
+
+		_, err := recipeService.GetRecipe(ctx, recipeID.Hex())
+
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("Household recipes are not visible to other users", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+		ctx := householdContext(member, primitive.NewObjectID(), models.HouseholdRoleOwner)
+
+		mockStorage.On("GetRecipeByID", ctx, recipeID.Hex()).Return(shared, nil).Once()
+
+		_, err := recipeService.GetRecipe(ctx, recipeID.Hex())
+
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+
+	t.Run("Translated in its household", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, nil)
+		ctx := userContext(testUser)
+
+		mockStorage.On("GetRecipeByID", ctx, recipeID.Hex()).Return(shared, nil).Once()
+
+		_, err := recipeService.TranslateRecipe(ctx, recipeID.Hex(), "english")
+
+		assert.ErrorIs(t, err, ErrForbidden)
+		mockAI.AssertNotCalled(t, "TranslateRecipe", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
 func TestGetRecipeWithEmptyID(t *testing.T) {
 	mockStorage := new(MockStorage)
-	recipeService := NewRecipeService(mockStorage, nil)
+	recipeService := NewRecipeService(mockStorage, nil, nil, nil)
 
-	ctx := context.Background()
+	ctx := userContext(testUser)
 	emptyID := ""
 
 	// No mock expectation needed since validation happens before storage call
@@ -407,9 +1082,9 @@ func TestGetRecipeWithEmptyID(t *testing.T) {
 // TestGetRecipeWithInvalidID tests the GetRecipe method with an invalid ID format
 func TestGetRecipeWithInvalidID(t *testing.T) {
 	mockStorage := new(MockStorage)
-	recipeService := NewRecipeService(mockStorage, nil)
+	recipeService := NewRecipeService(mockStorage, nil, nil, nil)
 
-	ctx := context.Background()
+	ctx := userContext(testUser)
 	invalidID := "not-a-valid-object-id"
 
 	mockStorage.On("GetRecipeByID", ctx, invalidID).Return(nil, errors.New("invalid ObjectID")).Once()
@@ -425,10 +1100,10 @@ func TestGetRecipeWithInvalidID(t *testing.T) {
 // TestGetRecipesWithExcessiveLimit tests the GetRecipes method with an extremely large limit
 func TestGetRecipesWithExcessiveLimit(t *testing.T) {
 	mockStorage := new(MockStorage)
-	recipeService := NewRecipeService(mockStorage, nil)
+	recipeService := NewRecipeService(mockStorage, nil, nil, nil)
 
-	ctx := context.Background()
-	filter := models.RecipeFilter{}
+	ctx := userContext(testUser)
+	filter := models.RecipeFilter{Viewer: &models.RecipeViewer{UserID: testUser.ID}}
 
 	// Test with a very large limit value
 	excessiveLimit := 1000000
@@ -453,9 +1128,9 @@ func TestGetRecipesWithExcessiveLimit(t *testing.T) {
 // TestCreateRecipeWithExtremeValues tests the CreateRecipe method with extreme values
 func TestCreateRecipeWithExtremeValues(t *testing.T) {
 	mockStorage := new(MockStorage)
-	recipeService := NewRecipeService(mockStorage, nil)
+	recipeService := NewRecipeService(mockStorage, nil, nil, nil)
 
-	ctx := context.Background()
+	ctx := userContext(testUser)
 
 	// Test with extremely large values
 	extremeRecipe := &models.Recipe{
@@ -469,6 +1144,7 @@ func TestCreateRecipeWithExtremeValues(t *testing.T) {
 		Servings: 999999,
 	}
 
+	expectNoDuplicates(ctx, mockStorage)
 	mockStorage.On("CreateRecipe", ctx, mock.AnythingOfType("*models.Recipe")).Return(extremeRecipe, nil).Once()
 
 	createdRecipe, err := recipeService.CreateRecipe(ctx, extremeRecipe)
@@ -481,9 +1157,9 @@ func TestCreateRecipeWithExtremeValues(t *testing.T) {
 // TestUpdateRecipeWithEmptyID tests the UpdateRecipe method with an empty ID
 func TestUpdateRecipeWithEmptyID(t *testing.T) {
 	mockStorage := new(MockStorage)
-	recipeService := NewRecipeService(mockStorage, nil)
+	recipeService := NewRecipeService(mockStorage, nil, nil, nil)
 
-	ctx := context.Background()
+	ctx := userContext(testUser)
 	emptyID := ""
 	recipe := &models.Recipe{
 		Title:       "Test Recipe",
@@ -507,9 +1183,9 @@ func TestUpdateRecipeWithEmptyID(t *testing.T) {
 // TestDeleteRecipeWithEmptyID tests the DeleteRecipe method with an empty ID
 func TestDeleteRecipeWithEmptyID(t *testing.T) {
 	mockStorage := new(MockStorage)
-	recipeService := NewRecipeService(mockStorage, nil)
+	recipeService := NewRecipeService(mockStorage, nil, nil, nil)
 
-	ctx := context.Background()
+	ctx := userContext(testUser)
 	emptyID := ""
 
 	err := recipeService.DeleteRecipe(ctx, emptyID)
@@ -522,9 +1198,9 @@ func TestDeleteRecipeWithEmptyID(t *testing.T) {
 // TestCreateRecipeWithSpecialCharacters tests the CreateRecipe method with special characters
 func TestCreateRecipeWithSpecialCharacters(t *testing.T) {
 	mockStorage := new(MockStorage)
-	recipeService := NewRecipeService(mockStorage, nil)
+	recipeService := NewRecipeService(mockStorage, nil, nil, nil)
 
-	ctx := context.Background()
+	ctx := userContext(testUser)
 
 	// Test with special characters in text fields
 	specialCharsRecipe := &models.Recipe{
@@ -538,6 +1214,7 @@ func TestCreateRecipeWithSpecialCharacters(t *testing.T) {
 		Servings: 4,
 	}
 
+	expectNoDuplicates(ctx, mockStorage)
 	mockStorage.On("CreateRecipe", ctx, mock.AnythingOfType("*models.Recipe")).Return(specialCharsRecipe, nil).Once()
 
 	createdRecipe, err := recipeService.CreateRecipe(ctx, specialCharsRecipe)
@@ -550,9 +1227,9 @@ func TestCreateRecipeWithSpecialCharacters(t *testing.T) {
 // TestCreateRecipeWithImage tests creating recipes with image validation
 func TestCreateRecipeWithImage(t *testing.T) {
 	mockStorage := new(MockStorage)
-	recipeService := NewRecipeService(mockStorage, nil)
+	recipeService := NewRecipeService(mockStorage, nil, nil, nil)
 
-	ctx := context.Background()
+	ctx := userContext(testUser)
 
 	// Valid JPEG base64 data (1x1 pixel JPEG)
 	validJPEGBase64 := "/9j/4AAQSkZJRgABAQEASABIAAD/2Q=="
@@ -584,6 +1261,7 @@ func TestCreateRecipeWithImage(t *testing.T) {
 			Image:       recipe.Image,
 		}
 
+		expectNoDuplicates(ctx, mockStorage)
 		mockStorage.On("CreateRecipe", ctx, mock.AnythingOfType("*models.Recipe")).Return(expectedRecipe, nil).Once()
 
 		createdRecipe, err := recipeService.CreateRecipe(ctx, recipe)
@@ -618,6 +1296,7 @@ func TestCreateRecipeWithImage(t *testing.T) {
 			Image:       recipe.Image,
 		}
 
+		expectNoDuplicates(ctx, mockStorage)
 		mockStorage.On("CreateRecipe", ctx, mock.AnythingOfType("*models.Recipe")).Return(expectedRecipe, nil).Once()
 
 		createdRecipe, err := recipeService.CreateRecipe(ctx, recipe)
@@ -652,6 +1331,7 @@ func TestCreateRecipeWithImage(t *testing.T) {
 			Image:       "",
 		}
 
+		expectNoDuplicates(ctx, mockStorage)
 		mockStorage.On("CreateRecipe", ctx, mock.AnythingOfType("*models.Recipe")).Return(expectedRecipe, nil).Once()
 
 		createdRecipe, err := recipeService.CreateRecipe(ctx, recipe)
@@ -707,3 +1387,738 @@ func TestCreateRecipeWithImage(t *testing.T) {
 		assert.ErrorIs(t, errors.Unwrap(err), ErrValidation)
 	})
 }
+
+// TestCreateRecipeFromURL tests creating recipes from webpages and direct image links
+func TestCreateRecipeFromURL(t *testing.T) {
+	jpegData, err := base64.StdEncoding.DecodeString("/9j/4AAQSkZJRgABAQEASABIAAD/2Q==")
+	assert.NoError(t, err)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/recipe.html":
+			w.Header().Set("Content-Type", "text/html; charset=utf-8")
+			w.Write([]byte("<html><body><h1>Pancakes</h1></body></html>"))
+		case "/recipe.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write(jpegData)
+		case "/untyped":
+			w.Header().Set("Content-Type", "application/octet-stream")
+			w.Write(jpegData)
+		case "/fake.jpg":
+			w.Header().Set("Content-Type", "image/jpeg")
+			w.Write([]byte("not really a jpeg"))
+		case "/recipe.webp":
+			w.Header().Set("Content-Type", "image/webp")
+			w.Write([]byte("RIFF....WEBP"))
+		case "/recipe.zip":
+			w.Header().Set("Content-Type", "application/zip")
+			w.Write([]byte("PK"))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	ctx := userContext(testUser)
+
+	analysisResult := &ai.RecipeAnalysisResult{
+		Title:       "Pancakes",
+		Ingredients: []models.Ingredient{{Name: "Flour", Quantity: 3, Unit: "dl"}},
+		Steps:       []string{"Mix", "Fry"},
+	}
+	t.Run("Webpage", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, newTestFetcher())
+
+		mockAI.On("AnalyzeRecipeWebpage", ctx, server.URL+"/recipe.html", []byte("<html><body><h1>Pancakes</h1></body></html>")).Return(analysisResult, nil).Once()
+		expectTagSuggestion(ctx, mockStorage, mockAI)
+		expectNoSourceDuplicates(ctx, mockStorage, server.URL+"/recipe.html")
+		expectNoDuplicates(ctx, mockStorage)
+		mockStorage.On("CreateRecipe", ctx, mock.MatchedBy(func(r *models.Recipe) bool {
+			return r.Title == "Pancakes" && r.Image == "" &&
+				r.Source.Type == models.SourceURL && r.Source.URL == server.URL+"/recipe.html"
+		})).Return(&models.Recipe{Title: "Pancakes"}, nil).Once()
+
+		recipe, err := recipeService.CreateRecipeFromURL(ctx, server.URL+"/recipe.html")
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Pancakes", recipe.Title)
+		assert.Empty(t, recipe.Image)
+		mockAI.AssertExpectations(t)
+		mockStorage.AssertExpectations(t)
+	})
+
+	for _, path := range []string{"/recipe.jpg", "/untyped"} {
+		t.Run("Image "+path, func(t *testing.T) {
+			mockStorage := new(MockStorage)
+			mockAI := new(MockAI)
+			recipeService := NewRecipeService(mockStorage, nil, mockAI, newTestFetcher())
+
+			image := base64.StdEncoding.EncodeToString(jpegData)
+
+			mockAI.On("AnalyzeRecipeImage", ctx, image, ai.ImageContentTypeJPEG).Return(analysisResult, nil).Once()
+			expectTagSuggestion(ctx, mockStorage, mockAI)
+			expectNoSourceDuplicates(ctx, mockStorage, server.URL+path)
+			expectNoDuplicates(ctx, mockStorage)
+			mockStorage.On("CreateRecipe", ctx, mock.MatchedBy(func(r *models.Recipe) bool {
+				return r.Title == "Pancakes" && r.Image == image &&
+					r.Source.Type == models.SourceURL && r.Source.URL == server.URL+path && r.Source.Image == server.URL+path
+			})).Return(&models.Recipe{Title: "Pancakes", Image: image}, nil).Once()
+
+			recipe, err := recipeService.CreateRecipeFromURL(ctx, server.URL+path)
+
+			assert.NoError(t, err)
+			assert.Equal(t, image, recipe.Image)
+			mockAI.AssertExpectations(t)
+			mockStorage.AssertExpectations(t)
+		})
+	}
+
+	for _, path := range []string{"/fake.jpg", "/recipe.webp", "/recipe.zip", "/missing"} {
+		t.Run("Invalid "+path, func(t *testing.T) {
+			mockStorage := new(MockStorage)
+			mockAI := new(MockAI)
+			recipeService := NewRecipeService(mockStorage, nil, mockAI, newTestFetcher())
+
+			expectNoSourceDuplicates(ctx, mockStorage, server.URL+path)
+
+			recipe, err := recipeService.CreateRecipeFromURL(ctx, server.URL+path)
+
+			assert.Nil(t, recipe)
+			assert.ErrorIs(t, err, ErrValidation)
+			mockAI.AssertNotCalled(t, "AnalyzeRecipeImage", mock.Anything, mock.Anything, mock.Anything)
+			mockAI.AssertNotCalled(t, "AnalyzeRecipeWebpage", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+
+	t.Run("Imported before", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, newTestFetcher())
+
+		existing := models.RecipeFingerprint{ID: primitive.NewObjectID(), Title: "Pancakes", SourceURL: server.URL + "/missing"}
+		mockStorage.On("GetRecipeFingerprintsByKeys", ctx, mock.Anything, []string{"url:" + server.URL + "/missing"}).Return([]models.RecipeFingerprint{existing}, nil).Once()
+
+		// The URL is not fetched (it would not be found) nor analyzed
+		_, err := recipeService.CreateRecipeFromURL(ctx, server.URL+"/missing?utm_source=news#steps")
+
+		var duplicateErr *DuplicateError
+		if assert.ErrorAs(t, err, &duplicateErr) && assert.Len(t, duplicateErr.Candidates, 1) {
+			assert.Equal(t, existing.ID, duplicateErr.Candidates[0].ID)
+			assert.Equal(t, models.DuplicateSourceURL, duplicateErr.Candidates[0].Reason)
+		}
+		mockAI.AssertNotCalled(t, "AnalyzeRecipeWebpage", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Blocked address", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, new(MockAI), fetch.NewClient(fetch.DefaultConfig()))
+
+		expectNoSourceDuplicates(ctx, mockStorage, server.URL+"/recipe.html")
+
+		recipe, err := recipeService.CreateRecipeFromURL(ctx, server.URL+"/recipe.html")
+
+		assert.Nil(t, recipe)
+		assert.ErrorIs(t, err, ErrValidation)
+		assert.Contains(t, err.Error(), "not allowed")
+	})
+
+	t.Run("AI disabled", func(t *testing.T) {
+		recipeService := NewRecipeService(new(MockStorage), nil, nil, newTestFetcher())
+
+		_, err := recipeService.CreateRecipeFromURL(ctx, server.URL+"/recipe.html")
+
+		assert.ErrorIs(t, err, ErrAIUnsupported)
+	})
+}
+
+// TestCreateRecipeFromImages tests creating a recipe from several images
+func TestCreateRecipeFromImages(t *testing.T) {
+	ctx := userContext(testUser)
+
+	validJPEGBase64 := "/9j/4AAQSkZJRgABAQEASABIAAD/2Q=="
+	validPNGBase64 := "iVBORw0KGgoAAAANSUhEUgAAAAEAAAABCAYAAAAfFcSJAAAADUlEQVR42mNkYPhfDwAChAI9DeAQu3QAAAABJRU5ErkJggg="
+
+	t.Run("Success", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, nil)
+
+		result := &ai.RecipeAnalysisResult{
+			Title:       "Recipe Card",
+			Ingredients: []models.Ingredient{{Name: "Flour", Quantity: 3, Unit: "dl"}},
+			Steps:       []string{"Step from the front", "Step from the back"},
+		}
+
+		mockAI.On("AnalyzeRecipeImages", ctx, []ai.Image{
+			{Base64: validJPEGBase64, ContentType: ai.ImageContentTypeJPEG},
+			{Base64: validPNGBase64, ContentType: ai.ImageContentTypePNG},
+		}).Return(result, nil).Once()
+		expectTagSuggestion(ctx, mockStorage, mockAI)
+		expectNoDuplicates(ctx, mockStorage)
+		mockStorage.On("CreateRecipe", ctx, mock.MatchedBy(func(r *models.Recipe) bool {
+			return r.Title == "Recipe Card" && len(r.Steps) == 2
+		})).Return(&models.Recipe{Title: "Recipe Card"}, nil).Once()
+
+		recipe, err := recipeService.CreateRecipeFromImages(ctx, []models.CreateRecipeFromImageRequest{
+			{Image: validJPEGBase64, ImageType: "jpeg"},
+			{Image: validPNGBase64, ImageType: "png"},
+		})
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Recipe Card", recipe.Title)
+		mockAI.AssertExpectations(t)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Validation errors", func(t *testing.T) {
+		recipeService := NewRecipeService(new(MockStorage), nil, new(MockAI), nil)
+
+		tooMany := make([]models.CreateRecipeFromImageRequest, _MaxImportImages+1)
+		for i := range tooMany {
+			tooMany[i] = models.CreateRecipeFromImageRequest{Image: validJPEGBase64, ImageType: "jpeg"}
+		}
+
+		for name, images := range map[string][]models.CreateRecipeFromImageRequest{
+			"no images":     nil,
+			"too many":      tooMany,
+			"invalid image": {{Image: validJPEGBase64, ImageType: "jpeg"}, {Image: validJPEGBase64, ImageType: "png"}},
+		} {
+			t.Run(name, func(t *testing.T) {
+				recipe, err := recipeService.CreateRecipeFromImages(ctx, images)
+
+				assert.Nil(t, recipe)
+				assert.ErrorIs(t, err, ErrValidation)
+			})
+		}
+	})
+
+	t.Run("AI error", func(t *testing.T) {
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(new(MockStorage), nil, mockAI, nil)
+
+		mockAI.On("AnalyzeRecipeImages", ctx, mock.Anything).Return(nil, errors.New("model error")).Once()
+
+		_, err := recipeService.CreateRecipeFromImages(ctx, []models.CreateRecipeFromImageRequest{
+			{Image: validJPEGBase64, ImageType: "jpeg"},
+		})
+
+		assert.ErrorIs(t, err, ErrAI)
+	})
+}
+
+// newTestPDF builds a one page PDF with the content stream and an optional image XObject /Im1
+func newTestPDF(content string, imageObject []byte) []byte {
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> /XObject << /Im1 6 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		string(imageObject),
+	}
+	if imageObject == nil {
+		objects[5] = "null"
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return buf.Bytes()
+}
+
+// TestCreateRecipeFromPDF tests creating a recipe from a PDF with and without a text layer
+func TestCreateRecipeFromPDF(t *testing.T) {
+	ctx := userContext(testUser)
+
+	textPDF := newTestPDF("BT /F1 12 Tf 72 770 Td (Pancakes) Tj 0 -16 Td (3 dl flour, 6 dl milk, 3 eggs and a pinch of salt) Tj 0 -16 Td (Whisk everything together, let the batter rest and fry thin pancakes in butter.) Tj ET", nil)
+
+	var jpegData bytes.Buffer
+	assert.NoError(t, jpeg.Encode(&jpegData, image.NewGray(image.Rect(0, 0, 400, 600)), nil))
+	imageObject := append([]byte(fmt.Sprintf("<< /Type /XObject /Subtype /Image /Width 400 /Height 600 /ColorSpace /DeviceGray /BitsPerComponent 8 /Filter /DCTDecode /Length %d >>\nstream\n", jpegData.Len())), jpegData.Bytes()...)
+	imageObject = append(imageObject, []byte("\nendstream")...)
+	scannedPDF := newTestPDF("q 595 0 0 842 0 0 cm /Im1 Do Q", imageObject)
+
+	analysisResult := &ai.RecipeAnalysisResult{
+		Title:       "Pancakes",
+		Ingredients: []models.Ingredient{{Name: "Flour", Quantity: 3, Unit: "dl"}},
+		Steps:       []string{"Whisk", "Fry"},
+	}
+
+	t.Run("Text layer", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, nil)
+
+		mockAI.On("AnalyzeRecipeText", ctx, mock.MatchedBy(func(text string) bool {
+			return strings.HasPrefix(text, "Pancakes\n3 dl flour")
+		})).Return(analysisResult, nil).Once()
+		expectTagSuggestion(ctx, mockStorage, mockAI)
+		expectNoDuplicates(ctx, mockStorage)
+		mockStorage.On("CreateRecipe", ctx, mock.MatchedBy(func(r *models.Recipe) bool {
+			return r.Title == "Pancakes"
+		})).Return(&models.Recipe{Title: "Pancakes"}, nil).Once()
+
+		recipe, err := recipeService.CreateRecipeFromPDF(ctx, base64.StdEncoding.EncodeToString(textPDF))
+
+		assert.NoError(t, err)
+		assert.Equal(t, "Pancakes", recipe.Title)
+		mockAI.AssertExpectations(t)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Scanned pages", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, nil)
+
+		mockAI.On("AnalyzeRecipeImages", ctx, []ai.Image{
+			{Base64: base64.StdEncoding.EncodeToString(jpegData.Bytes()), ContentType: ai.ImageContentTypeJPEG},
+		}).Return(analysisResult, nil).Once()
+		expectTagSuggestion(ctx, mockStorage, mockAI)
+		expectNoDuplicates(ctx, mockStorage)
+		mockStorage.On("CreateRecipe", ctx, mock.Anything).Return(&models.Recipe{Title: "Pancakes"}, nil).Once()
+
+		_, err := recipeService.CreateRecipeFromPDF(ctx, base64.StdEncoding.EncodeToString(scannedPDF))
+
+		assert.NoError(t, err)
+		mockAI.AssertExpectations(t)
+		mockAI.AssertNotCalled(t, "AnalyzeRecipeText", mock.Anything, mock.Anything)
+	})
+
+	t.Run("From URL", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/pdf")
+			w.Write(textPDF)
+		}))
+		defer server.Close()
+
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, newTestFetcher())
+
+		mockAI.On("AnalyzeRecipeText", ctx, mock.Anything).Return(analysisResult, nil).Once()
+		expectTagSuggestion(ctx, mockStorage, mockAI)
+		expectNoSourceDuplicates(ctx, mockStorage, server.URL+"/recipe.pdf")
+		expectNoDuplicates(ctx, mockStorage)
+		mockStorage.On("CreateRecipe", ctx, mock.Anything).Return(&models.Recipe{Title: "Pancakes"}, nil).Once()
+
+		_, err := recipeService.CreateRecipeFromURL(ctx, server.URL+"/recipe.pdf")
+
+		assert.NoError(t, err)
+		mockAI.AssertExpectations(t)
+	})
+
+	t.Run("Validation errors", func(t *testing.T) {
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(new(MockStorage), nil, mockAI, nil)
+
+		for name, data := range map[string]string{
+			"empty":      "",
+			"not base64": "%PDF-1.4",
+			"not a PDF":  base64.StdEncoding.EncodeToString([]byte("<html></html>")),
+			"malformed":  base64.StdEncoding.EncodeToString([]byte("%PDF-1.4\n%%EOF\n")),
+			"no content": base64.StdEncoding.EncodeToString(newTestPDF("0 0 m 100 100 l S", nil)),
+		} {
+			t.Run(name, func(t *testing.T) {
+				recipe, err := recipeService.CreateRecipeFromPDF(ctx, data)
+
+				assert.Nil(t, recipe)
+				assert.ErrorIs(t, err, ErrValidation)
+			})
+		}
+
+		mockAI.AssertNotCalled(t, "AnalyzeRecipeText", mock.Anything, mock.Anything)
+		mockAI.AssertNotCalled(t, "AnalyzeRecipeImages", mock.Anything, mock.Anything)
+	})
+
+	t.Run("AI disabled", func(t *testing.T) {
+		recipeService := NewRecipeService(new(MockStorage), nil, nil, nil)
+
+		_, err := recipeService.CreateRecipeFromPDF(ctx, base64.StdEncoding.EncodeToString(textPDF))
+
+		assert.ErrorIs(t, err, ErrAIUnsupported)
+	})
+}
+
+// TestGetAIUsage tests the GetAIUsage method
+func TestGetAIUsage(t *testing.T) {
+	ctx := userContext(&models.User{ID: primitive.NewObjectID(), Username: "root", Admin: true})
+	from := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 4, 1, 0, 0, 0, 0, time.UTC)
+
+	t.Run("Summary", func(t *testing.T) {
+		mockUsage := new(MockUsageStorage)
+		recipeService := NewRecipeService(new(MockStorage), mockUsage, nil, nil)
+
+		summary := &models.AIUsageSummary{From: from, To: to, Imports: 2, Cost: 0.01}
+		mockUsage.On("GetAIUsageSummary", ctx, from, to).Return(summary, nil).Once()
+
+		result, err := recipeService.GetAIUsage(ctx, from, to)
+
+		assert.NoError(t, err)
+		assert.Equal(t, summary, result)
+		mockUsage.AssertExpectations(t)
+	})
+
+	t.Run("Invalid period", func(t *testing.T) {
+		recipeService := NewRecipeService(new(MockStorage), new(MockUsageStorage), nil, nil)
+
+		_, err := recipeService.GetAIUsage(ctx, to, from)
+
+		assert.ErrorIs(t, err, ErrInvalidInput)
+	})
+
+	t.Run("Not enabled", func(t *testing.T) {
+		recipeService := NewRecipeService(new(MockStorage), nil, nil, nil)
+
+		_, err := recipeService.GetAIUsage(ctx, from, to)
+
+		assert.ErrorIs(t, err, ErrAIUnsupported)
+	})
+
+	t.Run("Not an admin", func(t *testing.T) {
+		mockUsage := new(MockUsageStorage)
+		recipeService := NewRecipeService(new(MockStorage), mockUsage, nil, nil)
+
+		_, err := recipeService.GetAIUsage(userContext(testUser), from, to)
+		assert.ErrorIs(t, err, ErrForbidden)
+		_, err = recipeService.GetAIUsage(context.Background(), from, to)
+		assert.ErrorIs(t, err, ErrForbidden)
+		mockUsage.AssertNotCalled(t, "GetAIUsageSummary", mock.Anything, mock.Anything, mock.Anything)
+	})
+}
+
+// TestSuggestTags tests the SuggestTags method
+func TestSuggestTags(t *testing.T) {
+	ctx := userContext(testUser)
+	recipeID := primitive.NewObjectID()
+	recipe := &models.Recipe{
+		ID:          recipeID,
+		Title:       "Spaghetti Aglio e Olio",
+		Ingredients: []models.Ingredient{{Name: "Spaghetti", Quantity: 400, Unit: "g"}},
+		Steps:       []string{"Boil", "Fry the garlic", "Toss"},
+		Tags:        []string{"Quick"},
+		OwnerID:     testUser.ID,
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, nil)
+
+		existingTags := []string{"Pasta", "Weeknight", "vegan"}
+		mockStorage.On("GetRecipeByID", ctx, recipeID.Hex()).Return(recipe, nil).Once()
+		// Only the tags of the recipes the user can see are given to the AI
+		mockStorage.On("GetTags", ctx, &models.RecipeViewer{UserID: testUser.ID}, _MaxExistingTags).Return(existingTags, nil).Once()
+		mockAI.On("SuggestRecipeTags", ctx, recipe, existingTags).Return(&ai.TagSuggestion{
+			Tags:    []string{"pasta", "garlic", "  Weeknight ", "pasta", ""},
+			Cuisine: "Italian",
+			Course:  "Main Course",
+			Dietary: []string{"Vegan"},
+		}, nil).Once()
+
+		suggestion, err := recipeService.SuggestTags(ctx, recipeID.Hex())
+
+		assert.NoError(t, err)
+		// Existing spellings are kept, new tags are lowercased and duplicates are dropped
+		assert.Equal(t, &models.TagSuggestion{
+			Tags:    []string{"Pasta", "garlic", "Weeknight", "italian", "main course", "vegan"},
+			Cuisine: "italian",
+			Course:  "main course",
+			Dietary: []string{"vegan"},
+		}, suggestion)
+		mockStorage.AssertExpectations(t)
+		mockAI.AssertExpectations(t)
+		// The recipe is not changed
+		mockStorage.AssertNotCalled(t, "UpdateRecipe", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Recipe not found", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, nil)
+
+		mockStorage.On("GetRecipeByID", ctx, recipeID.Hex()).Return(nil, storage.ErrNotFound).Once()
+
+		_, err := recipeService.SuggestTags(ctx, recipeID.Hex())
+
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		mockAI.AssertNotCalled(t, "SuggestRecipeTags", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("AI error", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, nil)
+
+		mockStorage.On("GetRecipeByID", ctx, recipeID.Hex()).Return(recipe, nil).Once()
+		mockStorage.On("GetTags", ctx, mock.Anything, _MaxExistingTags).Return([]string{}, nil).Once()
+		mockAI.On("SuggestRecipeTags", ctx, recipe, []string{}).Return(nil, ai.ErrUnavailable).Once()
+
+		_, err := recipeService.SuggestTags(ctx, recipeID.Hex())
+
+		assert.ErrorIs(t, err, ErrAI)
+		assert.ErrorIs(t, err, ai.ErrUnavailable)
+	})
+
+	t.Run("AI disabled", func(t *testing.T) {
+		recipeService := NewRecipeService(new(MockStorage), nil, nil, nil)
+
+		_, err := recipeService.SuggestTags(ctx, recipeID.Hex())
+
+		assert.ErrorIs(t, err, ErrAIUnsupported)
+	})
+}
+
+// TestAIImportTags tests that AI imports are tagged
+func TestAIImportTags(t *testing.T) {
+	ctx := userContext(testUser)
+	validJPEGBase64 := "/9j/4AAQSkZJRgABAQEASABIAAD/2Q=="
+	analysisResult := &ai.RecipeAnalysisResult{
+		Title:       "Pancakes",
+		Ingredients: []models.Ingredient{{Name: "Flour", Quantity: 3, Unit: "dl"}},
+		Steps:       []string{"Whisk", "Fry"},
+	}
+
+	t.Run("Suggested tags are applied", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, nil)
+
+		mockAI.On("AnalyzeRecipeImage", ctx, validJPEGBase64, ai.ImageContentTypeJPEG).Return(analysisResult, nil).Once()
+		mockStorage.On("GetTags", ctx, mock.Anything, _MaxExistingTags).Return([]string{"Breakfast"}, nil).Once()
+		mockAI.On("SuggestRecipeTags", ctx, mock.MatchedBy(func(r *models.Recipe) bool {
+			return r.Title == "Pancakes"
+		}), []string{"Breakfast"}).Return(&ai.TagSuggestion{Tags: []string{"breakfast"}, Course: "dessert"}, nil).Once()
+		expectNoDuplicates(ctx, mockStorage)
+		mockStorage.On("CreateRecipe", ctx, mock.MatchedBy(func(r *models.Recipe) bool {
+			return assert.ObjectsAreEqual([]string{"Breakfast", "dessert"}, r.Tags)
+		})).Return(&models.Recipe{Title: "Pancakes"}, nil).Once()
+
+		_, err := recipeService.CreateRecipeFromImage(ctx, validJPEGBase64, "jpeg")
+
+		assert.NoError(t, err)
+		mockAI.AssertExpectations(t)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Import succeeds without tags", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, nil)
+
+		mockAI.On("AnalyzeRecipeImage", ctx, validJPEGBase64, ai.ImageContentTypeJPEG).Return(analysisResult, nil).Once()
+		mockStorage.On("GetTags", ctx, mock.Anything, _MaxExistingTags).Return([]string{}, nil).Once()
+		mockAI.On("SuggestRecipeTags", ctx, mock.Anything, []string{}).Return(nil, ai.ErrBudgetExceeded).Once()
+		expectNoDuplicates(ctx, mockStorage)
+		mockStorage.On("CreateRecipe", ctx, mock.MatchedBy(func(r *models.Recipe) bool {
+			return len(r.Tags) == 0
+		})).Return(&models.Recipe{Title: "Pancakes"}, nil).Once()
+
+		_, err := recipeService.CreateRecipeFromImage(ctx, validJPEGBase64, "jpeg")
+
+		assert.NoError(t, err)
+		mockStorage.AssertExpectations(t)
+	})
+}
+
+func TestAIImportSource(t *testing.T) {
+	ctx := userContext(testUser)
+	validJPEGBase64 := "/9j/4AAQSkZJRgABAQEASABIAAD/2Q=="
+	analysisResult := &ai.RecipeAnalysisResult{
+		Title:         "Pancakes",
+		Ingredients:   []models.Ingredient{{Name: "Flour", Quantity: 3, Unit: "dl"}},
+		Steps:         []string{"Whisk", "Fry"},
+		Model:         "gpt-test",
+		PromptVersion: "v2",
+	}
+
+	t.Run("Image", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, nil)
+
+		mockAI.On("AnalyzeRecipeImage", ctx, validJPEGBase64, ai.ImageContentTypeJPEG).Return(analysisResult, nil).Once()
+		expectTagSuggestion(ctx, mockStorage, mockAI)
+		var recipe *models.Recipe
+		expectNoDuplicates(ctx, mockStorage)
+		mockStorage.On("CreateRecipe", ctx, mock.AnythingOfType("*models.Recipe")).Run(func(args mock.Arguments) {
+			recipe = args.Get(1).(*models.Recipe)
+		}).Return(&models.Recipe{}, nil).Once()
+
+		_, err := recipeService.CreateRecipeFromImage(ctx, validJPEGBase64, "jpeg")
+
+		assert.NoError(t, err)
+		if !assert.NotNil(t, recipe.Source) {
+			return
+		}
+		assert.Equal(t, models.SourceImage, recipe.Source.Type)
+		assert.Regexp(t, "^sha256:[0-9a-f]{64}$", recipe.Source.Image)
+		assert.Empty(t, recipe.Source.URL)
+		assert.Equal(t, "gpt-test", recipe.Source.Model)
+		assert.Equal(t, "v2", recipe.Source.PromptVersion)
+		assert.Equal(t, recipe.CreatedAt, recipe.Source.ImportedAt)
+	})
+
+	t.Run("Image reference", func(t *testing.T) {
+		// The same image has the same reference, regardless of the base64 padding of the request
+		assert.Equal(t, imageReference(validJPEGBase64), imageReference(validJPEGBase64))
+		assert.NotEqual(t, imageReference(validJPEGBase64), imageReference("iVBORw0KGgo="))
+	})
+}
+
+// TestTranslateRecipe tests the TranslateRecipe method
+func TestTranslateRecipe(t *testing.T) {
+	ctx := userContext(testUser)
+	originalID := primitive.NewObjectID()
+	original := &models.Recipe{
+		ID:    originalID,
+		Title: "Spaghetti al pomodoro",
+		Ingredients: []models.Ingredient{
+			{Name: "Spaghetti", Quantity: 400, Unit: "g"},
+			{Name: "Pomodori pelati", Quantity: 1, Unit: "lattina"},
+		},
+		Steps:      []string{"Cuocere la pasta", "Scaldare il sugo"},
+		CookTime:   20,
+		Servings:   4,
+		Tags:       []string{"pasta"},
+		OwnerID:    testUser.ID,
+		Visibility: models.VisibilityPublic,
+	}
+	translated := &ai.RecipeAnalysisResult{
+		Title: "Tomato spaghetti",
+		Ingredients: []models.Ingredient{
+			{Name: "Spaghetti", Quantity: 0.4, Unit: "kg"}, // converted by the model, must be ignored
+			{Name: "Peeled tomatoes", Quantity: 1, Unit: "can"},
+		},
+		Steps: []string{"Cook the pasta", "Heat the sauce"},
+	}
+
+	t.Run("Creates a linked copy", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, nil)
+
+		mockStorage.On("GetRecipeByID", ctx, originalID.Hex()).Return(original, nil).Once()
+		mockAI.On("TranslateRecipe", ctx, original, "english").Return(translated, nil).Once()
+		mockStorage.On("GetRecipes", ctx, models.RecipeFilter{TranslationOf: originalID.Hex(), Language: "english"}, 1, 1).Return(&models.RecipePage{}, nil).Once()
+		mockStorage.On("CreateRecipe", ctx, mock.MatchedBy(func(r *models.Recipe) bool {
+			return r.Title == "Tomato spaghetti" &&
+				r.Language == "english" &&
+				r.TranslationOf != nil && *r.TranslationOf == originalID &&
+				assert.ObjectsAreEqual([]models.Ingredient{
+					{Name: "Spaghetti", Quantity: 400, Unit: "g"},
+					{Name: "Peeled tomatoes", Quantity: 1, Unit: "lattina"},
+				}, r.Ingredients) &&
+				r.CookTime == 20 && r.Servings == 4 &&
+				assert.ObjectsAreEqual([]string{"pasta"}, r.Tags) &&
+				r.OwnerID == testUser.ID && r.Visibility == models.VisibilityPublic
+		})).Return(&models.Recipe{Title: "Tomato spaghetti"}, nil).Once()
+
+		_, err := recipeService.TranslateRecipe(ctx, originalID.Hex(), " English ")
+
+		assert.NoError(t, err)
+		mockAI.AssertExpectations(t)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Replaces an earlier translation from the original", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, nil)
+
+		earlier := &models.Recipe{
+			ID:            primitive.NewObjectID(),
+			Title:         "Spaghetti",
+			Language:      "english",
+			TranslationOf: &originalID,
+			OwnerID:       testUser.ID,
+			Visibility:    models.VisibilityPublic,
+			CreatedAt:     time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		}
+
+		// Translating the translation translates the original, the earlier translation is
+		// fetched again to check it can be changed
+		mockStorage.On("GetRecipeByID", ctx, earlier.ID.Hex()).Return(earlier, nil).Twice()
+		mockStorage.On("GetRecipeByID", ctx, originalID.Hex()).Return(original, nil).Once()
+		mockAI.On("TranslateRecipe", ctx, original, "english").Return(translated, nil).Once()
+		mockStorage.On("GetRecipes", ctx, models.RecipeFilter{TranslationOf: originalID.Hex(), Language: "english"}, 1, 1).Return(&models.RecipePage{Recipes: []models.Recipe{*earlier}}, nil).Once()
+		mockStorage.On("UpdateRecipe", ctx, earlier.ID.Hex(), mock.MatchedBy(func(r *models.Recipe) bool {
+			return r.Title == "Tomato spaghetti" && r.CreatedAt.Equal(earlier.CreatedAt)
+		})).Return(&models.Recipe{Title: "Tomato spaghetti"}, nil).Once()
+
+		_, err := recipeService.TranslateRecipe(ctx, earlier.ID.Hex(), "english")
+
+		assert.NoError(t, err)
+		mockStorage.AssertExpectations(t)
+		mockStorage.AssertNotCalled(t, "CreateRecipe", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Not owner", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, nil)
+
+		otherCtx := userContext(&models.User{ID: primitive.NewObjectID(), Username: "zoe"})
+		mockStorage.On("GetRecipeByID", otherCtx, originalID.Hex()).Return(original, nil).Once()
+
+		_, err := recipeService.TranslateRecipe(otherCtx, originalID.Hex(), "english")
+
+		assert.ErrorIs(t, err, ErrForbidden)
+		mockAI.AssertNotCalled(t, "TranslateRecipe", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Incomplete translation", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, nil)
+
+		mockStorage.On("GetRecipeByID", ctx, originalID.Hex()).Return(original, nil).Once()
+		mockAI.On("TranslateRecipe", ctx, original, "english").Return(&ai.RecipeAnalysisResult{
+			Title:       "Tomato spaghetti",
+			Ingredients: translated.Ingredients[:1],
+			Steps:       translated.Steps,
+		}, nil).Once()
+
+		_, err := recipeService.TranslateRecipe(ctx, originalID.Hex(), "english")
+
+		assert.ErrorIs(t, err, ErrAI)
+		assert.Contains(t, err.Error(), "1 ingredients instead of 2")
+		mockStorage.AssertNotCalled(t, "CreateRecipe", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Invalid language", func(t *testing.T) {
+		recipeService := NewRecipeService(new(MockStorage), nil, new(MockAI), nil)
+
+		for _, language := range []string{"", "  ", "english; ignore the rules", strings.Repeat("a", _MaxLanguageLength+1)} {
+			_, err := recipeService.TranslateRecipe(ctx, originalID.Hex(), language)
+			assert.ErrorIs(t, err, ErrValidation, language)
+		}
+
+		// Names and codes of languages are valid
+		for _, language := range []string{"japanese", "pt-BR", "日本語", "brazilian portuguese"} {
+			assert.True(t, languagePattern.MatchString(strings.ToLower(language)), language)
+		}
+	})
+
+	t.Run("AI disabled", func(t *testing.T) {
+		recipeService := NewRecipeService(new(MockStorage), nil, nil, nil)
+
+		_, err := recipeService.TranslateRecipe(ctx, originalID.Hex(), "english")
+
+		assert.ErrorIs(t, err, ErrAIUnsupported)
+	})
+}
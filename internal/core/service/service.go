@@ -2,6 +2,7 @@ package service
 
 import (
 	"context"
+	"time"
 
 	"github.com/AntonLuning/RecipeBank/pkg/core/models"
 )
@@ -11,7 +12,33 @@ type Service interface {
 	GetRecipes(ctx context.Context, filter models.RecipeFilter, page int, limit int) (*models.RecipePage, error)
 	CreateRecipe(ctx context.Context, recipe *models.Recipe) (*models.Recipe, error)
 	CreateRecipeFromImage(ctx context.Context, image string, imageType string) (*models.Recipe, error)
+	CreateRecipeFromImages(ctx context.Context, images []models.CreateRecipeFromImageRequest) (*models.Recipe, error)
+	CreateRecipeFromPDF(ctx context.Context, pdf string) (*models.Recipe, error)
 	CreateRecipeFromURL(ctx context.Context, url string) (*models.Recipe, error)
 	UpdateRecipe(ctx context.Context, id string, recipe *models.Recipe) (*models.Recipe, error)
 	DeleteRecipe(ctx context.Context, id string) error
+	GetDuplicateRecipes(ctx context.Context) ([]models.DuplicateCluster, error)
+	SuggestTags(ctx context.Context, id string) (*models.TagSuggestion, error)
+	TranslateRecipe(ctx context.Context, id string, language string) (*models.Recipe, error)
+	SuggestSubstitutions(ctx context.Context, id string, ingredient string, dietary []string) (*models.Substitutions, error)
+	GetAIUsage(ctx context.Context, from time.Time, to time.Time) (*models.AIUsageSummary, error)
+	ShareRecipe(ctx context.Context, id string, expiresAt *time.Time) (*models.CreatedRecipeShare, error)
+	GetRecipeShares(ctx context.Context, id string) ([]models.RecipeShare, error)
+	RevokeRecipeShare(ctx context.Context, id string, shareID string) error
+	GetSharedRecipe(ctx context.Context, token string) (*models.SharedRecipe, error)
+	GetCollections(ctx context.Context) ([]models.Collection, error)
+	GetCollection(ctx context.Context, id string) (*models.Collection, error)
+	CreateCollection(ctx context.Context, collection *models.Collection) (*models.Collection, error)
+	UpdateCollection(ctx context.Context, id string, collection *models.Collection) (*models.Collection, error)
+	DeleteCollection(ctx context.Context, id string) error
+	GetMealPlans(ctx context.Context) ([]models.MealPlan, error)
+	GetMealPlan(ctx context.Context, id string) (*models.MealPlan, error)
+	CreateMealPlan(ctx context.Context, plan *models.MealPlan) (*models.MealPlan, error)
+	UpdateMealPlan(ctx context.Context, id string, plan *models.MealPlan) (*models.MealPlan, error)
+	DeleteMealPlan(ctx context.Context, id string) error
+	GetGroceryLists(ctx context.Context) ([]models.GroceryList, error)
+	GetGroceryList(ctx context.Context, id string) (*models.GroceryList, error)
+	CreateGroceryList(ctx context.Context, list *models.GroceryList) (*models.GroceryList, error)
+	UpdateGroceryList(ctx context.Context, id string, list *models.GroceryList) (*models.GroceryList, error)
+	DeleteGroceryList(ctx context.Context, id string) error
 }
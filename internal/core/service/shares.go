@@ -0,0 +1,98 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+)
+
+const (
+	// Prefix of the tokens of share links
+	ShareTokenPrefix = "shr_"
+	// Length of the start of a token that is stored to tell share links apart
+	_ShareDisplayLength = len(ShareTokenPrefix) + 8
+)
+
+// ShareRecipe creates a public link to the recipe, which anyone with the token can view without
+// logging in until it expires (never if expiresAt is nil) or is revoked. Only those who can change
+// the recipe can share it. The token is only returned here, only its hash is stored.
+func (s *RecipeService) ShareRecipe(ctx context.Context, id string, expiresAt *time.Time) (*models.CreatedRecipeShare, error) {
+	recipe, err := s.getEditableRecipe(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return nil, fmt.Errorf("%w: expiry must be in the future", ErrValidation)
+	}
+
+	token, err := newToken(ShareTokenPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate share token: %w", err)
+	}
+
+	share, err := s.storage.CreateRecipeShare(ctx, &models.RecipeShare{
+		RecipeID:  recipe.ID,
+		Prefix:    token[:_ShareDisplayLength],
+		TokenHash: hashToken(token),
+		CreatedBy: viewerFromContext(ctx).UserID,
+		ExpiresAt: expiresAt,
+		CreatedAt: now,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create share link: %w", err)
+	}
+
+	return &models.CreatedRecipeShare{RecipeShare: *share, Token: token}, nil
+}
+
+// GetRecipeShares returns the share links of the recipe, including revoked and expired links.
+// Only those who can change the recipe can see them.
+func (s *RecipeService) GetRecipeShares(ctx context.Context, id string) ([]models.RecipeShare, error) {
+	recipe, err := s.getEditableRecipe(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	shares, err := s.storage.GetRecipeShares(ctx, recipe.ID.Hex())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get share links: %w", err)
+	}
+
+	return shares, nil
+}
+
+// RevokeRecipeShare revokes a share link of the recipe, so it can no longer be used
+func (s *RecipeService) RevokeRecipeShare(ctx context.Context, id string, shareID string) error {
+	recipe, err := s.getEditableRecipe(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if err := s.storage.RevokeRecipeShare(ctx, shareID, recipe.ID.Hex()); err != nil {
+		return fmt.Errorf("failed to revoke share link: %w", err)
+	}
+
+	return nil
+}
+
+// GetSharedRecipe returns the read-only view of the recipe of a share link. It needs no user, the
+// token grants access to the recipe regardless of its visibility and household.
+func (s *RecipeService) GetSharedRecipe(ctx context.Context, token string) (*models.SharedRecipe, error) {
+	token = strings.TrimSpace(token)
+	if !strings.HasPrefix(token, ShareTokenPrefix) {
+		return nil, fmt.Errorf("%w: shared recipe", storage.ErrNotFound)
+	}
+
+	recipe, err := s.storage.GetSharedRecipe(ctx, hashToken(token), time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get shared recipe: %w", err)
+	}
+
+	return models.NewSharedRecipe(recipe), nil
+}
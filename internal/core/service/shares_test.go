@@ -0,0 +1,131 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestShareRecipe(t *testing.T) {
+	ctx := userContext(testUser)
+	recipeID := primitive.NewObjectID()
+	recipe := &models.Recipe{ID: recipeID, Title: "Pancakes", OwnerID: testUser.ID, Visibility: models.VisibilityPrivate}
+
+	t.Run("Success", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+		expiresAt := time.Now().Add(time.Hour)
+
+		var stored *models.RecipeShare
+		mockStorage.On("GetRecipeByID", ctx, recipeID.Hex()).Return(recipe, nil).Once()
+		mockStorage.On("CreateRecipeShare", ctx, mock.AnythingOfType("*models.RecipeShare")).
+			Run(func(args mock.Arguments) { stored = args.Get(1).(*models.RecipeShare) }).
+			Return(&models.RecipeShare{ID: primitive.NewObjectID(), RecipeID: recipeID}, nil).Once()
+
+		share, err := recipeService.ShareRecipe(ctx, recipeID.Hex(), &expiresAt)
+
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(share.Token, ShareTokenPrefix))
+		assert.Equal(t, recipeID, stored.RecipeID)
+		assert.Equal(t, testUser.ID, stored.CreatedBy)
+		assert.Equal(t, &expiresAt, stored.ExpiresAt)
+		assert.Equal(t, share.Token[:_ShareDisplayLength], stored.Prefix)
+		assert.Equal(t, hashToken(share.Token), stored.TokenHash)
+		mockStorage.AssertExpectations(t)
+	})
+
+	t.Run("Expiry in the past", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+		expiresAt := time.Now().Add(-time.Minute)
+
+		mockStorage.On("GetRecipeByID", ctx, recipeID.Hex()).Return(recipe, nil).Once()
+
+		_, err := recipeService.ShareRecipe(ctx, recipeID.Hex(), &expiresAt)
+
+		assert.ErrorIs(t, err, ErrValidation)
+		mockStorage.AssertNotCalled(t, "CreateRecipeShare", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Public recipe of another user", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+		other := &models.Recipe{ID: recipeID, OwnerID: primitive.NewObjectID(), Visibility: models.VisibilityPublic}
+
+		mockStorage.On("GetRecipeByID", ctx, recipeID.Hex()).Return(other, nil).Once()
+
+		_, err := recipeService.ShareRecipe(ctx, recipeID.Hex(), nil)
+
+		assert.ErrorIs(t, err, ErrForbidden)
+		mockStorage.AssertNotCalled(t, "CreateRecipeShare", mock.Anything, mock.Anything)
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+		shareID := primitive.NewObjectID().Hex()
+
+		mockStorage.On("GetRecipeByID", ctx, recipeID.Hex()).Return(recipe, nil).Once()
+		mockStorage.On("RevokeRecipeShare", ctx, shareID, recipeID.Hex()).Return(nil).Once()
+
+		err := recipeService.RevokeRecipeShare(ctx, recipeID.Hex(), shareID)
+
+		assert.NoError(t, err)
+		mockStorage.AssertExpectations(t)
+	})
+}
+
+func TestGetSharedRecipe(t *testing.T) {
+	// Share links need no user
+	ctx := context.Background()
+	recipe := &models.Recipe{
+		ID:          primitive.NewObjectID(),
+		Title:       "Pancakes",
+		Steps:       []string{"Whisk", "Fry"},
+		OwnerID:     testUser.ID,
+		HouseholdID: &primitive.ObjectID{},
+		Visibility:  models.VisibilityPrivate,
+	}
+
+	t.Run("Success", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+		token := ShareTokenPrefix + "secret"
+
+		mockStorage.On("GetSharedRecipe", ctx, hashToken(token), mock.AnythingOfType("time.Time")).Return(recipe, nil).Once()
+
+		shared, err := recipeService.GetSharedRecipe(ctx, token)
+
+		require.NoError(t, err)
+		assert.Equal(t, &models.SharedRecipe{Title: "Pancakes", Steps: []string{"Whisk", "Fry"}}, shared)
+	})
+
+	t.Run("Not a share token", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+
+		_, err := recipeService.GetSharedRecipe(ctx, "hhi_secret")
+
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+		mockStorage.AssertNotCalled(t, "GetSharedRecipe", mock.Anything, mock.Anything, mock.Anything)
+	})
+
+	t.Run("Revoked or expired", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+
+		mockStorage.On("GetSharedRecipe", ctx, mock.Anything, mock.Anything).Return(nil, storage.ErrNotFound).Once()
+
+		_, err := recipeService.GetSharedRecipe(ctx, ShareTokenPrefix+"revoked")
+
+		assert.ErrorIs(t, err, storage.ErrNotFound)
+	})
+}
@@ -0,0 +1,404 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+)
+
+const (
+	SubstitutionSourceAI    = "ai"
+	SubstitutionSourceTable = "table"
+)
+
+const (
+	DietVegan      = "vegan"
+	DietVegetarian = "vegetarian"
+	DietGlutenFree = "gluten-free"
+	DietNutFree    = "nut-free"
+	DietDairyFree  = "dairy-free"
+)
+
+// Dietary constraints that substitutes can be asked to meet
+var supportedDiets = []string{DietVegan, DietVegetarian, DietGlutenFree, DietNutFree, DietDairyFree}
+
+// Units of ingredients that are counted (e.g. "3 eggs")
+var countUnits = []string{"", "st", "pc", "pcs", "piece", "pieces", "whole", "clove", "cloves"}
+
+// tableSubstitute is an ingredient of a substitution in the built-in table
+type tableSubstitute struct {
+	name string
+	// Quantity of the substitute per quantity of the original ingredient
+	ratio float32
+	// Unit of the substitute per piece of a counted original ingredient, e.g. 1 tbsp per egg.
+	// Empty to keep the unit of the original ingredient.
+	unit string
+}
+
+type tableOption struct {
+	substitutes []tableSubstitute
+	notes       string
+	// Dietary constraints that all the substitutes meet
+	diets []string
+}
+
+type tableEntry struct {
+	// Names of the ingredient, matched as the last words of the ingredient name
+	names   []string
+	options []tableOption
+}
+
+// Substitutes that meet all the supported dietary constraints
+var allDiets = supportedDiets
+
+// substitutionTable is the built-in, deterministic substitution table that is used when AI is
+// not enabled. It covers common ingredients only.
+var substitutionTable = []tableEntry{
+	{
+		names: []string{"butter"},
+		options: []tableOption{
+			{[]tableSubstitute{{"vegetable oil", 0.75, ""}}, "Use 3/4 of the amount, best for sautéing and cakes", allDiets},
+			{[]tableSubstitute{{"plant-based butter", 1, ""}}, "Works in most recipes, check the label for allergens", []string{DietVegan, DietVegetarian, DietGlutenFree, DietDairyFree}},
+			{[]tableSubstitute{{"coconut oil", 1, ""}}, "Adds a mild coconut flavor", []string{DietVegan, DietVegetarian, DietGlutenFree, DietDairyFree}},
+		},
+	},
+	{
+		names: []string{"egg", "eggs"},
+		options: []tableOption{
+			{[]tableSubstitute{{"ground flaxseed", 1, "tbsp"}, {"water", 3, "tbsp"}}, "Mix and let thicken for 5 minutes, for binding in baking", allDiets},
+			{[]tableSubstitute{{"mashed banana", 60, "g"}}, "Adds sweetness, for cakes and muffins", allDiets},
+			{[]tableSubstitute{{"unsweetened applesauce", 60, "g"}}, "Makes baked goods moist and dense", allDiets},
+		},
+	},
+	{
+		names: []string{"milk"},
+		options: []tableOption{
+			{[]tableSubstitute{{"soy milk", 1, ""}}, "Closest to milk in protein, works in baking and sauces", allDiets},
+			{[]tableSubstitute{{"oat milk", 1, ""}}, "Mild and slightly sweet, use certified oats if gluten is a concern", []string{DietVegan, DietVegetarian, DietNutFree, DietDairyFree}},
+			{[]tableSubstitute{{"almond milk", 1, ""}}, "Thinner than milk, best in baking and smoothies", []string{DietVegan, DietVegetarian, DietGlutenFree, DietDairyFree}},
+		},
+	},
+	{
+		names: []string{"buttermilk"},
+		options: []tableOption{
+			{[]tableSubstitute{{"milk", 1, ""}, {"lemon juice", 0.0625, ""}}, "Stir and let stand for 5 minutes", []string{DietVegetarian, DietGlutenFree, DietNutFree}},
+			{[]tableSubstitute{{"soy milk", 1, ""}, {"lemon juice", 0.0625, ""}}, "Stir and let stand for 5 minutes", allDiets},
+		},
+	},
+	{
+		names: []string{"cream", "heavy cream", "whipping cream"},
+		options: []tableOption{
+			{[]tableSubstitute{{"milk", 0.75, ""}, {"butter", 0.25, ""}}, "Melt the butter into the milk, for sauces and soups but does not whip", []string{DietVegetarian, DietGlutenFree, DietNutFree}},
+			{[]tableSubstitute{{"coconut cream", 1, ""}}, "Whips softer than cream and adds a coconut flavor", []string{DietVegan, DietVegetarian, DietGlutenFree, DietDairyFree}},
+			{[]tableSubstitute{{"oat cream", 1, ""}}, "For sauces and soups, does not whip", []string{DietVegan, DietVegetarian, DietNutFree, DietDairyFree}},
+		},
+	},
+	{
+		names: []string{"sour cream", "crème fraîche", "creme fraiche"},
+		options: []tableOption{
+			{[]tableSubstitute{{"greek yogurt", 1, ""}}, "Tangier, add it off the heat so it does not split", []string{DietVegetarian, DietGlutenFree, DietNutFree}},
+			{[]tableSubstitute{{"plant-based yogurt", 1, ""}}, "Use an unsweetened one", []string{DietVegan, DietVegetarian, DietGlutenFree, DietDairyFree}},
+		},
+	},
+	{
+		names: []string{"yogurt", "yoghurt"},
+		options: []tableOption{
+			{[]tableSubstitute{{"sour cream", 1, ""}}, "Richer and less tangy", []string{DietVegetarian, DietGlutenFree, DietNutFree}},
+			{[]tableSubstitute{{"plant-based yogurt", 1, ""}}, "Use an unsweetened one", []string{DietVegan, DietVegetarian, DietGlutenFree, DietDairyFree}},
+		},
+	},
+	{
+		names: []string{"flour", "all-purpose flour", "plain flour", "wheat flour"},
+		options: []tableOption{
+			{[]tableSubstitute{{"gluten-free flour blend", 1, ""}}, "Add 1/4 tsp xanthan gum per cup if the blend has none", allDiets},
+			{[]tableSubstitute{{"almond flour", 1, ""}}, "Denser and richer, best in cookies and cakes", []string{DietVegan, DietVegetarian, DietGlutenFree, DietDairyFree}},
+		},
+	},
+	{
+		names: []string{"cornstarch", "corn starch", "cornflour", "maizena"},
+		options: []tableOption{
+			{[]tableSubstitute{{"potato starch", 1, ""}}, "Thickens the same way, do not boil for long", allDiets},
+			{[]tableSubstitute{{"flour", 2, ""}}, "Cook a few minutes longer to remove the raw taste", []string{DietVegan, DietVegetarian, DietNutFree, DietDairyFree}},
+		},
+	},
+	{
+		names: []string{"breadcrumbs", "bread crumbs"},
+		options: []tableOption{
+			{[]tableSubstitute{{"rolled oats", 1, ""}}, "Pulse in a blender first, use certified oats if gluten is a concern", []string{DietVegan, DietVegetarian, DietNutFree, DietDairyFree}},
+			{[]tableSubstitute{{"ground almonds", 1, ""}}, "Browns faster", []string{DietVegan, DietVegetarian, DietGlutenFree, DietDairyFree}},
+		},
+	},
+	{
+		names: []string{"soy sauce"},
+		options: []tableOption{
+			{[]tableSubstitute{{"tamari", 1, ""}}, "Gluten-free when labeled so", allDiets},
+			{[]tableSubstitute{{"coconut aminos", 1, ""}}, "Sweeter and less salty", []string{DietVegan, DietVegetarian, DietGlutenFree, DietDairyFree}},
+		},
+	},
+	{
+		names: []string{"honey"},
+		options: []tableOption{
+			{[]tableSubstitute{{"maple syrup", 1, ""}}, "Slightly thinner, with a maple flavor", allDiets},
+			{[]tableSubstitute{{"agave syrup", 1, ""}}, "Sweeter, use a little less to taste", allDiets},
+		},
+	},
+	{
+		names: []string{"sugar", "white sugar", "granulated sugar"},
+		options: []tableOption{
+			{[]tableSubstitute{{"brown sugar", 1, ""}}, "Adds a light caramel flavor and moisture", allDiets},
+			{[]tableSubstitute{{"honey", 0.75, ""}}, "Reduce the other liquids slightly", []string{DietVegetarian, DietGlutenFree, DietNutFree, DietDairyFree}},
+		},
+	},
+	{
+		names: []string{"brown sugar"},
+		options: []tableOption{
+			{[]tableSubstitute{{"sugar", 1, ""}}, "Add 1 tbsp molasses per cup for the flavor", allDiets},
+		},
+	},
+	{
+		names: []string{"baking powder"},
+		options: []tableOption{
+			{[]tableSubstitute{{"baking soda", 0.25, ""}, {"cream of tartar", 0.5, ""}}, "Mix just before using", allDiets},
+		},
+	},
+	{
+		names: []string{"lemon juice"},
+		options: []tableOption{
+			{[]tableSubstitute{{"lime juice", 1, ""}}, "Slightly more bitter", allDiets},
+			{[]tableSubstitute{{"white wine vinegar", 0.5, ""}}, "For acidity only, without the citrus flavor", allDiets},
+		},
+	},
+	{
+		names: []string{"white wine", "red wine"},
+		options: []tableOption{
+			{[]tableSubstitute{{"vegetable stock", 1, ""}}, "Add a splash of vinegar for acidity", []string{DietVegan, DietVegetarian, DietNutFree, DietDairyFree}},
+		},
+	},
+	{
+		names: []string{"vinegar", "white wine vinegar", "red wine vinegar", "apple cider vinegar"},
+		options: []tableOption{
+			{[]tableSubstitute{{"lemon juice", 1, ""}}, "Adds a citrus flavor", allDiets},
+		},
+	},
+	{
+		names: []string{"parmesan", "parmigiano", "parmigiano reggiano"},
+		options: []tableOption{
+			{[]tableSubstitute{{"pecorino romano", 1, ""}}, "Saltier, reduce the salt", []string{DietGlutenFree, DietNutFree}},
+			{[]tableSubstitute{{"nutritional yeast", 0.5, ""}}, "Nutty and cheesy flavor", allDiets},
+		},
+	},
+	{
+		names: []string{"pine nuts"},
+		options: []tableOption{
+			{[]tableSubstitute{{"sunflower seeds", 1, ""}}, "Toast them first", allDiets},
+		},
+	},
+	{
+		names: []string{"nuts", "almonds", "walnuts", "pecans", "hazelnuts", "cashews", "peanuts"},
+		options: []tableOption{
+			{[]tableSubstitute{{"pumpkin seeds", 1, ""}}, "Toast them for more flavor", allDiets},
+			{[]tableSubstitute{{"sunflower seeds", 1, ""}}, "Toast them for more flavor", allDiets},
+		},
+	},
+	{
+		names: []string{"garlic"},
+		options: []tableOption{
+			{[]tableSubstitute{{"garlic powder", 0.125, "tsp"}}, "Add it with the other spices", allDiets},
+		},
+	},
+	{
+		names: []string{"mayonnaise"},
+		options: []tableOption{
+			{[]tableSubstitute{{"greek yogurt", 1, ""}}, "Lighter and tangier", []string{DietVegetarian, DietGlutenFree, DietNutFree}},
+		},
+	},
+}
+
+// SuggestSubstitutions suggests substitutes for an ingredient of a recipe that meet the dietary
+// constraints. The substitutes are suggested by AI, or taken from the built-in substitution table
+// when AI is not enabled.
+func (s *RecipeService) SuggestSubstitutions(ctx context.Context, id string, ingredient string, dietary []string) (*models.Substitutions, error) {
+	if strings.TrimSpace(ingredient) == "" {
+		return nil, fmt.Errorf("%w: ingredient is required", ErrValidation)
+	}
+
+	diets, err := normalizeDiets(dietary)
+	if err != nil {
+		return nil, err
+	}
+
+	recipe, err := s.GetRecipe(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	original, ok := findIngredient(recipe.Ingredients, ingredient)
+	if !ok {
+		return nil, fmt.Errorf("%w: ingredient %s is not in the recipe", ErrValidation, strings.TrimSpace(ingredient))
+	}
+
+	if s.ai == nil {
+		return &models.Substitutions{
+			Ingredient: original,
+			Options:    tableSubstitutions(original, diets),
+			Source:     SubstitutionSourceTable,
+		}, nil
+	}
+
+	result, err := s.ai.SuggestSubstitutions(ctx, recipe, original, diets)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to suggest substitutions: %w", ErrAI, err)
+	}
+
+	options := []models.SubstitutionOption{}
+	for _, option := range result.Options {
+		if len(option.Ingredients) > 0 {
+			options = append(options, option)
+		}
+	}
+
+	return &models.Substitutions{
+		Ingredient: original,
+		Options:    options,
+		Source:     SubstitutionSourceAI,
+	}, nil
+}
+
+// normalizeDiets validates, lowercases and deduplicates the dietary constraints
+func normalizeDiets(dietary []string) ([]string, error) {
+	diets := []string{}
+	for _, diet := range dietary {
+		diet = normalizeTag(diet)
+		if !slices.Contains(supportedDiets, diet) {
+			return nil, fmt.Errorf("%w: dietary constraint %s is not supported, use one of %s", ErrValidation, diet, strings.Join(supportedDiets, ", "))
+		}
+		if !slices.Contains(diets, diet) {
+			diets = append(diets, diet)
+		}
+	}
+	return diets, nil
+}
+
+// findIngredient finds the ingredient by name, preferring an exact match over one on the head noun
+// (e.g. "butter" finds "unsalted butter" but not "peanut butter")
+func findIngredient(ingredients []models.Ingredient, name string) (models.Ingredient, bool) {
+	name = normalizeTag(name)
+
+	for _, ingredient := range ingredients {
+		if normalizeTag(ingredient.Name) == name {
+			return ingredient, true
+		}
+	}
+	for _, ingredient := range ingredients {
+		if matchesHead(headWords(normalizeTag(ingredient.Name)), strings.Fields(name)) {
+			return ingredient, true
+		}
+	}
+
+	return models.Ingredient{}, false
+}
+
+// tableSubstitutions returns the options of the substitution table for the ingredient that meet
+// all the dietary constraints, with the quantities adjusted to the ingredient
+func tableSubstitutions(ingredient models.Ingredient, diets []string) []models.SubstitutionOption {
+	options := []models.SubstitutionOption{}
+
+	entry, ok := findTableEntry(normalizeTag(ingredient.Name))
+	if !ok {
+		return options
+	}
+
+	counted := slices.Contains(countUnits, normalizeTag(ingredient.Unit))
+
+	for _, option := range entry.options {
+		if !containsAll(option.diets, diets) {
+			continue
+		}
+
+		substitutes := make([]models.Ingredient, 0, len(option.substitutes))
+		for _, substitute := range option.substitutes {
+			unit := ingredient.Unit
+			quantity := ingredient.Quantity * substitute.ratio
+			if substitute.unit != "" {
+				unit = substitute.unit
+				if !counted {
+					// The ratio is per piece, the amount is unknown for other units
+					quantity = 0
+				}
+			}
+
+			substitutes = append(substitutes, models.Ingredient{
+				Name:     substitute.name,
+				Quantity: float32(math.Round(float64(quantity)*100) / 100),
+				Unit:     unit,
+			})
+		}
+
+		options = append(options, models.SubstitutionOption{Ingredients: substitutes, Notes: option.notes})
+	}
+
+	return options
+}
+
+// Words before the name of an ingredient that make it another ingredient, e.g. peanut butter is
+// not butter and coconut milk is not milk
+var otherIngredientModifiers = []string{
+	"almond", "apple", "cacao", "cashew", "chickpea", "cocoa", "coconut", "hazelnut", "ice", "nut",
+	"oat", "peanut", "pistachio", "rice", "shea", "soy", "walnut",
+}
+
+// findTableEntry finds the entry of the substitution table for the ingredient name, preferring
+// the longest matching name (e.g. "sour cream" over "cream"). Names are matched on the head noun
+// of the ingredient, so "cream cheese" is not cream.
+func findTableEntry(name string) (tableEntry, bool) {
+	var match tableEntry
+	var matchLength int
+
+	words := headWords(name)
+	for _, entry := range substitutionTable {
+		for _, entryName := range entry.names {
+			if len(entryName) > matchLength && matchesHead(words, strings.Fields(entryName)) {
+				match = entry
+				matchLength = len(entryName)
+			}
+		}
+	}
+
+	return match, matchLength > 0
+}
+
+// headWords returns the words of an ingredient name up to its head noun, without the details
+// after a comma or in parentheses (e.g. "butter, softened") and a counted unit (e.g. "garlic
+// cloves")
+func headWords(name string) []string {
+	name, _, _ = strings.Cut(name, ",")
+	name, _, _ = strings.Cut(name, "(")
+	words := strings.Fields(name)
+	if len(words) > 1 && slices.Contains(countUnits, words[len(words)-1]) {
+		words = words[:len(words)-1]
+	}
+	return words
+}
+
+// matchesHead reports whether the ingredient words end with the name, without a word before it
+// that makes it another ingredient
+func matchesHead(words []string, name []string) bool {
+	start := len(words) - len(name)
+	if start < 0 || !slices.Equal(words[start:], name) {
+		return false
+	}
+	return start == 0 || !slices.Contains(otherIngredientModifiers, words[start-1])
+}
+
+func containsAll(values []string, required []string) bool {
+	for _, value := range required {
+		if !slices.Contains(values, value) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"testing"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/ai"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestSuggestSubstitutions(t *testing.T) {
+	ctx := userContext(testUser)
+	recipeID := primitive.NewObjectID()
+	recipe := &models.Recipe{
+		ID:    recipeID,
+		Title: "Pancakes",
+		Ingredients: []models.Ingredient{
+			{Name: "Flour", Quantity: 3, Unit: "dl"},
+			{Name: "Milk", Quantity: 6, Unit: "dl"},
+			{Name: "Eggs", Quantity: 3},
+			{Name: "Unsalted butter", Quantity: 50, Unit: "g"},
+		},
+		Steps:   []string{"Whisk", "Fry"},
+		OwnerID: testUser.ID,
+	}
+
+	t.Run("AI", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, nil)
+
+		mockStorage.On("GetRecipeByID", ctx, recipeID.Hex()).Return(recipe, nil).Once()
+		mockAI.On("SuggestSubstitutions", ctx, recipe, recipe.Ingredients[1], []string{DietVegan}).Return(&ai.SubstitutionResult{
+			Options: []models.SubstitutionOption{
+				{Ingredients: []models.Ingredient{{Name: "Oat milk", Quantity: 6, Unit: "dl"}}, Notes: "Slightly sweeter"},
+				{Ingredients: []models.Ingredient{}}, // empty options are dropped
+			},
+		}, nil).Once()
+
+		substitutions, err := recipeService.SuggestSubstitutions(ctx, recipeID.Hex(), " milk ", []string{"Vegan", "vegan"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, &models.Substitutions{
+			Ingredient: recipe.Ingredients[1],
+			Options: []models.SubstitutionOption{
+				{Ingredients: []models.Ingredient{{Name: "Oat milk", Quantity: 6, Unit: "dl"}}, Notes: "Slightly sweeter"},
+			},
+			Source: SubstitutionSourceAI,
+		}, substitutions)
+		mockAI.AssertExpectations(t)
+	})
+
+	t.Run("AI error", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		mockAI := new(MockAI)
+		recipeService := NewRecipeService(mockStorage, nil, mockAI, nil)
+
+		mockStorage.On("GetRecipeByID", ctx, recipeID.Hex()).Return(recipe, nil).Once()
+		mockAI.On("SuggestSubstitutions", ctx, recipe, recipe.Ingredients[0], []string{}).Return(nil, ai.ErrTimeout).Once()
+
+		_, err := recipeService.SuggestSubstitutions(ctx, recipeID.Hex(), "flour", nil)
+
+		assert.ErrorIs(t, err, ErrAI)
+		assert.ErrorIs(t, err, ai.ErrTimeout)
+	})
+
+	t.Run("Table when AI is disabled", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+
+		mockStorage.On("GetRecipeByID", ctx, recipeID.Hex()).Return(recipe, nil)
+
+		// Partial names match, quantities are scaled and units kept
+		substitutions, err := recipeService.SuggestSubstitutions(ctx, recipeID.Hex(), "butter", []string{DietNutFree})
+		assert.NoError(t, err)
+		assert.Equal(t, SubstitutionSourceTable, substitutions.Source)
+		assert.Equal(t, recipe.Ingredients[3], substitutions.Ingredient)
+		assert.Equal(t, []models.SubstitutionOption{
+			{Ingredients: []models.Ingredient{{Name: "vegetable oil", Quantity: 37.5, Unit: "g"}}, Notes: "Use 3/4 of the amount, best for sautéing and cakes"},
+		}, substitutions.Options)
+
+		// Counted ingredients use the unit of the substitute per piece
+		substitutions, err = recipeService.SuggestSubstitutions(ctx, recipeID.Hex(), "eggs", []string{DietVegan})
+		assert.NoError(t, err)
+		assert.Len(t, substitutions.Options, 3)
+		assert.Equal(t, []models.Ingredient{
+			{Name: "ground flaxseed", Quantity: 3, Unit: "tbsp"},
+			{Name: "water", Quantity: 9, Unit: "tbsp"},
+		}, substitutions.Options[0].Ingredients)
+
+		// Unknown ingredients have no options
+		recipe := &models.Recipe{ID: recipeID, Title: "Risotto", Ingredients: []models.Ingredient{{Name: "Saffron", Quantity: 1, Unit: "g"}}, OwnerID: testUser.ID}
+		mockStorage.On("GetRecipeByID", ctx, "risotto").Return(recipe, nil).Once()
+		substitutions, err = recipeService.SuggestSubstitutions(ctx, "risotto", "saffron", nil)
+		assert.NoError(t, err)
+		assert.Empty(t, substitutions.Options)
+	})
+
+	t.Run("Validation errors", func(t *testing.T) {
+		mockStorage := new(MockStorage)
+		recipeService := NewRecipeService(mockStorage, nil, nil, nil)
+
+		mockStorage.On("GetRecipeByID", ctx, recipeID.Hex()).Return(recipe, nil)
+
+		_, err := recipeService.SuggestSubstitutions(ctx, recipeID.Hex(), "", nil)
+		assert.ErrorIs(t, err, ErrValidation)
+
+		_, err = recipeService.SuggestSubstitutions(ctx, recipeID.Hex(), "milk", []string{"keto"})
+		assert.ErrorIs(t, err, ErrValidation)
+		assert.Contains(t, err.Error(), "keto")
+
+		_, err = recipeService.SuggestSubstitutions(ctx, recipeID.Hex(), "saffron", nil)
+		assert.ErrorIs(t, err, ErrValidation)
+		assert.Contains(t, err.Error(), "not in the recipe")
+	})
+}
+
+func TestTableSubstitutions(t *testing.T) {
+	tests := []struct {
+		name       string
+		ingredient models.Ingredient
+		diets      []string
+		want       []string // first ingredient of each option
+	}{
+		{"longest name wins", models.Ingredient{Name: "sour cream", Quantity: 2, Unit: "dl"}, nil, []string{"greek yogurt", "plant-based yogurt"}},
+		{"whole words only", models.Ingredient{Name: "buttermilk", Quantity: 2, Unit: "dl"}, nil, []string{"milk", "soy milk"}},
+		{"all constraints are met", models.Ingredient{Name: "milk", Quantity: 1, Unit: "l"}, []string{DietGlutenFree, DietNutFree}, []string{"soy milk"}},
+		{"all dietary constraints", models.Ingredient{Name: "parmesan", Quantity: 50, Unit: "g"}, []string{DietVegetarian, DietNutFree, DietGlutenFree, DietDairyFree, DietVegan}, []string{"nutritional yeast"}},
+		{"per piece ratio with another unit", models.Ingredient{Name: "egg", Quantity: 100, Unit: "g"}, nil, []string{"ground flaxseed", "mashed banana", "unsweetened applesauce"}},
+		{"head noun", models.Ingredient{Name: "Unsalted butter", Quantity: 50, Unit: "g"}, nil, []string{"vegetable oil", "plant-based butter", "coconut oil"}},
+		{"details after the name", models.Ingredient{Name: "butter, softened (room temperature)", Quantity: 50, Unit: "g"}, nil, []string{"vegetable oil", "plant-based butter", "coconut oil"}},
+		{"counted unit after the name", models.Ingredient{Name: "garlic cloves", Quantity: 2}, nil, []string{"garlic powder"}},
+		{"peanut butter is not butter", models.Ingredient{Name: "peanut butter", Quantity: 2, Unit: "tbsp"}, []string{DietNutFree}, []string{}},
+		{"almond butter is not butter", models.Ingredient{Name: "almond butter", Quantity: 2, Unit: "tbsp"}, nil, []string{}},
+		{"coconut milk is not milk", models.Ingredient{Name: "coconut milk", Quantity: 4, Unit: "dl"}, nil, []string{}},
+		{"almond milk is not milk", models.Ingredient{Name: "unsweetened almond milk", Quantity: 2, Unit: "dl"}, nil, []string{}},
+		{"cream cheese is not cream", models.Ingredient{Name: "cream cheese", Quantity: 200, Unit: "g"}, nil, []string{}},
+		{"ice cream is not cream", models.Ingredient{Name: "vanilla ice cream", Quantity: 1, Unit: "l"}, nil, []string{}},
+		{"cream of tartar is not cream", models.Ingredient{Name: "cream of tartar", Quantity: 1, Unit: "tsp"}, nil, []string{}},
+		{"almond flour is not flour", models.Ingredient{Name: "almond flour", Quantity: 2, Unit: "dl"}, nil, []string{}},
+		{"egg noodles are not eggs", models.Ingredient{Name: "egg noodles", Quantity: 200, Unit: "g"}, nil, []string{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			options := tableSubstitutions(tt.ingredient, tt.diets)
+
+			names := []string{}
+			for _, option := range options {
+				names = append(names, option.Ingredients[0].Name)
+			}
+			assert.Equal(t, tt.want, names)
+		})
+	}
+
+	// The amount of a per piece substitute is unknown for other units
+	options := tableSubstitutions(models.Ingredient{Name: "egg", Quantity: 100, Unit: "g"}, nil)
+	assert.Equal(t, models.Ingredient{Name: "mashed banana", Unit: "g"}, options[1].Ingredients[0])
+}
@@ -0,0 +1,236 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/tracing"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// TracedService records a span for every call to another Service, as the parent of the spans of
+// the storage, page fetches and AI calls it makes
+type TracedService struct {
+	next Service
+}
+
+func NewTracedService(next Service) Service {
+	return &TracedService{next: next}
+}
+
+func (s *TracedService) GetRecipe(ctx context.Context, id string) (*models.Recipe, error) {
+	return traced(ctx, "GetRecipe", func(ctx context.Context) (*models.Recipe, error) {
+		return s.next.GetRecipe(ctx, id)
+	}, recipeIDAttribute(id))
+}
+
+func (s *TracedService) GetRecipes(ctx context.Context, filter models.RecipeFilter, page int, limit int) (*models.RecipePage, error) {
+	return traced(ctx, "GetRecipes", func(ctx context.Context) (*models.RecipePage, error) {
+		return s.next.GetRecipes(ctx, filter, page, limit)
+	}, attribute.Int("page", page), attribute.Int("limit", limit))
+}
+
+func (s *TracedService) CreateRecipe(ctx context.Context, recipe *models.Recipe) (*models.Recipe, error) {
+	return traced(ctx, "CreateRecipe", func(ctx context.Context) (*models.Recipe, error) {
+		return s.next.CreateRecipe(ctx, recipe)
+	})
+}
+
+func (s *TracedService) CreateRecipeFromImage(ctx context.Context, image string, imageType string) (*models.Recipe, error) {
+	return traced(ctx, "CreateRecipeFromImage", func(ctx context.Context) (*models.Recipe, error) {
+		return s.next.CreateRecipeFromImage(ctx, image, imageType)
+	}, attribute.String("image.type", imageType))
+}
+
+func (s *TracedService) CreateRecipeFromImages(ctx context.Context, images []models.CreateRecipeFromImageRequest) (*models.Recipe, error) {
+	return traced(ctx, "CreateRecipeFromImages", func(ctx context.Context) (*models.Recipe, error) {
+		return s.next.CreateRecipeFromImages(ctx, images)
+	}, attribute.Int("image.count", len(images)))
+}
+
+func (s *TracedService) CreateRecipeFromPDF(ctx context.Context, pdf string) (*models.Recipe, error) {
+	return traced(ctx, "CreateRecipeFromPDF", func(ctx context.Context) (*models.Recipe, error) {
+		return s.next.CreateRecipeFromPDF(ctx, pdf)
+	})
+}
+
+func (s *TracedService) CreateRecipeFromURL(ctx context.Context, url string) (*models.Recipe, error) {
+	return traced(ctx, "CreateRecipeFromURL", func(ctx context.Context) (*models.Recipe, error) {
+		return s.next.CreateRecipeFromURL(ctx, url)
+	}, attribute.String("recipe.source.url", url))
+}
+
+func (s *TracedService) UpdateRecipe(ctx context.Context, id string, recipe *models.Recipe) (*models.Recipe, error) {
+	return traced(ctx, "UpdateRecipe", func(ctx context.Context) (*models.Recipe, error) {
+		return s.next.UpdateRecipe(ctx, id, recipe)
+	}, recipeIDAttribute(id))
+}
+
+func (s *TracedService) DeleteRecipe(ctx context.Context, id string) error {
+	_, err := traced(ctx, "DeleteRecipe", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.next.DeleteRecipe(ctx, id)
+	}, recipeIDAttribute(id))
+	return err
+}
+
+func (s *TracedService) GetDuplicateRecipes(ctx context.Context) ([]models.DuplicateCluster, error) {
+	return traced(ctx, "GetDuplicateRecipes", func(ctx context.Context) ([]models.DuplicateCluster, error) {
+		return s.next.GetDuplicateRecipes(ctx)
+	})
+}
+
+func (s *TracedService) SuggestTags(ctx context.Context, id string) (*models.TagSuggestion, error) {
+	return traced(ctx, "SuggestTags", func(ctx context.Context) (*models.TagSuggestion, error) {
+		return s.next.SuggestTags(ctx, id)
+	}, recipeIDAttribute(id))
+}
+
+func (s *TracedService) TranslateRecipe(ctx context.Context, id string, language string) (*models.Recipe, error) {
+	return traced(ctx, "TranslateRecipe", func(ctx context.Context) (*models.Recipe, error) {
+		return s.next.TranslateRecipe(ctx, id, language)
+	}, recipeIDAttribute(id), attribute.String("recipe.language", language))
+}
+
+func (s *TracedService) SuggestSubstitutions(ctx context.Context, id string, ingredient string, dietary []string) (*models.Substitutions, error) {
+	return traced(ctx, "SuggestSubstitutions", func(ctx context.Context) (*models.Substitutions, error) {
+		return s.next.SuggestSubstitutions(ctx, id, ingredient, dietary)
+	}, recipeIDAttribute(id), attribute.StringSlice("recipe.dietary", dietary))
+}
+
+func (s *TracedService) GetAIUsage(ctx context.Context, from time.Time, to time.Time) (*models.AIUsageSummary, error) {
+	return traced(ctx, "GetAIUsage", func(ctx context.Context) (*models.AIUsageSummary, error) {
+		return s.next.GetAIUsage(ctx, from, to)
+	})
+}
+
+func (s *TracedService) ShareRecipe(ctx context.Context, id string, expiresAt *time.Time) (*models.CreatedRecipeShare, error) {
+	return traced(ctx, "ShareRecipe", func(ctx context.Context) (*models.CreatedRecipeShare, error) {
+		return s.next.ShareRecipe(ctx, id, expiresAt)
+	}, recipeIDAttribute(id))
+}
+
+func (s *TracedService) GetRecipeShares(ctx context.Context, id string) ([]models.RecipeShare, error) {
+	return traced(ctx, "GetRecipeShares", func(ctx context.Context) ([]models.RecipeShare, error) {
+		return s.next.GetRecipeShares(ctx, id)
+	}, recipeIDAttribute(id))
+}
+
+func (s *TracedService) RevokeRecipeShare(ctx context.Context, id string, shareID string) error {
+	_, err := traced(ctx, "RevokeRecipeShare", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.next.RevokeRecipeShare(ctx, id, shareID)
+	}, recipeIDAttribute(id), attribute.String("recipe.share.id", shareID))
+	return err
+}
+
+// GetSharedRecipe does not record the token, which gives access to the recipe
+func (s *TracedService) GetSharedRecipe(ctx context.Context, token string) (*models.SharedRecipe, error) {
+	return traced(ctx, "GetSharedRecipe", func(ctx context.Context) (*models.SharedRecipe, error) {
+		return s.next.GetSharedRecipe(ctx, token)
+	})
+}
+
+func (s *TracedService) GetCollections(ctx context.Context) ([]models.Collection, error) {
+	return traced(ctx, "GetCollections", func(ctx context.Context) ([]models.Collection, error) {
+		return s.next.GetCollections(ctx)
+	})
+}
+
+func (s *TracedService) GetCollection(ctx context.Context, id string) (*models.Collection, error) {
+	return traced(ctx, "GetCollection", func(ctx context.Context) (*models.Collection, error) {
+		return s.next.GetCollection(ctx, id)
+	}, attribute.String("collection.id", id))
+}
+
+func (s *TracedService) CreateCollection(ctx context.Context, collection *models.Collection) (*models.Collection, error) {
+	return traced(ctx, "CreateCollection", func(ctx context.Context) (*models.Collection, error) {
+		return s.next.CreateCollection(ctx, collection)
+	})
+}
+
+func (s *TracedService) UpdateCollection(ctx context.Context, id string, collection *models.Collection) (*models.Collection, error) {
+	return traced(ctx, "UpdateCollection", func(ctx context.Context) (*models.Collection, error) {
+		return s.next.UpdateCollection(ctx, id, collection)
+	}, attribute.String("collection.id", id))
+}
+
+func (s *TracedService) DeleteCollection(ctx context.Context, id string) error {
+	_, err := traced(ctx, "DeleteCollection", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.next.DeleteCollection(ctx, id)
+	}, attribute.String("collection.id", id))
+	return err
+}
+
+func (s *TracedService) GetMealPlans(ctx context.Context) ([]models.MealPlan, error) {
+	return traced(ctx, "GetMealPlans", func(ctx context.Context) ([]models.MealPlan, error) {
+		return s.next.GetMealPlans(ctx)
+	})
+}
+
+func (s *TracedService) GetMealPlan(ctx context.Context, id string) (*models.MealPlan, error) {
+	return traced(ctx, "GetMealPlan", func(ctx context.Context) (*models.MealPlan, error) {
+		return s.next.GetMealPlan(ctx, id)
+	}, attribute.String("meal_plan.id", id))
+}
+
+func (s *TracedService) CreateMealPlan(ctx context.Context, plan *models.MealPlan) (*models.MealPlan, error) {
+	return traced(ctx, "CreateMealPlan", func(ctx context.Context) (*models.MealPlan, error) {
+		return s.next.CreateMealPlan(ctx, plan)
+	})
+}
+
+func (s *TracedService) UpdateMealPlan(ctx context.Context, id string, plan *models.MealPlan) (*models.MealPlan, error) {
+	return traced(ctx, "UpdateMealPlan", func(ctx context.Context) (*models.MealPlan, error) {
+		return s.next.UpdateMealPlan(ctx, id, plan)
+	}, attribute.String("meal_plan.id", id))
+}
+
+func (s *TracedService) DeleteMealPlan(ctx context.Context, id string) error {
+	_, err := traced(ctx, "DeleteMealPlan", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.next.DeleteMealPlan(ctx, id)
+	}, attribute.String("meal_plan.id", id))
+	return err
+}
+
+func (s *TracedService) GetGroceryLists(ctx context.Context) ([]models.GroceryList, error) {
+	return traced(ctx, "GetGroceryLists", func(ctx context.Context) ([]models.GroceryList, error) {
+		return s.next.GetGroceryLists(ctx)
+	})
+}
+
+func (s *TracedService) GetGroceryList(ctx context.Context, id string) (*models.GroceryList, error) {
+	return traced(ctx, "GetGroceryList", func(ctx context.Context) (*models.GroceryList, error) {
+		return s.next.GetGroceryList(ctx, id)
+	}, attribute.String("grocery_list.id", id))
+}
+
+func (s *TracedService) CreateGroceryList(ctx context.Context, list *models.GroceryList) (*models.GroceryList, error) {
+	return traced(ctx, "CreateGroceryList", func(ctx context.Context) (*models.GroceryList, error) {
+		return s.next.CreateGroceryList(ctx, list)
+	})
+}
+
+func (s *TracedService) UpdateGroceryList(ctx context.Context, id string, list *models.GroceryList) (*models.GroceryList, error) {
+	return traced(ctx, "UpdateGroceryList", func(ctx context.Context) (*models.GroceryList, error) {
+		return s.next.UpdateGroceryList(ctx, id, list)
+	}, attribute.String("grocery_list.id", id))
+}
+
+func (s *TracedService) DeleteGroceryList(ctx context.Context, id string) error {
+	_, err := traced(ctx, "DeleteGroceryList", func(ctx context.Context) (struct{}, error) {
+		return struct{}{}, s.next.DeleteGroceryList(ctx, id)
+	}, attribute.String("grocery_list.id", id))
+	return err
+}
+
+func traced[T any](ctx context.Context, method string, call func(ctx context.Context) (T, error), attributes ...attribute.KeyValue) (T, error) {
+	ctx, span := tracing.Start(ctx, "RecipeService."+method, trace.WithAttributes(attributes...))
+	result, err := call(ctx)
+	tracing.End(span, err)
+	return result, err
+}
+
+func recipeIDAttribute(id string) attribute.KeyValue {
+	return attribute.String("recipe.id", id)
+}
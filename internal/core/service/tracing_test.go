@@ -0,0 +1,50 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/storage"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func TestTracedService(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+
+	mockStorage := new(MockStorage)
+	traced := NewTracedService(NewRecipeService(mockStorage, nil, nil, nil))
+	ctx := context.Background()
+
+	var storageSpan trace.SpanContext
+	mockStorage.On("GetRecipeByID", mock.Anything, "123").Run(func(args mock.Arguments) {
+		storageSpan = trace.SpanContextFromContext(args.Get(0).(context.Context))
+	}).Return(&models.Recipe{Title: "Omelett", Visibility: models.VisibilityPublic}, nil).Once()
+	mockStorage.On("GetRecipeByID", mock.Anything, "456").Return(nil, storage.ErrNotFound).Once()
+
+	_, err := traced.GetRecipe(ctx, "123")
+	require.NoError(t, err)
+	_, err = traced.GetRecipe(ctx, "456")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, "RecipeService.GetRecipe", spans[0].Name())
+	assert.Contains(t, spans[0].Attributes(), attribute.String("recipe.id", "123"))
+	// The storage is called within the span of the service
+	assert.Equal(t, spans[0].SpanContext().SpanID(), storageSpan.SpanID())
+
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	mockStorage.AssertExpectations(t)
+}
@@ -2,42 +2,63 @@ package service
 
 import (
 	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"net/http"
+	"net/url"
 	"strings"
-	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/fetch"
 )
 
-func validateURL(url string) error {
-	if url == "" {
-		return fmt.Errorf("URL cannot be empty")
-	}
+// Random bytes of the tokens of invites and share links
+const _TokenBytes = 32
 
-	// Create client with timeout to avoid hanging on slow responses
-	client := &http.Client{
-		Timeout: 5 * time.Second,
+func fetchURL(ctx context.Context, fetcher *fetch.Client, url string) (*fetch.Response, error) {
+	if url == "" {
+		return nil, fmt.Errorf("URL cannot be empty")
 	}
 
-	// Create a HEAD request
-	req, err := http.NewRequest("HEAD", url, nil)
+	resp, err := fetcher.Get(ctx, url)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	// Send the request
-	resp, err := client.Do(req)
-	if err != nil {
-		return err
+	// Fall back to sniffing the content when the server does not send a useful type
+	if resp.ContentType == "" || resp.ContentType == "application/octet-stream" {
+		resp.ContentType, _, _ = strings.Cut(http.DetectContentType(resp.Body), ";")
 	}
-	defer resp.Body.Close()
 
-	// Check if status code indicates success (2xx)
-	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		return fmt.Errorf("URL could not be found or is not accessible")
+	return resp, nil
+}
+
+// imageTypeFromContentType maps an image media type to the image types used in the API
+func imageTypeFromContentType(contentType string) (string, bool) {
+	switch contentType {
+	case "image/jpeg", "image/jpg", "image/pjpeg":
+		return "jpeg", true
+	case "image/png":
+		return "png", true
+	default:
+		return "", false
 	}
+}
 
-	return nil
+func isImageContentType(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/")
+}
+
+func isWebpageContentType(contentType string) bool {
+	switch contentType {
+	case "text/html", "application/xhtml+xml", "text/plain":
+		return true
+	default:
+		return false
+	}
 }
 
 func validateBase64Image(image string, imageType string) error {
@@ -93,3 +114,41 @@ func detectImageTypeFromBase64(image string) (string, error) {
 
 	return "", fmt.Errorf("unrecognized/unsupported image format (only JPEG and PNG are supported)")
 }
+
+// imageReference references an uploaded base64 encoded image by the SHA-256 hash of its bytes
+func imageReference(image string) string {
+	data, err := base64.StdEncoding.DecodeString(image)
+	if err != nil {
+		data = []byte(image)
+	}
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// validateSourceURL validates the optional URL of the source of a recipe
+func validateSourceURL(sourceURL string) error {
+	if sourceURL == "" {
+		return nil
+	}
+	u, err := url.Parse(sourceURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("source URL must be an http or https URL")
+	}
+	return nil
+}
+
+// newToken returns a random token with the prefix, of which only the hash is stored
+func newToken(prefix string) (string, error) {
+	random := make([]byte, _TokenBytes)
+	if _, err := rand.Read(random); err != nil {
+		return "", err
+	}
+	return prefix + base64.RawURLEncoding.EncodeToString(random), nil
+}
+
+// hashToken returns the hash of a token that is stored. Tokens are random, so a fast hash is
+// enough and allows looking them up by their hash.
+func hashToken(token string) string {
+	hash := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(hash[:])
+}
@@ -6,4 +6,5 @@ var (
 	ErrNotFound      = errors.New("resource not found")
 	ErrInvalidID     = errors.New("invalid ID format")
 	ErrDatabaseError = errors.New("database error")
+	ErrAlreadyExists = errors.New("resource already exists")
 )
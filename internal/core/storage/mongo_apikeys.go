@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (s *MongoStorage) CreateAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.apiKeys.InsertOne(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to save API key: %v", ErrDatabaseError, err)
+	}
+
+	key.ID = result.InsertedID.(primitive.ObjectID)
+
+	return key, nil
+}
+
+func (s *MongoStorage) GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var key models.APIKey
+	err := s.apiKeys.FindOne(ctx, bson.M{"key_hash": keyHash}).Decode(&key)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: API key", ErrNotFound)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return &key, nil
+}
+
+func (s *MongoStorage) GetAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objUserID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+
+	cursor, err := s.apiKeys.Find(ctx, bson.M{"user_id": objUserID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to find API keys: %v", ErrDatabaseError, err)
+	}
+	defer cursor.Close(ctx)
+
+	keys := []models.APIKey{}
+	if err := cursor.All(ctx, &keys); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode API keys: %v", ErrDatabaseError, err)
+	}
+
+	return keys, nil
+}
+
+func (s *MongoStorage) RevokeAPIKey(ctx context.Context, id string, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+	objUserID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+
+	result, err := s.apiKeys.UpdateOne(ctx,
+		bson.M{"_id": objID, "user_id": objUserID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return fmt.Errorf("%w: failed to revoke API key: %v", ErrDatabaseError, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: API key with ID %s", ErrNotFound, id)
+	}
+
+	return nil
+}
+
+func (s *MongoStorage) SetAPIKeyLastUsed(ctx context.Context, id string, usedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+
+	_, err = s.apiKeys.UpdateOne(ctx, bson.M{"_id": objID}, bson.M{"$max": bson.M{"last_used_at": usedAt}})
+	if err != nil {
+		return fmt.Errorf("%w: failed to update API key: %v", ErrDatabaseError, err)
+	}
+
+	return nil
+}
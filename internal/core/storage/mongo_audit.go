@@ -0,0 +1,24 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (s *MongoStorage) CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.auditLog.InsertOne(ctx, entry)
+	if err != nil {
+		return fmt.Errorf("%w: failed to save audit entry: %v", ErrDatabaseError, err)
+	}
+
+	entry.ID = result.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
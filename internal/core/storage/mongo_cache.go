@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type cacheEntry struct {
+	Key       string    `bson:"_id"`
+	Value     []byte    `bson:"value"`
+	CreatedAt time.Time `bson:"created_at"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+func (s *MongoStorage) GetCacheEntry(ctx context.Context, key string) ([]byte, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// The TTL monitor only runs once a minute, so expired entries may still be present
+	var entry cacheEntry
+	err := s.aiCache.FindOne(ctx, bson.M{
+		"_id":        key,
+		"expires_at": bson.M{"$gt": time.Now()},
+	}).Decode(&entry)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("%w: failed to get cache entry: %v", ErrDatabaseError, err)
+	}
+
+	return entry.Value, true, nil
+}
+
+func (s *MongoStorage) SetCacheEntry(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	now := time.Now()
+	entry := cacheEntry{
+		Key:       key,
+		Value:     value,
+		CreatedAt: now,
+		ExpiresAt: now.Add(ttl),
+	}
+
+	_, err := s.aiCache.ReplaceOne(ctx, bson.M{"_id": key}, entry, options.Replace().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("%w: failed to set cache entry: %v", ErrDatabaseError, err)
+	}
+
+	return nil
+}
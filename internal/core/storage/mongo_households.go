@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (s *MongoStorage) CreateHousehold(ctx context.Context, household *models.Household) (*models.Household, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.households.InsertOne(ctx, household)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to save household: %v", ErrDatabaseError, err)
+	}
+
+	household.ID = result.InsertedID.(primitive.ObjectID)
+
+	return household, nil
+}
+
+func (s *MongoStorage) GetHouseholdByID(ctx context.Context, id string) (*models.Household, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+
+	var household models.Household
+	err = s.households.FindOne(ctx, bson.M{"_id": objID}).Decode(&household)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: household with ID %s", ErrNotFound, id)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return &household, nil
+}
+
+func (s *MongoStorage) GetHouseholds(ctx context.Context, userID string) ([]models.Household, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objUserID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+
+	cursor, err := s.households.Find(ctx, bson.M{"members.user_id": objUserID}, options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to find households: %v", ErrDatabaseError, err)
+	}
+	defer cursor.Close(ctx)
+
+	households := []models.Household{}
+	if err := cursor.All(ctx, &households); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode households: %v", ErrDatabaseError, err)
+	}
+
+	return households, nil
+}
+
+func (s *MongoStorage) AddHouseholdMember(ctx context.Context, id string, member models.HouseholdMember) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+
+	result, err := s.households.UpdateOne(ctx,
+		bson.M{"_id": objID, "members.user_id": bson.M{"$ne": member.UserID}},
+		bson.M{"$push": bson.M{"members": member}, "$set": bson.M{"updated_at": time.Now()}},
+	)
+	if err != nil {
+		return fmt.Errorf("%w: failed to add household member: %v", ErrDatabaseError, err)
+	}
+	if result.MatchedCount == 0 {
+		// Either the household does not exist or the user is already a member
+		count, err := s.households.CountDocuments(ctx, bson.M{"_id": objID})
+		if err != nil {
+			return fmt.Errorf("%w: %v", ErrDatabaseError, err)
+		}
+		if count == 0 {
+			return fmt.Errorf("%w: household with ID %s", ErrNotFound, id)
+		}
+		return fmt.Errorf("%w: household member %s in household %s", ErrAlreadyExists, member.UserID.Hex(), id)
+	}
+
+	return nil
+}
+
+func (s *MongoStorage) SetHouseholdMemberRole(ctx context.Context, id string, userID string, role string) error {
+	return s.updateHouseholdMember(ctx, id, userID, bson.M{"$set": bson.M{"members.$.role": role, "updated_at": time.Now()}})
+}
+
+func (s *MongoStorage) RemoveHouseholdMember(ctx context.Context, id string, userID string) error {
+	objUserID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+	return s.updateHouseholdMember(ctx, id, userID, bson.M{"$pull": bson.M{"members": bson.M{"user_id": objUserID}}, "$set": bson.M{"updated_at": time.Now()}})
+}
+
+func (s *MongoStorage) updateHouseholdMember(ctx context.Context, id string, userID string, update bson.M) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+	objUserID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+
+	result, err := s.households.UpdateOne(ctx, bson.M{"_id": objID, "members.user_id": objUserID}, update)
+	if err != nil {
+		return fmt.Errorf("%w: failed to update household member: %v", ErrDatabaseError, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: household member %s in household %s", ErrNotFound, userID, id)
+	}
+
+	return nil
+}
+
+func (s *MongoStorage) CreateHouseholdInvite(ctx context.Context, invite *models.HouseholdInvite) (*models.HouseholdInvite, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.householdInvites.InsertOne(ctx, invite)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to save household invite: %v", ErrDatabaseError, err)
+	}
+
+	invite.ID = result.InsertedID.(primitive.ObjectID)
+
+	return invite, nil
+}
+
+func (s *MongoStorage) GetHouseholdInviteByHash(ctx context.Context, tokenHash string) (*models.HouseholdInvite, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var invite models.HouseholdInvite
+	err := s.householdInvites.FindOne(ctx, bson.M{"token_hash": tokenHash}).Decode(&invite)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: household invite", ErrNotFound)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return &invite, nil
+}
+
+// AcceptHouseholdInvite marks the invite as accepted in one update, so an invite cannot be
+// accepted twice
+func (s *MongoStorage) AcceptHouseholdInvite(ctx context.Context, tokenHash string, userID string, now time.Time) (*models.HouseholdInvite, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objUserID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+
+	var invite models.HouseholdInvite
+	err = s.householdInvites.FindOneAndUpdate(ctx,
+		bson.M{"token_hash": tokenHash, "accepted_at": bson.M{"$exists": false}, "expires_at": bson.M{"$gt": now}},
+		bson.M{"$set": bson.M{"accepted_by": objUserID, "accepted_at": now}},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&invite)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: household invite", ErrNotFound)
+		}
+		return nil, fmt.Errorf("%w: failed to accept household invite: %v", ErrDatabaseError, err)
+	}
+
+	return &invite, nil
+}
+
+func (s *MongoStorage) ReleaseHouseholdInvite(ctx context.Context, id string, userID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+	objUserID, err := primitive.ObjectIDFromHex(userID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+
+	result, err := s.householdInvites.UpdateOne(ctx,
+		bson.M{"_id": objID, "accepted_by": objUserID},
+		bson.M{"$unset": bson.M{"accepted_by": "", "accepted_at": ""}},
+	)
+	if err != nil {
+		return fmt.Errorf("%w: failed to release household invite: %v", ErrDatabaseError, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: household invite %s accepted by %s", ErrNotFound, id, userID)
+	}
+
+	return nil
+}
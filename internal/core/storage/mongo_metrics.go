@@ -0,0 +1,124 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/internal/core/metrics"
+	"github.com/AntonLuning/RecipeBank/internal/tracing"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+	"go.mongodb.org/mongo-driver/event"
+	"go.opentelemetry.io/otel/codes"
+	semconv "go.opentelemetry.io/otel/semconv/v1.28.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// runningCommand is a database command that has been started
+type runningCommand struct {
+	collection string
+	span       trace.Span
+}
+
+// newCommandMonitor records the latency and failures of the database commands on collections in
+// the metrics, and as spans of the traces they are part of. Commands that are not run on a
+// collection (e.g. handshakes) are not recorded, and commands outside of a trace (e.g. creating
+// the indexes) get no span.
+func newCommandMonitor() *event.CommandMonitor {
+	// Commands that are running, by request ID
+	var commands sync.Map
+
+	finished := func(e event.CommandFinishedEvent, failure string) {
+		value, ok := commands.LoadAndDelete(e.RequestID)
+		if !ok {
+			return
+		}
+		command := value.(runningCommand)
+
+		metrics.StorageOperationDuration.WithLabelValues(command.collection, e.CommandName).Observe(e.Duration.Seconds())
+		if failure != "" {
+			metrics.StorageOperationErrors.WithLabelValues(command.collection, e.CommandName).Inc()
+			command.span.SetStatus(codes.Error, failure)
+		}
+		command.span.End()
+	}
+
+	return &event.CommandMonitor{
+		Started: func(ctx context.Context, e *event.CommandStartedEvent) {
+			collection, ok := commandCollection(e)
+			if !ok {
+				return
+			}
+
+			span := trace.SpanFromContext(ctx)
+			if span.SpanContext().IsValid() {
+				_, span = tracing.Start(ctx, e.CommandName+" "+collection,
+					trace.WithSpanKind(trace.SpanKindClient),
+					trace.WithAttributes(
+						semconv.DBSystemMongoDB,
+						semconv.DBNamespace(e.DatabaseName),
+						semconv.DBCollectionName(collection),
+						semconv.DBOperationName(e.CommandName),
+					),
+				)
+			}
+			commands.Store(e.RequestID, runningCommand{collection: collection, span: span})
+		},
+		Succeeded: func(_ context.Context, e *event.CommandSucceededEvent) {
+			finished(e.CommandFinishedEvent, "")
+		},
+		Failed: func(_ context.Context, e *event.CommandFailedEvent) {
+			finished(e.CommandFinishedEvent, e.Failure)
+		},
+	}
+}
+
+// commandCollection returns the collection a command runs on, which is the value of the command
+// itself (e.g. {"find": "recipes"}), or the collection field of getMore
+func commandCollection(e *event.CommandStartedEvent) (string, bool) {
+	value := e.Command.Lookup(e.CommandName)
+	if e.CommandName == "getMore" {
+		value = e.Command.Lookup("collection")
+	}
+	if value.Type != bsontype.String {
+		return "", false
+	}
+	return value.StringValue(), true
+}
+
+// CountRecipesByVisibility counts all recipes by their visibility, "none" for recipes created
+// before recipes had owners. It is not isolated by household, it is used for metrics.
+func (s *MongoStorage) CountRecipesByVisibility(ctx context.Context) (map[string]int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := s.collection.Aggregate(ctx, bson.A{
+		bson.M{"$group": bson.M{
+			"_id":   bson.M{"$ifNull": bson.A{"$visibility", ""}},
+			"count": bson.M{"$sum": 1},
+		}},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to count recipes: %v", ErrDatabaseError, err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Visibility string `bson:"_id"`
+		Count      int64  `bson:"count"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode recipe counts: %v", ErrDatabaseError, err)
+	}
+
+	counts := map[string]int64{}
+	for _, result := range results {
+		if result.Visibility == "" {
+			result.Visibility = "none"
+		}
+		counts[result.Visibility] += result.Count
+	}
+	return counts, nil
+}
@@ -0,0 +1,248 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (s *MongoStorage) CreateCollection(ctx context.Context, collection *models.Collection) (*models.Collection, error) {
+	collection.HouseholdID = contextHousehold(ctx)
+
+	id, err := insertOwned(ctx, s.recipeCollections, "collection", collection)
+	if err != nil {
+		return nil, err
+	}
+	collection.ID = id
+
+	return collection, nil
+}
+
+func (s *MongoStorage) GetCollectionByID(ctx context.Context, id string, ownerID string) (*models.Collection, error) {
+	return findOwned[models.Collection](ctx, s.recipeCollections, "collection", id, ownerID)
+}
+
+func (s *MongoStorage) GetCollections(ctx context.Context, ownerID string) ([]models.Collection, error) {
+	return listOwned[models.Collection](ctx, s.recipeCollections, "collections", ownerID)
+}
+
+func (s *MongoStorage) UpdateCollection(ctx context.Context, id string, ownerID string, collection *models.Collection) (*models.Collection, error) {
+	return updateOwned[models.Collection](ctx, s.recipeCollections, "collection", id, ownerID, bson.M{
+		"name":        collection.Name,
+		"description": collection.Description,
+		"recipe_ids":  collection.RecipeIDs,
+		"updated_at":  collection.UpdatedAt,
+	})
+}
+
+func (s *MongoStorage) DeleteCollection(ctx context.Context, id string, ownerID string) error {
+	return deleteOwned(ctx, s.recipeCollections, "collection", id, ownerID)
+}
+
+func (s *MongoStorage) CreateMealPlan(ctx context.Context, plan *models.MealPlan) (*models.MealPlan, error) {
+	plan.HouseholdID = contextHousehold(ctx)
+
+	id, err := insertOwned(ctx, s.mealPlans, "meal plan", plan)
+	if err != nil {
+		return nil, err
+	}
+	plan.ID = id
+
+	return plan, nil
+}
+
+func (s *MongoStorage) GetMealPlanByID(ctx context.Context, id string, ownerID string) (*models.MealPlan, error) {
+	return findOwned[models.MealPlan](ctx, s.mealPlans, "meal plan", id, ownerID)
+}
+
+func (s *MongoStorage) GetMealPlans(ctx context.Context, ownerID string) ([]models.MealPlan, error) {
+	return listOwned[models.MealPlan](ctx, s.mealPlans, "meal plans", ownerID)
+}
+
+func (s *MongoStorage) UpdateMealPlan(ctx context.Context, id string, ownerID string, plan *models.MealPlan) (*models.MealPlan, error) {
+	return updateOwned[models.MealPlan](ctx, s.mealPlans, "meal plan", id, ownerID, bson.M{
+		"name":       plan.Name,
+		"meals":      plan.Meals,
+		"updated_at": plan.UpdatedAt,
+	})
+}
+
+func (s *MongoStorage) DeleteMealPlan(ctx context.Context, id string, ownerID string) error {
+	return deleteOwned(ctx, s.mealPlans, "meal plan", id, ownerID)
+}
+
+func (s *MongoStorage) CreateGroceryList(ctx context.Context, list *models.GroceryList) (*models.GroceryList, error) {
+	list.HouseholdID = contextHousehold(ctx)
+
+	id, err := insertOwned(ctx, s.groceryLists, "grocery list", list)
+	if err != nil {
+		return nil, err
+	}
+	list.ID = id
+
+	return list, nil
+}
+
+func (s *MongoStorage) GetGroceryListByID(ctx context.Context, id string, ownerID string) (*models.GroceryList, error) {
+	return findOwned[models.GroceryList](ctx, s.groceryLists, "grocery list", id, ownerID)
+}
+
+func (s *MongoStorage) GetGroceryLists(ctx context.Context, ownerID string) ([]models.GroceryList, error) {
+	return listOwned[models.GroceryList](ctx, s.groceryLists, "grocery lists", ownerID)
+}
+
+func (s *MongoStorage) UpdateGroceryList(ctx context.Context, id string, ownerID string, list *models.GroceryList) (*models.GroceryList, error) {
+	return updateOwned[models.GroceryList](ctx, s.groceryLists, "grocery list", id, ownerID, bson.M{
+		"name":       list.Name,
+		"items":      list.Items,
+		"updated_at": list.UpdatedAt,
+	})
+}
+
+func (s *MongoStorage) DeleteGroceryList(ctx context.Context, id string, ownerID string) error {
+	return deleteOwned(ctx, s.groceryLists, "grocery list", id, ownerID)
+}
+
+// contextHousehold returns the household of the context that new documents are created in, nil
+// outside households
+func contextHousehold(ctx context.Context) *primitive.ObjectID {
+	if householdID, ok := HouseholdFromContext(ctx); ok {
+		return &householdID
+	}
+	return nil
+}
+
+// ownedFilter returns the filter isolated by the household of the context, for the documents
+// that belong to a household or to a user: in the context of a household only its documents
+// match, otherwise only the owner's documents outside households. Every query of collections,
+// meal plans and grocery lists must be isolated.
+func ownedFilter(ctx context.Context, filter bson.M, ownerID string) (bson.M, error) {
+	if householdID, ok := HouseholdFromContext(ctx); ok {
+		return bson.M{"$and": bson.A{filter, bson.M{"household_id": householdID}}}, nil
+	}
+
+	objOwnerID, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+	return bson.M{"$and": bson.A{filter, bson.M{"household_id": bson.M{"$exists": false}, "owner_id": objOwnerID}}}, nil
+}
+
+func insertOwned(ctx context.Context, coll *mongo.Collection, kind string, document any) (primitive.ObjectID, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := coll.InsertOne(ctx, document)
+	if err != nil {
+		return primitive.NilObjectID, fmt.Errorf("%w: failed to save %s: %v", ErrDatabaseError, kind, err)
+	}
+
+	return result.InsertedID.(primitive.ObjectID), nil
+}
+
+func findOwned[T any](ctx context.Context, coll *mongo.Collection, kind string, id string, ownerID string) (*T, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+	filter, err := ownedFilter(ctx, bson.M{"_id": objID}, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var document T
+	err = coll.FindOne(ctx, filter).Decode(&document)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: %s with ID %s", ErrNotFound, kind, id)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return &document, nil
+}
+
+// listOwned returns the documents by name
+func listOwned[T any](ctx context.Context, coll *mongo.Collection, kind string, ownerID string) ([]T, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter, err := ownedFilter(ctx, bson.M{}, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	cursor, err := coll.Find(ctx, filter, options.Find().SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to find %s: %v", ErrDatabaseError, kind, err)
+	}
+	defer cursor.Close(ctx)
+
+	documents := []T{}
+	if err := cursor.All(ctx, &documents); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode %s: %v", ErrDatabaseError, kind, err)
+	}
+
+	return documents, nil
+}
+
+// updateOwned sets the fields of the document and returns the updated document
+func updateOwned[T any](ctx context.Context, coll *mongo.Collection, kind string, id string, ownerID string, fields bson.M) (*T, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+	filter, err := ownedFilter(ctx, bson.M{"_id": objID}, ownerID)
+	if err != nil {
+		return nil, err
+	}
+
+	var document T
+	err = coll.FindOneAndUpdate(ctx, filter, bson.M{"$set": fields},
+		options.FindOneAndUpdate().SetReturnDocument(options.After),
+	).Decode(&document)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: %s with ID %s", ErrNotFound, kind, id)
+		}
+		return nil, fmt.Errorf("%w: failed to update %s: %v", ErrDatabaseError, kind, err)
+	}
+
+	return &document, nil
+}
+
+func deleteOwned(ctx context.Context, coll *mongo.Collection, kind string, id string, ownerID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+	filter, err := ownedFilter(ctx, bson.M{"_id": objID}, ownerID)
+	if err != nil {
+		return err
+	}
+
+	result, err := coll.DeleteOne(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("%w: failed to delete %s: %v", ErrDatabaseError, kind, err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("%w: %s with ID %s", ErrNotFound, kind, id)
+	}
+
+	return nil
+}
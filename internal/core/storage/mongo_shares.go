@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (s *MongoStorage) CreateRecipeShare(ctx context.Context, share *models.RecipeShare) (*models.RecipeShare, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.recipeShares.InsertOne(ctx, share)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to save recipe share: %v", ErrDatabaseError, err)
+	}
+
+	share.ID = result.InsertedID.(primitive.ObjectID)
+
+	return share, nil
+}
+
+func (s *MongoStorage) GetRecipeShares(ctx context.Context, recipeID string) ([]models.RecipeShare, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objRecipeID, err := primitive.ObjectIDFromHex(recipeID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+
+	cursor, err := s.recipeShares.Find(ctx, bson.M{"recipe_id": objRecipeID}, options.Find().SetSort(bson.D{{Key: "created_at", Value: -1}}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to find recipe shares: %v", ErrDatabaseError, err)
+	}
+	defer cursor.Close(ctx)
+
+	shares := []models.RecipeShare{}
+	if err := cursor.All(ctx, &shares); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode recipe shares: %v", ErrDatabaseError, err)
+	}
+
+	return shares, nil
+}
+
+func (s *MongoStorage) RevokeRecipeShare(ctx context.Context, id string, recipeID string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+	objRecipeID, err := primitive.ObjectIDFromHex(recipeID)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+
+	result, err := s.recipeShares.UpdateOne(ctx,
+		bson.M{"_id": objID, "recipe_id": objRecipeID, "revoked_at": bson.M{"$exists": false}},
+		bson.M{"$set": bson.M{"revoked_at": time.Now()}},
+	)
+	if err != nil {
+		return fmt.Errorf("%w: failed to revoke recipe share: %v", ErrDatabaseError, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: recipe share with ID %s", ErrNotFound, id)
+	}
+
+	return nil
+}
+
+func (s *MongoStorage) GetSharedRecipe(ctx context.Context, tokenHash string, now time.Time) (*models.Recipe, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var share models.RecipeShare
+	err := s.recipeShares.FindOne(ctx, bson.M{
+		"token_hash": tokenHash,
+		"revoked_at": bson.M{"$exists": false},
+		"$or": bson.A{
+			bson.M{"expires_at": bson.M{"$exists": false}},
+			bson.M{"expires_at": bson.M{"$gt": now}},
+		},
+	}).Decode(&share)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: shared recipe", ErrNotFound)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	var recipe models.Recipe
+	err = s.collection.FindOne(ctx, bson.M{"_id": share.RecipeID}).Decode(&recipe)
+	if err != nil {
+		// The recipe of the link has been deleted
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: shared recipe", ErrNotFound)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return &recipe, nil
+}
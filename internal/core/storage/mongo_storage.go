@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"math"
+	"regexp"
 	"time"
 
 	"github.com/AntonLuning/RecipeBank/pkg/core/models"
@@ -14,10 +15,22 @@ import (
 )
 
 type MongoStorage struct {
-	client      *mongo.Client
-	db          *mongo.Database
-	collection  *mongo.Collection
-	initialized bool
+	client            *mongo.Client
+	db                *mongo.Database
+	collection        *mongo.Collection
+	aiCache           *mongo.Collection
+	aiUsage           *mongo.Collection
+	users             *mongo.Collection
+	loginAttempts     *mongo.Collection
+	auditLog          *mongo.Collection
+	apiKeys           *mongo.Collection
+	households        *mongo.Collection
+	householdInvites  *mongo.Collection
+	recipeShares      *mongo.Collection
+	recipeCollections *mongo.Collection
+	mealPlans         *mongo.Collection
+	groceryLists      *mongo.Collection
+	initialized       bool
 }
 
 type StorageConfig struct {
@@ -28,7 +41,7 @@ type StorageConfig struct {
 	Database string
 }
 
-func NewMongoStorage(ctx context.Context, config StorageConfig) (RecipeStorage, error) {
+func NewMongoStorage(ctx context.Context, config StorageConfig) (*MongoStorage, error) {
 	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
 	defer cancel()
 
@@ -39,7 +52,7 @@ func NewMongoStorage(ctx context.Context, config StorageConfig) (RecipeStorage,
 		config.Port,
 	)
 
-	clientOptions := options.Client().ApplyURI(uri)
+	clientOptions := options.Client().ApplyURI(uri).SetMonitor(newCommandMonitor())
 
 	client, err := mongo.Connect(ctx, clientOptions)
 	if err != nil {
@@ -55,9 +68,21 @@ func NewMongoStorage(ctx context.Context, config StorageConfig) (RecipeStorage,
 	collection := db.Collection("recipes")
 
 	return &MongoStorage{
-		client:     client,
-		db:         db,
-		collection: collection,
+		client:            client,
+		db:                db,
+		collection:        collection,
+		aiCache:           db.Collection("ai_cache"),
+		aiUsage:           db.Collection("ai_usage"),
+		users:             db.Collection("users"),
+		loginAttempts:     db.Collection("login_attempts"),
+		auditLog:          db.Collection("audit_log"),
+		apiKeys:           db.Collection("api_keys"),
+		households:        db.Collection("households"),
+		householdInvites:  db.Collection("household_invites"),
+		recipeShares:      db.Collection("recipe_shares"),
+		recipeCollections: db.Collection("collections"),
+		mealPlans:         db.Collection("meal_plans"),
+		groceryLists:      db.Collection("grocery_lists"),
 	}, nil
 }
 
@@ -85,6 +110,30 @@ func (s *MongoStorage) Initialize(ctx context.Context) error {
 			Keys:    bson.D{{Key: "ingredients.name", Value: 1}},
 			Options: options.Index().SetName("ingredients_name"),
 		},
+		{
+			Keys:    bson.D{{Key: "translation_of", Value: 1}, {Key: "language", Value: 1}},
+			Options: options.Index().SetName("translation_of_language").SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "source.type", Value: 1}},
+			Options: options.Index().SetName("source_type"),
+		},
+		{
+			Keys:    bson.D{{Key: "owner_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("owner_id_created_at"),
+		},
+		{
+			Keys:    bson.D{{Key: "visibility", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("visibility_created_at"),
+		},
+		{
+			Keys:    bson.D{{Key: "household_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("household_id_created_at").SetSparse(true),
+		},
+		{
+			Keys:    bson.D{{Key: "duplicate_keys", Value: 1}},
+			Options: options.Index().SetName("duplicate_keys"),
+		},
 	}
 
 	_, err := s.collection.Indexes().CreateMany(ctx, indexes)
@@ -92,6 +141,127 @@ func (s *MongoStorage) Initialize(ctx context.Context) error {
 		return fmt.Errorf("%w: failed to create indexes: %v", ErrDatabaseError, err)
 	}
 
+	// Recipes created before recipes had a visibility were seen by everyone, and stay public until
+	// they are given to a user
+	_, err = s.collection.UpdateMany(ctx,
+		bson.M{"visibility": bson.M{"$in": bson.A{nil, ""}}},
+		bson.M{"$set": bson.M{"visibility": models.VisibilityPublic}},
+	)
+	if err != nil {
+		return fmt.Errorf("%w: failed to migrate recipe visibility: %v", ErrDatabaseError, err)
+	}
+
+	// Recipes imported before their source was recorded have the prompt version at the top level,
+	// it is moved into the source unless the source has one
+	_, err = s.collection.UpdateMany(ctx,
+		bson.M{"prompt_version": bson.M{"$exists": true}},
+		mongo.Pipeline{
+			{{Key: "$set", Value: bson.M{"source.prompt_version": bson.M{"$ifNull": bson.A{"$source.prompt_version", "$prompt_version"}}}}},
+			{{Key: "$unset", Value: "prompt_version"}},
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("%w: failed to migrate recipe prompt versions: %v", ErrDatabaseError, err)
+	}
+
+	// Expired AI cache entries are removed by MongoDB
+	_, err = s.aiCache.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: failed to create AI cache indexes: %v", ErrDatabaseError, err)
+	}
+
+	_, err = s.aiUsage.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "created_at", Value: 1}},
+		Options: options.Index().SetName("created_at"),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: failed to create AI usage indexes: %v", ErrDatabaseError, err)
+	}
+
+	_, err = s.users.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "username", Value: 1}},
+		Options: options.Index().SetName("username").SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: failed to create user indexes: %v", ErrDatabaseError, err)
+	}
+
+	// Forgotten failed logins and expired locks are removed by MongoDB
+	_, err = s.loginAttempts.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expires_at", Value: 1}},
+		Options: options.Index().SetName("expires_at_ttl").SetExpireAfterSeconds(0),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: failed to create login attempt indexes: %v", ErrDatabaseError, err)
+	}
+
+	_, err = s.auditLog.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "created_at", Value: 1}},
+		Options: options.Index().SetName("created_at"),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: failed to create audit log indexes: %v", ErrDatabaseError, err)
+	}
+
+	_, err = s.apiKeys.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "key_hash", Value: 1}},
+			Options: options.Index().SetName("key_hash").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "user_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("user_id_created_at"),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: failed to create API key indexes: %v", ErrDatabaseError, err)
+	}
+
+	_, err = s.households.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "members.user_id", Value: 1}},
+		Options: options.Index().SetName("members_user_id"),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: failed to create household indexes: %v", ErrDatabaseError, err)
+	}
+
+	_, err = s.householdInvites.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "token_hash", Value: 1}},
+		Options: options.Index().SetName("token_hash").SetUnique(true),
+	})
+	if err != nil {
+		return fmt.Errorf("%w: failed to create household invite indexes: %v", ErrDatabaseError, err)
+	}
+
+	_, err = s.recipeShares.Indexes().CreateMany(ctx, []mongo.IndexModel{
+		{
+			Keys:    bson.D{{Key: "token_hash", Value: 1}},
+			Options: options.Index().SetName("token_hash").SetUnique(true),
+		},
+		{
+			Keys:    bson.D{{Key: "recipe_id", Value: 1}, {Key: "created_at", Value: -1}},
+			Options: options.Index().SetName("recipe_id_created_at"),
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: failed to create recipe share indexes: %v", ErrDatabaseError, err)
+	}
+
+	// Collections, meal plans and grocery lists are found by household, or by owner outside
+	// households
+	for _, coll := range []*mongo.Collection{s.recipeCollections, s.mealPlans, s.groceryLists} {
+		_, err = coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+			Keys:    bson.D{{Key: "household_id", Value: 1}, {Key: "owner_id", Value: 1}, {Key: "name", Value: 1}},
+			Options: options.Index().SetName("household_id_owner_id_name"),
+		})
+		if err != nil {
+			return fmt.Errorf("%w: failed to create %s indexes: %v", ErrDatabaseError, coll.Name(), err)
+		}
+	}
+
 	s.initialized = true
 	return nil
 }
@@ -106,6 +276,12 @@ func (s *MongoStorage) CreateRecipe(ctx context.Context, recipe *models.Recipe)
 	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
+	// Recipes are always created in the household of the context
+	recipe.HouseholdID = nil
+	if householdID, ok := HouseholdFromContext(ctx); ok {
+		recipe.HouseholdID = &householdID
+	}
+
 	result, err := s.collection.InsertOne(ctx, recipe)
 	if err != nil {
 		return nil, fmt.Errorf("%w: failed to save recipe: %v", ErrDatabaseError, err)
@@ -126,7 +302,7 @@ func (s *MongoStorage) GetRecipeByID(ctx context.Context, id string) (*models.Re
 	}
 
 	var recipe models.Recipe
-	err = s.collection.FindOne(ctx, bson.M{"_id": objID}).Decode(&recipe)
+	err = s.collection.FindOne(ctx, scoped(ctx, bson.M{"_id": objID})).Decode(&recipe)
 	if err != nil {
 		if err == mongo.ErrNoDocuments {
 			return nil, fmt.Errorf("%w: recipe with ID %s", ErrNotFound, id)
@@ -169,6 +345,35 @@ func (s *MongoStorage) GetRecipes(ctx context.Context, filter models.RecipeFilte
 	if len(filter.Tags) > 0 {
 		bsonFilter["tags"] = bson.M{"$all": filter.Tags}
 	}
+	if filter.TranslationOf != "" {
+		objID, err := primitive.ObjectIDFromHex(filter.TranslationOf)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidID, err)
+		}
+		bsonFilter["translation_of"] = objID
+	}
+	if filter.Language != "" {
+		bsonFilter["language"] = filter.Language
+	}
+	if filter.SourceType != "" {
+		bsonFilter["source.type"] = filter.SourceType
+	}
+	if filter.SourceURL != "" {
+		bsonFilter["source.url"] = bson.M{"$regex": primitive.Regex{Pattern: regexp.QuoteMeta(filter.SourceURL), Options: "i"}}
+	}
+	if filter.SourceModel != "" {
+		bsonFilter["source.model"] = filter.SourceModel
+	}
+	if filter.Viewer != nil {
+		if visible := visibilityFilter(*filter.Viewer); visible != nil {
+			bsonFilter["$or"] = visible
+		}
+		if filter.Mine {
+			bsonFilter["owner_id"] = filter.Viewer.UserID
+		}
+	}
+
+	bsonFilter = scoped(ctx, bsonFilter)
 
 	total, err := s.collection.CountDocuments(ctx, bsonFilter)
 	if err != nil {
@@ -217,18 +422,20 @@ func (s *MongoStorage) UpdateRecipe(ctx context.Context, id string, recipe *mode
 
 	result, err := s.collection.UpdateOne(
 		ctx,
-		bson.M{"_id": recipe.ID},
+		scoped(ctx, bson.M{"_id": recipe.ID}),
 		bson.M{
 			"$set": bson.M{
-				"title":       recipe.Title,
-				"description": recipe.Description,
-				"ingredients": recipe.Ingredients,
-				"steps":       recipe.Steps,
-				"cook_time":   recipe.CookTime,
-				"servings":    recipe.Servings,
-				"tags":        recipe.Tags,
-				"image":       recipe.Image,
-				"updated_at":  recipe.UpdatedAt,
+				"title":          recipe.Title,
+				"description":    recipe.Description,
+				"ingredients":    recipe.Ingredients,
+				"steps":          recipe.Steps,
+				"cook_time":      recipe.CookTime,
+				"servings":       recipe.Servings,
+				"tags":           recipe.Tags,
+				"image":          recipe.Image,
+				"visibility":     recipe.Visibility,
+				"updated_at":     recipe.UpdatedAt,
+				"duplicate_keys": recipe.DuplicateKeys,
 			},
 		},
 	)
@@ -251,7 +458,7 @@ func (s *MongoStorage) DeleteRecipe(ctx context.Context, id string) error {
 		return fmt.Errorf("invalid recipe ID format: %w", err)
 	}
 
-	result, err := s.collection.DeleteOne(ctx, bson.M{"_id": objID})
+	result, err := s.collection.DeleteOne(ctx, scoped(ctx, bson.M{"_id": objID}))
 	if err != nil {
 		return fmt.Errorf("failed to delete recipe: %w", err)
 	}
@@ -260,5 +467,216 @@ func (s *MongoStorage) DeleteRecipe(ctx context.Context, id string) error {
 		return fmt.Errorf("%w: recipe with ID %s", ErrNotFound, id)
 	}
 
+	// Translations are copies linked to the original, they are not left behind without it
+	_, err = s.collection.DeleteMany(ctx, scoped(ctx, bson.M{"translation_of": objID}))
+	if err != nil {
+		return fmt.Errorf("%w: failed to delete translations: %v", ErrDatabaseError, err)
+	}
+
 	return nil
 }
+
+func (s *MongoStorage) SetTranslationsVisibility(ctx context.Context, id string, visibility string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+
+	_, err = s.collection.UpdateMany(ctx,
+		scoped(ctx, bson.M{"translation_of": objID}),
+		bson.M{"$set": bson.M{"visibility": visibility}},
+	)
+	if err != nil {
+		return fmt.Errorf("%w: failed to update translations: %v", ErrDatabaseError, err)
+	}
+
+	return nil
+}
+
+// GetTags returns the tags used in the collection, the most used first
+func (s *MongoStorage) GetTags(ctx context.Context, viewer *models.RecipeViewer, limit int) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	filter := bson.M{}
+	if viewer != nil {
+		if visible := visibilityFilter(*viewer); visible != nil {
+			filter["$or"] = visible
+		}
+	}
+
+	pipeline := bson.A{
+		bson.M{"$match": scoped(ctx, filter)},
+		bson.M{"$unwind": "$tags"},
+		bson.M{"$group": bson.M{"_id": "$tags", "count": bson.M{"$sum": 1}}},
+		bson.M{"$sort": bson.D{{Key: "count", Value: -1}, {Key: "_id", Value: 1}}},
+		bson.M{"$limit": limit},
+	}
+
+	cursor, err := s.collection.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to aggregate tags: %v", ErrDatabaseError, err)
+	}
+	defer cursor.Close(ctx)
+
+	var results []struct {
+		Tag string `bson:"_id"`
+	}
+	if err := cursor.All(ctx, &results); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode tags: %v", ErrDatabaseError, err)
+	}
+
+	tags := make([]string, 0, len(results))
+	for _, result := range results {
+		tags = append(tags, result.Tag)
+	}
+
+	return tags, nil
+}
+
+// Fields of a recipe in its fingerprint
+var fingerprintProjection = bson.M{"title": 1, "ingredients.name": 1, "source.url": 1, "created_at": 1}
+
+// GetRecipeFingerprints returns the fingerprints of the recipes the viewer can see that are not
+// translations, the oldest first
+func (s *MongoStorage) GetRecipeFingerprints(ctx context.Context, viewer *models.RecipeViewer) ([]models.RecipeFingerprint, error) {
+	return s.getRecipeFingerprints(ctx, viewer, bson.M{})
+}
+
+// GetRecipeFingerprintsByKeys returns the fingerprints of the recipes the viewer can see that are
+// not translations and have any of the duplicate keys, the oldest first
+func (s *MongoStorage) GetRecipeFingerprintsByKeys(ctx context.Context, viewer *models.RecipeViewer, keys []string) ([]models.RecipeFingerprint, error) {
+	if len(keys) == 0 {
+		return []models.RecipeFingerprint{}, nil
+	}
+	return s.getRecipeFingerprints(ctx, viewer, bson.M{"duplicate_keys": bson.M{"$in": keys}})
+}
+
+func (s *MongoStorage) getRecipeFingerprints(ctx context.Context, viewer *models.RecipeViewer, filter bson.M) ([]models.RecipeFingerprint, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	filter["translation_of"] = bson.M{"$exists": false}
+	if viewer != nil {
+		if visible := visibilityFilter(*viewer); visible != nil {
+			filter["$or"] = visible
+		}
+	}
+
+	findOptions := options.Find().
+		SetProjection(fingerprintProjection).
+		SetSort(bson.D{{Key: "created_at", Value: 1}, {Key: "_id", Value: 1}})
+
+	cursor, err := s.collection.Find(ctx, scoped(ctx, filter), findOptions)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to find recipes: %v", ErrDatabaseError, err)
+	}
+	defer cursor.Close(ctx)
+
+	var recipes []models.Recipe
+	if err := cursor.All(ctx, &recipes); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode recipes: %v", ErrDatabaseError, err)
+	}
+
+	fingerprints := make([]models.RecipeFingerprint, 0, len(recipes))
+	for _, recipe := range recipes {
+		fingerprints = append(fingerprints, models.NewRecipeFingerprint(&recipe))
+	}
+
+	return fingerprints, nil
+}
+
+// SetMissingDuplicateKeys sets the duplicate keys of the recipes created before recipes had them.
+// It is a migration, so it is not isolated by household.
+func (s *MongoStorage) SetMissingDuplicateKeys(ctx context.Context, keys func(models.RecipeFingerprint) []string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Minute)
+	defer cancel()
+
+	cursor, err := s.collection.Find(ctx, bson.M{"duplicate_keys": bson.M{"$exists": false}}, options.Find().SetProjection(fingerprintProjection))
+	if err != nil {
+		return 0, fmt.Errorf("%w: failed to find recipes: %v", ErrDatabaseError, err)
+	}
+	defer cursor.Close(ctx)
+
+	var updated int64
+	for cursor.Next(ctx) {
+		var recipe models.Recipe
+		if err := cursor.Decode(&recipe); err != nil {
+			return updated, fmt.Errorf("%w: failed to decode recipe: %v", ErrDatabaseError, err)
+		}
+
+		_, err := s.collection.UpdateOne(ctx, bson.M{"_id": recipe.ID}, bson.M{
+			"$set": bson.M{"duplicate_keys": keys(models.NewRecipeFingerprint(&recipe))},
+		})
+		if err != nil {
+			return updated, fmt.Errorf("%w: failed to set duplicate keys: %v", ErrDatabaseError, err)
+		}
+		updated++
+	}
+	if err := cursor.Err(); err != nil {
+		return updated, fmt.Errorf("%w: failed to find recipes: %v", ErrDatabaseError, err)
+	}
+
+	return updated, nil
+}
+
+// AssignRecipeOwner gives the recipes without an owner to the user, with the visibility
+func (s *MongoStorage) AssignRecipeOwner(ctx context.Context, ownerID string, visibility string) (int64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(ownerID)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+
+	result, err := s.collection.UpdateMany(
+		ctx,
+		scoped(ctx, bson.M{"owner_id": bson.M{"$exists": false}}),
+		bson.M{"$set": bson.M{"owner_id": objID, "visibility": visibility}},
+	)
+	if err != nil {
+		return 0, fmt.Errorf("%w: failed to assign recipe owner: %v", ErrDatabaseError, err)
+	}
+
+	return result.ModifiedCount, nil
+}
+
+// visibilityFilter returns the conditions of which one must match for the viewer to see a
+// recipe, nil if the viewer can see all recipes. Keep in sync with models.Recipe.VisibleTo.
+func visibilityFilter(viewer models.RecipeViewer) bson.A {
+	if viewer.Admin {
+		return nil
+	}
+
+	visible := bson.A{bson.M{"visibility": models.VisibilityPublic}}
+	if !viewer.Anonymous() {
+		visible = append(visible, bson.M{"owner_id": viewer.UserID})
+	}
+	if !viewer.HouseholdID.IsZero() {
+		visible = append(visible, bson.M{"visibility": models.VisibilityHousehold, "household_id": viewer.HouseholdID})
+	}
+	return visible
+}
+
+// householdFilter returns the condition that isolates the recipes of households. In the context
+// of a household only its recipes match, otherwise only recipes outside households and public
+// recipes.
+func householdFilter(ctx context.Context) bson.M {
+	if householdID, ok := HouseholdFromContext(ctx); ok {
+		return bson.M{"household_id": householdID}
+	}
+	return bson.M{"$or": bson.A{
+		bson.M{"household_id": bson.M{"$exists": false}},
+		bson.M{"visibility": models.VisibilityPublic},
+	}}
+}
+
+// scoped returns the recipe filter isolated by the household of the context, every recipe query
+// must be scoped
+func scoped(ctx context.Context, filter bson.M) bson.M {
+	return bson.M{"$and": bson.A{filter, householdFilter(ctx)}}
+}
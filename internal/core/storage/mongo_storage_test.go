@@ -11,6 +11,7 @@ import (
 	"github.com/AntonLuning/RecipeBank/pkg/core/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
@@ -770,3 +771,873 @@ func TestUpdateRecipeWithImage(t *testing.T) {
 	require.NoError(t, err)
 	assert.Equal(t, updateRecipe.Image, retrieved.Image)
 }
+
+func TestCacheEntry(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	_, found, err := storage.GetCacheEntry(ctx, "missing")
+	require.NoError(t, err)
+	assert.False(t, found)
+
+	require.NoError(t, storage.SetCacheEntry(ctx, "key", []byte(`{"title":"Omelett"}`), time.Hour))
+	value, found, err := storage.GetCacheEntry(ctx, "key")
+	require.NoError(t, err)
+	assert.True(t, found)
+	assert.Equal(t, `{"title":"Omelett"}`, string(value))
+
+	// Setting an existing key replaces the entry
+	require.NoError(t, storage.SetCacheEntry(ctx, "key", []byte(`{"title":"Pancakes"}`), time.Hour))
+	value, _, err = storage.GetCacheEntry(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, `{"title":"Pancakes"}`, string(value))
+
+	// Expired entries are not returned, even before MongoDB removes them
+	require.NoError(t, storage.SetCacheEntry(ctx, "expired", []byte(`{}`), -time.Minute))
+	_, found, err = storage.GetCacheEntry(ctx, "expired")
+	require.NoError(t, err)
+	assert.False(t, found)
+}
+
+func TestAIUsage(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	march := time.Date(2025, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	usages := []*models.AIUsage{
+		{Operation: "image", Model: "gpt-4.1-mini", PromptTokens: 1000, CompletionTokens: 200, Cost: 0.1, CreatedAt: march.Add(time.Hour)},
+		{Operation: "webpage", Model: "gpt-4.1-mini", PromptTokens: 3000, CompletionTokens: 300, Cost: 0.2, CreatedAt: march.Add(48 * time.Hour)},
+		{Operation: "text", Model: "gpt-4.1", PromptTokens: 500, CompletionTokens: 100, Cost: 0.5, CreatedAt: march.Add(72 * time.Hour)},
+		{Operation: "text", Model: "gpt-4.1", PromptTokens: 500, CompletionTokens: 100, Cost: 0.5, CreatedAt: march.AddDate(0, -1, 0)}, // outside the period
+	}
+	for _, usage := range usages {
+		require.NoError(t, storage.CreateAIUsage(ctx, usage))
+		assert.False(t, usage.ID.IsZero())
+	}
+
+	summary, err := storage.GetAIUsageSummary(ctx, march, march.AddDate(0, 1, 0))
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), summary.Imports)
+	assert.Equal(t, int64(4500), summary.PromptTokens)
+	assert.Equal(t, int64(600), summary.CompletionTokens)
+	assert.InDelta(t, 0.8, summary.Cost, 1e-9)
+	require.Len(t, summary.Models, 2)
+	assert.Equal(t, "gpt-4.1", summary.Models[0].Model) // sorted by cost
+	assert.Equal(t, int64(2), summary.Models[1].Imports)
+
+	cost, err := storage.GetAIUsageCost(ctx, march, march.Add(24*time.Hour))
+	require.NoError(t, err)
+	assert.InDelta(t, 0.1, cost, 1e-9)
+}
+
+func TestGetTags(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	tags, err := storage.GetTags(ctx, nil, 10)
+	require.NoError(t, err)
+	assert.Empty(t, tags)
+
+	for _, recipeTags := range [][]string{{"pasta", "quick"}, {"quick", "soup"}, {"quick", "pasta"}, nil} {
+		_, err := storage.CreateRecipe(ctx, &models.Recipe{Title: "Recipe", Tags: recipeTags})
+		require.NoError(t, err)
+	}
+
+	// Most used first, ties sorted by name
+	tags, err = storage.GetTags(ctx, nil, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"quick", "pasta", "soup"}, tags)
+
+	tags, err = storage.GetTags(ctx, nil, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"quick", "pasta"}, tags)
+}
+
+func TestGetTagsVisibility(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	user, other := primitive.NewObjectID(), primitive.NewObjectID()
+
+	for _, recipe := range []*models.Recipe{
+		{Title: "Mine", Tags: []string{"pasta"}, OwnerID: user, Visibility: models.VisibilityPrivate},
+		{Title: "Public", Tags: []string{"soup"}, OwnerID: other, Visibility: models.VisibilityPublic},
+		{Title: "Secret", Tags: []string{"grandmas-secret"}, OwnerID: other, Visibility: models.VisibilityPrivate},
+	} {
+		_, err := storage.CreateRecipe(ctx, recipe)
+		require.NoError(t, err)
+	}
+
+	// Tags of the private recipes of other users are not revealed
+	tags, err := storage.GetTags(ctx, &models.RecipeViewer{UserID: user}, 10)
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"pasta", "soup"}, tags)
+
+	tags, err = storage.GetTags(ctx, &models.RecipeViewer{}, 10)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"soup"}, tags)
+
+	tags, err = storage.GetTags(ctx, &models.RecipeViewer{UserID: primitive.NewObjectID(), Admin: true}, 10)
+	require.NoError(t, err)
+	assert.Len(t, tags, 3)
+}
+
+func TestGetRecipesTranslations(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	original, err := storage.CreateRecipe(ctx, &models.Recipe{Title: "Spaghetti al pomodoro"})
+	require.NoError(t, err)
+	for _, language := range []string{"english", "japanese"} {
+		_, err := storage.CreateRecipe(ctx, &models.Recipe{Title: "Spaghetti", Language: language, TranslationOf: &original.ID})
+		require.NoError(t, err)
+	}
+
+	page, err := storage.GetRecipes(ctx, models.RecipeFilter{TranslationOf: original.ID.Hex()}, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), page.Total)
+
+	page, err = storage.GetRecipes(ctx, models.RecipeFilter{TranslationOf: original.ID.Hex(), Language: "japanese"}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, page.Recipes, 1)
+	assert.Equal(t, "japanese", page.Recipes[0].Language)
+	assert.Equal(t, original.ID, *page.Recipes[0].TranslationOf)
+
+	_, err = storage.GetRecipes(ctx, models.RecipeFilter{TranslationOf: "invalid"}, 1, 10)
+	assert.ErrorIs(t, err, ErrInvalidID)
+}
+
+func TestDeleteRecipeTranslations(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	original, err := storage.CreateRecipe(ctx, &models.Recipe{Title: "Spaghetti al pomodoro"})
+	require.NoError(t, err)
+	translation, err := storage.CreateRecipe(ctx, &models.Recipe{Title: "Spaghetti", Language: "english", TranslationOf: &original.ID})
+	require.NoError(t, err)
+	other, err := storage.CreateRecipe(ctx, &models.Recipe{Title: "Pancakes"})
+	require.NoError(t, err)
+	otherTranslation, err := storage.CreateRecipe(ctx, &models.Recipe{Title: "Pannkakor", Language: "swedish", TranslationOf: &other.ID})
+	require.NoError(t, err)
+
+	// Deleting a translation keeps the original
+	require.NoError(t, storage.DeleteRecipe(ctx, otherTranslation.ID.Hex()))
+	_, err = storage.GetRecipeByID(ctx, other.ID.Hex())
+	assert.NoError(t, err)
+
+	// Deleting the original deletes its translations
+	require.NoError(t, storage.DeleteRecipe(ctx, original.ID.Hex()))
+	_, err = storage.GetRecipeByID(ctx, translation.ID.Hex())
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = storage.GetRecipeByID(ctx, other.ID.Hex())
+	assert.NoError(t, err)
+}
+
+func TestSetTranslationsVisibility(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	original, err := storage.CreateRecipe(ctx, &models.Recipe{Title: "Spaghetti al pomodoro", Visibility: models.VisibilityPrivate})
+	require.NoError(t, err)
+	other, err := storage.CreateRecipe(ctx, &models.Recipe{Title: "Pancakes", Visibility: models.VisibilityPrivate})
+	require.NoError(t, err)
+	// More translations than a page of recipes
+	for i := range 120 {
+		_, err := storage.CreateRecipe(ctx, &models.Recipe{Title: "Spaghetti", Language: fmt.Sprintf("language %d", i), TranslationOf: &original.ID, Visibility: models.VisibilityPrivate})
+		require.NoError(t, err)
+	}
+	_, err = storage.CreateRecipe(ctx, &models.Recipe{Title: "Pannkakor", Language: "swedish", TranslationOf: &other.ID, Visibility: models.VisibilityPrivate})
+	require.NoError(t, err)
+
+	require.NoError(t, storage.SetTranslationsVisibility(ctx, original.ID.Hex(), models.VisibilityPublic))
+
+	count, err := storage.collection.CountDocuments(ctx, bson.M{"translation_of": original.ID, "visibility": models.VisibilityPublic})
+	require.NoError(t, err)
+	assert.Equal(t, int64(120), count)
+	// The original is updated by UpdateRecipe, and other recipes are not changed
+	count, err = storage.collection.CountDocuments(ctx, bson.M{"visibility": models.VisibilityPrivate})
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	assert.ErrorIs(t, storage.SetTranslationsVisibility(ctx, "invalid", models.VisibilityPublic), ErrInvalidID)
+}
+
+func TestGetRecipesSource(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	sources := []*models.RecipeSource{
+		{Type: models.SourceURL, URL: "https://www.example.com/pancakes", Model: "gpt-4.1-mini", PromptVersion: "v1"},
+		{Type: models.SourceURL, URL: "https://recipes.test/soup", Model: "llama3.2", PromptVersion: "v1"},
+		{Type: models.SourceImage, Image: "sha256:abc", Model: "gpt-4.1-mini", PromptVersion: "v1"},
+		{Type: models.SourceManual},
+	}
+	for i, source := range sources {
+		_, err := storage.CreateRecipe(ctx, &models.Recipe{Title: fmt.Sprintf("Recipe %d", i), Source: source})
+		require.NoError(t, err)
+	}
+
+	page, err := storage.GetRecipes(ctx, models.RecipeFilter{SourceType: models.SourceURL}, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), page.Total)
+
+	// The URL filter matches a part of the URL, regular expression characters are literal
+	page, err = storage.GetRecipes(ctx, models.RecipeFilter{SourceURL: "EXAMPLE.com"}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, page.Recipes, 1)
+	assert.Equal(t, sources[0].URL, page.Recipes[0].Source.URL)
+
+	page, err = storage.GetRecipes(ctx, models.RecipeFilter{SourceURL: "example.com.*"}, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), page.Total)
+
+	page, err = storage.GetRecipes(ctx, models.RecipeFilter{SourceType: models.SourceImage, SourceModel: "gpt-4.1-mini"}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, page.Recipes, 1)
+	assert.Equal(t, "sha256:abc", page.Recipes[0].Source.Image)
+}
+
+func TestGetRecipeFingerprints(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	original, err := storage.CreateRecipe(ctx, &models.Recipe{
+		Title:       "Tomato soup",
+		Ingredients: []models.Ingredient{{Name: "Tomatoes", Quantity: 1, Unit: "kg"}, {Name: "Onion", Quantity: 1}},
+		Source:      &models.RecipeSource{Type: models.SourceURL, URL: "https://example.com/soup"},
+		CreatedAt:   time.Now().Add(-time.Hour),
+	})
+	require.NoError(t, err)
+	_, err = storage.CreateRecipe(ctx, &models.Recipe{Title: "Pancakes", CreatedAt: time.Now()})
+	require.NoError(t, err)
+	// Translations are not duplicates
+	_, err = storage.CreateRecipe(ctx, &models.Recipe{Title: "Tomatensuppe", Language: "german", TranslationOf: &original.ID, CreatedAt: time.Now()})
+	require.NoError(t, err)
+
+	fingerprints, err := storage.GetRecipeFingerprints(ctx, nil)
+	require.NoError(t, err)
+	require.Len(t, fingerprints, 2)
+	assert.Equal(t, original.ID, fingerprints[0].ID)
+	assert.Equal(t, []string{"Tomatoes", "Onion"}, fingerprints[0].IngredientNames)
+	assert.Equal(t, "https://example.com/soup", fingerprints[0].SourceURL)
+	assert.Equal(t, "Pancakes", fingerprints[1].Title)
+}
+
+func TestGetRecipeFingerprintsByKeys(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	soup, err := storage.CreateRecipe(ctx, &models.Recipe{Title: "Tomato soup", DuplicateKeys: []string{"url:https://example.com/soup", "title:tomato", "title:soup"}})
+	require.NoError(t, err)
+	_, err = storage.CreateRecipe(ctx, &models.Recipe{Title: "Pancakes", DuplicateKeys: []string{"title:pancake"}})
+	require.NoError(t, err)
+	// Created before recipes had duplicate keys
+	legacy, err := storage.CreateRecipe(ctx, &models.Recipe{Title: "Onion soup"})
+	require.NoError(t, err)
+	_, err = storage.collection.UpdateOne(ctx, bson.M{"_id": legacy.ID}, bson.M{"$unset": bson.M{"duplicate_keys": ""}})
+	require.NoError(t, err)
+
+	fingerprints, err := storage.GetRecipeFingerprintsByKeys(ctx, nil, []string{"title:soup", "title:chicken"})
+	require.NoError(t, err)
+	require.Len(t, fingerprints, 1)
+	assert.Equal(t, soup.ID, fingerprints[0].ID)
+
+	fingerprints, err = storage.GetRecipeFingerprintsByKeys(ctx, nil, []string{})
+	require.NoError(t, err)
+	assert.Empty(t, fingerprints)
+
+	updated, err := storage.SetMissingDuplicateKeys(ctx, func(fingerprint models.RecipeFingerprint) []string {
+		return []string{"title:onion", "title:soup"}
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), updated)
+
+	fingerprints, err = storage.GetRecipeFingerprintsByKeys(ctx, nil, []string{"title:soup"})
+	require.NoError(t, err)
+	assert.Len(t, fingerprints, 2)
+}
+
+func TestRecipeVisibility(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	owner := primitive.NewObjectID()
+	other := primitive.NewObjectID()
+
+	for _, recipe := range []*models.Recipe{
+		{Title: "Private", OwnerID: owner, Visibility: models.VisibilityPrivate, CreatedAt: time.Now().Add(-3 * time.Minute)},
+		{Title: "Household", OwnerID: owner, Visibility: models.VisibilityHousehold, CreatedAt: time.Now().Add(-2 * time.Minute)},
+		{Title: "Public", OwnerID: owner, Visibility: models.VisibilityPublic, CreatedAt: time.Now().Add(-time.Minute)},
+		{Title: "Other", OwnerID: other, Visibility: models.VisibilityPrivate, CreatedAt: time.Now()},
+	} {
+		_, err := storage.CreateRecipe(ctx, recipe)
+		require.NoError(t, err)
+	}
+
+	titles := func(filter models.RecipeFilter) []string {
+		page, err := storage.GetRecipes(ctx, filter, 1, 10)
+		require.NoError(t, err)
+		titles := []string{}
+		for _, recipe := range page.Recipes {
+			titles = append(titles, recipe.Title)
+		}
+		return titles
+	}
+
+	assert.Equal(t, []string{"Public", "Household", "Private"}, titles(models.RecipeFilter{Viewer: &models.RecipeViewer{UserID: owner}}))
+	assert.Equal(t, []string{"Other", "Public"}, titles(models.RecipeFilter{Viewer: &models.RecipeViewer{UserID: other}}))
+	assert.Equal(t, []string{"Other"}, titles(models.RecipeFilter{Viewer: &models.RecipeViewer{UserID: other}, Mine: true}))
+	assert.Equal(t, []string{"Public"}, titles(models.RecipeFilter{Viewer: &models.RecipeViewer{}}))
+	assert.Len(t, titles(models.RecipeFilter{Viewer: &models.RecipeViewer{UserID: other, Admin: true}}), 4)
+
+	fingerprints, err := storage.GetRecipeFingerprints(ctx, &models.RecipeViewer{UserID: other})
+	require.NoError(t, err)
+	assert.Len(t, fingerprints, 2)
+}
+
+func TestInitializeMigratesVisibility(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Created before recipes had owners and a visibility
+	result, err := storage.collection.InsertOne(ctx, bson.M{"title": "Legacy", "created_at": time.Now()})
+	require.NoError(t, err)
+	private, err := storage.CreateRecipe(ctx, &models.Recipe{Title: "Private", OwnerID: primitive.NewObjectID(), Visibility: models.VisibilityPrivate, CreatedAt: time.Now()})
+	require.NoError(t, err)
+
+	storage.initialized = false
+	require.NoError(t, storage.Initialize(ctx))
+
+	// Anonymous viewers still see the legacy recipe
+	page, err := storage.GetRecipes(ctx, models.RecipeFilter{Viewer: &models.RecipeViewer{}}, 1, 10)
+	require.NoError(t, err)
+	require.Len(t, page.Recipes, 1)
+	assert.Equal(t, result.InsertedID, page.Recipes[0].ID)
+	assert.Equal(t, models.VisibilityPublic, page.Recipes[0].Visibility)
+
+	recipe, err := storage.GetRecipeByID(ctx, private.ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, models.VisibilityPrivate, recipe.Visibility)
+}
+
+func TestInitializeMigratesPromptVersion(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	// Imported before the source of recipes was recorded
+	result, err := storage.collection.InsertOne(ctx, bson.M{"title": "Legacy", "prompt_version": "v1", "visibility": models.VisibilityPublic, "created_at": time.Now()})
+	require.NoError(t, err)
+	imported, err := storage.CreateRecipe(ctx, &models.Recipe{Title: "Imported", Visibility: models.VisibilityPublic, CreatedAt: time.Now(),
+		Source: &models.RecipeSource{Type: models.SourceURL, URL: "https://example.com/recipe", PromptVersion: "v2"}})
+	require.NoError(t, err)
+
+	storage.initialized = false
+	require.NoError(t, storage.Initialize(ctx))
+
+	recipe, err := storage.GetRecipeByID(ctx, result.InsertedID.(primitive.ObjectID).Hex())
+	require.NoError(t, err)
+	require.NotNil(t, recipe.Source)
+	assert.Equal(t, "v1", recipe.Source.PromptVersion)
+	count, err := storage.collection.CountDocuments(ctx, bson.M{"prompt_version": bson.M{"$exists": true}})
+	require.NoError(t, err)
+	assert.Zero(t, count)
+
+	recipe, err = storage.GetRecipeByID(ctx, imported.ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, imported.Source.URL, recipe.Source.URL)
+	assert.Equal(t, "v2", recipe.Source.PromptVersion)
+}
+
+func TestAssignRecipeOwner(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	owner := primitive.NewObjectID()
+
+	legacy, err := storage.CreateRecipe(ctx, &models.Recipe{Title: "Legacy", CreatedAt: time.Now()})
+	require.NoError(t, err)
+	owned, err := storage.CreateRecipe(ctx, &models.Recipe{Title: "Owned", OwnerID: primitive.NewObjectID(), Visibility: models.VisibilityPrivate, CreatedAt: time.Now()})
+	require.NoError(t, err)
+
+	assigned, err := storage.AssignRecipeOwner(ctx, owner.Hex(), models.VisibilityPublic)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), assigned)
+
+	recipe, err := storage.GetRecipeByID(ctx, legacy.ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, owner, recipe.OwnerID)
+	assert.Equal(t, models.VisibilityPublic, recipe.Visibility)
+
+	recipe, err = storage.GetRecipeByID(ctx, owned.ID.Hex())
+	require.NoError(t, err)
+	assert.NotEqual(t, owner, recipe.OwnerID)
+
+	// Running it again assigns nothing
+	assigned, err = storage.AssignRecipeOwner(ctx, owner.Hex(), models.VisibilityPublic)
+	require.NoError(t, err)
+	assert.Zero(t, assigned)
+}
+
+func TestGetUser(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	result, err := storage.users.InsertOne(ctx, models.User{Username: "anton", PasswordHash: "hash", CreatedAt: time.Now()})
+	require.NoError(t, err)
+	id := result.InsertedID.(primitive.ObjectID)
+
+	// Usernames are unique
+	_, err = storage.users.InsertOne(ctx, models.User{Username: "anton", PasswordHash: "other"})
+	assert.Error(t, err)
+
+	user, err := storage.GetUserByUsername(ctx, "anton")
+	require.NoError(t, err)
+	assert.Equal(t, id, user.ID)
+	assert.Equal(t, "hash", user.PasswordHash)
+
+	user, err = storage.GetUserByID(ctx, id.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, "anton", user.Username)
+
+	_, err = storage.GetUserByUsername(ctx, "nobody")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = storage.GetUserByID(ctx, "invalid")
+	assert.ErrorIs(t, err, ErrInvalidID)
+}
+
+func TestLoginAttempts(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	_, err := storage.GetLoginAttempts(ctx, "user:anton")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	attempts, err := storage.RecordFailedLogin(ctx, "user:anton", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempts.Failures)
+	firstFailureAt := attempts.FirstFailureAt
+
+	attempts, err = storage.RecordFailedLogin(ctx, "user:anton", time.Hour)
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempts.Failures)
+	assert.WithinDuration(t, firstFailureAt, attempts.FirstFailureAt, time.Millisecond)
+
+	require.NoError(t, storage.ReleaseLoginAttempt(ctx, "user:anton"))
+	attempts, err = storage.GetLoginAttempts(ctx, "user:anton")
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempts.Failures)
+	require.NoError(t, storage.ReleaseLoginAttempt(ctx, "user:anton"))
+	require.NoError(t, storage.ReleaseLoginAttempt(ctx, "user:anton"))
+	attempts, err = storage.GetLoginAttempts(ctx, "user:anton")
+	require.NoError(t, err)
+	assert.Equal(t, 0, attempts.Failures)
+	require.NoError(t, storage.ReleaseLoginAttempt(ctx, "ip:192.0.2.1"))
+
+	// The count starts over outside the window
+	time.Sleep(10 * time.Millisecond)
+	attempts, err = storage.RecordFailedLogin(ctx, "user:anton", time.Millisecond)
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempts.Failures)
+
+	until := time.Now().Add(2 * time.Hour).Truncate(time.Millisecond)
+	require.NoError(t, storage.LockLogin(ctx, "user:anton", until))
+	attempts, err = storage.GetLoginAttempts(ctx, "user:anton")
+	require.NoError(t, err)
+	assert.True(t, attempts.Locked(time.Now()))
+	assert.WithinDuration(t, until, attempts.ExpiresAt, time.Millisecond)
+
+	require.NoError(t, storage.DeleteLoginAttempts(ctx, "user:anton"))
+	assert.ErrorIs(t, storage.DeleteLoginAttempts(ctx, "user:anton"), ErrNotFound)
+}
+
+func TestCreateAuditEntry(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	entry := &models.AuditEntry{Action: models.AuditAccountLocked, Username: "anton", Failures: 5, CreatedAt: time.Now()}
+	require.NoError(t, storage.CreateAuditEntry(ctx, entry))
+	assert.False(t, entry.ID.IsZero())
+
+	count, err := storage.auditLog.CountDocuments(ctx, map[string]any{"action": models.AuditAccountLocked})
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
+
+func TestAPIKeys(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	userID := primitive.NewObjectID()
+
+	older, err := storage.CreateAPIKey(ctx, &models.APIKey{UserID: userID, Name: "Old", KeyHash: "hash-1", Scopes: []string{models.ScopeRecipesRead}, CreatedAt: time.Now().Add(-time.Hour)})
+	require.NoError(t, err)
+	newer, err := storage.CreateAPIKey(ctx, &models.APIKey{UserID: userID, Name: "New", KeyHash: "hash-2", Scopes: []string{models.ScopeAIImport}, CreatedAt: time.Now()})
+	require.NoError(t, err)
+	_, err = storage.CreateAPIKey(ctx, &models.APIKey{UserID: primitive.NewObjectID(), Name: "Other", KeyHash: "hash-3", CreatedAt: time.Now()})
+	require.NoError(t, err)
+
+	// Hashes are unique
+	_, err = storage.CreateAPIKey(ctx, &models.APIKey{UserID: userID, KeyHash: "hash-1"})
+	assert.Error(t, err)
+
+	key, err := storage.GetAPIKeyByHash(ctx, "hash-2")
+	require.NoError(t, err)
+	assert.Equal(t, newer.ID, key.ID)
+	_, err = storage.GetAPIKeyByHash(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	keys, err := storage.GetAPIKeys(ctx, userID.Hex())
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "New", keys[0].Name)
+
+	usedAt := time.Now().Truncate(time.Millisecond)
+	require.NoError(t, storage.SetAPIKeyLastUsed(ctx, older.ID.Hex(), usedAt))
+
+	// Keys are only revoked by their user, and once
+	assert.ErrorIs(t, storage.RevokeAPIKey(ctx, older.ID.Hex(), primitive.NewObjectID().Hex()), ErrNotFound)
+	require.NoError(t, storage.RevokeAPIKey(ctx, older.ID.Hex(), userID.Hex()))
+	assert.ErrorIs(t, storage.RevokeAPIKey(ctx, older.ID.Hex(), userID.Hex()), ErrNotFound)
+
+	key, err = storage.GetAPIKeyByHash(ctx, "hash-1")
+	require.NoError(t, err)
+	assert.NotNil(t, key.RevokedAt)
+	if assert.NotNil(t, key.LastUsedAt) {
+		assert.WithinDuration(t, usedAt, *key.LastUsedAt, time.Millisecond)
+	}
+	assert.False(t, key.Active(time.Now()))
+}
+
+func TestManageUsers(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+
+	user, err := storage.CreateUser(ctx, &models.User{Username: "zoe", PasswordHash: "hash", CreatedAt: time.Now()})
+	require.NoError(t, err)
+	_, err = storage.CreateUser(ctx, &models.User{Username: "anton", PasswordHash: "hash", CreatedAt: time.Now()})
+	require.NoError(t, err)
+
+	_, err = storage.CreateUser(ctx, &models.User{Username: "zoe", PasswordHash: "other"})
+	assert.ErrorIs(t, err, ErrAlreadyExists)
+
+	users, err := storage.GetUsers(ctx)
+	require.NoError(t, err)
+	require.Len(t, users, 2)
+	assert.Equal(t, "anton", users[0].Username)
+
+	require.NoError(t, storage.SetUserPassword(ctx, user.ID.Hex(), "new hash"))
+	disabledAt := time.Now().Truncate(time.Millisecond)
+	require.NoError(t, storage.SetUserDisabled(ctx, user.ID.Hex(), &disabledAt))
+
+	updated, err := storage.GetUserByID(ctx, user.ID.Hex())
+	require.NoError(t, err)
+	assert.Equal(t, "new hash", updated.PasswordHash)
+	assert.True(t, updated.Disabled())
+
+	require.NoError(t, storage.SetUserDisabled(ctx, user.ID.Hex(), nil))
+	updated, err = storage.GetUserByID(ctx, user.ID.Hex())
+	require.NoError(t, err)
+	assert.False(t, updated.Disabled())
+
+	require.NoError(t, storage.DeleteUser(ctx, user.ID.Hex()))
+	assert.ErrorIs(t, storage.DeleteUser(ctx, user.ID.Hex()), ErrNotFound)
+	assert.ErrorIs(t, storage.SetUserPassword(ctx, user.ID.Hex(), "hash"), ErrNotFound)
+}
+
+func TestHouseholds(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	owner := primitive.NewObjectID()
+	member := primitive.NewObjectID()
+
+	household, err := storage.CreateHousehold(ctx, &models.Household{
+		Name:      "The Smiths",
+		Members:   []models.HouseholdMember{{UserID: owner, Role: models.HouseholdRoleOwner, JoinedAt: time.Now()}},
+		CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+	id := household.ID.Hex()
+
+	// Invites can be accepted once, before they expire
+	_, err = storage.CreateHouseholdInvite(ctx, &models.HouseholdInvite{HouseholdID: household.ID, TokenHash: "hash", Role: models.HouseholdRoleEditor, ExpiresAt: time.Now().Add(time.Hour)})
+	require.NoError(t, err)
+	_, err = storage.CreateHouseholdInvite(ctx, &models.HouseholdInvite{HouseholdID: household.ID, TokenHash: "expired", Role: models.HouseholdRoleEditor, ExpiresAt: time.Now().Add(-time.Hour)})
+	require.NoError(t, err)
+
+	invite, err := storage.AcceptHouseholdInvite(ctx, "hash", member.Hex(), time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, member, *invite.AcceptedBy)
+	_, err = storage.AcceptHouseholdInvite(ctx, "hash", member.Hex(), time.Now())
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = storage.AcceptHouseholdInvite(ctx, "expired", member.Hex(), time.Now())
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Only the user who accepted an invite can release it, then it can be accepted again
+	assert.ErrorIs(t, storage.ReleaseHouseholdInvite(ctx, invite.ID.Hex(), owner.Hex()), ErrNotFound)
+	require.NoError(t, storage.ReleaseHouseholdInvite(ctx, invite.ID.Hex(), member.Hex()))
+	found, err := storage.GetHouseholdInviteByHash(ctx, "hash")
+	require.NoError(t, err)
+	assert.Nil(t, found.AcceptedBy)
+	invite, err = storage.AcceptHouseholdInvite(ctx, "hash", member.Hex(), time.Now())
+	require.NoError(t, err)
+	_, err = storage.GetHouseholdInviteByHash(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, storage.AddHouseholdMember(ctx, id, models.HouseholdMember{UserID: member, Role: invite.Role, JoinedAt: time.Now()}))
+	err = storage.AddHouseholdMember(ctx, id, models.HouseholdMember{UserID: member, Role: models.HouseholdRoleViewer})
+	assert.ErrorIs(t, err, ErrAlreadyExists)
+
+	households, err := storage.GetHouseholds(ctx, member.Hex())
+	require.NoError(t, err)
+	require.Len(t, households, 1)
+	assert.Len(t, households[0].Members, 2)
+
+	require.NoError(t, storage.SetHouseholdMemberRole(ctx, id, member.Hex(), models.HouseholdRoleViewer))
+	household, err = storage.GetHouseholdByID(ctx, id)
+	require.NoError(t, err)
+	membership, ok := household.Member(member)
+	require.True(t, ok)
+	assert.Equal(t, models.HouseholdRoleViewer, membership.Role)
+
+	require.NoError(t, storage.RemoveHouseholdMember(ctx, id, member.Hex()))
+	err = storage.RemoveHouseholdMember(ctx, id, member.Hex())
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	households, err = storage.GetHouseholds(ctx, member.Hex())
+	require.NoError(t, err)
+	assert.Empty(t, households)
+}
+
+func TestPlanningIsolation(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	owner := primitive.NewObjectID()
+	other := primitive.NewObjectID()
+	household := primitive.NewObjectID()
+	householdCtx := WithHousehold(context.Background(), household)
+	otherHouseholdCtx := WithHousehold(context.Background(), primitive.NewObjectID())
+	personalCtx := context.Background()
+
+	shared, err := storage.CreateCollection(householdCtx, &models.Collection{Name: "Family dinners", OwnerID: owner, RecipeIDs: []primitive.ObjectID{}})
+	require.NoError(t, err)
+	assert.Equal(t, household, *shared.HouseholdID)
+	personal, err := storage.CreateCollection(personalCtx, &models.Collection{Name: "Mine", OwnerID: owner, RecipeIDs: []primitive.ObjectID{}, HouseholdID: &household})
+	require.NoError(t, err)
+	assert.Nil(t, personal.HouseholdID, "created outside households")
+
+	names := func(ctx context.Context, ownerID primitive.ObjectID) []string {
+		collections, err := storage.GetCollections(ctx, ownerID.Hex())
+		require.NoError(t, err)
+		names := []string{}
+		for _, collection := range collections {
+			names = append(names, collection.Name)
+		}
+		return names
+	}
+
+	// Every member of the household finds its collections, and only those
+	assert.Equal(t, []string{"Family dinners"}, names(householdCtx, owner))
+	assert.Equal(t, []string{"Family dinners"}, names(householdCtx, other))
+	assert.Empty(t, names(otherHouseholdCtx, owner))
+	// Outside households only the owner finds its collections
+	assert.Equal(t, []string{"Mine"}, names(personalCtx, owner))
+	assert.Empty(t, names(personalCtx, other))
+
+	_, err = storage.GetCollectionByID(otherHouseholdCtx, shared.ID.Hex(), owner.Hex())
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = storage.GetCollectionByID(personalCtx, personal.ID.Hex(), other.Hex())
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = storage.UpdateCollection(personalCtx, shared.ID.Hex(), owner.Hex(), &models.Collection{Name: "Stolen"})
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.ErrorIs(t, storage.DeleteCollection(otherHouseholdCtx, shared.ID.Hex(), owner.Hex()), ErrNotFound)
+
+	updated, err := storage.UpdateCollection(householdCtx, shared.ID.Hex(), other.Hex(), &models.Collection{Name: "Family favourites", RecipeIDs: []primitive.ObjectID{}, UpdatedAt: time.Now()})
+	require.NoError(t, err)
+	assert.Equal(t, "Family favourites", updated.Name)
+	assert.Equal(t, owner, updated.OwnerID)
+
+	// Meal plans and grocery lists are isolated the same way
+	plan, err := storage.CreateMealPlan(householdCtx, &models.MealPlan{Name: "Week 12", OwnerID: owner, Meals: []models.PlannedMeal{{Date: "2025-03-17", Meal: models.MealDinner, RecipeID: primitive.NewObjectID()}}})
+	require.NoError(t, err)
+	_, err = storage.GetMealPlanByID(personalCtx, plan.ID.Hex(), owner.Hex())
+	assert.ErrorIs(t, err, ErrNotFound)
+	found, err := storage.GetMealPlanByID(householdCtx, plan.ID.Hex(), other.Hex())
+	require.NoError(t, err)
+	assert.Len(t, found.Meals, 1)
+
+	list, err := storage.CreateGroceryList(personalCtx, &models.GroceryList{Name: "Saturday", OwnerID: owner, Items: []models.GroceryItem{{Name: "Flour"}}})
+	require.NoError(t, err)
+	lists, err := storage.GetGroceryLists(householdCtx, owner.Hex())
+	require.NoError(t, err)
+	assert.Empty(t, lists)
+	assert.ErrorIs(t, storage.DeleteGroceryList(personalCtx, list.ID.Hex(), other.Hex()), ErrNotFound)
+	require.NoError(t, storage.DeleteGroceryList(personalCtx, list.ID.Hex(), owner.Hex()))
+}
+
+func TestHouseholdIsolation(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	owner := primitive.NewObjectID()
+	household := primitive.NewObjectID()
+	personalCtx := context.Background()
+	householdCtx := WithHousehold(context.Background(), household)
+	otherCtx := WithHousehold(context.Background(), primitive.NewObjectID())
+
+	shared, err := storage.CreateRecipe(householdCtx, &models.Recipe{Title: "Shared", OwnerID: owner, Visibility: models.VisibilityHousehold, CreatedAt: time.Now()})
+	require.NoError(t, err)
+	assert.Equal(t, household, *shared.HouseholdID)
+	public, err := storage.CreateRecipe(householdCtx, &models.Recipe{Title: "Public", OwnerID: owner, Visibility: models.VisibilityPublic, Tags: []string{"dinner"}, CreatedAt: time.Now()})
+	require.NoError(t, err)
+	// The household of a new recipe is the one of the context
+	personal, err := storage.CreateRecipe(personalCtx, &models.Recipe{Title: "Personal", OwnerID: owner, Visibility: models.VisibilityPrivate, HouseholdID: &household, CreatedAt: time.Now()})
+	require.NoError(t, err)
+	assert.Nil(t, personal.HouseholdID)
+
+	count := func(ctx context.Context) int64 {
+		page, err := storage.GetRecipes(ctx, models.RecipeFilter{}, 1, 10)
+		require.NoError(t, err)
+		return page.Total
+	}
+	assert.Equal(t, int64(2), count(householdCtx))
+	assert.Equal(t, int64(2), count(personalCtx)) // Personal and public recipes
+	assert.Zero(t, count(otherCtx))
+
+	// Recipes of a household cannot be found or changed outside it
+	_, err = storage.GetRecipeByID(otherCtx, shared.ID.Hex())
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = storage.GetRecipeByID(personalCtx, shared.ID.Hex())
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = storage.GetRecipeByID(personalCtx, public.ID.Hex())
+	assert.NoError(t, err)
+	_, err = storage.GetRecipeByID(householdCtx, personal.ID.Hex())
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = storage.UpdateRecipe(otherCtx, shared.ID.Hex(), &models.Recipe{Title: "Taken"})
+	assert.ErrorIs(t, err, ErrNotFound)
+	err = storage.DeleteRecipe(otherCtx, shared.ID.Hex())
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	tags, err := storage.GetTags(otherCtx, nil, 10)
+	require.NoError(t, err)
+	assert.Empty(t, tags)
+	fingerprints, err := storage.GetRecipeFingerprints(householdCtx, nil)
+	require.NoError(t, err)
+	assert.Len(t, fingerprints, 2)
+
+	// Members see the household recipes of the household, others only the public ones
+	member := &models.RecipeViewer{UserID: primitive.NewObjectID(), HouseholdID: household, HouseholdRole: models.HouseholdRoleViewer}
+	page, err := storage.GetRecipes(householdCtx, models.RecipeFilter{Viewer: member}, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), page.Total)
+	page, err = storage.GetRecipes(personalCtx, models.RecipeFilter{Viewer: &models.RecipeViewer{UserID: primitive.NewObjectID()}}, 1, 10)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), page.Total)
+}
+
+func TestRecipeShares(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	owner := primitive.NewObjectID()
+	household := primitive.NewObjectID()
+	ctx := context.Background()
+	recipe, err := storage.CreateRecipe(WithHousehold(ctx, household), &models.Recipe{Title: "Secret", OwnerID: owner, Visibility: models.VisibilityHousehold, CreatedAt: time.Now()})
+	require.NoError(t, err)
+
+	now := time.Now()
+	expired := now.Add(-time.Minute)
+	share, err := storage.CreateRecipeShare(ctx, &models.RecipeShare{RecipeID: recipe.ID, TokenHash: "active", CreatedBy: owner, CreatedAt: now.Add(-time.Hour)})
+	require.NoError(t, err)
+	_, err = storage.CreateRecipeShare(ctx, &models.RecipeShare{RecipeID: recipe.ID, TokenHash: "expired", CreatedBy: owner, ExpiresAt: &expired, CreatedAt: now})
+	require.NoError(t, err)
+
+	// Share links are not isolated by household
+	shared, err := storage.GetSharedRecipe(ctx, "active", now)
+	require.NoError(t, err)
+	assert.Equal(t, recipe.ID, shared.ID)
+	_, err = storage.GetSharedRecipe(ctx, "expired", now)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = storage.GetSharedRecipe(ctx, "unknown", now)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	shares, err := storage.GetRecipeShares(ctx, recipe.ID.Hex())
+	require.NoError(t, err)
+	require.Len(t, shares, 2)
+	assert.Equal(t, "expired", shares[0].TokenHash)
+
+	// Share links are revoked once, and only through their recipe
+	assert.ErrorIs(t, storage.RevokeRecipeShare(ctx, share.ID.Hex(), primitive.NewObjectID().Hex()), ErrNotFound)
+	require.NoError(t, storage.RevokeRecipeShare(ctx, share.ID.Hex(), recipe.ID.Hex()))
+	assert.ErrorIs(t, storage.RevokeRecipeShare(ctx, share.ID.Hex(), recipe.ID.Hex()), ErrNotFound)
+	_, err = storage.GetSharedRecipe(ctx, "active", now)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	// Links of deleted recipes are not found
+	deleted, err := storage.CreateRecipe(ctx, &models.Recipe{Title: "Deleted", OwnerID: owner, CreatedAt: time.Now()})
+	require.NoError(t, err)
+	_, err = storage.CreateRecipeShare(ctx, &models.RecipeShare{RecipeID: deleted.ID, TokenHash: "deleted", CreatedBy: owner, CreatedAt: now})
+	require.NoError(t, err)
+	require.NoError(t, storage.DeleteRecipe(ctx, deleted.ID.Hex()))
+	_, err = storage.GetSharedRecipe(ctx, "deleted", now)
+	assert.ErrorIs(t, err, ErrNotFound)
+}
+
+func TestCountRecipesByVisibility(t *testing.T) {
+	storage, cleanup := createTestStorage(t)
+	defer cleanup()
+
+	ctx := context.Background()
+	for _, recipe := range []*models.Recipe{
+		{Title: "Legacy"},
+		{Title: "Private", OwnerID: primitive.NewObjectID(), Visibility: models.VisibilityPrivate},
+		{Title: "Public", OwnerID: primitive.NewObjectID(), Visibility: models.VisibilityPublic},
+	} {
+		_, err := storage.CreateRecipe(ctx, recipe)
+		require.NoError(t, err)
+	}
+	// Recipes in households are counted too
+	_, err := storage.CreateRecipe(WithHousehold(ctx, primitive.NewObjectID()), &models.Recipe{Title: "Household", Visibility: models.VisibilityPublic})
+	require.NoError(t, err)
+
+	counts, err := storage.CountRecipesByVisibility(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, map[string]int64{"none": 1, "private": 1, "public": 2}, counts)
+}
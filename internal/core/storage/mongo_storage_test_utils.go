@@ -67,10 +67,20 @@ func createTestStorage(t *testing.T) (*MongoStorage, func()) {
 	}
 
 	// Create storage instance
+	db := client.Database("test_db")
 	storage := &MongoStorage{
-		client:     client,
-		db:         client.Database("test_db"),
-		collection: client.Database("test_db").Collection("recipes"),
+		client:           client,
+		db:               db,
+		collection:       db.Collection("recipes"),
+		aiCache:          db.Collection("ai_cache"),
+		aiUsage:          db.Collection("ai_usage"),
+		users:            db.Collection("users"),
+		loginAttempts:    db.Collection("login_attempts"),
+		auditLog:         db.Collection("audit_log"),
+		apiKeys:          db.Collection("api_keys"),
+		households:       db.Collection("households"),
+		householdInvites: db.Collection("household_invites"),
+		recipeShares:     db.Collection("recipe_shares"),
 	}
 
 	// Initialize storage
@@ -86,6 +96,12 @@ func createTestStorage(t *testing.T) (*MongoStorage, func()) {
 		if err := storage.collection.Drop(ctx); err != nil {
 			t.Errorf("Failed to drop test collection: %v", err)
 		}
+		if err := storage.aiCache.Drop(ctx); err != nil {
+			t.Errorf("Failed to drop test AI cache collection: %v", err)
+		}
+		if err := storage.aiUsage.Drop(ctx); err != nil {
+			t.Errorf("Failed to drop test AI usage collection: %v", err)
+		}
 		if err := storage.client.Disconnect(ctx); err != nil {
 			t.Errorf("Failed to disconnect client: %v", err)
 		}
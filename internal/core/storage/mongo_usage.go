@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func (s *MongoStorage) CreateAIUsage(ctx context.Context, usage *models.AIUsage) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.aiUsage.InsertOne(ctx, usage)
+	if err != nil {
+		return fmt.Errorf("%w: failed to save AI usage: %v", ErrDatabaseError, err)
+	}
+
+	usage.ID = result.InsertedID.(primitive.ObjectID)
+
+	return nil
+}
+
+func (s *MongoStorage) GetAIUsageCost(ctx context.Context, from time.Time, to time.Time) (float64, error) {
+	summary, err := s.GetAIUsageSummary(ctx, from, to)
+	if err != nil {
+		return 0, err
+	}
+	return summary.Cost, nil
+}
+
+func (s *MongoStorage) GetAIUsageSummary(ctx context.Context, from time.Time, to time.Time) (*models.AIUsageSummary, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	pipeline := bson.A{
+		bson.M{"$match": bson.M{"created_at": bson.M{"$gte": from, "$lt": to}}},
+		bson.M{"$group": bson.M{
+			"_id":               "$model",
+			"imports":           bson.M{"$sum": 1},
+			"prompt_tokens":     bson.M{"$sum": "$prompt_tokens"},
+			"completion_tokens": bson.M{"$sum": "$completion_tokens"},
+			"cost":              bson.M{"$sum": "$cost"},
+		}},
+		bson.M{"$sort": bson.M{"cost": -1, "_id": 1}},
+	}
+
+	cursor, err := s.aiUsage.Aggregate(ctx, pipeline)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to aggregate AI usage: %v", ErrDatabaseError, err)
+	}
+	defer cursor.Close(ctx)
+
+	modelUsages := []models.AIModelUsage{}
+	if err := cursor.All(ctx, &modelUsages); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode AI usage: %v", ErrDatabaseError, err)
+	}
+
+	summary := &models.AIUsageSummary{
+		From:   from,
+		To:     to,
+		Models: modelUsages,
+	}
+	for _, usage := range modelUsages {
+		summary.Imports += usage.Imports
+		summary.PromptTokens += usage.PromptTokens
+		summary.CompletionTokens += usage.CompletionTokens
+		summary.Cost += usage.Cost
+	}
+
+	return summary, nil
+}
@@ -0,0 +1,224 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func (s *MongoStorage) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+
+	return s.getUser(ctx, bson.M{"_id": objID}, fmt.Sprintf("user with ID %s", id))
+}
+
+func (s *MongoStorage) GetUserByUsername(ctx context.Context, username string) (*models.User, error) {
+	return s.getUser(ctx, bson.M{"username": username}, fmt.Sprintf("user %s", username))
+}
+
+func (s *MongoStorage) getUser(ctx context.Context, filter bson.M, description string) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var user models.User
+	err := s.users.FindOne(ctx, filter).Decode(&user)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: %s", ErrNotFound, description)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return &user, nil
+}
+
+func (s *MongoStorage) GetLoginAttempts(ctx context.Context, key string) (*models.LoginAttempts, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var attempts models.LoginAttempts
+	err := s.loginAttempts.FindOne(ctx, bson.M{"_id": key}).Decode(&attempts)
+	if err != nil {
+		if err == mongo.ErrNoDocuments {
+			return nil, fmt.Errorf("%w: login attempts of %s", ErrNotFound, key)
+		}
+		return nil, fmt.Errorf("%w: %v", ErrDatabaseError, err)
+	}
+
+	return &attempts, nil
+}
+
+func (s *MongoStorage) RecordFailedLogin(ctx context.Context, key string, window time.Duration) (*models.LoginAttempts, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// The count starts over atomically if there is none or its first failure is outside the window.
+	// Expressions in the same $set stage refer to the document before the update.
+	now := time.Now()
+	startOver := bson.M{"$or": bson.A{
+		bson.M{"$eq": bson.A{bson.M{"$type": "$first_failure_at"}, "missing"}},
+		bson.M{"$lt": bson.A{"$first_failure_at", now.Add(-window)}},
+	}}
+	update := bson.A{bson.M{"$set": bson.M{
+		"failures":         bson.M{"$cond": bson.A{startOver, 1, bson.M{"$add": bson.A{"$failures", 1}}}},
+		"first_failure_at": bson.M{"$cond": bson.A{startOver, now, "$first_failure_at"}},
+		"expires_at":       bson.M{"$max": bson.A{now.Add(window), "$locked_until"}},
+	}}}
+
+	var attempts models.LoginAttempts
+	err := s.loginAttempts.FindOneAndUpdate(ctx, bson.M{"_id": key}, update,
+		options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After),
+	).Decode(&attempts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to record failed login: %v", ErrDatabaseError, err)
+	}
+
+	return &attempts, nil
+}
+
+func (s *MongoStorage) ReleaseLoginAttempt(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// The count never goes below zero, also when the window started over in between
+	_, err := s.loginAttempts.UpdateOne(ctx, bson.M{"_id": key, "failures": bson.M{"$gt": 0}}, bson.M{
+		"$inc": bson.M{"failures": -1},
+	})
+	if err != nil {
+		return fmt.Errorf("%w: failed to release login attempt: %v", ErrDatabaseError, err)
+	}
+
+	return nil
+}
+
+func (s *MongoStorage) LockLogin(ctx context.Context, key string, until time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	_, err := s.loginAttempts.UpdateOne(ctx, bson.M{"_id": key}, bson.M{
+		"$set": bson.M{"locked_until": until},
+		"$max": bson.M{"expires_at": until},
+	}, options.Update().SetUpsert(true))
+	if err != nil {
+		return fmt.Errorf("%w: failed to lock logins: %v", ErrDatabaseError, err)
+	}
+
+	return nil
+}
+
+func (s *MongoStorage) DeleteLoginAttempts(ctx context.Context, key string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.loginAttempts.DeleteOne(ctx, bson.M{"_id": key})
+	if err != nil {
+		return fmt.Errorf("%w: failed to delete login attempts: %v", ErrDatabaseError, err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("%w: login attempts of %s", ErrNotFound, key)
+	}
+
+	return nil
+}
+
+func (s *MongoStorage) GetUsers(ctx context.Context) ([]models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	cursor, err := s.users.Find(ctx, bson.M{}, options.Find().SetSort(bson.D{{Key: "username", Value: 1}}))
+	if err != nil {
+		return nil, fmt.Errorf("%w: failed to find users: %v", ErrDatabaseError, err)
+	}
+	defer cursor.Close(ctx)
+
+	users := []models.User{}
+	if err := cursor.All(ctx, &users); err != nil {
+		return nil, fmt.Errorf("%w: failed to decode users: %v", ErrDatabaseError, err)
+	}
+
+	return users, nil
+}
+
+func (s *MongoStorage) CreateUser(ctx context.Context, user *models.User) (*models.User, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	result, err := s.users.InsertOne(ctx, user)
+	if err != nil {
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, fmt.Errorf("%w: user %s", ErrAlreadyExists, user.Username)
+		}
+		return nil, fmt.Errorf("%w: failed to save user: %v", ErrDatabaseError, err)
+	}
+
+	user.ID = result.InsertedID.(primitive.ObjectID)
+
+	return user, nil
+}
+
+func (s *MongoStorage) SetUserPassword(ctx context.Context, id string, passwordHash string) error {
+	return s.updateUser(ctx, id, bson.M{"$set": bson.M{"password_hash": passwordHash, "updated_at": time.Now()}})
+}
+
+func (s *MongoStorage) SetUserDisabled(ctx context.Context, id string, disabledAt *time.Time) error {
+	if disabledAt == nil {
+		return s.updateUser(ctx, id, bson.M{"$unset": bson.M{"disabled_at": ""}, "$set": bson.M{"updated_at": time.Now()}})
+	}
+	return s.updateUser(ctx, id, bson.M{"$set": bson.M{"disabled_at": *disabledAt, "updated_at": time.Now()}})
+}
+
+func (s *MongoStorage) SetUserAdmin(ctx context.Context, id string, admin bool) error {
+	if !admin {
+		return s.updateUser(ctx, id, bson.M{"$unset": bson.M{"admin": ""}, "$set": bson.M{"updated_at": time.Now()}})
+	}
+	return s.updateUser(ctx, id, bson.M{"$set": bson.M{"admin": true, "updated_at": time.Now()}})
+}
+
+func (s *MongoStorage) updateUser(ctx context.Context, id string, update bson.M) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+
+	result, err := s.users.UpdateOne(ctx, bson.M{"_id": objID}, update)
+	if err != nil {
+		return fmt.Errorf("%w: failed to update user: %v", ErrDatabaseError, err)
+	}
+	if result.MatchedCount == 0 {
+		return fmt.Errorf("%w: user with ID %s", ErrNotFound, id)
+	}
+
+	return nil
+}
+
+func (s *MongoStorage) DeleteUser(ctx context.Context, id string) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	objID, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidID, err)
+	}
+
+	result, err := s.users.DeleteOne(ctx, bson.M{"_id": objID})
+	if err != nil {
+		return fmt.Errorf("%w: failed to delete user: %v", ErrDatabaseError, err)
+	}
+	if result.DeletedCount == 0 {
+		return fmt.Errorf("%w: user with ID %s", ErrNotFound, id)
+	}
+
+	return nil
+}
@@ -0,0 +1,22 @@
+package storage
+
+import (
+	"context"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type householdKey struct{}
+
+// WithHousehold returns a context whose recipe queries are scoped to the household. Recipes of
+// other households cannot be found, changed or created in the context.
+func WithHousehold(ctx context.Context, householdID primitive.ObjectID) context.Context {
+	return context.WithValue(ctx, householdKey{}, householdID)
+}
+
+// HouseholdFromContext returns the household the context is scoped to, or false if it is not
+// scoped to a household
+func HouseholdFromContext(ctx context.Context) (primitive.ObjectID, bool) {
+	householdID, ok := ctx.Value(householdKey{}).(primitive.ObjectID)
+	return householdID, ok && !householdID.IsZero()
+}
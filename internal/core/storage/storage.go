@@ -2,17 +2,157 @@ package storage
 
 import (
 	"context"
+	"time"
 
 	"github.com/AntonLuning/RecipeBank/pkg/core/models"
 )
 
-// RecipeStorage defines the interface for recipe storage operations
+// RecipeStorage defines the interface for recipe storage operations. Recipe queries are isolated
+// by household (see WithHousehold): in the context of a household only its recipes are found,
+// and otherwise only recipes outside households and public recipes. Collections, meal plans and
+// grocery lists are isolated the same way, but outside households only those of the given owner
+// are found.
 type RecipeStorage interface {
 	GetRecipeByID(ctx context.Context, id string) (*models.Recipe, error)
 	GetRecipes(ctx context.Context, filter models.RecipeFilter, page, limit int) (*models.RecipePage, error)
+	// CreateRecipe creates a recipe in the household of the context, or outside households
 	CreateRecipe(ctx context.Context, recipe *models.Recipe) (*models.Recipe, error)
+	// UpdateRecipe updates a recipe, its owner, household, source and translation cannot be changed
 	UpdateRecipe(ctx context.Context, id string, recipe *models.Recipe) (*models.Recipe, error)
+	// DeleteRecipe deletes a recipe and its translations
 	DeleteRecipe(ctx context.Context, id string) error
+	// SetTranslationsVisibility gives all translations of a recipe the visibility at once
+	SetTranslationsVisibility(ctx context.Context, id string, visibility string) error
+	// GetTags returns at most limit tags used in the recipes the viewer can see (all recipes if
+	// nil), the most used first
+	GetTags(ctx context.Context, viewer *models.RecipeViewer, limit int) ([]string, error)
+	// GetRecipeFingerprints returns the fields compared to find duplicates of the recipes the
+	// viewer can see (all if nil) that are not translations, the oldest first
+	GetRecipeFingerprints(ctx context.Context, viewer *models.RecipeViewer) ([]models.RecipeFingerprint, error)
+	// GetRecipeFingerprintsByKeys is GetRecipeFingerprints for the recipes with any of the
+	// duplicate keys
+	GetRecipeFingerprintsByKeys(ctx context.Context, viewer *models.RecipeViewer, keys []string) ([]models.RecipeFingerprint, error)
+	// SetMissingDuplicateKeys sets the duplicate keys of the recipes that were created before
+	// recipes had them, in every household. It returns the number of recipes updated.
+	SetMissingDuplicateKeys(ctx context.Context, keys func(models.RecipeFingerprint) []string) (int64, error)
+	// AssignRecipeOwner gives the recipes without an owner, which were created before recipes
+	// had owners, to the user with the visibility. It returns the number of recipes assigned.
+	AssignRecipeOwner(ctx context.Context, ownerID string, visibility string) (int64, error)
+	CreateRecipeShare(ctx context.Context, share *models.RecipeShare) (*models.RecipeShare, error)
+	// GetRecipeShares returns the share links of a recipe, the newest first
+	GetRecipeShares(ctx context.Context, recipeID string) ([]models.RecipeShare, error)
+	// RevokeRecipeShare revokes a share link of the recipe, ErrNotFound if it is already revoked
+	RevokeRecipeShare(ctx context.Context, id string, recipeID string) error
+	// GetSharedRecipe returns the recipe of an active share link by the hash of its token. It is not
+	// isolated by household, since the link grants access to the recipe.
+	GetSharedRecipe(ctx context.Context, tokenHash string, now time.Time) (*models.Recipe, error)
+	// CreateCollection creates a collection in the household of the context, or outside households
+	CreateCollection(ctx context.Context, collection *models.Collection) (*models.Collection, error)
+	GetCollectionByID(ctx context.Context, id string, ownerID string) (*models.Collection, error)
+	// GetCollections returns the collections, by name
+	GetCollections(ctx context.Context, ownerID string) ([]models.Collection, error)
+	// UpdateCollection updates a collection, its owner and household cannot be changed
+	UpdateCollection(ctx context.Context, id string, ownerID string, collection *models.Collection) (*models.Collection, error)
+	DeleteCollection(ctx context.Context, id string, ownerID string) error
+	// CreateMealPlan creates a meal plan in the household of the context, or outside households
+	CreateMealPlan(ctx context.Context, plan *models.MealPlan) (*models.MealPlan, error)
+	GetMealPlanByID(ctx context.Context, id string, ownerID string) (*models.MealPlan, error)
+	// GetMealPlans returns the meal plans, by name
+	GetMealPlans(ctx context.Context, ownerID string) ([]models.MealPlan, error)
+	// UpdateMealPlan updates a meal plan, its owner and household cannot be changed
+	UpdateMealPlan(ctx context.Context, id string, ownerID string, plan *models.MealPlan) (*models.MealPlan, error)
+	DeleteMealPlan(ctx context.Context, id string, ownerID string) error
+	// CreateGroceryList creates a grocery list in the household of the context, or outside
+	// households
+	CreateGroceryList(ctx context.Context, list *models.GroceryList) (*models.GroceryList, error)
+	GetGroceryListByID(ctx context.Context, id string, ownerID string) (*models.GroceryList, error)
+	// GetGroceryLists returns the grocery lists, by name
+	GetGroceryLists(ctx context.Context, ownerID string) ([]models.GroceryList, error)
+	// UpdateGroceryList updates a grocery list, its owner and household cannot be changed
+	UpdateGroceryList(ctx context.Context, id string, ownerID string, list *models.GroceryList) (*models.GroceryList, error)
+	DeleteGroceryList(ctx context.Context, id string, ownerID string) error
 	Initialize(ctx context.Context) error
 	Close(ctx context.Context) error
 }
+
+// CacheStorage defines the interface for storing cached AI results that expire
+type CacheStorage interface {
+	GetCacheEntry(ctx context.Context, key string) ([]byte, bool, error)
+	SetCacheEntry(ctx context.Context, key string, value []byte, ttl time.Duration) error
+}
+
+// UsageStorage defines the interface for AI usage record operations
+type UsageStorage interface {
+	CreateAIUsage(ctx context.Context, usage *models.AIUsage) error
+	GetAIUsageCost(ctx context.Context, from time.Time, to time.Time) (float64, error)
+	GetAIUsageSummary(ctx context.Context, from time.Time, to time.Time) (*models.AIUsageSummary, error)
+}
+
+// UserStorage defines the interface for user operations. Users are managed by administrators,
+// not through the API.
+type UserStorage interface {
+	GetUserByID(ctx context.Context, id string) (*models.User, error)
+	GetUserByUsername(ctx context.Context, username string) (*models.User, error)
+	// GetUsers returns all users, by username
+	GetUsers(ctx context.Context) ([]models.User, error)
+	// CreateUser creates a user, ErrAlreadyExists if the username is taken
+	CreateUser(ctx context.Context, user *models.User) (*models.User, error)
+	SetUserPassword(ctx context.Context, id string, passwordHash string) error
+	// SetUserDisabled disables the user at the given time, or enables the user if it is nil
+	SetUserDisabled(ctx context.Context, id string, disabledAt *time.Time) error
+	SetUserAdmin(ctx context.Context, id string, admin bool) error
+	DeleteUser(ctx context.Context, id string) error
+	// GetLoginAttempts returns the failed logins of the key, ErrNotFound if there are none
+	GetLoginAttempts(ctx context.Context, key string) (*models.LoginAttempts, error)
+	// RecordFailedLogin counts a failed login of the key, starting over if the first counted
+	// failure is older than the window
+	RecordFailedLogin(ctx context.Context, key string, window time.Duration) (*models.LoginAttempts, error)
+	// ReleaseLoginAttempt uncounts a login attempt of the key that was counted before it turned
+	// out to be valid
+	ReleaseLoginAttempt(ctx context.Context, key string) error
+	// LockLogin locks logins of the key until the given time
+	LockLogin(ctx context.Context, key string, until time.Time) error
+	// DeleteLoginAttempts removes the failed logins and lock of the key
+	DeleteLoginAttempts(ctx context.Context, key string) error
+}
+
+// AuditStorage defines the interface for the audit log
+type AuditStorage interface {
+	CreateAuditEntry(ctx context.Context, entry *models.AuditEntry) error
+}
+
+// HouseholdStorage defines the interface for household operations
+type HouseholdStorage interface {
+	CreateHousehold(ctx context.Context, household *models.Household) (*models.Household, error)
+	GetHouseholdByID(ctx context.Context, id string) (*models.Household, error)
+	// GetHouseholds returns the households the user is a member of, by name
+	GetHouseholds(ctx context.Context, userID string) ([]models.Household, error)
+	// AddHouseholdMember adds a member to a household, ErrAlreadyExists if the user is a member
+	AddHouseholdMember(ctx context.Context, id string, member models.HouseholdMember) error
+	// SetHouseholdMemberRole changes the role of a member, ErrNotFound if the user is not a member
+	SetHouseholdMemberRole(ctx context.Context, id string, userID string, role string) error
+	// RemoveHouseholdMember removes a member, ErrNotFound if the user is not a member
+	RemoveHouseholdMember(ctx context.Context, id string, userID string) error
+	CreateHouseholdInvite(ctx context.Context, invite *models.HouseholdInvite) (*models.HouseholdInvite, error)
+	// GetHouseholdInviteByHash returns the invite with the token hash, also when it is accepted or
+	// expired
+	GetHouseholdInviteByHash(ctx context.Context, tokenHash string) (*models.HouseholdInvite, error)
+	// AcceptHouseholdInvite marks the invite as accepted by the user, ErrNotFound if there is no
+	// invite with the hash that is unaccepted and unexpired at the given time
+	AcceptHouseholdInvite(ctx context.Context, tokenHash string, userID string, now time.Time) (*models.HouseholdInvite, error)
+	// ReleaseHouseholdInvite makes an invite that the user accepted unaccepted again, ErrNotFound
+	// if the user has not accepted it
+	ReleaseHouseholdInvite(ctx context.Context, id string, userID string) error
+}
+
+// APIKeyStorage defines the interface for API key operations
+type APIKeyStorage interface {
+	CreateAPIKey(ctx context.Context, key *models.APIKey) (*models.APIKey, error)
+	GetAPIKeyByHash(ctx context.Context, keyHash string) (*models.APIKey, error)
+	// GetAPIKeys returns the API keys of the user, including revoked and expired ones, the newest
+	// first
+	GetAPIKeys(ctx context.Context, userID string) ([]models.APIKey, error)
+	// RevokeAPIKey revokes an API key of the user, ErrNotFound if the user has no active key with the ID
+	RevokeAPIKey(ctx context.Context, id string, userID string) error
+	SetAPIKeyLastUsed(ctx context.Context, id string, usedAt time.Time) error
+}
@@ -0,0 +1,83 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Trace context of a caller, as sent in the traceparent header
+const (
+	_TraceParent = "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"
+	_TraceID     = "4bf92f3577b34da6a3ce929d0e0e4736"
+)
+
+// newSpanRecorder installs a tracer provider that records the ended spans
+func newSpanRecorder(t *testing.T) *tracetest.SpanRecorder {
+	t.Helper()
+	_, err := Setup(context.Background(), "test", Config{})
+	require.NoError(t, err)
+
+	recorder := tracetest.NewSpanRecorder()
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func TestSetup(t *testing.T) {
+	shutdown, err := Setup(context.Background(), "test", Config{Exporter: ExporterStdout, SampleRatio: 1})
+	require.NoError(t, err)
+	assert.NoError(t, shutdown(context.Background()))
+
+	_, err = Setup(context.Background(), "test", Config{Exporter: "zipkin"})
+	assert.ErrorContains(t, err, "unsupported trace exporter")
+}
+
+func TestEnd(t *testing.T) {
+	recorder := newSpanRecorder(t)
+
+	_, span := Start(context.Background(), "ok")
+	End(span, nil)
+	_, span = Start(context.Background(), "failed")
+	End(span, errors.New("database is down"))
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+	assert.Equal(t, "database is down", spans[1].Status().Description)
+}
+
+func TestPropagation(t *testing.T) {
+	recorder := newSpanRecorder(t)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /shared/{token}", func(w http.ResponseWriter, r *http.Request) {
+		// Requests to other services continue the trace
+		header := http.Header{}
+		Inject(r.Context(), header)
+		assert.Contains(t, header.Get("traceparent"), _TraceID)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/shared/shr_secret", nil)
+	req.Header.Set("traceparent", _TraceParent)
+	Middleware(mux).ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 1)
+	assert.Equal(t, "GET /shared/{token}", spans[0].Name())
+	assert.Equal(t, trace.SpanKindServer, spans[0].SpanKind())
+	assert.Equal(t, _TraceID, spans[0].SpanContext().TraceID().String())
+	assert.Equal(t, "00f067aa0ba902b7", spans[0].Parent().SpanID().String())
+}
@@ -3,6 +3,7 @@ package ui
 import (
 	"fmt"
 
+	"github.com/AntonLuning/RecipeBank/internal/tracing"
 	"github.com/caarlos0/env/v11"
 )
 
@@ -19,6 +20,10 @@ type AppConfig struct {
 	Port uint16 `env:"PORT" envDefault:"9999"`
 	// Path of static assets
 	AssetsPath string `env:"ASSETS_PATH,required"`
+	// URL the UI is publicly reached at, for absolute links in the Open Graph tags of shared recipes
+	PublicURL string `env:"PUBLIC_URL" envDefault:"http://localhost:9999"`
+	// Tracing of requests, continued by the API
+	Tracing tracing.Config `envPrefix:"TRACING_"`
 }
 
 func Config() AppConfig {
@@ -0,0 +1,20 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/AntonLuning/RecipeBank/internal/tracing"
+)
+
+// getAPI sends a GET request to the API, passing on the trace context of the page request so the
+// API continues its trace
+func getAPI(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	tracing.Inject(ctx, req.Header)
+
+	return http.DefaultClient.Do(req)
+}
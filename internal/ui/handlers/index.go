@@ -12,7 +12,7 @@ import (
 
 func GetIndexPage(w http.ResponseWriter, r *http.Request) {
 	// Fetch recipes from the API
-	resp, err := http.Get("http://localhost:9876/api/v1/recipe")
+	resp, err := getAPI(r.Context(), "http://localhost:9876/api/v1/recipe")
 	if err != nil {
 		slog.Error("Failed to fetch recipes", "error", err)
 		http.Error(w, "Failed to fetch recipes", http.StatusInternalServerError)
@@ -0,0 +1,130 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/AntonLuning/RecipeBank/internal/ui/views"
+	"github.com/AntonLuning/RecipeBank/pkg/core/models"
+	"github.com/a-h/templ"
+)
+
+// API endpoint of shared recipes, which needs no authentication
+const _SharedRecipeURL = "http://localhost:9876/api/v1/shared/"
+
+var errSharedRecipeNotFound = errors.New("shared recipe not found")
+
+// GetSharedRecipePage renders the public page of a recipe share link, with Open Graph tags for
+// link previews. The absolute links of the tags are built from the public URL of the UI, never from
+// the request.
+func GetSharedRecipePage(publicURL string) http.HandlerFunc {
+	publicURL = strings.TrimSuffix(publicURL, "/")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.PathValue("token")
+		recipe, err := fetchSharedRecipe(r.Context(), token)
+		if errors.Is(err, errSharedRecipeNotFound) {
+			w.WriteHeader(http.StatusNotFound)
+			views.SharedRecipeNotFound().Render(r.Context(), w)
+			return
+		}
+		if err != nil {
+			slog.Error("Failed to fetch shared recipe", "error", err)
+			http.Error(w, "Failed to fetch recipe", http.StatusInternalServerError)
+			return
+		}
+
+		pageURL := publicURL + "/shared/" + url.PathEscape(token)
+		meta := views.ShareMeta{
+			Title:       recipe.Title,
+			Description: recipe.Description,
+			URL:         pageURL,
+		}
+		if meta.Description == "" {
+			meta.Description = "A recipe shared from RecipeBank"
+		}
+		if recipe.Image != "" {
+			meta.ImageURL = pageURL + "/image"
+		}
+
+		templ.Handler(views.SharedLayout(meta, views.SharedRecipe(recipe, meta.ImageURL))).ServeHTTP(w, r)
+	}
+}
+
+// GetSharedRecipeImage serves the image of a shared recipe, so it can be used in link previews
+func GetSharedRecipeImage(w http.ResponseWriter, r *http.Request) {
+	recipe, err := fetchSharedRecipe(r.Context(), r.PathValue("token"))
+	if errors.Is(err, errSharedRecipeNotFound) {
+		http.NotFound(w, r)
+		return
+	}
+	if err != nil {
+		slog.Error("Failed to fetch shared recipe", "error", err)
+		http.Error(w, "Failed to fetch recipe", http.StatusInternalServerError)
+		return
+	}
+
+	if recipe.Image == "" {
+		http.NotFound(w, r)
+		return
+	}
+	// Images are stored as base64, with or without a data URL prefix
+	encoded := recipe.Image
+	if _, data, ok := strings.Cut(encoded, ";base64,"); ok {
+		encoded = data
+	}
+	image, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		slog.Error("Failed to decode shared recipe image", "error", err)
+		http.Error(w, "Failed to decode image", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", http.DetectContentType(image))
+	// Revoked links must stop serving the image, so neither browsers nor shared caches reuse it
+	// without asking again
+	w.Header().Set("Cache-Control", "private, no-cache")
+	w.Write(image)
+}
+
+// fetchSharedRecipe fetches the recipe of a share link from the API
+func fetchSharedRecipe(ctx context.Context, token string) (*models.SharedRecipe, error) {
+	if token == "" {
+		return nil, errSharedRecipeNotFound
+	}
+
+	resp, err := getAPI(ctx, _SharedRecipeURL+url.PathEscape(token))
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, errSharedRecipeNotFound
+	}
+
+	var apiResp struct {
+		Success bool                 `json:"success"`
+		Data    *models.SharedRecipe `json:"data"`
+		Error   *models.APIError     `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&apiResp); err != nil {
+		return nil, fmt.Errorf("failed to decode API response: %w", err)
+	}
+	if !apiResp.Success || apiResp.Data == nil {
+		errorMsg := "unknown error occurred"
+		if apiResp.Error != nil {
+			errorMsg = apiResp.Error.Message
+		}
+		return nil, fmt.Errorf("API returned an error: %s", errorMsg)
+	}
+
+	return apiResp.Data, nil
+}
@@ -12,8 +12,10 @@ func InitAssets(m *http.ServeMux, assetsPath string, isDebug bool) {
 	m.Handle("GET /favicon.ico", serveFavicon(assetsPath))
 }
 
-func InitRoutes(m *http.ServeMux) {
+func InitRoutes(m *http.ServeMux, publicURL string) {
 	m.HandleFunc("GET /", handlers.GetIndexPage)
+	m.HandleFunc("GET /shared/{token}", handlers.GetSharedRecipePage(publicURL))
+	m.HandleFunc("GET /shared/{token}/image", handlers.GetSharedRecipeImage)
 }
 
 func serveFavicon(assetsPath string) http.Handler {
@@ -1,5 +1,11 @@
 package models
 
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
 // Request models
 
 // GetRecipesQuery represents query parameters for getting recipes
@@ -13,14 +19,23 @@ type GetRecipesQuery struct {
 // RecipeRequest represents the request body for creating/updating recipes
 // @Description Recipe creation/update request
 type RecipeRequest struct {
-	Title       string       `json:"title" validate:"required" example:"Chocolate Chip Cookies"`
-	Description string       `json:"description" example:"Delicious homemade chocolate chip cookies"`
-	Ingredients []Ingredient `json:"ingredients" validate:"required,min=1,dive"`
-	Steps       []string     `json:"steps" validate:"required,min=1" example:"['Preheat oven to 375°F', 'Mix ingredients', 'Bake for 10 minutes']"`
-	CookTime    int          `json:"cook_time" example:"30"`
-	Servings    int          `json:"servings" example:"12"`
-	Tags        []string     `json:"tags" example:"['dessert', 'cookies', 'baking']"`
-	Image       string       `json:"image,omitempty" example:"data:image/jpeg;base64,/9j/4AAQSkZJRgABAQAAAQ..."` // Base64 encoded image (optional)
+	Title       string         `json:"title" validate:"required" example:"Chocolate Chip Cookies"`
+	Description string         `json:"description" example:"Delicious homemade chocolate chip cookies"`
+	Ingredients []Ingredient   `json:"ingredients" validate:"required,min=1,dive"`
+	Steps       []string       `json:"steps" validate:"required,min=1" example:"['Preheat oven to 375°F', 'Mix ingredients', 'Bake for 10 minutes']"`
+	CookTime    int            `json:"cook_time" example:"30"`
+	Servings    int            `json:"servings" example:"12"`
+	Tags        []string       `json:"tags" example:"['dessert', 'cookies', 'baking']"`
+	Image       string         `json:"image,omitempty" example:"data:image/jpeg;base64,/9j/4AAQSkZJRgABAQAAAQ..."` // Base64 encoded image (optional)
+	Source      *SourceRequest `json:"source,omitempty"`                                                           // Where the recipe came from, manual if not set (only on creation)
+	Visibility  string         `json:"visibility,omitempty" enums:"private,household,public" example:"private"`    // Who can see the recipe, private on creation and unchanged on update if not set
+}
+
+// SourceRequest represents the source of a recipe given on creation
+// @Description Source of a recipe entered by hand or imported from a file
+type SourceRequest struct {
+	Type string `json:"type" enums:"manual,file" example:"file"`
+	URL  string `json:"url,omitempty" example:"https://example.com/recipe"`
 }
 
 // Alias the RecipeRequest for better semantics
@@ -34,10 +49,103 @@ type CreateRecipeFromImageRequest struct {
 	ImageType string `json:"image_type" example:"jpeg"`                                        // "jpeg", "jpg", "png"
 }
 
+// CreateRecipeFromImagesRequest represents the request for creating a recipe from several images
+// @Description Request for AI-powered recipe creation from several images of the same recipe (e.g. two pages of a cookbook)
+type CreateRecipeFromImagesRequest struct {
+	Images []CreateRecipeFromImageRequest `json:"images"` // Images in reading order
+}
+
+// CreateRecipeFromPDFRequest represents the request for creating a recipe from a PDF
+// @Description Request for AI-powered recipe creation from a PDF, either with a text layer or with scanned pages
+type CreateRecipeFromPDFRequest struct {
+	PDF string `json:"pdf" example:"JVBERi0xLjQK..."` // Base64 encoded PDF
+}
+
 // CreateRecipeFromUrlRequest represents the request for creating a recipe from a URL
 // @Description Request for AI-powered recipe creation from URL
 type CreateRecipeFromUrlRequest struct {
-	URL string `json:"url" example:"https://example.com/recipe"` // URL to a webpage with recipe or to an image of a recipe
+	URL string `json:"url" example:"https://example.com/recipe"` // URL to a webpage with recipe or to an image or PDF of a recipe
+}
+
+// TranslateRecipeRequest represents the request for translating a recipe
+// @Description Request for AI-powered translation of a recipe
+type TranslateRecipeRequest struct {
+	Language string `json:"language" example:"english"` // Language name (e.g. "english") or code (e.g. "en")
+}
+
+// SubstitutionsRequest represents the request for ingredient substitution suggestions
+// @Description Request for substitutes of an ingredient of a recipe
+type SubstitutionsRequest struct {
+	Ingredient string   `json:"ingredient" example:"butter"`           // Name of the ingredient to substitute, as in the recipe
+	Dietary    []string `json:"dietary,omitempty" example:"['vegan']"` // Constraints the substitutes must meet: "vegan", "vegetarian", "gluten-free", "nut-free", "dairy-free"
+}
+
+// LoginRequest represents the request for logging in
+// @Description Pre-shared credentials of a user
+type LoginRequest struct {
+	Username string `json:"username" example:"anton"`
+	Password string `json:"password" example:"secret"`
+}
+
+// RefreshTokenRequest represents the request for new tokens
+// @Description Refresh token issued on login
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+}
+
+// CreateAPIKeyRequest represents the request for creating an API key
+// @Description Name, scopes and optional expiry of a new API key
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name" example:"Kitchen shortcuts"`
+	Scopes    []string   `json:"scopes" example:"recipes:read,ai:import"` // "recipes:read", "recipes:write" and/or "ai:import"
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`                    // Never expires if empty
+}
+
+// CreateRecipeShareRequest represents the request for creating a share link of a recipe
+// @Description Optional expiry of a new share link
+type CreateRecipeShareRequest struct {
+	ExpiresAt *time.Time `json:"expires_at,omitempty"` // Never expires if empty
+}
+
+// CreateHouseholdRequest represents the request for creating a household
+// @Description Name of a new household, the logged in user becomes its owner
+type CreateHouseholdRequest struct {
+	Name string `json:"name" example:"The Smiths"`
+}
+
+// CreateHouseholdInviteRequest represents the request for creating an invite to a household
+// @Description Role of the member who accepts the invite, and its optional expiry
+type CreateHouseholdInviteRequest struct {
+	Role      string     `json:"role" example:"editor"` // "owner", "editor" or "viewer"
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`  // In 7 days if empty
+}
+
+// UpdateHouseholdMemberRequest represents the request for changing the role of a member
+// @Description New role of a household member
+type UpdateHouseholdMemberRequest struct {
+	Role string `json:"role" example:"viewer"` // "owner", "editor" or "viewer"
+}
+
+// CollectionRequest represents the request body for creating/updating collections
+// @Description Collection creation/update request
+type CollectionRequest struct {
+	Name        string               `json:"name" example:"Weeknight dinners"`
+	Description string               `json:"description,omitempty" example:"Quick dinners for busy evenings"`
+	RecipeIDs   []primitive.ObjectID `json:"recipe_ids" swaggertype:"array,string" example:"507f1f77bcf86cd799439011"` // Recipes the user can see
+}
+
+// MealPlanRequest represents the request body for creating/updating meal plans
+// @Description Meal plan creation/update request
+type MealPlanRequest struct {
+	Name  string        `json:"name" example:"Week 12"`
+	Meals []PlannedMeal `json:"meals"` // Recipes the user can see
+}
+
+// GroceryListRequest represents the request body for creating/updating grocery lists
+// @Description Grocery list creation/update request
+type GroceryListRequest struct {
+	Name  string        `json:"name" example:"Saturday shopping"`
+	Items []GroceryItem `json:"items"`
 }
 
 // Response models
@@ -50,9 +158,40 @@ type APIResponse struct {
 	Error   *APIError `json:"error,omitempty"`
 }
 
+// TagSuggestion represents AI suggested tags for a recipe
+// @Description AI suggested tags for a recipe, preferring the tags already used in the collection
+type TagSuggestion struct {
+	Tags    []string `json:"tags" example:"['pasta', 'weeknight', 'italian', 'main course', 'vegetarian']"` // All suggested tags, including the labels below
+	Cuisine string   `json:"cuisine,omitempty" example:"italian"`
+	Course  string   `json:"course,omitempty" example:"main course"`
+	Dietary []string `json:"dietary,omitempty" example:"['vegetarian']"`
+}
+
+// Substitutions represents the substitution options for an ingredient of a recipe
+// @Description Substitution options for an ingredient of a recipe, with quantities adjusted to the recipe
+type Substitutions struct {
+	Ingredient Ingredient           `json:"ingredient"` // The ingredient of the recipe that is substituted
+	Options    []SubstitutionOption `json:"options"`
+	Source     string               `json:"source" example:"ai"` // "ai", or "table" for the built-in substitution table
+}
+
+// SubstitutionOption represents one way to substitute an ingredient
+// @Description One way to substitute an ingredient, possibly with several ingredients (e.g. flaxseed and water for an egg)
+type SubstitutionOption struct {
+	Ingredients []Ingredient `json:"ingredients"`
+	Notes       string       `json:"notes,omitempty" example:"Use 3/4 of the amount, best for sautéing and cakes"`
+}
+
 // APIError represents an API error
 // @Description API error information
 type APIError struct {
 	Code    string `json:"code" example:"validation_error"`
 	Message string `json:"message" example:"The provided input data is invalid"`
+	Details any    `json:"details,omitempty" swaggertype:"object"` // Additional information for some errors, e.g. DuplicateDetails
+}
+
+// DuplicateDetails represents the details of a duplicate_recipe error
+// @Description Existing recipes that the new recipe is likely a duplicate of
+type DuplicateDetails struct {
+	Candidates []DuplicateCandidate `json:"candidates"`
 }
@@ -0,0 +1,43 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Scopes of API keys
+const (
+	ScopeRecipesRead  = "recipes:read"  // Viewing recipes, collections, meal plans and grocery lists
+	ScopeRecipesWrite = "recipes:write" // Creating, updating and deleting them
+	ScopeAIImport     = "ai:import"     // AI imports and other AI features, which have a cost
+)
+
+// APIKeyScopes are the scopes that can be granted to API keys
+var APIKeyScopes = []string{ScopeRecipesRead, ScopeRecipesWrite, ScopeAIImport}
+
+// APIKey represents a key for scripts and integrations, which acts as its user within its scopes
+type APIKey struct {
+	ID         primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	UserID     primitive.ObjectID `bson:"user_id" json:"user_id"`
+	Name       string             `bson:"name" json:"name" example:"Kitchen shortcuts"`
+	Prefix     string             `bson:"prefix" json:"prefix" example:"rbk_Xy3kP9q"` // Start of the key, to tell keys apart
+	KeyHash    string             `bson:"key_hash" json:"-"`                          // SHA-256 hash of the key
+	Scopes     []string           `bson:"scopes" json:"scopes" example:"recipes:read,ai:import"`
+	ExpiresAt  *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	LastUsedAt *time.Time         `bson:"last_used_at,omitempty" json:"last_used_at,omitempty"`
+	RevokedAt  *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	CreatedAt  time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Active reports whether the key can be used at the given time
+func (k *APIKey) Active(now time.Time) bool {
+	return k.RevokedAt == nil && (k.ExpiresAt == nil || k.ExpiresAt.After(now))
+}
+
+// CreatedAPIKey represents a new API key, with the key itself which is only returned once
+// @Description New API key, store the key now since only its hash is kept
+type CreatedAPIKey struct {
+	APIKey
+	Key string `json:"key" example:"rbk_Xy3kP9q..."`
+}
@@ -0,0 +1,77 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Roles of the members of a household
+const (
+	HouseholdRoleOwner  = "owner"  // Manages the household and its members, and changes its recipes
+	HouseholdRoleEditor = "editor" // Creates recipes in the household and changes its recipes
+	HouseholdRoleViewer = "viewer" // Only sees the recipes of the household
+)
+
+// HouseholdRoles are the valid roles of a household member
+var HouseholdRoles = []string{HouseholdRoleOwner, HouseholdRoleEditor, HouseholdRoleViewer}
+
+// Household represents a group of users, such as a family, that share recipes
+// @Description Household and its members
+type Household struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty" example:"507f1f77bcf86cd799439013"`
+	Name      string             `bson:"name" json:"name" example:"The Smiths"`
+	Members   []HouseholdMember  `bson:"members" json:"members"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// HouseholdMember represents a user in a household, and its role
+type HouseholdMember struct {
+	UserID   primitive.ObjectID `bson:"user_id" json:"user_id" example:"507f1f77bcf86cd799439012"`
+	Role     string             `bson:"role" json:"role" enums:"owner,editor,viewer" example:"editor"`
+	JoinedAt time.Time          `bson:"joined_at" json:"joined_at"`
+}
+
+// Member returns the membership of the user, or false if the user is not a member
+func (h *Household) Member(userID primitive.ObjectID) (*HouseholdMember, bool) {
+	for i := range h.Members {
+		if h.Members[i].UserID == userID {
+			return &h.Members[i], true
+		}
+	}
+	return nil, false
+}
+
+// Owners returns the number of members with the owner role
+func (h *Household) Owners() int {
+	owners := 0
+	for _, member := range h.Members {
+		if member.Role == HouseholdRoleOwner {
+			owners++
+		}
+	}
+	return owners
+}
+
+// HouseholdInvite represents an invite link to join a household with a role. An invite can be
+// accepted once, before it expires.
+type HouseholdInvite struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty"`
+	HouseholdID primitive.ObjectID  `bson:"household_id" json:"household_id"`
+	TokenHash   string              `bson:"token_hash" json:"-"` // SHA-256 hash of the token
+	Role        string              `bson:"role" json:"role" enums:"owner,editor,viewer" example:"editor"`
+	CreatedBy   primitive.ObjectID  `bson:"created_by" json:"created_by"`
+	ExpiresAt   time.Time           `bson:"expires_at" json:"expires_at"`
+	AcceptedBy  *primitive.ObjectID `bson:"accepted_by,omitempty" json:"accepted_by,omitempty"`
+	AcceptedAt  *time.Time          `bson:"accepted_at,omitempty" json:"accepted_at,omitempty"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+}
+
+// CreatedHouseholdInvite represents a new invite, with the token which is only returned once
+// @Description New invite, share the link or token now since only its hash is kept
+type CreatedHouseholdInvite struct {
+	HouseholdInvite
+	Token string `json:"token" example:"hhi_Xy3kP9q..."`
+	Link  string `json:"link" example:"/api/v1/households/join?token=hhi_Xy3kP9q..."` // Path to accept the invite at, with a POST request
+}
@@ -0,0 +1,73 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// Collection represents a named group of recipes, such as "Weeknight dinners". Like meal plans
+// and grocery lists it belongs to a household, or to its owner when it is created outside
+// households.
+// @Description Named group of recipes
+type Collection struct {
+	ID          primitive.ObjectID   `bson:"_id,omitempty" json:"id,omitempty" example:"507f1f77bcf86cd799439014"`
+	Name        string               `bson:"name" json:"name" example:"Weeknight dinners"`
+	Description string               `bson:"description,omitempty" json:"description,omitempty" example:"Quick dinners for busy evenings"`
+	RecipeIDs   []primitive.ObjectID `bson:"recipe_ids" json:"recipe_ids" swaggertype:"array,string" example:"507f1f77bcf86cd799439011"`
+	OwnerID     primitive.ObjectID   `bson:"owner_id" json:"owner_id" example:"507f1f77bcf86cd799439012"`                             // User that created the collection
+	HouseholdID *primitive.ObjectID  `bson:"household_id,omitempty" json:"household_id,omitempty" example:"507f1f77bcf86cd799439013"` // Household the collection belongs to, none for personal collections
+	CreatedAt   time.Time            `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time            `bson:"updated_at" json:"updated_at"`
+}
+
+// Meals of a day in a meal plan
+const (
+	MealBreakfast = "breakfast"
+	MealLunch     = "lunch"
+	MealDinner    = "dinner"
+	MealSnack     = "snack"
+)
+
+// Meals are the valid meals of a planned meal, in the order of a day
+var Meals = []string{MealBreakfast, MealLunch, MealDinner, MealSnack}
+
+// MealPlan represents the recipes planned for the meals of some days
+// @Description Recipes planned for meals
+type MealPlan struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty" example:"507f1f77bcf86cd799439015"`
+	Name        string              `bson:"name" json:"name" example:"Week 12"`
+	Meals       []PlannedMeal       `bson:"meals" json:"meals"` // By date and meal
+	OwnerID     primitive.ObjectID  `bson:"owner_id" json:"owner_id" example:"507f1f77bcf86cd799439012"`
+	HouseholdID *primitive.ObjectID `bson:"household_id,omitempty" json:"household_id,omitempty" example:"507f1f77bcf86cd799439013"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// PlannedMeal represents a recipe planned for a meal of a day
+type PlannedMeal struct {
+	Date     string             `bson:"date" json:"date" example:"2025-03-17"` // YYYY-MM-DD
+	Meal     string             `bson:"meal" json:"meal" enums:"breakfast,lunch,dinner,snack" example:"dinner"`
+	RecipeID primitive.ObjectID `bson:"recipe_id" json:"recipe_id" swaggertype:"string" example:"507f1f77bcf86cd799439011"`
+	Servings int                `bson:"servings,omitempty" json:"servings,omitempty" example:"4"` // The servings of the recipe if empty
+}
+
+// GroceryList represents the items to buy, which are checked off while shopping
+// @Description Items to buy
+type GroceryList struct {
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty" example:"507f1f77bcf86cd799439016"`
+	Name        string              `bson:"name" json:"name" example:"Saturday shopping"`
+	Items       []GroceryItem       `bson:"items" json:"items"`
+	OwnerID     primitive.ObjectID  `bson:"owner_id" json:"owner_id" example:"507f1f77bcf86cd799439012"`
+	HouseholdID *primitive.ObjectID `bson:"household_id,omitempty" json:"household_id,omitempty" example:"507f1f77bcf86cd799439013"`
+	CreatedAt   time.Time           `bson:"created_at" json:"created_at"`
+	UpdatedAt   time.Time           `bson:"updated_at" json:"updated_at"`
+}
+
+// GroceryItem represents an item of a grocery list
+type GroceryItem struct {
+	Name     string  `bson:"name" json:"name" example:"Flour"`
+	Quantity float32 `bson:"quantity,omitempty" json:"quantity,omitempty" example:"2.5"`
+	Unit     string  `bson:"unit,omitempty" json:"unit,omitempty" example:"kg"`
+	Checked  bool    `bson:"checked" json:"checked" example:"false"`
+}
@@ -9,17 +9,107 @@ import (
 // Recipe represents a recipe in the system
 // @Description Recipe information
 type Recipe struct {
-	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty" example:"507f1f77bcf86cd799439011"`
-	Title       string             `bson:"title" json:"title" example:"Chocolate Chip Cookies"`
-	Description string             `bson:"description" json:"description" example:"Delicious homemade chocolate chip cookies"`
-	Ingredients []Ingredient       `bson:"ingredients" json:"ingredients"`
-	Steps       []string           `bson:"steps" json:"steps" example:"['Preheat oven to 375°F', 'Mix ingredients', 'Bake for 10 minutes']"`
-	CookTime    int                `bson:"cook_time" json:"cook_time,omitempty" example:"30"` // in minutes
-	Servings    int                `bson:"servings" json:"servings,omitempty" example:"12"`
-	Tags        []string           `bson:"tags" json:"tags,omitempty" example:"['dessert', 'cookies', 'baking']"`
-	Image       string             `bson:"image,omitempty" json:"image,omitempty" example:"data:image/jpeg;base64,/9j/4AAQSkZJRgABAQAAAQ..."` // Base64 encoded image
-	CreatedAt   time.Time          `bson:"created_at" json:"created_at" example:"2023-01-15T09:30:00Z"`
-	UpdatedAt   time.Time          `bson:"updated_at" json:"updated_at" example:"2023-01-15T09:30:00Z"`
+	ID          primitive.ObjectID  `bson:"_id,omitempty" json:"id,omitempty" example:"507f1f77bcf86cd799439011"`
+	Title       string              `bson:"title" json:"title" example:"Chocolate Chip Cookies"`
+	Description string              `bson:"description" json:"description" example:"Delicious homemade chocolate chip cookies"`
+	Ingredients []Ingredient        `bson:"ingredients" json:"ingredients"`
+	Steps       []string            `bson:"steps" json:"steps" example:"['Preheat oven to 375°F', 'Mix ingredients', 'Bake for 10 minutes']"`
+	CookTime    int                 `bson:"cook_time" json:"cook_time,omitempty" example:"30"` // in minutes
+	Servings    int                 `bson:"servings" json:"servings,omitempty" example:"12"`
+	Tags        []string            `bson:"tags" json:"tags,omitempty" example:"['dessert', 'cookies', 'baking']"`
+	Image       string              `bson:"image,omitempty" json:"image,omitempty" example:"data:image/jpeg;base64,/9j/4AAQSkZJRgABAQAAAQ..."` // Base64 encoded image
+	Source      *RecipeSource       `bson:"source,omitempty" json:"source,omitempty"`                                                          // Where the recipe came from
+	OwnerID     primitive.ObjectID  `bson:"owner_id,omitempty" json:"owner_id,omitempty" example:"507f1f77bcf86cd799439012"`                   // User that created the recipe
+	Visibility  string              `bson:"visibility" json:"visibility" enums:"private,household,public" example:"private"`                   // Who can see the recipe besides its owner and admins
+	HouseholdID *primitive.ObjectID `bson:"household_id,omitempty" json:"household_id,omitempty" example:"507f1f77bcf86cd799439013"`           // Household the recipe belongs to, none for personal recipes
+	// Translations are stored as linked copies of the original recipe
+	Language      string              `bson:"language,omitempty" json:"language,omitempty" example:"english"`                              // Language of a translated copy
+	TranslationOf *primitive.ObjectID `bson:"translation_of,omitempty" json:"translation_of,omitempty" example:"507f1f77bcf86cd799439011"` // ID of the original recipe of a translated copy
+	CreatedAt     time.Time           `bson:"created_at" json:"created_at" example:"2023-01-15T09:30:00Z"`
+	UpdatedAt     time.Time           `bson:"updated_at" json:"updated_at" example:"2023-01-15T09:30:00Z"`
+	// Keys its likely duplicates share with it, so they are found without comparing every recipe
+	DuplicateKeys []string `bson:"duplicate_keys" json:"-"`
+}
+
+// Visibilities of a recipe, its owner and admins can always see it
+const (
+	VisibilityPrivate   = "private"   // Only the owner
+	VisibilityHousehold = "household" // The members of its household
+	VisibilityPublic    = "public"    // Everyone, also without logging in
+)
+
+// Visibilities are the valid visibilities of a recipe
+var Visibilities = []string{VisibilityPrivate, VisibilityHousehold, VisibilityPublic}
+
+// RecipeViewer is the user recipes are shown to
+type RecipeViewer struct {
+	UserID        primitive.ObjectID // Zero for anonymous viewers
+	Admin         bool               // Admins can see and change all recipes
+	HouseholdID   primitive.ObjectID // Active household, zero for personal recipes
+	HouseholdRole string             // Role in the active household, empty for admins that are not members
+}
+
+// Anonymous reports whether the viewer is not logged in
+func (v RecipeViewer) Anonymous() bool {
+	return v.UserID.IsZero()
+}
+
+// CanEditHousehold reports whether the viewer can create and change recipes in the active
+// household
+func (v RecipeViewer) CanEditHousehold() bool {
+	return !v.HouseholdID.IsZero() && (v.Admin || v.HouseholdRole == HouseholdRoleOwner || v.HouseholdRole == HouseholdRoleEditor)
+}
+
+// InHousehold reports whether the recipe belongs to the household
+func (r *Recipe) InHousehold(householdID primitive.ObjectID) bool {
+	return r.HouseholdID != nil && !householdID.IsZero() && *r.HouseholdID == householdID
+}
+
+// VisibleTo reports whether the viewer can see the recipe
+func (r *Recipe) VisibleTo(viewer RecipeViewer) bool {
+	switch {
+	case r.Visibility == VisibilityPublic, r.EditableBy(viewer):
+		return true
+	case r.Visibility == VisibilityHousehold:
+		return r.InHousehold(viewer.HouseholdID)
+	default:
+		return false
+	}
+}
+
+// EditableBy reports whether the viewer can update or delete the recipe. Owners and editors of a
+// household can change the recipes of the household that are not private.
+func (r *Recipe) EditableBy(viewer RecipeViewer) bool {
+	switch {
+	case viewer.Admin:
+		return true
+	case viewer.Anonymous():
+		return false
+	case r.OwnerID == viewer.UserID:
+		return true
+	default:
+		return r.Visibility != VisibilityPrivate && r.InHousehold(viewer.HouseholdID) && viewer.CanEditHousehold()
+	}
+}
+
+// Origins of a recipe
+const (
+	SourceManual = "manual" // Entered by hand
+	SourceImage  = "image"  // Extracted from uploaded images
+	SourceURL    = "url"    // Extracted from a webpage, image or PDF at a URL
+	SourcePDF    = "pdf"    // Extracted from an uploaded PDF
+	SourceFile   = "file"   // Imported from a file, e.g. an export of another recipe app
+)
+
+// RecipeSource records where a recipe came from
+// @Description Provenance of a recipe
+type RecipeSource struct {
+	Type          string    `bson:"type" json:"type" enums:"manual,image,url,pdf,file" example:"url"`
+	URL           string    `bson:"url,omitempty" json:"url,omitempty" example:"https://example.com/recipe"`
+	Image         string    `bson:"image,omitempty" json:"image,omitempty" example:"sha256:9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"` // Reference to the original image: its URL, or the SHA-256 hash of an uploaded image
+	Model         string    `bson:"model,omitempty" json:"model,omitempty" example:"gpt-4.1-mini-2025-04-14"`                                                 // AI model the recipe was extracted with
+	PromptVersion string    `bson:"prompt_version,omitempty" json:"prompt_version,omitempty" example:"v1"`                                                    // Version of the AI prompt templates the recipe was extracted with
+	ImportedAt    time.Time `bson:"imported_at" json:"imported_at" example:"2023-01-15T09:30:00Z"`
 }
 
 // Ingredient represents an ingredient in a recipe
@@ -37,6 +127,15 @@ type RecipeFilter struct {
 	IngredientNames []string `json:"ingredient_names,omitempty" example:"['flour', 'sugar']"`
 	CookTime        int      `json:"cook_time,omitempty" example:"30"`
 	Tags            []string `json:"tags,omitempty" example:"['dessert', 'quick']"`
+	TranslationOf   string   `json:"translation_of,omitempty" example:"507f1f77bcf86cd799439011"`
+	Language        string   `json:"language,omitempty" example:"english"`
+	SourceType      string   `json:"source_type,omitempty" example:"url"`
+	SourceURL       string   `json:"source_url,omitempty" example:"example.com"` // Part of the source URL, e.g. the domain
+	SourceModel     string   `json:"source_model,omitempty" example:"gpt-4.1-mini-2025-04-14"`
+	Mine            bool     `json:"mine,omitempty" example:"true"` // Only recipes owned by the viewer
+	// Viewer the recipes are filtered for, only the recipes it can see are returned. All
+	// recipes are returned if nil.
+	Viewer *RecipeViewer `json:"-"`
 }
 
 // RecipePage represents a paginated response of recipes
@@ -48,3 +147,60 @@ type RecipePage struct {
 	Limit      int      `json:"limit" example:"10"`
 	TotalPages int      `json:"total_pages" example:"10"`
 }
+
+// RecipeFingerprint holds the fields of a recipe that are compared to find duplicates
+type RecipeFingerprint struct {
+	ID              primitive.ObjectID
+	Title           string
+	IngredientNames []string
+	SourceURL       string
+	CreatedAt       time.Time
+}
+
+// NewRecipeFingerprint returns the fingerprint of the recipe
+func NewRecipeFingerprint(recipe *Recipe) RecipeFingerprint {
+	fingerprint := RecipeFingerprint{
+		ID:              recipe.ID,
+		Title:           recipe.Title,
+		IngredientNames: make([]string, 0, len(recipe.Ingredients)),
+		CreatedAt:       recipe.CreatedAt,
+	}
+	for _, ingredient := range recipe.Ingredients {
+		fingerprint.IngredientNames = append(fingerprint.IngredientNames, ingredient.Name)
+	}
+	if recipe.Source != nil {
+		fingerprint.SourceURL = recipe.Source.URL
+	}
+	return fingerprint
+}
+
+// Reasons for recipes to be likely duplicates
+const (
+	DuplicateSourceURL = "source_url" // Imported from the same URL
+	DuplicateSimilar   = "similar"    // Similar title and ingredients
+)
+
+// DuplicateCandidate represents an existing recipe that is likely a duplicate of a new recipe
+// @Description Existing recipe that is likely a duplicate of the new recipe
+type DuplicateCandidate struct {
+	ID         primitive.ObjectID `json:"id" example:"507f1f77bcf86cd799439011"`
+	Title      string             `json:"title" example:"Chocolate Chip Cookies"`
+	Reason     string             `json:"reason" enums:"source_url,similar" example:"similar"`
+	Similarity float64            `json:"similarity" example:"0.92"` // From 0 to 1, 1 for the same source URL
+}
+
+// DuplicateCluster represents a group of recipes that are likely duplicates of each other
+// @Description Group of recipes that are likely duplicates of each other
+type DuplicateCluster struct {
+	Reason  string            `json:"reason" enums:"source_url,similar" example:"source_url"` // source_url if all recipes have the same source URL
+	Recipes []DuplicateRecipe `json:"recipes"`                                                // Oldest first
+}
+
+// DuplicateRecipe represents a recipe of a duplicate cluster
+// @Description Recipe of a duplicate cluster
+type DuplicateRecipe struct {
+	ID        primitive.ObjectID `json:"id" example:"507f1f77bcf86cd799439011"`
+	Title     string             `json:"title" example:"Chocolate Chip Cookies"`
+	SourceURL string             `json:"source_url,omitempty" example:"https://example.com/recipe"`
+	CreatedAt time.Time          `json:"created_at" example:"2023-01-15T09:30:00Z"`
+}
@@ -0,0 +1,67 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// RecipeShare represents a public link to a recipe, which anyone with the token can view without
+// logging in until it expires or is revoked
+type RecipeShare struct {
+	ID        primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	RecipeID  primitive.ObjectID `bson:"recipe_id" json:"recipe_id"`
+	Prefix    string             `bson:"prefix" json:"prefix" example:"shr_Xy3kP9q"` // Start of the token, to tell links apart
+	TokenHash string             `bson:"token_hash" json:"-"`                        // SHA-256 hash of the token
+	CreatedBy primitive.ObjectID `bson:"created_by" json:"created_by"`
+	ExpiresAt *time.Time         `bson:"expires_at,omitempty" json:"expires_at,omitempty"`
+	RevokedAt *time.Time         `bson:"revoked_at,omitempty" json:"revoked_at,omitempty"`
+	CreatedAt time.Time          `bson:"created_at" json:"created_at"`
+}
+
+// Active reports whether the link can be used at the given time
+func (s *RecipeShare) Active(now time.Time) bool {
+	return s.RevokedAt == nil && (s.ExpiresAt == nil || s.ExpiresAt.After(now))
+}
+
+// CreatedRecipeShare represents a new share link, with the token which is only returned once
+// @Description New share link, store the token now since only its hash is kept
+type CreatedRecipeShare struct {
+	RecipeShare
+	Token string `json:"token" example:"shr_Xy3kP9q..."`
+	Link  string `json:"link" example:"/shared/shr_Xy3kP9q..."` // Path of the public recipe page of the UI
+}
+
+// SharedRecipe represents the read-only view of a recipe behind a share link, without who owns
+// it or where it is stored
+// @Description Read-only recipe of a share link
+type SharedRecipe struct {
+	Title       string        `json:"title" example:"Chocolate Chip Cookies"`
+	Description string        `json:"description" example:"Delicious homemade chocolate chip cookies"`
+	Ingredients []Ingredient  `json:"ingredients"`
+	Steps       []string      `json:"steps" example:"['Preheat oven to 375°F', 'Mix ingredients', 'Bake for 10 minutes']"`
+	CookTime    int           `json:"cook_time,omitempty" example:"30"` // in minutes
+	Servings    int           `json:"servings,omitempty" example:"12"`
+	Tags        []string      `json:"tags,omitempty" example:"['dessert', 'cookies', 'baking']"`
+	Image       string        `json:"image,omitempty" example:"data:image/jpeg;base64,/9j/4AAQSkZJRgABAQAAAQ..."` // Base64 encoded image
+	Source      *RecipeSource `json:"source,omitempty"`
+	Language    string        `json:"language,omitempty" example:"english"`
+	UpdatedAt   time.Time     `json:"updated_at" example:"2023-01-15T09:30:00Z"`
+}
+
+// NewSharedRecipe returns the read-only view of the recipe
+func NewSharedRecipe(recipe *Recipe) *SharedRecipe {
+	return &SharedRecipe{
+		Title:       recipe.Title,
+		Description: recipe.Description,
+		Ingredients: recipe.Ingredients,
+		Steps:       recipe.Steps,
+		CookTime:    recipe.CookTime,
+		Servings:    recipe.Servings,
+		Tags:        recipe.Tags,
+		Image:       recipe.Image,
+		Source:      recipe.Source,
+		Language:    recipe.Language,
+		UpdatedAt:   recipe.UpdatedAt,
+	}
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// AIUsage represents the token usage of a single AI import
+// @Description Token usage and estimated cost of an AI import
+type AIUsage struct {
+	ID               primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty" example:"507f1f77bcf86cd799439011"`
+	Operation        string             `bson:"operation" json:"operation" example:"image"` // "image", "images", "webpage" or "text"
+	Model            string             `bson:"model" json:"model" example:"gpt-4.1-mini-2025-04-14"`
+	PromptTokens     int64              `bson:"prompt_tokens" json:"prompt_tokens" example:"1250"`
+	CompletionTokens int64              `bson:"completion_tokens" json:"completion_tokens" example:"380"`
+	Cost             float64            `bson:"cost" json:"cost" example:"0.0011"` // Estimated cost in USD
+	CreatedAt        time.Time          `bson:"created_at" json:"created_at" example:"2023-01-15T09:30:00Z"`
+}
+
+// AIUsageSummary represents the aggregated AI usage of a period
+// @Description Aggregated AI token usage and estimated cost of a period
+type AIUsageSummary struct {
+	From             time.Time      `json:"from" example:"2023-01-01T00:00:00Z"`
+	To               time.Time      `json:"to" example:"2023-02-01T00:00:00Z"`
+	Imports          int64          `json:"imports" example:"42"`
+	PromptTokens     int64          `json:"prompt_tokens" example:"52500"`
+	CompletionTokens int64          `json:"completion_tokens" example:"15960"`
+	Cost             float64        `json:"cost" example:"0.046"` // Estimated cost in USD
+	Models           []AIModelUsage `json:"models"`
+}
+
+// AIModelUsage represents the aggregated AI usage of a single model
+// @Description Aggregated AI token usage and estimated cost of a model
+type AIModelUsage struct {
+	Model            string  `bson:"_id" json:"model" example:"gpt-4.1-mini-2025-04-14"`
+	Imports          int64   `bson:"imports" json:"imports" example:"42"`
+	PromptTokens     int64   `bson:"prompt_tokens" json:"prompt_tokens" example:"52500"`
+	CompletionTokens int64   `bson:"completion_tokens" json:"completion_tokens" example:"15960"`
+	Cost             float64 `bson:"cost" json:"cost" example:"0.046"`
+}
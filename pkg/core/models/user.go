@@ -0,0 +1,71 @@
+package models
+
+import (
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// User represents a user that can change recipes. Users are managed in the database.
+type User struct {
+	ID           primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Username     string             `bson:"username" json:"username"`
+	PasswordHash string             `bson:"password_hash" json:"-"` // bcrypt hash of the password
+	DisabledAt   *time.Time         `bson:"disabled_at,omitempty" json:"disabled_at,omitempty"`
+	Admin        bool               `bson:"admin,omitempty" json:"admin,omitempty"` // Admins can see and change all recipes
+	CreatedAt    time.Time          `bson:"created_at" json:"created_at"`
+	UpdatedAt    time.Time          `bson:"updated_at" json:"updated_at"`
+}
+
+// Disabled reports whether the user can no longer log in
+func (u *User) Disabled() bool {
+	return u.DisabledAt != nil
+}
+
+// AuthTokens represents the tokens issued on login
+// @Description Signed access token for the Authorization header, and a refresh token for new tokens when it expires
+type AuthTokens struct {
+	AccessToken  string `json:"access_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	RefreshToken string `json:"refresh_token" example:"eyJhbGciOiJIUzI1NiIsInR5cCI6IkpXVCJ9..."`
+	TokenType    string `json:"token_type" example:"Bearer"`
+	ExpiresIn    int    `json:"expires_in" example:"900"` // Lifetime of the access token in seconds
+}
+
+// LoginAttempts represents the recent failed logins of a username or client IP, and its lock
+type LoginAttempts struct {
+	Key            string     `bson:"_id" json:"key"` // "user:<username>" or "ip:<address>"
+	Failures       int        `bson:"failures" json:"failures"`
+	FirstFailureAt time.Time  `bson:"first_failure_at" json:"first_failure_at"`
+	LockedUntil    *time.Time `bson:"locked_until,omitempty" json:"locked_until,omitempty"`
+	ExpiresAt      time.Time  `bson:"expires_at" json:"expires_at"` // When the attempts are forgotten
+}
+
+// Locked reports whether logins are locked at the given time
+func (a *LoginAttempts) Locked(now time.Time) bool {
+	return a.LockedUntil != nil && a.LockedUntil.After(now)
+}
+
+// Audited actions
+const (
+	AuditAccountLocked   = "account_locked"
+	AuditAccountUnlocked = "account_unlocked"
+	AuditUserCreated     = "user_created"
+	AuditUserDisabled    = "user_disabled"
+	AuditUserEnabled     = "user_enabled"
+	AuditUserDeleted     = "user_deleted"
+	AuditPasswordReset   = "password_reset"
+	AuditAdminGranted    = "admin_granted"
+	AuditAdminRevoked    = "admin_revoked"
+)
+
+// AuditEntry represents a security-relevant action, such as the lock of an account
+type AuditEntry struct {
+	ID          primitive.ObjectID `bson:"_id,omitempty" json:"id,omitempty"`
+	Action      string             `bson:"action" json:"action"`
+	Username    string             `bson:"username,omitempty" json:"username,omitempty"`   // Username the action concerns
+	ClientIP    string             `bson:"client_ip,omitempty" json:"client_ip,omitempty"` // Client IP the action concerns
+	Actor       string             `bson:"actor,omitempty" json:"actor,omitempty"`         // Who performed the action, empty for the system
+	Failures    int                `bson:"failures,omitempty" json:"failures,omitempty"`
+	LockedUntil *time.Time         `bson:"locked_until,omitempty" json:"locked_until,omitempty"`
+	CreatedAt   time.Time          `bson:"created_at" json:"created_at"`
+}